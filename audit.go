@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sky93/aaguid-information-generator/internal"
+)
+
+// -----------------------------------------------------------------------------
+// URL Audit
+// -----------------------------------------------------------------------------
+
+/*
+auditURLsMain implements the "audit-urls" subcommand: it checks every status report and rogue list
+URL of the merged dataset (see aaguids.AuditURLs) and writes the results as JSON to --out. If --out
+already exists, its successful checks are kept and only the remaining URLs are requested, so an
+interrupted audit (e.g. with Ctrl-C) can be resumed by running the same command again.
+*/
+func auditURLsMain(args []string) error {
+	fset := flag.NewFlagSet("audit-urls", flag.ExitOnError)
+	mdsFile := fset.String("mds-file", "", "Read the MDS3 BLOB (JWT) from this file instead of downloading it")
+	passkeyFile := fset.String("passkey-file", "", "Read the passkey-authenticator-aaguids JSON from this file instead of downloading it")
+	out := fset.String("out", "url-audit.json", "Write results to this file, resuming from it if it exists")
+	concurrency := fset.Int("concurrency", 4, "Maximum number of requests in flight")
+	hostDelay := fset.Duration("host-delay", time.Second, "Minimum interval between requests to the same host")
+	timeout := fset.Duration("timeout", 15*time.Second, "Timeout of each request")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	blob, entries, _, _, err := loadDataset(ctx, *mdsFile, *passkeyFile)
+	if err != nil {
+		return err
+	}
+	p := aaguids.NewProvider(entries, aaguids.Dataset{Serial: blob.No, NextUpdate: blob.NextUpdate, EntryCount: len(entries)})
+
+	var previous []aaguids.URLCheck
+	switch raw, err := os.ReadFile(*out); {
+	case err == nil:
+		if err := json.Unmarshal(raw, &previous); err != nil {
+			return fmt.Errorf("reading previous results from %s: %w", *out, err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	checks := p.AuditURLs(ctx, client, *concurrency, aaguids.WithHostDelay(*hostDelay), aaguids.WithResume(previous))
+
+	raw, err := json.MarshalIndent(checks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, append(raw, '\n'), 0o644); err != nil {
+		return err
+	}
+
+	broken := 0
+	for _, c := range checks {
+		switch {
+		case c.Error != "" || c.TLSError != "" || !c.OK():
+			broken++
+			fmt.Printf("BROKEN  %s  status=%d %s%s\n", c.URL, c.StatusCode, c.TLSError, c.Error)
+		case c.CrossHostRedirect:
+			fmt.Printf("MOVED   %s  -> %s\n", c.URL, c.FinalURL)
+		}
+	}
+	fmt.Printf("%d URLs checked, %d broken; results in %s\n", len(checks), broken, *out)
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// Credential Inventory Audit
+// -----------------------------------------------------------------------------
+
+/*
+auditInventoryMain implements the "audit" subcommand: it audits a dump of registered credentials
+against the merged dataset (see aaguids.AuditInventory) and writes the findings to --out as CSV or
+JSON. Malformed rows are reported as findings; only an unreadable input file stops the run.
+*/
+func auditInventoryMain(args []string) error {
+	fset := flag.NewFlagSet("audit", flag.ExitOnError)
+	input := fset.String("input", "", "Credential dump to audit: CSV with a header row, or a JSON array if the name ends in .json")
+	out := fset.String("out", "-", `Write the findings to this file ("-" for standard output)`)
+	format := fset.String("format", "csv", `Output format: "csv" or "json"`)
+	mdsFile := fset.String("mds-file", "", "Read the MDS3 BLOB (JWT) from this file instead of downloading it")
+	passkeyFile := fset.String("passkey-file", "", "Read the passkey-authenticator-aaguids JSON from this file instead of downloading it")
+	minCert := fset.String("min-cert-level", "", "Lowest acceptable FIDO_CERTIFIED* status, e.g. FIDO_CERTIFIED_L1")
+	unknown := fset.String("unknown", "deny", `Treatment of unknown AAGUIDs: "deny", "allow" or "allow_with_warning"`)
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if *input == "" {
+		return errors.New("audit: --input is required")
+	}
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("audit: --format must be csv or json, not %q", *format)
+	}
+	pol := aaguids.Policy{MinCertificationLevel: aaguids.AuthenticatorStatus(*minCert)}
+	if err := pol.Unknown.UnmarshalText([]byte(*unknown)); err != nil {
+		return fmt.Errorf("audit: --unknown: %w", err)
+	}
+
+	rows, err := readInventory(*input)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", *input, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	blob, entries, _, _, err := loadDataset(ctx, *mdsFile, *passkeyFile)
+	if err != nil {
+		return err
+	}
+	p := aaguids.NewProvider(entries, aaguids.Dataset{Serial: blob.No, NextUpdate: blob.NextUpdate, EntryCount: len(entries)})
+	findings := p.AuditInventory(ctx, rows, pol)
+
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(findings)
+	} else {
+		err = writeFindingsCSV(w, findings)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d credentials audited, %d findings\n", len(rows), len(findings))
+	return nil
+}
+
+// inventoryColumns maps the accepted CSV header names, lowercased without "_", "-" or spaces, to
+// the CredentialRecord field they fill.
+var inventoryColumns = map[string]string{
+	"credentialid":         "credentialId",
+	"id":                   "credentialId",
+	"aaguid":               "aaguid",
+	"registeredat":         "registeredAt",
+	"registrationdate":     "registeredAt",
+	"created":              "registeredAt",
+	"firmwareversion":      "firmwareVersion",
+	"authenticatorversion": "firmwareVersion",
+}
+
+// readInventory reads a credential dump: a JSON array of CredentialRecord objects when name ends
+// in .json, CSV with a header row otherwise. Field values are not validated here.
+func readInventory(name string) ([]aaguids.CredentialRecord, error) {
+	raw, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		var rows []struct {
+			aaguids.CredentialRecord
+			FirmwareVersion json.RawMessage `json:"firmwareVersion"` // a number or a string
+		}
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return nil, err
+		}
+		out := make([]aaguids.CredentialRecord, len(rows))
+		for i, r := range rows {
+			out[i] = r.CredentialRecord
+			out[i].Line = i + 1
+			if v := strings.Trim(string(r.FirmwareVersion), `"`); v != "null" {
+				out[i].FirmwareVersion = v
+			}
+		}
+		return out, nil
+	}
+
+	cr := csv.NewReader(bytes.NewReader(raw))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, h := range header {
+		key := strings.NewReplacer("_", "", "-", "", " ", "").Replace(strings.ToLower(h))
+		if field, ok := inventoryColumns[key]; ok {
+			columns[field] = i
+		}
+	}
+	if _, ok := columns["aaguid"]; !ok {
+		return nil, errors.New("no aaguid column in header")
+	}
+	var out []aaguids.CredentialRecord
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		get := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(rec) {
+				return rec[i]
+			}
+			return ""
+		}
+		out = append(out, aaguids.CredentialRecord{
+			CredentialID:    get("credentialId"),
+			AAGUID:          get("aaguid"),
+			RegisteredAt:    get("registeredAt"),
+			FirmwareVersion: get("firmwareVersion"),
+			Line:            line,
+		})
+	}
+}
+
+// writeFindingsCSV writes one CSV row per finding.
+func writeFindingsCSV(w io.Writer, findings []aaguids.AuditFinding) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"line", "credential_id", "aaguid", "kind", "since_registration", "status_now", "reason_now", "reason_at_registration", "detail", "notes"})
+	for _, f := range findings {
+		aaguid, statusNow, reasonNow, reasonThen := f.Record.AAGUID, "", "", ""
+		if f.Now != nil {
+			// The decision carries the canonical spelling, so the CSV joins on it exactly.
+			aaguid, statusNow, reasonNow = f.Now.AAGUID, string(f.Now.Status), string(f.Now.Reason)
+		}
+		if f.AtRegistration != nil {
+			reasonThen = string(f.AtRegistration.Reason)
+		}
+		cw.Write([]string{
+			strconv.Itoa(f.Record.Line), f.Record.CredentialID, aaguid, string(f.Kind),
+			strconv.FormatBool(f.SinceRegistration), statusNow, reasonNow, reasonThen, f.Detail,
+			strings.Join(f.Notes, "; "),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
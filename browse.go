@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sky93/aaguid-information-generator/internal"
+)
+
+// -----------------------------------------------------------------------------
+// Terminal Browser
+// -----------------------------------------------------------------------------
+
+/*
+browseMain implements the "browse" subcommand: a terminal UI over the merged dataset, built from the
+same sources as generation. By default both feeds are downloaded; --mds-file and --passkey-file
+browse local copies instead (e.g. a freshly downloaded BLOB).
+
+Keys:
+
+  - typing: incremental search over description, AAGUID and vendor
+  - ↑/↓, PgUp/PgDn: move the selection
+  - ctrl+r: revoked only, ctrl+f: fido2 only, ctrl+b: biometric-certified only
+  - esc: clear the search, ctrl+c: quit
+*/
+func browseMain(args []string) error {
+	fs := flag.NewFlagSet("browse", flag.ExitOnError)
+	mdsFile := fs.String("mds-file", "", "Read the MDS3 BLOB (JWT) from this file instead of downloading it")
+	passkeyFile := fs.String("passkey-file", "", "Read the passkey-authenticator-aaguids JSON from this file instead of downloading it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	blob, entries, _, _, err := loadDataset(context.Background(), *mdsFile, *passkeyFile)
+	if err != nil {
+		return err
+	}
+	p := aaguids.NewProvider(entries, aaguids.Dataset{Serial: blob.No, NextUpdate: blob.NextUpdate, EntryCount: len(entries)})
+
+	_, err = tea.NewProgram(newBrowser(p), tea.WithAltScreen()).Run()
+	return err
+}
+
+// browseFilters are the keystroke-toggled filters of the browser.
+type browseFilters struct {
+	revoked   bool
+	fido2     bool
+	biometric bool
+}
+
+// match reports whether e passes every enabled filter.
+func (f browseFilters) match(e aaguids.Entry) bool {
+	if f.revoked && !e.IsRevoked() {
+		return false
+	}
+	if f.fido2 && e.Summary().ProtocolFamily != "fido2" {
+		return false
+	}
+	if f.biometric && !e.HasBiometricCertification(aaguids.BiometricCertLevel1) {
+		return false
+	}
+	return true
+}
+
+// browser is the bubbletea model of the "browse" subcommand.
+type browser struct {
+	p       *aaguids.Provider
+	query   string
+	filters browseFilters
+	visible []aaguids.Entry
+	cursor  int
+	offset  int
+	width   int
+	height  int
+}
+
+func newBrowser(p *aaguids.Provider) *browser {
+	b := &browser{p: p, width: 100, height: 30}
+	b.refilter()
+	return b
+}
+
+// refilter recomputes the visible entries from the query and filters, keeping the cursor in range.
+func (b *browser) refilter() {
+	q := strings.ToLower(b.query)
+	b.visible = b.visible[:0]
+	for e := range b.p.Where(b.filters.match) {
+		if q == "" || b.matchesQuery(e, q) {
+			b.visible = append(b.visible, e)
+		}
+	}
+	b.cursor = min(b.cursor, max(len(b.visible)-1, 0))
+	b.offset = min(b.offset, b.cursor)
+}
+
+// matchesQuery reports whether the lowercase query q occurs in the description, AAGUID or vendor of e.
+func (b *browser) matchesQuery(e aaguids.Entry, q string) bool {
+	return strings.Contains(strings.ToLower(e.DisplayName()), q) ||
+		strings.Contains(strings.ToLower(e.Key()), q) ||
+		strings.Contains(strings.ToLower(b.p.Vendor(e.Key()).Name), q)
+}
+
+func (b *browser) Init() tea.Cmd {
+	return nil
+}
+
+func (b *browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		b.width, b.height = msg.Width, msg.Height
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			return b, tea.Quit
+		case tea.KeyUp:
+			b.move(-1)
+		case tea.KeyDown:
+			b.move(1)
+		case tea.KeyPgUp:
+			b.move(-b.listHeight())
+		case tea.KeyPgDown:
+			b.move(b.listHeight())
+		case tea.KeyCtrlR:
+			b.filters.revoked = !b.filters.revoked
+			b.refilter()
+		case tea.KeyCtrlF:
+			b.filters.fido2 = !b.filters.fido2
+			b.refilter()
+		case tea.KeyCtrlB:
+			b.filters.biometric = !b.filters.biometric
+			b.refilter()
+		case tea.KeyEsc:
+			b.query = ""
+			b.refilter()
+		case tea.KeyBackspace:
+			if r := []rune(b.query); len(r) > 0 {
+				b.query = string(r[:len(r)-1])
+				b.refilter()
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			b.query += string(msg.Runes)
+			b.refilter()
+		}
+	}
+	return b, nil
+}
+
+// move shifts the cursor by delta rows and scrolls the list to keep it visible.
+func (b *browser) move(delta int) {
+	b.cursor = max(0, min(b.cursor+delta, len(b.visible)-1))
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if h := b.listHeight(); b.cursor >= b.offset+h {
+		b.offset = b.cursor - h + 1
+	}
+}
+
+// listHeight is the number of list rows that fit below the header and the detail pane.
+func (b *browser) listHeight() int {
+	return max(b.height/2-3, 3)
+}
+
+func (b *browser) View() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Search: %s█\n", b.query)
+	fmt.Fprintf(&sb, "%d entries  [ctrl+r] revoked %s  [ctrl+f] fido2 %s  [ctrl+b] biometric %s  [esc] clear  [ctrl+c] quit\n\n",
+		len(b.visible), onOff(b.filters.revoked), onOff(b.filters.fido2), onOff(b.filters.biometric))
+
+	end := min(b.offset+b.listHeight(), len(b.visible))
+	for i := b.offset; i < end; i++ {
+		marker := "  "
+		if i == b.cursor {
+			marker = "> "
+		}
+		sb.WriteString(truncate(marker+listRow(b.visible[i]), b.width))
+		sb.WriteByte('\n')
+	}
+	sb.WriteString(strings.Repeat("─", max(b.width, 1)))
+	sb.WriteByte('\n')
+	if len(b.visible) > 0 {
+		sb.WriteString(b.detail(b.visible[b.cursor]))
+	}
+	return sb.String()
+}
+
+// listRow renders one list line: description, identifier and latest status.
+func listRow(e aaguids.Entry) string {
+	s := e.Summary()
+	return fmt.Sprintf("%-40s %-36s %s", truncate(s.Description, 40), e.Key(), s.Status)
+}
+
+// detail renders the detail pane of e.
+func (b *browser) detail(e aaguids.Entry) string {
+	var sb strings.Builder
+	s := e.Summary()
+	v := b.p.Vendor(e.Key())
+	fmt.Fprintf(&sb, "%s\n", s.Description)
+	fmt.Fprintf(&sb, "ID:             %s\n", e.Key())
+	fmt.Fprintf(&sb, "Vendor:         %s (%s)\n", orDash(v.Name), v.Confidence)
+	fmt.Fprintf(&sb, "Protocol:       %s\n", orDash(s.ProtocolFamily))
+	fmt.Fprintf(&sb, "Certification:  %s\n", orDash(string(s.CertificationLevel)))
+
+	transports := "-"
+	if gi, ok := e.GetInfo(); ok && len(gi.Transports) > 0 {
+		transports = strings.Join(gi.Transports, ", ")
+	}
+	fmt.Fprintf(&sb, "Transports:     %s\n", transports)
+
+	sb.WriteString("Status timeline:\n")
+	n := 0
+	for r := range e.StatusReportsSeq() {
+		date := "(undated)"
+		if r.EffectiveDate != nil {
+			date = *r.EffectiveDate
+		}
+		fmt.Fprintf(&sb, "  %-10s  %s\n", date, r.Status)
+		n++
+	}
+	if n == 0 {
+		sb.WriteString("  -\n")
+	}
+
+	sb.WriteString("Trust anchors:\n")
+	anchors := trustAnchors(e.MetadataStatement)
+	for _, a := range anchors {
+		fmt.Fprintf(&sb, "  %s\n", truncate(a, b.width-2))
+	}
+	if len(anchors) == 0 {
+		sb.WriteString("  -\n")
+	}
+	return sb.String()
+}
+
+// trustAnchors describes each attestation root of m by subject and expiry.
+func trustAnchors(m aaguids.MetadataStatement) []string {
+	var out []string
+	for i, c := range m.AttestationRootCertificates {
+		der, err := base64.StdEncoding.DecodeString(c)
+		if err != nil {
+			out = append(out, fmt.Sprintf("root %d: undecodable (%v)", i, err))
+			continue
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			out = append(out, fmt.Sprintf("root %d: unparseable (%v)", i, err))
+			continue
+		}
+		out = append(out, fmt.Sprintf("%s (expires %s)", cert.Subject, cert.NotAfter.Format("2006-01-02")))
+	}
+	return out
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,234 @@
+/*
+metadata-server is an example relying-party sidecar built only from the public API of the aaguids
+package. It:
+
+  - starts from the embedded dataset (aaguids.Default)
+  - refreshes it from MDS3, caching the last verified BLOB on disk so restarts work offline
+  - installs a strict Policy: unknown authenticators are denied and FIDO_CERTIFIED_L1 is required;
+    a stricter level can be trialled in shadow mode first (aaguids.ShadowPolicy)
+  - serves entry lookups, policy decisions, the dataset identity and a denylist export over HTTP,
+    shaping entries with a FieldMask so public deployments never expose attestation roots
+  - answers Kubernetes liveness and readiness probes from Provider.Health
+  - logs every status change of a known authenticator via slog
+  - with -verify-metadata, only self-tests the embedded dataset (aaguids.RunSelfTest) and exits,
+    for deploy-time checks
+
+Run it from the repository root with:
+
+	go run ./examples/metadata-server -addr=:8080 -cache-dir=/tmp/metadata-server
+
+Every flag can also be set through the environment variable shown in its usage. In a real
+deployment, import the generated package (e.g. yourmodule/internal/aaguids) instead of the
+template package imported here, whose embedded dataset is empty.
+*/
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/sky93/aaguid-information-generator/internal"
+)
+
+// -----------------------------------------------------------------------------
+// Configuration
+// -----------------------------------------------------------------------------
+
+// config holds the settings of one server run.
+type config struct {
+	addr            string
+	mdsURL          string
+	mdsFile         string
+	cacheDir        string
+	refreshInterval time.Duration
+	staleNotReady   time.Duration
+	minCertLevel    string
+	candidateLevel  string
+	fields          string
+	uniformTiming   bool
+	verifyMetadata  bool
+}
+
+// parseConfig reads the flags, falling back to the METADATA_SERVER_* environment variables.
+func parseConfig(args []string) (config, error) {
+	var c config
+	fs := flag.NewFlagSet("metadata-server", flag.ContinueOnError)
+	fs.StringVar(&c.addr, "addr", env("METADATA_SERVER_ADDR", ":8080"), "listen address (METADATA_SERVER_ADDR)")
+	fs.StringVar(&c.mdsURL, "mds-url", env("METADATA_SERVER_MDS_URL", "https://mds3.fidoalliance.org/"), "MDS3 BLOB URL (METADATA_SERVER_MDS_URL)")
+	fs.StringVar(&c.mdsFile, "mds-file", env("METADATA_SERVER_MDS_FILE", ""), "read the BLOB from this file once instead of refreshing from -mds-url (METADATA_SERVER_MDS_FILE)")
+	fs.StringVar(&c.cacheDir, "cache-dir", env("METADATA_SERVER_CACHE_DIR", ""), "directory caching the last verified BLOB; empty disables caching (METADATA_SERVER_CACHE_DIR)")
+	fs.DurationVar(&c.refreshInterval, "refresh", envDuration("METADATA_SERVER_REFRESH", 24*time.Hour), "MDS refresh interval (METADATA_SERVER_REFRESH)")
+	fs.DurationVar(&c.staleNotReady, "stale-not-ready-after", envDuration("METADATA_SERVER_STALE_NOT_READY_AFTER", 7*24*time.Hour), "fail /readyz once the dataset is this far past its nextUpdate; 0 never fails it for staleness (METADATA_SERVER_STALE_NOT_READY_AFTER)")
+	fs.StringVar(&c.minCertLevel, "min-cert-level", env("METADATA_SERVER_MIN_CERT_LEVEL", string(aaguids.FIDO_CERTIFIED_L1)), "lowest accepted FIDO_CERTIFIED* status; empty accepts uncertified authenticators (METADATA_SERVER_MIN_CERT_LEVEL)")
+	fs.StringVar(&c.candidateLevel, "candidate-min-cert-level", env("METADATA_SERVER_CANDIDATE_MIN_CERT_LEVEL", ""), "trial this -min-cert-level in shadow mode, logging the decisions it would change; empty disables shadow mode (METADATA_SERVER_CANDIDATE_MIN_CERT_LEVEL)")
+	fs.StringVar(&c.fields, "fields", env("METADATA_SERVER_FIELDS", "public"), `entry fields served: "public" or "internal" (METADATA_SERVER_FIELDS)`)
+	fs.BoolVar(&c.uniformTiming, "uniform-lookup-timing", env("METADATA_SERVER_UNIFORM_LOOKUP_TIMING", "") == "true", "make lookups of known and unknown AAGUIDs take the same work, at some cost per request (METADATA_SERVER_UNIFORM_LOOKUP_TIMING)")
+	fs.BoolVar(&c.verifyMetadata, "verify-metadata", false, "self-test the embedded dataset, print the report and exit")
+	if err := fs.Parse(args); err != nil {
+		return c, err
+	}
+	if _, ok := fieldMasks[c.fields]; !ok {
+		return c, fmt.Errorf("-fields must be public or internal, not %q", c.fields)
+	}
+	if c.refreshInterval <= 0 {
+		return c, errors.New("-refresh must be positive")
+	}
+	if c.staleNotReady < 0 {
+		return c, errors.New("-stale-not-ready-after must not be negative")
+	}
+	return c, nil
+}
+
+// fieldMasks maps the values of -fields to their masks.
+var fieldMasks = map[string]aaguids.FieldMask{
+	"public":   aaguids.MaskPublic,
+	"internal": aaguids.MaskInternal,
+}
+
+func env(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return d
+	}
+	return def
+}
+
+// -----------------------------------------------------------------------------
+// Main
+// -----------------------------------------------------------------------------
+
+func main() {
+	log := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	cfg, err := parseConfig(os.Args[1:])
+	if err != nil {
+		log.Error("invalid configuration", "err", err)
+		os.Exit(2)
+	}
+	if cfg.verifyMetadata {
+		os.Exit(aaguids.RunSelfTest(os.Stdout, false, aaguids.SelfTestConfig{}))
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg, log); err != nil {
+		log.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+// run wires the provider, refresher, change log and HTTP server together and blocks until ctx is
+// done or the server fails.
+func run(ctx context.Context, cfg config, log *slog.Logger) error {
+	p := aaguids.Default()
+	p.SetUniformLookupTiming(cfg.uniformTiming)
+	log.Info("embedded dataset loaded", "dataset", p.DatasetInfo(), "version", aaguids.Version())
+
+	// Every outbound request goes through one FetchCoordinator, so overlapping refreshes share a
+	// download and retries are spaced per host.
+	fetch := aaguids.NewFetchCoordinator(aaguids.WithFetchObserver(func(ev aaguids.FetchEvent) {
+		log.Debug("outbound request", "kind", ev.Kind, "url", ev.URL, "wait", ev.Wait, "err", ev.Err)
+	}))
+	r := &refresher{
+		p:        p,
+		client:   fetch.Client(&http.Client{Timeout: time.Minute}),
+		url:      cfg.mdsURL,
+		interval: cfg.refreshInterval,
+		log:      log,
+	}
+	if cfg.cacheDir != "" {
+		if err := os.MkdirAll(cfg.cacheDir, 0o755); err != nil {
+			return err
+		}
+		r.cacheFile = filepath.Join(cfg.cacheDir, "mds3.jwt")
+	}
+	if cfg.mdsFile != "" {
+		err := r.loadFile(cfg.mdsFile)
+		p.RecordRefresh(err)
+		if err != nil {
+			return err
+		}
+	} else {
+		if err := r.loadFile(r.cacheFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warn("ignoring BLOB cache", "err", err)
+		}
+		go r.run(ctx)
+	}
+
+	if err := logChanges(ctx, p, log); err != nil {
+		return err
+	}
+
+	pol := aaguids.Policy{
+		Unknown:               aaguids.UnknownDeny,
+		MinCertificationLevel: aaguids.AuthenticatorStatus(cfg.minCertLevel),
+	}
+	sp := aaguids.ShadowPolicy{Active: pol, Candidate: pol, OnDivergence: func(d aaguids.Divergence) {
+		log.Info("candidate policy diverges", "divergence", d)
+	}}
+	if cfg.candidateLevel != "" {
+		sp.Candidate.MinCertificationLevel = aaguids.AuthenticatorStatus(cfg.candidateLevel)
+	}
+	var health []aaguids.HealthOption
+	if cfg.staleNotReady > 0 {
+		health = append(health, aaguids.WithStaleNotReadyAfter(cfg.staleNotReady))
+	}
+	srv := &http.Server{
+		Addr:              cfg.addr,
+		Handler:           newHandler(p, sp, fieldMasks[cfg.fields], log, health...),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	log.Info("listening", "addr", cfg.addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// logChanges logs the change events of every authenticator in the current dataset until ctx is
+// done. Authenticators first seen in a later refresh are not watched.
+func logChanges(ctx context.Context, p *aaguids.Provider, log *slog.Logger) error {
+	var ids []string
+	for e := range p.Where(nil) {
+		if k := e.Key(); k != "" {
+			ids = append(ids, k)
+		}
+	}
+	events, err := p.Watch(ctx, ids)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for ev := range events {
+			log.Info("authenticator changed",
+				"aaguid", ev.AAGUID,
+				"kind", ev.Kind.String(),
+				"oldStatus", ev.OldStatus,
+				"newStatus", ev.NewStatus,
+				"serial", ev.Serial,
+			)
+		}
+	}()
+	return nil
+}
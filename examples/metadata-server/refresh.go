@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sky93/aaguid-information-generator/internal"
+)
+
+// refresher keeps a Provider up to date with the MDS3 BLOB, caching the last verified BLOB in
+// cacheFile ("" disables caching). BLOBs are verified against roots, nil meaning the system trust
+// store.
+type refresher struct {
+	p         *aaguids.Provider
+	roots     *x509.CertPool
+	client    *http.Client
+	url       string
+	cacheFile string
+	interval  time.Duration
+	log       *slog.Logger
+}
+
+// run refreshes immediately and then every interval until ctx is done. Failures are logged and
+// keep the current dataset.
+func (r *refresher) run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if err := r.refresh(ctx); err != nil {
+			r.log.Warn("MDS refresh failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh downloads the BLOB, applies it when it is newer than the current dataset and writes it
+// to the cache. The outcome is recorded for Provider.Health, so a failed refresh of a stale
+// dataset shows as serving stale.
+func (r *refresher) refresh(ctx context.Context) (err error) {
+	defer func() { r.p.RecordRefresh(err) }()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	jwt, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	applied, err := r.apply(jwt)
+	if err != nil || !applied || r.cacheFile == "" {
+		return err
+	}
+	return writeFileAtomic(r.cacheFile, jwt)
+}
+
+// loadFile applies the BLOB stored at name. An empty name is reported as os.ErrNotExist.
+func (r *refresher) loadFile(name string) error {
+	if name == "" {
+		return os.ErrNotExist
+	}
+	jwt, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	_, err = r.apply(jwt)
+	return err
+}
+
+// apply verifies jwt and updates the Provider if its serial is newer than the current dataset's.
+// UpdateFromBLOB merges the BLOB over the community entries of the current dataset, so those of the
+// embedded dataset survive every refresh.
+func (r *refresher) apply(jwt []byte) (bool, error) {
+	blob, err := aaguids.ParseMetadataBLOB(jwt, r.roots)
+	if err != nil {
+		return false, err
+	}
+	if current := r.p.DatasetInfo().Serial; blob.No <= current {
+		r.log.Debug("MDS BLOB is not newer than the current dataset", "serial", blob.No, "current", current)
+		return false, nil
+	}
+	r.p.UpdateFromBLOB(blob)
+	r.log.Info("dataset updated", "serial", blob.No, "nextUpdate", blob.NextUpdate, "entries", r.p.DatasetInfo().EntryCount)
+	return true, nil
+}
+
+// writeFileAtomic replaces name with data so readers never observe a partial file.
+func writeFileAtomic(name string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/sky93/aaguid-information-generator/internal"
+)
+
+/*
+newHandler returns the HTTP API of the server. Entries are always serialized through mask:
+
+  - GET /v1/entries/{aaguid}: the entry, or 404 when the AAGUID is not in the dataset; U2F entries
+    are served under their synthetic key (see aaguids.U2FKey)
+  - GET /v1/entries/{aaguid}/icon: the entry's icon with HTTP caching, see Provider.ServeIcon
+    (?theme=dark, ?fallback=avatar)
+  - GET /v1/decisions/{aaguid}: the Decision of the active policy of sp, with status 403 when it
+    rejects the AAGUID; the candidate policy is evaluated alongside and only logged
+  - GET /v1/dataset: the dataset identity and library version
+  - GET /v1/denylist: every AAGUID whose latest status is in aaguids.DefaultDenyStatuses
+  - GET /v1/export: the full dataset, see Provider.ExportJSON
+  - GET /healthz and /readyz: liveness and readiness of the dataset, see Provider.HealthHandler;
+    health configures the readiness thresholds
+*/
+func newHandler(p *aaguids.Provider, sp aaguids.ShadowPolicy, mask aaguids.FieldMask, log *slog.Logger, health ...aaguids.HealthOption) http.Handler {
+	mux := http.NewServeMux()
+
+	probes := p.HealthHandler(health...)
+	mux.Handle("GET /healthz", probes)
+	mux.Handle("GET /readyz", probes)
+
+	mux.HandleFunc("GET /v1/entries/{aaguid}", func(w http.ResponseWriter, r *http.Request) {
+		e, ok := p.GetEntry(r.PathValue("aaguid"))
+		if !ok {
+			http.Error(w, "unknown AAGUID", http.StatusNotFound)
+			return
+		}
+		raw, err := mask.MarshalEntry(e)
+		if err != nil {
+			log.Error("encoding entry", "err", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, log, http.StatusOK, json.RawMessage(raw))
+	})
+
+	mux.HandleFunc("GET /v1/entries/{aaguid}/icon", func(w http.ResponseWriter, r *http.Request) {
+		p.ServeIcon(w, r, r.PathValue("aaguid"))
+	})
+
+	mux.HandleFunc("GET /v1/decisions/{aaguid}", func(w http.ResponseWriter, r *http.Request) {
+		d, _ := p.ShadowDecision(sp, r.PathValue("aaguid"))
+		status := http.StatusOK
+		if !d.Allowed {
+			status = http.StatusForbidden
+			log.Info("authenticator rejected", "aaguid", d.AAGUID, "reason", d.Reason, "status", d.Status)
+		}
+		writeJSON(w, log, status, d)
+	})
+
+	mux.HandleFunc("GET /v1/dataset", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, log, http.StatusOK, struct {
+			Dataset aaguids.Dataset `json:"dataset"`
+			Version string          `json:"version"`
+		}{p.DatasetInfo(), aaguids.Version()})
+	})
+
+	mux.HandleFunc("GET /v1/denylist", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, log, http.StatusOK, denylist(p))
+	})
+
+	mux.HandleFunc("GET /v1/export", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := p.ExportJSON(w, aaguids.WithFieldMask(mask)); err != nil {
+			log.Error("exporting dataset", "err", err)
+		}
+	})
+
+	return mux
+}
+
+// deniedAuthenticator is one element of the denylist export.
+type deniedAuthenticator struct {
+	AAGUID        string                      `json:"aaguid"`
+	Status        aaguids.AuthenticatorStatus `json:"status"`
+	EffectiveDate *string                     `json:"effectiveDate,omitempty"`
+}
+
+// denylist returns the entries whose latest status is denied by default, in ascending key order.
+// U2F entries are listed by their synthetic key.
+func denylist(p *aaguids.Provider) []deniedAuthenticator {
+	out := make([]deniedAuthenticator, 0)
+	for e := range p.Where(func(e aaguids.Entry) bool { return e.CurrentStatusIs(aaguids.DefaultDenyStatuses...) }) {
+		reports := slices.Collect(e.StatusReportsSeq())
+		latest := reports[len(reports)-1]
+		out = append(out, deniedAuthenticator{AAGUID: e.Key(), Status: latest.Status, EffectiveDate: latest.EffectiveDate})
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, log *slog.Logger, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("writing response", "err", err)
+	}
+}
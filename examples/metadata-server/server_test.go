@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sky93/aaguid-information-generator/internal"
+)
+
+const (
+	yubiKey = "ee882879-721c-4913-9775-3dfcce97072a"
+	revoked = "0bb43545-fd2c-4185-87dd-feb0b2916ace"
+	synced  = "ea9b8d66-4d01-1d21-3ce4-b6b48cb575d4" // community-only
+)
+
+// testBLOB is the payload the fake MDS serves: one certified and one revoked authenticator.
+const testBLOB = `{
+  "legalHeader": "test",
+  "no": 7,
+  "nextUpdate": "2099-01-01",
+  "entries": [
+    {
+      "aaguid": "ee882879-721c-4913-9775-3dfcce97072a",
+      "metadataStatement": {"aaguid": "ee882879-721c-4913-9775-3dfcce97072a", "description": "YubiKey 5 Series", "protocolFamily": "fido2", "schema": 3},
+      "statusReports": [{"status": "FIDO_CERTIFIED_L1", "effectiveDate": "2020-05-12"}],
+      "timeOfLastStatusChange": "2020-05-12"
+    },
+    {
+      "aaguid": "0bb43545-fd2c-4185-87dd-feb0b2916ace",
+      "metadataStatement": {"aaguid": "0bb43545-fd2c-4185-87dd-feb0b2916ace", "description": "Revoked Key", "protocolFamily": "fido2", "schema": 3},
+      "statusReports": [
+        {"status": "FIDO_CERTIFIED_L1", "effectiveDate": "2019-01-01"},
+        {"status": "REVOKED", "effectiveDate": "2023-06-01"}
+      ],
+      "timeOfLastStatusChange": "2023-06-01"
+    }
+  ]
+}`
+
+// signBLOB returns payload as an ES256 JWT signed by a fresh self-signed CA, and a pool holding
+// that CA.
+func signBLOB(t *testing.T, payload string) ([]byte, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test MDS signer"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _ := json.Marshal(map[string]any{"alg": "ES256", "typ": "JWT", "x5c": []string{base64.StdEncoding.EncodeToString(der)}})
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString([]byte(payload))
+	digest := sha256.Sum256([]byte(input))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return []byte(input + "." + base64.RawURLEncoding.EncodeToString(sig)), roots
+}
+
+// communityDataset stands in for a generated embedded dataset: community entries only, for the
+// certified authenticator of testBLOB and for a synced one MDS does not list.
+func communityDataset() *aaguids.Provider {
+	entry := func(aaguid, name string) aaguids.Entry {
+		return aaguids.Entry{
+			AAGUID:              aaguid,
+			MetadataStatement:   aaguids.MetadataStatement{AAGUID: aaguid},
+			CommunityExtensions: &aaguids.CommunityExtensions{Source: "passkey-community", DisplayName: name},
+		}
+	}
+	return aaguids.NewProvider(map[string]aaguids.Entry{
+		yubiKey: entry(yubiKey, "YubiKey 5"),
+		synced:  entry(synced, "Google Password Manager"),
+	}, aaguids.Dataset{Serial: 1, Sources: []aaguids.DatasetSource{{Name: "passkey-community"}}})
+}
+
+func TestRefreshPolicyHTTP(t *testing.T) {
+	jwt, roots := signBLOB(t, testBLOB)
+	mds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jwt)
+	}))
+	defer mds.Close()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	p := communityDataset()
+	r := &refresher{
+		p:         p,
+		roots:     roots,
+		client:    mds.Client(),
+		url:       mds.URL,
+		cacheFile: filepath.Join(t.TempDir(), "mds3.jwt"),
+		interval:  time.Hour,
+		log:       log,
+	}
+	if err := r.refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if err := r.loadFile(r.cacheFile); err != nil {
+		t.Errorf("reloading the cached BLOB: %v", err)
+	}
+
+	pol := aaguids.Policy{Unknown: aaguids.UnknownDeny, MinCertificationLevel: aaguids.FIDO_CERTIFIED_L1}
+	srv := httptest.NewServer(newHandler(p, aaguids.ShadowPolicy{Active: pol, Candidate: pol}, aaguids.MaskPublic, log))
+	defer srv.Close()
+
+	get := func(path string, v any) int {
+		t.Helper()
+		resp, err := srv.Client().Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil && resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		return resp.StatusCode
+	}
+
+	var e struct {
+		StatusReports       []struct{ Status string }
+		CommunityExtensions *aaguids.CommunityExtensions
+	}
+	if code := get("/v1/entries/"+yubiKey, &e); code != http.StatusOK || len(e.StatusReports) != 1 ||
+		e.CommunityExtensions == nil || e.CommunityExtensions.DisplayName != "YubiKey 5" {
+		t.Errorf("GET entry of the YubiKey: %d %+v, want the MDS status and the community name", code, e)
+	}
+	var gpm struct{ CommunityExtensions *aaguids.CommunityExtensions }
+	if code := get("/v1/entries/"+synced, &gpm); code != http.StatusOK || gpm.CommunityExtensions == nil {
+		t.Errorf("GET entry of the community-only authenticator after refresh: %d", code)
+	}
+	if code := get("/v1/entries/00000000-0000-0000-0000-000000000001", &struct{}{}); code != http.StatusNotFound {
+		t.Errorf("GET entry of an unknown AAGUID: %d, want 404", code)
+	}
+
+	var d aaguids.Decision
+	if code := get("/v1/decisions/"+yubiKey, &d); code != http.StatusOK || !d.Allowed {
+		t.Errorf("decision for the YubiKey: %d %+v", code, d)
+	}
+	if code := get("/v1/decisions/"+revoked, &d); code != http.StatusForbidden || d.Allowed || d.Reason != aaguids.ReasonStatusDenied {
+		t.Errorf("decision for the revoked authenticator: %d %+v, want 403 %s", code, d, aaguids.ReasonStatusDenied)
+	}
+
+	var info struct {
+		Dataset aaguids.Dataset
+		Version string
+	}
+	if code := get("/v1/dataset", &info); code != http.StatusOK {
+		t.Fatalf("GET dataset: %d", code)
+	}
+	if info.Dataset.Serial != 7 || info.Dataset.EntryCount != 3 || len(info.Dataset.Sources) != 2 ||
+		info.Dataset.Sources[0].Name != aaguids.SourceFIDOMDS3 || info.Dataset.Sources[1].Name != "passkey-community" {
+		t.Errorf("dataset = %+v, want serial 7 with the MDS and community sources and 3 entries", info.Dataset)
+	}
+	if info.Version != aaguids.Version() {
+		t.Errorf("version = %q", info.Version)
+	}
+}
+
+func TestRefreshRecordsHealth(t *testing.T) {
+	jwt, roots := signBLOB(t, testBLOB)
+	var fail atomic.Bool
+	fail.Store(true)
+	mds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(jwt)
+	}))
+	defer mds.Close()
+
+	// A dataset past its nextUpdate, as after a long MDS outage.
+	p := aaguids.NewProvider(map[string]aaguids.Entry{yubiKey: {AAGUID: yubiKey}}, aaguids.Dataset{Serial: 1, NextUpdate: "2020-01-01"})
+	r := &refresher{p: p, roots: roots, client: mds.Client(), url: mds.URL, log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	if h := p.Health(); !h.Stale || h.ServingStale || !h.LastRefresh.IsZero() {
+		t.Fatalf("before any refresh: %+v, want stale but not serving stale", h)
+	}
+
+	if err := r.refresh(context.Background()); err == nil {
+		t.Fatal("refresh against a failing MDS succeeded")
+	}
+	if h := p.Health(); !h.ServingStale || h.LastRefreshErr == nil || h.LastRefresh.IsZero() {
+		t.Errorf("after a failed refresh: %+v, want serving stale with the error recorded", h)
+	}
+
+	fail.Store(false)
+	if err := r.refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if h := p.Health(); h.Stale || h.ServingStale || h.LastRefreshErr != nil || h.Serial != 7 {
+		t.Errorf("after a successful refresh: %+v, want serial 7, fresh", h)
+	}
+}
+
+func TestHealthProbes(t *testing.T) {
+	mds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer mds.Close()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	pol := aaguids.Policy{Unknown: aaguids.UnknownDeny}
+
+	// probe returns the status code and body of GET path on a server for p.
+	probe := func(p *aaguids.Provider, path string) (int, map[string]any) {
+		t.Helper()
+		srv := httptest.NewServer(newHandler(p, aaguids.ShadowPolicy{Active: pol, Candidate: pol}, aaguids.MaskPublic, log,
+			aaguids.WithStaleNotReadyAfter(7*24*time.Hour)))
+		defer srv.Close()
+		resp, err := srv.Client().Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		return resp.StatusCode, body
+	}
+
+	fresh := communityDataset()
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if code, body := probe(fresh, path); code != http.StatusOK || body["ready"] != true {
+			t.Errorf("GET %s on a fresh dataset: %d %v, want 200 and ready", path, code, body)
+		}
+	}
+
+	// A dataset years past its nextUpdate whose refresh fails is served stale.
+	stale := aaguids.NewProvider(map[string]aaguids.Entry{yubiKey: {AAGUID: yubiKey}}, aaguids.Dataset{Serial: 1, NextUpdate: "2020-01-01"})
+	r := &refresher{p: stale, client: mds.Client(), url: mds.URL, log: log}
+	if err := r.refresh(context.Background()); err == nil {
+		t.Fatal("refresh against a failing MDS succeeded")
+	}
+	if code, body := probe(stale, "/readyz"); code != http.StatusServiceUnavailable || body["ready"] != false || body["servingStale"] != true {
+		t.Errorf("GET /readyz while serving stale: %d %v, want 503, not ready and serving stale", code, body)
+	}
+	// Liveness never fails for staleness, so the process is not restarted.
+	if code, body := probe(stale, "/healthz"); code != http.StatusOK || body["status"] != aaguids.HealthDegraded {
+		t.Errorf("GET /healthz while serving stale: %d %v, want 200 and degraded", code, body)
+	}
+}
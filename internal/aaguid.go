@@ -0,0 +1,136 @@
+package aaguids
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+/*
+AAGUID is the 128-bit Authenticator Attestation GUID defined in WebAuthn § 6.5.1 “Attested
+Credential Data”. Its canonical text form is the lowercase, dash-separated UUID layout
+"xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx".
+*/
+type AAGUID [16]byte
+
+// String returns the canonical lowercase, dash-separated form of the AAGUID.
+func (a AAGUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], a[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], a[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], a[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], a[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:36], a[10:16])
+	return string(buf[:])
+}
+
+// IsZero reports whether a is the all-zero AAGUID, which authenticators without a model identity
+// (e.g. U2F and privacy-preserving ones) report.
+func (a AAGUID) IsZero() bool {
+	return a == AAGUID{}
+}
+
+// zeroAAGUID is the canonical form of the all-zero AAGUID. It has no letters, so no other case of it
+// exists.
+const zeroAAGUID = "00000000-0000-0000-0000-000000000000"
+
+/*
+IsZeroAAGUID reports whether s, in any spelling the default NormalizerChain accepts, is the
+all-zero AAGUID. U2F authenticators and privacy-preserving ones (e.g. those returning "none"
+attestation) report it; it identifies no model, so GetEntry never matches it. See
+GetEntryWithKeyIdentifiers for finding the entry of such an authenticator.
+*/
+func IsZeroAAGUID(s string) bool {
+	id, _, err := defaultChain.Parse(s)
+	return err == nil && id.IsZero()
+}
+
+// MarshalText encodes the AAGUID in its canonical String form, so it encodes to JSON as a string
+// and can key JSON objects.
+func (a AAGUID) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText decodes an AAGUID in the layout ParseAAGUID accepts.
+func (a *AAGUID) UnmarshalText(b []byte) error {
+	v, err := ParseAAGUID(string(b))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// ParsedAAGUID parses the AAGUID field of e. It fails for entries without one, such as U2F
+// entries (see Entry.Key), and for malformed upstream values.
+func (e Entry) ParsedAAGUID() (AAGUID, error) {
+	return ParseAAGUID(e.AAGUID)
+}
+
+// ParseAAGUID decodes the dash-separated UUID layout, accepting upper- or lowercase hex. Errors
+// name the length, the misplaced dash or the first non-hex character that was wrong.
+func ParseAAGUID(s string) (AAGUID, error) {
+	var a AAGUID
+	if len(s) != 36 {
+		return a, fmt.Errorf("aaguid must be 36 characters in the xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx layout, got %d", len(s))
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if c != '-' {
+				return a, fmt.Errorf("aaguid must have a dash at position %d, got %q", i+1, c)
+			}
+		case !isHexDigit(c):
+			return a, fmt.Errorf("aaguid contains non-hex character %q at position %d", c, i+1)
+		}
+	}
+	h := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+	hex.Decode(a[:], []byte(h))
+	return a, nil
+}
+
+// isHexDigit reports whether c is a hex digit in either case.
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// FormatAAGUID returns the canonical lowercase, dash-separated form of the raw AAGUID bytes, e.g.
+// the 16 bytes following rpIdHash, flags and signCount in authenticatorData.
+func FormatAAGUID(b [16]byte) string {
+	return AAGUID(b).String()
+}
+
+// GetEntryFromBytes retrieves the Entry of a raw AAGUID from the embedded dataset. See
+// Provider.GetEntryFromBytes.
+func GetEntryFromBytes(aaguid [16]byte) (Entry, bool) {
+	return Default().GetEntryFromBytes(aaguid)
+}
+
+// GetEntryFromBytes retrieves the Entry of a raw AAGUID as taken from authenticatorData; see
+// GetEntryID.
+func (p *Provider) GetEntryFromBytes(aaguid [16]byte) (Entry, bool) {
+	return p.GetEntryID(AAGUID(aaguid))
+}
+
+// GetEntryID retrieves the Entry of a parsed AAGUID from the embedded dataset. See
+// Provider.GetEntryID.
+func GetEntryID(id AAGUID) (Entry, bool) {
+	return Default().GetEntryID(id)
+}
+
+// GetEntryID retrieves the Entry of id, matching dataset keys stored in any case. The
+// NormalizerChain is not consulted, since there is nothing to normalize. The zero AAGUID reports
+// false, as with GetEntry.
+func (p *Provider) GetEntryID(id AAGUID) (Entry, bool) {
+	if id.IsZero() {
+		return Entry{}, false
+	}
+	s := p.current()
+	if p.uniformLookup.Load() {
+		return s.lookupCanonicalUniform(id.String())
+	}
+	return s.lookupCanonical(id.String())
+}
@@ -0,0 +1,61 @@
+package aaguids
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzParseAAGUID(f *testing.F) {
+	// Only the canonical layout parses; the other spellings must fail without panicking.
+	for _, s := range []string{
+		"cb69481e-8ff7-4039-93ec-0a2729a154a8",
+		"CB69481E-8FF7-4039-93EC-0A2729A154A8",
+		"{cb69481e-8ff7-4039-93ec-0a2729a154a8}",
+		"urn:uuid:cb69481e-8ff7-4039-93ec-0a2729a154a8",
+		"cb69481e8ff7403993ec0a2729a154a8",
+		"y2lIHo_3QDmT7AonKaFUqA",
+		"00000000-0000-0000-0000-000000000000",
+		"",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		id, err := ParseAAGUID(s)
+		if err != nil {
+			return
+		}
+		if got := id.String(); got != strings.ToLower(s) {
+			t.Errorf("ParseAAGUID(%q).String() = %q, want the lowercase input", s, got)
+		}
+		again, err := ParseAAGUID(id.String())
+		if err != nil || again != id {
+			t.Errorf("ParseAAGUID(%q) = %v, %v; want %v", id.String(), again, err, id)
+		}
+	})
+}
+
+func TestFormatAAGUIDRoundTrip(t *testing.T) {
+	// The embedded dataset, which is empty until the generator stamps it, and a BLOB fixture.
+	for name, p := range map[string]*Provider{
+		"embedded":   Default(),
+		"blob-mixed": NewProvider(readTestBLOB(t, "blob-mixed.json").EntriesByKey(), Dataset{}),
+	} {
+		for _, k := range p.AAGUIDs() {
+			if isSyntheticKey(k) {
+				continue
+			}
+			id, err := ParseAAGUID(k)
+			if err != nil {
+				t.Errorf("%s: ParseAAGUID(%q): %v", name, k, err)
+				continue
+			}
+			if got := FormatAAGUID(id); got != k {
+				t.Errorf("%s: FormatAAGUID(ParseAAGUID(%q)) = %q", name, k, got)
+			}
+			want, _ := p.GetEntry(k)
+			if e, ok := p.GetEntryFromBytes(id); !ok || e.Key() != want.Key() {
+				t.Errorf("%s: GetEntryFromBytes(%x) = %q, %v; want %q", name, [16]byte(id), e.Key(), ok, k)
+			}
+		}
+	}
+}
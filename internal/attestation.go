@@ -0,0 +1,61 @@
+package aaguids
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// oidFIDOGenCEAAGUID is id-fido-gen-ce-aaguid, the X.509 extension carrying the AAGUID in packed
+// attestation certificates (WebAuthn § 8.2.1).
+var oidFIDOGenCEAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// ErrAAGUIDMismatch is returned by VerifyCertAAGUIDBinding when the certificate names another AAGUID.
+var ErrAAGUIDMismatch = errors.New("aaguids: attestation certificate AAGUID does not match")
+
+/*
+AAGUIDFromAttestationCert returns the canonical AAGUID from the certificate's id-fido-gen-ce-aaguid
+extension. found is false when the extension is absent; an extension whose value is not an OCTET
+STRING of exactly 16 bytes is an error rather than a silent mismatch.
+*/
+func AAGUIDFromAttestationCert(cert *x509.Certificate) (aaguid string, found bool, err error) {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidFIDOGenCEAAGUID) {
+			continue
+		}
+		var raw []byte
+		rest, err := asn1.Unmarshal(ext.Value, &raw)
+		if err != nil {
+			return "", true, fmt.Errorf("decoding id-fido-gen-ce-aaguid: %w", err)
+		}
+		if len(rest) != 0 {
+			return "", true, errors.New("decoding id-fido-gen-ce-aaguid: trailing data after OCTET STRING")
+		}
+		if len(raw) != len(AAGUID{}) {
+			return "", true, fmt.Errorf("decoding id-fido-gen-ce-aaguid: want 16 bytes, got %d", len(raw))
+		}
+		return AAGUID(raw).String(), true, nil
+	}
+	return "", false, nil
+}
+
+/*
+VerifyCertAAGUIDBinding checks that the AAGUID in the attestation certificate, if present, matches
+claimedAAGUID taken from the authenticator data. Per WebAuthn § 8.2.1 the extension is optional, so
+a certificate without it passes; a differing AAGUID yields an error wrapping ErrAAGUIDMismatch.
+*/
+func VerifyCertAAGUIDBinding(cert *x509.Certificate, claimedAAGUID string) error {
+	claimed, err := ParseAAGUID(claimedAAGUID)
+	if err != nil {
+		return boundedErrorf("claimed AAGUID %q: %w", claimedAAGUID, err)
+	}
+	inCert, found, err := AAGUIDFromAttestationCert(cert)
+	if err != nil || !found {
+		return err
+	}
+	if inCert != claimed.String() {
+		return fmt.Errorf("%w: certificate has %s, authenticator data has %s", ErrAAGUIDMismatch, inCert, claimed)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package aaguids
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidAttestationObject reports input that is not a CBOR attestation object with an
+	// authData byte string; the wrapping error says what was wrong with it.
+	ErrInvalidAttestationObject = errors.New("aaguids: invalid attestation object")
+	// ErrAuthDataTruncated reports authenticator data shorter than its flags and lengths require.
+	ErrAuthDataTruncated = errors.New("aaguids: authenticator data is truncated")
+	// ErrNoAttestedCredentialData reports authenticator data without the AT flag, e.g. from an
+	// assertion rather than a registration.
+	ErrNoAttestedCredentialData = errors.New("aaguids: authenticator data has no attested credential data")
+)
+
+// Offsets into authenticator data (WebAuthn § 6.1 “Authenticator Data”).
+const (
+	authDataFlags          = 32                    // after the 32-byte rpIdHash
+	authDataAAGUID         = authDataFlags + 1 + 4 // after the flags byte and 4-byte signCount
+	authDataCredIDLen      = authDataAAGUID + 16   // after the AAGUID
+	authDataCredID         = authDataCredIDLen + 2 // after the 2-byte credentialIdLength
+	authDataFlagAT    byte = 0x40                  // attested credential data included
+)
+
+// cborMaxDepth bounds the nesting of the CBOR items skipped in an attestation object.
+const cborMaxDepth = 16
+
+/*
+ParseAAGUIDFromAttestationObject returns the canonical AAGUID of the attested credential in a
+WebAuthn attestation object, the CBOR map of "fmt", "attStmt" and "authData" in
+response.attestationObject of a registration, so the AAGUID can be looked up without a WebAuthn
+library. Only the CBOR structure is decoded; the attestation is not verified. Errors wrap
+ErrInvalidAttestationObject for input that is not such a map, and otherwise those of
+ParseAAGUIDFromAuthenticatorData.
+*/
+func ParseAAGUIDFromAttestationObject(cbor []byte) (string, error) {
+	authData, err := attestationAuthData(cbor)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidAttestationObject, err)
+	}
+	return ParseAAGUIDFromAuthenticatorData(authData)
+}
+
+/*
+ParseAAGUIDFromAuthenticatorData returns the canonical AAGUID of the attested credential data in
+authData. It fails with an error wrapping ErrNoAttestedCredentialData if the AT flag is not set,
+and one wrapping ErrAuthDataTruncated if authData ends before the AAGUID or the credential ID. The
+all-zero AAGUID of U2F and "none" attestation is returned as is; see IsZeroAAGUID.
+*/
+func ParseAAGUIDFromAuthenticatorData(authData []byte) (string, error) {
+	if len(authData) < authDataAAGUID {
+		return "", fmt.Errorf("%w: %d bytes, need at least %d for rpIdHash, flags and signCount", ErrAuthDataTruncated, len(authData), authDataAAGUID)
+	}
+	if authData[authDataFlags]&authDataFlagAT == 0 {
+		return "", fmt.Errorf("%w: flags 0x%02x", ErrNoAttestedCredentialData, authData[authDataFlags])
+	}
+	if len(authData) < authDataCredID {
+		return "", fmt.Errorf("%w: %d bytes, need at least %d for the AAGUID and credentialIdLength", ErrAuthDataTruncated, len(authData), authDataCredID)
+	}
+	if n := int(binary.BigEndian.Uint16(authData[authDataCredIDLen:])); len(authData) < authDataCredID+n {
+		return "", fmt.Errorf("%w: credential ID of %d bytes, %d left", ErrAuthDataTruncated, n, len(authData)-authDataCredID)
+	}
+	return AAGUID(authData[authDataAAGUID:authDataCredIDLen]).String(), nil
+}
+
+// attestationAuthData returns the "authData" byte string of the CBOR attestation object b.
+func attestationAuthData(b []byte) ([]byte, error) {
+	major, n, off, err := cborHead(b)
+	if err != nil {
+		return nil, err
+	}
+	if major != 5 {
+		return nil, fmt.Errorf("top-level CBOR item is of major type %d, not a map", major)
+	}
+	var authData []byte
+	found := false
+	for i := uint64(0); i < n; i++ {
+		kMajor, kLen, kOff, err := cborHead(b[off:])
+		if err != nil {
+			return nil, fmt.Errorf("map key %d: %w", i, err)
+		}
+		var key string
+		if kMajor == 3 && uint64(len(b[off+kOff:])) >= kLen {
+			key = string(b[off+kOff : off+kOff+int(kLen)])
+		}
+		size, err := cborItemSize(b[off:], 0)
+		if err != nil {
+			return nil, fmt.Errorf("map key %d: %w", i, err)
+		}
+		off += size
+		if key == "authData" {
+			vMajor, vLen, vOff, err := cborHead(b[off:])
+			if err != nil {
+				return nil, fmt.Errorf("authData: %w", err)
+			}
+			if vMajor != 2 {
+				return nil, fmt.Errorf("authData is of major type %d, not a byte string", vMajor)
+			}
+			if uint64(len(b[off+vOff:])) < vLen {
+				return nil, errors.New("authData: CBOR byte string is truncated")
+			}
+			authData, found = b[off+vOff:off+vOff+int(vLen)], true
+		}
+		if size, err = cborItemSize(b[off:], 0); err != nil {
+			return nil, fmt.Errorf("map value %d: %w", i, err)
+		}
+		off += size
+	}
+	if !found {
+		return nil, errors.New("no authData in the attestation object")
+	}
+	return authData, nil
+}
+
+/*
+cborHead decodes the head of the CBOR data item at the start of b (RFC 8949 § 3): its major type,
+its argument (the length of strings, arrays and maps) and the size of the head. Indefinite lengths,
+which CTAP2 canonical encoding never uses, are rejected.
+*/
+func cborHead(b []byte) (major byte, arg uint64, size int, err error) {
+	if len(b) == 0 {
+		return 0, 0, 0, errors.New("CBOR input is truncated")
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), 1, nil
+	case info <= 27:
+		size = 1 << (info - 24)
+		if len(b) < 1+size {
+			return 0, 0, 0, errors.New("CBOR input is truncated")
+		}
+		for _, c := range b[1 : 1+size] {
+			arg = arg<<8 | uint64(c)
+		}
+		return major, arg, 1 + size, nil
+	case info == 31:
+		return 0, 0, 0, errors.New("indefinite-length CBOR items are not supported")
+	default:
+		return 0, 0, 0, fmt.Errorf("invalid CBOR additional information %d", info)
+	}
+}
+
+// cborItemSize returns the encoded size of the CBOR data item at the start of b, nested items
+// included.
+func cborItemSize(b []byte, depth int) (int, error) {
+	if depth > cborMaxDepth {
+		return 0, errors.New("CBOR items are nested too deeply")
+	}
+	major, arg, size, err := cborHead(b)
+	if err != nil {
+		return 0, err
+	}
+	var items uint64
+	switch major {
+	case 0, 1, 7: // integers, simple values and floats carry no content
+		return size, nil
+	case 2, 3: // byte and text strings
+		if uint64(len(b)-size) < arg {
+			return 0, errors.New("CBOR string is truncated")
+		}
+		return size + int(arg), nil
+	case 4:
+		items = arg
+	case 5:
+		items = 2 * arg
+	case 6: // a tag is followed by one item
+		items = 1
+	}
+	for ; items > 0; items-- {
+		if size >= len(b) {
+			return 0, errors.New("CBOR input is truncated")
+		}
+		n, err := cborItemSize(b[size:], depth+1)
+		if err != nil {
+			return 0, err
+		}
+		size += n
+	}
+	return size, nil
+}
@@ -0,0 +1,74 @@
+package aaguids
+
+import "strconv"
+
+/*
+Badge is what a UI needs to show the certification of a model, e.g. "FIDO Certified L1 (2022)"
+linking to the certificate, as returned by Entry.CertificationBadge:
+
+  - Level: the FIDO_CERTIFIED* status of the latest certification report
+  - Label: the human-readable name of Level (see AuthenticatorStatus.Label), or its translation
+    given with WithBadgeLabels
+  - Year: the year of the report's effective date, 0 if it is missing or unparseable
+  - CertificateNumber / URL: from the report, "" if absent
+*/
+type Badge struct {
+	Level             AuthenticatorStatus `json:"level"`
+	Label             string              `json:"label"`
+	Year              int                 `json:"year,omitempty"`
+	CertificateNumber string              `json:"certificateNumber,omitempty"`
+	URL               string              `json:"url,omitempty"`
+}
+
+// String returns the badge text, "Label (Year)", or just the label when the year is unknown.
+func (b Badge) String() string {
+	if b.Year == 0 {
+		return b.Label
+	}
+	return b.Label + " (" + strconv.Itoa(b.Year) + ")"
+}
+
+// BadgeOption adjusts Entry.CertificationBadge.
+type BadgeOption func(*badgeConfig)
+
+// badgeConfig is the configuration of one CertificationBadge call.
+type badgeConfig struct {
+	labels map[AuthenticatorStatus]string
+}
+
+// WithBadgeLabels overrides Badge.Label by level, e.g. with translations; levels it lacks use
+// AuthenticatorStatus.Label.
+func WithBadgeLabels(labels map[AuthenticatorStatus]string) BadgeOption {
+	return func(c *badgeConfig) { c.labels = labels }
+}
+
+/*
+CertificationBadge returns the badge of e's most recent FIDO_CERTIFIED* status report in timeline
+order. Later reports of other kinds, such as UPDATE_AVAILABLE or a security notification, do not
+change which report is used. It reports false if e was never certified, or if its certification
+no longer holds (see CertificationLevel), so a UI shows no badge on revoked hardware; a model
+certified again after being revoked gets the badge of the new certification.
+*/
+func (e Entry) CertificationBadge(opts ...BadgeOption) (Badge, bool) {
+	var cfg badgeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	r, ok := e.latestCertification()
+	if !ok || !e.CertificationLevel().AtLeast(CertificationFIDOCertified) {
+		return Badge{}, false
+	}
+	b := Badge{
+		Level:             r.Status,
+		Label:             r.Status.Label(),
+		CertificateNumber: deref(r.CertificateNumber),
+		URL:               deref(r.URL),
+	}
+	if l, ok := cfg.labels[r.Status]; ok {
+		b.Label = l
+	}
+	if t, ok := r.EffectiveTime(); ok {
+		b.Year = t.Year()
+	}
+	return b, true
+}
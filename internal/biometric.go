@@ -0,0 +1,158 @@
+package aaguids
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+/*
+BiometricCertLevel is the certLevel of a BiometricStatusReport: the level of FIDO Biometric
+Component Certification achieved by one modality. Higher levels impose stricter requirements, so
+levels compare numerically; use IsAtLeast rather than comparing raw integers.
+*/
+type BiometricCertLevel uint8
+
+// Biometric certification levels defined by the FIDO Biometric Requirements.
+const (
+	BiometricCertLevel1 BiometricCertLevel = 1
+	BiometricCertLevel2 BiometricCertLevel = 2
+)
+
+// IsAtLeast reports whether l meets the given minimum level. Level 0, the zero value, never
+// counts as certified, even for a minimum of 0.
+func (l BiometricCertLevel) IsAtLeast(level BiometricCertLevel) bool {
+	return l != 0 && l >= level
+}
+
+/*
+BiometricModality is the modality of a BiometricStatusReport, one of the user verification method
+names from FIDO Registry § 3.1 “User Verification Methods”.
+*/
+type BiometricModality string
+
+// Biometric modalities defined by the FIDO Registry.
+const (
+	ModalityFingerprint  BiometricModality = "fingerprint_internal"
+	ModalityFaceprint    BiometricModality = "faceprint_internal"
+	ModalityEyeprint     BiometricModality = "eyeprint_internal"
+	ModalityVoiceprint   BiometricModality = "voiceprint_internal"
+	ModalityHandprint    BiometricModality = "handprint_internal"
+	ModalityPatternprint BiometricModality = "patternprint_internal"
+)
+
+// Known reports whether m is one of the modalities defined by the FIDO Registry.
+func (m BiometricModality) Known() bool {
+	switch m {
+	case ModalityFingerprint, ModalityFaceprint, ModalityEyeprint, ModalityVoiceprint,
+		ModalityHandprint, ModalityPatternprint:
+		return true
+	}
+	return false
+}
+
+// ErrUnknownModality is returned by ValidateBiometricStatusReports for a modality not defined by
+// the FIDO Registry.
+var ErrUnknownModality = errors.New("aaguids: unknown biometric modality")
+
+/*
+ValidateBiometricStatusReports checks the modalities of e's biometric status reports and returns
+an error wrapping ErrUnknownModality for each unknown one, joined with errors.Join. ParseMetadataBLOB
+accepts unknown modalities so that new registry values do not break parsing; the generator reports
+them as warnings.
+*/
+func ValidateBiometricStatusReports(e Entry) error {
+	var errs []error
+	for i, r := range e.BiometricStatusReports {
+		if !r.Modality.Known() {
+			errs = append(errs, boundedErrorf("biometricStatusReports[%d]: %w %q", i, ErrUnknownModality, r.Modality))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HasBiometricCertification reports whether any biometric status report of e, current or
+// superseded, is at least level. Use CurrentBiometricStatus for the current state of a modality.
+func (e Entry) HasBiometricCertification(level BiometricCertLevel) bool {
+	for _, r := range e.BiometricStatusReports {
+		if r.CertLevel.IsAtLeast(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveTime returns the parsed EffectiveDate of r, or false if it is unset or unparseable.
+func (r BiometricStatusReport) effectiveTime() (time.Time, bool) {
+	if r.EffectiveDate == nil {
+		return time.Time{}, false
+	}
+	return parseISODate(*r.EffectiveDate)
+}
+
+/*
+BiometricTimeline returns the biometric status reports of e for modality in timeline order:
+ascending effective date, with undated reports treated as effective from the beginning of time.
+Reports with the same date are ordered by ascending certLevel, and those that still tie keep their
+position in the BLOB, so conflicting same-date reports always resolve to the highest level, and
+always the same way.
+*/
+func (e Entry) BiometricTimeline(modality BiometricModality) []BiometricStatusReport {
+	var reports []BiometricStatusReport
+	for _, r := range e.BiometricStatusReports {
+		if r.Modality == modality {
+			reports = append(reports, r)
+		}
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		ti, _ := reports[i].effectiveTime()
+		tj, _ := reports[j].effectiveTime()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return reports[i].CertLevel < reports[j].CertLevel
+	})
+	return reports
+}
+
+/*
+BiometricStatusAt returns the biometric status report for modality in effect at t, e.g. the
+fingerprint certification an authenticator had when a credential was created: the last report of
+BiometricTimeline effective on or before t. It reports false if no report for modality was
+effective yet.
+*/
+func (e Entry) BiometricStatusAt(modality BiometricModality, t time.Time) (BiometricStatusReport, bool) {
+	reports := e.BiometricTimeline(modality)
+	for i := len(reports) - 1; i >= 0; i-- {
+		if at, _ := reports[i].effectiveTime(); !at.After(t) {
+			return reports[i], true
+		}
+	}
+	return BiometricStatusReport{}, false
+}
+
+// CurrentBiometricStatus returns the current biometric certification of modality: the last report
+// of BiometricTimeline, regardless of its date. It reports false if e has no report for modality.
+func (e Entry) CurrentBiometricStatus(modality BiometricModality) (BiometricStatusReport, bool) {
+	reports := e.BiometricTimeline(modality)
+	if len(reports) == 0 {
+		return BiometricStatusReport{}, false
+	}
+	return reports[len(reports)-1], true
+}
+
+// hasBiometricCertificationAt is HasBiometricCertification restricted to the current biometric
+// status of each modality at t.
+func (e Entry) hasBiometricCertificationAt(level BiometricCertLevel, t time.Time) bool {
+	seen := make(map[BiometricModality]bool)
+	for _, r := range e.BiometricStatusReports {
+		if seen[r.Modality] {
+			continue
+		}
+		seen[r.Modality] = true
+		if at, ok := e.BiometricStatusAt(r.Modality, t); ok && at.CertLevel.IsAtLeast(level) {
+			return true
+		}
+	}
+	return false
+}
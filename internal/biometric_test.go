@@ -0,0 +1,180 @@
+package aaguids
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// bioReport returns a biometric report for modality, identified by its certificate number.
+func bioReport(modality BiometricModality, level BiometricCertLevel, date, number string) BiometricStatusReport {
+	r := BiometricStatusReport{Modality: modality, CertLevel: level, CertificateNumber: &number}
+	if date != "" {
+		r.EffectiveDate = &date
+	}
+	return r
+}
+
+// numbers lists the certificate numbers of reports, which the tests use as report names.
+func numbers(reports []BiometricStatusReport) []string {
+	out := make([]string, len(reports))
+	for i, r := range reports {
+		out[i] = *r.CertificateNumber
+	}
+	return out
+}
+
+// permutations returns every ordering of s.
+func permutations[T any](s []T) [][]T {
+	if len(s) <= 1 {
+		return [][]T{slices.Clone(s)}
+	}
+	var out [][]T
+	for i := range s {
+		rest := append(slices.Clone(s[:i]), s[i+1:]...)
+		for _, p := range permutations(rest) {
+			out = append(out, append([]T{s[i]}, p...))
+		}
+	}
+	return out
+}
+
+func TestBiometricTimelineTieBreak(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports []BiometricStatusReport
+		want    []string // timeline by certificate number
+	}{
+		{
+			name: "dates ascending",
+			reports: []BiometricStatusReport{
+				bioReport(ModalityFingerprint, 2, "2022-01-01", "b"), bioReport(ModalityFingerprint, 1, "2020-01-01", "a"),
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "same date: higher level last",
+			reports: []BiometricStatusReport{
+				bioReport(ModalityFingerprint, 2, "2021-01-01", "l2"), bioReport(ModalityFingerprint, 1, "2021-01-01", "l1"),
+			},
+			want: []string{"l1", "l2"},
+		},
+		{
+			name: "same date and level: BLOB order",
+			reports: []BiometricStatusReport{
+				bioReport(ModalityFingerprint, 1, "2021-01-01", "first"), bioReport(ModalityFingerprint, 1, "2021-01-01", "second"),
+			},
+			want: []string{"first", "second"},
+		},
+		{
+			name: "undated first, then ties by level",
+			reports: []BiometricStatusReport{
+				bioReport(ModalityFingerprint, 1, "2021-01-01", "dated-l1"), bioReport(ModalityFingerprint, 2, "", "undated-l2"),
+				bioReport(ModalityFingerprint, 1, "", "undated-l1"), bioReport(ModalityFingerprint, 2, "2021-01-01", "dated-l2"),
+			},
+			want: []string{"undated-l1", "undated-l2", "dated-l1", "dated-l2"},
+		},
+		{
+			name: "unparseable date counts as undated",
+			reports: []BiometricStatusReport{
+				bioReport(ModalityFingerprint, 1, "2021-01-01", "dated"), bioReport(ModalityFingerprint, 2, "01/02/2020", "garbled"),
+			},
+			want: []string{"garbled", "dated"},
+		},
+		{
+			name: "other modalities ignored",
+			reports: []BiometricStatusReport{
+				bioReport(ModalityFaceprint, 2, "2019-01-01", "face"), bioReport(ModalityFingerprint, 1, "2020-01-01", "finger"),
+			},
+			want: []string{"finger"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Entry{BiometricStatusReports: tt.reports}
+			if got := numbers(e.BiometricTimeline(ModalityFingerprint)); !slices.Equal(got, tt.want) {
+				t.Errorf("BiometricTimeline = %q, want %q", got, tt.want)
+			}
+			cur, ok := e.CurrentBiometricStatus(ModalityFingerprint)
+			if !ok || *cur.CertificateNumber != tt.want[len(tt.want)-1] {
+				t.Errorf("CurrentBiometricStatus = %v, %v; want the last report of the timeline", cur.CertificateNumber, ok)
+			}
+		})
+	}
+
+	// Reports with distinct (date, level) pairs resolve the same way in any BLOB order.
+	distinct := []BiometricStatusReport{
+		bioReport(ModalityFingerprint, 2, "2021-01-01", "2021-l2"),
+		bioReport(ModalityFingerprint, 1, "2021-01-01", "2021-l1"),
+		bioReport(ModalityFingerprint, 1, "2019-06-01", "2019-l1"),
+		bioReport(ModalityFingerprint, 2, "", "undated-l2"),
+	}
+	want := []string{"undated-l2", "2019-l1", "2021-l1", "2021-l2"}
+	for _, perm := range permutations(distinct) {
+		if got := numbers(Entry{BiometricStatusReports: perm}.BiometricTimeline(ModalityFingerprint)); !slices.Equal(got, want) {
+			t.Errorf("BLOB order %q: timeline %q, want %q", numbers(perm), got, want)
+		}
+	}
+}
+
+func TestBiometricStatusAt(t *testing.T) {
+	e := Entry{BiometricStatusReports: []BiometricStatusReport{
+		bioReport(ModalityFingerprint, 2, "2022-03-01", "recertified"),
+		bioReport(ModalityFingerprint, 1, "2020-01-01", "initial"),
+		bioReport(ModalityFingerprint, 1, "2022-03-01", "same-day-l1"),
+		bioReport(ModalityFaceprint, 1, "", "face"),
+	}}
+	tests := []struct {
+		modality BiometricModality
+		at       string
+		want     string // "" when none is in effect
+	}{
+		{ModalityFingerprint, "2019-12-31", ""},
+		{ModalityFingerprint, "2020-01-01", "initial"},
+		{ModalityFingerprint, "2022-02-28", "initial"},
+		{ModalityFingerprint, "2022-03-01", "recertified"},
+		{ModalityFingerprint, "2030-01-01", "recertified"},
+		{ModalityFaceprint, "1999-01-01", "face"},
+		{ModalityEyeprint, "2030-01-01", ""},
+	}
+	for _, tt := range tests {
+		at := day(t, tt.at)
+		r, ok := e.BiometricStatusAt(tt.modality, at)
+		got := ""
+		if ok {
+			got = *r.CertificateNumber
+		}
+		if got != tt.want {
+			t.Errorf("BiometricStatusAt(%s, %s) = %q, want %q", tt.modality, tt.at, got, tt.want)
+		}
+		// Later in the same day still sees that day's reports.
+		if r2, ok2 := e.BiometricStatusAt(tt.modality, at.Add(23*time.Hour)); ok2 != ok || (ok && *r2.CertificateNumber != got) {
+			t.Errorf("BiometricStatusAt(%s, %s 23:00) differs from midnight", tt.modality, tt.at)
+		}
+	}
+}
+
+func TestPolicyBiometricLevelAsOf(t *testing.T) {
+	e := Entry{
+		AAGUID:        testYubiKey,
+		StatusReports: []StatusReport{report(FIDO_CERTIFIED_L1, "2019-01-01")},
+		BiometricStatusReports: []BiometricStatusReport{
+			bioReport(ModalityFingerprint, 1, "2020-01-01", "initial"),
+			bioReport(ModalityFingerprint, 2, "2022-03-01", "recertified"),
+		},
+	}
+	p := testProvider(t, e)
+	pol := Policy{MinBiometricLevel: BiometricCertLevel2}
+	for _, tt := range []struct {
+		at      string
+		allowed bool
+	}{{"2021-06-01", false}, {"2022-03-01", true}, {"2023-01-01", true}} {
+		d := p.TrustDecision(pol, testYubiKey, AsOf(day(t, tt.at)))
+		if d.Allowed != tt.allowed {
+			t.Errorf("as of %s: allowed %v (%s), want %v", tt.at, d.Allowed, d.Reason, tt.allowed)
+		}
+	}
+	if d := p.TrustDecision(pol, testYubiKey); !d.Allowed {
+		t.Errorf("current decision: %s, want allowed by the level 2 recertification", d.Reason)
+	}
+}
@@ -0,0 +1,249 @@
+package aaguids
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+BLOBPayload represents the top-level JSON payload from the MDS3 JWT. This payload matches the
+“Metadata BLOB Payload” described in the FIDO Metadata Service v3.0 specification (cf. §3.1.6
+“MetadataBLOBPayload” dictionary), although it is simplified to focus on the elements we need:
+
+  - LegalHeader: A statement referencing usage acceptance
+  - No: An incremental, monotonically increasing number identifying the MDS BLOB
+  - NextUpdate: A date by which a new BLOB update should be published
+  - Entries: A slice of Entry structures describing various authenticator models
+  - RawEntries: the upstream JSON of each element of Entries, at the same index; only filled by
+    ParseMetadataBLOB with WithRawEntries
+*/
+type BLOBPayload struct {
+	LegalHeader string            `json:"legalHeader"`
+	No          int               `json:"no"`
+	NextUpdate  string            `json:"nextUpdate"`
+	Entries     []Entry           `json:"entries"`
+	RawEntries  []json.RawMessage `json:"-"`
+
+	// StatusTruncations lists the entries whose status reports ParseMetadataBLOB cut down, in BLOB
+	// order; see WithStatusReportLimit.
+	StatusTruncations []StatusTruncation `json:"-"`
+}
+
+/*
+jwsHeader models the JWT header portion (for JWS) needed to parse the MDS3-signed JWT.
+
+Typical fields:
+  - Alg:  e.g. "RS256", "ES256", etc.
+  - Typ:  typically "JWT"
+  - X5c:  an array of base64-encoded certificates that chain back to a trusted root
+*/
+type jwsHeader struct {
+	Alg string   `json:"alg"`
+	Typ string   `json:"typ"`
+	X5c []string `json:"x5c"`
+}
+
+// mapMetadataSignatureType is used for bridging JOSE alg strings like "RS256" to x509.SignatureAlgorithm values.
+var mapMetadataSignatureType = map[string]x509.SignatureAlgorithm{
+	"RS256": x509.SHA256WithRSA,
+	"PS256": x509.SHA256WithRSAPSS,
+	"ES256": x509.ECDSAWithSHA256,
+	"RS384": x509.SHA384WithRSA,
+	"PS384": x509.SHA384WithRSAPSS,
+	"ES384": x509.ECDSAWithSHA384,
+	"RS512": x509.SHA512WithRSA,
+	"PS512": x509.SHA512WithRSAPSS,
+	"ES512": x509.ECDSAWithSHA512,
+}
+
+/*
+ParseMetadataBLOB verifies the MDS3 JWT and decodes its payload. The x5c chain in the JWT header is
+validated against roots; pass nil to use the system trust store, which covers the production MDS
+signing chain. Test BLOBs signed by a private CA need that CA in roots.
+*/
+func ParseMetadataBLOB(jwt []byte, roots *x509.CertPool, opts ...ParseOption) (BLOBPayload, error) {
+	var cfg parseConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	_, payloadBytes, err := parseAndVerifyJWT(jwt, roots)
+	if err != nil {
+		return BLOBPayload{}, fmt.Errorf("JWT parsing & verification failed: %w", err)
+	}
+	var blob BLOBPayload
+	if err := json.Unmarshal(payloadBytes, &blob); err != nil {
+		return BLOBPayload{}, fmt.Errorf("cannot unmarshal MDS payload: %w", err)
+	}
+	if cfg.rawEntries {
+		var raw struct {
+			Entries []json.RawMessage `json:"entries"`
+		}
+		if err := json.Unmarshal(payloadBytes, &raw); err != nil {
+			return BLOBPayload{}, fmt.Errorf("cannot unmarshal MDS payload entries: %w", err)
+		}
+		blob.RawEntries = raw.Entries
+	}
+	if limit := effectiveStatusReportLimit(cfg.maxStatusReports); limit > 0 {
+		for i, e := range blob.Entries {
+			if cut, ok := limitStatusReports(e, limit); ok {
+				blob.Entries[i] = cut
+				blob.StatusTruncations = append(blob.StatusTruncations, StatusTruncation{
+					Key: e.Key(), Total: len(e.StatusReports), Kept: len(cut.StatusReports),
+				})
+			}
+		}
+	}
+	return blob, nil
+}
+
+/*
+EntriesByKey returns the BLOB's entries keyed as the generated dataset keys them:
+
+  - FIDO2 entries by their AAGUID
+  - UAF entries by UAFKey of their AAID
+  - U2F entries by U2FKey of their first attestation certificate key identifier
+
+Entries with a malformed AAGUID, and entries with none of these identifiers, are skipped.
+*/
+func (b BLOBPayload) EntriesByKey() map[string]Entry {
+	entries := make(map[string]Entry)
+	for _, e := range b.Entries {
+		if k, ok := blobKey(e); ok {
+			entries[k] = e
+		}
+	}
+	return entries
+}
+
+// RawEntriesByKey returns RawEntries keyed as EntriesByKey keys Entries, or nil when the BLOB was
+// parsed without WithRawEntries.
+func (b BLOBPayload) RawEntriesByKey() map[string]json.RawMessage {
+	if b.RawEntries == nil {
+		return nil
+	}
+	raw := make(map[string]json.RawMessage)
+	for i, e := range b.Entries {
+		if k, ok := blobKey(e); ok && i < len(b.RawEntries) {
+			raw[k] = b.RawEntries[i]
+		}
+	}
+	return raw
+}
+
+// blobKey returns the dataset key of a BLOB entry, or false for entries EntriesByKey skips.
+func blobKey(e Entry) (string, bool) {
+	k := e.Key()
+	if k == "" {
+		return "", false
+	}
+	if e.AAGUID != "" {
+		if _, err := ParseAAGUID(e.AAGUID); err != nil {
+			return "", false
+		}
+	}
+	return k, true
+}
+
+/*
+parseAndVerifyJWT splits the given JWT into header, payload, and signature. It then:
+
+  - Decodes the header (which must have x5c certificates)
+  - Verifies the certificate chain against roots (nil means the system trust store)
+  - Uses the "alg" field to map to a x509.SignatureAlgorithm
+  - Verifies the signature across the "header.payload" with the leaf cert
+
+Returns:
+  - headerDecoded: The decoded header bytes
+  - payloadDecoded: The decoded payload bytes
+  - err: Any error from decoding or verification
+*/
+func parseAndVerifyJWT(jwtBytes []byte, roots *x509.CertPool) (headerDecoded, payloadDecoded []byte, err error) {
+	parts := strings.Split(string(jwtBytes), ".")
+	if len(parts) != 3 {
+		return nil, nil, errors.New("invalid JWT: must have 3 dot-separated parts")
+	}
+
+	headerPart, err := base64RawURIDecode(parts[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode header part: %w", err)
+	}
+	payloadPart, err := base64RawURIDecode(parts[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode payload part: %w", err)
+	}
+	signaturePart, err := base64RawURIDecode(parts[2])
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode signature part: %w", err)
+	}
+
+	// Unmarshal the header JSON, expecting a jwsHeader struct
+	var hdr jwsHeader
+	if unmarshalErr := json.Unmarshal(headerPart, &hdr); unmarshalErr != nil {
+		return nil, nil, fmt.Errorf("unmarshal JWT header: %w", unmarshalErr)
+	}
+	if len(hdr.X5c) == 0 {
+		return nil, nil, errors.New("no x5c field present in header")
+	}
+
+	// Convert each base64 PEM entry in X5c to an x509.Certificate
+	certs := make([]*x509.Certificate, 0, len(hdr.X5c))
+	for i, c := range hdr.X5c {
+		der, decErr := base64.StdEncoding.DecodeString(c)
+		if decErr != nil {
+			return nil, nil, fmt.Errorf("decode x5c[%d]: %w", i, decErr)
+		}
+		cert, parseErr := x509.ParseCertificate(der)
+		if parseErr != nil {
+			return nil, nil, fmt.Errorf("parse x5c[%d]: %w", i, parseErr)
+		}
+		certs = append(certs, cert)
+	}
+
+	leafCert := certs[0]
+	intermediates := x509.NewCertPool()
+	for _, ic := range certs[1:] {
+		intermediates.AddCert(ic)
+	}
+
+	// Perform minimal chain validation.
+	if _, verifyErr := leafCert.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         roots,
+	}); verifyErr != nil {
+		return nil, nil, fmt.Errorf("certificate chain verify fail: %w", verifyErr)
+	}
+
+	// Map the JOSE alg string to x509 signature algorithm
+	sigAlg, ok := mapMetadataSignatureType[hdr.Alg]
+	if !ok {
+		return nil, nil, fmt.Errorf("unrecognized signature alg: %s", hdr.Alg)
+	}
+
+	// The actual signing input is "header.base64 + '.' + payload.base64"
+	signingInput := []byte(parts[0] + "." + parts[1])
+	if sigErr := leafCert.CheckSignature(sigAlg, signingInput, signaturePart); sigErr != nil {
+		return nil, nil, fmt.Errorf("JWT signature check failed: %w", sigErr)
+	}
+
+	return headerPart, payloadPart, nil
+}
+
+/*
+base64RawURIDecode performs a Base64-URL decode with or without padding. It transforms
+'-' → '+' and '_' → '/', then applies padding logic if needed, and finally decodes.
+*/
+func base64RawURIDecode(s string) ([]byte, error) {
+	s = strings.ReplaceAll(s, "-", "+")
+	s = strings.ReplaceAll(s, "_", "/")
+	switch len(s) % 4 {
+	case 2:
+		s += "=="
+	case 3:
+		s += "="
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
@@ -0,0 +1,297 @@
+package aaguids
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrSignatureInvalid is returned by VerifyCanonicalJSON when the signature does not match.
+var ErrSignatureInvalid = errors.New("aaguids: canonical JSON signature is invalid")
+
+/*
+CanonicalJSON returns the canonical encoding of e, a byte-stable form suitable for signing. The
+entry is first normalized, then encoded following RFC 8785 (JSON Canonicalization Scheme):
+
+Normalization:
+
+  - Entry.AAGUID and MetadataStatement.AAGUID in the lowercase dashed form of AAGUID.String
+    (values that are not AAGUIDs are kept as they are)
+  - authenticatorGetInfo.aaguid and attestation certificate key identifiers in lowercase hex
+  - status reports in timeline order (see StatusReportsSeq)
+
+Encoding:
+
+  - every field of the Entry JSON encoding is present, absent optional values as null
+  - object members sorted by the UTF-16 code units of their names
+  - no whitespace outside strings
+  - strings escape only '"', '\' and control characters, the latter as \b, \t, \n, \f, \r or
+    \u00xx with lowercase hex; everything else, including non-ASCII, is written as UTF-8
+  - integers in plain decimal notation; other numbers in the shortest ECMAScript form
+*/
+func CanonicalJSON(e Entry) ([]byte, error) {
+	raw, err := json.Marshal(normalizeEntry(e))
+	if err != nil {
+		return nil, entryErrorf(e, "encoding entry: %w", err)
+	}
+	return canonicalize(raw)
+}
+
+/*
+DatasetCanonicalJSON returns the canonical encoding of the current snapshot of p: an object with
+the members "dataset" (the Dataset of DatasetInfo) and "entries" (every entry normalized as by
+CanonicalJSON, keyed by its canonical key), encoded by the rules of CanonicalJSON.
+*/
+func DatasetCanonicalJSON(p *Provider) ([]byte, error) {
+	s := p.current()
+	entries := make(map[string]Entry, len(s.entries))
+	for _, k := range s.keys {
+		ck := canonicalKey(k)
+		if _, dup := entries[ck]; dup {
+			return nil, fmt.Errorf("entries %q and another key canonicalize to %q", k, ck)
+		}
+		entries[ck] = normalizeEntry(s.entries[k])
+	}
+	raw, err := json.Marshal(struct {
+		Dataset Dataset          `json:"dataset"`
+		Entries map[string]Entry `json:"entries"`
+	}{p.DatasetInfo(), entries})
+	if err != nil {
+		return nil, fmt.Errorf("encoding dataset: %w", err)
+	}
+	return canonicalize(raw)
+}
+
+/*
+SignCanonicalJSON returns a detached signature over canonical, the output of CanonicalJSON or
+DatasetCanonicalJSON. Ed25519 signers sign the bytes directly; other signers (ECDSA, RSA
+PKCS #1 v1.5) sign their SHA-256 digest.
+*/
+func SignCanonicalJSON(signer crypto.Signer, canonical []byte) ([]byte, error) {
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		return signer.Sign(rand.Reader, canonical, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(canonical)
+	return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// VerifyCanonicalJSON checks a signature made by SignCanonicalJSON. It returns ErrSignatureInvalid
+// when the signature does not match and an error for unsupported key types.
+func VerifyCanonicalJSON(pub crypto.PublicKey, canonical, sig []byte) error {
+	digest := sha256.Sum256(canonical)
+	var ok bool
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, canonical, sig)
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(k, digest[:], sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	default:
+		return fmt.Errorf("aaguids: unsupported public key type %T", pub)
+	}
+	if !ok {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// normalizeEntry applies the normalization rules of CanonicalJSON to a copy of e.
+func normalizeEntry(e Entry) Entry {
+	e = canonicalIDs(e)
+	if e.StatusReports != nil {
+		e.StatusReports = e.StatusHistory()
+	}
+	return e
+}
+
+/*
+canonicalIDs returns e with its identifiers in the form the package emits them: Entry.AAGUID and
+MetadataStatement.AAGUID as by canonicalKey, authenticatorGetInfo.aaguid and attestation
+certificate key identifiers in lowercase hex. Slices and the getInfo it changes are copied.
+*/
+func canonicalIDs(e Entry) Entry {
+	canonicalAAGUID := func(s string) string {
+		if id, err := ParseAAGUID(s); err == nil {
+			return id.String()
+		}
+		return s
+	}
+	lower := func(ids []string) []string {
+		if ids == nil {
+			return nil
+		}
+		out := make([]string, len(ids))
+		for i, id := range ids {
+			out[i] = strings.ToLower(id)
+		}
+		return out
+	}
+
+	e.AAGUID = canonicalAAGUID(e.AAGUID)
+	e.MetadataStatement.AAGUID = canonicalAAGUID(e.MetadataStatement.AAGUID)
+	e.AttestationCertificateKeyIdentifiers = lower(e.AttestationCertificateKeyIdentifiers)
+	e.MetadataStatement.AttestationCertificateKeyIdentifiers = lower(e.MetadataStatement.AttestationCertificateKeyIdentifiers)
+	if gi := e.MetadataStatement.AuthenticatorGetInfo; gi != nil && gi.AAGUID != strings.ToLower(gi.AAGUID) {
+		c := *gi
+		c.AAGUID = strings.ToLower(c.AAGUID)
+		e.MetadataStatement.AuthenticatorGetInfo = &c
+	}
+	return e
+}
+
+/*
+MarshalJSON encodes e with the member names of the MDS BLOB and its identifiers in canonical form
+(see canonicalIDs), so JSON exports, HTTP responses and stored datasets spell AAGUIDs exactly as
+Entry.Key does, however upstream spelled them. Decoding is unchanged, and the retained upstream
+JSON (see RawEntryJSON) keeps the published spelling.
+*/
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type plain Entry
+	return json.Marshal(plain(canonicalIDs(e)))
+}
+
+// canonicalize re-encodes a JSON document by the encoding rules of CanonicalJSON.
+func canonicalize(raw []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("decoding for canonicalization: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		n, err := canonicalNumber(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(n)
+	case string:
+		writeCanonicalString(buf, v)
+	case []any:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]any:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return lessUTF16(names[i], names[j]) })
+		buf.WriteByte('{')
+		for i, name := range names {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonicalString(buf, name)
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[name]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("canonical JSON: unexpected %T", v)
+	}
+	return nil
+}
+
+// canonicalNumber formats n: integers exactly in decimal, other values like ECMAScript's
+// Number.prototype.toString.
+func canonicalNumber(n json.Number) (string, error) {
+	s := n.String()
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return strconv.FormatInt(i, 10), nil
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return strconv.FormatUint(u, 10), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", fmt.Errorf("canonical JSON: number %q is not representable", s)
+	}
+	if f == 0 {
+		return "0", nil
+	}
+	if abs := math.Abs(f); abs >= 1e21 || abs < 1e-6 {
+		// ECMAScript writes exponents without padding and with an explicit sign: 1e+21, 1e-7.
+		out := strconv.FormatFloat(f, 'e', -1, 64)
+		mant, exp, _ := strings.Cut(out, "e")
+		sign, digits := exp[:1], strings.TrimLeft(exp[1:], "0")
+		return mant + "e" + sign + digits, nil
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), nil
+}
+
+// writeCanonicalString writes s as a JSON string with the minimal escaping of RFC 8785 § 3.2.2.2.
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			buf.WriteString(`\"`)
+		case r == '\\':
+			buf.WriteString(`\\`)
+		case r == '\b':
+			buf.WriteString(`\b`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\f':
+			buf.WriteString(`\f`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r < 0x20:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hex[r>>4])
+			buf.WriteByte(hex[r&0xf])
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// lessUTF16 orders strings by their UTF-16 code units, as RFC 8785 § 3.2.3 requires for names.
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
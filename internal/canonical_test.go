@@ -0,0 +1,335 @@
+package aaguids
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	uuidPattern   = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	u2fKeyPattern = regexp.MustCompile(`(?i)u2f:[0-9a-f]+`)
+)
+
+// lintCanonical reports every AAGUID and U2F key in out that is not in canonical form.
+func lintCanonical(t *testing.T, name string, out []byte) {
+	t.Helper()
+	for _, re := range []*regexp.Regexp{uuidPattern, u2fKeyPattern} {
+		for _, m := range re.FindAll(out, -1) {
+			if s := string(m); s != strings.ToLower(s) {
+				t.Errorf("%s emits %q, not in canonical form", name, s)
+			}
+		}
+	}
+}
+
+// uppercaseProvider returns a Provider holding the entries of blob-mixed.json under uppercase
+// keys, with their own identifiers in uppercase too.
+func uppercaseProvider(t *testing.T) *Provider {
+	t.Helper()
+	entries := make(map[string]Entry)
+	for k, e := range readTestBLOB(t, "blob-mixed.json").EntriesByKey() {
+		e.AAGUID = strings.ToUpper(e.AAGUID)
+		e.MetadataStatement.AAGUID = strings.ToUpper(e.MetadataStatement.AAGUID)
+		e.CommunityExtensions = &CommunityExtensions{Source: "test", DisplayName: e.MetadataStatement.Description}
+		entries[strings.ToUpper(k)] = e
+	}
+	return NewProvider(entries, Dataset{Serial: 1})
+}
+
+func TestEmittedKeysAreCanonical(t *testing.T) {
+	p := uppercaseProvider(t)
+
+	exports := map[string]func(*bytes.Buffer) error{
+		"ExportJSON": func(b *bytes.Buffer) error { return p.ExportJSON(b) },
+		"ExportCapabilityMatrixCSV": func(b *bytes.Buffer) error {
+			return p.ExportCapabilityMatrixCSV(b, nil)
+		},
+		"ExportCapabilityMatrixJSON": func(b *bytes.Buffer) error {
+			return p.ExportCapabilityMatrixJSON(b, nil)
+		},
+		"ExportCommunityFormat": func(b *bytes.Buffer) error { return p.ExportCommunityFormat(b) },
+		"DatasetCanonicalJSON": func(b *bytes.Buffer) error {
+			out, err := DatasetCanonicalJSON(p)
+			b.Write(out)
+			return err
+		},
+		"Summaries": func(b *bytes.Buffer) error { return json.NewEncoder(b).Encode(p.Summaries()) },
+	}
+	for name, export := range exports {
+		var buf bytes.Buffer
+		if err := export(&buf); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		lintCanonical(t, name, buf.Bytes())
+	}
+
+	var keys []string
+	for k, e := range p.Entries() {
+		keys = append(keys, k, e.Key())
+		if b, err := json.Marshal(e); err != nil {
+			t.Fatal(err)
+		} else {
+			lintCanonical(t, "Entry.MarshalJSON", b)
+		}
+	}
+	keys = append(keys, p.AAGUIDs()...)
+	lintCanonical(t, "Entries and AAGUIDs", []byte(strings.Join(keys, " ")))
+	if n := len(p.AAGUIDs()); n != 3 {
+		t.Errorf("AAGUIDs() = %d keys, want 3", n)
+	}
+}
+
+func TestExportJSONRoundTripsThroughSnapshots(t *testing.T) {
+	p := uppercaseProvider(t)
+	var want bytes.Buffer
+	if err := p.ExportJSON(&want); err != nil {
+		t.Fatal(err)
+	}
+
+	var snap bytes.Buffer
+	if err := p.SaveSnapshot(&snap); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSnapshot(&snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	if err := loaded.ExportJSON(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Errorf("ExportJSON after SaveSnapshot and LoadSnapshot differs:\n got %s\nwant %s", got.Bytes(), want.Bytes())
+	}
+
+	// Keys spelled two ways name one authenticator; exporting both would emit a duplicate key.
+	dup := NewProvider(map[string]Entry{
+		"EE882879-721C-4913-9775-3DFCCE97072A": {AAGUID: "EE882879-721C-4913-9775-3DFCCE97072A"},
+		"ee882879-721c-4913-9775-3dfcce97072a": {AAGUID: "ee882879-721c-4913-9775-3dfcce97072a"},
+	}, Dataset{})
+	if err := dup.ExportJSON(&bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "canonicalize to") {
+		t.Errorf("ExportJSON of a dataset with duplicate spellings: error = %v", err)
+	}
+}
+
+func TestCanonicalizeEncodingRules(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		// RFC 8785 § 3.2.4 and Appendix B.
+		{"rfc 8785 sample",
+			`{"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+			  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+			  "literals": [null, true, false]}`,
+			`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`},
+		{"names by UTF-16 code units",
+			`{"\u20ac": 1, "\r": 2, "\ufb33": 3, "1": 4, "\ud83d\ude00": 5, "\u0080": 6, "\u00f6": 7}`,
+			"{\"\\r\":2,\"1\":4,\"\u0080\":6,\"\u00f6\":7,\"\u20ac\":1,\"\U0001f600\":5,\"\ufb33\":3}"},
+		{"nested objects sorted at every level", `{"b": {"d": 1, "c": [{"f": 1, "e": 2}]}, "a": {}}`, `{"a":{},"b":{"c":[{"e":2,"f":1}],"d":1}}`},
+		{"insignificant whitespace", " {\n\t\"a\" :\r\n [ 1 , 2 ] } ", `{"a":[1,2]}`},
+		{"whitespace inside strings kept", `{"a": " x\ty "}`, `{"a":" x\ty "}`},
+		{"empty containers", `[{}, [], ""]`, `[{},[],""]`},
+		{"control characters", `"\u0000\u0008\u0009\u000a\u000c\u000d\u001f\u007f"`, `"\u0000\b\t\n\f\r\u001f` + "\x7f" + `"`},
+		{"escapes only quote and backslash", `"\u003c\u003e\u0026\u2028/"`, "\"<>&\u2028/\""},
+		{"non-ASCII as UTF-8", `"gr\u00fc\u00dfe \u65e5\u672c \ud83d\udd11"`, `"grüße 日本 🔑"`},
+		{"integers", `[0, -0, 1, -1, 255, 9007199254740993, -9223372036854775808, 18446744073709551615]`,
+			`[0,0,1,-1,255,9007199254740993,-9223372036854775808,18446744073709551615]`},
+		{"integral floats", `[1.0, 1e2, 100E-2, -0.0, 1.5e+3]`, `[1,100,1,0,1500]`},
+		{"fractions", `[0.1, 0.5, 1.25, -3.75, 123.456]`, `[0.1,0.5,1.25,-3.75,123.456]`},
+		{"exponent thresholds", `[1e21, 1e20, 0.000001, 0.0000001, 1.5e-7, -2e22]`,
+			`[1e+21,100000000000000000000,0.000001,1e-7,1.5e-7,-2e+22]`},
+		{"integers beyond 64 bits as doubles", `[18446744073709551616, 123456789012345678901234]`,
+			`[18446744073709552000,1.2345678901234569e+23]`},
+		{"literals", `[true, false, null]`, `[true,false,null]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := canonicalize([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("canonicalize(%s)\n got %s\nwant %s", tt.in, got, tt.want)
+			}
+			// Canonical output is a fixed point.
+			again, err := canonicalize(got)
+			if err != nil || !bytes.Equal(again, got) {
+				t.Errorf("canonicalizing the output again: %s, %v", again, err)
+			}
+		})
+	}
+
+	for _, in := range []string{`1e400`, `-1e400`, `{"a": 1`, `[1,]`, `"unterminated`} {
+		if out, err := canonicalize([]byte(in)); err == nil {
+			t.Errorf("canonicalize(%s) = %s, want an error", in, out)
+		}
+	}
+}
+
+func TestCanonicalJSONNormalization(t *testing.T) {
+	str := func(s string) *string { return &s }
+	entry := func(aaguid, keyID, getInfoAAGUID string, reports ...StatusReport) Entry {
+		return Entry{
+			AAGUID: aaguid,
+			MetadataStatement: MetadataStatement{
+				AAGUID:                               aaguid,
+				Description:                          "Key \"5\" <NFC> ü",
+				AttestationCertificateKeyIdentifiers: []string{keyID},
+				AuthenticatorGetInfo:                 &AuthenticatorGetInfo{AAGUID: getInfoAAGUID, Versions: []string{"FIDO_2_0"}},
+			},
+			AttestationCertificateKeyIdentifiers: []string{keyID},
+			StatusReports:                        reports,
+		}
+	}
+	certified := StatusReport{Status: FIDO_CERTIFIED_L1, EffectiveDate: str("2020-01-01")}
+	revoked := StatusReport{Status: REVOKED, EffectiveDate: str("2023-06-01")}
+	undated := StatusReport{Status: NOT_FIDO_CERTIFIED}
+
+	want, err := CanonicalJSON(entry(testYubiKey, "bf7bcaa0d0c6187a8c6abbdd16a15640e7c7bde2",
+		"ee882879721c491397753dfcce97072a", undated, certified, revoked))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spellings := map[string]Entry{
+		"uppercase identifiers": entry("EE882879-721C-4913-9775-3DFCCE97072A", "BF7BCAA0D0C6187A8C6ABBDD16A15640E7C7BDE2",
+			"EE882879721C491397753DFCCE97072A", undated, certified, revoked),
+		"reports out of order": entry(testYubiKey, "bf7bcaa0d0c6187a8c6abbdd16a15640e7c7bde2",
+			"ee882879721c491397753dfcce97072a", revoked, undated, certified),
+	}
+	for name, e := range spellings {
+		before := fmt.Sprint(e)
+		got, err := CanonicalJSON(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s:\n got %s\nwant %s", name, got, want)
+		}
+		if fmt.Sprint(e) != before {
+			t.Errorf("%s: CanonicalJSON modified its argument", name)
+		}
+	}
+
+	// Decoding the canonical form and encoding it again yields the same bytes.
+	var decoded Entry
+	if err := json.Unmarshal(want, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := CanonicalJSON(decoded); err != nil || !bytes.Equal(again, want) {
+		t.Errorf("canonical form does not survive decoding:\n got %s\nwant %s", again, want)
+	}
+
+	for _, rule := range []string{
+		`"aaguid":"ee882879-721c-4913-9775-3dfcce97072a"`,
+		`"attestationCertificateKeyIdentifiers":["bf7bcaa0d0c6187a8c6abbdd16a15640e7c7bde2"]`,
+		`"aaguid":"ee882879721c491397753dfcce97072a"`,
+		`"Description":"Key \"5\" <NFC> ü"`,
+		`"rogueListURL":""`,
+		`"biometricStatusReports":null`,
+	} {
+		if !bytes.Contains(want, []byte(rule)) {
+			t.Errorf("canonical form lacks %s:\n%s", rule, want)
+		}
+	}
+	var order []AuthenticatorStatus
+	for _, r := range decoded.StatusReports {
+		order = append(order, r.Status)
+	}
+	if fmt.Sprint(order) != fmt.Sprint([]AuthenticatorStatus{NOT_FIDO_CERTIFIED, FIDO_CERTIFIED_L1, REVOKED}) {
+		t.Errorf("status reports in canonical form: %v, want timeline order", order)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, want); err != nil || !bytes.Equal(compact.Bytes(), want) {
+		t.Errorf("canonical form has insignificant whitespace: %s", want)
+	}
+
+	// Any identifier that is not an AAGUID is kept as it is.
+	odd, err := CanonicalJSON(Entry{AAGUID: "Not-An-AAGUID"})
+	if err != nil || !bytes.Contains(odd, []byte(`"aaguid":"Not-An-AAGUID"`)) {
+		t.Errorf("CanonicalJSON of a non-AAGUID identifier: %s, %v", odd, err)
+	}
+}
+
+func TestDatasetCanonicalJSONIsStable(t *testing.T) {
+	blob := readTestBLOB(t, "blob-mixed.json")
+	info := Dataset{Serial: blob.No, NextUpdate: "2099-01-01"}
+	want, err := DatasetCanonicalJSON(NewProvider(blob.EntriesByKey(), info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rebuilding the dataset many times varies map iteration order.
+	for range 20 {
+		got, err := DatasetCanonicalJSON(NewProvider(blob.EntriesByKey(), info))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("DatasetCanonicalJSON differs between identical providers: %v", err)
+		}
+	}
+	// The same entries under other key spellings canonicalize identically.
+	upper := make(map[string]Entry)
+	for k, e := range blob.EntriesByKey() {
+		upper[strings.ToUpper(k)] = e
+	}
+	if got, err := DatasetCanonicalJSON(NewProvider(upper, info)); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("uppercase keys:\n got %s\nwant %s", got, want)
+	}
+	if !bytes.HasPrefix(want, []byte(`{"dataset":{`)) || !bytes.Contains(want, []byte(`"`+testU2FKey+`":{`)) {
+		t.Errorf("unexpected layout: %.200s", want)
+	}
+
+	dup := NewProvider(map[string]Entry{
+		"EE882879-721C-4913-9775-3DFCCE97072A": {AAGUID: testYubiKey},
+		testYubiKey:                            {AAGUID: testYubiKey},
+	}, info)
+	if _, err := DatasetCanonicalJSON(dup); err == nil {
+		t.Error("DatasetCanonicalJSON accepted two keys with one canonical form")
+	}
+}
+
+func TestSignCanonicalJSON(t *testing.T) {
+	canonical, err := CanonicalJSON(Entry{AAGUID: testYubiKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Replace(canonical, []byte("ee88"), []byte("ee89"), 1)
+	for name, signer := range map[string]crypto.Signer{"ed25519": edKey, "ecdsa": ecKey, "rsa": rsaKey} {
+		sig, err := SignCanonicalJSON(signer, canonical)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := VerifyCanonicalJSON(signer.Public(), canonical, sig); err != nil {
+			t.Errorf("%s: verifying a valid signature: %v", name, err)
+		}
+		if err := VerifyCanonicalJSON(signer.Public(), tampered, sig); !errors.Is(err, ErrSignatureInvalid) {
+			t.Errorf("%s: verifying a tampered document: %v, want ErrSignatureInvalid", name, err)
+		}
+	}
+	if err := VerifyCanonicalJSON("not a key", canonical, nil); err == nil || errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("unsupported key type: %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package aaguids
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Capability is a tri-state capability cell: CapabilityUnknown when the metadata is silent.
+type Capability int
+
+const (
+	CapabilityUnknown Capability = iota
+	CapabilityNo
+	CapabilityYes
+)
+
+// capabilityOf returns CapabilityYes or CapabilityNo for a known value, else CapabilityUnknown.
+func capabilityOf(yes, known bool) Capability {
+	switch {
+	case !known:
+		return CapabilityUnknown
+	case yes:
+		return CapabilityYes
+	}
+	return CapabilityNo
+}
+
+// String returns "unknown", "no" or "yes".
+func (c Capability) String() string {
+	switch c {
+	case CapabilityNo:
+		return "no"
+	case CapabilityYes:
+		return "yes"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the capability as its String form.
+func (c Capability) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// Attachment is how an authenticator attaches to the client device; "" when unknown.
+type Attachment string
+
+const (
+	AttachmentPlatform      Attachment = "platform"
+	AttachmentCrossPlatform Attachment = "cross-platform"
+	AttachmentBoth          Attachment = "both" // e.g. a phone acting as a hybrid roaming authenticator
+)
+
+/*
+CapabilityMatrix is a flat, per-model digest of what an authenticator can do, with one typed field
+per cell. Each field is derived from the metadata by the rule given here, and is CapabilityUnknown
+(or empty) when the metadata says nothing:
+
+  - Transports: getInfo transports; without them, mapped from attachmentHint ("wired" → "usb",
+    "bluetooth" → "ble", "nfc", "internal")
+  - Attachment: from attachmentHint, "internal" meaning platform and "external", "wired",
+    "wireless", "nfc", "bluetooth" or "network" cross-platform; both kinds make AttachmentBoth.
+    Without hints, the getInfo "plat" option (absent counts as false, as in CTAP), and last,
+    community-only passkey providers are platform (see Entry.IsPlatform)
+  - ResidentKey: the getInfo "rk" option; absent means no
+  - UserVerification: with userVerificationDetails, yes if any alternative (OR) includes a
+    method other than "presence_internal" and "none"; without them, yes if getInfo lists the
+    "uv" or "clientPin" option, present but false included, since the capability exists
+  - UserVerificationAlways: with userVerificationDetails, yes if every alternative verifies the
+    user, so no registration can skip it; without them, yes if getInfo "alwaysUv" is true and
+    unknown otherwise
+  - UserVerificationMethods: every method named in userVerificationDetails, sorted
+  - Algorithms / AttestationTypes: authenticationAlgorithms and attestationTypes as listed
+  - CertificationLevel: the latest FIDO_CERTIFIED* status in timeline order, "" if never certified
+  - BiometricCertLevel: the highest current level over the modalities with biometric status
+    reports (see CurrentBiometricStatus), 0 if none
+  - EnterpriseAttestation: see Entry.SupportsEnterpriseAttestation
+*/
+type CapabilityMatrix struct {
+	AAGUID                  string              `json:"aaguid"`
+	Name                    string              `json:"name"`
+	ProtocolFamily          string              `json:"protocolFamily,omitempty"`
+	Transports              []string            `json:"transports,omitempty"`
+	Attachment              Attachment          `json:"attachment,omitempty"`
+	ResidentKey             Capability          `json:"residentKey"`
+	UserVerification        Capability          `json:"userVerification"`
+	UserVerificationAlways  Capability          `json:"userVerificationAlways"`
+	UserVerificationMethods []string            `json:"userVerificationMethods,omitempty"`
+	Algorithms              []string            `json:"algorithms,omitempty"`
+	AttestationTypes        []string            `json:"attestationTypes,omitempty"`
+	CertificationLevel      AuthenticatorStatus `json:"certificationLevel,omitempty"`
+	BiometricCertLevel      BiometricCertLevel  `json:"biometricCertLevel,omitempty"`
+	EnterpriseAttestation   Capability          `json:"enterpriseAttestation"`
+}
+
+// CapabilityMatrix returns the capability digest of e; see the CapabilityMatrix type for the rules.
+func (e Entry) CapabilityMatrix() CapabilityMatrix {
+	m := e.MetadataStatement
+	c := CapabilityMatrix{
+		AAGUID:                e.Key(),
+		Name:                  e.DisplayName(),
+		ProtocolFamily:        m.ProtocolFamily,
+		Transports:            e.capabilityTransports(),
+		Attachment:            e.capabilityAttachment(),
+		Algorithms:            slices.Clone(m.AuthenticationAlgorithms),
+		AttestationTypes:      slices.Clone(m.AttestationTypes),
+		EnterpriseAttestation: capabilityOf(e.SupportsEnterpriseAttestation()),
+	}
+	g, hasInfo := e.GetInfo()
+	c.ResidentKey = capabilityOf(g.Option("rk") == OptionTrue, hasInfo)
+	c.UserVerification, c.UserVerificationAlways, c.UserVerificationMethods = e.capabilityUserVerification()
+	if r, ok := e.latestCertification(); ok {
+		c.CertificationLevel = r.Status
+	}
+	for _, r := range e.BiometricStatusReports {
+		if cur, ok := e.CurrentBiometricStatus(r.Modality); ok && cur.CertLevel > c.BiometricCertLevel {
+			c.BiometricCertLevel = cur.CertLevel
+		}
+	}
+	return c
+}
+
+// attachmentHintTransports maps attachmentHint values to WebAuthn transport names.
+var attachmentHintTransports = map[string]string{
+	"wired":     "usb",
+	"bluetooth": "ble",
+	"nfc":       "nfc",
+	"internal":  "internal",
+}
+
+// capabilityTransports derives CapabilityMatrix.Transports.
+func (e Entry) capabilityTransports() []string {
+	if g, ok := e.GetInfo(); ok && len(g.Transports) > 0 {
+		return sortedUnique(g.Transports)
+	}
+	var out []string
+	for _, h := range e.MetadataStatement.AttachmentHint {
+		if t, ok := attachmentHintTransports[h]; ok {
+			out = append(out, t)
+		}
+	}
+	return sortedUnique(out)
+}
+
+// capabilityAttachment derives CapabilityMatrix.Attachment.
+func (e Entry) capabilityAttachment() Attachment {
+	var platform, roaming bool
+	for _, h := range e.MetadataStatement.AttachmentHint {
+		switch h {
+		case "internal":
+			platform = true
+		case "external", "wired", "wireless", "nfc", "bluetooth", "network":
+			roaming = true
+		}
+	}
+	switch {
+	case platform && roaming:
+		return AttachmentBoth
+	case platform:
+		return AttachmentPlatform
+	case roaming:
+		return AttachmentCrossPlatform
+	}
+	if g, ok := e.GetInfo(); ok {
+		if g.Option("plat") == OptionTrue {
+			return AttachmentPlatform
+		}
+		return AttachmentCrossPlatform
+	}
+	if e.IsPlatform() {
+		return AttachmentPlatform
+	}
+	return ""
+}
+
+// capabilityUserVerification derives the user verification cells of CapabilityMatrix.
+func (e Entry) capabilityUserVerification() (uv, always Capability, methods []string) {
+	details := e.MetadataStatement.UserVerificationDetails
+	if len(details) > 0 {
+		some, every := false, true
+		for _, and := range details {
+			verifies := false
+			for _, d := range and {
+				methods = append(methods, d.UserVerificationMethod)
+				if d.UserVerificationMethod != "presence_internal" && d.UserVerificationMethod != "none" {
+					verifies = true
+				}
+			}
+			some, every = some || verifies, every && verifies
+		}
+		return capabilityOf(some, true), capabilityOf(every, true), sortedUnique(methods)
+	}
+	g, ok := e.GetInfo()
+	if !ok {
+		return CapabilityUnknown, CapabilityUnknown, nil
+	}
+	uv = capabilityOf(g.Option("uv") != OptionAbsent || g.Option("clientPin") != OptionAbsent, true)
+	if g.Option("alwaysUv") == OptionTrue {
+		always = CapabilityYes
+	}
+	return uv, always, nil
+}
+
+// sortedUnique returns the distinct values of s, sorted, or nil if s is empty.
+func sortedUnique(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	out := slices.Clone(s)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// capabilityColumns are the CSV columns of ExportCapabilityMatrixCSV, in order.
+var capabilityColumns = []string{
+	"aaguid", "name", "protocol_family", "transports", "attachment", "resident_key", "user_verification",
+	"user_verification_always", "user_verification_methods", "algorithms", "attestation_types",
+	"certification_level", "biometric_cert_level", "enterprise_attestation",
+}
+
+// csvRecord returns the CSV cells of c in capabilityColumns order; lists are joined with ";".
+func (c CapabilityMatrix) csvRecord() []string {
+	bio := ""
+	if c.BiometricCertLevel != 0 {
+		bio = strconv.Itoa(int(c.BiometricCertLevel))
+	}
+	return []string{
+		c.AAGUID, c.Name, c.ProtocolFamily, strings.Join(c.Transports, ";"), string(c.Attachment),
+		c.ResidentKey.String(), c.UserVerification.String(), c.UserVerificationAlways.String(),
+		strings.Join(c.UserVerificationMethods, ";"), strings.Join(c.Algorithms, ";"),
+		strings.Join(c.AttestationTypes, ";"), string(c.CertificationLevel), bio,
+		c.EnterpriseAttestation.String(),
+	}
+}
+
+// CapabilityMatrices returns the capability matrix of every embedded entry matching filter. See
+// Provider.CapabilityMatrices.
+func CapabilityMatrices(filter Filter) []CapabilityMatrix {
+	return Default().CapabilityMatrices(filter)
+}
+
+// CapabilityMatrices returns the capability matrix of every entry matching filter (nil for all),
+// in ascending key order.
+func (p *Provider) CapabilityMatrices(filter Filter) []CapabilityMatrix {
+	var out []CapabilityMatrix
+	for e := range p.Where(filter) {
+		out = append(out, e.CapabilityMatrix())
+	}
+	return out
+}
+
+// ExportCapabilityMatrixCSV writes the embedded dataset's capability matrix. See
+// Provider.ExportCapabilityMatrixCSV.
+func ExportCapabilityMatrixCSV(w io.Writer, filter Filter) error {
+	return Default().ExportCapabilityMatrixCSV(w, filter)
+}
+
+/*
+ExportCapabilityMatrixCSV writes the capability matrix of the entries matching filter (nil for all)
+as CSV with a header row, one row per entry in ascending key order. Tri-state cells are "yes", "no"
+or "unknown", lists are joined with ";", and unknown or absent values are empty.
+*/
+func (p *Provider) ExportCapabilityMatrixCSV(w io.Writer, filter Filter) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(capabilityColumns); err != nil {
+		return fmt.Errorf("writing capability matrix: %w", err)
+	}
+	for _, c := range p.CapabilityMatrices(filter) {
+		if err := cw.Write(c.csvRecord()); err != nil {
+			return fmt.Errorf("writing capability matrix: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("writing capability matrix: %w", err)
+	}
+	return nil
+}
+
+// ExportCapabilityMatrixJSON writes the embedded dataset's capability matrix. See
+// Provider.ExportCapabilityMatrixJSON.
+func ExportCapabilityMatrixJSON(w io.Writer, filter Filter) error {
+	return Default().ExportCapabilityMatrixJSON(w, filter)
+}
+
+// ExportCapabilityMatrixJSON writes the capability matrix of the entries matching filter (nil for
+// all) as an indented JSON array in ascending key order.
+func (p *Provider) ExportCapabilityMatrixJSON(w io.Writer, filter Filter) error {
+	rows := p.CapabilityMatrices(filter)
+	if rows == nil {
+		rows = []CapabilityMatrix{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rows); err != nil {
+		return fmt.Errorf("writing capability matrix: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package aaguids
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// capabilityEntry returns a FIDO2 entry with the given attachment hints and getInfo options; a nil
+// options map means no getInfo.
+func capabilityEntry(hints []string, options map[string]bool, transports ...string) Entry {
+	e := Entry{AAGUID: testYubiKey, MetadataStatement: MetadataStatement{
+		AAGUID: testYubiKey, ProtocolFamily: "fido2", AttachmentHint: hints,
+	}}
+	if options != nil {
+		e.MetadataStatement.AuthenticatorGetInfo = &AuthenticatorGetInfo{Options: options, Transports: transports}
+	}
+	return e
+}
+
+// uvDetails builds userVerificationDetails from alternatives of method names: the outer slice is
+// OR, each inner slice AND.
+func uvDetails(alternatives ...[]string) [][]VerificationMethod {
+	out := make([][]VerificationMethod, len(alternatives))
+	for i, and := range alternatives {
+		for _, m := range and {
+			out[i] = append(out[i], VerificationMethod{UserVerificationMethod: m})
+		}
+	}
+	return out
+}
+
+func TestCapabilityTransports(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		e    Entry
+		want []string
+	}{
+		{"getInfo, sorted and unique", capabilityEntry([]string{"wired"}, map[string]bool{}, "usb", "nfc", "usb"), []string{"nfc", "usb"}},
+		{"getInfo without transports falls back to hints", capabilityEntry([]string{"wired", "nfc"}, map[string]bool{}), []string{"nfc", "usb"}},
+		{"hints only", capabilityEntry([]string{"bluetooth", "internal", "external"}, nil), []string{"ble", "internal"}},
+		{"hints without transports", capabilityEntry([]string{"external", "wireless"}, nil), nil},
+		{"nothing", capabilityEntry(nil, nil), nil},
+	} {
+		if got := tt.e.CapabilityMatrix().Transports; !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Transports = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCapabilityAttachment(t *testing.T) {
+	community := Entry{AAGUID: testGPM, CommunityExtensions: &CommunityExtensions{Source: "test", DisplayName: "Google Password Manager"}}
+	for _, tt := range []struct {
+		name string
+		e    Entry
+		want Attachment
+	}{
+		{"internal hint", capabilityEntry([]string{"internal"}, nil), AttachmentPlatform},
+		{"roaming hints", capabilityEntry([]string{"external", "wired", "nfc"}, nil), AttachmentCrossPlatform},
+		{"network hint", capabilityEntry([]string{"network"}, nil), AttachmentCrossPlatform},
+		{"both kinds of hint", capabilityEntry([]string{"internal", "wireless"}, nil), AttachmentBoth},
+		{"hints win over plat", capabilityEntry([]string{"external"}, map[string]bool{"plat": true}), AttachmentCrossPlatform},
+		{"plat true", capabilityEntry(nil, map[string]bool{"plat": true}), AttachmentPlatform},
+		{"plat false", capabilityEntry(nil, map[string]bool{"plat": false}), AttachmentCrossPlatform},
+		{"plat absent counts as false", capabilityEntry(nil, map[string]bool{}), AttachmentCrossPlatform},
+		{"unrecognized hints fall through", capabilityEntry([]string{"ready"}, nil), ""},
+		{"community passkey provider", community, AttachmentPlatform},
+		{"nothing", capabilityEntry(nil, nil), ""},
+	} {
+		if got := tt.e.CapabilityMatrix().Attachment; got != tt.want {
+			t.Errorf("%s: Attachment = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCapabilityResidentKey(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		options map[string]bool
+		want    Capability
+	}{
+		{"rk true", map[string]bool{"rk": true}, CapabilityYes},
+		{"rk false", map[string]bool{"rk": false}, CapabilityNo},
+		{"rk absent", map[string]bool{}, CapabilityNo},
+		{"no getInfo", nil, CapabilityUnknown},
+	} {
+		if got := capabilityEntry(nil, tt.options).CapabilityMatrix().ResidentKey; got != tt.want {
+			t.Errorf("%s: ResidentKey = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCapabilityUserVerification(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		details     [][]VerificationMethod
+		options     map[string]bool
+		uv, always  Capability
+		wantMethods []string
+	}{
+		// userVerificationDetails: OR over the alternatives for uv, AND over them for always.
+		{"single verifying method", uvDetails([]string{"fingerprint_internal"}), nil, CapabilityYes, CapabilityYes,
+			[]string{"fingerprint_internal"}},
+		{"presence only", uvDetails([]string{"presence_internal"}), nil, CapabilityNo, CapabilityNo,
+			[]string{"presence_internal"}},
+		{"none", uvDetails([]string{"none"}), nil, CapabilityNo, CapabilityNo, []string{"none"}},
+		{"verifying or presence-only alternative", uvDetails([]string{"passcode_internal"}, []string{"presence_internal"}), nil,
+			CapabilityYes, CapabilityNo, []string{"passcode_internal", "presence_internal"}},
+		{"every alternative verifies", uvDetails([]string{"fingerprint_internal"}, []string{"passcode_external"}), nil,
+			CapabilityYes, CapabilityYes, []string{"fingerprint_internal", "passcode_external"}},
+		{"presence and a verifying method together", uvDetails([]string{"presence_internal", "fingerprint_internal"}), nil,
+			CapabilityYes, CapabilityYes, []string{"fingerprint_internal", "presence_internal"}},
+		{"methods sorted and unique", uvDetails([]string{"presence_internal", "fingerprint_internal"}, []string{"presence_internal"}), nil,
+			CapabilityYes, CapabilityNo, []string{"fingerprint_internal", "presence_internal"}},
+		{"details win over getInfo", uvDetails([]string{"presence_internal"}), map[string]bool{"uv": true, "alwaysUv": true},
+			CapabilityNo, CapabilityNo, []string{"presence_internal"}},
+
+		// getInfo only: a present uv or clientPin option means the capability exists.
+		{"uv true", nil, map[string]bool{"uv": true}, CapabilityYes, CapabilityUnknown, nil},
+		{"clientPin false", nil, map[string]bool{"clientPin": false}, CapabilityYes, CapabilityUnknown, nil},
+		{"neither option", nil, map[string]bool{"up": true}, CapabilityNo, CapabilityUnknown, nil},
+		{"alwaysUv true", nil, map[string]bool{"uv": true, "alwaysUv": true}, CapabilityYes, CapabilityYes, nil},
+		{"alwaysUv false", nil, map[string]bool{"uv": true, "alwaysUv": false}, CapabilityYes, CapabilityUnknown, nil},
+		{"no metadata", nil, nil, CapabilityUnknown, CapabilityUnknown, nil},
+	} {
+		e := capabilityEntry(nil, tt.options)
+		e.MetadataStatement.UserVerificationDetails = tt.details
+		c := e.CapabilityMatrix()
+		if c.UserVerification != tt.uv || c.UserVerificationAlways != tt.always {
+			t.Errorf("%s: UserVerification = %v, UserVerificationAlways = %v; want %v, %v",
+				tt.name, c.UserVerification, c.UserVerificationAlways, tt.uv, tt.always)
+		}
+		if !slices.Equal(c.UserVerificationMethods, tt.wantMethods) {
+			t.Errorf("%s: UserVerificationMethods = %v, want %v", tt.name, c.UserVerificationMethods, tt.wantMethods)
+		}
+	}
+}
+
+func TestCapabilityListsAreCopied(t *testing.T) {
+	e := capabilityEntry(nil, nil)
+	e.MetadataStatement.AuthenticationAlgorithms = []string{"secp256r1_ecdsa_sha256_raw", "ed25519_eddsa_sha512_raw"}
+	e.MetadataStatement.AttestationTypes = []string{"basic_full", "none"}
+	c := e.CapabilityMatrix()
+	if !slices.Equal(c.Algorithms, e.MetadataStatement.AuthenticationAlgorithms) || !slices.Equal(c.AttestationTypes, e.MetadataStatement.AttestationTypes) {
+		t.Fatalf("Algorithms = %v, AttestationTypes = %v", c.Algorithms, c.AttestationTypes)
+	}
+	c.Algorithms[0], c.AttestationTypes[0] = "changed", "changed"
+	if e.MetadataStatement.AuthenticationAlgorithms[0] == "changed" || e.MetadataStatement.AttestationTypes[0] == "changed" {
+		t.Error("the matrix shares its lists with the entry")
+	}
+}
+
+func TestCapabilityCertificationLevel(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		reports []StatusReport
+		want    AuthenticatorStatus
+	}{
+		{"never certified", []StatusReport{report(NOT_FIDO_CERTIFIED, "2020-01-01")}, ""},
+		{"latest level in timeline order", []StatusReport{
+			report(FIDO_CERTIFIED_L2, "2022-01-01"), report(FIDO_CERTIFIED_L1, "2020-01-01"),
+		}, FIDO_CERTIFIED_L2},
+		{"later non-certification status does not clear it", []StatusReport{
+			report(FIDO_CERTIFIED_L1, "2020-01-01"), report(UPDATE_AVAILABLE, "2021-01-01"),
+		}, FIDO_CERTIFIED_L1},
+		{"a later lower level replaces a higher one", []StatusReport{
+			report(FIDO_CERTIFIED_L2, "2020-01-01"), report(FIDO_CERTIFIED_L1, "2023-01-01"),
+		}, FIDO_CERTIFIED_L1},
+		{"no reports", nil, ""},
+	} {
+		e := capabilityEntry(nil, nil)
+		e.StatusReports = tt.reports
+		if got := e.CapabilityMatrix().CertificationLevel; got != tt.want {
+			t.Errorf("%s: CertificationLevel = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCapabilityBiometricCertLevel(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		reports []BiometricStatusReport
+		want    BiometricCertLevel
+	}{
+		{"no reports", nil, 0},
+		{"highest over modalities", []BiometricStatusReport{
+			bioReport(ModalityFingerprint, BiometricCertLevel1, "2021-01-01", "fp"),
+			bioReport(ModalityFaceprint, BiometricCertLevel2, "2021-01-01", "face"),
+		}, BiometricCertLevel2},
+		{"current report only, not a superseded higher one", []BiometricStatusReport{
+			bioReport(ModalityFingerprint, BiometricCertLevel2, "2020-01-01", "old"),
+			bioReport(ModalityFingerprint, BiometricCertLevel1, "2022-01-01", "new"),
+		}, BiometricCertLevel1},
+	} {
+		e := capabilityEntry(nil, nil)
+		e.BiometricStatusReports = tt.reports
+		if got := e.CapabilityMatrix().BiometricCertLevel; got != tt.want {
+			t.Errorf("%s: BiometricCertLevel = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCapabilityEnterpriseAttestation(t *testing.T) {
+	for ep, want := range map[string]Capability{
+		"absent": CapabilityNo, "false": CapabilityYes, "true": CapabilityYes, "unknown": CapabilityUnknown,
+	} {
+		if got := epEntries()[ep].CapabilityMatrix().EnterpriseAttestation; got != want {
+			t.Errorf("ep %s: EnterpriseAttestation = %v, want %v", ep, got, want)
+		}
+	}
+}
+
+func TestExportCapabilityMatrix(t *testing.T) {
+	fido2 := capabilityEntry([]string{"external"}, map[string]bool{"rk": true}, "usb", "nfc")
+	fido2.MetadataStatement.AuthenticationAlgorithms = []string{"secp256r1_ecdsa_sha256_raw", "ed25519_eddsa_sha512_raw"}
+	fido2.BiometricStatusReports = []BiometricStatusReport{bioReport(ModalityFingerprint, BiometricCertLevel1, "2021-01-01", "fp")}
+	uaf := Entry{AAID: "4e4e#4005", MetadataStatement: MetadataStatement{ProtocolFamily: "uaf", Description: "UAF, with a comma"}}
+	p := testProvider(t, fido2, uaf)
+
+	var buf bytes.Buffer
+	if err := p.ExportCapabilityMatrixCSV(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || !slices.Equal(rows[0], capabilityColumns) {
+		t.Fatalf("CSV rows %q", rows)
+	}
+	cell := func(row []string, column string) string { return row[slices.Index(capabilityColumns, column)] }
+	for column, want := range map[string]string{
+		"aaguid": testYubiKey, "transports": "nfc;usb", "attachment": "cross-platform", "resident_key": "yes",
+		"user_verification": "no", "user_verification_always": "unknown",
+		"algorithms": "secp256r1_ecdsa_sha256_raw;ed25519_eddsa_sha512_raw", "biometric_cert_level": "1",
+		"enterprise_attestation": "no", "certification_level": "",
+	} {
+		if got := cell(rows[1], column); got != want {
+			t.Errorf("FIDO2 row, %s = %q, want %q", column, got, want)
+		}
+	}
+	if cell(rows[2], "aaguid") != "uaf:4E4E#4005" || cell(rows[2], "name") != "UAF, with a comma" || cell(rows[2], "resident_key") != "unknown" {
+		t.Errorf("UAF row %q", rows[2])
+	}
+
+	buf.Reset()
+	if err := p.ExportCapabilityMatrixJSON(&buf, ProtocolFamilyIs("uaf")); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0]["aaguid"] != "uaf:4E4E#4005" || got[0]["residentKey"] != "unknown" {
+		t.Errorf("filtered JSON %s", buf.Bytes())
+	}
+
+	buf.Reset()
+	if err := p.ExportCapabilityMatrixJSON(&buf, func(Entry) bool { return false }); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("empty JSON export %q, want []", buf.String())
+	}
+}
@@ -0,0 +1,104 @@
+package aaguids
+
+import "strings"
+
+/*
+CertificationRecord is one certification-type status report (FIDO_CERTIFIED and its leveled
+successors) together with the key of the entry carrying it. Optional report fields are "" when
+absent.
+*/
+type CertificationRecord struct {
+	AAGUID              string              `json:"aaguid"`
+	Level               AuthenticatorStatus `json:"level"`
+	Descriptor          string              `json:"descriptor,omitempty"`
+	Number              string              `json:"number,omitempty"`
+	PolicyVersion       string              `json:"policyVersion,omitempty"`
+	RequirementsVersion string              `json:"requirementsVersion,omitempty"`
+	EffectiveDate       string              `json:"effectiveDate,omitempty"`
+}
+
+// CertificationPredicate selects CertificationRecords, see FilterCertifications.
+type CertificationPredicate func(CertificationRecord) bool
+
+// CertificationRecords flattens the certification reports of the embedded dataset. See
+// Provider.CertificationRecords.
+func CertificationRecords() []CertificationRecord {
+	return Default().CertificationRecords()
+}
+
+// CertificationRecords flattens every certification-type status report in the current snapshot,
+// in ascending key order and, within an entry, in timeline order.
+func (p *Provider) CertificationRecords() []CertificationRecord {
+	s := p.current()
+	var out []CertificationRecord
+	for _, k := range s.keys {
+		for r := range s.entries[k].StatusReportsSeq() {
+			if !isCertificationLevel(r.Status) {
+				continue
+			}
+			out = append(out, CertificationRecord{
+				AAGUID:              canonicalKey(k),
+				Level:               r.Status,
+				Descriptor:          deref(r.CertificationDescriptor),
+				Number:              deref(r.CertificateNumber),
+				PolicyVersion:       deref(r.CertificationPolicyVersion),
+				RequirementsVersion: deref(r.CertificationRequirementsVersion),
+				EffectiveDate:       deref(r.EffectiveDate),
+			})
+		}
+	}
+	return out
+}
+
+// FilterCertifications returns the records matching every predicate, keeping their order.
+func FilterCertifications(records []CertificationRecord, preds ...CertificationPredicate) []CertificationRecord {
+	var out []CertificationRecord
+next:
+	for _, r := range records {
+		for _, pred := range preds {
+			if !pred(r) {
+				continue next
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+/*
+PolicyVersionAtLeast matches records certified under policy version min or later. Versions are
+compared as by CertificationVersion.Compare ("1.10" > "1.3", "1.3" == "1.3.0"; an optional leading
+"v" and a suffix are ignored). When either version does not parse, only an exact string match counts.
+*/
+func PolicyVersionAtLeast(min string) CertificationPredicate {
+	return func(r CertificationRecord) bool { return versionAtLeast(r.PolicyVersion, min) }
+}
+
+// RequirementsVersionAtLeast is PolicyVersionAtLeast for the certification requirements version.
+func RequirementsVersionAtLeast(min string) CertificationPredicate {
+	return func(r CertificationRecord) bool { return versionAtLeast(r.RequirementsVersion, min) }
+}
+
+// DescriptorContains matches records whose certification descriptor contains sub, ignoring case.
+func DescriptorContains(sub string) CertificationPredicate {
+	sub = strings.ToLower(sub)
+	return func(r CertificationRecord) bool { return strings.Contains(strings.ToLower(r.Descriptor), sub) }
+}
+
+// versionAtLeast reports whether v >= min, see PolicyVersionAtLeast.
+func versionAtLeast(v, min string) bool {
+	a, errA := ParseCertificationVersion(v)
+	b, errB := ParseCertificationVersion(min)
+	if errA != nil || errB != nil {
+		return v == min
+	}
+	return a.Compare(b) >= 0
+}
+
+// deref returns *s, or "" for nil.
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,137 @@
+package aaguids
+
+import (
+	"fmt"
+	"slices"
+)
+
+/*
+CertificationLevel is the certification an entry holds, ordered so that levels compare with < and
+AtLeast:
+
+	CertificationNone < NotFIDOCertified < SelfAssertionSubmitted < FIDOCertified < L1 < L1plus
+		< L2 < L2plus < L3 < L3plus
+
+FIDOCertified ranks below L1, since FIDO_CERTIFIED_L1 supersedes it (FIDO Metadata Service
+§ 3.1.4 “AuthenticatorStatus enum”). CertificationNone, the zero value, means no certification is
+known, including entries whose certification was revoked.
+*/
+type CertificationLevel int
+
+const (
+	CertificationNone CertificationLevel = iota
+	CertificationNotFIDOCertified
+	CertificationSelfAssertionSubmitted
+	CertificationFIDOCertified
+	CertificationL1
+	CertificationL1plus
+	CertificationL2
+	CertificationL2plus
+	CertificationL3
+	CertificationL3plus
+)
+
+// certificationStatuses maps the levels above CertificationNone to their status, in level order.
+var certificationStatuses = []AuthenticatorStatus{
+	CertificationNotFIDOCertified:       NOT_FIDO_CERTIFIED,
+	CertificationSelfAssertionSubmitted: SELF_ASSERTION_SUBMITTED,
+	CertificationFIDOCertified:          FIDO_CERTIFIED,
+	CertificationL1:                     FIDO_CERTIFIED_L1,
+	CertificationL1plus:                 FIDO_CERTIFIED_L1plus,
+	CertificationL2:                     FIDO_CERTIFIED_L2,
+	CertificationL2plus:                 FIDO_CERTIFIED_L2plus,
+	CertificationL3:                     FIDO_CERTIFIED_L3,
+	CertificationL3plus:                 FIDO_CERTIFIED_L3plus,
+}
+
+// CertificationLevelOf returns the level a status reports, or false for statuses that say nothing
+// about certification (e.g. UPDATE_AVAILABLE).
+func CertificationLevelOf(s AuthenticatorStatus) (CertificationLevel, bool) {
+	if s == "" {
+		return CertificationNone, false
+	}
+	i := slices.Index(certificationStatuses, s)
+	if i < 0 {
+		return CertificationNone, false
+	}
+	return CertificationLevel(i), true
+}
+
+// Status returns the AuthenticatorStatus of the level, "" for CertificationNone.
+func (l CertificationLevel) Status() AuthenticatorStatus {
+	if l <= CertificationNone || int(l) >= len(certificationStatuses) {
+		return ""
+	}
+	return certificationStatuses[l]
+}
+
+// AtLeast reports whether l is min or a higher level.
+func (l CertificationLevel) AtLeast(min CertificationLevel) bool {
+	return l >= min
+}
+
+// String returns the status name of the level, e.g. "FIDO_CERTIFIED_L1", or "none".
+func (l CertificationLevel) String() string {
+	if s := l.Status(); s != "" {
+		return string(s)
+	}
+	if l == CertificationNone {
+		return "none"
+	}
+	return fmt.Sprintf("CertificationLevel(%d)", int(l))
+}
+
+// MarshalText encodes the level as its String form.
+func (l CertificationLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText decodes the String form of a level.
+func (l *CertificationLevel) UnmarshalText(b []byte) error {
+	if string(b) == "none" {
+		*l = CertificationNone
+		return nil
+	}
+	v, ok := CertificationLevelOf(AuthenticatorStatus(b))
+	if !ok {
+		return fmt.Errorf("unknown certification level %q", b)
+	}
+	*l = v
+	return nil
+}
+
+/*
+CertificationLevel returns the certification e holds: its status reports are walked in timeline
+order, each certification status (see CertificationLevelOf) sets the level, and REVOKED resets it
+to CertificationNone, so a revoked entry reports no certification regardless of earlier
+FIDO_CERTIFIED reports, until a later report certifies it again. Other statuses, such as
+UPDATE_AVAILABLE, leave the level unchanged.
+*/
+func (e Entry) CertificationLevel() CertificationLevel {
+	return certificationLevel(e.StatusHistory())
+}
+
+// certificationLevel is CertificationLevel for reports in timeline order.
+func certificationLevel(reports []StatusReport) CertificationLevel {
+	level := CertificationNone
+	for _, r := range reports {
+		if r.Status == REVOKED {
+			level = CertificationNone
+		} else if l, ok := CertificationLevelOf(r.Status); ok {
+			level = l
+		}
+	}
+	return level
+}
+
+// EntriesWithMinimumCertification returns the embedded entries certified at level or above. See
+// Provider.EntriesWithMinimumCertification.
+func EntriesWithMinimumCertification(level CertificationLevel) []Entry {
+	return Default().EntriesWithMinimumCertification(level)
+}
+
+// EntriesWithMinimumCertification returns the entries whose CertificationLevel is level or above,
+// in ascending key order.
+func (p *Provider) EntriesWithMinimumCertification(level CertificationLevel) []Entry {
+	return slices.Collect(p.Where(func(e Entry) bool { return e.CertificationLevel().AtLeast(level) }))
+}
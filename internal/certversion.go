@@ -0,0 +1,220 @@
+package aaguids
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrVersionAbsent is returned by the version accessors of StatusReport when the field is unset.
+	ErrVersionAbsent = errors.New("aaguids: certification version is absent")
+	// ErrVersionMalformed is returned when a certification version does not have the
+	// major.minor[.patch...][suffix] form.
+	ErrVersionMalformed = errors.New("aaguids: certification version is malformed")
+)
+
+/*
+CertificationVersion is a parsed certificationPolicyVersion or certificationRequirementsVersion.
+Upstream values follow a major.minor pattern, sometimes with more components or a suffix:
+
+  - Raw: the value as published
+  - Parts: the numeric components, e.g. [1 3 7] for "1.3.7"; an optional leading "v" is dropped
+  - Suffix: whatever follows the last component, e.g. "-rc1" for "1.2-rc1"; "" if none
+*/
+type CertificationVersion struct {
+	Raw    string   `json:"raw"`
+	Parts  []uint64 `json:"parts"`
+	Suffix string   `json:"suffix,omitempty"`
+}
+
+/*
+ParseCertificationVersion parses a certification version. The numeric components must be
+dot-separated decimals, and a suffix must start with a letter, '-', '+', '_' or a space, so
+"1.2-rc1" and "1.3 (2021)" parse but "1.x" and "1..2" are malformed. Errors wrap
+ErrVersionMalformed.
+*/
+func ParseCertificationVersion(s string) (CertificationVersion, error) {
+	v := CertificationVersion{Raw: s}
+	rest := strings.TrimSpace(s)
+	if len(rest) > 1 && (rest[0] == 'v' || rest[0] == 'V') && rest[1] >= '0' && rest[1] <= '9' {
+		rest = rest[1:]
+	}
+	for {
+		end := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if end < 0 {
+			end = len(rest)
+		}
+		n, err := strconv.ParseUint(rest[:end], 10, 64)
+		if err != nil {
+			return CertificationVersion{}, boundedErrorf("%w: %q", ErrVersionMalformed, s)
+		}
+		v.Parts = append(v.Parts, n)
+		rest = rest[end:]
+		if !strings.HasPrefix(rest, ".") {
+			break
+		}
+		rest = rest[1:]
+	}
+	if rest != "" {
+		if r := []rune(rest)[0]; !unicode.IsLetter(r) && !strings.ContainsRune("-+_ ", r) {
+			return CertificationVersion{}, boundedErrorf("%w: %q", ErrVersionMalformed, s)
+		}
+		v.Suffix = rest
+	}
+	return v, nil
+}
+
+// Compare returns -1, 0 or +1 as v is older than, equal to or newer than o. Components are
+// compared numerically with missing ones as 0 ("1.3" == "1.3.0"); suffixes are ignored.
+func (v CertificationVersion) Compare(o CertificationVersion) int {
+	for i := 0; i < max(len(v.Parts), len(o.Parts)); i++ {
+		var x, y uint64
+		if i < len(v.Parts) {
+			x = v.Parts[i]
+		}
+		if i < len(o.Parts) {
+			y = o.Parts[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// String returns the version as published.
+func (v CertificationVersion) String() string {
+	return v.Raw
+}
+
+// PolicyVersion parses the report's certificationPolicyVersion. The error wraps ErrVersionAbsent
+// or ErrVersionMalformed.
+func (r StatusReport) PolicyVersion() (CertificationVersion, error) {
+	return parseVersionField(r.CertificationPolicyVersion)
+}
+
+// RequirementsVersion parses the report's certificationRequirementsVersion. The error wraps
+// ErrVersionAbsent or ErrVersionMalformed.
+func (r StatusReport) RequirementsVersion() (CertificationVersion, error) {
+	return parseVersionField(r.CertificationRequirementsVersion)
+}
+
+// parseVersionField implements the StatusReport version accessors.
+func parseVersionField(s *string) (CertificationVersion, error) {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return CertificationVersion{}, ErrVersionAbsent
+	}
+	return ParseCertificationVersion(*s)
+}
+
+/*
+VersionDiagnostic reports a certification version that could not be parsed and was left out of
+version queries:
+
+  - AAGUID: the entry's dataset key (see Entry.Key)
+  - Field: "certificationPolicyVersion" or "certificationRequirementsVersion"
+  - Value: the value as published
+  - Status / EffectiveDate: the certification report carrying it
+*/
+type VersionDiagnostic struct {
+	AAGUID        string              `json:"aaguid"`
+	Field         string              `json:"field"`
+	Value         string              `json:"value"`
+	Status        AuthenticatorStatus `json:"status"`
+	EffectiveDate string              `json:"effectiveDate,omitempty"`
+}
+
+// versionedKey is one entry of the requirements version index.
+type versionedKey struct {
+	key     string
+	version CertificationVersion
+}
+
+// certVersionIndex is built on first use per snapshot by certVersions.
+type certVersionIndex struct {
+	byRequirements []versionedKey // latest certification per entry, ascending version, then key
+	diagnostics    []VersionDiagnostic
+}
+
+// certVersions indexes the latest certification report of every entry by requirements version
+// and collects the unparseable versions of those reports.
+func (s *snapshot) certVersions() *certVersionIndex {
+	s.certVersionsOnce.Do(func() {
+		idx := &certVersionIndex{}
+		for _, k := range s.keys {
+			r, ok := s.entries[k].latestCertification()
+			if !ok {
+				continue
+			}
+			diagnose := func(field string, value *string) (CertificationVersion, bool) {
+				v, err := parseVersionField(value)
+				if errors.Is(err, ErrVersionMalformed) {
+					idx.diagnostics = append(idx.diagnostics, VersionDiagnostic{
+						AAGUID: canonicalKey(k), Field: field, Value: *value, Status: r.Status, EffectiveDate: deref(r.EffectiveDate),
+					})
+				}
+				return v, err == nil
+			}
+			diagnose("certificationPolicyVersion", r.CertificationPolicyVersion)
+			if v, ok := diagnose("certificationRequirementsVersion", r.CertificationRequirementsVersion); ok {
+				idx.byRequirements = append(idx.byRequirements, versionedKey{k, v})
+			}
+		}
+		sort.SliceStable(idx.byRequirements, func(i, j int) bool {
+			return idx.byRequirements[i].version.Compare(idx.byRequirements[j].version) < 0
+		})
+		s.certVersionIdx = idx
+	})
+	return s.certVersionIdx
+}
+
+// EntriesCertifiedUnderRequirements queries the embedded dataset. See
+// Provider.EntriesCertifiedUnderRequirements.
+func EntriesCertifiedUnderRequirements(min string) []Entry {
+	return Default().EntriesCertifiedUnderRequirements(min)
+}
+
+/*
+EntriesCertifiedUnderRequirements returns the entries whose latest certification report, in
+timeline order, was issued under certification requirements version min or later (see
+CertificationVersion.Compare), in ascending key order. Entries whose version is absent or
+unparseable are left out; the unparseable ones are listed by CertificationVersionDiagnostics. An
+unparseable min matches nothing.
+*/
+func (p *Provider) EntriesCertifiedUnderRequirements(min string) []Entry {
+	want, err := ParseCertificationVersion(min)
+	if err != nil {
+		return nil
+	}
+	s := p.current()
+	idx := s.certVersions().byRequirements
+	i := sort.Search(len(idx), func(i int) bool { return idx[i].version.Compare(want) >= 0 })
+	keys := make([]string, 0, len(idx)-i)
+	for _, vk := range idx[i:] {
+		keys = append(keys, vk.key)
+	}
+	sort.Strings(keys)
+	out := make([]Entry, len(keys))
+	for j, k := range keys {
+		out[j] = s.entries[k]
+	}
+	return out
+}
+
+// CertificationVersionDiagnostics lists the embedded dataset's unparseable versions. See
+// Provider.CertificationVersionDiagnostics.
+func CertificationVersionDiagnostics() []VersionDiagnostic {
+	return Default().CertificationVersionDiagnostics()
+}
+
+// CertificationVersionDiagnostics lists the policy and requirements versions of latest
+// certification reports that could not be parsed, in ascending key order, for manual review.
+func (p *Provider) CertificationVersionDiagnostics() []VersionDiagnostic {
+	return append([]VersionDiagnostic(nil), p.current().certVersions().diagnostics...)
+}
@@ -0,0 +1,195 @@
+package aaguids
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+/*
+collator orders display names for one language with the Unicode Collation Algorithm as tailored
+for it by golang.org/x/text/collate: case, accents and width only decide between names that are
+otherwise equal ("é" sorts with "e", "Ｙ" with "Y"), and letters the language treats as letters
+of their own sort where its alphabet puts them (Swedish "å", "ä", "ö" after "z", Spanish "ñ"
+after "n", ...). A collate.Collator is not safe for concurrent use, so keys are computed under mu.
+*/
+type collator struct {
+	mu  sync.Mutex
+	c   *collate.Collator
+	buf collate.Buffer
+}
+
+// collationMatcher maps language tags to the languages collate has tailorings for.
+var collationMatcher = language.NewMatcher(collate.Supported())
+
+// defaultCollator orders names by the root collation, for callers without a language.
+var defaultCollator = &collator{c: collate.New(language.Und)}
+
+// collators caches the collator of every supported language; it is keyed by the matched supported
+// tag, not by lang, so callers passing arbitrary tags from requests cannot grow it.
+var collators sync.Map // string → *collator
+
+// collatorFor returns the cached collator for lang, building it on first use.
+func collatorFor(lang string) *collator {
+	tag, err := language.Parse(strings.ReplaceAll(lang, "_", "-"))
+	if err != nil || tag == language.Und {
+		return defaultCollator
+	}
+	_, i, conf := collationMatcher.Match(tag)
+	if conf == language.No {
+		return defaultCollator
+	}
+	supported := collate.Supported()[i]
+	if c, ok := collators.Load(supported.String()); ok {
+		return c.(*collator)
+	}
+	actual, _ := collators.LoadOrStore(supported.String(), &collator{c: collate.New(supported)})
+	return actual.(*collator)
+}
+
+// collationKey holds the precomputed sort key of one name and the name itself, which breaks ties
+// between names the collation considers equal.
+type collationKey struct {
+	sort, name string
+}
+
+// key returns the comparison key of s.
+func (c *collator) key(s string) collationKey {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := collationKey{string(c.c.KeyFromString(&c.buf, s)), s}
+	c.buf.Reset()
+	return k
+}
+
+// compare orders two names by their keys, see collator.
+func (a collationKey) compare(b collationKey) int {
+	if a.sort != b.sort {
+		return strings.Compare(a.sort, b.sort)
+	}
+	return strings.Compare(a.name, b.name)
+}
+
+/*
+DisplayNameFor returns the name to show for the entry in lang, an IETF language tag: the
+alternativeDescriptions value matched with matchLanguage ("fr-CA" finds "fr-CA", then "fr", then
+"fr-FR"), falling back to DisplayName.
+*/
+func (e Entry) DisplayNameFor(lang string) string {
+	if name, ok := matchLanguage(e.MetadataStatement.AlternativeDescriptions, lang); ok {
+		return name
+	}
+	return e.DisplayName()
+}
+
+/*
+matchLanguage returns the value of tags for lang, both IETF language tags compared
+case-insensitively with "_" read as "-". It tries lang, then lang with trailing subtags removed one
+by one (RFC 4647 lookup: "zh-Hant-TW", "zh-Hant", "zh"), then the first tag in sort order with the
+same base language, so "fr" also finds "fr-FR". It reports false if lang is empty or nothing
+matches.
+*/
+func matchLanguage[V any](tags map[string]V, lang string) (V, bool) {
+	var zero V
+	lang = strings.ReplaceAll(lang, "_", "-")
+	if lang == "" || len(tags) == 0 {
+		return zero, false
+	}
+	for prefix := lang; ; {
+		for tag, v := range tags {
+			if strings.EqualFold(strings.ReplaceAll(tag, "_", "-"), prefix) {
+				return v, true
+			}
+		}
+		i := strings.LastIndexByte(prefix, '-')
+		if i < 0 {
+			break
+		}
+		prefix = prefix[:i]
+	}
+	base, _, _ := strings.Cut(lang, "-")
+	best, found := "", false
+	for tag := range tags {
+		tagBase, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+		if strings.EqualFold(tagBase, base) && (!found || tag < best) {
+			best, found = tag, true
+		}
+	}
+	if !found {
+		return zero, false
+	}
+	return tags[best], true
+}
+
+// SortOption adjusts ListEntriesSorted.
+type SortOption func(*sortConfig)
+
+// sortConfig is the configuration of one ListEntriesSorted call.
+type sortConfig struct {
+	platformFirst bool
+}
+
+// PlatformFirst lists platform authenticators and passkey providers (see IsPlatform) before
+// roaming authenticators; each group is sorted on its own.
+func PlatformFirst() SortOption {
+	return func(c *sortConfig) { c.platformFirst = true }
+}
+
+/*
+IsPlatform reports whether the entry is a platform authenticator or passkey provider rather than a
+roaming security key: its authenticatorGetInfo sets the "plat" option, or it is only known from the
+community passkey provider list and has no metadata statement of its own.
+*/
+func (e Entry) IsPlatform() bool {
+	if info, ok := e.GetInfo(); ok && info.Option("plat") == OptionTrue {
+		return true
+	}
+	return e.CommunityExtensions != nil && e.MetadataStatement.ProtocolFamily == ""
+}
+
+// ListEntriesSorted sorts the entries of the embedded dataset by name. See
+// Provider.ListEntriesSorted.
+func ListEntriesSorted(lang string, filter Filter, opts ...SortOption) []Entry {
+	return Default().ListEntriesSorted(lang, filter, opts...)
+}
+
+/*
+ListEntriesSorted returns the entries matching filter (nil for all), sorted by DisplayNameFor(lang)
+with the collation rules of lang (see collator) and then by dataset key, so the order is stable
+across calls. Collators are cached per language.
+*/
+func (p *Provider) ListEntriesSorted(lang string, filter Filter, opts ...SortOption) []Entry {
+	var cfg sortConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	type row struct {
+		e        Entry
+		key      string
+		name     collationKey
+		platform bool
+	}
+	c := collatorFor(lang)
+	var rows []row
+	for e := range p.Where(filter) {
+		rows = append(rows, row{e: e, key: e.Key(), name: c.key(e.DisplayNameFor(lang)), platform: cfg.platformFirst && e.IsPlatform()})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.platform != b.platform {
+			return a.platform
+		}
+		if n := a.name.compare(b.name); n != 0 {
+			return n < 0
+		}
+		return a.key < b.key
+	})
+	out := make([]Entry, len(rows))
+	for i, r := range rows {
+		out[i] = r.e
+	}
+	return out
+}
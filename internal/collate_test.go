@@ -0,0 +1,57 @@
+package aaguids
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCollatorOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		lang  string
+		names []string // in the wanted order
+	}{
+		{"accents sort with their base letter", "", []string{"Eagle", "Éclair", "Ezra"}},
+		{"accents only break ties", "", []string{"resume", "résumé", "resumes"}},
+		{"case sorts with the letter", "", []string{"alpha", "Beta", "gamma"}},
+		{"case only breaks ties", "", []string{"yubikey", "YubiKey", "YubiKeys"}},
+		{"fullwidth sorts with ASCII", "", []string{"Google", "Ｙｕｂｉ", "Zebra"}},
+		{"digits before letters", "", []string{"1Password", "Apple", "Bitwarden"}},
+		{"swedish letters after z", "sv", []string{"Zeta", "Åke", "Ära", "Örn"}},
+		{"root keeps them with a and o", "en", []string{"Åke", "Ära", "Örn", "Zeta"}},
+		{"regional tags use their language", "sv-SE", []string{"Zeta", "Åke"}},
+		{"unknown tags use the root order", "zz-bogus", []string{"Eagle", "Éclair", "Ezra"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := collatorFor(tt.lang)
+			got := slices.Clone(tt.names)
+			slices.Reverse(got)
+			slices.SortFunc(got, func(a, b string) int { return c.key(a).compare(c.key(b)) })
+			if !slices.Equal(got, tt.names) {
+				t.Errorf("sorted %q, want %q", got, tt.names)
+			}
+		})
+	}
+}
+
+func TestCollatorForCachesBySupportedTag(t *testing.T) {
+	if collatorFor("sv") != collatorFor("sv-SE") || collatorFor("sv") != collatorFor("sv_FI") {
+		t.Error("regional Swedish tags built separate collators")
+	}
+	if collatorFor("") != defaultCollator || collatorFor("not a tag!") != defaultCollator {
+		t.Error("empty and invalid tags do not use the default collator")
+	}
+}
+
+func TestCollationKeyBreaksTiesByName(t *testing.T) {
+	// Names the collation considers equal (here, differing only in ignorable characters) still
+	// order deterministically.
+	a, b := defaultCollator.key("Key\u00ad"), defaultCollator.key("Key")
+	if a.compare(b) == 0 {
+		t.Fatal("distinct names compared equal")
+	}
+	if a.compare(b) != -b.compare(a) {
+		t.Error("compare is not antisymmetric")
+	}
+}
@@ -0,0 +1,120 @@
+package aaguids
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+/*
+CommunityExtensions holds what community-maintained lists (such as passkey-authenticator-aaguids)
+add to an entry. None of it is MDS data, so it is kept out of MetadataStatement and encoded under
+"communityExtensions" rather than mixed in with the spec fields:
+
+  - Source: the label of the list the extensions came from (see SourceEntries)
+  - DisplayName: the product name users know the authenticator by, e.g. "Google Password Manager"
+  - Icon / IconDark: PNG data URLs for light and dark backgrounds (icon_light / icon_dark)
+  - Website: the vendor's product page
+  - Tags: free-form labels such as "synced" or "hardware"
+
+Accessors such as DisplayName and IconFor consult the extensions and the metadata statement
+together, so callers need not care where a value came from.
+*/
+type CommunityExtensions struct {
+	Source      string   `json:"source"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Icon        string   `json:"icon,omitempty"`
+	IconDark    string   `json:"iconDark,omitempty"`
+	Website     string   `json:"website,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// DisplayName returns the name to show for the entry: the community display name if there is one,
+// otherwise the metadata statement's description.
+func (e Entry) DisplayName() string {
+	if c := e.CommunityExtensions; c != nil && c.DisplayName != "" {
+		return c.DisplayName
+	}
+	return e.MetadataStatement.Description
+}
+
+// communityRecord is one value of the community list format: {"name", "icon_light", "icon_dark"}.
+type communityRecord struct {
+	Name      string `json:"name"`
+	IconLight string `json:"icon_light,omitempty"`
+	IconDark  string `json:"icon_dark,omitempty"`
+}
+
+// CommunityExportOption adjusts ExportCommunityFormat.
+type CommunityExportOption func(*communityExport)
+
+// communityExport is the configuration of one ExportCommunityFormat call.
+type communityExport struct {
+	filter  Filter
+	noIcons bool
+}
+
+// CommunityFilter exports only the entries matching f.
+func CommunityFilter(f Filter) CommunityExportOption {
+	return func(x *communityExport) { x.filter = f }
+}
+
+// WithoutCommunityIcons leaves icon_light and icon_dark out of every record.
+func WithoutCommunityIcons() CommunityExportOption {
+	return func(x *communityExport) { x.noIcons = true }
+}
+
+// ExportCommunityFormat writes the embedded dataset in the community list format. See
+// Provider.ExportCommunityFormat.
+func ExportCommunityFormat(w io.Writer, opts ...CommunityExportOption) error {
+	return Default().ExportCommunityFormat(w, opts...)
+}
+
+/*
+ExportCommunityFormat writes the current snapshot in the format of the passkey-authenticator-aaguids
+list: one JSON object mapping lowercase, dashed AAGUIDs to {"name", "icon_light", "icon_dark"}, with
+keys in ascending order and two-space indentation.
+
+  - name is DisplayName; entries without one are left out, as are UAF and U2F entries, which
+    have no AAGUID
+  - icon_light and icon_dark are the entry's own variant for that theme (see IconFor) without
+    falling back to the other, and only when it is a well-formed PNG data URL; otherwise omitted
+*/
+func (p *Provider) ExportCommunityFormat(w io.Writer, opts ...CommunityExportOption) error {
+	var x communityExport
+	for _, opt := range opts {
+		opt(&x)
+	}
+	out := make(map[string]communityRecord)
+	for id, e := range p.All() {
+		if x.filter != nil && !x.filter(e) {
+			continue
+		}
+		rec := communityRecord{Name: e.DisplayName()}
+		if rec.Name == "" {
+			continue
+		}
+		if !x.noIcons {
+			rec.IconLight = firstPNGDataURL(e.iconVariants(Light))
+			rec.IconDark = firstPNGDataURL(e.iconVariants(Dark))
+		}
+		out[id.String()] = rec
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(out); err != nil {
+		return fmt.Errorf("encoding community list: %w", err)
+	}
+	return nil
+}
+
+// firstPNGDataURL returns the first well-formed PNG data URL of candidates, or "".
+func firstPNGDataURL(candidates []string) string {
+	for _, c := range candidates {
+		if _, err := decodePNGDataURL(c); err == nil {
+			return c
+		}
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package aaguids
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+// communityProvider returns the mixed BLOB fixture plus community entries covering every record
+// shape: both icons, a light icon from the metadata statement, a malformed icon, an uppercase key and
+// an entry without a name.
+func communityProvider(t *testing.T) *Provider {
+	t.Helper()
+	entries := readTestBLOB(t, "blob-mixed.json").EntriesByKey()
+	entries[testGPM] = Entry{AAGUID: testGPM, CommunityExtensions: &CommunityExtensions{
+		Source: "test", DisplayName: "Google Password Manager", Icon: testDarkIcon, IconDark: testDarkIcon,
+	}}
+	entries[testWindowsHello] = Entry{AAGUID: testWindowsHello, MetadataStatement: MetadataStatement{
+		Description: "Windows Hello <Hardware> Authenticator", Icon: testDarkIcon,
+	}}
+	const (
+		malformed = "00000000-0000-4000-8000-000000000001"
+		unnamed   = "00000000-0000-4000-8000-000000000002"
+		upper     = "00000000-0000-4000-8000-00000000000A"
+	)
+	entries[malformed] = Entry{AAGUID: malformed, CommunityExtensions: &CommunityExtensions{
+		Source: "test", DisplayName: "Broken Icon Key",
+		Icon: "data:image/png;base64,bm90IGEgcG5n", IconDark: "https://example.com/icon.png",
+	}}
+	entries[upper] = Entry{AAGUID: upper, MetadataStatement: MetadataStatement{Description: "Uppercase Key"}}
+	entries[unnamed] = Entry{AAGUID: unnamed}
+	return NewProvider(entries, Dataset{Serial: 1})
+}
+
+func TestCommunityFormatGolden(t *testing.T) {
+	p := communityProvider(t)
+	for name, opts := range map[string][]CommunityExportOption{
+		"community.json":          nil,
+		"community-no-icons.json": {WithoutCommunityIcons()},
+		"community-filtered.json": {CommunityFilter(func(e Entry) bool { return e.CommunityExtensions != nil })},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := p.ExportCommunityFormat(&buf, opts...); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, filepath.Join("testdata", name), buf.Bytes())
+		})
+	}
+}
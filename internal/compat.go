@@ -0,0 +1,57 @@
+package aaguids
+
+import "maps"
+
+// This file keeps the spellings of exported API that releases removed or retyped, each marked
+// Deprecated and forwarding to its replacement. MIGRATION.md lists them; it is generated from
+// testdata/deprecations.json, the table compat_test.go checks every spelling against.
+
+// CertLevelUint8 returns r.CertLevel as the uint8 CertLevel was before it became a
+// BiometricCertLevel.
+//
+// Deprecated: compare CertLevel with BiometricCertLevel.IsAtLeast, or convert it with uint8.
+func (r BiometricStatusReport) CertLevelUint8() uint8 {
+	return uint8(r.CertLevel)
+}
+
+// ModalityString returns r.Modality as the string Modality was before it became a
+// BiometricModality.
+//
+// Deprecated: compare Modality with the Modality constants, or convert it with string.
+func (r BiometricStatusReport) ModalityString() string {
+	return string(r.Modality)
+}
+
+// withCompatFields returns entries with the deprecated fields of every entry filled in from their
+// replacements (see compatEntry), copying the map only if an entry changes.
+func withCompatFields(entries map[string]Entry) map[string]Entry {
+	var out map[string]Entry
+	for k, e := range entries {
+		c, changed := compatEntry(e)
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = maps.Clone(entries)
+		}
+		out[k] = c
+	}
+	if out == nil {
+		return entries
+	}
+	return out
+}
+
+// compatEntry sets MetadataStatement.IconDark and MetadataStatement.KeyProtection of e from
+// CommunityExtensions.IconDark and UAF.KeyProtection, reporting whether either changed.
+func compatEntry(e Entry) (Entry, bool) {
+	m := &e.MetadataStatement
+	changed := false
+	if c := e.CommunityExtensions; c != nil && c.IconDark != "" && m.IconDark != c.IconDark {
+		m.IconDark, changed = c.IconDark, true
+	}
+	if keyProtection := m.UAF != nil && len(m.UAF.KeyProtection) > 0; keyProtection != m.KeyProtection {
+		m.KeyProtection, changed = keyProtection, true
+	}
+	return e, changed
+}
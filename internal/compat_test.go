@@ -0,0 +1,175 @@
+package aaguids
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// deprecation is one row of testdata/deprecations.json.
+type deprecation struct {
+	Old    string `json:"old"`
+	Change string `json:"change"`
+	New    string `json:"new"`
+	Compat string `json:"compat"`
+	Reason string `json:"reason"`
+}
+
+func readDeprecations(t *testing.T) []deprecation {
+	t.Helper()
+	var table []deprecation
+	if err := json.Unmarshal(readTestdata(t, "deprecations.json"), &table); err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+const testDarkIcon = "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg=="
+
+// compatChecks holds, for every row of the table by its old spelling, a test that uses the old
+// spelling and checks that it agrees with the new API. The uses are deliberate: that this file
+// compiles is part of the check.
+var compatChecks = map[string]func(t *testing.T){
+	"MetadataStatement.IconDark string": func(t *testing.T) {
+		withIcon := Entry{AAGUID: testYubiKey, CommunityExtensions: &CommunityExtensions{Source: "test", IconDark: testDarkIcon}}
+		without := Entry{AAGUID: testGPM}
+		entries := map[string]Entry{testYubiKey: withIcon, testGPM: without}
+		p := NewProvider(entries, Dataset{})
+		for _, k := range []string{testYubiKey, testGPM} {
+			e, _ := p.GetEntry(k)
+			var old string = e.MetadataStatement.IconDark
+			var want string
+			if e.CommunityExtensions != nil {
+				want = e.CommunityExtensions.IconDark
+			}
+			if old != want {
+				t.Errorf("%s: MetadataStatement.IconDark = %q, CommunityExtensions.IconDark = %q", k, old, want)
+			}
+		}
+		if e, _ := p.GetEntry(testYubiKey); e.MetadataStatement.IconDark != testDarkIcon {
+			t.Errorf("MetadataStatement.IconDark not filled in")
+		}
+		if entries[testYubiKey].MetadataStatement.IconDark != "" {
+			t.Error("filling in MetadataStatement.IconDark modified the caller's map")
+		}
+	},
+	"BiometricStatusReport.CertLevel uint8": func(t *testing.T) {
+		for _, level := range []BiometricCertLevel{BiometricCertLevel1, BiometricCertLevel2} {
+			r := BiometricStatusReport{CertLevel: level}
+			var old uint8 = r.CertLevelUint8()
+			if old != uint8(r.CertLevel) {
+				t.Errorf("CertLevelUint8() = %d, want %d", old, r.CertLevel)
+			}
+			for min := uint8(1); min <= 2; min++ {
+				if oldStyle, newStyle := r.CertLevelUint8() >= min, r.CertLevel.IsAtLeast(BiometricCertLevel(min)); oldStyle != newStyle {
+					t.Errorf("level %d, minimum %d: raw comparison %v, IsAtLeast %v", level, min, oldStyle, newStyle)
+				}
+			}
+			if (r.CertLevel == 2) != (r.CertLevel == BiometricCertLevel2) {
+				t.Errorf("level %d compares differently with an untyped constant", level)
+			}
+		}
+	},
+	"BiometricStatusReport.Modality string": func(t *testing.T) {
+		for _, m := range []BiometricModality{ModalityFingerprint, ModalityFaceprint, "smellprint_internal"} {
+			r := BiometricStatusReport{Modality: m}
+			var old string = r.ModalityString()
+			if old != string(r.Modality) {
+				t.Errorf("ModalityString() = %q, want %q", old, r.Modality)
+			}
+			if (r.Modality == "fingerprint_internal") != (r.Modality == ModalityFingerprint) {
+				t.Errorf("modality %q compares differently with a string literal", m)
+			}
+		}
+	},
+	"MetadataStatement.KeyProtection bool": func(t *testing.T) {
+		uaf := Entry{AAID: "1234#5678", MetadataStatement: decodeTestStatement(t, "uaf-statement.json")}
+		fido2 := Entry{AAGUID: testYubiKey, MetadataStatement: MetadataStatement{ProtocolFamily: "fido2"}}
+		p := testProvider(t, uaf, fido2)
+		for _, k := range []string{"uaf:1234#5678", testYubiKey} {
+			e, _ := p.GetEntry(k)
+			var old bool = e.MetadataStatement.KeyProtection
+			want := e.MetadataStatement.UAF != nil && len(e.MetadataStatement.UAF.KeyProtection) > 0
+			if old != want {
+				t.Errorf("%s: KeyProtection = %v, UAF.KeyProtection = %v", k, old, e.MetadataStatement.UAF)
+			}
+		}
+		if e, _ := p.GetEntry("uaf:1234#5678"); !e.MetadataStatement.KeyProtection {
+			t.Error("KeyProtection not set for a UAF entry listing key protection")
+		}
+	},
+}
+
+func TestDeprecatedSpellings(t *testing.T) {
+	table := readDeprecations(t)
+	seen := make(map[string]bool)
+	for _, d := range table {
+		seen[d.Old] = true
+		check, ok := compatChecks[d.Old]
+		if !ok {
+			t.Errorf("deprecations.json lists %q but compatChecks has no check for it", d.Old)
+			continue
+		}
+		t.Run(d.Old, check)
+	}
+	for old := range compatChecks {
+		if !seen[old] {
+			t.Errorf("compatChecks checks %q, which deprecations.json does not list", old)
+		}
+	}
+}
+
+func TestDeprecatedFieldsStayOutOfEncodings(t *testing.T) {
+	e := Entry{
+		AAGUID:              testYubiKey,
+		MetadataStatement:   MetadataStatement{IconDark: testDarkIcon, KeyProtection: true},
+		CommunityExtensions: &CommunityExtensions{Source: "test", IconDark: testDarkIcon},
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("KeyProtection")) || bytes.Count(b, []byte(testDarkIcon)) != 1 {
+		t.Errorf("deprecated fields leaked into the JSON encoding: %s", b)
+	}
+
+	// Integrity is computed over the encoding, so filling the fields in does not change it.
+	p := NewProvider(map[string]Entry{testYubiKey: e}, Dataset{})
+	plain := e
+	plain.MetadataStatement.IconDark, plain.MetadataStatement.KeyProtection = "", false
+	got, err := ComputeIntegrity(p.current().entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ComputeIntegrity(map[string]Entry{testYubiKey: plain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("integrity with compat fields %s, without %s", got, want)
+	}
+}
+
+// renderMigrationGuide renders table as MIGRATION.md.
+func renderMigrationGuide(table []deprecation) []byte {
+	var b strings.Builder
+	b.WriteString("# Migrating to the current API\n\n")
+	b.WriteString("<!-- Generated from internal/testdata/deprecations.json by `go test ./internal -run TestMigrationGuide -update`. DO NOT EDIT. -->\n\n")
+	b.WriteString("This release breaks the API of earlier ones: the symbols below were removed or retyped. ")
+	b.WriteString("Removed fields are kept as deprecated fields, so code using them compiles and behaves as before. ")
+	b.WriteString("Code comparing a retyped field with a constant or converting it compiles unchanged; code assigning it to its old type should switch to the deprecated accessor listed. ")
+	b.WriteString("`staticcheck` and gopls flag every use of a deprecated spelling; new code should use the replacement.\n\n")
+	b.WriteString("| Old API | Change | Use instead | Kept for compatibility | Why |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, d := range table {
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n", d.Old, d.Change, d.New, d.Compat, d.Reason)
+	}
+	return []byte(b.String())
+}
+
+func TestMigrationGuide(t *testing.T) {
+	checkGolden(t, filepath.Join("..", "MIGRATION.md"), renderMigrationGuide(readDeprecations(t)))
+}
@@ -0,0 +1,147 @@
+package aaguids
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// conformanceDefault records the opt-in of EnableConformanceDefault.
+var conformanceDefault atomic.Bool
+
+/*
+EnableConformanceDefault allows conformance data to be loaded into the Default provider, and with
+it the package-level API. Without this opt-in, Default().Update panics on a Dataset marked as
+conformance data, so test metadata cannot silently replace production data process-wide. Only
+conformance test servers should call it.
+*/
+func EnableConformanceDefault() {
+	conformanceDefault.Store(true)
+}
+
+/*
+NewConformanceProvider builds a Provider from the test metadata of the FIDO conformance tools:
+
+  - statements: a directory or .zip file holding the conformance metadata statements (*.json, in
+    any subdirectory), decoded like the statements of a BLOB
+  - endpoints: the test MDS endpoints listed by the conformance tools; each one is fetched with
+    client (http.DefaultClient if nil) and parsed with ParseMetadataBLOB against roots, the test
+    trust root, so statement and BLOB handling share the production code paths; pass
+    FetchCoordinator.Client to share a coordinator with other components
+
+Endpoint entries take precedence over loose statements with the same key. The returned Provider's
+DatasetInfo has Conformance set; see EnableConformanceDefault for using it as the Default provider.
+*/
+func NewConformanceProvider(ctx context.Context, statements string, endpoints []string, roots *x509.CertPool, client *http.Client) (*Provider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var blob BLOBPayload
+	if statements != "" {
+		ms, err := readConformanceStatements(statements)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range ms {
+			blob.Entries = append(blob.Entries, Entry{
+				AAGUID:                               m.AAGUID,
+				AAID:                                 m.AAID,
+				AttestationCertificateKeyIdentifiers: m.AttestationCertificateKeyIdentifiers,
+				MetadataStatement:                    m,
+			})
+		}
+	}
+	entries := blob.EntriesByKey()
+
+	info := Dataset{Conformance: true, Sources: []DatasetSource{{Name: "fido-conformance-statements", URL: statements}}}
+	for _, u := range endpoints {
+		jwt, err := fetchConformanceBLOB(ctx, client, u)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ParseMetadataBLOB(jwt, roots)
+		if err != nil {
+			return nil, fmt.Errorf("conformance endpoint %s: %w", u, err)
+		}
+		for k, e := range b.EntriesByKey() {
+			entries[k] = e
+		}
+		info.Serial = max(info.Serial, b.No)
+		info.Sources = append(info.Sources, DatasetSource{Name: "fido-conformance-mds", URL: u})
+	}
+	info.EntryCount = len(entries)
+	return NewProvider(entries, info), nil
+}
+
+// readConformanceStatements decodes every *.json file of the directory or ZIP archive at name,
+// in path order.
+func readConformanceStatements(name string) ([]MetadataStatement, error) {
+	var fsys fs.FS
+	if strings.EqualFold(path.Ext(name), ".zip") {
+		zr, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, fmt.Errorf("opening conformance statements: %w", err)
+		}
+		defer zr.Close()
+		fsys = zr
+	} else {
+		fsys = os.DirFS(name)
+	}
+
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(path.Ext(p), ".json") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing conformance statements: %w", err)
+	}
+	sort.Strings(files)
+
+	out := make([]MetadataStatement, 0, len(files))
+	for _, f := range files {
+		raw, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return nil, fmt.Errorf("reading conformance statement %s: %w", f, err)
+		}
+		var m MetadataStatement
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, fmt.Errorf("decoding conformance statement %s: %w", f, err)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// fetchConformanceBLOB downloads the JWT served by a conformance MDS endpoint.
+func fetchConformanceBLOB(ctx context.Context, client *http.Client, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("conformance endpoint %s: %w", u, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("conformance endpoint %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("conformance endpoint %s: unexpected status code %d", u, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,117 @@
+package aaguids
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// SourceFIDOMDS3 is the DatasetSource name of the FIDO Metadata Service BLOB.
+const SourceFIDOMDS3 = "fido-mds3"
+
+/*
+DatasetSource identifies one upstream feed that contributed entries to the generated dataset,
+e.g. the FIDO MDS3 BLOB or the community passkey-authenticator-aaguids list, and how fresh its
+contribution is:
+
+  - Name / URL: the feed
+  - Version: the feed's own version of the data, e.g. the MDS serial or a digest or commit of a
+    community list; "" if unknown
+  - UpdatedAt: RFC 3339 time of the last successful update from the feed; a feed that contributed
+    no entries keeps the UpdatedAt and Version of the previous dataset (see Provider.Update)
+  - NextUpdate: when the feed promises new data (ISO-8601), "" if it makes no promise
+  - EntryCount: the number of entries the feed contributed before merging
+
+Datasets built before this bookkeeping only carry Name and URL.
+*/
+type DatasetSource struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Version    string `json:"version,omitempty"`
+	UpdatedAt  string `json:"updatedAt,omitempty"`
+	NextUpdate string `json:"nextUpdate,omitempty"`
+	EntryCount int    `json:"entryCount,omitempty"`
+}
+
+/*
+carrySourceFreshness returns a copy of sources in which every source that contributed no entries
+keeps the Version and UpdatedAt of the same-named source of old, so a feed that came back empty,
+possibly an upstream outage, does not look freshly updated.
+*/
+func carrySourceFreshness(old *snapshot, sources []DatasetSource) []DatasetSource {
+	if sources == nil {
+		return nil
+	}
+	sources = slices.Clone(sources)
+	if old == nil {
+		return sources
+	}
+	for i, src := range sources {
+		if src.EntryCount > 0 {
+			continue
+		}
+		for _, prev := range old.info.Sources {
+			if prev.Name == src.Name && prev.UpdatedAt != "" {
+				sources[i].Version, sources[i].UpdatedAt = prev.Version, prev.UpdatedAt
+				break
+			}
+		}
+	}
+	return sources
+}
+
+/*
+Dataset describes the identity of the metadata compiled into this package. All values are stamped
+by the generator at generation time, so they always match the embedded map:
+
+  - Serial: the MDS BLOB "no" the dataset was built from
+  - NextUpdate: the MDS BLOB "nextUpdate" date (ISO-8601, date only)
+  - GeneratedAt: RFC 3339 UTC timestamp of the generator run
+  - Sources: the upstream feeds that were merged
+  - EntryCount: number of entries in the embedded map
+  - Integrity: "sha256:<hex>" digest as computed by ComputeIntegrity
+  - LegalHeaderHashes: the LegalHeaderHash of every distinct legal header of the entries, sorted
+    (see ComputeLegalHeaderHashes); always recomputed when a Provider loads a dataset
+  - Conformance: the data comes from the FIDO conformance tools, not production MDS (see
+    NewConformanceProvider); never set for the embedded dataset
+*/
+type Dataset struct {
+	Serial            int             `json:"serial"`
+	NextUpdate        string          `json:"nextUpdate"`
+	GeneratedAt       string          `json:"generatedAt"`
+	Sources           []DatasetSource `json:"sources"`
+	EntryCount        int             `json:"entryCount"`
+	Integrity         string          `json:"integrity"`
+	Conformance       bool            `json:"conformance,omitempty"`
+	LegalHeaderHashes []string        `json:"legalHeaderHashes,omitempty"`
+}
+
+// Version returns the release of aaguid-information-generator that produced this package.
+// Builds from an untagged checkout report "(devel)".
+func Version() string {
+	if libraryVersion == "" {
+		return "(devel)"
+	}
+	return libraryVersion
+}
+
+// DatasetInfo returns the identity of the embedded dataset, suitable for structured startup logs.
+func DatasetInfo() Dataset {
+	return Default().DatasetInfo()
+}
+
+/*
+ComputeIntegrity returns the "sha256:<hex>" digest of the given entries. The digest is taken over
+the JSON encoding of the map (encoding/json sorts map keys), so the generator and a running binary
+compute the same value for the same data.
+*/
+func ComputeIntegrity(entries map[string]Entry) (string, error) {
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		return "", fmt.Errorf("encoding entries for integrity hash: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,131 @@
+package aaguids
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// entryRefDescriptionLimit bounds the description quoted by FormatEntryRef, in runes.
+	entryRefDescriptionLimit = 64
+	// entryErrorLimit bounds the message of errors built by entryErrorf, in bytes.
+	entryErrorLimit = 512
+)
+
+/*
+FormatEntryRef returns a compact, single-line reference to e for error messages and log lines: its
+dataset key (see Entry.Key) followed by its quoted display name, cut to 64 runes, e.g.
+
+	ee882879-721c-4913-9775-3dfcce97072a ("YubiKey 5 Series")
+
+Icons, legal headers and certificates never appear; refer to certificates with CertificateRef.
+*/
+func FormatEntryRef(e Entry) string {
+	key := e.Key()
+	if key == "" {
+		key = "<no key>"
+	}
+	name := elideDataURLs(e.DisplayName())
+	if name == "" {
+		return key
+	}
+	if utf8.RuneCountInString(name) > entryRefDescriptionLimit {
+		name = string([]rune(name)[:entryRefDescriptionLimit-1]) + "…"
+	}
+	return fmt.Sprintf("%s (%q)", key, name)
+}
+
+// CertificateRef returns the "sha256:<hex>" fingerprint of a base64 DER certificate, as it appears
+// in MDS, for use in messages instead of the certificate itself.
+func CertificateRef(b64 string) string {
+	der, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		der = []byte(b64)
+	}
+	return fingerprintString(sha256.Sum256(der))
+}
+
+/*
+entryErrorf is how errors carrying Entry context are built in this package: the message is
+FormatEntryRef(e), ": ", then format applied to args, with at most one %w wrapping as in
+fmt.Errorf. Any data: URL that slips into the arguments is elided and the whole message is cut to
+entryErrorLimit bytes, so an error can be logged or attached to a trace as it is.
+*/
+func entryErrorf(e Entry, format string, args ...any) error {
+	return boundedErrorf(FormatEntryRef(e)+": "+format, args...)
+}
+
+// boundedErrorf is fmt.Errorf with the message bounded as by entryErrorf, for errors that quote
+// dataset or caller-supplied values (an AAID, a date, a URL) without an Entry to refer to.
+func boundedErrorf(format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	return &entryError{msg: boundErrorMessage(err.Error()), err: err}
+}
+
+// entryError carries the bounded message of an entryErrorf or boundedErrorf error while
+// unwrapping like the original, including to each error of a format with several %w.
+type entryError struct {
+	msg string
+	err error
+}
+
+func (e *entryError) Error() string { return e.msg }
+
+func (e *entryError) Unwrap() []error {
+	switch err := e.err.(type) {
+	case interface{ Unwrap() []error }:
+		return err.Unwrap()
+	case interface{ Unwrap() error }:
+		if inner := err.Unwrap(); inner != nil {
+			return []error{inner}
+		}
+	}
+	return nil
+}
+
+// boundErrorMessage elides data: URLs in msg and cuts it to entryErrorLimit bytes on a rune boundary.
+func boundErrorMessage(msg string) string {
+	out := elideDataURLs(msg)
+	if len(out) <= entryErrorLimit {
+		return out
+	}
+	cut := entryErrorLimit - len("…")
+	for cut > 0 && !utf8.RuneStart(out[cut]) {
+		cut--
+	}
+	return out[:cut] + "…"
+}
+
+// elideDataURLs replaces every data: URL in s, up to the next whitespace, quote or closing
+// bracket, by a length note. "data:" inside a word, as in "metadata:", is not a URL.
+func elideDataURLs(s string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "data:")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		if i > 0 && isWordByte(s[i-1]) {
+			b.WriteString(s[:i+len("data:")])
+			s = s[i+len("data:"):]
+			continue
+		}
+		b.WriteString(s[:i])
+		rest := s[i:]
+		end := strings.IndexAny(rest, " \t\n\"')]")
+		if end < 0 {
+			end = len(rest)
+		}
+		b.WriteString(elided(rest[:end]))
+		s = rest[end:]
+	}
+}
+
+// isWordByte reports whether c is an ASCII letter or digit.
+func isWordByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
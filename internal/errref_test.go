@@ -0,0 +1,490 @@
+package aaguids
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"math/big"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// Hostile values, as a compromised or broken upstream feed might publish them: a 10 KB icon-sized
+// data: URL and 10 KB of plain text.
+var (
+	hostileDataURL = "data:image/png;base64," + strings.Repeat("iVBORw0K", 10<<10/8)
+	hostileText    = strings.Repeat("x", 10<<10)
+)
+
+// hostileEntry returns an entry whose every free-text field is hostile.
+func hostileEntry() Entry {
+	return Entry{
+		AAGUID: testYubiKey,
+		MetadataStatement: MetadataStatement{
+			AAGUID:      testYubiKey,
+			Description: hostileDataURL + " " + hostileText,
+			LegalHeader: hostileText,
+			Icon:        hostileDataURL,
+		},
+	}
+}
+
+func TestFormatEntryRef(t *testing.T) {
+	long := strings.Repeat("é", entryRefDescriptionLimit+10)
+	for _, tc := range []struct {
+		name string
+		e    Entry
+		want string
+	}{
+		{"no key", Entry{}, "<no key>"},
+		{"no name", Entry{AAGUID: testYubiKey}, testYubiKey},
+		{"name", Entry{AAGUID: testYubiKey, MetadataStatement: MetadataStatement{Description: "YubiKey 5 Series"}},
+			testYubiKey + ` ("YubiKey 5 Series")`},
+		{"long name", Entry{AAGUID: testYubiKey, MetadataStatement: MetadataStatement{Description: long}},
+			testYubiKey + ` ("` + long[:(entryRefDescriptionLimit-1)*len("é")] + `…")`},
+		{"data URL", Entry{AAGUID: testYubiKey, MetadataStatement: MetadataStatement{Description: "key " + testDarkIcon}},
+			testYubiKey + ` ("key ` + elided(testDarkIcon) + `")`},
+		{"UAF", Entry{AAID: "4e4e#4005"}, "uaf:4E4E#4005"},
+	} {
+		if got := FormatEntryRef(tc.e); got != tc.want {
+			t.Errorf("%s: FormatEntryRef = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestBoundedErrorf(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	for _, tc := range []struct {
+		name string
+		err  error
+		want string // the message, or its prefix when it is cut
+		is   []error
+	}{
+		{"short", boundedErrorf("%w: %s", errA, "short"), "a: short", []error{errA}},
+		{"two %w", boundedErrorf("%w: %w", errA, errB), "a: b", []error{errA, errB}},
+		{"no %w", boundedErrorf("plain %d", 1), "plain 1", nil},
+		{"long", boundedErrorf("%w: %s", errA, hostileText), "a: xxx", []error{errA}},
+		{"data URL", boundedErrorf("%w: icon %s", errA, testDarkIcon), "a: icon " + elided(testDarkIcon), []error{errA}},
+		{"runes", boundedErrorf("%w: %s", errA, strings.Repeat("€", entryErrorLimit)), "a: €€€", []error{errA}},
+		{"entry", entryErrorf(hostileEntry(), "%w", errA), testYubiKey + ` ("` + elided(hostileDataURL), []error{errA}},
+	} {
+		msg := tc.err.Error()
+		if len(msg) > entryErrorLimit || !utf8.ValidString(msg) {
+			t.Errorf("%s: message of %d bytes (valid UTF-8 %v), want at most %d", tc.name, len(msg), utf8.ValidString(msg), entryErrorLimit)
+		}
+		if !strings.HasPrefix(msg, tc.want) {
+			t.Errorf("%s: message %.80q, want prefix %q", tc.name, msg, tc.want)
+		}
+		if long := len(tc.want) < len(msg); long != strings.HasSuffix(msg, "…") && tc.name != "entry" {
+			t.Errorf("%s: message %.80q cut %v", tc.name, msg, long)
+		}
+		for _, target := range tc.is {
+			if !errors.Is(tc.err, target) {
+				t.Errorf("%s: errors.Is(%v) = false", tc.name, target)
+			}
+		}
+	}
+}
+
+// testCertificate issues a P-256 certificate from tmpl, signed by parent (self-signed when parent
+// is nil), and returns it with its key.
+func testCertificate(t *testing.T, tmpl *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// attestationCerts returns a root named by a hostile common name, valid until notAfter, and a leaf
+// it issued whose id-fido-gen-ce-aaguid extension names aaguid.
+func attestationCerts(t *testing.T, notAfter time.Time, aaguid string) (root, leaf *x509.Certificate) {
+	t.Helper()
+	root, rootKey := testCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: hostileDataURL},
+		NotBefore:             time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	id, err := ParseAAGUID(aaguid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext, err := asn1.Marshal(id[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ = testCertificate(t, &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		Subject:         pkix.Name{CommonName: hostileText},
+		NotBefore:       time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:        time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC),
+		ExtraExtensions: []pkix.Extension{{Id: oidFIDOGenCEAAGUID, Value: ext}},
+	}, root, rootKey)
+	return root, leaf
+}
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// okResponse returns a 200 response with body.
+func okResponse(body string) *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+// errorCases builds, for every exported sentinel error by name, an error wrapping it from
+// hostile input through the API that returns it.
+func errorCases(t *testing.T) map[string]func() error {
+	str := func(s string) *string { return &s }
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	packed := func(e Entry, in PackedAttestationInput) func() error {
+		return func() error {
+			in.Now = now
+			return VerifyPackedAttestation(e, in)
+		}
+	}
+	withRoot := func(root *x509.Certificate) Entry {
+		e := hostileEntry()
+		e.MetadataStatement.AttestationTypes = []string{AttestationBasicFull}
+		e.MetadataStatement.AuthenticationAlgorithms = []string{"secp256r1_ecdsa_sha256_raw"}
+		e.MetadataStatement.AttestationRootCertificates = []string{base64.StdEncoding.EncodeToString(root.Raw)}
+		return e
+	}
+	root, leaf := attestationCerts(t, time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC), testYubiKey)
+	_, otherLeaf := attestationCerts(t, time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC), testYubiKey)
+	expiredRoot, expiredLeaf := attestationCerts(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), testYubiKey)
+	_, mismatchedLeaf := attestationCerts(t, time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC), testGPM)
+	compromised := withRoot(root)
+	compromised.StatusReports = []StatusReport{{Status: ATTESTATION_KEY_COMPROMISE, EffectiveDate: str("2025-01-01")}}
+	hostileList := hostileEntry()
+	hostileList.MetadataStatement.AttestationTypes = []string{hostileDataURL, hostileText}
+	hostileList.MetadataStatement.AuthenticationAlgorithms = []string{hostileDataURL, hostileText}
+
+	uaf := Entry{AAID: "4e4e#4005", MetadataStatement: MetadataStatement{ProtocolFamily: "uaf", AAID: "4e4e#" + hostileDataURL}}
+	getInfo := hostileEntry()
+	getInfo.MetadataStatement.AuthenticatorGetInfo = &AuthenticatorGetInfo{AAGUID: hostileDataURL}
+	modality := hostileEntry()
+	modality.BiometricStatusReports = []BiometricStatusReport{{Modality: BiometricModality(hostileDataURL)}}
+	noIcon := hostileEntry()
+	noIcon.MetadataStatement.Icon = "data:image/png;base64,!" + hostileText
+
+	return map[string]func() error{
+		"ErrAAGUIDMismatch": func() error { return VerifyCertAAGUIDBinding(mismatchedLeaf, testYubiKey) },
+		"ErrInvalidAttestationObject": func() error {
+			_, err := ParseAAGUIDFromAttestationObject([]byte(hostileDataURL))
+			return err
+		},
+		"ErrAuthDataTruncated": func() error {
+			_, err := ParseAAGUIDFromAuthenticatorData([]byte(hostileDataURL[:40]))
+			return err
+		},
+		"ErrNoAttestedCredentialData": func() error {
+			_, err := ParseAAGUIDFromAuthenticatorData(make([]byte, 37))
+			return err
+		},
+		"ErrUnknownModality": func() error { return ValidateBiometricStatusReports(modality) },
+		"ErrSignatureInvalid": func() error {
+			pub, _, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return VerifyCanonicalJSON(pub, []byte(hostileDataURL), []byte(hostileText))
+		},
+		"ErrVersionAbsent": func() error {
+			_, err := StatusReport{CertificationPolicyVersion: str(" ")}.PolicyVersion()
+			return err
+		},
+		"ErrVersionMalformed": func() error {
+			_, err := StatusReport{CertificationPolicyVersion: str(hostileDataURL)}.PolicyVersion()
+			return err
+		},
+		"ErrFetchRejected":         func() error { return fetchRejected(t) },
+		"ErrGetInfoAAGUIDMismatch": func() error { return ValidateGetInfoAAGUID(getInfo) },
+		"ErrNoIcon": func() error {
+			_, err := noIcon.IconPNG(Light)
+			return err
+		},
+		"ErrInvalidISO8601Date": func() error {
+			_, err := ParseISO8601Date(hostileDataURL)
+			return err
+		},
+		"ErrEntryMismatch":             packed(hostileEntry(), PackedAttestationInput{AAGUID: testGPM}),
+		"ErrAttestationTypeNotAllowed": packed(hostileList, PackedAttestationInput{AAGUID: testYubiKey}),
+		"ErrAlgorithmNotListed": packed(hostileList, PackedAttestationInput{
+			AAGUID: testYubiKey, AttestationType: hostileText, Algorithm: -7,
+		}),
+		"ErrBatchCompromised": packed(compromised, PackedAttestationInput{
+			AAGUID: testYubiKey, Algorithm: -7, Certificates: []*x509.Certificate{leaf},
+		}),
+		"ErrNoRootMatched": packed(withRoot(root), PackedAttestationInput{
+			AAGUID: testYubiKey, Algorithm: -7, Certificates: []*x509.Certificate{otherLeaf},
+		}),
+		"ErrRootExpired": packed(withRoot(expiredRoot), PackedAttestationInput{
+			AAGUID: testYubiKey, Algorithm: -7, Certificates: []*x509.Certificate{expiredLeaf},
+		}),
+		"ErrUnknownAAGUID": func() error {
+			_, err := testProvider(t, hostileEntry()).Lookup("uaf:ABCD#1234")
+			return err
+		},
+		"ErrZeroAAGUID": func() error {
+			_, err := testProvider(t, hostileEntry()).Lookup("00000000-0000-0000-0000-000000000000")
+			return err
+		},
+		"ErrInvalidAAGUID": func() error {
+			_, err := testProvider(t, hostileEntry()).Lookup(hostileDataURL)
+			return err
+		},
+		"ErrRogueListUnavailable": func() error { return rogueListError(t, false, "") },
+		"ErrRogueListStale":       func() error { return rogueListError(t, true, "") },
+		"ErrRogueListHash":        func() error { return rogueListError(t, false, "AAAA") },
+		"ErrSelfTestFailed": func() error {
+			_, err := testProvider(t, hostileEntry(), uaf).SelfTest(SelfTestConfig{RequiredAAGUIDs: []string{hostileDataURL}, Now: now})
+			return err
+		},
+		"ErrInvalidSnapshot": func() error {
+			_, err := LoadSnapshot(strings.NewReader(hostileDataURL))
+			return err
+		},
+		"ErrUnsupportedSnapshot": func() error {
+			head := binary.BigEndian.AppendUint16([]byte(snapshotMagic), SnapshotFormatVersion+1)
+			head = binary.BigEndian.AppendUint16(head, 0)
+			head = binary.BigEndian.AppendUint32(head, snapshotFlagGzip)
+			_, err := LoadSnapshot(io.MultiReader(bytes.NewReader(head), strings.NewReader(hostileDataURL)))
+			return err
+		},
+		"ErrLegacySnapshot": func() error {
+			_, err := LoadSnapshot(strings.NewReader(`{"icon": "` + hostileDataURL + `"}`))
+			return err
+		},
+		"ErrInvalidAAID": func() error {
+			_, err := ParseAAID(hostileDataURL)
+			return err
+		},
+		"ErrIncompleteUAFEntry": func() error { return ValidateUAFEntry(uaf) },
+	}
+}
+
+// fetchRejected fills the queue of a coordinator with one request in flight and one waiting, and
+// returns the error of a third request to a hostile URL.
+func fetchRejected(t *testing.T) error {
+	release, queued := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	c := NewFetchCoordinator(
+		WithFetchConcurrency(1),
+		WithFetchQueueLimit(1),
+		WithFetchHostInterval(0),
+		WithFetchTransport(roundTripFunc(func(*http.Request) (*http.Response, error) {
+			<-release
+			return okResponse("[]"), nil
+		})),
+		WithFetchObserver(func(ev FetchEvent) {
+			if ev.Kind == FetchQueued {
+				once.Do(func() { close(queued) })
+			}
+		}),
+	)
+	defer close(release)
+	rt := c.Client(nil).Transport
+	for _, path := range []string{"/first", "/second"} {
+		go func() {
+			req, _ := http.NewRequest(http.MethodPost, "https://rogue.example"+path, nil)
+			if resp, err := rt.RoundTrip(req); err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	<-queued
+	req, err := http.NewRequest(http.MethodPost, "https://rogue.example/"+hostileDataURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rt.RoundTrip(req)
+	return err
+}
+
+// rogueListError returns the IsRogue error for a rogue list at a hostile URL after two failed
+// refreshes. With stale the first fetch succeeds instead and the list is judged after the
+// staleness bound; with hash set every fetch serves a list that does not match that rogueListHash.
+func rogueListError(t *testing.T, stale bool, hash string) error {
+	e := hostileEntry()
+	e.RogueListURL, e.RogueListHash = "https://rogue.example/"+hostileDataURL, hash
+	var fetches atomic.Int32
+	client := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		if fetches.Add(1) == 1 && stale {
+			return okResponse("[]"), nil
+		}
+		return nil, errors.New(hostileText)
+	})}
+	if hash != "" {
+		client.Transport = roundTripFunc(func(*http.Request) (*http.Response, error) { return okResponse(hostileDataURL), nil })
+	}
+	m := NewRogueListManager(testProvider(t, e), WithRogueListClient(client))
+	for range 2 {
+		m.Refresh(context.Background())
+	}
+	if stale {
+		m.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+	}
+	_, err := m.IsRogue(testYubiKey, hostileText)
+	return err
+}
+
+// exportedSentinels returns the names of the package's exported errors.New variables.
+func exportedSentinels(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			spec, ok := n.(*ast.ValueSpec)
+			if !ok {
+				return true
+			}
+			for i, id := range spec.Names {
+				if !id.IsExported() || i >= len(spec.Values) {
+					continue
+				}
+				if call, ok := spec.Values[i].(*ast.CallExpr); ok && fmt.Sprint(call.Fun) == "&{errors New}" {
+					names = append(names, id.Name)
+				}
+			}
+			return false
+		})
+	}
+	return names
+}
+
+// errorParts splits an errors.Join result into the errors it joins, which are logged as separate
+// lines; any other error is one part.
+func errorParts(err error) []error {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		parts := j.Unwrap()
+		msgs := make([]string, len(parts))
+		for i, p := range parts {
+			msgs[i] = p.Error()
+		}
+		if strings.Join(msgs, "\n") == err.Error() {
+			return parts
+		}
+	}
+	return []error{err}
+}
+
+func TestErrorsBoundHostileInput(t *testing.T) {
+	cases := errorCases(t)
+	sentinels := exportedSentinels(t)
+	if len(sentinels) == 0 {
+		t.Fatal("found no sentinel errors")
+	}
+	for _, name := range sentinels {
+		if _, ok := cases[name]; !ok {
+			t.Errorf("%s has no case in errorCases", name)
+		}
+		if _, ok := sentinelByName[name]; !ok {
+			t.Errorf("%s is missing from sentinelByName", name)
+		}
+	}
+	for name, build := range cases {
+		t.Run(name, func(t *testing.T) {
+			sentinel, ok := sentinelByName[name]
+			if !ok {
+				t.Fatalf("%s is not an exported sentinel error", name)
+			}
+			err := build()
+			if !errors.Is(err, sentinel) {
+				t.Fatalf("error %.120q does not wrap %s", err, name)
+			}
+			for _, part := range errorParts(err) {
+				msg := part.Error()
+				if len(msg) > entryErrorLimit {
+					t.Errorf("message of %d bytes, want at most %d: %.120q", len(msg), entryErrorLimit, msg)
+				}
+				if strings.Contains(msg, "data:image") {
+					t.Errorf("message quotes a data: URL: %.120q", msg)
+				}
+			}
+		})
+	}
+}
+
+// sentinelByName maps the name of every exported sentinel error to its value.
+var sentinelByName = map[string]error{
+	"ErrAAGUIDMismatch":            ErrAAGUIDMismatch,
+	"ErrInvalidAttestationObject":  ErrInvalidAttestationObject,
+	"ErrAuthDataTruncated":         ErrAuthDataTruncated,
+	"ErrNoAttestedCredentialData":  ErrNoAttestedCredentialData,
+	"ErrUnknownModality":           ErrUnknownModality,
+	"ErrSignatureInvalid":          ErrSignatureInvalid,
+	"ErrVersionAbsent":             ErrVersionAbsent,
+	"ErrVersionMalformed":          ErrVersionMalformed,
+	"ErrFetchRejected":             ErrFetchRejected,
+	"ErrGetInfoAAGUIDMismatch":     ErrGetInfoAAGUIDMismatch,
+	"ErrNoIcon":                    ErrNoIcon,
+	"ErrInvalidISO8601Date":        ErrInvalidISO8601Date,
+	"ErrEntryMismatch":             ErrEntryMismatch,
+	"ErrAlgorithmNotListed":        ErrAlgorithmNotListed,
+	"ErrAttestationTypeNotAllowed": ErrAttestationTypeNotAllowed,
+	"ErrBatchCompromised":          ErrBatchCompromised,
+	"ErrUnknownAAGUID":             ErrUnknownAAGUID,
+	"ErrZeroAAGUID":                ErrZeroAAGUID,
+	"ErrInvalidAAGUID":             ErrInvalidAAGUID,
+	"ErrRogueListUnavailable":      ErrRogueListUnavailable,
+	"ErrRogueListStale":            ErrRogueListStale,
+	"ErrRogueListHash":             ErrRogueListHash,
+	"ErrNoRootMatched":             ErrNoRootMatched,
+	"ErrRootExpired":               ErrRootExpired,
+	"ErrSelfTestFailed":            ErrSelfTestFailed,
+	"ErrInvalidSnapshot":           ErrInvalidSnapshot,
+	"ErrUnsupportedSnapshot":       ErrUnsupportedSnapshot,
+	"ErrLegacySnapshot":            ErrLegacySnapshot,
+	"ErrInvalidAAID":               ErrInvalidAAID,
+	"ErrIncompleteUAFEntry":        ErrIncompleteUAFEntry,
+}
@@ -0,0 +1,100 @@
+package aaguids
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// SourceEmbeddedMinimal is the DatasetSource name of the Provider returned by FromEmbeddedMinimal.
+const SourceEmbeddedMinimal = "embedded-minimal"
+
+/*
+FromEmbeddedMinimal returns a Provider serving the minimal fallback dataset embedded in every
+generated package: the AAGUIDs listed in the generator's reviewed minimal-aaguids.json, reduced to
+what status checks need. Entries keep their description, community display name, status reports
+and timeOfLastStatusChange; icons, attestation roots, getInfo and the rest of the metadata
+statement are dropped, so lookups succeed but attestation verification and icon rendering do not.
+
+Its DatasetInfo lists SourceEmbeddedMinimal as the only source, and Health reports it as degraded.
+*/
+func FromEmbeddedMinimal(opts ...ProviderOption) *Provider {
+	entries := minimalMetadata
+	if entries == nil {
+		entries = map[string]Entry{}
+	}
+	info := minimalDatasetInfo
+	info.Sources = []DatasetSource{{Name: SourceEmbeddedMinimal}}
+	info.EntryCount = len(entries)
+	p := &Provider{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.update(entries, info, nil, nil, OriginEmbedded)
+	return p
+}
+
+// ProviderSource loads a Provider for FirstAvailable, e.g. from a file or a remote store.
+type ProviderSource func() (*Provider, error)
+
+// PrunedFileSource returns a ProviderSource reading a SavePruned file with LoadPruned.
+func PrunedFileSource(path string, opts ...ProviderOption) ProviderSource {
+	return func() (*Provider, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("opening dataset: %w", err)
+		}
+		defer f.Close()
+		return LoadPruned(f, opts...)
+	}
+}
+
+// SnapshotFileSource returns a ProviderSource reading a SaveSnapshot file with LoadSnapshot.
+func SnapshotFileSource(path string, opts ...ProviderOption) ProviderSource {
+	return func() (*Provider, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("opening dataset: %w", err)
+		}
+		defer f.Close()
+		return LoadSnapshot(f, opts...)
+	}
+}
+
+// EmbeddedMinimalSource returns a ProviderSource for FromEmbeddedMinimal, which never fails.
+func EmbeddedMinimalSource(opts ...ProviderOption) ProviderSource {
+	return func() (*Provider, error) { return FromEmbeddedMinimal(opts...), nil }
+}
+
+/*
+FirstAvailable tries sources in order and returns the Provider of the first that loads, so a
+service can still start when its usual dataset is unreachable:
+
+	p, err := aaguids.FirstAvailable(
+		aaguids.PrunedFileSource("/var/lib/aaguids/dataset.gz"),
+		aaguids.EmbeddedMinimalSource(),
+	)
+
+The errors of the sources tried before are kept and reported by Health. A source returning a nil
+Provider without an error is skipped. If every source fails, the joined errors are returned; end
+the chain with EmbeddedMinimalSource to always get a Provider.
+*/
+func FirstAvailable(sources ...ProviderSource) (*Provider, error) {
+	var errs []error
+	for i, src := range sources {
+		p, err := src()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("dataset source %d: %w", i, err))
+			continue
+		}
+		if p == nil {
+			continue
+		}
+		p.fallbackErr = errors.Join(errs...)
+		return p, nil
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no dataset source available")
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,349 @@
+package aaguids
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrFetchRejected is returned by a FetchCoordinator's client when a request finds the queue full.
+var ErrFetchRejected = errors.New("aaguids: fetch rejected, coordinator queue is full")
+
+// defaultFetchBodyLimit bounds the response bodies a FetchCoordinator buffers for deduplication.
+const defaultFetchBodyLimit = 64 << 20
+
+// FetchEventKind names what a FetchEvent reports.
+type FetchEventKind string
+
+const (
+	FetchQueued   FetchEventKind = "queued"   // the request waits for a concurrency slot or its host
+	FetchDeduped  FetchEventKind = "deduped"  // the request joined an identical one in flight
+	FetchRejected FetchEventKind = "rejected" // the request was refused (ErrFetchRejected)
+	FetchDone     FetchEventKind = "done"     // the request reached the network and returned
+)
+
+/*
+FetchEvent is passed to the observer of a FetchCoordinator (see WithFetchObserver):
+
+  - Kind: what happened
+  - Method / URL / Host: the request
+  - Wait: for FetchDone, how long the request waited before it was sent
+  - Err: for FetchDone, the transport error, if any
+*/
+type FetchEvent struct {
+	Kind   FetchEventKind
+	Method string
+	URL    string
+	Host   string
+	Wait   time.Duration
+	Err    error
+}
+
+// FetchOption configures a FetchCoordinator.
+type FetchOption func(*FetchCoordinator)
+
+// WithFetchTransport sets the transport requests are finally sent with (default
+// http.DefaultTransport).
+func WithFetchTransport(rt http.RoundTripper) FetchOption {
+	return func(c *FetchCoordinator) { c.transport = rt }
+}
+
+// WithFetchConcurrency caps the number of requests in flight across all hosts (default 8).
+func WithFetchConcurrency(n int) FetchOption {
+	return func(c *FetchCoordinator) { c.concurrency = max(n, 1) }
+}
+
+// WithFetchHostInterval sets the minimum spacing between two requests to the same host (default
+// 500ms; 0 disables per-host limiting).
+func WithFetchHostInterval(d time.Duration) FetchOption {
+	return func(c *FetchCoordinator) { c.hostInterval = max(d, 0) }
+}
+
+// WithFetchBackoff sets how long a host is left alone after a failed request, a transport error or
+// a 429 or 5xx response: initial after the first failure, doubled for each consecutive one up to
+// limit, and reset by a success (default 1s up to 1m; an initial of 0 disables backoff).
+func WithFetchBackoff(initial, limit time.Duration) FetchOption {
+	return func(c *FetchCoordinator) { c.backoffInitial, c.backoffLimit = max(initial, 0), max(limit, initial) }
+}
+
+// WithFetchQueueLimit sets how many requests may wait at once before new ones are rejected with
+// ErrFetchRejected (default 0, unbounded).
+func WithFetchQueueLimit(n int) FetchOption {
+	return func(c *FetchCoordinator) { c.queueLimit = max(n, 0) }
+}
+
+// WithFetchBodyLimit bounds the response bodies buffered for deduplicated requests (default 64
+// MiB); a longer body fails the request.
+func WithFetchBodyLimit(n int64) FetchOption {
+	return func(c *FetchCoordinator) { c.bodyLimit = n }
+}
+
+// WithFetchObserver sets a function called for every FetchEvent, e.g. to feed metrics. It is
+// called synchronously and must not block.
+func WithFetchObserver(f func(FetchEvent)) FetchOption {
+	return func(c *FetchCoordinator) { c.observe = f }
+}
+
+/*
+FetchCoordinator is shared by the outbound HTTP of the package, so that the RogueListManager, the
+URL auditor and the caller's own fetches do not independently hammer the same hosts. All requests
+go through the http.Client returned by Client, which:
+
+  - caps the number of requests in flight across all hosts (WithFetchConcurrency)
+  - spaces requests to the same host (WithFetchHostInterval), and backs off from a host whose
+    requests fail (WithFetchBackoff)
+  - deduplicates identical GET and HEAD requests in flight: callers asking for the same URL share
+    one request, and each gets its own copy of the buffered response
+  - rejects requests beyond the queue limit with ErrFetchRejected (WithFetchQueueLimit)
+  - reports queued, deduplicated, rejected and completed requests to the observer
+
+Configure it on a Provider with WithFetchCoordinator; without one, every component uses its own
+client directly, as before. A FetchCoordinator is safe for concurrent use.
+*/
+type FetchCoordinator struct {
+	transport      http.RoundTripper
+	concurrency    int
+	hostInterval   time.Duration
+	backoffInitial time.Duration
+	backoffLimit   time.Duration
+	queueLimit     int
+	bodyLimit      int64
+	observe        func(FetchEvent)
+
+	sem chan struct{}
+
+	mu       sync.Mutex
+	queued   int
+	hostNext map[string]time.Time       // host → earliest start of its next request
+	backoff  map[string]time.Duration   // host → backoff after its consecutive failures
+	inflight map[string]*coalescedFetch // method + URL → shared request
+}
+
+// coalescedFetch is one request in flight, shared by every caller asking for the same URL.
+type coalescedFetch struct {
+	done    chan struct{}
+	waiters int
+	cancel  context.CancelFunc
+
+	resp *http.Response // body already read into body
+	body []byte
+	err  error
+}
+
+// NewFetchCoordinator returns a coordinator configured with opts.
+func NewFetchCoordinator(opts ...FetchOption) *FetchCoordinator {
+	c := &FetchCoordinator{
+		transport:      http.DefaultTransport,
+		concurrency:    8,
+		hostInterval:   500 * time.Millisecond,
+		backoffInitial: time.Second,
+		backoffLimit:   time.Minute,
+		bodyLimit:      defaultFetchBodyLimit,
+		hostNext:       make(map[string]time.Time),
+		backoff:        make(map[string]time.Duration),
+		inflight:       make(map[string]*coalescedFetch),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.sem = make(chan struct{}, c.concurrency)
+	return c
+}
+
+// WithFetchCoordinator routes the Provider's outbound requests (rogue lists, URL audits) through c.
+func WithFetchCoordinator(c *FetchCoordinator) ProviderOption {
+	return func(p *Provider) { p.fetch = c }
+}
+
+/*
+Client returns an http.Client whose requests go through c. It keeps base's redirect policy,
+cookie jar and timeout (base may be nil); base's own Transport is not used, since requests are
+sent with the coordinator's.
+*/
+func (c *FetchCoordinator) Client(base *http.Client) *http.Client {
+	out := &http.Client{Transport: coordinatedTransport{c}}
+	if base != nil {
+		out.CheckRedirect, out.Jar, out.Timeout = base.CheckRedirect, base.Jar, base.Timeout
+	}
+	return out
+}
+
+// fetchClient returns the client the Provider's components send requests with: client (or
+// http.DefaultClient if nil), routed through the Provider's FetchCoordinator when one is set.
+func (p *Provider) fetchClient(client *http.Client) *http.Client {
+	if p.fetch != nil {
+		return p.fetch.Client(client)
+	}
+	if client == nil {
+		return http.DefaultClient
+	}
+	return client
+}
+
+// coordinatedTransport is the RoundTripper of the clients returned by FetchCoordinator.Client.
+type coordinatedTransport struct{ c *FetchCoordinator }
+
+func (t coordinatedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.c.roundTrip(req)
+}
+
+// roundTrip sends req, joining an identical request in flight when it can.
+func (c *FetchCoordinator) roundTrip(req *http.Request) (*http.Response, error) {
+	if !dedupable(req) {
+		return c.send(req)
+	}
+	key := req.Method + " " + req.URL.String()
+	c.mu.Lock()
+	f, joined := c.inflight[key]
+	if !joined {
+		ctx, cancel := context.WithCancel(context.WithoutCancel(req.Context()))
+		f = &coalescedFetch{done: make(chan struct{}), cancel: cancel}
+		c.inflight[key] = f
+		go c.lead(key, f, req.Clone(ctx))
+	}
+	f.waiters++
+	c.mu.Unlock()
+	if joined {
+		c.emit(FetchEvent{Kind: FetchDeduped, Method: req.Method, URL: req.URL.String(), Host: req.URL.Host})
+	}
+
+	select {
+	case <-f.done:
+	case <-req.Context().Done():
+		c.mu.Lock()
+		if f.waiters--; f.waiters == 0 {
+			f.cancel()
+			if c.inflight[key] == f {
+				delete(c.inflight, key)
+			}
+		}
+		c.mu.Unlock()
+		return nil, req.Context().Err()
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	resp := *f.resp
+	resp.Header = f.resp.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(f.body))
+	resp.Request = req
+	return &resp, nil
+}
+
+// lead sends the shared request of f and buffers its response for every waiter.
+func (c *FetchCoordinator) lead(key string, f *coalescedFetch, req *http.Request) {
+	defer f.cancel()
+	resp, err := c.send(req)
+	if err == nil {
+		f.body, err = io.ReadAll(io.LimitReader(resp.Body, c.bodyLimit+1))
+		resp.Body.Close()
+		if err == nil && int64(len(f.body)) > c.bodyLimit {
+			err = boundedErrorf("fetching %s: response body exceeds %d bytes", req.URL, c.bodyLimit)
+		}
+	}
+	f.resp, f.err = resp, err
+	c.mu.Lock()
+	if c.inflight[key] == f {
+		delete(c.inflight, key)
+	}
+	c.mu.Unlock()
+	close(f.done)
+}
+
+// send waits for a concurrency slot and for req's host, then sends req with the transport and
+// records the outcome for the host's backoff.
+func (c *FetchCoordinator) send(req *http.Request) (*http.Response, error) {
+	ctx, host := req.Context(), req.URL.Host
+	start := time.Now()
+
+	c.mu.Lock()
+	if c.queueLimit > 0 && c.queued >= c.queueLimit {
+		c.mu.Unlock()
+		c.emit(FetchEvent{Kind: FetchRejected, Method: req.Method, URL: req.URL.String(), Host: host})
+		return nil, boundedErrorf("%w: %s %s", ErrFetchRejected, req.Method, req.URL)
+	}
+	c.queued++
+	c.mu.Unlock()
+	dequeue := sync.OnceFunc(func() {
+		c.mu.Lock()
+		c.queued--
+		c.mu.Unlock()
+	})
+	defer dequeue()
+
+	select {
+	case c.sem <- struct{}{}:
+	default:
+		c.emit(FetchEvent{Kind: FetchQueued, Method: req.Method, URL: req.URL.String(), Host: host})
+		select {
+		case c.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	defer func() { <-c.sem }()
+
+	now := time.Now()
+	c.mu.Lock()
+	at := c.hostNext[host]
+	if at.Before(now) {
+		at = now
+	}
+	if c.hostInterval > 0 {
+		c.hostNext[host] = at.Add(c.hostInterval)
+	}
+	c.mu.Unlock()
+	if wait := at.Sub(now); wait > 0 {
+		c.emit(FetchEvent{Kind: FetchQueued, Method: req.Method, URL: req.URL.String(), Host: host, Wait: wait})
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	dequeue()
+
+	wait := time.Since(start)
+	resp, err := c.transport.RoundTrip(req)
+	c.recordOutcome(host, err == nil && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests)
+	c.emit(FetchEvent{Kind: FetchDone, Method: req.Method, URL: req.URL.String(), Host: host, Wait: wait, Err: err})
+	return resp, err
+}
+
+// recordOutcome updates the backoff of host after a request: a failure delays the host's next
+// request by the backoff, doubled for each consecutive failure (see WithFetchBackoff), and a
+// success resets it.
+func (c *FetchCoordinator) recordOutcome(host string, ok bool) {
+	if c.backoffInitial <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ok {
+		delete(c.backoff, host)
+		return
+	}
+	b := min(max(2*c.backoff[host], c.backoffInitial), c.backoffLimit)
+	c.backoff[host] = b
+	if next := time.Now().Add(b); next.After(c.hostNext[host]) {
+		c.hostNext[host] = next
+	}
+}
+
+// emit reports ev to the observer, if any.
+func (c *FetchCoordinator) emit(ev FetchEvent) {
+	if c.observe != nil {
+		c.observe(ev)
+	}
+}
+
+// dedupable reports whether req can share a response with identical requests: a GET or HEAD
+// without a body.
+func dedupable(req *http.Request) bool {
+	return (req.Method == "" || req.Method == http.MethodGet || req.Method == http.MethodHead) &&
+		(req.Body == nil || req.Body == http.NoBody)
+}
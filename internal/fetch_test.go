@@ -0,0 +1,125 @@
+package aaguids
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchCoordinatorSharesConcurrentFetches(t *testing.T) {
+	const callers = 8
+	var hits atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, "blob")
+	}))
+	defer srv.Close()
+
+	var deduped sync.WaitGroup
+	deduped.Add(callers - 1)
+	c := NewFetchCoordinator(WithFetchHostInterval(0), WithFetchObserver(func(ev FetchEvent) {
+		if ev.Kind == FetchDeduped {
+			deduped.Done()
+		}
+	}))
+	client := c.Client(nil)
+
+	type result struct {
+		body string
+		etag string
+		err  error
+	}
+	results := make(chan result, callers)
+	for range callers {
+		go func() {
+			resp, err := client.Get(srv.URL + "/blob")
+			if err != nil {
+				results <- result{err: err}
+				return
+			}
+			defer resp.Body.Close()
+			b, err := io.ReadAll(resp.Body)
+			results <- result{string(b), resp.Header.Get("ETag"), err}
+		}()
+	}
+	// Every caller but the first joins its request before the server answers.
+	deduped.Wait()
+	close(release)
+	for range callers {
+		r := <-results
+		if r.err != nil || r.body != "blob" || r.etag != `"v1"` {
+			t.Errorf("caller got %q, ETag %q, %v; want the shared response", r.body, r.etag, r.err)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("%d callers made %d requests, want 1", callers, n)
+	}
+
+	// Once the shared request is done, the next caller fetches again.
+	resp, err := client.Get(srv.URL + "/blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := hits.Load(); n != 2 {
+		t.Errorf("%d requests after a later fetch, want 2", n)
+	}
+}
+
+func TestFetchCoordinatorBacksOffAfterFailure(t *testing.T) {
+	const initial, limit = 40 * time.Millisecond, 100 * time.Millisecond
+	// The server fails three times, then succeeds.
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var waits []time.Duration
+	c := NewFetchCoordinator(WithFetchHostInterval(0), WithFetchBackoff(initial, limit), WithFetchObserver(func(ev FetchEvent) {
+		if ev.Kind == FetchQueued {
+			mu.Lock()
+			waits = append(waits, ev.Wait)
+			mu.Unlock()
+		}
+	}))
+	client := c.Client(nil)
+
+	// want is the backoff each request waits for: none before the first failure, then doubling up
+	// to the limit, and none again after the success.
+	for i, want := range []time.Duration{0, initial, 2 * initial, limit, 0} {
+		mu.Lock()
+		waits = nil
+		mu.Unlock()
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+
+		mu.Lock()
+		got := waits
+		mu.Unlock()
+		switch {
+		case want == 0 && len(got) != 0:
+			t.Errorf("request %d waited %v, want no backoff", i, got)
+		case want > 0 && (len(got) != 1 || got[0] > want || got[0] < want/2):
+			t.Errorf("request %d waited %v, want a backoff of about %s", i, got, want)
+		}
+	}
+	if n := hits.Load(); n != 5 {
+		t.Errorf("%d requests reached the server, want 5", n)
+	}
+}
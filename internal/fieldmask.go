@@ -0,0 +1,171 @@
+package aaguids
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+/*
+FieldMask shapes the JSON encoding of entries served to clients, by JSON field path of Entry (e.g.
+"metadataStatement.attestationRootCertificates"). Paths through arrays apply to every element,
+so "statusReports.status" keeps the status of each report.
+
+  - Include: the paths to keep, with everything beneath them; nil keeps every field
+  - Exclude: the paths to drop, applied after Include
+
+Masking works on the encoded form: the entry is encoded, the mask is applied to the encoding and
+the result re-encoded, so shared Entry values are never modified.
+*/
+type FieldMask struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+var (
+	// MaskInternal keeps every field, for trusted internal clients.
+	MaskInternal = FieldMask{}
+
+	// MaskPublic keeps what a public client needs to present an authenticator: its identifiers,
+	// name, icons and status (including certification levels). Attestation roots, certificates,
+	// rogue lists, status URLs and legal headers are dropped.
+	MaskPublic = FieldMask{Include: []string{
+		"aaguid",
+		"attestationCertificateKeyIdentifiers",
+		"metadataStatement.Description",
+		"metadataStatement.alternativeDescriptions",
+		"metadataStatement.protocolFamily",
+		"metadataStatement.icon",
+		"communityExtensions",
+		"statusReports.status",
+		"statusReports.effectiveDate",
+		"timeOfLastStatusChange",
+	}}
+)
+
+// Validate reports paths of m that do not name a JSON field of Entry, which would otherwise fail
+// silently (an Exclude typo exposes the field it was meant to drop).
+func (m FieldMask) Validate() error {
+	for _, p := range append(append([]string(nil), m.Include...), m.Exclude...) {
+		if !validFieldPath(reflect.TypeFor[Entry](), strings.Split(p, ".")) {
+			return fmt.Errorf("aaguids: field mask path %q does not name an Entry field", p)
+		}
+	}
+	return nil
+}
+
+// MarshalEntry returns the JSON encoding of e shaped by m.
+func (m FieldMask) MarshalEntry(e Entry) ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return nil, entryErrorf(e, "encoding entry: %w", err)
+	}
+	if m.Include == nil && m.Exclude == nil {
+		return raw, nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, entryErrorf(e, "decoding entry: %w", err)
+	}
+	if m.Include != nil {
+		v = keepPaths(v, pathTree(m.Include))
+	}
+	dropPaths(v, pathTree(m.Exclude))
+	return json.Marshal(v)
+}
+
+// fieldTree is a set of field paths split into segments; a nil subtree marks the end of a path.
+type fieldTree map[string]fieldTree
+
+func pathTree(paths []string) fieldTree {
+	t := make(fieldTree)
+	for _, p := range paths {
+		node := t
+		segs := strings.Split(p, ".")
+		for i, s := range segs {
+			next, ok := node[s]
+			if ok && next == nil {
+				break // a shorter path already covers this one
+			}
+			if i == len(segs)-1 {
+				node[s] = nil
+				break
+			}
+			if next == nil {
+				next = make(fieldTree)
+				node[s] = next
+			}
+			node = next
+		}
+	}
+	return t
+}
+
+// keepPaths returns v reduced to the paths in t.
+func keepPaths(v any, t fieldTree) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, sub := range t {
+			val, ok := v[k]
+			if !ok {
+				continue
+			}
+			if sub == nil {
+				out[k] = val
+			} else {
+				out[k] = keepPaths(val, sub)
+			}
+		}
+		return out
+	case []any:
+		for i := range v {
+			v[i] = keepPaths(v[i], t)
+		}
+		return v
+	}
+	return nil
+}
+
+// dropPaths deletes the paths in t from v in place.
+func dropPaths(v any, t fieldTree) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, sub := range t {
+			if sub == nil {
+				delete(v, k)
+			} else if val, ok := v[k]; ok {
+				dropPaths(val, sub)
+			}
+		}
+	case []any:
+		for _, elem := range v {
+			dropPaths(elem, t)
+		}
+	}
+}
+
+// validFieldPath reports whether segs names a JSON field path of t. Everything beneath a map is
+// accepted, since map keys are data rather than fields.
+func validFieldPath(t reflect.Type, segs []string) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if len(segs) == 0 || t.Kind() == reflect.Map {
+		return true
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() && jsonFieldName(f) == segs[0] {
+			return validFieldPath(f.Type, segs[1:])
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package aaguids
+
+import "slices"
+
+/*
+The Filter combinators below compose queries for Where and the Entries* helpers, e.g. "FIDO2,
+pinUvAuthProtocol 2, certified L1 or better":
+
+	aaguids.And(
+		aaguids.ProtocolFamilyIs("fido2"),
+		aaguids.SupportsPinUvAuthProtocol(2),
+		aaguids.CertifiedAtLeast(aaguids.FIDO_CERTIFIED_L1),
+	)
+
+A nil Filter passed to a combinator matches every entry, as it does for Where.
+*/
+
+// And matches the entries matched by every filter. And() matches every entry.
+func And(filters ...Filter) Filter {
+	return func(e Entry) bool {
+		for _, f := range filters {
+			if f != nil && !f(e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches the entries matched by at least one filter. Or() matches nothing.
+func Or(filters ...Filter) Filter {
+	return func(e Entry) bool {
+		for _, f := range filters {
+			if f == nil || f(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches the entries f does not match.
+func Not(f Filter) Filter {
+	return func(e Entry) bool {
+		return f != nil && !f(e)
+	}
+}
+
+// ProtocolFamilyIs matches entries whose statement declares the protocol family ("fido2", "u2f"
+// or "uaf").
+func ProtocolFamilyIs(family string) Filter {
+	return func(e Entry) bool {
+		return e.MetadataStatement.ProtocolFamily == family
+	}
+}
+
+// CertifiedAtLeast matches entries whose latest FIDO_CERTIFIED* status report, in timeline order,
+// is level or a higher one. Entries that were never certified do not match.
+func CertifiedAtLeast(level AuthenticatorStatus) Filter {
+	return func(e Entry) bool {
+		r, ok := e.latestCertification()
+		return ok && certificationRank(r.Status) >= certificationRank(level)
+	}
+}
+
+// SupportsPinUvAuthProtocol matches entries whose getInfo lists the pinUvAuthProtocol version.
+// Entries with unknown protocols (see Entry.PinUvAuthProtocols) do not match.
+func SupportsPinUvAuthProtocol(version uint) Filter {
+	return func(e Entry) bool {
+		return slices.Contains(e.PinUvAuthProtocols(), version)
+	}
+}
@@ -0,0 +1,75 @@
+package aaguids
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// rootFingerprints returns the SHA-256 fingerprints of the statement's attestation root
+// certificates, computed over their DER encoding. Roots that are not valid base64 are skipped.
+func (m MetadataStatement) rootFingerprints() [][32]byte {
+	var out [][32]byte
+	for _, c := range m.AttestationRootCertificates {
+		der, err := base64.StdEncoding.DecodeString(c)
+		if err != nil {
+			continue
+		}
+		out = append(out, sha256.Sum256(der))
+	}
+	return out
+}
+
+// rootFingerprintIndex maps every attestation root fingerprint to the sorted keys of the entries
+// trusting that root. A root shared by many entries maps to all of them.
+func rootFingerprintIndex(entries map[string]Entry, keys []string) map[[32]byte][]string {
+	idx := make(map[[32]byte][]string)
+	for _, k := range keys {
+		for _, fp := range entries[k].MetadataStatement.rootFingerprints() {
+			if ks := idx[fp]; len(ks) == 0 || ks[len(ks)-1] != k {
+				idx[fp] = append(ks, k)
+			}
+		}
+	}
+	return idx
+}
+
+// FindEntriesByRootFingerprint returns the entries of the embedded dataset that list an attestation
+// root whose DER encoding has the given SHA-256 digest, in ascending key order.
+func FindEntriesByRootFingerprint(sha256 [32]byte) []Entry {
+	return Default().FindEntriesByRootFingerprint(sha256)
+}
+
+/*
+FindEntriesByRootFingerprint returns the entries in the current snapshot that list an attestation
+root whose DER encoding has the given SHA-256 digest, in ascending key order. The index is built
+when the snapshot is loaded, so lookups do not parse any certificates. It returns nil if no entry
+trusts such a root.
+*/
+func (p *Provider) FindEntriesByRootFingerprint(sha256 [32]byte) []Entry {
+	s := p.current()
+	keys := s.rootFingerprints[sha256]
+	if len(keys) == 0 {
+		return nil
+	}
+	out := make([]Entry, len(keys))
+	for i, k := range keys {
+		out[i] = s.entries[k]
+	}
+	return out
+}
+
+// RootFingerprints returns the SHA-256 fingerprints of the attestation roots of the embedded entry
+// identified by aaGuid, in statement order.
+func RootFingerprints(aaGuid string) [][32]byte {
+	return Default().RootFingerprints(aaGuid)
+}
+
+// RootFingerprints returns the SHA-256 fingerprints of the attestation roots of the entry
+// identified by aaGuid, in statement order. It returns nil if the entry is unknown or lists no roots.
+func (p *Provider) RootFingerprints(aaGuid string) [][32]byte {
+	e, ok := p.GetEntry(aaGuid)
+	if !ok {
+		return nil
+	}
+	return e.MetadataStatement.rootFingerprints()
+}
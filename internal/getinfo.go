@@ -0,0 +1,208 @@
+package aaguids
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+/*
+OptionState is the tri-state value of a CTAP2 option in authenticatorGetInfo. For most options an
+absent ID means the authenticator lacks the feature, while false means it has the feature but it
+is not currently enabled or configured (CTAP 2.1 § 6.4, "options"). For example, for "clientPin":
+
+  - OptionAbsent: no PIN support
+  - OptionFalse: PIN supported but not yet set
+  - OptionTrue: PIN supported and set
+*/
+type OptionState int
+
+const (
+	OptionAbsent OptionState = iota
+	OptionFalse
+	OptionTrue
+)
+
+// String returns "absent", "false" or "true".
+func (o OptionState) String() string {
+	switch o {
+	case OptionFalse:
+		return "false"
+	case OptionTrue:
+		return "true"
+	}
+	return "absent"
+}
+
+// Option returns the tri-state value of the option id, e.g. "clientPin", "uv", "ep".
+func (g AuthenticatorGetInfo) Option(id string) OptionState {
+	v, ok := g.Options[id]
+	switch {
+	case !ok:
+		return OptionAbsent
+	case v:
+		return OptionTrue
+	}
+	return OptionFalse
+}
+
+// GetInfo returns the statement's authenticatorGetInfo, or false if the entry carries none (e.g.
+// UAF and U2F entries, and community-list entries).
+func (e Entry) GetInfo() (AuthenticatorGetInfo, bool) {
+	if e.MetadataStatement.AuthenticatorGetInfo == nil {
+		return AuthenticatorGetInfo{}, false
+	}
+	return *e.MetadataStatement.AuthenticatorGetInfo, true
+}
+
+// The capability helpers below return (supported, known): known is false when the entry has no
+// authenticatorGetInfo, in which case supported is always false and says nothing about the device.
+
+// SupportsCTAP21 reports whether "FIDO_2_1" is among the getInfo versions. The "FIDO_2_1_PRE"
+// preview does not count, since it predates the final 2.1 semantics.
+func (e Entry) SupportsCTAP21() (supported, known bool) {
+	g, ok := e.GetInfo()
+	return ok && slices.Contains(g.Versions, "FIDO_2_1"), ok
+}
+
+// SupportsCredProtect reports whether the credProtect extension (CTAP 2.1 § 12.1) is listed.
+func (e Entry) SupportsCredProtect() (supported, known bool) {
+	g, ok := e.GetInfo()
+	return ok && slices.Contains(g.Extensions, "credProtect"), ok
+}
+
+/*
+SupportsEnterpriseAttestation reports whether the "ep" option is present. Present-but-false means
+the authenticator supports enterprise attestation but it is disabled until a platform enables it,
+which still counts as supported; an absent option means no support.
+*/
+func (e Entry) SupportsEnterpriseAttestation() (supported, known bool) {
+	g, ok := e.GetInfo()
+	return ok && g.Option("ep") != OptionAbsent, ok
+}
+
+// SupportsAlwaysUV reports whether the "alwaysUv" option is present. As with "ep", false means
+// the feature exists but is currently off.
+func (e Entry) SupportsAlwaysUV() (supported, known bool) {
+	g, ok := e.GetInfo()
+	return ok && g.Option("alwaysUv") != OptionAbsent, ok
+}
+
+/*
+MinPINLength returns the getInfo minPINLength. CTAP 2.1 specifies a default of 4 when the field is
+absent, but absence is reported as false so callers can tell "reported 4" from "not reported" and
+apply their own default.
+*/
+func (e Entry) MinPINLength() (int, bool) {
+	g, ok := e.GetInfo()
+	if !ok || g.MinPINLength == nil {
+		return 0, false
+	}
+	return int(*g.MinPINLength), true
+}
+
+/*
+PinUvAuthProtocols returns a copy of the getInfo pinUvAuthProtocols, in the authenticator's order
+of preference (e.g. [2, 1]). It returns nil when the protocols are unknown: the entry has no
+authenticatorGetInfo or the getInfo omits the field. A getInfo listing no protocols yields an
+empty, non-nil slice.
+*/
+func (e Entry) PinUvAuthProtocols() []uint {
+	g, ok := e.GetInfo()
+	if !ok || g.PinUvAuthProtocols == nil {
+		return nil
+	}
+	return append([]uint{}, g.PinUvAuthProtocols...)
+}
+
+// EntriesByPinUvAuthProtocol returns the embedded entries supporting the pinUvAuthProtocol
+// version. See Provider.EntriesByPinUvAuthProtocol.
+func EntriesByPinUvAuthProtocol(version uint) []Entry {
+	return Default().EntriesByPinUvAuthProtocol(version)
+}
+
+// EntriesByPinUvAuthProtocol returns the entries whose getInfo lists the pinUvAuthProtocol version,
+// in ascending AAGUID order. Entries with unknown protocols are never included; combine
+// SupportsPinUvAuthProtocol with Not and Where to find entries lacking a version.
+func (p *Provider) EntriesByPinUvAuthProtocol(version uint) []Entry {
+	return slices.Collect(p.Where(SupportsPinUvAuthProtocol(version)))
+}
+
+// ErrGetInfoAAGUIDMismatch is returned by ValidateGetInfoAAGUID when the authenticatorGetInfo
+// aaguid of a statement is malformed or names another AAGUID than the entry.
+var ErrGetInfoAAGUIDMismatch = errors.New("aaguids: authenticatorGetInfo aaguid does not match the entry")
+
+/*
+GetInfoAAGUID returns the aaguid of the statement's authenticatorGetInfo, which MDS publishes as 32
+hex characters without dashes (the dashed layout is accepted too). It reports false if the entry
+has no getInfo, the field is empty or malformed.
+
+The entry's AAGUID, not this value, is authoritative: it is the key MDS publishes the entry under
+and the one every lookup in this package uses. The getInfo copy is vendor-reported and has been
+seen to disagree with it in published metadata; see ValidateGetInfoAAGUID.
+*/
+func (e Entry) GetInfoAAGUID() (AAGUID, bool) {
+	g, ok := e.GetInfo()
+	if !ok || g.AAGUID == "" {
+		return AAGUID{}, false
+	}
+	id, err := parseGetInfoAAGUID(g.AAGUID)
+	return id, err == nil
+}
+
+// parseGetInfoAAGUID parses a getInfo aaguid, undashed or in the dashed layout.
+func parseGetInfoAAGUID(s string) (AAGUID, error) {
+	var id AAGUID
+	if len(s) == 32 {
+		if _, err := hex.Decode(id[:], []byte(s)); err != nil {
+			return AAGUID{}, fmt.Errorf("aaguid is not hex: %w", err)
+		}
+		return id, nil
+	}
+	return ParseAAGUID(s)
+}
+
+/*
+ValidateGetInfoAAGUID returns an error wrapping ErrGetInfoAAGUIDMismatch if e's
+authenticatorGetInfo aaguid is malformed or differs from the entry's AAGUID. Entries without an
+AAGUID, without getInfo or with an empty getInfo aaguid pass. ParseMetadataBLOB accepts mismatches,
+since they occur in real published metadata; the generator reports them as warnings.
+*/
+func ValidateGetInfoAAGUID(e Entry) error {
+	g, ok := e.GetInfo()
+	if !ok || g.AAGUID == "" || e.AAGUID == "" {
+		return nil
+	}
+	want, err := ParseAAGUID(e.AAGUID)
+	if err != nil {
+		return nil
+	}
+	got, err := parseGetInfoAAGUID(g.AAGUID)
+	if err != nil {
+		return boundedErrorf("%w: authenticatorGetInfo.aaguid %q: %v", ErrGetInfoAAGUIDMismatch, g.AAGUID, err)
+	}
+	if got != want {
+		return fmt.Errorf("%w: authenticatorGetInfo.aaguid is %s", ErrGetInfoAAGUIDMismatch, got)
+	}
+	return nil
+}
+
+// MismatchedGetInfoAAGUIDs returns the keys of embedded entries failing ValidateGetInfoAAGUID. See
+// Provider.MismatchedGetInfoAAGUIDs.
+func MismatchedGetInfoAAGUIDs() []string {
+	return Default().MismatchedGetInfoAAGUIDs()
+}
+
+// MismatchedGetInfoAAGUIDs returns the sorted keys of the entries whose authenticatorGetInfo aaguid
+// is malformed or differs from the entry's AAGUID (see ValidateGetInfoAAGUID).
+func (p *Provider) MismatchedGetInfoAAGUIDs() []string {
+	var out []string
+	s := p.current()
+	for _, k := range s.keys {
+		if ValidateGetInfoAAGUID(s.entries[k]) != nil {
+			out = append(out, canonicalKey(k))
+		}
+	}
+	return out
+}
@@ -0,0 +1,184 @@
+package aaguids
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+const (
+	testYubiKey54    = "cb69481e-8ff7-4039-93ec-0a2729a154a8"
+	testYubiKey57    = "a25342c0-3cdc-4414-8e46-f4807fca511c"
+	testWindowsHello = "08987058-cadc-4b81-b6e1-30de50dcbe96"
+)
+
+// getInfoEntries decodes testdata/getinfo-entries.json: the getInfo of a YubiKey 5 on firmware
+// 5.4 (CTAP 2.1 preview) and 5.7 (CTAP 2.1), of Windows Hello, and a community entry without one.
+func getInfoEntries(t *testing.T) map[string]Entry {
+	t.Helper()
+	var list []Entry
+	if err := json.Unmarshal(readTestdata(t, "getinfo-entries.json"), &list); err != nil {
+		t.Fatal(err)
+	}
+	m := make(map[string]Entry, len(list))
+	for _, e := range list {
+		m[e.AAGUID] = e
+	}
+	return m
+}
+
+// capability is a (supported, known) result of a capability helper.
+type capability struct{ supported, known bool }
+
+func TestGetInfoCapabilities(t *testing.T) {
+	entries := getInfoEntries(t)
+	tests := []struct {
+		aaguid         string
+		ctap21         capability
+		credProtect    capability
+		ep             capability
+		alwaysUV       capability
+		minPIN         int
+		minPINKnown    bool
+		protocols      []uint
+		supports       []uint // versions SupportsPinUvAuthProtocol matches, of 1 and 2
+		clientPin, uv  OptionState
+		getInfoMatches bool
+	}{
+		{
+			// CTAP 2.1 preview only: no final 2.1, PIN set, no UV, minPINLength not reported.
+			aaguid: testYubiKey54, ctap21: capability{false, true}, credProtect: capability{true, true},
+			ep: capability{false, true}, alwaysUV: capability{false, true},
+			protocols: []uint{1}, supports: []uint{1}, clientPin: OptionTrue, uv: OptionAbsent, getInfoMatches: true,
+		},
+		{
+			// alwaysUv and clientPin are present but false: supported, not enabled.
+			aaguid: testYubiKey57, ctap21: capability{true, true}, credProtect: capability{true, true},
+			ep: capability{false, true}, alwaysUV: capability{true, true}, minPIN: 4, minPINKnown: true,
+			protocols: []uint{2, 1}, supports: []uint{1, 2}, clientPin: OptionFalse, uv: OptionAbsent, getInfoMatches: true,
+		},
+		{
+			// A platform authenticator without PIN support, verifying the user itself.
+			aaguid: testWindowsHello, ctap21: capability{false, true}, credProtect: capability{false, true},
+			ep: capability{false, true}, alwaysUV: capability{false, true},
+			clientPin: OptionAbsent, uv: OptionTrue, getInfoMatches: true,
+		},
+		{
+			// No getInfo: every helper reports unknown rather than unsupported.
+			aaguid: testGPM, clientPin: OptionAbsent, uv: OptionAbsent,
+		},
+	}
+	for _, tt := range tests {
+		e, ok := entries[tt.aaguid]
+		if !ok {
+			t.Fatalf("fixture has no entry %s", tt.aaguid)
+		}
+		t.Run(e.DisplayName()+" "+tt.aaguid[:8], func(t *testing.T) {
+			check := func(name string, got, want capability) {
+				t.Helper()
+				if got != want {
+					t.Errorf("%s = %v, want %v", name, got, want)
+				}
+			}
+			var c capability
+			c.supported, c.known = e.SupportsCTAP21()
+			check("SupportsCTAP21", c, tt.ctap21)
+			c.supported, c.known = e.SupportsCredProtect()
+			check("SupportsCredProtect", c, tt.credProtect)
+			c.supported, c.known = e.SupportsEnterpriseAttestation()
+			check("SupportsEnterpriseAttestation", c, tt.ep)
+			c.supported, c.known = e.SupportsAlwaysUV()
+			check("SupportsAlwaysUV", c, tt.alwaysUV)
+
+			if n, known := e.MinPINLength(); n != tt.minPIN || known != tt.minPINKnown {
+				t.Errorf("MinPINLength() = %d, %v; want %d, %v", n, known, tt.minPIN, tt.minPINKnown)
+			}
+			if got := e.PinUvAuthProtocols(); !slices.Equal(got, tt.protocols) || (got == nil) != (tt.protocols == nil) {
+				t.Errorf("PinUvAuthProtocols() = %v, want %v", got, tt.protocols)
+			}
+			for _, v := range []uint{1, 2} {
+				if got, want := SupportsPinUvAuthProtocol(v)(e), slices.Contains(tt.supports, v); got != want {
+					t.Errorf("SupportsPinUvAuthProtocol(%d) = %v, want %v", v, got, want)
+				}
+			}
+			g, _ := e.GetInfo()
+			if got := g.Option("clientPin"); got != tt.clientPin {
+				t.Errorf(`Option("clientPin") = %v, want %v`, got, tt.clientPin)
+			}
+			if got := g.Option("uv"); got != tt.uv {
+				t.Errorf(`Option("uv") = %v, want %v`, got, tt.uv)
+			}
+			if id, ok := e.GetInfoAAGUID(); ok != tt.getInfoMatches || (ok && id.String() != tt.aaguid) {
+				t.Errorf("GetInfoAAGUID() = %s, %v", id, ok)
+			}
+			if err := ValidateGetInfoAAGUID(e); err != nil {
+				t.Errorf("ValidateGetInfoAAGUID: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetInfoHelpersDoNotAlias(t *testing.T) {
+	e := getInfoEntries(t)[testYubiKey57]
+	protocols := e.PinUvAuthProtocols()
+	protocols[0] = 99
+	if e.MetadataStatement.AuthenticatorGetInfo.PinUvAuthProtocols[0] != 2 {
+		t.Error("PinUvAuthProtocols returned the entry's own slice")
+	}
+
+	// A getInfo listing no protocols differs from one omitting the field.
+	e.MetadataStatement.AuthenticatorGetInfo = &AuthenticatorGetInfo{PinUvAuthProtocols: []uint{}}
+	if got := e.PinUvAuthProtocols(); got == nil || len(got) != 0 {
+		t.Errorf("PinUvAuthProtocols() of an empty list = %#v, want an empty non-nil slice", got)
+	}
+}
+
+func TestPinUvAuthProtocolQueries(t *testing.T) {
+	entries := getInfoEntries(t)
+	certify := func(aaguid string, level AuthenticatorStatus) {
+		e := entries[aaguid]
+		e.StatusReports = []StatusReport{report(level, "2024-01-01")}
+		entries[aaguid] = e
+	}
+	certify(testYubiKey54, FIDO_CERTIFIED_L2)
+	certify(testYubiKey57, FIDO_CERTIFIED_L1)
+	p := NewProvider(entries, Dataset{})
+
+	keys := func(list []Entry) []string {
+		var out []string
+		for _, e := range list {
+			out = append(out, e.AAGUID)
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		got  []Entry
+		want []string
+	}{
+		{"protocol 1, from [1] and [2,1]", p.EntriesByPinUvAuthProtocol(1), []string{testYubiKey57, testYubiKey54}},
+		{"protocol 2, only from [2,1]", p.EntriesByPinUvAuthProtocol(2), []string{testYubiKey57}},
+		{"unlisted protocol", p.EntriesByPinUvAuthProtocol(3), nil},
+		{
+			"fido2 and protocol 2 and certified L1+",
+			slices.Collect(p.Where(And(ProtocolFamilyIs("fido2"), SupportsPinUvAuthProtocol(2), CertifiedAtLeast(FIDO_CERTIFIED_L1)))),
+			[]string{testYubiKey57},
+		},
+		{
+			"protocol 1 and certified L2+",
+			slices.Collect(p.Where(And(SupportsPinUvAuthProtocol(1), CertifiedAtLeast(FIDO_CERTIFIED_L2)))),
+			[]string{testYubiKey54},
+		},
+		{
+			// Unknown protocols never match, so only the [1] entry is known to lack protocol 2.
+			"known to lack protocol 2",
+			slices.Collect(p.Where(And(Not(SupportsPinUvAuthProtocol(2)), func(e Entry) bool { return e.PinUvAuthProtocols() != nil }))),
+			[]string{testYubiKey54},
+		},
+	}
+	for _, tt := range tests {
+		if got := keys(tt.got); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
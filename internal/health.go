@@ -0,0 +1,321 @@
+package aaguids
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"slices"
+	"time"
+)
+
+// DatasetOrigin says how the current dataset of a Provider was loaded, see HealthStatus.
+type DatasetOrigin string
+
+const (
+	OriginEmbedded DatasetOrigin = "embedded" // compiled into this package: Default or FromEmbeddedMinimal
+	OriginStore    DatasetOrigin = "store"    // read back from storage with LoadPruned or LoadSnapshot
+	OriginFetched  DatasetOrigin = "fetched"  // installed from a parsed MDS BLOB by UpdateFromBLOB
+	OriginProvided DatasetOrigin = "provided" // handed over by the caller: NewProvider, Update or UpdateMerged
+)
+
+/*
+HealthStatus describes the dataset a Provider is serving, as reported by Provider.Health:
+
+  - Loaded: the dataset has at least one entry
+  - Origin: how it was loaded; Prune keeps the origin of the Provider pruned
+  - Source: the name of the first DatasetSource of the dataset, "" if it has none
+  - Serial / NextUpdate: the MDS BLOB identity of the dataset
+  - Stale / StaleBy: NextUpdate has passed, and by how much (see Provider.Staleness); false for a
+    missing or unparseable NextUpdate
+  - ServingStale: the dataset is stale and the last refresh recorded with RecordRefresh failed, so
+    lookups are served from the last good snapshot while refreshes keep being retried
+  - LoadedAt: when the dataset was installed in the Provider
+  - LastRefresh / LastRefreshErr / RefreshAge: the last attempt recorded with RecordRefresh, its
+    error (nil if it succeeded) and how long ago it was; all zero if none was recorded
+  - Degraded: the dataset is the minimal fallback (see FromEmbeddedMinimal)
+  - IconsStripped: no entry has an icon, as in the minimal fallback or a dataset pruned of them
+  - Fallback: why FirstAvailable passed over the sources before the one in use; nil if the first
+    source loaded or the Provider was not built by FirstAvailable
+  - Sources: the freshness of each DatasetSource, so one stale feed of a merged dataset shows even
+    when the aggregate NextUpdate has not passed
+*/
+type HealthStatus struct {
+	Loaded         bool
+	Origin         DatasetOrigin
+	Source         string
+	Serial         int
+	NextUpdate     string
+	Stale          bool
+	StaleBy        time.Duration
+	ServingStale   bool
+	LoadedAt       time.Time
+	LastRefresh    time.Time
+	LastRefreshErr error
+	RefreshAge     time.Duration
+	Degraded       bool
+	IconsStripped  bool
+	Fallback       error
+	Sources        []SourceHealth
+}
+
+/*
+SourceHealth is the freshness of one DatasetSource in a HealthStatus:
+
+  - Name / Version / EntryCount: from the DatasetSource
+  - UpdatedAt / Age: the last successful update of the source and how long ago it was; zero if the
+    dataset does not record it
+  - Stale / StaleBy: the source's own NextUpdate has passed, and by how much
+*/
+type SourceHealth struct {
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	EntryCount int           `json:"entryCount"`
+	UpdatedAt  time.Time     `json:"updatedAt,omitzero"`
+	Age        time.Duration `json:"-"`
+	Stale      bool          `json:"stale"`
+	StaleBy    time.Duration `json:"-"`
+}
+
+// Health reports the health of the embedded dataset. See Provider.Health.
+func Health() HealthStatus {
+	return Default().Health()
+}
+
+// Health reports which dataset p is serving, how fresh it is and whether p is running in degraded
+// mode. An Update to a full dataset clears Degraded; Fallback keeps describing how p was first
+// loaded.
+func (p *Provider) Health() HealthStatus {
+	return p.health(time.Now())
+}
+
+// health implements Health as of now.
+func (p *Provider) health(now time.Time) HealthStatus {
+	s := p.current()
+	h := HealthStatus{
+		Loaded:        len(s.entries) > 0,
+		Origin:        s.origin,
+		Serial:        s.info.Serial,
+		NextUpdate:    s.info.NextUpdate,
+		LoadedAt:      s.loadedAt,
+		Degraded:      slices.ContainsFunc(s.info.Sources, func(src DatasetSource) bool { return src.Name == SourceEmbeddedMinimal }),
+		IconsStripped: len(s.entries) > 0 && !s.hasIcons,
+		Fallback:      p.fallbackErr,
+	}
+	if len(s.info.Sources) > 0 {
+		h.Source = s.info.Sources[0].Name
+	}
+	for _, src := range s.info.Sources {
+		sh := SourceHealth{Name: src.Name, Version: src.Version, EntryCount: src.EntryCount}
+		if t, err := time.Parse(time.RFC3339, src.UpdatedAt); err == nil {
+			sh.UpdatedAt, sh.Age = t, now.Sub(t)
+		}
+		if next, ok := parseISODate(src.NextUpdate); ok && now.After(next) {
+			sh.Stale, sh.StaleBy = true, now.Sub(next)
+		}
+		h.Sources = append(h.Sources, sh)
+	}
+	h.StaleBy, h.Stale = s.staleness(now)
+	p.refreshMu.Lock()
+	h.LastRefresh, h.LastRefreshErr = p.lastRefresh, p.lastRefreshErr
+	p.refreshMu.Unlock()
+	h.ServingStale = h.Stale && h.LastRefreshErr != nil
+	if !h.LastRefresh.IsZero() {
+		h.RefreshAge = now.Sub(h.LastRefresh)
+	}
+	return h
+}
+
+/*
+RecordRefresh records the outcome of an attempt to refresh p's dataset for Health: err is what
+ended the attempt, nil if it succeeded, whether or not it installed a new dataset. Call it from the
+refresh loop that fetches and parses the BLOB and calls UpdateFromBLOB; p does not refresh itself,
+so without it Health reports no refresh attempts.
+*/
+func (p *Provider) RecordRefresh(err error) {
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+	p.lastRefresh, p.lastRefreshErr = time.Now(), err
+}
+
+// anyIcons reports whether any entry has an icon of either theme.
+func anyIcons(entries map[string]Entry) bool {
+	for _, e := range entries {
+		if slices.ContainsFunc(e.iconCandidates(Light), func(icon string) bool { return icon != "" }) {
+			return true
+		}
+	}
+	return false
+}
+
+// HealthOption configures the thresholds of Provider.HealthHandler.
+type HealthOption func(*healthConfig)
+
+// healthConfig holds the thresholds of one HealthHandler; negative durations are disabled.
+type healthConfig struct {
+	degradedAfter   time.Duration
+	notReadyAfter   time.Duration
+	sourceMaxAge    time.Duration
+	degradedUnready bool
+}
+
+// WithStaleDegradedAfter reports the dataset as degraded once it is stale by more than d, e.g.
+// 7*24*time.Hour (default: staleness alone never degrades).
+func WithStaleDegradedAfter(d time.Duration) HealthOption {
+	return func(c *healthConfig) { c.degradedAfter = max(d, 0) }
+}
+
+// WithStaleNotReadyAfter fails /readyz once the dataset is stale by more than d (default:
+// staleness alone never fails it).
+func WithStaleNotReadyAfter(d time.Duration) HealthOption {
+	return func(c *healthConfig) { c.notReadyAfter = max(d, 0) }
+}
+
+// WithSourceMaxAge reports the dataset as degraded once any of its sources was last updated more
+// than d ago, e.g. a community list that stopped contributing (default: source age never degrades).
+func WithSourceMaxAge(d time.Duration) HealthOption {
+	return func(c *healthConfig) { c.sourceMaxAge = max(d, 0) }
+}
+
+// WithNotReadyWhenDegraded fails /readyz whenever the status is degraded, e.g. for services that
+// must not run on the minimal fallback.
+func WithNotReadyWhenDegraded() HealthOption {
+	return func(c *healthConfig) { c.degradedUnready = true }
+}
+
+// Health check states reported in the "status" field of HealthHandler responses.
+const (
+	HealthOK          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+)
+
+/*
+healthReport is the JSON body of HealthHandler responses: the HealthStatus with durations in
+seconds and errors as strings, the derived status, and the reasons for anything but HealthOK.
+*/
+type healthReport struct {
+	Status         string         `json:"status"`
+	Ready          bool           `json:"ready"`
+	Reasons        []string       `json:"reasons,omitempty"`
+	Loaded         bool           `json:"loaded"`
+	Origin         DatasetOrigin  `json:"origin,omitempty"`
+	Source         string         `json:"source,omitempty"`
+	Serial         int            `json:"serial"`
+	NextUpdate     string         `json:"nextUpdate,omitempty"`
+	Stale          bool           `json:"stale"`
+	StaleSeconds   int64          `json:"staleSeconds,omitempty"`
+	ServingStale   bool           `json:"servingStale"`
+	LoadedAt       time.Time      `json:"loadedAt"`
+	LastRefresh    *time.Time     `json:"lastRefresh,omitempty"`
+	LastRefreshErr string         `json:"lastRefreshError,omitempty"`
+	RefreshAgeSecs int64          `json:"refreshAgeSeconds,omitempty"`
+	MinimalDataset bool           `json:"minimalFallback"`
+	IconsStripped  bool           `json:"iconsStripped"`
+	Fallback       string         `json:"fallbackError,omitempty"`
+	Sources        []SourceHealth `json:"sources,omitempty"`
+}
+
+// report derives the state of h under the thresholds of c.
+func (c healthConfig) report(h HealthStatus) healthReport {
+	r := healthReport{
+		Status:         HealthOK,
+		Ready:          true,
+		Loaded:         h.Loaded,
+		Origin:         h.Origin,
+		Source:         h.Source,
+		Serial:         h.Serial,
+		NextUpdate:     h.NextUpdate,
+		Stale:          h.Stale,
+		StaleSeconds:   int64(h.StaleBy / time.Second),
+		ServingStale:   h.ServingStale,
+		LoadedAt:       h.LoadedAt,
+		RefreshAgeSecs: int64(h.RefreshAge / time.Second),
+		MinimalDataset: h.Degraded,
+		IconsStripped:  h.IconsStripped,
+		Sources:        h.Sources,
+	}
+	if !h.LastRefresh.IsZero() {
+		r.LastRefresh = &h.LastRefresh
+	}
+	if h.LastRefreshErr != nil {
+		r.LastRefreshErr = h.LastRefreshErr.Error()
+	}
+	if h.Fallback != nil {
+		r.Fallback = h.Fallback.Error()
+	}
+	degrade := func(reason string) {
+		r.Status = HealthDegraded
+		r.Reasons = append(r.Reasons, reason)
+	}
+	if !h.Loaded {
+		r.Status, r.Ready = HealthUnavailable, false
+		r.Reasons = append(r.Reasons, "no dataset loaded")
+		return r
+	}
+	if h.Degraded {
+		degrade("serving the minimal fallback dataset")
+	}
+	if h.IconsStripped {
+		degrade("icons are stripped")
+	}
+	if h.LastRefreshErr != nil {
+		degrade("last refresh failed")
+	}
+	if c.degradedAfter >= 0 && h.Stale && h.StaleBy > c.degradedAfter {
+		degrade("dataset is past its nextUpdate")
+	}
+	for _, src := range h.Sources {
+		switch {
+		case src.Stale:
+			degrade("source " + src.Name + " is past its nextUpdate")
+		case c.sourceMaxAge >= 0 && !src.UpdatedAt.IsZero() && src.Age > c.sourceMaxAge:
+			degrade("source " + src.Name + " has not been updated recently")
+		}
+	}
+	if c.notReadyAfter >= 0 && h.Stale && h.StaleBy > c.notReadyAfter {
+		r.Ready = false
+		r.Reasons = append(r.Reasons, "dataset is too stale to serve")
+	}
+	if c.degradedUnready && r.Status == HealthDegraded {
+		r.Ready = false
+	}
+	return r
+}
+
+// HealthHandler serves the health of the embedded dataset. See Provider.HealthHandler.
+func HealthHandler(opts ...HealthOption) http.Handler {
+	return Default().HealthHandler(opts...)
+}
+
+/*
+HealthHandler returns an http.Handler serving the Health of p as JSON, for Kubernetes probes: mount
+it at /healthz and /readyz. A request whose path ends in "readyz" is a readiness check, anything
+else a liveness check:
+
+  - liveness always answers 200, with "status" set to "ok", "degraded" or "unavailable", so a
+    missing or stale dataset never gets the process restarted
+  - readiness answers 503 instead when no dataset is loaded, and as configured by
+    WithStaleNotReadyAfter and WithNotReadyWhenDegraded
+
+The status is degraded while serving the minimal fallback, with icons stripped, after a failed
+refresh (see RecordRefresh), when stale beyond WithStaleDegradedAfter, or when any single source
+is past its own nextUpdate or older than WithSourceMaxAge. "reasons" lists every
+condition found, and "servingStale" is true while a stale dataset is served after a failed refresh.
+*/
+func (p *Provider) HealthHandler(opts ...HealthOption) http.Handler {
+	cfg := healthConfig{degradedAfter: -1, notReadyAfter: -1, sourceMaxAge: -1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rep := cfg.report(p.Health())
+		code := http.StatusOK
+		if path.Base(r.URL.Path) == "readyz" && !rep.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(rep)
+	})
+}
@@ -0,0 +1,64 @@
+package aaguids
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// updateGoldens makes golden tests rewrite their files instead of comparing against them.
+var updateGoldens = flag.Bool("update", false, "rewrite golden files")
+
+// testProvider returns a Provider holding entries, each under its dataset key (see Entry.Key).
+func testProvider(t testing.TB, entries ...Entry) *Provider {
+	t.Helper()
+	m := make(map[string]Entry, len(entries))
+	for _, e := range entries {
+		k := e.Key()
+		if k == "" {
+			t.Fatalf("test entry %+v has no dataset key", e)
+		}
+		m[k] = e
+	}
+	return NewProvider(m, Dataset{Serial: 1, NextUpdate: "2099-01-01"})
+}
+
+// readTestBLOB decodes testdata/name as a BLOB payload, without the JWT around it.
+func readTestBLOB(t testing.TB, name string) BLOBPayload {
+	t.Helper()
+	var blob BLOBPayload
+	if err := json.Unmarshal(readTestdata(t, name), &blob); err != nil {
+		t.Fatalf("decoding %s: %v", name, err)
+	}
+	return blob
+}
+
+// readTestdata returns the contents of testdata/name.
+func readTestdata(t testing.TB, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// checkGolden compares got with the file at path, or rewrites the file with -update.
+func checkGolden(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if *updateGoldens {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s is out of date (run go test -update):\n got %s\nwant %s", path, got, want)
+	}
+}
@@ -0,0 +1,246 @@
+package aaguids
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DatasetRef identifies one snapshot in a HistoryStore: the BLOB serial and the date it was taken.
+type DatasetRef struct {
+	Serial int       `json:"serial"`
+	Date   time.Time `json:"date"`
+}
+
+/*
+HistoryStore keeps successive metadata snapshots so questions like "what did MDS say about this
+AAGUID on 2023-09-01" can be answered after the fact. Each snapshot is stored as one gzip-compressed
+JSON file in the store directory; all snapshots are loaded into memory by OpenHistoryStore, so
+queries never touch the disk.
+
+A HistoryStore is safe for concurrent use.
+*/
+type HistoryStore struct {
+	dir string
+
+	mu        sync.RWMutex
+	snapshots []historySnapshot // ascending by Date, then Serial
+}
+
+// historySnapshot is one stored dataset, keyed by canonical AAGUID.
+type historySnapshot struct {
+	Ref     DatasetRef       `json:"ref"`
+	Entries map[string]Entry `json:"entries"`
+}
+
+// historyFileSuffix is the extension of snapshot files in a HistoryStore directory.
+const historyFileSuffix = ".json.gz"
+
+// OpenHistoryStore opens (creating if needed) the store in dir and loads every snapshot file in it.
+func OpenHistoryStore(dir string) (*HistoryStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating history directory: %w", err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing history directory: %w", err)
+	}
+	h := &HistoryStore{dir: dir}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), historyFileSuffix) {
+			continue
+		}
+		snap, err := readHistorySnapshot(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		h.snapshots = append(h.snapshots, snap)
+	}
+	h.sortSnapshots()
+	return h, nil
+}
+
+// readHistorySnapshot decodes one snapshot file.
+func readHistorySnapshot(path string) (historySnapshot, error) {
+	var snap historySnapshot
+	f, err := os.Open(path)
+	if err != nil {
+		return snap, fmt.Errorf("opening snapshot: %w", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return snap, fmt.Errorf("decompressing snapshot %s: %w", filepath.Base(path), err)
+	}
+	if err := json.NewDecoder(zr).Decode(&snap); err != nil {
+		return snap, fmt.Errorf("decoding snapshot %s: %w", filepath.Base(path), err)
+	}
+	return snap, nil
+}
+
+// sortSnapshots orders snapshots by date, then serial.
+func (h *HistoryStore) sortSnapshots() {
+	sort.SliceStable(h.snapshots, func(i, j int) bool {
+		a, b := h.snapshots[i].Ref, h.snapshots[j].Ref
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Serial < b.Serial
+	})
+}
+
+/*
+Ingest stores entries as the snapshot identified by ref, replacing any stored snapshot with the same
+serial. Keys that are not well-formed AAGUIDs are kept as-is; AAGUID keys are stored in canonical form.
+*/
+func (h *HistoryStore) Ingest(ref DatasetRef, entries map[string]Entry) error {
+	snap := historySnapshot{Ref: ref, Entries: make(map[string]Entry, len(entries))}
+	for k, e := range entries {
+		snap.Entries[canonicalKey(k)] = e
+	}
+
+	path := filepath.Join(h.dir, fmt.Sprintf("%010d%s", ref.Serial, historyFileSuffix))
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("creating snapshot file: %w", err)
+	}
+	zw := gzip.NewWriter(f)
+	encErr := json.NewEncoder(zw).Encode(snap)
+	closeErr := errors.Join(zw.Close(), f.Close())
+	if err := errors.Join(encErr, closeErr); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing snapshot %d: %w", ref.Serial, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("storing snapshot %d: %w", ref.Serial, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	kept := h.snapshots[:0]
+	for _, s := range h.snapshots {
+		if s.Ref.Serial != ref.Serial {
+			kept = append(kept, s)
+		}
+	}
+	h.snapshots = append(kept, snap)
+	h.sortSnapshots()
+	return nil
+}
+
+// IngestBLOB stores the entries of a verified BLOB, keyed as by BLOBPayload.EntriesByKey, as the
+// snapshot taken at date.
+func (h *HistoryStore) IngestBLOB(blob BLOBPayload, date time.Time) error {
+	return h.Ingest(DatasetRef{Serial: blob.No, Date: date}, blob.EntriesByKey())
+}
+
+// Snapshots lists the stored snapshots, oldest first.
+func (h *HistoryStore) Snapshots() []DatasetRef {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	refs := make([]DatasetRef, len(h.snapshots))
+	for i, s := range h.snapshots {
+		refs[i] = s.Ref
+	}
+	return refs
+}
+
+/*
+EntryAsOf returns the entry as recorded in the latest snapshot taken at or before t, together with
+that snapshot's ref. It returns false if no snapshot predates t or the AAGUID was absent from it.
+*/
+func (h *HistoryStore) EntryAsOf(aaGuid string, t time.Time) (Entry, DatasetRef, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	key := canonicalKey(aaGuid)
+	for i := len(h.snapshots) - 1; i >= 0; i-- {
+		s := h.snapshots[i]
+		if s.Ref.Date.After(t) {
+			continue
+		}
+		e, ok := s.Entries[key]
+		return e, s.Ref, ok
+	}
+	return Entry{}, DatasetRef{}, false
+}
+
+// HistoricalStatus is one status report together with the first and last snapshot that carried it.
+type HistoricalStatus struct {
+	Report    StatusReport `json:"report"`
+	FirstSeen DatasetRef   `json:"firstSeen"`
+	LastSeen  DatasetRef   `json:"lastSeen"`
+}
+
+/*
+SnapshotTimeline is the merged status history of one AAGUID across every stored snapshot.
+
+  - Reports: distinct status reports in timeline order, each with the snapshot range that carried it
+  - FirstSeen / LastSeen: the first and last snapshot containing the entry
+  - Removed: the first snapshot after LastSeen, set when the entry has disappeared upstream
+*/
+type SnapshotTimeline struct {
+	Reports   []HistoricalStatus `json:"reports"`
+	FirstSeen DatasetRef         `json:"firstSeen"`
+	LastSeen  DatasetRef         `json:"lastSeen"`
+	Removed   *DatasetRef        `json:"removed,omitempty"`
+}
+
+/*
+StatusHistoryAcrossSnapshots merges the per-snapshot status reports of aaGuid into one timeline. A
+report is identified by its full content, so a report that upstream later amended appears twice.
+Entries that were later removed from MDS keep their history, with Removed set. It returns false if
+no snapshot ever contained the AAGUID.
+*/
+func (h *HistoryStore) StatusHistoryAcrossSnapshots(aaGuid string) (SnapshotTimeline, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	key := canonicalKey(aaGuid)
+
+	var tl SnapshotTimeline
+	seen := false
+	index := make(map[string]int) // report identity → position in merged
+	var merged []HistoricalStatus
+	for _, s := range h.snapshots {
+		e, ok := s.Entries[key]
+		if !ok {
+			if seen && tl.Removed == nil {
+				ref := s.Ref
+				tl.Removed = &ref
+			}
+			continue
+		}
+		if !seen {
+			tl.FirstSeen = s.Ref
+			seen = true
+		}
+		tl.LastSeen = s.Ref
+		tl.Removed = nil
+		for _, r := range e.StatusReports {
+			id, _ := json.Marshal(r)
+			if i, ok := index[string(id)]; ok {
+				merged[i].LastSeen = s.Ref
+				continue
+			}
+			index[string(id)] = len(merged)
+			merged = append(merged, HistoricalStatus{Report: r, FirstSeen: s.Ref, LastSeen: s.Ref})
+		}
+	}
+	if !seen {
+		return SnapshotTimeline{}, false
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		ti, _ := merged[i].Report.EffectiveTime()
+		tj, _ := merged[j].Report.EffectiveTime()
+		return ti.Before(tj)
+	})
+	tl.Reports = merged
+	return tl, true
+}
@@ -0,0 +1,133 @@
+package aaguids
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryStore(t *testing.T) {
+	dir := t.TempDir()
+	h, err := OpenHistoryStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := func(s string) time.Time { return day(t, s) }
+	ref := func(serial int, date string) DatasetRef { return DatasetRef{Serial: serial, Date: d(date)} }
+	certified, revoked := report(FIDO_CERTIFIED_L1, "2022-01-01"), report(REVOKED, "2023-03-01")
+	const flapping = "00000000-0000-4000-8000-000000000001"
+
+	// Ingested out of order: the store sorts by date. testYubiKey is revoked in serial 2 and removed
+	// in serial 3; flapping is missing from serial 2 only.
+	snapshots := []struct {
+		ref     DatasetRef
+		entries map[string]Entry
+	}{
+		{ref(3, "2024-01-01"), map[string]Entry{
+			testGPM:  {AAGUID: testGPM, StatusReports: []StatusReport{certified}},
+			flapping: {AAGUID: flapping},
+		}},
+		{ref(1, "2023-01-01"), map[string]Entry{
+			strings.ToUpper(testYubiKey): {AAGUID: testYubiKey, StatusReports: []StatusReport{certified}},
+			flapping:                     {AAGUID: flapping},
+		}},
+		{ref(2, "2023-06-01"), map[string]Entry{
+			testYubiKey: {AAGUID: testYubiKey, StatusReports: []StatusReport{certified, revoked}},
+			testGPM:     {AAGUID: testGPM, StatusReports: []StatusReport{certified}},
+		}},
+	}
+	for _, s := range snapshots {
+		if err := h.Ingest(s.ref, s.entries); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Everything is read back from disk.
+	h, err = OpenHistoryStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Snapshots(); len(got) != 3 || got[0].Serial != 1 || got[1].Serial != 2 || got[2].Serial != 3 {
+		t.Fatalf("Snapshots() = %v, want serials 1, 2, 3", got)
+	}
+
+	for _, tt := range []struct {
+		aaguid  string
+		at      string
+		serial  int
+		reports int
+		ok      bool
+	}{
+		{testYubiKey, "2022-12-31", 0, 0, false},
+		{testYubiKey, "2023-01-01", 1, 1, true},
+		{strings.ToUpper(testYubiKey), "2023-05-31", 1, 1, true},
+		{testYubiKey, "2023-09-01", 2, 2, true},
+		// Removed upstream: the snapshot is found, the entry is not.
+		{testYubiKey, "2025-01-01", 3, 0, false},
+		{flapping, "2023-09-01", 2, 0, false},
+	} {
+		e, r, ok := h.EntryAsOf(tt.aaguid, d(tt.at))
+		if ok != tt.ok || r.Serial != tt.serial || len(e.StatusReports) != tt.reports {
+			t.Errorf("EntryAsOf(%s, %s) = %d reports, serial %d, %v; want %d, %d, %v",
+				tt.aaguid, tt.at, len(e.StatusReports), r.Serial, ok, tt.reports, tt.serial, tt.ok)
+		}
+	}
+
+	tl, ok := h.StatusHistoryAcrossSnapshots(strings.ToUpper(testYubiKey))
+	if !ok {
+		t.Fatal("no timeline for the removed entry")
+	}
+	if tl.FirstSeen != ref(1, "2023-01-01") || tl.LastSeen != ref(2, "2023-06-01") ||
+		tl.Removed == nil || *tl.Removed != ref(3, "2024-01-01") {
+		t.Errorf("timeline spans %v to %v, removed %v; want 1 to 2, removed in 3", tl.FirstSeen, tl.LastSeen, tl.Removed)
+	}
+	want := []HistoricalStatus{
+		{Report: certified, FirstSeen: ref(1, "2023-01-01"), LastSeen: ref(2, "2023-06-01")},
+		{Report: revoked, FirstSeen: ref(2, "2023-06-01"), LastSeen: ref(2, "2023-06-01")},
+	}
+	if len(tl.Reports) != len(want) {
+		t.Fatalf("timeline has %d reports, want %d: %+v", len(tl.Reports), len(want), tl.Reports)
+	}
+	for i, r := range tl.Reports {
+		if r.Report.Status != want[i].Report.Status || r.FirstSeen != want[i].FirstSeen || r.LastSeen != want[i].LastSeen {
+			t.Errorf("report %d = %s seen %v to %v, want %s seen %v to %v", i, r.Report.Status, r.FirstSeen.Serial,
+				r.LastSeen.Serial, want[i].Report.Status, want[i].FirstSeen.Serial, want[i].LastSeen.Serial)
+		}
+	}
+
+	// An entry that comes back is not removed.
+	if tl, ok := h.StatusHistoryAcrossSnapshots(flapping); !ok || tl.Removed != nil || tl.LastSeen.Serial != 3 {
+		t.Errorf("timeline of a returning entry = %+v, %v; want it last seen in 3 and not removed", tl, ok)
+	}
+	if _, ok := h.StatusHistoryAcrossSnapshots("00000000-0000-4000-8000-000000000002"); ok {
+		t.Error("timeline for an AAGUID no snapshot contained")
+	}
+
+	// Ingesting a serial again replaces its snapshot.
+	if err := h.Ingest(ref(3, "2024-01-01"), map[string]Entry{testYubiKey: {AAGUID: testYubiKey}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, r, ok := h.EntryAsOf(testYubiKey, d("2025-01-01")); !ok || r.Serial != 3 || len(h.Snapshots()) != 3 {
+		t.Errorf("after re-ingesting serial 3: EntryAsOf = serial %d, %v; %d snapshots", r.Serial, ok, len(h.Snapshots()))
+	}
+}
+
+func TestOpenHistoryStoreCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "0000000001"+historyFileSuffix), []byte("not gzip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Other files are ignored.
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenHistoryStore(dir); err == nil || !strings.Contains(err.Error(), "decompressing snapshot") {
+		t.Errorf("OpenHistoryStore over a corrupt snapshot: %v", err)
+	}
+	os.Remove(filepath.Join(dir, "0000000001"+historyFileSuffix))
+	if h, err := OpenHistoryStore(dir); err != nil || len(h.Snapshots()) != 0 {
+		t.Errorf("OpenHistoryStore = %v, %v; want an empty store", h, err)
+	}
+}
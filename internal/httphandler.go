@@ -0,0 +1,120 @@
+package aaguids
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HandlerOption configures the handler returned by NewHTTPHandler.
+type HandlerOption func(*handler)
+
+// WithResponseMask shapes every entry the handler serves with m (default MaskPublic). See
+// FieldMask.
+func WithResponseMask(m FieldMask) HandlerOption {
+	return func(h *handler) { h.mask = m }
+}
+
+/*
+WithUniformTiming makes the handler look entries and icons up with uniform timing (see
+SetUniformLookupTiming) whatever the Provider's own setting, so response times do not tell a
+remote caller which AAGUIDs the dataset holds. Other users of the Provider are unaffected.
+
+Only the lookup is uniform: a found entry is still encoded and an icon decoded afterwards, which a
+404 skips. Encoding a masked entry takes far longer than the lookup (BenchmarkHTTPHandlerEntry
+measures both paths), so deployments that must hide whether an entry exists should also pad
+response times to a fixed minimum.
+*/
+func WithUniformTiming() HandlerOption {
+	return func(h *handler) { h.uniform = true }
+}
+
+// handler is the http.Handler returned by NewHTTPHandler.
+type handler struct {
+	p       *Provider
+	mask    FieldMask
+	uniform bool // see WithUniformTiming
+	mux     *http.ServeMux
+}
+
+// uniformLookup reports whether h's lookups use uniform timing.
+func (h *handler) uniformLookup() bool {
+	return h.uniform || h.p.uniformLookup.Load()
+}
+
+/*
+NewHTTPHandler returns a read-only HTTP API over p, for mounting with e.g.
+mux.Handle("/metadata/", http.StripPrefix("/metadata", aaguids.NewHTTPHandler(p))):
+
+  - GET /aaguids/{aaguid}: the entry as JSON, shaped by the response mask; 400 Bad Request when
+    the id is malformed and 404 Not Found when it is not in the dataset (see Provider.Lookup).
+    UAF and U2F entries are served under their synthetic key
+  - GET /aaguids/{aaguid}/icon: the entry's icon with HTTP caching (?theme=dark,
+    ?fallback=avatar), see Provider.ServeIcon
+  - GET /dataset: the dataset identity (see DatasetInfo) and the library version
+
+The response mask applies to the encoding only (see FieldMask.MarshalEntry), so entries shared with
+other callers of p are never modified. It defaults to MaskPublic, which keeps attestation roots and
+rogue list URLs out of responses; trusted internal deployments pass WithResponseMask(MaskInternal).
+Public deployments worried about dataset probing can add WithUniformTiming.
+
+There is no gRPC counterpart: the module has no gRPC or protobuf dependency and does not take one
+on for a second transport. A gRPC service can be built on Provider and FieldMask.MarshalEntry in
+the same way.
+*/
+func NewHTTPHandler(p *Provider, opts ...HandlerOption) http.Handler {
+	h := &handler{p: p, mask: MaskPublic, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mux.HandleFunc("GET /aaguids/{aaguid}", h.serveEntry)
+	h.mux.HandleFunc("GET /aaguids/{aaguid}/icon", h.serveIcon)
+	h.mux.HandleFunc("GET /dataset", h.serveDataset)
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *handler) serveEntry(w http.ResponseWriter, r *http.Request) {
+	// The id is checked before the lookup rather than after a miss, so hits and misses of
+	// well-formed ids do the same work.
+	id := r.PathValue("aaguid")
+	if err := h.p.checkKey(id); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s := h.p.current()
+	k, ok := h.p.lookupKeyMode(s, id, h.uniformLookup())
+	if !ok {
+		http.Error(w, "unknown AAGUID", http.StatusNotFound)
+		return
+	}
+	e := s.entries[k]
+	raw, err := h.mask.MarshalEntry(e)
+	if err != nil {
+		h.p.log().Error("aaguids: encoding entry", "key", e.Key(), "err", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, json.RawMessage(raw))
+}
+
+func (h *handler) serveIcon(w http.ResponseWriter, r *http.Request) {
+	h.p.serveIcon(w, r, r.PathValue("aaguid"), h.uniformLookup())
+}
+
+func (h *handler) serveDataset(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, struct {
+		Dataset Dataset `json:"dataset"`
+		Version string  `json:"version"`
+	}{h.p.DatasetInfo(), Version()})
+}
+
+func (h *handler) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.p.log().Error("aaguids: writing response", "err", err)
+	}
+}
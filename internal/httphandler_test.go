@@ -0,0 +1,283 @@
+package aaguids
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Values of fields MaskPublic drops; none of them may appear in a public response.
+const (
+	testRootCert   = "MIIBfjCCASWgAwIBAgIBATAKBggqhkjOPQQDAjAXMRUwEwYDVQQDDAxGVCBGSURPIDAyMDA"
+	testRogueList  = "https://rogue.example/list"
+	testStatusURL  = "https://status.example/report"
+	testStatusCert = "MIIC-status-report-certificate"
+)
+
+// sensitiveEntry returns an entry carrying every kind of field MaskPublic drops.
+func sensitiveEntry() Entry {
+	str := func(s string) *string { return &s }
+	return Entry{
+		AAGUID: testYubiKey,
+		MetadataStatement: MetadataStatement{
+			AAGUID:                      testYubiKey,
+			Description:                 "YubiKey 5 Series",
+			ProtocolFamily:              "fido2",
+			AttestationRootCertificates: []string{testRootCert},
+			LegalHeader:                 "Submission of this statement and retrieval and use of this statement",
+		},
+		StatusReports: []StatusReport{{
+			Status:        FIDO_CERTIFIED_L1,
+			EffectiveDate: str("2020-05-12"),
+			URL:           str(testStatusURL),
+			Certificate:   str(testStatusCert),
+		}},
+		TimeOfLastStatusChange: "2020-05-12",
+		RogueListURL:           testRogueList,
+		RogueListHash:          "8a0d",
+	}
+}
+
+// get serves one request and returns the status and body.
+func get(t *testing.T, h http.Handler, path string) (int, []byte) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	body, err := io.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, body
+}
+
+func TestHTTPHandlerResponseMask(t *testing.T) {
+	p := testProvider(t, sensitiveEntry())
+	secrets := []string{testRootCert, testRogueList, testStatusURL, testStatusCert, "legalHeader", "rogueListHash"}
+
+	tests := []struct {
+		name   string
+		opts   []HandlerOption
+		public bool
+	}{
+		{"default", nil, true},
+		{"public", []HandlerOption{WithResponseMask(MaskPublic)}, true},
+		{"internal", []HandlerOption{WithResponseMask(MaskInternal)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := get(t, NewHTTPHandler(p, tt.opts...), "/aaguids/"+testYubiKey)
+			if code != http.StatusOK {
+				t.Fatalf("status %d: %s", code, body)
+			}
+			for _, s := range secrets {
+				if got := bytes.Contains(body, []byte(s)); got == tt.public {
+					t.Errorf("response contains %q: %v, want %v\n%s", s, got, !tt.public, body)
+				}
+			}
+			var e Entry
+			if err := json.Unmarshal(body, &e); err != nil {
+				t.Fatal(err)
+			}
+			if e.AAGUID != testYubiKey || e.MetadataStatement.Description != "YubiKey 5 Series" ||
+				len(e.StatusReports) != 1 || e.StatusReports[0].Status != FIDO_CERTIFIED_L1 {
+				t.Errorf("response lost the name or status: %s", body)
+			}
+		})
+	}
+
+	// Masking leaves the shared entry alone.
+	if e, _ := p.GetEntry(testYubiKey); len(e.MetadataStatement.AttestationRootCertificates) != 1 ||
+		e.RogueListURL != testRogueList || e.StatusReports[0].URL == nil {
+		t.Errorf("serving a masked response modified the dataset entry: %+v", e)
+	}
+}
+
+func TestHTTPHandlerRoutes(t *testing.T) {
+	blob := readTestBLOB(t, "blob-mixed.json")
+	p := NewProvider(blob.EntriesByKey(), Dataset{Serial: blob.No})
+	h := NewHTTPHandler(p)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/aaguids/" + testYubiKey, http.StatusOK},
+		{"/aaguids/EE882879-721C-4913-9775-3DFCCE97072A", http.StatusOK},
+		{"/aaguids/urn:uuid:ee882879-721c-4913-9775-3dfcce97072a", http.StatusOK},
+		{"/aaguids/uaf:4e4e%234005", http.StatusOK},
+		{"/aaguids/" + testU2FKey, http.StatusOK},
+		{"/aaguids/00000000-0000-0000-0000-000000000001", http.StatusNotFound},
+		{"/aaguids/00000000-0000-0000-0000-000000000000", http.StatusNotFound},
+		{"/aaguids/not-an-aaguid", http.StatusBadRequest},
+		{"/aaguids/u2f:zz", http.StatusBadRequest},
+		{"/unknown", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code, body := get(t, h, tt.path); code != tt.want {
+			t.Errorf("GET %s: %d, want %d: %s", tt.path, code, tt.want, body)
+		}
+	}
+
+	code, body := get(t, h, "/dataset")
+	var info struct {
+		Dataset Dataset
+		Version string
+	}
+	if err := json.Unmarshal(body, &info); err != nil || code != http.StatusOK {
+		t.Fatalf("GET /dataset: %d %v", code, err)
+	}
+	if info.Dataset.Serial != 42 || info.Version != Version() {
+		t.Errorf("GET /dataset = %+v", info)
+	}
+}
+
+// testPNG returns a w×1 PNG, and its data URL.
+func testPNG(t *testing.T, w int) ([]byte, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, 1))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestHTTPHandlerIcons(t *testing.T) {
+	light, lightURL := testPNG(t, 2)
+	dark, darkURL := testPNG(t, 3)
+	const (
+		lightOnly = "00000000-0000-0000-0000-00000000000a"
+		noIcon    = "00000000-0000-0000-0000-00000000000b"
+		unknown   = "00000000-0000-0000-0000-00000000000c"
+	)
+	entries := map[string]Entry{
+		testYubiKey: {
+			AAGUID:              testYubiKey,
+			MetadataStatement:   MetadataStatement{AAGUID: testYubiKey, Description: "YubiKey 5", Icon: lightURL},
+			CommunityExtensions: &CommunityExtensions{Source: "test", IconDark: darkURL},
+		},
+		lightOnly: {AAGUID: lightOnly, MetadataStatement: MetadataStatement{Description: "Light", Icon: lightURL}},
+		noIcon:    {AAGUID: noIcon, MetadataStatement: MetadataStatement{Description: "plain key", Icon: "data:image/png;base64,bm90IGEgcG5n"}},
+	}
+	p := NewProvider(entries, Dataset{Serial: 42})
+	h := NewHTTPHandler(p)
+	etagPattern := regexp.MustCompile(`^"42-[0-9a-f]{32}"$`)
+
+	tests := []struct {
+		name        string
+		path        string
+		wantCode    int
+		wantType    string
+		wantBody    []byte // nil: not checked
+		wantCaching bool
+	}{
+		{"light", "/aaguids/" + testYubiKey + "/icon", http.StatusOK, "image/png", light, true},
+		{"dark", "/aaguids/" + testYubiKey + "/icon?theme=dark", http.StatusOK, "image/png", dark, true},
+		{"dark theme case-insensitive", "/aaguids/" + testYubiKey + "/icon?theme=DARK", http.StatusOK, "image/png", dark, true},
+		{"other spelling", "/aaguids/EE882879-721C-4913-9775-3DFCCE97072A/icon", http.StatusOK, "image/png", light, true},
+		{"dark falls back to light", "/aaguids/" + lightOnly + "/icon?theme=dark", http.StatusOK, "image/png", light, true},
+		{"unusable icon", "/aaguids/" + noIcon + "/icon", http.StatusNotFound, "", nil, false},
+		{"unusable icon with avatar", "/aaguids/" + noIcon + "/icon?fallback=avatar", http.StatusOK, "image/svg+xml", nil, true},
+		{"unknown", "/aaguids/" + unknown + "/icon", http.StatusNotFound, "", nil, false},
+		{"unknown with avatar", "/aaguids/" + unknown + "/icon?fallback=avatar", http.StatusOK, "image/svg+xml", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d", rec.Code, tt.wantCode)
+			}
+			hd := rec.Header()
+			if tt.wantType != "" && hd.Get("Content-Type") != tt.wantType {
+				t.Errorf("Content-Type %q, want %q", hd.Get("Content-Type"), tt.wantType)
+			}
+			if tt.wantBody != nil && !bytes.Equal(rec.Body.Bytes(), tt.wantBody) {
+				t.Errorf("body is not the expected icon")
+			}
+			if !tt.wantCaching {
+				if hd.Get("ETag") != "" {
+					t.Errorf("error response has ETag %s", hd.Get("ETag"))
+				}
+				return
+			}
+			if etag := hd.Get("ETag"); !etagPattern.MatchString(etag) {
+				t.Errorf("ETag %s is not a strong serial-content tag", etag)
+			}
+			if cc := hd.Get("Cache-Control"); cc != "public, max-age=604800" {
+				t.Errorf("Cache-Control %q", cc)
+			}
+			if hd.Get("X-Content-Type-Options") != "nosniff" || hd.Get("Content-Length") != strconv.Itoa(rec.Body.Len()) {
+				t.Errorf("headers %v", hd)
+			}
+		})
+	}
+
+	serve := func(method, path, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, path, nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+	icon := "/aaguids/" + testYubiKey + "/icon"
+	lightTag := serve(http.MethodGet, icon, "").Header().Get("ETag")
+	darkTag := serve(http.MethodGet, icon+"?theme=dark", "").Header().Get("ETag")
+	if lightTag == darkTag {
+		t.Errorf("light and dark icons share the ETag %s", lightTag)
+	}
+	if tag := serve(http.MethodGet, "/aaguids/"+lightOnly+"/icon", "").Header().Get("ETag"); tag != lightTag {
+		t.Errorf("the same icon of two entries has ETags %s and %s", lightTag, tag)
+	}
+
+	revalidate := []struct {
+		ifNoneMatch string
+		want        int
+	}{
+		{lightTag, http.StatusNotModified},
+		{"W/" + lightTag, http.StatusNotModified},
+		{`"other", ` + lightTag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{darkTag, http.StatusOK},
+		{`"42-0"`, http.StatusOK},
+	}
+	for _, tt := range revalidate {
+		rec := serve(http.MethodGet, icon, tt.ifNoneMatch)
+		if rec.Code != tt.want {
+			t.Errorf("If-None-Match %s: %d, want %d", tt.ifNoneMatch, rec.Code, tt.want)
+		}
+		if rec.Code == http.StatusNotModified && (rec.Body.Len() != 0 || rec.Header().Get("ETag") != lightTag) {
+			t.Errorf("If-None-Match %s: 304 with %d body bytes and ETag %s", tt.ifNoneMatch, rec.Body.Len(), rec.Header().Get("ETag"))
+		}
+	}
+	if rec := serve(http.MethodHead, icon, ""); rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("HEAD: %d with %d body bytes", rec.Code, rec.Body.Len())
+	}
+
+	avatar := serve(http.MethodGet, "/aaguids/"+noIcon+"/icon?fallback=avatar", "").Body.String()
+	if again := serve(http.MethodGet, "/aaguids/"+noIcon+"/icon?fallback=avatar", "").Body.String(); again != avatar ||
+		!strings.HasPrefix(avatar, "<svg") || !strings.Contains(avatar, ">P</text>") {
+		t.Errorf("avatar is not deterministic or lacks the description's initial:\n%s\n%s", avatar, again)
+	}
+	if other := serve(http.MethodGet, "/aaguids/"+unknown+"/icon?fallback=avatar", "").Body.String(); other == avatar {
+		t.Error("different keys got the same avatar")
+	}
+
+	// A refresh invalidates cached icons even when the icon is unchanged.
+	p.Update(entries, Dataset{Serial: 43})
+	rec := serve(http.MethodGet, icon, lightTag)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("ETag"), `"43-`) {
+		t.Errorf("after refresh: %d with ETag %s, want 200 with a serial 43 ETag", rec.Code, rec.Header().Get("ETag"))
+	}
+}
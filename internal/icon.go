@@ -0,0 +1,93 @@
+package aaguids
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Theme selects between the light and dark variants of an authenticator icon.
+type Theme int
+
+const (
+	// Light selects the community icon_light (CommunityExtensions.Icon), then the MDS icon.
+	Light Theme = iota
+	// Dark selects the community icon_dark (CommunityExtensions.IconDark); MDS has no dark variant.
+	Dark
+)
+
+// ErrNoIcon is returned by IconPNG when an entry has no valid icon in either theme.
+var ErrNoIcon = errors.New("aaguids: entry has no valid icon")
+
+// pngDataURLPrefix is the only data: URL form the metadata statement specification allows for icons.
+const pngDataURLPrefix = "data:image/png;base64,"
+
+// pngSignature is the fixed 8-byte header every PNG file starts with.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+/*
+IconFor returns the icon data URL for theme, falling back to the other variant when the preferred
+one is absent or malformed. Only well-formed PNG data URLs are returned; ok is false when the entry
+has no usable icon at all, so a UI can render a placeholder avatar instead.
+*/
+func (e Entry) IconFor(theme Theme) (dataURL string, ok bool) {
+	for _, candidate := range e.iconCandidates(theme) {
+		if _, err := decodePNGDataURL(candidate); err == nil {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// IconPNG returns the decoded PNG bytes of the icon IconFor would select, or an error wrapping
+// ErrNoIcon.
+func (e Entry) IconPNG(theme Theme) ([]byte, error) {
+	dataURL, ok := e.IconFor(theme)
+	if !ok {
+		return nil, entryErrorf(e, "%w", ErrNoIcon)
+	}
+	return decodePNGDataURL(dataURL)
+}
+
+// iconCandidates lists the icon variants in preference order for theme, followed by those of the
+// other theme.
+func (e Entry) iconCandidates(theme Theme) []string {
+	other := Dark
+	if theme == Dark {
+		other = Light
+	}
+	return append(e.iconVariants(theme), e.iconVariants(other)...)
+}
+
+// iconVariants lists the icons of theme itself in preference order, without fallback.
+func (e Entry) iconVariants(theme Theme) []string {
+	var c CommunityExtensions
+	if e.CommunityExtensions != nil {
+		c = *e.CommunityExtensions
+	}
+	if theme == Dark {
+		return []string{c.IconDark}
+	}
+	return []string{c.Icon, e.MetadataStatement.Icon}
+}
+
+// decodePNGDataURL decodes a "data:image/png;base64," URL and checks the PNG signature.
+func decodePNGDataURL(dataURL string) ([]byte, error) {
+	if dataURL == "" {
+		return nil, ErrNoIcon
+	}
+	payload, ok := strings.CutPrefix(dataURL, pngDataURLPrefix)
+	if !ok {
+		return nil, fmt.Errorf("icon is not a %q URL", pngDataURLPrefix)
+	}
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("decoding icon base64: %w", err)
+	}
+	if !bytes.HasPrefix(raw, pngSignature) {
+		return nil, errors.New("icon payload is not a PNG image")
+	}
+	return raw, nil
+}
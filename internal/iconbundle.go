@@ -0,0 +1,141 @@
+package aaguids
+
+import (
+	"archive/zip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// BundleFormat selects the container written by ExportIconBundle.
+type BundleFormat int
+
+const (
+	// BundleZIP writes a ZIP archive holding manifest.json and one PNG file per icon.
+	BundleZIP BundleFormat = iota
+	// BundleJSON writes a single JSON document holding the manifest and the base64 PNG files.
+	BundleJSON
+)
+
+// IconPaths are the bundle-relative PNG paths of one entry. A nil path means the variant is not
+// available and the frontend should fall back (e.g. to the other variant or a placeholder avatar).
+type IconPaths struct {
+	Light *string `json:"light"`
+	Dark  *string `json:"dark"`
+}
+
+// iconBundle is the BundleJSON document; Files maps paths to standard base64 PNG bytes.
+type iconBundle struct {
+	Manifest map[string]IconPaths `json:"manifest"`
+	Files    map[string]string    `json:"files"`
+}
+
+// ExportIconBundle writes an icon bundle for the embedded dataset. See Provider.ExportIconBundle.
+func ExportIconBundle(w io.Writer, format BundleFormat, entries []string) error {
+	return Default().ExportIconBundle(w, format, entries)
+}
+
+/*
+ExportIconBundle writes the icons of the given entries (every entry when entries is nil) as one
+bundle, so a frontend can fetch them at once instead of embedding data URLs:
+
+  - entries are named by any spelling GetEntry accepts, and listed by their canonical key (see
+    Entry.Key)
+  - files are named "<key>.png" and "<key>-dark.png" (":" in keys is replaced by "_")
+  - the manifest maps every requested entry's key to its IconPaths; entries without a valid icon in a
+    variant are listed with a null path rather than omitted
+  - keys, files and JSON fields are written in sorted order and ZIP timestamps are left zero, so the
+    same dataset always produces byte-identical bundles
+
+Unlike IconFor, variants do not fall back to each other. Unknown keys are an error.
+*/
+func (p *Provider) ExportIconBundle(w io.Writer, format BundleFormat, entries []string) error {
+	s := p.current()
+	keys := entries
+	if keys == nil {
+		keys = s.keys
+	}
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+
+	b := iconBundle{Manifest: make(map[string]IconPaths, len(keys)), Files: make(map[string]string)}
+	files := make(map[string][]byte)
+	norm := p.normalizerChain()
+	for _, k := range keys {
+		stored, ok := s.resolveKey(norm, k)
+		if !ok {
+			return fmt.Errorf("icon bundle: unknown entry %q", k)
+		}
+		e, key := s.entries[stored], canonicalKey(stored)
+		base := strings.ReplaceAll(key, ":", "_")
+		var paths IconPaths
+		if png, ok := firstPNG(e.iconVariants(Light)); ok {
+			name := base + ".png"
+			paths.Light, files[name] = &name, png
+		}
+		if png, ok := firstPNG(e.iconVariants(Dark)); ok {
+			name := base + "-dark.png"
+			paths.Dark, files[name] = &name, png
+		}
+		b.Manifest[key] = paths
+	}
+
+	switch format {
+	case BundleJSON:
+		for name, png := range files {
+			b.Files[name] = base64.StdEncoding.EncodeToString(png)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(b)
+	case BundleZIP:
+		return writeIconZIP(w, b.Manifest, files)
+	}
+	return fmt.Errorf("icon bundle: unsupported format %d", int(format))
+}
+
+// writeIconZIP writes manifest.json followed by the PNG files in name order.
+func writeIconZIP(w io.Writer, manifest map[string]IconPaths, files map[string][]byte) error {
+	zw := zip.NewWriter(w)
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	add := func(name string, data []byte, method uint16) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			return fmt.Errorf("icon bundle: %s: %w", name, err)
+		}
+		_, err = f.Write(data)
+		return err
+	}
+	if err := add("manifest.json", raw, zip.Deflate); err != nil {
+		return err
+	}
+	for _, name := range names {
+		// PNG data is already compressed.
+		if err := add(name, files[name], zip.Store); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// firstPNG decodes the first well-formed PNG data URL of candidates.
+func firstPNG(candidates []string) ([]byte, bool) {
+	for _, c := range candidates {
+		if png, err := decodePNGDataURL(c); err == nil {
+			return png, true
+		}
+	}
+	return nil, false
+}
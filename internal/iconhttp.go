@@ -0,0 +1,109 @@
+package aaguids
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// iconMaxAge is the Cache-Control max-age of icon responses. Icons change rarely, and the dataset
+// serial in the ETag makes clients revalidate after a refresh once the max-age has passed.
+const iconMaxAge = 7 * 24 * 60 * 60
+
+// ServeIcon serves the icon of an embedded entry. See Provider.ServeIcon.
+func ServeIcon(w http.ResponseWriter, r *http.Request, aaGuid string) {
+	Default().ServeIcon(w, r, aaGuid)
+}
+
+/*
+ServeIcon writes the icon of the entry identified by aaGuid as the response to r, for a route
+such as "GET /aaguids/{aaguid}/icon":
+
+  - ?theme=dark selects the dark variant, falling back to the light one as IconFor does
+  - the body is the decoded PNG with Content-Type image/png
+  - the ETag is strong and combines the dataset serial with the SHA-256 of the body, so it
+    changes with the content and after every refresh; Cache-Control allows caching for a week
+  - a request whose If-None-Match lists the current ETag gets 304 Not Modified without a body
+  - entries without a usable icon, and unknown entries, get 404 Not Found, unless
+    ?fallback=avatar asks for a generated SVG avatar (see iconAvatar), which is the same for
+    the same key and description
+*/
+func (p *Provider) ServeIcon(w http.ResponseWriter, r *http.Request, aaGuid string) {
+	p.serveIcon(w, r, aaGuid, p.uniformLookup.Load())
+}
+
+// serveIcon is ServeIcon with uniform lookup timing chosen by the caller.
+func (p *Provider) serveIcon(w http.ResponseWriter, r *http.Request, aaGuid string, uniform bool) {
+	theme := Light
+	if strings.EqualFold(r.URL.Query().Get("theme"), "dark") {
+		theme = Dark
+	}
+	// The serial and the entry come from one snapshot, so the ETag never pairs an icon with the
+	// serial of a later refresh.
+	s := p.current()
+	var e Entry
+	body, contentType := []byte(nil), "image/png"
+	if k, ok := p.lookupKeyMode(s, aaGuid, uniform); ok {
+		e = s.entries[k]
+		body, _ = e.IconPNG(theme)
+	}
+	if body == nil {
+		if r.URL.Query().Get("fallback") != "avatar" {
+			http.NotFound(w, r)
+			return
+		}
+		body, contentType = iconAvatar(aaGuid, e.DisplayName()), "image/svg+xml"
+	}
+
+	sum := sha256.Sum256(body)
+	etag := fmt.Sprintf(`"%d-%s"`, s.info.Serial, hex.EncodeToString(sum[:16]))
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", "public, max-age="+strconv.Itoa(iconMaxAge))
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(body)
+	}
+}
+
+// etagMatches reports whether an If-None-Match header value lists etag, using the weak comparison
+// RFC 9110 § 13.1.2 prescribes for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+iconAvatar renders a placeholder avatar: the first letter of description (or "?") on a circle
+whose hue is derived from the SHA-256 of key, so an entry always gets the same avatar.
+*/
+func iconAvatar(key, description string) []byte {
+	sum := sha256.Sum256([]byte(strings.ToLower(key)))
+	hue := int(sum[0])<<8 | int(sum[1])
+	letter := "?"
+	if r, _ := utf8.DecodeRuneInString(strings.TrimSpace(description)); r != utf8.RuneError && unicode.IsPrint(r) {
+		letter = string(unicode.ToUpper(r))
+	}
+	return fmt.Appendf(nil, `<svg xmlns="http://www.w3.org/2000/svg" width="64" height="64" viewBox="0 0 64 64">`+
+		`<circle cx="32" cy="32" r="32" fill="hsl(%d,55%%,45%%)"/>`+
+		`<text x="32" y="32" dy=".35em" text-anchor="middle" font-family="sans-serif" font-size="30" fill="#fff">%s</text>`+
+		`</svg>`, hue%360, html.EscapeString(letter))
+}
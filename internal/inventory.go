@@ -0,0 +1,190 @@
+package aaguids
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+CredentialRecord is one row of a stored credential inventory, as exported from a relying party's
+credential store. Fields are kept as the raw text of the dump so that AuditInventory, rather than
+the loader, can report malformed values row by row:
+
+  - CredentialID: the credential identifier, only echoed in findings
+  - AAGUID: the authenticator's AAGUID in any spelling the Provider's NormalizerChain accepts
+  - RegisteredAt: the registration date, YYYY-MM-DD or RFC 3339; "" when unknown
+  - FirmwareVersion: the authenticatorVersion in decimal; "" when unknown
+  - Line: the position of the record in its source (e.g. a CSV line number), only echoed
+*/
+type CredentialRecord struct {
+	CredentialID    string `json:"credentialId"`
+	AAGUID          string `json:"aaguid"`
+	RegisteredAt    string `json:"registeredAt,omitempty"`
+	FirmwareVersion string `json:"firmwareVersion,omitempty"`
+	Line            int    `json:"line,omitempty"`
+}
+
+// FindingKind classifies an AuditFinding.
+type FindingKind string
+
+const (
+	// FindingRevoked means the status that denied the authenticator is REVOKED.
+	FindingRevoked FindingKind = "revoked"
+	// FindingCompromised means the status that denied the authenticator reports a compromise of
+	// user or attestation keys or a user verification bypass.
+	FindingCompromised FindingKind = "compromised"
+	// FindingStatusDenied means the status that denied the authenticator is another of
+	// Policy.DenyStatuses.
+	FindingStatusDenied FindingKind = "status_denied"
+	// FindingBelowCertificationLevel means the entry lacks Policy.MinCertificationLevel.
+	FindingBelowCertificationLevel FindingKind = "below_certification_level"
+	// FindingBelowBiometricLevel means the entry lacks Policy.MinBiometricLevel.
+	FindingBelowBiometricLevel FindingKind = "below_biometric_level"
+	// FindingNoMetadataStatement means the entry has no FIDO metadata statement and the policy
+	// sets Policy.RequireMetadataStatement.
+	FindingNoMetadataStatement FindingKind = "no_metadata_statement"
+	// FindingMetadataTooStale means the dataset is staler than Policy.MaxMetadataStaleness allows,
+	// so the credential could not be judged.
+	FindingMetadataTooStale FindingKind = "metadata_too_stale"
+	// FindingUnknownAuthenticator means the AAGUID is zero or not in the dataset and the policy
+	// denies it.
+	FindingUnknownAuthenticator FindingKind = "unknown_authenticator"
+	// FindingInvalidAAGUID means the record's AAGUID does not parse; nothing else was evaluated.
+	FindingInvalidAAGUID FindingKind = "invalid_aaguid"
+	// FindingIncompleteRecord means the policy accepts the credential today, but the record had
+	// missing or malformed fields (see AuditFinding.Notes) so the audit is weaker than requested.
+	FindingIncompleteRecord FindingKind = "incomplete_record"
+)
+
+/*
+AuditFinding reports one credential of an inventory that needs attention:
+
+  - Record: the audited row
+  - Kind: what is wrong
+  - Detail: a human-readable explanation
+  - Now: the Decision of the policy today; nil for FindingInvalidAAGUID
+  - AtRegistration: the Decision as of the registration date (see AsOf); nil when the date is
+    missing or malformed
+  - SinceRegistration: the credential was acceptable at registration and is not any more, i.e.
+    the problem arose afterwards rather than being accepted by mistake
+  - Notes: input problems that did not stop the evaluation, such as a missing registration date
+*/
+type AuditFinding struct {
+	Record            CredentialRecord `json:"record"`
+	Kind              FindingKind      `json:"kind"`
+	Detail            string           `json:"detail"`
+	Now               *Decision        `json:"now,omitempty"`
+	AtRegistration    *Decision        `json:"atRegistration,omitempty"`
+	SinceRegistration bool             `json:"sinceRegistration"`
+	Notes             []string         `json:"notes,omitempty"`
+}
+
+// AuditInventory audits rows against the embedded dataset. See Provider.AuditInventory.
+func AuditInventory(ctx context.Context, rows []CredentialRecord, pol Policy) []AuditFinding {
+	return Default().AuditInventory(ctx, rows, pol)
+}
+
+/*
+AuditInventory evaluates pol for every credential of rows both as of its registration date and
+now, and returns the findings in row order: credentials whose authenticator is now revoked,
+compromised, below the required certification or biometric level, or unknown, plus rows that
+could not be fully audited. Credentials the policy accepts with a complete record produce no
+finding.
+
+Malformed input never aborts the run: an AAGUID that does not parse yields FindingInvalidAAGUID for
+its row, and a missing or malformed registration date or firmware version is noted on the row's
+finding while the rest of the evaluation proceeds. When ctx is done, the findings of the rows
+audited so far are returned.
+
+The whole audit runs against one snapshot, so an Update landing mid-run is not observed.
+*/
+func (p *Provider) AuditInventory(ctx context.Context, rows []CredentialRecord, pol Policy) []AuditFinding {
+	s, norm := p.current(), p.normalizerChain()
+	var findings []AuditFinding
+	for _, row := range rows {
+		if ctx.Err() != nil {
+			break
+		}
+		if f, ok := auditCredential(s, norm, pol, row); ok {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// auditCredential audits one row, reporting false when it needs no attention.
+func auditCredential(s *snapshot, norm NormalizerChain, pol Policy, row CredentialRecord) (AuditFinding, bool) {
+	f := AuditFinding{Record: row}
+	if _, _, err := norm.Parse(row.AAGUID); err != nil {
+		f.Kind, f.Detail = FindingInvalidAAGUID, err.Error()
+		return f, true
+	}
+
+	var opts []EvaluateOption
+	if v := strings.TrimSpace(row.FirmwareVersion); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			opts = append(opts, WithAuthenticatorVersion(n))
+		} else {
+			f.Notes = append(f.Notes, fmt.Sprintf("firmware version %q is not a number; evaluated for every version", v))
+		}
+	}
+
+	evaluate := func(opts ...EvaluateOption) *Decision {
+		d := newEvaluation(opts).run(s, norm, pol, row.AAGUID)
+		return &d
+	}
+	f.Now = evaluate(opts...)
+
+	if v := strings.TrimSpace(row.RegisteredAt); v == "" {
+		f.Notes = append(f.Notes, "no registration date; evaluated as of now only")
+	} else if t, ok := parseISODate(v); ok {
+		f.AtRegistration = evaluate(append(opts, AsOf(t))...)
+	} else {
+		f.Notes = append(f.Notes, fmt.Sprintf("registration date %q is not a date; evaluated as of now only", v))
+	}
+
+	if f.Now.Allowed {
+		if len(f.Notes) == 0 {
+			return AuditFinding{}, false
+		}
+		f.Kind, f.Detail = FindingIncompleteRecord, "accepted now, but "+f.Notes[0]
+		return f, true
+	}
+	f.Kind = findingKind(*f.Now)
+	f.SinceRegistration = f.AtRegistration != nil && f.AtRegistration.Allowed
+	f.Detail = string(f.Now.Reason)
+	if f.Now.Status != "" {
+		f.Detail += ": status " + string(f.Now.Status)
+	}
+	if f.SinceRegistration {
+		f.Detail += " (acceptable at registration)"
+	}
+	return f, true
+}
+
+// findingKind classifies a rejecting Decision.
+func findingKind(d Decision) FindingKind {
+	switch d.Reason {
+	case ReasonStatusDenied:
+		switch d.Status {
+		case REVOKED:
+			return FindingRevoked
+		case ATTESTATION_KEY_COMPROMISE, USER_KEY_REMOTE_COMPROMISE, USER_KEY_PHYSICAL_COMPROMISE, USER_VERIFICATION_BYPASS:
+			return FindingCompromised
+		}
+		return FindingStatusDenied
+	case ReasonBelowCertificationLevel:
+		return FindingBelowCertificationLevel
+	case ReasonBelowBiometricLevel:
+		return FindingBelowBiometricLevel
+	case ReasonNoMetadataStatement:
+		return FindingNoMetadataStatement
+	case ReasonMetadataTooStale:
+		return FindingMetadataTooStale
+	case ReasonInvalidAAGUID:
+		return FindingInvalidAAGUID
+	}
+	return FindingUnknownAuthenticator
+}
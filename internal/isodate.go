@@ -0,0 +1,93 @@
+package aaguids
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidISO8601Date reports a date that is neither "2006-01-02" nor an RFC 3339 timestamp.
+var ErrInvalidISO8601Date = errors.New("aaguids: invalid ISO 8601 date")
+
+/*
+ISO8601Date is a date as MDS publishes it in effectiveDate and similar members: usually date-only
+("2006-01-02"), which time.Time's own JSON decoding rejects, occasionally a full RFC 3339 timestamp
+with a zone suffix. DateOnly records which form it was read from, so it encodes back to the same
+form; date-only values are midnight UTC. The zero value stands for an unset date and encodes as "".
+
+It defines its JSON and text methods itself, shadowing those promoted from the embedded time.Time,
+which would reject date-only input and re-encode a date-only value as an RFC 3339 timestamp, so it
+works the same as a JSON value, a flag value or a map key. StatusReport and BiometricStatusReport keep
+EffectiveDate as the *string of the BLOB, which the generated dataset and existing callers rely on,
+and parse it with EffectiveTime.
+*/
+type ISO8601Date struct {
+	time.Time
+	DateOnly bool
+}
+
+// ParseISO8601Date parses s as "2006-01-02" or an RFC 3339 timestamp, failing with an error
+// wrapping ErrInvalidISO8601Date otherwise.
+func ParseISO8601Date(s string) (ISO8601Date, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return ISO8601Date{Time: t, DateOnly: true}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return ISO8601Date{Time: t}, nil
+	}
+	return ISO8601Date{}, boundedErrorf("%w: %q", ErrInvalidISO8601Date, s)
+}
+
+// String returns the date in the form it was read from, or "" for the zero value.
+func (d ISO8601Date) String() string {
+	switch {
+	case d.IsZero():
+		return ""
+	case d.DateOnly:
+		return d.Format(time.DateOnly)
+	default:
+		return d.Format(time.RFC3339Nano)
+	}
+}
+
+// MarshalJSON encodes d as a JSON string in its String form.
+func (d ISO8601Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+/*
+UnmarshalJSON decodes a JSON string in either form ParseISO8601Date accepts. The empty string and
+null, both seen in the wild for missing dates, decode to the zero value.
+*/
+func (d *ISO8601Date) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*d = ISO8601Date{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidISO8601Date, err)
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// MarshalText encodes d in its String form.
+func (d ISO8601Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes either form ParseISO8601Date accepts; the empty string decodes to the
+// zero value.
+func (d *ISO8601Date) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*d = ISO8601Date{}
+		return nil
+	}
+	parsed, err := ParseISO8601Date(string(b))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
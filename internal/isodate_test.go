@@ -0,0 +1,86 @@
+package aaguids
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestISO8601DateRoundTrip(t *testing.T) {
+	tests := []struct {
+		in       string
+		dateOnly bool
+		want     time.Time
+	}{
+		{"2024-01-02", true, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"2024-01-02T03:04:05Z", false, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2024-01-02T03:04:05+02:00", false, time.Date(2024, 1, 2, 1, 4, 5, 0, time.UTC)},
+		{"2024-01-02T03:04:05.5-07:00", false, time.Date(2024, 1, 2, 10, 4, 5, 5e8, time.UTC)},
+	}
+	for _, tt := range tests {
+		var d ISO8601Date
+		if err := json.Unmarshal([]byte(`"`+tt.in+`"`), &d); err != nil {
+			t.Errorf("decoding %q: %v", tt.in, err)
+			continue
+		}
+		if !d.Equal(tt.want) || d.DateOnly != tt.dateOnly {
+			t.Errorf("decoding %q = %v (date-only %v), want %v (date-only %v)", tt.in, d.Time, d.DateOnly, tt.want, tt.dateOnly)
+		}
+		b, err := json.Marshal(d)
+		if err != nil || string(b) != `"`+tt.in+`"` {
+			t.Errorf("re-encoding %q = %s, %v; want it unchanged", tt.in, b, err)
+		}
+		text, _ := d.MarshalText()
+		var again ISO8601Date
+		if err := again.UnmarshalText(text); err != nil || again != d {
+			t.Errorf("text round trip of %q = %v, %v", tt.in, again, err)
+		}
+	}
+}
+
+func TestISO8601DateUnsetAndInvalid(t *testing.T) {
+	var v struct {
+		Date ISO8601Date  `json:"date"`
+		Ptr  *ISO8601Date `json:"ptr"`
+	}
+	for _, in := range []string{`{"date":null,"ptr":null}`, `{"date":"","ptr":null}`, `{}`} {
+		v.Date, v.Ptr = ISO8601Date{}, nil
+		if err := json.Unmarshal([]byte(in), &v); err != nil {
+			t.Errorf("decoding %s: %v", in, err)
+		}
+		if !v.Date.IsZero() || v.Ptr != nil {
+			t.Errorf("decoding %s = %+v, want unset dates", in, v)
+		}
+	}
+	if b, _ := json.Marshal(v); string(b) != `{"date":"","ptr":null}` {
+		t.Errorf("encoding unset dates = %s", b)
+	}
+	for _, in := range []string{`"2024-1-2"`, `"2024-01-02T03:04:05"`, `"02/01/2024"`, `"2024-13-01"`, `20240102`} {
+		var d ISO8601Date
+		if err := json.Unmarshal([]byte(in), &d); !errors.Is(err, ErrInvalidISO8601Date) {
+			t.Errorf("decoding %s: error = %v, want ErrInvalidISO8601Date", in, err)
+		}
+	}
+	keys := map[ISO8601Date]bool{}
+	if err := json.Unmarshal([]byte(`{"2024-01-02":true}`), &keys); err != nil || !keys[ISO8601Date{Time: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), DateOnly: true}] {
+		t.Errorf("decoding a date map key = %v, %v", keys, err)
+	}
+}
+
+func TestStatusReportEffectiveTime(t *testing.T) {
+	for in, want := range map[string]time.Time{
+		"2024-01-02":                time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		"2024-01-02T03:04:05+02:00": time.Date(2024, 1, 2, 1, 4, 5, 0, time.UTC),
+	} {
+		got, ok := StatusReport{EffectiveDate: goPtr(in)}.EffectiveTime()
+		if !ok || !got.Equal(want) {
+			t.Errorf("EffectiveTime(%q) = %v, %v; want %v", in, got, ok, want)
+		}
+	}
+	for _, r := range []StatusReport{{}, {EffectiveDate: goPtr("")}, {EffectiveDate: goPtr("soon")}} {
+		if _, ok := r.EffectiveTime(); ok {
+			t.Errorf("EffectiveTime of %v reported a date", r.EffectiveDate)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package aaguids
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sort"
+)
+
+/*
+LegalHeaderHash returns the "sha256:<hex>" digest of a metadata statement legalHeader, the form
+recorded in Dataset.LegalHeaderHashes and accepted by WithAcceptedLegalHeader. MDS requires users
+of metadata statements to accept these terms (FIDO Metadata Statement § 4 “legalHeader”).
+*/
+func LegalHeaderHash(header string) string {
+	sum := sha256.Sum256([]byte(header))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ComputeLegalHeaderHashes returns the sorted LegalHeaderHash of every distinct non-empty legal
+// header among entries.
+func ComputeLegalHeaderHashes(entries map[string]Entry) []string {
+	return legalHeaderHashes(distinctLegalHeaders(entries))
+}
+
+// LegalHeaders returns the distinct legal headers of the embedded dataset. See
+// Provider.LegalHeaders.
+func LegalHeaders() []string {
+	return Default().LegalHeaders()
+}
+
+/*
+LegalHeaders returns the distinct non-empty legal headers of the entries in the current snapshot,
+sorted. A dataset merged from several sources (see MergeEntries) can carry one header per source,
+since each entry keeps the header of the statement it was built from.
+*/
+func (p *Provider) LegalHeaders() []string {
+	return append([]string(nil), p.current().legalHeaders...)
+}
+
+/*
+WithAcceptedLegalHeader records that the terms of the legal header with the given LegalHeaderHash
+have been accepted. Once at least one header is accepted, every Update that brings in a header not
+accepted yet logs a warning with the Provider's logger (see WithLogger), so a change of terms
+upstream does not go unnoticed. Unaccepted headers already present in the initial dataset are
+reported the same way.
+*/
+func WithAcceptedLegalHeader(hash string) ProviderOption {
+	return func(p *Provider) { p.AcceptLegalHeader(hash) }
+}
+
+// AcceptLegalHeader records acceptance of a legal header like WithAcceptedLegalHeader, for
+// Providers that already exist such as Default. It does not re-check the current snapshot.
+func (p *Provider) AcceptLegalHeader(hash string) {
+	p.legalMu.Lock()
+	defer p.legalMu.Unlock()
+	if p.acceptedHeaders == nil {
+		p.acceptedHeaders = make(map[string]bool)
+	}
+	p.acceptedHeaders[hash] = true
+}
+
+// distinctLegalHeaders returns the sorted distinct non-empty legal headers of entries.
+func distinctLegalHeaders(entries map[string]Entry) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, e := range entries {
+		if h := e.MetadataStatement.LegalHeader; h != "" && !seen[h] {
+			seen[h] = true
+			out = append(out, h)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// legalHeaderHashes returns the sorted hashes of headers.
+func legalHeaderHashes(headers []string) []string {
+	if len(headers) == 0 {
+		return nil
+	}
+	out := make([]string, len(headers))
+	for i, h := range headers {
+		out[i] = LegalHeaderHash(h)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// checkLegalHeaders warns about the headers of cur that were not in old and are not accepted.
+func (p *Provider) checkLegalHeaders(old, cur *snapshot) {
+	p.legalMu.Lock()
+	defer p.legalMu.Unlock()
+	if len(p.acceptedHeaders) == 0 {
+		return
+	}
+	var before map[string]bool
+	if old != nil {
+		before = make(map[string]bool, len(old.info.LegalHeaderHashes))
+		for _, h := range old.info.LegalHeaderHashes {
+			before[h] = true
+		}
+	}
+	for _, h := range cur.info.LegalHeaderHashes {
+		if !p.acceptedHeaders[h] && !before[h] {
+			p.log().Warn("aaguids: dataset legal header has not been accepted",
+				slog.String("hash", h), slog.Int("serial", cur.info.Serial))
+		}
+	}
+}
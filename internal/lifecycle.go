@@ -0,0 +1,62 @@
+package aaguids
+
+import "time"
+
+/*
+Changelog is the cumulative record the generator keeps across runs (changelog.json next to the
+generated package) and stamps into metadata.go. MDS does not say when an entry first appeared, so
+the generator records it itself:
+
+  - BeganSerial / Began: the MDS serial and date (YYYY-MM-DD) of the first run that kept the
+    changelog; nothing is known about entries before it
+  - Entries: one LifecycleRecord per dataset key in canonical form (see Entry.Key); entries removed
+    upstream keep theirs
+*/
+type Changelog struct {
+	BeganSerial int                        `json:"beganSerial"`
+	Began       string                     `json:"began"`
+	Entries     map[string]LifecycleRecord `json:"entries"`
+}
+
+/*
+LifecycleRecord tracks one entry across generator runs:
+
+  - FirstSeenSerial / FirstSeen: the run that first saw the entry; unset for entries already
+    present when the changelog began
+  - LastChangedSerial / LastChanged: the last run in which the entry's content changed; unset
+    when it has not changed since it was first recorded
+  - Digest: "sha256:<hex>" of the entry's JSON encoding in the latest run that included it
+*/
+type LifecycleRecord struct {
+	FirstSeenSerial   int    `json:"firstSeenSerial,omitempty"`
+	FirstSeen         string `json:"firstSeen,omitempty"`
+	LastChangedSerial int    `json:"lastChangedSerial,omitempty"`
+	LastChanged       string `json:"lastChanged,omitempty"`
+	Digest            string `json:"digest"`
+}
+
+/*
+EntryLifecycle returns when the entry identified by aaGuid (an AAGUID in any spelling ParseAAGUID
+accepts, or a synthetic UAF or U2F key) first appeared in the embedded changelog and when it last
+changed, at the granularity of generator runs. ok is false for entries the changelog has never seen.
+
+The zero time is the documented sentinel for "unknown": FirstSeen is zero for entries that were
+already present when the changelog began, so they are never mistaken for brand-new models, and
+LastChanged is zero for entries that have not changed since they were first recorded.
+*/
+func EntryLifecycle(aaGuid string) (firstSeen, lastChanged time.Time, ok bool) {
+	r, ok := changelog.Entries[canonicalKey(aaGuid)]
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return parseChangelogDate(r.FirstSeen), parseChangelogDate(r.LastChanged), true
+}
+
+// parseChangelogDate parses a changelog date, returning the zero time for an unset one.
+func parseChangelogDate(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
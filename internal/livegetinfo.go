@@ -0,0 +1,167 @@
+package aaguids
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DiscrepancySeverity classifies a Discrepancy found by CompareWithLiveGetInfo.
+type DiscrepancySeverity string
+
+const (
+	// DiscrepancyBenign: the device offers more than, or is configured differently from, what the
+	// metadata describes, as expected of firmware newer than the metadata statement.
+	DiscrepancyBenign DiscrepancySeverity = "benign"
+	// DiscrepancySuspicious: the metadata claims something the device does not have, or the device
+	// identifies as another model; the hardware may not be what its metadata describes.
+	DiscrepancySuspicious DiscrepancySeverity = "suspicious"
+)
+
+/*
+Discrepancy is one difference between the authenticatorGetInfo of a metadata statement and the
+getInfo response of a device:
+
+  - Field: the getInfo member, with the option ID for options, e.g. "versions" or "options.uv"
+  - Metadata / Device: the values on either side, formatted for display; "" when absent
+  - Severity: see DiscrepancySeverity
+  - Reason: why it was classified so
+*/
+type Discrepancy struct {
+	Field    string              `json:"field"`
+	Metadata string              `json:"metadata"`
+	Device   string              `json:"device"`
+	Severity DiscrepancySeverity `json:"severity"`
+	Reason   string              `json:"reason"`
+}
+
+// statefulOptions are the CTAP options whose false value still means the feature is supported,
+// only not configured or enabled (see OptionState); for the others false means unsupported.
+var statefulOptions = map[string]bool{
+	"clientPin": true, "uv": true, "bioEnroll": true, "userVerificationMgmtPreview": true,
+	"ep": true, "alwaysUv": true,
+}
+
+/*
+CompareWithLiveGetInfo compares the authenticatorGetInfo of e's metadata statement with live, the
+getInfo response read from the device over CTAP2 by the caller, e.g. on a provisioning station
+before devices are issued. Each difference is classified:
+
+  - aaguid: a device AAGUID other than e's is suspicious; it is compared even when e has no getInfo
+  - versions, transports, algorithms: members the metadata lists and the device lacks are
+    suspicious, members only the device has are benign
+  - options: for options where false still means supported (clientPin, uv, bioEnroll, ep, alwaysUv,
+    ...), a supported option missing on the device is suspicious, any other difference benign, since
+    devices are rarely configured like the reference unit; for the others, an option the metadata
+    reports true and the device not is suspicious, the reverse benign, with "up" defaulting to true;
+    any difference in "plat" is suspicious, as it tells platform and roaming authenticators apart
+  - maxMsgSize: a smaller or missing device value is suspicious, a larger one benign
+
+The result is in that field order, options by ID, and nil if nothing differs. Members not listed
+above, such as firmwareVersion or remainingDiscoverableCredentials, change with firmware or use and
+are not compared.
+*/
+func CompareWithLiveGetInfo(e Entry, live AuthenticatorGetInfo) []Discrepancy {
+	var out []Discrepancy
+	add := func(field, meta, dev string, sev DiscrepancySeverity, reason string) {
+		out = append(out, Discrepancy{Field: field, Metadata: meta, Device: dev, Severity: sev, Reason: reason})
+	}
+
+	if live.AAGUID != "" && e.AAGUID != "" {
+		want, errWant := e.ParsedAAGUID()
+		got, errGot := parseGetInfoAAGUID(live.AAGUID)
+		if errWant == nil && (errGot != nil || got != want) {
+			add("aaguid", want.String(), live.AAGUID, DiscrepancySuspicious, "the device identifies as another model")
+		}
+	}
+	meta, ok := e.GetInfo()
+	if !ok {
+		return out
+	}
+
+	compareSets := func(field string, metaVals, devVals []string) {
+		for _, v := range metaVals {
+			if !slices.Contains(devVals, v) {
+				add(field, v, "", DiscrepancySuspicious, "listed in metadata, missing on the device")
+			}
+		}
+		for _, v := range devVals {
+			if !slices.Contains(metaVals, v) {
+				add(field, "", v, DiscrepancyBenign, "only on the device, e.g. newer firmware")
+			}
+		}
+	}
+	compareSets("versions", meta.Versions, live.Versions)
+
+	ids := slices.Collect(maps.Keys(meta.Options))
+	for id := range live.Options {
+		if _, ok := meta.Options[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		m, d := meta.Option(id), live.Option(id)
+		if m == d {
+			continue
+		}
+		field := "options." + id
+		switch {
+		case id == "plat":
+			if optionEnabled(id, m) != optionEnabled(id, d) {
+				add(field, m.String(), d.String(), DiscrepancySuspicious, "platform and roaming authenticators differ")
+			}
+		case statefulOptions[id] && d == OptionAbsent:
+			add(field, m.String(), d.String(), DiscrepancySuspicious, "supported in metadata, unsupported on the device")
+		case statefulOptions[id]:
+			add(field, m.String(), d.String(), DiscrepancyBenign, optionStateReason(m))
+		case optionEnabled(id, m) && !optionEnabled(id, d):
+			add(field, m.String(), d.String(), DiscrepancySuspicious, "supported in metadata, unsupported on the device")
+		case !optionEnabled(id, m) && optionEnabled(id, d):
+			add(field, m.String(), d.String(), DiscrepancyBenign, "only on the device, e.g. newer firmware")
+		}
+	}
+
+	compareSets("transports", meta.Transports, live.Transports)
+	compareSets("algorithms", algorithmNames(meta.Algorithms), algorithmNames(live.Algorithms))
+
+	switch m, d := meta.MaxMsgSize, live.MaxMsgSize; {
+	case m == nil && d != nil:
+		add("maxMsgSize", "", strconv.FormatUint(*d, 10), DiscrepancyBenign, "only on the device, e.g. newer firmware")
+	case m != nil && d == nil:
+		add("maxMsgSize", strconv.FormatUint(*m, 10), "", DiscrepancySuspicious, "listed in metadata, missing on the device")
+	case m != nil && *d < *m:
+		add("maxMsgSize", strconv.FormatUint(*m, 10), strconv.FormatUint(*d, 10), DiscrepancySuspicious, "smaller on the device")
+	case m != nil && *d > *m:
+		add("maxMsgSize", strconv.FormatUint(*m, 10), strconv.FormatUint(*d, 10), DiscrepancyBenign, "larger on the device, e.g. newer firmware")
+	}
+	return out
+}
+
+// optionEnabled reports whether a non-stateful option is supported, applying the CTAP default of
+// true for "up" and false for the rest when the option is absent.
+func optionEnabled(id string, s OptionState) bool {
+	if s == OptionAbsent {
+		return id == "up"
+	}
+	return s == OptionTrue
+}
+
+// optionStateReason explains a difference in a stateful option supported on both sides.
+func optionStateReason(meta OptionState) string {
+	if meta == OptionAbsent {
+		return "only on the device, e.g. newer firmware"
+	}
+	return "supported on both, configured differently"
+}
+
+// algorithmNames formats credential parameters as "type:alg" for comparison, e.g. "public-key:-7".
+func algorithmNames(params []PublicKeyCredentialParameters) []string {
+	out := make([]string, len(params))
+	for i, p := range params {
+		out[i] = fmt.Sprintf("%s:%d", strings.ToLower(p.Type), p.Alg)
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package aaguids
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// The LogValue methods below keep log lines compact: icons, legal headers and certificates are
+// replaced by a length note, and nested collections are summarized by count.
+
+// LogValue implements slog.LogValuer with the fields needed to identify an entry in a log line.
+func (e Entry) LogValue() slog.Value {
+	s := e.Summary()
+	attrs := []slog.Attr{
+		slog.String("aaguid", s.AAGUID),
+		slog.String("description", s.Description),
+	}
+	if s.Status != "" {
+		attrs = append(attrs, slog.String("status", string(s.Status)))
+	}
+	if s.StatusDate != "" {
+		attrs = append(attrs, slog.String("statusDate", s.StatusDate))
+	}
+	if s.CertificationLevel != "" {
+		attrs = append(attrs, slog.String("certLevel", string(s.CertificationLevel)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// LogValue implements slog.LogValuer, summarizing the statement without its icon or legal text.
+func (m MetadataStatement) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("aaguid", m.AAGUID),
+		slog.String("description", m.Description),
+		slog.String("protocolFamily", m.ProtocolFamily),
+		slog.Int("schema", int(m.Schema)),
+		slog.Uint64("authenticatorVersion", m.AuthenticatorVersion),
+		slog.Int("attestationRoots", len(m.AttestationRootCertificates)),
+		slog.Int("authenticationAlgorithms", len(m.AuthenticationAlgorithms)),
+	}
+	if m.AAID != "" {
+		attrs = append(attrs, slog.String("aaid", m.AAID))
+	}
+	if m.Icon != "" {
+		attrs = append(attrs, slog.String("icon", elided(m.Icon)))
+	}
+	if m.LegalHeader != "" {
+		attrs = append(attrs, slog.String("legalHeader", elided(m.LegalHeader)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// LogValue implements slog.LogValuer, omitting unset optional fields and eliding the certificate.
+func (r StatusReport) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("status", string(r.Status))}
+	if r.EffectiveDate != nil {
+		attrs = append(attrs, slog.String("effectiveDate", *r.EffectiveDate))
+	}
+	if r.AuthenticatorVersion != nil {
+		attrs = append(attrs, slog.Uint64("authenticatorVersion", *r.AuthenticatorVersion))
+	}
+	if r.URL != nil {
+		attrs = append(attrs, slog.String("url", *r.URL))
+	}
+	if r.CertificateNumber != nil {
+		attrs = append(attrs, slog.String("certificateNumber", *r.CertificateNumber))
+	}
+	if r.Certificate != nil {
+		attrs = append(attrs, slog.String("certificate", elided(*r.Certificate)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+/*
+Redacted returns a deep copy of e in which icons, legal headers, attestation root certificates and
+status report certificates are replaced by a length note. Everything else is kept, so the copy is
+safe to attach to error reports without dragging kilobytes of base64 along.
+*/
+func (e Entry) Redacted() Entry {
+	r := e
+	m := &r.MetadataStatement
+	m.LegalHeader = elided(m.LegalHeader)
+	m.Icon = elided(m.Icon)
+	if c := r.CommunityExtensions; c != nil {
+		cc := *c
+		cc.Icon, cc.IconDark = elided(c.Icon), elided(c.IconDark)
+		cc.Tags = append([]string(nil), c.Tags...)
+		r.CommunityExtensions = &cc
+	}
+	m.AttestationCertificateKeyIdentifiers = append([]string(nil), m.AttestationCertificateKeyIdentifiers...)
+	m.AuthenticationAlgorithms = append([]string(nil), m.AuthenticationAlgorithms...)
+	m.AttestationTypes = append([]string(nil), m.AttestationTypes...)
+	m.AttachmentHint = append([]string(nil), m.AttachmentHint...)
+	if m.UserVerificationDetails != nil {
+		uvd := make([][]VerificationMethod, len(m.UserVerificationDetails))
+		for i, and := range m.UserVerificationDetails {
+			uvd[i] = append([]VerificationMethod(nil), and...)
+		}
+		m.UserVerificationDetails = uvd
+	}
+	if m.AttestationRootCertificates != nil {
+		roots := make([]string, len(m.AttestationRootCertificates))
+		for i, c := range m.AttestationRootCertificates {
+			roots[i] = elided(c)
+		}
+		m.AttestationRootCertificates = roots
+	}
+	if m.AlternativeDescriptions != nil {
+		alt := make(AlternativeDescription, len(m.AlternativeDescriptions))
+		for k, v := range m.AlternativeDescriptions {
+			alt[k] = v
+		}
+		m.AlternativeDescriptions = alt
+	}
+	r.AttestationCertificateKeyIdentifiers = append([]string(nil), e.AttestationCertificateKeyIdentifiers...)
+	r.BiometricStatusReports = append([]BiometricStatusReport(nil), e.BiometricStatusReports...)
+	if e.StatusReports != nil {
+		r.StatusReports = make([]StatusReport, len(e.StatusReports))
+		for i, sr := range e.StatusReports {
+			if sr.Certificate != nil {
+				sr.Certificate = goPtr(elided(*sr.Certificate))
+			}
+			r.StatusReports[i] = sr
+		}
+	}
+	return r
+}
+
+// elided replaces a non-empty bulky value with a note of its length.
+func elided(s string) string {
+	if s == "" {
+		return ""
+	}
+	return fmt.Sprintf("[elided %d bytes]", len(s))
+}
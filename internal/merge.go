@@ -0,0 +1,238 @@
+package aaguids
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// SourceEntries is one input of MergeEntries: the entries of an upstream feed, the label its
+// fields are attributed to (e.g. a DatasetSource name), optionally the upstream JSON of the
+// entries by key for RawEntryJSON (see BLOBPayload.RawEntriesByKey), and optionally the identity
+// and freshness of the feed for MergedSources.
+type SourceEntries struct {
+	Label   string
+	Entries map[string]Entry
+	Raw     map[string]json.RawMessage
+	Source  DatasetSource
+}
+
+// MergedSources returns the Dataset.Sources of a merge of sources, in priority order: each
+// source's Source, named by its Label if Source.Name is unset, with EntryCount set to the number
+// of entries it contributed.
+func MergedSources(sources ...SourceEntries) []DatasetSource {
+	out := make([]DatasetSource, len(sources))
+	for i, src := range sources {
+		out[i] = src.Source
+		if out[i].Name == "" {
+			out[i].Name = src.Label
+		}
+		out[i].EntryCount = len(src.Entries)
+	}
+	return out
+}
+
+// FieldProvenance maps dataset keys to the fields of their entry that came from a lower-priority
+// source, as JSON field path (e.g. "metadataStatement.icon") → source label.
+type FieldProvenance map[string]map[string]string
+
+/*
+MergeEntries merges sources field by field, in descending priority order. For every key, the entry
+of the highest-priority source that has the key is the base, and each field that is unset there
+(zero value, nil or empty) is filled from the first lower-priority source that sets it. Nested
+structs such as MetadataStatement are merged recursively; slices, maps and pointers are taken
+whole from a single source.
+
+The returned FieldProvenance only lists fields that did not come from an entry's base source, so
+its size is bounded by the number of filled-in fields rather than by the dataset size.
+*/
+func MergeEntries(sources ...SourceEntries) (map[string]Entry, FieldProvenance) {
+	merged := make(map[string]Entry)
+	prov := make(FieldProvenance)
+	for i, src := range sources {
+		for k, e := range src.Entries {
+			if _, done := merged[k]; done {
+				continue
+			}
+			base := reflect.New(reflect.TypeFor[Entry]()).Elem()
+			base.Set(reflect.ValueOf(e))
+			fields := make(map[string]string)
+			for _, lower := range sources[i+1:] {
+				if le, ok := lower.Entries[k]; ok {
+					fillZeroFields(base, reflect.ValueOf(le), "", lower.Label, fields)
+				}
+			}
+			merged[k] = base.Interface().(Entry)
+			if len(fields) > 0 {
+				prov[k] = fields
+			}
+		}
+	}
+	return merged, prov
+}
+
+// fillZeroFields copies the fields of src into the unset fields of dst (both structs of the same
+// type), recording each copied field's path in fields.
+func fillZeroFields(dst, src reflect.Value, prefix, label string, fields map[string]string) {
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := jsonFieldName(f)
+		if name == "-" {
+			continue
+		}
+		d, s := dst.Field(i), src.Field(i)
+		if d.Kind() == reflect.Struct {
+			fillZeroFields(d, s, prefix+name+".", label, fields)
+			continue
+		}
+		if isUnset(d) && !isUnset(s) {
+			d.Set(s)
+			fields[prefix+name] = label
+		}
+	}
+}
+
+// isUnset reports whether v is a zero value or an empty slice or map.
+func isUnset(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// jsonFieldName returns the JSON name of a struct field.
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// EntryFieldProvenance returns the field provenance of the embedded entry identified by aaGuid.
+// See Provider.EntryFieldProvenance.
+func EntryFieldProvenance(aaGuid string) map[string]string {
+	return Default().EntryFieldProvenance(aaGuid)
+}
+
+/*
+EntryFieldProvenance returns, for the entry identified by aaGuid, the JSON paths of the fields that
+were filled in from a lower-priority source by MergeEntries, mapped to that source's label. Fields
+not listed came from the entry's base source. It returns nil for unknown entries, entries built
+from a single source, and Providers not built by UpdateMerged. The embedded dataset records the
+provenance of the generator's merge.
+*/
+func (p *Provider) EntryFieldProvenance(aaGuid string) map[string]string {
+	s := p.current()
+	k, _ := p.lookupKey(s, aaGuid)
+	fields := s.provenance[k]
+	if fields == nil {
+		return nil
+	}
+	out := make(map[string]string, len(fields))
+	for k, v := range fields {
+		out[k] = v
+	}
+	return out
+}
+
+// UpdateMerged merges sources with MergeEntries and applies the result as Update does, keeping the
+// field provenance for EntryFieldProvenance and the raw JSON of the sources for RawEntryJSON. If
+// info has no Sources, they are set with MergedSources.
+func (p *Provider) UpdateMerged(info Dataset, sources ...SourceEntries) {
+	entries, prov := MergeEntries(sources...)
+	if limit := effectiveStatusReportLimit(p.maxStatusReports); limit > 0 {
+		for _, k := range slices.Sorted(maps.Keys(entries)) {
+			if cut, ok := limitStatusReports(entries[k], limit); ok {
+				p.log().Warn("aaguids: status reports truncated", "key", canonicalKey(k),
+					"total", len(entries[k].StatusReports), "kept", len(cut.StatusReports))
+				entries[k] = cut
+			}
+		}
+	}
+	if info.Sources == nil {
+		info.Sources = MergedSources(sources...)
+	}
+	p.update(entries, info, prov, mergedRaw(sources), OriginProvided)
+}
+
+// ExportOption adjusts ExportJSON.
+type ExportOption func(*export)
+
+// export is the configuration of one ExportJSON call.
+type export struct {
+	provenance bool
+	mask       *FieldMask
+}
+
+// WithProvenance adds the "provenance" member, the FieldProvenance of the exported entries, to
+// the output of ExportJSON.
+func WithProvenance() ExportOption {
+	return func(x *export) { x.provenance = true }
+}
+
+// WithFieldMask shapes every exported entry with m (see FieldMask.MarshalEntry).
+func WithFieldMask(m FieldMask) ExportOption {
+	return func(x *export) { x.mask = &m }
+}
+
+// ExportJSON writes the embedded dataset as JSON. See Provider.ExportJSON.
+func ExportJSON(w io.Writer, opts ...ExportOption) error {
+	return Default().ExportJSON(w, opts...)
+}
+
+/*
+ExportJSON writes the current snapshot of p to w as one JSON object with the members "dataset"
+(DatasetInfo) and "entries" (every entry by its canonical key, see Entry.Key). With WithProvenance,
+the member "provenance" maps each merged entry's key to its EntryFieldProvenance; it is omitted when
+no field came from a lower-priority source. With WithFieldMask, entries are shaped by the mask.
+*/
+func (p *Provider) ExportJSON(w io.Writer, opts ...ExportOption) error {
+	var x export
+	for _, opt := range opts {
+		opt(&x)
+	}
+	s := p.current()
+	entries := make(map[string]any, len(s.entries))
+	for _, k := range s.keys {
+		ck := canonicalKey(k)
+		if _, dup := entries[ck]; dup {
+			return fmt.Errorf("entries %q and another key canonicalize to %q", k, ck)
+		}
+		if x.mask == nil {
+			entries[ck] = s.entries[k]
+			continue
+		}
+		raw, err := x.mask.MarshalEntry(s.entries[k])
+		if err != nil {
+			return err
+		}
+		entries[ck] = raw
+	}
+	out := struct {
+		Dataset    Dataset         `json:"dataset"`
+		Entries    map[string]any  `json:"entries"`
+		Provenance FieldProvenance `json:"provenance,omitempty"`
+	}{Dataset: s.info, Entries: entries}
+	if x.provenance && s.provenance != nil {
+		out.Provenance = make(FieldProvenance, len(s.provenance))
+		for k, fp := range s.provenance {
+			out.Provenance[canonicalKey(k)] = fp
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return fmt.Errorf("encoding dataset: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package aaguids
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestMergeEntriesThreeSources(t *testing.T) {
+	const icon = "data:image/png;base64,AAAA"
+	// overrides renames one entry, mds carries status, community carries icons and fills in
+	// whatever the other two leave unset. Each source supplies at least one field.
+	overrides := SourceEntries{Label: "overrides", Entries: map[string]Entry{
+		testYubiKey: {AAGUID: testYubiKey, MetadataStatement: MetadataStatement{Description: "YubiKey (internal name)"}},
+	}}
+	mds := SourceEntries{Label: "mds", Entries: map[string]Entry{
+		testYubiKey: {
+			AAGUID:                 testYubiKey,
+			TimeOfLastStatusChange: "2024-01-01",
+			StatusReports:          []StatusReport{report(FIDO_CERTIFIED_L1, "2024-01-01")},
+			MetadataStatement:      MetadataStatement{Description: "YubiKey 5", ProtocolFamily: "fido2"},
+		},
+		testGPM: {AAGUID: testGPM, MetadataStatement: MetadataStatement{Description: "Google Password Manager"}},
+	}}
+	community := SourceEntries{Label: "community", Entries: map[string]Entry{
+		testYubiKey: {
+			AAGUID:            testYubiKey,
+			RogueListURL:      "https://example.com/rogue",
+			MetadataStatement: MetadataStatement{Description: "YubiKey", ProtocolFamily: "u2f", Icon: icon},
+		},
+		testGPM:          {AAGUID: testGPM, MetadataStatement: MetadataStatement{Icon: icon}},
+		testWindowsHello: {AAGUID: testWindowsHello, MetadataStatement: MetadataStatement{Description: "Windows Hello"}},
+	}}
+
+	entries, prov := MergeEntries(overrides, mds, community)
+
+	yk := entries[testYubiKey]
+	ms := yk.MetadataStatement
+	if ms.Description != "YubiKey (internal name)" || ms.ProtocolFamily != "fido2" || ms.Icon != icon {
+		t.Errorf("merged statement %q / %q / %q, want the override's name, mds's family and the community icon", ms.Description, ms.ProtocolFamily, ms.Icon)
+	}
+	if len(yk.StatusReports) != 1 || yk.TimeOfLastStatusChange != "2024-01-01" || yk.RogueListURL != "https://example.com/rogue" {
+		t.Errorf("merged entry %+v, want mds's status and community's rogue list", yk)
+	}
+	if entries[testGPM].MetadataStatement.Icon != icon || entries[testWindowsHello].MetadataStatement.Description != "Windows Hello" {
+		t.Error("entries missing from higher-priority sources were not filled or kept")
+	}
+
+	// Provenance lists exactly the fields filled from below the base source: nothing for
+	// fields the base set, nor for entries that only one source has.
+	want := FieldProvenance{
+		testYubiKey: {
+			"statusReports":                    "mds",
+			"timeOfLastStatusChange":           "mds",
+			"metadataStatement.protocolFamily": "mds",
+			"metadataStatement.icon":           "community",
+			"rogueListURL":                     "community",
+		},
+		testGPM: {"metadataStatement.icon": "community"},
+	}
+	if !maps.EqualFunc(prov, want, maps.Equal) {
+		t.Errorf("provenance %v, want %v", prov, want)
+	}
+
+	// The provider serves the same provenance, and copies of it.
+	p := NewProvider(nil, Dataset{})
+	p.UpdateMerged(Dataset{}, overrides, mds, community)
+	got := p.EntryFieldProvenance(testYubiKey)
+	if !maps.Equal(got, want[testYubiKey]) {
+		t.Errorf("EntryFieldProvenance = %v, want %v", got, want[testYubiKey])
+	}
+	got["statusReports"] = "tampered"
+	if p.EntryFieldProvenance(testYubiKey)["statusReports"] != "mds" {
+		t.Error("modifying the returned provenance changed the provider's")
+	}
+	if p.EntryFieldProvenance(testWindowsHello) != nil {
+		t.Error("single-source entry has provenance")
+	}
+}
@@ -3,6 +3,43 @@ package aaguids
 // metadata is a map linking unique identifier to its corresponding Entry in the Metadata.
 var metadata map[string]Entry
 
+// datasetInfo describes the dataset held in metadata. It is stamped by the generator.
+var datasetInfo Dataset
+
+// fieldProvenance records which fields of merged entries came from a lower-priority feed (see
+// MergeEntries). It is stamped by the generator.
+var fieldProvenance FieldProvenance
+
+// changelog is the generator's cumulative per-entry lifecycle record (see EntryLifecycle). It is
+// stamped by the generator.
+var changelog Changelog
+
+// libraryVersion is the generator release that produced this package. It is stamped by the generator.
+var libraryVersion string
+
+// curatedVendors is the reviewed vendor table from vendors.json. It is stamped by the generator.
+var curatedVendors VendorTable
+
+// curatedRelations is the reviewed relations registry from relations.json. It is stamped by the
+// generator.
+var curatedRelations []AAGUIDRelation
+
+// knownPasskeyProviders lists the AAGUIDs of the passkey provider constants, in the order of
+// passkey-providers.json (see KnownPasskeyProviders). It is stamped by the generator, which also
+// declares one constant per provider after it.
+var knownPasskeyProviders []string
+
+// statusTranslations holds the end-user status descriptions of status-translations.json by
+// language tag (see StatusDescription). It is stamped by the generator.
+var statusTranslations map[string]map[AuthenticatorStatus]string
+
+// minimalMetadata is the status-only fallback dataset built from minimal-aaguids.json (see
+// FromEmbeddedMinimal). It is stamped by the generator.
+var minimalMetadata map[string]Entry
+
+// minimalDatasetInfo describes the dataset held in minimalMetadata. It is stamped by the generator.
+var minimalDatasetInfo Dataset
+
 // goPtr returns a pointer to the given value of any type.
 func goPtr[T any](v T) *T {
 	return &v
@@ -11,6 +48,5 @@ func goPtr[T any](v T) *T {
 // GetEntry retrieves the metadata Entry identified by aaGuid.
 // Returns the Entry and a boolean indicating if it exists in the metadata map.
 func GetEntry(aaGuid string) (e Entry, exists bool) {
-	e, exists = metadata[aaGuid]
-	return
+	return Default().GetEntry(aaGuid)
 }
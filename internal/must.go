@@ -0,0 +1,51 @@
+package aaguids
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// The Must* helpers below panic instead of returning errors. They exist for code generators,
+// tests and one-off scripts working with known-good input; never call them on a request path.
+
+// MustGetEntry is like GetEntry but panics if aaGuid names no entry of the embedded dataset. It
+// accepts every key GetEntry does, synthetic UAF and U2F keys included, and the panic carries the
+// Lookup error saying whether aaGuid was malformed or absent.
+func MustGetEntry(aaGuid string) Entry {
+	e, err := Lookup(aaGuid)
+	switch {
+	case errors.Is(err, ErrUnknownAAGUID):
+		panic(fmt.Sprintf("aaguids: MustGetEntry(%q): not present in dataset serial %d", aaGuid, DatasetInfo().Serial))
+	case err != nil:
+		panic(fmt.Sprintf("aaguids: MustGetEntry(%q): %v", aaGuid, err))
+	}
+	return e
+}
+
+// MustParseAAGUID is like ParseAAGUID but panics if s is malformed.
+func MustParseAAGUID(s string) AAGUID {
+	a, err := ParseAAGUID(s)
+	if err != nil {
+		panic(fmt.Sprintf("aaguids: MustParseAAGUID(%q): %v", s, err))
+	}
+	return a
+}
+
+// MustParseMetadataBLOB is like ParseMetadataBLOB but panics with the verification failure if the
+// JWT does not verify against roots or its payload does not decode.
+func MustParseMetadataBLOB(jwt []byte, roots *x509.CertPool, opts ...ParseOption) BLOBPayload {
+	blob, err := ParseMetadataBLOB(jwt, roots, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("aaguids: MustParseMetadataBLOB(%s): %v", abbreviate(string(jwt), 64), err))
+	}
+	return blob
+}
+
+// abbreviate shortens s to at most n bytes for inclusion in panic messages, noting the full length.
+func abbreviate(s string, n int) string {
+	if len(s) <= n {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%q... (%d bytes)", s[:n], len(s))
+}
@@ -0,0 +1,154 @@
+package aaguids
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+/*
+Normalizer is one hook of a NormalizerChain. Normalize rewrites an AAGUID spelling into one closer
+to the canonical layout and reports whether it applied; it must be pure and safe for concurrent
+use. Name identifies the hook in lookup traces.
+*/
+type Normalizer struct {
+	Name      string
+	Normalize func(string) (string, bool)
+}
+
+/*
+NormalizerChain is the ordered list of hooks a Provider runs on caller-supplied AAGUIDs before
+canonical parsing with ParseAAGUID. Every hook sees the output of the previous ones, so spellings
+combining several quirks (e.g. an uppercase, braced GUID) are handled by composing simple hooks.
+The chain is rerun while any hook applies (up to maxNormalizerPasses times), so a hook appended
+after the built-in ones can still unwrap a spelling they need to see afterwards.
+*/
+type NormalizerChain []Normalizer
+
+// The built-in normalizers, in the order of DefaultNormalizers.
+var (
+	// NormalizeURN strips a "urn:uuid:" prefix (RFC 9562 § 4), in any case.
+	NormalizeURN = Normalizer{Name: "urn", Normalize: func(s string) (string, bool) {
+		const prefix = "urn:uuid:"
+		if len(s) > len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+			return s[len(prefix):], true
+		}
+		return s, false
+	}}
+
+	// NormalizeBraces strips the surrounding braces of the Microsoft GUID spelling "{...}".
+	NormalizeBraces = Normalizer{Name: "braces", Normalize: func(s string) (string, bool) {
+		if len(s) > 2 && s[0] == '{' && s[len(s)-1] == '}' {
+			return s[1 : len(s)-1], true
+		}
+		return s, false
+	}}
+
+	// NormalizeBase64URL decodes the base64url encoding (padded or not) of the 16 raw AAGUID bytes,
+	// as sent by some mobile SDKs.
+	NormalizeBase64URL = Normalizer{Name: "base64url", Normalize: func(s string) (string, bool) {
+		if len(s) != 22 && !(len(s) == 24 && strings.HasSuffix(s, "==")) {
+			return s, false
+		}
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimSuffix(s, "=="))
+		if err != nil || len(b) != len(AAGUID{}) {
+			return s, false
+		}
+		return AAGUID(b).String(), true
+	}}
+
+	// NormalizeDashes inserts the dashes into 32 hex characters.
+	NormalizeDashes = Normalizer{Name: "dashes", Normalize: func(s string) (string, bool) {
+		if len(s) != 32 {
+			return s, false
+		}
+		var a AAGUID
+		if _, err := hex.Decode(a[:], []byte(s)); err != nil {
+			return s, false
+		}
+		return a.String(), true
+	}}
+
+	// NormalizeCase lowercases the hex digits.
+	NormalizeCase = Normalizer{Name: "case", Normalize: func(s string) (string, bool) {
+		l := strings.ToLower(s)
+		return l, l != s
+	}}
+)
+
+// DefaultNormalizers returns the built-in chain every Provider starts with: NormalizeURN,
+// NormalizeBraces, NormalizeBase64URL, NormalizeDashes and NormalizeCase.
+func DefaultNormalizers() NormalizerChain {
+	return NormalizerChain{NormalizeURN, NormalizeBraces, NormalizeBase64URL, NormalizeDashes, NormalizeCase}
+}
+
+// maxNormalizerPasses bounds the reruns of a NormalizerChain, so hooks that keep applying to their
+// own output cannot loop forever.
+const maxNormalizerPasses = 4
+
+// Parse runs the chain on s and parses the result with ParseAAGUID. It also returns the names of
+// the hooks that applied, in order.
+func (c NormalizerChain) Parse(s string) (AAGUID, []string, error) {
+	var matched []string
+	for pass := 0; pass < maxNormalizerPasses; pass++ {
+		applied := false
+		for _, n := range c {
+			if out, ok := n.Normalize(s); ok {
+				s = out
+				applied = true
+				matched = append(matched, n.Name)
+			}
+		}
+		if !applied {
+			break
+		}
+	}
+	id, err := ParseAAGUID(s)
+	return id, matched, err
+}
+
+// WithNormalizers appends hooks to the Provider's NormalizerChain, after the built-in ones.
+func WithNormalizers(hooks ...Normalizer) ProviderOption {
+	return func(p *Provider) { p.AppendNormalizers(hooks...) }
+}
+
+// AppendNormalizers appends hooks to the Provider's NormalizerChain, like WithNormalizers, for
+// Providers that already exist such as Default. Lookups already running keep the previous chain.
+func (p *Provider) AppendNormalizers(hooks ...Normalizer) {
+	p.normMu.Lock()
+	defer p.normMu.Unlock()
+	c := append(p.Normalizers(), hooks...)
+	p.normalizers.Store(&c)
+}
+
+// Normalizers returns a copy of the Provider's NormalizerChain.
+func (p *Provider) Normalizers() NormalizerChain {
+	return append(NormalizerChain(nil), p.normalizerChain()...)
+}
+
+// NormalizeAAGUID returns the canonical lowercase spelling of s, accepted by the default
+// NormalizerChain (see DefaultNormalizers), or the ParseAAGUID error for input it cannot fix.
+func NormalizeAAGUID(s string) (string, error) {
+	id, _, err := defaultChain.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// ParseAAGUID parses s with the Provider's NormalizerChain.
+func (p *Provider) ParseAAGUID(s string) (AAGUID, error) {
+	id, _, err := p.normalizerChain().Parse(s)
+	return id, err
+}
+
+// normalizerChain returns the current chain without copying; callers must not modify it.
+func (p *Provider) normalizerChain() NormalizerChain {
+	if c := p.normalizers.Load(); c != nil {
+		return *c
+	}
+	return defaultChain
+}
+
+// defaultChain is the shared chain of Providers without appended hooks.
+var defaultChain = DefaultNormalizers()
@@ -0,0 +1,149 @@
+package aaguids
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizerSpellings(t *testing.T) {
+	p := NewProvider(readTestBLOB(t, "blob-mixed.json").EntriesByKey(), Dataset{})
+	id, err := ParseAAGUID(testYubiKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.RawURLEncoding.EncodeToString(id[:])
+	upper := strings.ToUpper(testYubiKey)
+	dashless := strings.ReplaceAll(testYubiKey, "-", "")
+
+	for _, tt := range []struct {
+		spelling string
+		matched  []string
+	}{
+		{testYubiKey, nil},
+		{upper, []string{"case"}},
+		{"{" + testYubiKey + "}", []string{"braces"}},
+		{"{" + upper + "}", []string{"braces", "case"}},
+		{"urn:uuid:" + testYubiKey, []string{"urn"}},
+		{"URN:UUID:" + upper, []string{"urn", "case"}},
+		{"urn:uuid:{" + testYubiKey + "}", []string{"urn", "braces"}},
+		{dashless, []string{"dashes"}},
+		{strings.ToUpper(dashless), []string{"dashes"}},
+		{"{" + dashless + "}", []string{"braces", "dashes"}},
+		{b64, []string{"base64url"}},
+		{b64 + "==", []string{"base64url"}},
+	} {
+		got, matched, err := DefaultNormalizers().Parse(tt.spelling)
+		if err != nil || got != id || !slices.Equal(matched, tt.matched) {
+			t.Errorf("Parse(%q) = %v, %q, %v; want %v, %q", tt.spelling, got, matched, err, id, tt.matched)
+		}
+		if s, err := NormalizeAAGUID(tt.spelling); err != nil || s != testYubiKey {
+			t.Errorf("NormalizeAAGUID(%q) = %q, %v; want %q", tt.spelling, s, err, testYubiKey)
+		}
+		if e, ok := p.GetEntry(tt.spelling); !ok || e.Key() != testYubiKey {
+			t.Errorf("GetEntry(%q) = %q, %v; want %q", tt.spelling, e.Key(), ok, testYubiKey)
+		}
+	}
+}
+
+func TestNormalizerInvalidShapes(t *testing.T) {
+	p := NewProvider(readTestBLOB(t, "blob-mixed.json").EntriesByKey(), Dataset{})
+	for _, s := range []string{
+		"",
+		"{}",
+		"urn:uuid:",
+		"{" + testYubiKey,
+		testYubiKey + "}",
+		"{{" + testYubiKey + "}",
+		testYubiKey[:35],
+		testYubiKey + "0",
+		strings.Replace(testYubiKey, "e", "g", 1),
+		strings.ReplaceAll(testYubiKey, "-", "")[:31],
+		strings.ReplaceAll(testYubiKey, "-", "") + "00",
+		"7ogoeXIcSROXdT38zpcHKg=",
+		"7ogoeXIcSROXdT38zpcH!!",
+		strings.ReplaceAll(testYubiKey, "-", "_"),
+	} {
+		if e, ok := p.GetEntry(s); ok {
+			t.Errorf("GetEntry(%q) = %q, want no entry", s, e.Key())
+		}
+		if id, err := p.ParseAAGUID(s); err == nil {
+			t.Errorf("ParseAAGUID(%q) = %v, want an error", s, id)
+		}
+	}
+	// The all-zero AAGUID parses in every spelling but never names an entry.
+	for _, s := range []string{"00000000-0000-0000-0000-000000000000", "{00000000000000000000000000000000}"} {
+		if e, ok := p.GetEntry(s); ok {
+			t.Errorf("GetEntry(%q) = %q, want no entry", s, e.Key())
+		}
+	}
+}
+
+func TestAppendedNormalizer(t *testing.T) {
+	// A hook after the built-in ones unwraps quotes; the rerun then strips the braces inside.
+	quotes := Normalizer{Name: "quotes", Normalize: func(s string) (string, bool) {
+		if len(s) > 2 && s[0] == '"' && s[len(s)-1] == '"' {
+			return s[1 : len(s)-1], true
+		}
+		return s, false
+	}}
+	p := NewProvider(readTestBLOB(t, "blob-mixed.json").EntriesByKey(), Dataset{}, WithNormalizers(quotes))
+	spelling := `"{` + strings.ToUpper(testYubiKey) + `}"`
+
+	if e, ok := p.GetEntry(spelling); !ok || e.Key() != testYubiKey {
+		t.Errorf("GetEntry(%q) = %q, %v; want %q", spelling, e.Key(), ok, testYubiKey)
+	}
+	if _, matched, err := p.Normalizers().Parse(spelling); err != nil || !slices.Equal(matched, []string{"case", "quotes", "braces"}) {
+		t.Errorf("Parse(%q) matched %q, %v", spelling, matched, err)
+	}
+	if _, err := NormalizeAAGUID(spelling); err == nil {
+		t.Errorf("NormalizeAAGUID(%q) succeeded without the appended hook", spelling)
+	}
+
+	// A hook that always applies stops after maxNormalizerPasses.
+	passes := 0
+	loop := Normalizer{Name: "loop", Normalize: func(s string) (string, bool) {
+		passes++
+		return s, true
+	}}
+	if _, _, err := (NormalizerChain{loop}).Parse("not an aaguid"); err == nil || passes != maxNormalizerPasses {
+		t.Errorf("looping hook ran %d times (err %v), want %d and an error", passes, err, maxNormalizerPasses)
+	}
+}
+
+func TestNormalizedGolden(t *testing.T) {
+	// Every spelling of every fixture AAGUID, then the shapes no hook fixes, with what the default
+	// chain makes of each.
+	var inputs []string
+	for k := range readTestBLOB(t, "blob-mixed.json").EntriesByKey() {
+		if isSyntheticKey(k) {
+			continue
+		}
+		id, err := ParseAAGUID(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dashless := strings.ReplaceAll(k, "-", "")
+		b64 := base64.RawURLEncoding.EncodeToString(id[:])
+		inputs = append(inputs, k, strings.ToUpper(k), "{"+k+"}", "urn:uuid:"+k,
+			"URN:UUID:{"+strings.ToUpper(k)+"}", dashless, strings.ToUpper(dashless), b64, b64+"==")
+	}
+	slices.Sort(inputs)
+	inputs = append(inputs, "", "{}", "urn:uuid:", "{"+testYubiKey, testYubiKey[:35],
+		strings.Replace(testYubiKey, "e", "g", 1), strings.ReplaceAll(testYubiKey, "-", "")[:31],
+		strings.ReplaceAll(testYubiKey, "-", "_"), "7ogoeXIcSROXdT38zpcHKg=")
+
+	var b strings.Builder
+	for _, s := range inputs {
+		id, matched, err := DefaultNormalizers().Parse(s)
+		if err != nil {
+			fmt.Fprintf(&b, "%q\terror: %v\n", s, err)
+			continue
+		}
+		fmt.Fprintf(&b, "%q\t%s\t%s\n", s, id, strings.Join(matched, ","))
+	}
+	checkGolden(t, filepath.Join("testdata", "normalized.txt"), []byte(b.String()))
+}
@@ -0,0 +1,195 @@
+package aaguids
+
+import (
+	"iter"
+	"strconv"
+	"strings"
+)
+
+// Template keys of NotificationTemplates besides the status names.
+const (
+	TemplateAdded   = "added"   // the model appeared in the dataset
+	TemplateRemoved = "removed" // the model was removed upstream
+	TemplateGeneric = "generic" // any change without a more specific template
+	TemplateDetails = "details" // the {details} fragment, only filled in when there is a URL
+)
+
+/*
+NotificationTemplates maps template keys to message templates for DraftNotifications. Keys are the
+status a change moved to (e.g. "UPDATE_AVAILABLE", "REVOKED"), TemplateAdded, TemplateRemoved and
+TemplateGeneric. Templates may use these placeholders:
+
+  - {name}: the model's display name
+  - {aaguid}: the AAGUID, or the synthetic key of a UAF or U2F entry
+  - {status} / {previousStatus}: the new and the previous status labels (see AuthenticatorStatus.Label)
+  - {url}: the URL of the latest status report, "" if none
+  - {details}: the TemplateDetails fragment (which may use {url}) if there is a URL, else ""
+  - {users}: the number of affected users
+
+Localize messages by passing a map of translated templates (see WithNotificationTemplates); keys
+missing from it fall back to DefaultNotificationTemplates.
+*/
+type NotificationTemplates map[string]string
+
+// DefaultNotificationTemplates are the English templates DraftNotifications uses unless overridden.
+var DefaultNotificationTemplates = NotificationTemplates{
+	string(UPDATE_AVAILABLE):             "The vendor of your {name} has released a firmware update.{details}",
+	string(REVOKED):                      "Your {name} is no longer trusted by the FIDO Alliance and should be replaced.{details}",
+	string(ATTESTATION_KEY_COMPROMISE):   "The attestation key of some {name} models has been compromised; please review your security key.{details}",
+	string(USER_VERIFICATION_BYPASS):     "A flaw lets your {name} skip user verification; please replace or update it.{details}",
+	string(USER_KEY_REMOTE_COMPROMISE):   "Keys stored on your {name} can be extracted remotely; please replace it.{details}",
+	string(USER_KEY_PHYSICAL_COMPROMISE): "Keys stored on your {name} can be extracted with physical access; keep it safe and consider replacing it.{details}",
+	TemplateAdded:                        "{name} is now listed in the FIDO Metadata Service with status {status}.",
+	TemplateRemoved:                      "Your {name} is no longer listed in the FIDO Metadata Service.",
+	TemplateGeneric:                      "The status of your {name} changed from {previousStatus} to {status}.",
+	TemplateDetails:                      " Details: {url}",
+}
+
+/*
+NotificationDraft is a suggested notice for the users of one model, built by DraftNotifications:
+
+  - AAGUID / Name: the model
+  - Kind / OldStatus / NewStatus / Serial: the ChangeEvent it was drafted from
+  - Severity: of the new status (see AuthenticatorStatus.Severity); SeverityInfo for added models
+    and cleared statuses, SeverityWarning for removed ones
+  - AffectedUsers: the count returned by the caller's lookup
+  - Template: the template key used; TemplateGeneric when no specific template exists
+  - Message: the template with its placeholders filled in
+  - URL: the URL of the latest status report, "" if none
+*/
+type NotificationDraft struct {
+	AAGUID        string              `json:"aaguid"`
+	Name          string              `json:"name"`
+	Kind          ChangeKind          `json:"kind"`
+	OldStatus     AuthenticatorStatus `json:"oldStatus,omitempty"`
+	NewStatus     AuthenticatorStatus `json:"newStatus,omitempty"`
+	Serial        int                 `json:"serial"`
+	Severity      StatusSeverity      `json:"severity"`
+	AffectedUsers int                 `json:"affectedUsers"`
+	Template      string              `json:"template"`
+	Message       string              `json:"message"`
+	URL           string              `json:"url,omitempty"`
+}
+
+// NotificationOption adjusts DraftNotifications.
+type NotificationOption func(*notificationConfig)
+
+// notificationConfig is the configuration of one DraftNotifications call.
+type notificationConfig struct {
+	templates      NotificationTemplates
+	includeUnowned bool
+}
+
+// WithNotificationTemplates overrides templates by key, e.g. with translations; keys it lacks use
+// DefaultNotificationTemplates.
+func WithNotificationTemplates(t NotificationTemplates) NotificationOption {
+	return func(c *notificationConfig) { c.templates = t }
+}
+
+// IncludeUnowned also drafts notices for models no user owns (an affected-user count of 0).
+func IncludeUnowned() NotificationOption {
+	return func(c *notificationConfig) { c.includeUnowned = true }
+}
+
+/*
+DraftNotifications turns change events, e.g. those received from Watch, into notification drafts
+for the users owning the affected models. affectedUsers maps an event's AAGUID to the number of
+users owning that model, and events for models with no users are skipped unless IncludeUnowned is
+given. Every other event yields a draft: transitions without a specific template get a
+TemplateGeneric one, so no change goes unreported. Drafts are returned in event order.
+
+The embedded changelog records when entries changed but not their status transitions, so it cannot
+feed this function; keep the Provider snapshots from before and after an update and Watch them.
+*/
+func DraftNotifications(events iter.Seq[ChangeEvent], affectedUsers func(aaguid string) int, opts ...NotificationOption) []NotificationDraft {
+	var cfg notificationConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	var out []NotificationDraft
+	for ev := range events {
+		users := 0
+		if affectedUsers != nil {
+			users = affectedUsers(ev.AAGUID)
+		}
+		if users == 0 && !cfg.includeUnowned {
+			continue
+		}
+		out = append(out, cfg.draft(ev, users))
+	}
+	return out
+}
+
+// draft builds the NotificationDraft of one event.
+func (cfg notificationConfig) draft(ev ChangeEvent, users int) NotificationDraft {
+	d := NotificationDraft{
+		AAGUID:        ev.AAGUID,
+		Name:          ev.Entry.DisplayName(),
+		Kind:          ev.Kind,
+		OldStatus:     ev.OldStatus,
+		NewStatus:     ev.NewStatus,
+		Serial:        ev.Serial,
+		AffectedUsers: users,
+	}
+	if d.Name == "" {
+		d.Name = ev.AAGUID
+	}
+	if r, ok := ev.Entry.LatestStatus(); ok && r.URL != nil {
+		d.URL = *r.URL
+	}
+
+	var keys []string
+	switch ev.Kind {
+	case ChangeAdded:
+		d.Severity = SeverityInfo
+		keys = []string{TemplateAdded}
+	case ChangeRemoved:
+		d.Severity = SeverityWarning
+		keys = []string{TemplateRemoved}
+	default:
+		d.Severity = SeverityInfo
+		if ev.NewStatus != "" {
+			d.Severity = ev.NewStatus.Severity()
+			keys = []string{string(ev.NewStatus)}
+		}
+	}
+	keys = append(keys, TemplateGeneric)
+
+	fill := strings.NewReplacer(
+		"{name}", d.Name,
+		"{aaguid}", d.AAGUID,
+		"{status}", statusLabel(d.NewStatus),
+		"{previousStatus}", statusLabel(d.OldStatus),
+		"{url}", d.URL,
+		"{users}", strconv.Itoa(users),
+	)
+	details := ""
+	if t, ok := cfg.template(TemplateDetails); ok && d.URL != "" {
+		details = fill.Replace(t)
+	}
+	for _, k := range keys {
+		if t, ok := cfg.template(k); ok {
+			d.Template = k
+			d.Message = fill.Replace(strings.ReplaceAll(t, "{details}", details))
+			break
+		}
+	}
+	return d
+}
+
+// template returns the template for key, from the overrides first.
+func (cfg notificationConfig) template(key string) (string, bool) {
+	if t, ok := cfg.templates[key]; ok {
+		return t, true
+	}
+	t, ok := DefaultNotificationTemplates[key]
+	return t, ok
+}
+
+// statusLabel returns the label of s for messages, "none" when unset.
+func statusLabel(s AuthenticatorStatus) string {
+	if s == "" {
+		return "none"
+	}
+	return s.Label()
+}
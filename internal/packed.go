@@ -0,0 +1,153 @@
+package aaguids
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"slices"
+	"time"
+)
+
+// COSEAlgorithm is a COSE algorithm identifier, as carried in the "alg" of an attestation
+// statement (e.g. -7 for ES256).
+type COSEAlgorithm int64
+
+// Attestation types as listed in attestationTypes (FIDO Registry of Predefined Values § 3.6.3).
+const (
+	AttestationBasicFull      = "basic_full"
+	AttestationBasicSurrogate = "basic_surrogate" // self attestation
+	AttestationECDAA          = "ecdaa"
+	AttestationAttCA          = "attca"
+	AttestationAnonCA         = "anonca"
+	AttestationNone           = "none"
+)
+
+var (
+	// ErrEntryMismatch is returned by VerifyPackedAttestation when the claimed AAGUID is not the
+	// metadata entry's.
+	ErrEntryMismatch = errors.New("aaguids: claimed AAGUID does not match the metadata entry")
+	// ErrAlgorithmNotListed is returned when the attestation algorithm is not among the entry's
+	// authenticationAlgorithms.
+	ErrAlgorithmNotListed = errors.New("aaguids: attestation algorithm is not listed in the metadata")
+	// ErrAttestationTypeNotAllowed is returned when the attestation type is not among the entry's
+	// attestationTypes.
+	ErrAttestationTypeNotAllowed = errors.New("aaguids: attestation type is not listed in the metadata")
+	// ErrBatchCompromised is returned when an ATTESTATION_KEY_COMPROMISE report covers the
+	// attestation certificate.
+	ErrBatchCompromised = errors.New("aaguids: attestation key is reported compromised")
+)
+
+/*
+coseAlgorithmNames maps COSE algorithms to the authenticationAlgorithms names (FIDO Registry of
+Predefined Values § 3.6.1) that sign with them; the raw and DER signature encodings are both accepted.
+*/
+var coseAlgorithmNames = map[COSEAlgorithm][]string{
+	-7:     {"secp256r1_ecdsa_sha256_raw", "secp256r1_ecdsa_sha256_der"},
+	-35:    {"secp384r1_ecdsa_sha384_raw", "secp384r1_ecdsa_sha384_der"},
+	-36:    {"secp521r1_ecdsa_sha512_raw", "secp521r1_ecdsa_sha512_der"},
+	-47:    {"secp256k1_ecdsa_sha256_raw", "secp256k1_ecdsa_sha256_der"},
+	-8:     {"ed25519_eddsa_sha512_raw", "ed448_eddsa_sha512_raw"},
+	-19:    {"ed25519_eddsa_sha512_raw"},
+	-257:   {"rsassa_pkcsv15_sha256_raw"},
+	-258:   {"rsassa_pkcsv15_sha384_raw"},
+	-259:   {"rsassa_pkcsv15_sha512_raw"},
+	-65535: {"rsassa_pkcsv15_sha1_raw"},
+	-37:    {"rsassa_pss_sha256_raw", "rsassa_pss_sha256_der"},
+	-38:    {"rsassa_pss_sha384_raw", "rsassa_pss_sha384_der"},
+	-39:    {"rsassa_pss_sha512_raw", "rsassa_pss_sha512_der"},
+}
+
+/*
+PackedAttestationInput carries what VerifyPackedAttestation needs from a packed attestation
+statement and the authenticator data, once the caller's WebAuthn library has verified the
+signature:
+
+  - Certificates: the parsed x5c, leaf first; empty for self attestation
+  - Algorithm: the attestation statement's alg
+  - AAGUID: the AAGUID from the attested credential data
+  - AttestationType: the attestation type the verifier established (AttestationBasicFull, ...); ""
+    means AttestationBasicFull when Certificates is set and AttestationBasicSurrogate otherwise
+  - Now: the time to verify the chain at; the zero value means time.Now()
+*/
+type PackedAttestationInput struct {
+	Certificates    []*x509.Certificate
+	Algorithm       COSEAlgorithm
+	AAGUID          string
+	AttestationType string
+	Now             time.Time
+}
+
+/*
+VerifyPackedAttestation performs the metadata-driven checks of a packed attestation against
+metadataEntry, in this order, and returns the first failure:
+
+ 1. the claimed AAGUID is the entry's (ErrEntryMismatch)
+ 2. the attestation type is among attestationTypes (ErrAttestationTypeNotAllowed)
+ 3. the algorithm is among authenticationAlgorithms (ErrAlgorithmNotListed)
+ 4. the certificate chain leads to the entry's attestation roots (see VerifyAttestationChain)
+ 5. the leaf's id-fido-gen-ce-aaguid extension, if present, matches (ErrAAGUIDMismatch)
+ 6. no ATTESTATION_KEY_COMPROMISE report names a certificate of the chain, or names none at all
+    (ErrBatchCompromised)
+
+Checks 4 to 6 need certificates and are skipped for self attestation. The signature over the
+authenticator data and client data hash is not verified here; that stays with the caller's WebAuthn
+library. Status-based trust, such as a REVOKED entry, is a Policy decision (see TrustDecision).
+*/
+func VerifyPackedAttestation(metadataEntry Entry, attStmt PackedAttestationInput) error {
+	e := metadataEntry
+	claimed, err := ParseAAGUID(attStmt.AAGUID)
+	if err != nil {
+		return entryErrorf(e, "claimed AAGUID %q: %w", attStmt.AAGUID, err)
+	}
+	if want, err := ParseAAGUID(e.AAGUID); err != nil || want != claimed {
+		return entryErrorf(e, "%w: claimed %s", ErrEntryMismatch, claimed)
+	}
+
+	attType := attStmt.AttestationType
+	if attType == "" {
+		attType = AttestationBasicSurrogate
+		if len(attStmt.Certificates) > 0 {
+			attType = AttestationBasicFull
+		}
+	}
+	if !slices.Contains(e.MetadataStatement.AttestationTypes, attType) {
+		return entryErrorf(e, "%w: %s not in %v", ErrAttestationTypeNotAllowed, attType, e.MetadataStatement.AttestationTypes)
+	}
+
+	names, known := coseAlgorithmNames[attStmt.Algorithm]
+	if !slices.ContainsFunc(names, func(n string) bool { return slices.Contains(e.MetadataStatement.AuthenticationAlgorithms, n) }) {
+		if !known {
+			return entryErrorf(e, "%w: unknown COSE algorithm %d", ErrAlgorithmNotListed, attStmt.Algorithm)
+		}
+		return entryErrorf(e, "%w: COSE algorithm %d not in %v", ErrAlgorithmNotListed, attStmt.Algorithm,
+			e.MetadataStatement.AuthenticationAlgorithms)
+	}
+
+	if len(attStmt.Certificates) == 0 {
+		return nil
+	}
+	now := attStmt.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if err := VerifyAttestationChain(e, attStmt.Certificates, now); err != nil {
+		return err
+	}
+	if err := VerifyCertAAGUIDBinding(attStmt.Certificates[0], claimed.String()); err != nil {
+		return entryErrorf(e, "%w", err)
+	}
+	for _, r := range e.StatusReports {
+		if r.Status != ATTESTATION_KEY_COMPROMISE {
+			continue
+		}
+		if r.Certificate == nil {
+			return entryErrorf(e, "%w: all attestation keys of the model", ErrBatchCompromised)
+		}
+		for _, c := range attStmt.Certificates {
+			if sameCertificate(*r.Certificate, sha256.Sum256(c.Raw)) {
+				return entryErrorf(e, "%w: %s", ErrBatchCompromised, CertificateRef(*r.Certificate))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package aaguids
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyPackedAttestation(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b64 := func(c *x509.Certificate) string { return base64.StdEncoding.EncodeToString(c.Raw) }
+	root := readTestCertificate(t, "u2f-root.pem")
+	leaf := readTestCertificate(t, "u2f-attestation.pem")
+	batch2 := readTestCertificate(t, "u2f-attestation-batch2.pem")
+	unrelated := readTestCertificate(t, "unrelated.pem")
+	extRoot, extLeaf := attestationCerts(t, time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC), testYubiKey)
+	otherRoot, otherLeaf := attestationCerts(t, time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC), testGPM)
+	expiredRoot, expiredLeaf := attestationCerts(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), testYubiKey)
+
+	entry := func(roots []*x509.Certificate, reports ...StatusReport) Entry {
+		e := Entry{AAGUID: testYubiKey, StatusReports: reports, MetadataStatement: MetadataStatement{
+			AAGUID:                   testYubiKey,
+			Description:              "YubiKey 5 Series",
+			AttestationTypes:         []string{AttestationBasicFull},
+			AuthenticationAlgorithms: []string{"secp256r1_ecdsa_sha256_raw"},
+		}}
+		for _, r := range roots {
+			e.MetadataStatement.AttestationRootCertificates = append(e.MetadataStatement.AttestationRootCertificates, b64(r))
+		}
+		return e
+	}
+	compromised := func(c *x509.Certificate) StatusReport {
+		r := report(ATTESTATION_KEY_COMPROMISE, "2024-06-01")
+		if c != nil {
+			s := b64(c)
+			r.Certificate = &s
+		}
+		return r
+	}
+	basic := entry([]*x509.Certificate{root, extRoot, otherRoot, expiredRoot})
+	selfAttested := entry(nil)
+	selfAttested.MetadataStatement.AttestationTypes = []string{AttestationBasicSurrogate}
+
+	tests := []struct {
+		name  string
+		entry Entry
+		in    PackedAttestationInput
+		is    error  // nil for success, or the sentinel the error wraps
+		msg   string // a fragment of the message, for errors without a sentinel
+	}{
+		{name: "valid", entry: basic, in: PackedAttestationInput{Certificates: []*x509.Certificate{leaf}, Algorithm: -7, AAGUID: testYubiKey}},
+		{name: "valid with the AAGUID extension", entry: basic, in: PackedAttestationInput{Certificates: []*x509.Certificate{extLeaf}, Algorithm: -7, AAGUID: testYubiKey}},
+		{name: "self attestation", entry: selfAttested, in: PackedAttestationInput{Algorithm: -7, AAGUID: strings.ToUpper(testYubiKey)}},
+		{
+			name: "malformed claimed AAGUID", entry: basic, msg: "claimed AAGUID",
+			in: PackedAttestationInput{Certificates: []*x509.Certificate{leaf}, Algorithm: -7, AAGUID: "not-an-aaguid"},
+		},
+		{
+			name: "claimed AAGUID of another entry", entry: basic, is: ErrEntryMismatch,
+			in: PackedAttestationInput{Certificates: []*x509.Certificate{leaf}, Algorithm: -7, AAGUID: testGPM},
+		},
+		{
+			name: "self attestation not listed", entry: basic, is: ErrAttestationTypeNotAllowed,
+			in: PackedAttestationInput{Algorithm: -7, AAGUID: testYubiKey},
+		},
+		{
+			name: "explicit attestation type not listed", entry: basic, is: ErrAttestationTypeNotAllowed,
+			in: PackedAttestationInput{Certificates: []*x509.Certificate{leaf}, Algorithm: -7, AAGUID: testYubiKey, AttestationType: AttestationAttCA},
+		},
+		{
+			name: "algorithm not listed", entry: basic, is: ErrAlgorithmNotListed,
+			in: PackedAttestationInput{Certificates: []*x509.Certificate{leaf}, Algorithm: -257, AAGUID: testYubiKey},
+		},
+		{
+			name: "unknown algorithm", entry: basic, is: ErrAlgorithmNotListed, msg: "unknown COSE algorithm",
+			in: PackedAttestationInput{Certificates: []*x509.Certificate{leaf}, Algorithm: 12345, AAGUID: testYubiKey},
+		},
+		{
+			name: "chain to no metadata root", entry: basic, is: ErrNoRootMatched,
+			in: PackedAttestationInput{Certificates: []*x509.Certificate{unrelated}, Algorithm: -7, AAGUID: testYubiKey},
+		},
+		{
+			name: "expired root", entry: basic, is: ErrRootExpired,
+			in: PackedAttestationInput{Certificates: []*x509.Certificate{expiredLeaf}, Algorithm: -7, AAGUID: testYubiKey, Now: now},
+		},
+		{
+			name: "certificate names another AAGUID", entry: basic, is: ErrAAGUIDMismatch,
+			in: PackedAttestationInput{Certificates: []*x509.Certificate{otherLeaf}, Algorithm: -7, AAGUID: testYubiKey},
+		},
+		{
+			name: "batch compromised", entry: entry([]*x509.Certificate{root}, compromised(leaf)), is: ErrBatchCompromised,
+			in: PackedAttestationInput{Certificates: []*x509.Certificate{leaf}, Algorithm: -7, AAGUID: testYubiKey},
+		},
+		{
+			name: "every batch compromised", entry: entry([]*x509.Certificate{root}, compromised(nil)), is: ErrBatchCompromised,
+			in: PackedAttestationInput{Certificates: []*x509.Certificate{leaf}, Algorithm: -7, AAGUID: testYubiKey},
+		},
+		{
+			name: "another batch compromised", entry: entry([]*x509.Certificate{root}, compromised(batch2)),
+			in: PackedAttestationInput{Certificates: []*x509.Certificate{leaf}, Algorithm: -7, AAGUID: testYubiKey},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.in.Now.IsZero() {
+				tt.in.Now = now
+			}
+			err := VerifyPackedAttestation(tt.entry, tt.in)
+			if tt.is == nil && tt.msg == "" {
+				if err != nil {
+					t.Fatalf("VerifyPackedAttestation: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("VerifyPackedAttestation succeeded, want an error")
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("error %q does not wrap %q", err, tt.is)
+			}
+			if !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("error %q does not mention %q", err, tt.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package aaguids
+
+import "slices"
+
+/*
+KnownPasskeyProviders returns the AAGUIDs of the well-known passkey providers that have an exported
+constant in this package, such as AppleICloudKeychain, GooglePasswordManager or OnePassword, in the
+order of the generator's reviewed passkey-providers.json. Only providers present in the embedded
+dataset get a constant, so every AAGUID returned resolves with GetEntry on the embedded dataset.
+*/
+func KnownPasskeyProviders() []string {
+	return slices.Clone(knownPasskeyProviders)
+}
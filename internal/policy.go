@@ -0,0 +1,616 @@
+package aaguids
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReasonCode is the machine-readable reason of a Decision.
+type ReasonCode string
+
+const (
+	// ReasonAllowed means every check passed.
+	ReasonAllowed ReasonCode = "allowed"
+	// ReasonInvalidAAGUID means the AAGUID is not in the dash-separated UUID layout.
+	ReasonInvalidAAGUID ReasonCode = "invalid_aaguid"
+	// ReasonZeroAAGUID means the AAGUID is all zeros, which identifies no model.
+	ReasonZeroAAGUID ReasonCode = "zero_aaguid"
+	// ReasonUnknownAuthenticator means the AAGUID is not in the dataset and was denied.
+	ReasonUnknownAuthenticator ReasonCode = "unknown_authenticator"
+	// ReasonUnknownAllowed means the AAGUID is not in the dataset and UnknownAllow accepted it.
+	ReasonUnknownAllowed ReasonCode = "unknown_allowed"
+	// ReasonUnknownFlagged means the AAGUID is not in the dataset and UnknownAllowWithWarning
+	// accepted it; Decision.Warning is set.
+	ReasonUnknownFlagged ReasonCode = "unknown_flagged"
+	// ReasonUnknownAllowlisted means the AAGUID is not in the dataset but is on an unexpired entry
+	// of Policy.UnknownAllowlist.
+	ReasonUnknownAllowlisted ReasonCode = "unknown_allowlisted"
+	// ReasonNoMetadataStatement means the entry has no FIDO metadata statement, e.g. a passkey
+	// provider only known from the community list, and Policy.RequireMetadataStatement is set.
+	ReasonNoMetadataStatement ReasonCode = "no_metadata_statement"
+	// ReasonMetadataTooStale means the dataset is past its nextUpdate by more than
+	// Policy.MaxMetadataStaleness and Policy.StaleMetadataGrace, and Policy.StaleMetadata is
+	// StaleMetadataDeny.
+	ReasonMetadataTooStale ReasonCode = "metadata_too_stale"
+	// ReasonStatusDenied means a status still in effect is one of Policy.DenyStatuses; see
+	// Provider.TrustDecision for when a later report lifts one.
+	ReasonStatusDenied ReasonCode = "status_denied"
+	// ReasonBelowCertificationLevel means the entry lacks Policy.MinCertificationLevel.
+	ReasonBelowCertificationLevel ReasonCode = "below_certification_level"
+	// ReasonBelowBiometricLevel means the entry lacks Policy.MinBiometricLevel.
+	ReasonBelowBiometricLevel ReasonCode = "below_biometric_level"
+	// ReasonEnterpriseAttestationUnsupported means enterprise attestation was requested (see
+	// WithEnterpriseAttestationRequested) but the model does not list the "ep" option.
+	ReasonEnterpriseAttestationUnsupported ReasonCode = "enterprise_attestation_unsupported"
+	// ReasonEnterpriseAttestationDisallowed means the model supports the requested enterprise
+	// attestation but the Policy does not allow it for this model.
+	ReasonEnterpriseAttestationDisallowed ReasonCode = "enterprise_attestation_disallowed"
+)
+
+// DefaultDenyStatuses are the statuses a zero Policy rejects: revocation and every kind of
+// compromise reported by MDS.
+var DefaultDenyStatuses = []AuthenticatorStatus{
+	REVOKED, USER_VERIFICATION_BYPASS, ATTESTATION_KEY_COMPROMISE,
+	USER_KEY_REMOTE_COMPROMISE, USER_KEY_PHYSICAL_COMPROMISE,
+}
+
+// UnknownAuthenticatorMode selects how a Policy treats AAGUIDs that are zero or not in the dataset.
+type UnknownAuthenticatorMode int
+
+const (
+	// UnknownDeny rejects unknown AAGUIDs. It is the zero value.
+	UnknownDeny UnknownAuthenticatorMode = iota
+	// UnknownAllow accepts unknown AAGUIDs.
+	UnknownAllow
+	// UnknownAllowWithWarning accepts unknown AAGUIDs and sets Decision.Warning.
+	UnknownAllowWithWarning
+)
+
+// String returns "deny", "allow" or "allow_with_warning".
+func (m UnknownAuthenticatorMode) String() string {
+	switch m {
+	case UnknownAllow:
+		return "allow"
+	case UnknownAllowWithWarning:
+		return "allow_with_warning"
+	case UnknownDeny:
+		return "deny"
+	}
+	return fmt.Sprintf("UnknownAuthenticatorMode(%d)", int(m))
+}
+
+// MarshalText encodes the mode as its String form.
+func (m UnknownAuthenticatorMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText decodes the String form of a mode.
+func (m *UnknownAuthenticatorMode) UnmarshalText(b []byte) error {
+	for _, v := range []UnknownAuthenticatorMode{UnknownDeny, UnknownAllow, UnknownAllowWithWarning} {
+		if v.String() == string(b) {
+			*m = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown authenticator mode %q", b)
+}
+
+/*
+AllowedUnknown exempts one AAGUID that is not (yet) in the dataset from UnknownDeny, e.g. hardware
+validated manually before MDS lists it. A zero Expires never expires; otherwise the exception
+applies strictly before Expires, so temporary exceptions age out without a policy change.
+*/
+type AllowedUnknown struct {
+	AAGUID  string    `json:"aaguid"`
+	Expires time.Time `json:"expires,omitzero"`
+	Note    string    `json:"note,omitempty"`
+}
+
+// activeAt reports whether the exception applies at now.
+func (a AllowedUnknown) activeAt(now time.Time) bool {
+	return a.Expires.IsZero() || now.Before(a.Expires)
+}
+
+/*
+Policy decides whether authenticators are acceptable for registration. The zero Policy denies
+unknown AAGUIDs and entries with a status of DefaultDenyStatuses still in effect, and imposes no
+certification requirement.
+
+  - Unknown: how AAGUIDs that are zero or not in the dataset are treated
+  - UnknownAllowlist: AAGUIDs accepted although they are not in the dataset (never the zero AAGUID)
+  - RequireMetadataStatement: whether entries without a FIDO metadata statement are denied
+  - DenyStatuses: statuses to reject (nil means DefaultDenyStatuses)
+  - MinCertificationLevel: lowest acceptable FIDO_CERTIFIED* level ("" for none)
+  - MinBiometricLevel: lowest acceptable biometric certification level (0 for none)
+  - AllowEnterpriseAttestation: whether registrations requesting enterprise attestation are allowed
+  - EnterpriseAttestationAAGUIDs: with AllowEnterpriseAttestation, the only models it is allowed
+    for (empty for every model supporting it)
+  - MaxMetadataStaleness: how far past its nextUpdate the dataset may be (see Provider.Staleness)
+    before StaleMetadata applies (0 for no limit)
+  - StaleMetadata: whether a dataset staler than that denies decisions or only flags them
+  - StaleMetadataGrace: with StaleMetadataDeny, how much longer decisions are only flagged before
+    they are denied, e.g. to alert on a stuck refresh a day before registrations fail (0 for none)
+
+Policies encode to JSON as a versioned document for review and configuration; see
+Policy.MarshalJSON and PolicyFromJSON. DefaultPolicy, StrictPolicy and PermissivePolicy are presets.
+*/
+type Policy struct {
+	Unknown                  UnknownAuthenticatorMode `json:"unknown"`
+	UnknownAllowlist         []AllowedUnknown         `json:"unknownAllowlist,omitempty"`
+	RequireMetadataStatement bool                     `json:"requireMetadataStatement,omitempty"`
+	DenyStatuses             []AuthenticatorStatus    `json:"denyStatuses,omitempty"`
+	MinCertificationLevel    AuthenticatorStatus      `json:"minCertificationLevel,omitempty"`
+	MinBiometricLevel        BiometricCertLevel       `json:"minBiometricLevel,omitempty"`
+
+	AllowEnterpriseAttestation   bool     `json:"allowEnterpriseAttestation,omitempty"`
+	EnterpriseAttestationAAGUIDs []string `json:"enterpriseAttestationAAGUIDs,omitempty"`
+
+	MaxMetadataStaleness time.Duration     `json:"maxMetadataStaleness,omitempty"`
+	StaleMetadata        StaleMetadataMode `json:"staleMetadata,omitempty"`
+	StaleMetadataGrace   time.Duration     `json:"staleMetadataGrace,omitempty"`
+}
+
+/*
+Decision is the outcome of evaluating a Policy for one AAGUID.
+
+  - AAGUID: the canonical form of the evaluated AAGUID (as given if it is malformed)
+  - Status: the effective status after version scoping and batch matching ("" if none); with
+    ReasonStatusDenied, the denied status, which a later report such as UPDATE_AVAILABLE may follow
+  - Warning: the decision should be flagged for review (UnknownAllowWithWarning, StaleMetadataWarn)
+  - MetadataStale: the dataset was staler than Policy.MaxMetadataStaleness
+  - Entry: the dataset entry, zero if the AAGUID is unknown
+  - Trace: the checks that ran, only populated with WithTrace
+*/
+type Decision struct {
+	Allowed       bool                `json:"allowed"`
+	Reason        ReasonCode          `json:"reason"`
+	AAGUID        string              `json:"aaguid"`
+	Status        AuthenticatorStatus `json:"status,omitempty"`
+	Warning       bool                `json:"warning,omitempty"`
+	MetadataStale bool                `json:"metadataStale,omitempty"`
+	Entry         Entry               `json:"-"`
+	Trace         []TraceStep         `json:"trace,omitempty"`
+}
+
+// EvaluateOption adjusts a single Policy evaluation.
+type EvaluateOption func(*evaluation)
+
+// evaluation is the per-call state of TrustDecision.
+type evaluation struct {
+	tracing bool
+	trace   []TraceStep
+	version *uint64
+	cert    *x509.Certificate
+	now     func() time.Time
+	asOf    *time.Time
+
+	enterprise bool
+	uniform    bool // see WithUniformLookupTiming
+}
+
+// WithClock sets the clock used for time-dependent checks such as UnknownAllowlist expiry. The
+// default is time.Now.
+func WithClock(now func() time.Time) EvaluateOption {
+	return func(ev *evaluation) { ev.now = now }
+}
+
+/*
+AsOf evaluates the policy as it would have been decided at t, e.g. the registration date of a
+stored credential: status and biometric status reports effective after t are ignored, and t is
+also the clock (see WithClock). The current entry is used; changes to its other fields since t
+are not undone.
+*/
+func AsOf(t time.Time) EvaluateOption {
+	return func(ev *evaluation) {
+		ev.asOf = &t
+		ev.now = func() time.Time { return t }
+	}
+}
+
+/*
+WithAuthenticatorVersion scopes status evaluation to the given authenticatorVersion (e.g. the
+firmware version reported by the authenticator): status reports about later versions, updates the
+device already has and the security issues they fix are ignored, see
+Entry.EffectiveStatusForVersion.
+*/
+func WithAuthenticatorVersion(v uint64) EvaluateOption {
+	return func(ev *evaluation) { ev.version = &v }
+}
+
+/*
+WithAttestationCertificate supplies the attestation certificate of the registration. An
+ATTESTATION_KEY_COMPROMISE report that names a specific certificate then only applies if it is
+this one, so other batches of the same model are not rejected.
+*/
+func WithAttestationCertificate(cert *x509.Certificate) EvaluateOption {
+	return func(ev *evaluation) { ev.cert = cert }
+}
+
+/*
+WithEnterpriseAttestationRequested evaluates a registration for which the relying party requested
+enterprise attestation (CTAP 2.1 § 7.1, "ep" option). The model must support it and the Policy
+must allow it for the model; see ReasonEnterpriseAttestationUnsupported and
+ReasonEnterpriseAttestationDisallowed.
+*/
+func WithEnterpriseAttestationRequested() EvaluateOption {
+	return func(ev *evaluation) { ev.enterprise = true }
+}
+
+// Evaluate applies pol to aaGuid using the embedded dataset. See Provider.TrustDecision.
+func (pol Policy) Evaluate(aaGuid string, opts ...EvaluateOption) Decision {
+	return Default().TrustDecision(pol, aaGuid, opts...)
+}
+
+/*
+TrustDecision applies pol to aaGuid using the current snapshot. The checks run in this order and
+the first failing one determines the Decision:
+
+ 1. aaguid format: aaGuid must parse with the Provider's NormalizerChain; the trace lists the hooks
+    that applied
+ 2. zero aaguid: the all-zero AAGUID identifies no model and is handled per Policy.Unknown
+ 3. metadata staleness: with MaxMetadataStaleness, a dataset staler than that at the evaluation
+    clock flags the Decision and goes on; once also past StaleMetadataGrace, it denies the
+    AAGUID instead unless StaleMetadata is StaleMetadataWarn. Both limits are inclusive
+ 4. dataset lookup: unknown AAGUIDs are checked against UnknownAllowlist, then Policy.Unknown
+ 5. metadata statement: with RequireMetadataStatement, the entry must come with a FIDO metadata
+    statement rather than only community data
+ 6. as of: reports effective after the evaluation date are dropped (AsOf)
+ 7. version scoping: reports about later authenticator versions are dropped
+    (WithAuthenticatorVersion)
+ 8. batch certificate match: compromise reports naming another certificate are dropped
+    (WithAttestationCertificate)
+ 9. status evaluation: no remaining report in DenyStatuses may still be in effect. A REVOKED or
+    other denied report is lifted by a later FIDO_CERTIFIED* report, as IsRevoked has it; a
+    security issue is only lifted by the remediation of version scoping, as
+    HasSecurityIssueForVersion has it. A later UPDATE_AVAILABLE lifts neither.
+ 10. cert level: the remaining certification, as Entry.CertificationLevel derives it, must reach
+    MinCertificationLevel; a later REVOKED report leaves none
+ 11. biometric level: a biometric status report must reach MinBiometricLevel (with AsOf, the
+    one in effect at the evaluation date, see BiometricStatusAt)
+ 12. enterprise attestation: when requested (WithEnterpriseAttestationRequested), the entry's
+    getInfo must list the "ep" option, present but false included (see
+    Entry.SupportsEnterpriseAttestation), and AllowEnterpriseAttestation must cover the model
+*/
+func (p *Provider) TrustDecision(pol Policy, aaGuid string, opts ...EvaluateOption) Decision {
+	return p.evaluation(opts).run(p.current(), p.normalizerChain(), pol, aaGuid)
+}
+
+// evaluation returns newEvaluation(opts) with p's lookup timing (see WithUniformLookupTiming).
+func (p *Provider) evaluation(opts []EvaluateOption) *evaluation {
+	ev := newEvaluation(opts)
+	ev.uniform = p.uniformLookup.Load()
+	return ev
+}
+
+// newEvaluation returns the state of one evaluation with opts applied.
+func newEvaluation(opts []EvaluateOption) *evaluation {
+	ev := &evaluation{now: time.Now}
+	for _, opt := range opts {
+		opt(ev)
+	}
+	return ev
+}
+
+// run decides and attaches the trace.
+func (ev *evaluation) run(s *snapshot, norm NormalizerChain, pol Policy, aaGuid string) Decision {
+	d := ev.decide(s, norm, pol, aaGuid)
+	d.Trace = ev.trace
+	return d
+}
+
+func (ev *evaluation) decide(s *snapshot, norm NormalizerChain, pol Policy, aaGuid string) Decision {
+	d := Decision{AAGUID: aaGuid}
+
+	id, matched, err := norm.Parse(aaGuid)
+	if err != nil {
+		if ev.tracing {
+			ev.record(CheckAAGUIDFormat, OutcomeFail, err.Error(), "aaguid", aaGuid, "normalizers", strings.Join(matched, ","))
+		}
+		d.Reason = ReasonInvalidAAGUID
+		return d
+	}
+	d.AAGUID = id.String()
+	if ev.tracing {
+		ev.record(CheckAAGUIDFormat, OutcomePass, "", "aaguid", aaGuid, "normalizers", strings.Join(matched, ","))
+	}
+
+	if id.IsZero() {
+		if ev.tracing {
+			ev.record(CheckZeroAAGUID, OutcomeFail, "", "unknown", pol.Unknown.String())
+		}
+		d.Allowed, d.Warning = pol.unknownAllowed()
+		d.Reason = ReasonZeroAAGUID
+		return d
+	}
+	if ev.tracing {
+		ev.record(CheckZeroAAGUID, OutcomePass, "")
+	}
+
+	if pol.MaxMetadataStaleness > 0 {
+		staleBy, _ := s.staleness(ev.now())
+		switch {
+		case staleBy <= pol.MaxMetadataStaleness:
+			if ev.tracing {
+				ev.record(CheckMetadataStaleness, OutcomePass, "", "nextUpdate", s.info.NextUpdate)
+			}
+		case pol.StaleMetadata == StaleMetadataWarn || staleBy <= pol.MaxMetadataStaleness+pol.StaleMetadataGrace:
+			if ev.tracing {
+				note := "stale, warning only"
+				if pol.StaleMetadata != StaleMetadataWarn {
+					note = "stale, within grace"
+				}
+				ev.record(CheckMetadataStaleness, OutcomePass, note, "nextUpdate", s.info.NextUpdate,
+					"staleBy", staleBy.String())
+			}
+			d.MetadataStale, d.Warning = true, true
+		default:
+			if ev.tracing {
+				ev.record(CheckMetadataStaleness, OutcomeFail, "", "nextUpdate", s.info.NextUpdate, "staleBy", staleBy.String())
+			}
+			d.MetadataStale, d.Reason = true, ReasonMetadataTooStale
+			return d
+		}
+	} else if ev.tracing {
+		ev.record(CheckMetadataStaleness, OutcomeSkipped, "no staleness limit")
+	}
+
+	lookup := s.lookupCanonical
+	if ev.uniform {
+		lookup = s.lookupCanonicalUniform
+	}
+	e, ok := lookup(d.AAGUID)
+	if !ok {
+		if ev.tracing {
+			ev.record(CheckDatasetLookup, OutcomeFail, "not in dataset", "serial", strconv.Itoa(s.info.Serial))
+		}
+		return ev.decideUnknown(pol, d)
+	}
+	d.Entry = e
+	if ev.tracing {
+		ev.record(CheckDatasetLookup, OutcomePass, e.DisplayName(), "serial", strconv.Itoa(s.info.Serial))
+	}
+
+	if pol.RequireMetadataStatement {
+		if !hasMetadataStatement(e) {
+			if ev.tracing {
+				ev.record(CheckMetadataStatement, OutcomeFail, "community data only")
+			}
+			d.Reason = ReasonNoMetadataStatement
+			return d
+		}
+		if ev.tracing {
+			ev.record(CheckMetadataStatement, OutcomePass, "")
+		}
+	} else if ev.tracing {
+		ev.record(CheckMetadataStatement, OutcomeSkipped, "metadata statement not required")
+	}
+
+	reports := e.StatusHistory()
+	if ev.asOf != nil {
+		kept := slices.DeleteFunc(reports, func(r StatusReport) bool {
+			t, ok := r.EffectiveTime()
+			return ok && t.After(*ev.asOf)
+		})
+		if ev.tracing {
+			ev.record(CheckAsOf, OutcomePass, strconv.Itoa(len(kept))+" report(s) apply",
+				"asOf", ev.asOf.UTC().Format(time.RFC3339))
+		}
+		reports = kept
+	} else if ev.tracing {
+		ev.record(CheckAsOf, OutcomeSkipped, "evaluated as of now")
+	}
+
+	if ev.version != nil {
+		kept := scopeToVersion(reports, *ev.version)
+		if ev.tracing {
+			ev.record(CheckVersionScoping, OutcomePass, strconv.Itoa(len(kept))+" report(s) apply",
+				"authenticatorVersion", strconv.FormatUint(*ev.version, 10))
+		}
+		reports = kept
+	} else if ev.tracing {
+		ev.record(CheckVersionScoping, OutcomeSkipped, "no authenticator version supplied")
+	}
+
+	if ev.cert != nil {
+		fp := sha256.Sum256(ev.cert.Raw)
+		kept := slices.DeleteFunc(reports, func(r StatusReport) bool {
+			return r.Status == ATTESTATION_KEY_COMPROMISE && r.Certificate != nil && !sameCertificate(*r.Certificate, fp)
+		})
+		if ev.tracing {
+			ev.record(CheckBatchCertificate, OutcomePass, strconv.Itoa(len(kept))+" report(s) apply",
+				"certificate", fingerprintString(fp))
+		}
+		reports = kept
+	} else if ev.tracing {
+		ev.record(CheckBatchCertificate, OutcomeSkipped, "no attestation certificate supplied")
+	}
+
+	deny := pol.DenyStatuses
+	if deny == nil {
+		deny = DefaultDenyStatuses
+	}
+	if len(reports) > 0 {
+		d.Status = reports[len(reports)-1].Status
+	}
+	if denied, ok := deniedStatus(reports, deny); ok {
+		d.Status = denied
+		if ev.tracing {
+			ev.record(CheckStatus, OutcomeFail, "", "status", string(d.Status))
+		}
+		d.Reason = ReasonStatusDenied
+		return d
+	}
+	if ev.tracing {
+		ev.record(CheckStatus, OutcomePass, "", "status", string(d.Status))
+	}
+
+	if pol.MinCertificationLevel != "" {
+		level := certificationLevel(reports).Status()
+		if certificationRank(level) < certificationRank(pol.MinCertificationLevel) {
+			if ev.tracing {
+				ev.record(CheckCertLevel, OutcomeFail, "", "level", string(level), "minimum", string(pol.MinCertificationLevel))
+			}
+			d.Reason = ReasonBelowCertificationLevel
+			return d
+		}
+		if ev.tracing {
+			ev.record(CheckCertLevel, OutcomePass, "", "level", string(level), "minimum", string(pol.MinCertificationLevel))
+		}
+	} else if ev.tracing {
+		ev.record(CheckCertLevel, OutcomeSkipped, "no minimum certification level")
+	}
+
+	if pol.MinBiometricLevel != 0 {
+		certified := e.HasBiometricCertification(pol.MinBiometricLevel)
+		if ev.asOf != nil {
+			certified = e.hasBiometricCertificationAt(pol.MinBiometricLevel, *ev.asOf)
+		}
+		if !certified {
+			if ev.tracing {
+				ev.record(CheckBiometricLevel, OutcomeFail, "", "minimum", strconv.Itoa(int(pol.MinBiometricLevel)))
+			}
+			d.Reason = ReasonBelowBiometricLevel
+			return d
+		}
+		if ev.tracing {
+			ev.record(CheckBiometricLevel, OutcomePass, "", "minimum", strconv.Itoa(int(pol.MinBiometricLevel)))
+		}
+	} else if ev.tracing {
+		ev.record(CheckBiometricLevel, OutcomeSkipped, "no minimum biometric level")
+	}
+
+	if ev.enterprise {
+		if reason, ok := pol.enterpriseAttestation(e, d.AAGUID); !ok {
+			if ev.tracing {
+				ev.record(CheckEnterpriseAttestation, OutcomeFail, string(reason), "ep", epOption(e))
+			}
+			d.Reason = reason
+			return d
+		}
+		if ev.tracing {
+			ev.record(CheckEnterpriseAttestation, OutcomePass, "", "ep", epOption(e))
+		}
+	} else if ev.tracing {
+		ev.record(CheckEnterpriseAttestation, OutcomeSkipped, "enterprise attestation not requested")
+	}
+
+	d.Allowed, d.Reason = true, ReasonAllowed
+	return d
+}
+
+// hasMetadataStatement reports whether e carries a FIDO metadata statement, which entries only
+// known from the community passkey provider list lack (see IsPlatform).
+func hasMetadataStatement(e Entry) bool {
+	return e.MetadataStatement.ProtocolFamily != ""
+}
+
+// enterpriseAttestation checks a requested enterprise attestation of e, whose canonical AAGUID is
+// id, and returns the reason it fails.
+func (pol Policy) enterpriseAttestation(e Entry, id string) (ReasonCode, bool) {
+	if supported, _ := e.SupportsEnterpriseAttestation(); !supported {
+		return ReasonEnterpriseAttestationUnsupported, false
+	}
+	if !pol.AllowEnterpriseAttestation {
+		return ReasonEnterpriseAttestationDisallowed, false
+	}
+	if len(pol.EnterpriseAttestationAAGUIDs) > 0 && !slices.ContainsFunc(pol.EnterpriseAttestationAAGUIDs, func(a string) bool {
+		return strings.EqualFold(a, id)
+	}) {
+		return ReasonEnterpriseAttestationDisallowed, false
+	}
+	return "", true
+}
+
+// epOption returns the "ep" option of e for traces: "absent", "false", "true", or "unknown" when
+// the entry has no authenticatorGetInfo.
+func epOption(e Entry) string {
+	g, ok := e.GetInfo()
+	if !ok {
+		return "unknown"
+	}
+	return g.Option("ep").String()
+}
+
+// unknownAllowed returns whether pol.Unknown accepts an unknown AAGUID and whether to flag it.
+func (pol Policy) unknownAllowed() (allowed, warning bool) {
+	return pol.Unknown == UnknownAllow || pol.Unknown == UnknownAllowWithWarning, pol.Unknown == UnknownAllowWithWarning
+}
+
+// decideUnknown completes d for a well-formed AAGUID that is not in the dataset.
+func (ev *evaluation) decideUnknown(pol Policy, d Decision) Decision {
+	now := ev.now()
+	for _, a := range pol.UnknownAllowlist {
+		if !strings.EqualFold(a.AAGUID, d.AAGUID) {
+			continue
+		}
+		if a.activeAt(now) {
+			if ev.tracing {
+				ev.record(CheckUnknownAllowlist, OutcomePass, a.Note, "now", now.UTC().Format(time.RFC3339))
+			}
+			d.Allowed, d.Reason = true, ReasonUnknownAllowlisted
+			return d
+		}
+		if ev.tracing {
+			ev.record(CheckUnknownAllowlist, OutcomeFail, "exception expired",
+				"now", now.UTC().Format(time.RFC3339), "expires", a.Expires.UTC().Format(time.RFC3339))
+		}
+	}
+
+	allowed, warning := pol.unknownAllowed()
+	d.Allowed, d.Warning = allowed, d.Warning || warning
+	switch pol.Unknown {
+	case UnknownAllow:
+		d.Reason = ReasonUnknownAllowed
+	case UnknownAllowWithWarning:
+		d.Reason = ReasonUnknownFlagged
+	default:
+		d.Reason = ReasonUnknownAuthenticator
+	}
+	if ev.tracing {
+		outcome := OutcomeFail
+		if d.Allowed {
+			outcome = OutcomePass
+		}
+		ev.record(CheckUnknownMode, outcome, "", "unknown", pol.Unknown.String())
+	}
+	return d
+}
+
+/*
+deniedStatus returns the latest status of reports, in timeline order, that is in deny and still in
+effect: a security issue always, any other status unless a FIDO_CERTIFIED* report follows it.
+*/
+func deniedStatus(reports []StatusReport, deny []AuthenticatorStatus) (AuthenticatorStatus, bool) {
+	certifiedLater := false
+	for i := len(reports) - 1; i >= 0; i-- {
+		s := reports[i].Status
+		if slices.Contains(deny, s) && (!certifiedLater || slices.Contains(securityIssueStatuses, s)) {
+			return s, true
+		}
+		if isCertificationLevel(s) {
+			certifiedLater = true
+		}
+	}
+	return "", false
+}
+
+// certificationRank orders the FIDO_CERTIFIED* statuses from 1 to 7 (see CertificationLevel);
+// anything else ranks 0.
+func certificationRank(s AuthenticatorStatus) int {
+	if l, ok := CertificationLevelOf(s); ok && l >= CertificationFIDOCertified {
+		return int(l-CertificationFIDOCertified) + 1
+	}
+	return 0
+}
+
+// sameCertificate reports whether the certificate of a status report, in any encoding
+// ParsedCertificate accepts, has fingerprint fp.
+func sameCertificate(b64 string, fp [32]byte) bool {
+	der, err := decodeCertificateDER(b64)
+	return err == nil && sha256.Sum256(der) == fp
+}
@@ -0,0 +1,322 @@
+package aaguids
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// epEntries returns entries whose getInfo "ep" option is absent, false and true, and one without a
+// getInfo, by that option state.
+func epEntries() map[string]Entry {
+	getInfo := func(aaguid string, options map[string]bool) Entry {
+		return Entry{AAGUID: aaguid, MetadataStatement: MetadataStatement{
+			AAGUID:               aaguid,
+			ProtocolFamily:       "fido2",
+			AuthenticatorGetInfo: &AuthenticatorGetInfo{Versions: []string{"FIDO_2_1"}, Options: options},
+		}}
+	}
+	return map[string]Entry{
+		"absent":  getInfo(testYubiKey54, map[string]bool{"rk": true}),
+		"false":   getInfo(testYubiKey57, map[string]bool{"rk": true, "ep": false}),
+		"true":    getInfo(testWindowsHello, map[string]bool{"ep": true}),
+		"unknown": {AAGUID: testGPM},
+	}
+}
+
+func TestSupportsEnterpriseAttestationTriState(t *testing.T) {
+	for ep, want := range map[string]capability{
+		"absent":  {false, true},
+		"false":   {true, true}, // supported but not yet enabled by a platform
+		"true":    {true, true},
+		"unknown": {false, false},
+	} {
+		var got capability
+		got.supported, got.known = epEntries()[ep].SupportsEnterpriseAttestation()
+		if got != want {
+			t.Errorf("ep %s: SupportsEnterpriseAttestation() = %v, want %v", ep, got, want)
+		}
+	}
+}
+
+func TestEnterpriseAttestationDecision(t *testing.T) {
+	entries := epEntries()
+	list := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	p := testProvider(t, list...)
+
+	allowAll := Policy{AllowEnterpriseAttestation: true}
+	allowOne := Policy{AllowEnterpriseAttestation: true, EnterpriseAttestationAAGUIDs: []string{strings.ToUpper(testWindowsHello)}}
+	tests := []struct {
+		name      string
+		pol       Policy
+		ep        string
+		requested bool
+		want      ReasonCode
+	}{
+		// Without the request the option plays no part, whatever the policy.
+		{"not requested, absent", Policy{}, "absent", false, ReasonAllowed},
+		{"not requested, unknown", Policy{}, "unknown", false, ReasonAllowed},
+
+		// An absent option and a missing getInfo are both unsupported, whatever the policy allows.
+		{"absent", allowAll, "absent", true, ReasonEnterpriseAttestationUnsupported},
+		{"unknown", allowAll, "unknown", true, ReasonEnterpriseAttestationUnsupported},
+		{"absent, disallowed", Policy{}, "absent", true, ReasonEnterpriseAttestationUnsupported},
+
+		// Present but false is supported: the policy decides.
+		{"false, disallowed", Policy{}, "false", true, ReasonEnterpriseAttestationDisallowed},
+		{"false, allowed", allowAll, "false", true, ReasonAllowed},
+		{"true, disallowed", Policy{}, "true", true, ReasonEnterpriseAttestationDisallowed},
+		{"true, allowed", allowAll, "true", true, ReasonAllowed},
+
+		// EnterpriseAttestationAAGUIDs limits the models, matching any case.
+		{"allowlisted", allowOne, "true", true, ReasonAllowed},
+		{"not allowlisted", allowOne, "false", true, ReasonEnterpriseAttestationDisallowed},
+		{"allowlist without allow", Policy{EnterpriseAttestationAAGUIDs: []string{testWindowsHello}}, "true", true,
+			ReasonEnterpriseAttestationDisallowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pol := tt.pol
+			pol.Unknown = UnknownAllow
+			opts := []EvaluateOption{WithTrace()}
+			if tt.requested {
+				opts = append(opts, WithEnterpriseAttestationRequested())
+			}
+			d := p.TrustDecision(pol, entries[tt.ep].AAGUID, opts...)
+			if d.Reason != tt.want || d.Allowed != (tt.want == ReasonAllowed) {
+				t.Fatalf("decision %v %s, want %s", d.Allowed, d.Reason, tt.want)
+			}
+
+			step := d.Trace[len(d.Trace)-1]
+			if step.Check != CheckEnterpriseAttestation {
+				t.Fatalf("last traced check %s, want %s", step.Check, CheckEnterpriseAttestation)
+			}
+			switch {
+			case !tt.requested:
+				if step.Outcome != OutcomeSkipped {
+					t.Errorf("outcome %s without the request, want %s", step.Outcome, OutcomeSkipped)
+				}
+			case step.Inputs["ep"] != tt.ep:
+				t.Errorf("traced ep option %q, want %q", step.Inputs["ep"], tt.ep)
+			}
+		})
+	}
+}
+
+func TestStatusDeniedWhileInEffect(t *testing.T) {
+	v := func(n uint64) *uint64 { return &n }
+	bypass := versioned(report(USER_VERIFICATION_BYPASS, "2021-01-01"), 2)
+	update := versioned(report(UPDATE_AVAILABLE, "2022-01-01"), 5)
+	for _, tt := range []struct {
+		name    string
+		reports []StatusReport
+		deny    []AuthenticatorStatus // nil for DefaultDenyStatuses
+		version *uint64
+		want    AuthenticatorStatus // the denied status, "" if allowed
+	}{
+		{
+			name:    "revoked, then an update",
+			reports: []StatusReport{report(FIDO_CERTIFIED_L1, "2020-01-01"), report(REVOKED, "2021-01-01"), report(UPDATE_AVAILABLE, "2022-01-01")},
+			want:    REVOKED,
+		},
+		{
+			name:    "attestation key compromise, then an update",
+			reports: []StatusReport{report(FIDO_CERTIFIED_L1, "2020-01-01"), report(ATTESTATION_KEY_COMPROMISE, "2021-01-01"), report(UPDATE_AVAILABLE, "2022-01-01")},
+			want:    ATTESTATION_KEY_COMPROMISE,
+		},
+		{
+			name:    "revoked, then certified again",
+			reports: []StatusReport{report(REVOKED, "2021-01-01"), report(FIDO_CERTIFIED_L1, "2022-01-01")},
+		},
+		{
+			name:    "compromise, then certified again",
+			reports: []StatusReport{report(USER_KEY_REMOTE_COMPROMISE, "2021-01-01"), report(FIDO_CERTIFIED_L1, "2022-01-01")},
+			want:    USER_KEY_REMOTE_COMPROMISE,
+		},
+		{
+			name:    "version with the compromise, before the fix",
+			reports: []StatusReport{report(FIDO_CERTIFIED_L1, "2020-01-01"), bypass, update},
+			version: v(3),
+			want:    USER_VERIFICATION_BYPASS,
+		},
+		{
+			name:    "version with the fix",
+			reports: []StatusReport{report(FIDO_CERTIFIED_L1, "2020-01-01"), bypass, update},
+			version: v(5),
+		},
+		{
+			name:    "version before the compromise",
+			reports: []StatusReport{report(FIDO_CERTIFIED_L1, "2020-01-01"), bypass, update},
+			version: v(1),
+		},
+		{
+			name:    "version with the update does not lift a revocation",
+			reports: []StatusReport{report(FIDO_CERTIFIED_L1, "2020-01-01"), report(REVOKED, "2021-01-01"), update},
+			version: v(5),
+			want:    REVOKED,
+		},
+		{
+			name:    "denied notice, then certified",
+			reports: []StatusReport{report(UPDATE_AVAILABLE, "2021-01-01"), report(FIDO_CERTIFIED_L1, "2022-01-01")},
+			deny:    []AuthenticatorStatus{UPDATE_AVAILABLE},
+		},
+		{
+			name:    "certified, then a denied notice",
+			reports: []StatusReport{report(FIDO_CERTIFIED_L1, "2021-01-01"), report(UPDATE_AVAILABLE, "2022-01-01")},
+			deny:    []AuthenticatorStatus{UPDATE_AVAILABLE},
+			want:    UPDATE_AVAILABLE,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Entry{AAGUID: testYubiKey, StatusReports: tt.reports}
+			p := NewProvider(map[string]Entry{testYubiKey: e}, Dataset{})
+			var opts []EvaluateOption
+			if tt.version != nil {
+				opts = append(opts, WithAuthenticatorVersion(*tt.version))
+			}
+			d := p.TrustDecision(Policy{DenyStatuses: tt.deny}, testYubiKey, opts...)
+			if tt.want == "" {
+				if !d.Allowed {
+					t.Fatalf("denied (%s, status %s), want allowed", d.Reason, d.Status)
+				}
+			} else if d.Allowed || d.Reason != ReasonStatusDenied || d.Status != tt.want {
+				t.Fatalf("allowed %v (%s, status %s), want %s denied", d.Allowed, d.Reason, d.Status, tt.want)
+			}
+			if tt.deny != nil {
+				return
+			}
+			// The default deny statuses are revocation and the security issues, so the status
+			// helpers must agree with the decision.
+			issue := e.HasSecurityIssue()
+			if tt.version != nil {
+				issue = e.HasSecurityIssueForVersion(*tt.version)
+			}
+			if helpers := e.IsRevoked() || issue; helpers == d.Allowed {
+				t.Errorf("IsRevoked() || HasSecurityIssue = %v, but allowed %v", helpers, d.Allowed)
+			}
+		})
+	}
+}
+
+func TestMinCertificationLevelDecision(t *testing.T) {
+	// Deny no status, so only the certification check can reject.
+	pol := Policy{DenyStatuses: []AuthenticatorStatus{}, MinCertificationLevel: FIDO_CERTIFIED_L1}
+	for _, tt := range []struct {
+		name    string
+		reports []StatusReport
+		allowed bool
+	}{
+		{"L2", []StatusReport{report(FIDO_CERTIFIED_L2, "2020-01-01")}, true},
+		{"L2, then revoked", []StatusReport{report(FIDO_CERTIFIED_L2, "2020-01-01"), report(REVOKED, "2021-01-01")}, false},
+		{"L2, revoked, then an update", []StatusReport{
+			report(FIDO_CERTIFIED_L2, "2020-01-01"), report(REVOKED, "2021-01-01"), report(UPDATE_AVAILABLE, "2022-01-01"),
+		}, false},
+		{"revoked, then L1 again", []StatusReport{report(REVOKED, "2021-01-01"), report(FIDO_CERTIFIED_L1, "2022-01-01")}, true},
+		{"L2, then an update", []StatusReport{report(FIDO_CERTIFIED_L2, "2020-01-01"), report(UPDATE_AVAILABLE, "2021-01-01")}, true},
+		{"FIDO_CERTIFIED is below L1", []StatusReport{report(FIDO_CERTIFIED, "2020-01-01")}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Entry{AAGUID: testYubiKey, StatusReports: tt.reports}
+			d := NewProvider(map[string]Entry{testYubiKey: e}, Dataset{}).TrustDecision(pol, testYubiKey)
+			if d.Allowed != tt.allowed || !d.Allowed && d.Reason != ReasonBelowCertificationLevel {
+				t.Errorf("allowed %v (%s), want %v", d.Allowed, d.Reason, tt.allowed)
+			}
+			if got := e.CertificationLevel().AtLeast(CertificationL1); got != d.Allowed {
+				t.Errorf("CertificationLevel() %s disagrees with the decision", e.CertificationLevel())
+			}
+		})
+	}
+}
+
+func TestMetadataStalenessGrace(t *testing.T) {
+	next := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	entries := map[string]Entry{
+		testYubiKey: {AAGUID: testYubiKey, StatusReports: []StatusReport{report(FIDO_CERTIFIED_L1, "2020-01-01")}},
+		testGPM:     {AAGUID: testGPM, StatusReports: []StatusReport{report(REVOKED, "2021-01-01")}},
+	}
+	p := NewProvider(entries, Dataset{NextUpdate: next.Format(time.DateOnly)})
+	const limit, grace = 72 * time.Hour, 24 * time.Hour
+	for _, tt := range []struct {
+		name    string
+		pol     Policy
+		aaguid  string
+		staleBy time.Duration
+		reason  ReasonCode
+		stale   bool // Decision.MetadataStale
+	}{
+		{"not yet stale", Policy{MaxMetadataStaleness: limit, StaleMetadataGrace: grace}, testYubiKey, -time.Hour, ReasonAllowed, false},
+		{"at the limit", Policy{MaxMetadataStaleness: limit, StaleMetadataGrace: grace}, testYubiKey, limit, ReasonAllowed, false},
+		{"just past the limit", Policy{MaxMetadataStaleness: limit, StaleMetadataGrace: grace}, testYubiKey, limit + time.Second, ReasonAllowed, true},
+		{"just inside the grace window", Policy{MaxMetadataStaleness: limit, StaleMetadataGrace: grace}, testYubiKey, limit + grace, ReasonAllowed, true},
+		{"just past the grace window", Policy{MaxMetadataStaleness: limit, StaleMetadataGrace: grace}, testYubiKey, limit + grace + time.Second, ReasonMetadataTooStale, true},
+		{"no grace", Policy{MaxMetadataStaleness: limit}, testYubiKey, limit + time.Second, ReasonMetadataTooStale, true},
+		{
+			"warn mode past the grace window",
+			Policy{MaxMetadataStaleness: limit, StaleMetadata: StaleMetadataWarn, StaleMetadataGrace: grace},
+			testYubiKey, limit + grace + time.Hour, ReasonAllowed, true,
+		},
+		// The grace window only flags the staleness; the rest of the policy still applies.
+		{"revoked within the grace window", Policy{MaxMetadataStaleness: limit, StaleMetadataGrace: grace}, testGPM, limit + time.Hour, ReasonStatusDenied, true},
+		{"grace without a staleness limit", Policy{StaleMetadataGrace: grace}, testYubiKey, 365 * 24 * time.Hour, ReasonAllowed, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := WithClock(func() time.Time { return next.Add(tt.staleBy) })
+			d := p.TrustDecision(tt.pol, tt.aaguid, clock)
+			if d.Reason != tt.reason || d.Allowed != (tt.reason == ReasonAllowed) {
+				t.Errorf("allowed %v (%s), want %s", d.Allowed, d.Reason, tt.reason)
+			}
+			// A decision denied for staleness needs no warning.
+			if warn := tt.stale && tt.reason != ReasonMetadataTooStale; d.MetadataStale != tt.stale || d.Warning != warn {
+				t.Errorf("metadataStale %v, warning %v, want %v, %v", d.MetadataStale, d.Warning, tt.stale, warn)
+			}
+		})
+	}
+}
+
+func TestUnknownAllowlistExpiry(t *testing.T) {
+	const unknown = "00000000-0000-4000-8000-000000000001"
+	expires := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := testProvider(t)
+	for _, tt := range []struct {
+		name   string
+		pol    Policy
+		now    time.Time
+		reason ReasonCode
+	}{
+		{"one second before expiry", Policy{}, expires.Add(-time.Second), ReasonUnknownAllowlisted},
+		{"exactly at expiry", Policy{}, expires, ReasonUnknownAuthenticator},
+		{"one second after expiry", Policy{}, expires.Add(time.Second), ReasonUnknownAuthenticator},
+		// An expired exception falls back to the policy's mode rather than always denying.
+		{"expired under allow-with-warning", Policy{Unknown: UnknownAllowWithWarning}, expires, ReasonUnknownFlagged},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pol.UnknownAllowlist = []AllowedUnknown{{AAGUID: strings.ToUpper(unknown), Expires: expires, Note: "validated"}}
+			d := p.TrustDecision(tt.pol, unknown, WithClock(func() time.Time { return tt.now }), WithTrace())
+			if d.Reason != tt.reason || d.Allowed != (tt.reason != ReasonUnknownAuthenticator) {
+				t.Errorf("allowed %v (%s), want %s", d.Allowed, d.Reason, tt.reason)
+			}
+			want := OutcomePass
+			if tt.reason != ReasonUnknownAllowlisted {
+				want = OutcomeFail
+			}
+			i := slices.IndexFunc(d.Trace, func(s TraceStep) bool { return s.Check == CheckUnknownAllowlist })
+			if i < 0 || d.Trace[i].Outcome != want {
+				t.Errorf("trace %+v, want an allowlist check with outcome %s", d.Trace, want)
+			}
+		})
+	}
+	// AsOf moves the clock too, so a stored credential registered before expiry still passes.
+	pol := Policy{UnknownAllowlist: []AllowedUnknown{{AAGUID: unknown, Expires: expires}}}
+	if d := p.TrustDecision(pol, unknown, AsOf(expires.Add(-time.Second))); d.Reason != ReasonUnknownAllowlisted {
+		t.Errorf("AsOf before expiry: %s, want %s", d.Reason, ReasonUnknownAllowlisted)
+	}
+	// A zero Expires never expires.
+	pol.UnknownAllowlist[0].Expires = time.Time{}
+	if d := p.TrustDecision(pol, unknown, WithClock(func() time.Time { return expires.AddDate(100, 0, 0) })); d.Reason != ReasonUnknownAllowlisted {
+		t.Errorf("exception without expiry: %s, want %s", d.Reason, ReasonUnknownAllowlisted)
+	}
+}
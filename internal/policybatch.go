@@ -0,0 +1,100 @@
+package aaguids
+
+import (
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TrustInput is one credential of a TrustDecisions batch: its AAGUID and the EvaluateOptions that
+// apply to it alone, e.g. WithAuthenticatorVersion or WithAttestationCertificate.
+type TrustInput struct {
+	AAGUID  string
+	Options []EvaluateOption
+}
+
+// trustBatchParallelMin is the batch size from which TrustDecisions evaluates concurrently; below
+// it, starting workers costs more than it saves.
+const trustBatchParallelMin = 256
+
+// TrustDecisions applies pol to every input using the embedded dataset. See
+// Provider.TrustDecisions.
+func TrustDecisions(pol Policy, inputs []TrustInput, opts ...EvaluateOption) []Decision {
+	return Default().TrustDecisions(pol, inputs, opts...)
+}
+
+/*
+TrustDecisions applies pol to every input as TrustDecision does and returns the decisions in input
+order, e.g. to re-check all credentials of a user at step-up. The setup is done once per batch:
+
+  - every input is evaluated against the same snapshot and NormalizerChain, so an Update landing
+    mid-batch is not observed and all decisions carry one Dataset.Serial
+  - opts apply to every input, before the input's own Options
+  - the clock is read once, so time-dependent checks such as UnknownAllowlist expiry agree across
+    the batch (AsOf or WithClock in an input's Options still take precedence)
+
+Inputs without Options of their own are evaluated once per distinct AAGUID string, so a user's
+credentials of the same few models cost a few evaluations; BenchmarkTrustDecisions compares a batch
+of 100 with a loop over TrustDecision. Batches with trustBatchParallelMin distinct evaluations or
+more are spread over up to GOMAXPROCS workers. A malformed AAGUID only affects its own Decision
+(ReasonInvalidAAGUID).
+*/
+func (p *Provider) TrustDecisions(pol Policy, inputs []TrustInput, opts ...EvaluateOption) []Decision {
+	s, norm := p.current(), p.normalizerChain()
+	base := p.evaluation(opts)
+	if base.asOf == nil {
+		now := base.now()
+		base.now = func() time.Time { return now }
+	}
+	out := make([]Decision, len(inputs))
+	decide := func(i int) {
+		ev := *base
+		for _, opt := range inputs[i].Options {
+			opt(&ev)
+		}
+		out[i] = ev.run(s, norm, pol, inputs[i].AAGUID)
+	}
+
+	// An input without Options of its own that repeats an earlier AAGUID shares that input's
+	// decision: same snapshot, clock and options make the same decision.
+	todo := make([]int, 0, len(inputs))
+	shares := make(map[int]int)
+	seen := make(map[string]int)
+	for i, in := range inputs {
+		if len(in.Options) == 0 {
+			if j, ok := seen[in.AAGUID]; ok {
+				shares[i] = j
+				continue
+			}
+			seen[in.AAGUID] = i
+		}
+		todo = append(todo, i)
+	}
+
+	workers := min(runtime.GOMAXPROCS(0), len(todo)/(trustBatchParallelMin/4))
+	if len(todo) < trustBatchParallelMin || workers < 2 {
+		for _, i := range todo {
+			decide(i)
+		}
+	} else {
+		var wg sync.WaitGroup
+		chunk := (len(todo) + workers - 1) / workers
+		for lo := 0; lo < len(todo); lo += chunk {
+			part := todo[lo:min(lo+chunk, len(todo))]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, i := range part {
+					decide(i)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	for i, j := range shares {
+		out[i] = out[j]
+		out[i].Trace = slices.Clone(out[j].Trace)
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package aaguids
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// trustInputs returns n inputs cycling through keys, with every seventh AAGUID malformed.
+func trustInputs(keys []string, n int) []TrustInput {
+	inputs := make([]TrustInput, n)
+	for i := range inputs {
+		inputs[i].AAGUID = keys[i%len(keys)]
+		if i%7 == 3 {
+			inputs[i].AAGUID = fmt.Sprintf("not-an-aaguid-%d", i)
+		}
+	}
+	return inputs
+}
+
+func TestTrustDecisionsMatchLoop(t *testing.T) {
+	p, _ := syntheticProvider(t, 300)
+	clock := WithClock(func() time.Time { return time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC) })
+	pol := StrictPolicy()
+	// Below and above trustBatchParallelMin, so both the serial and the concurrent path run.
+	for _, n := range []int{0, 1, 100, trustBatchParallelMin * 4} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			inputs := trustInputs(p.AAGUIDs(), n)
+			got := p.TrustDecisions(pol, inputs, clock)
+			if len(got) != n {
+				t.Fatalf("%d decisions for %d inputs", len(got), n)
+			}
+			for i, in := range inputs {
+				want := p.TrustDecision(pol, in.AAGUID, clock)
+				if !reflect.DeepEqual(got[i], want) {
+					t.Fatalf("decision %d for %q: %+v, want %+v", i, in.AAGUID, got[i], want)
+				}
+				if i%7 == 3 && (got[i].Allowed || got[i].Reason != ReasonInvalidAAGUID) {
+					t.Errorf("decision %d for a malformed AAGUID: %v %s", i, got[i].Allowed, got[i].Reason)
+				}
+			}
+		})
+	}
+}
+
+func TestTrustDecisionsInputOptions(t *testing.T) {
+	p, key := syntheticProvider(t, 50)
+	pol := Policy{UnknownAllowlist: []AllowedUnknown{{AAGUID: "00000000-0000-4000-8000-000000000001", Expires: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}}}
+	before := WithClock(func() time.Time { return time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC) })
+	after := WithClock(func() time.Time { return time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC) })
+	inputs := []TrustInput{
+		{AAGUID: "00000000-0000-4000-8000-000000000001"},
+		{AAGUID: "00000000-0000-4000-8000-000000000001", Options: []EvaluateOption{after}},
+		{AAGUID: key, Options: []EvaluateOption{WithTrace()}},
+	}
+	got := p.TrustDecisions(pol, inputs, before)
+	if !got[0].Allowed {
+		t.Errorf("batch clock: allowlisted AAGUID denied (%s)", got[0].Reason)
+	}
+	if got[1].Allowed {
+		t.Error("an input's own clock did not take precedence over the batch clock")
+	}
+	if len(got[0].Trace) != 0 || len(got[2].Trace) == 0 {
+		t.Error("an input's WithTrace applied to other inputs, or not to its own")
+	}
+
+	// Repeated inputs share one evaluation but not its Trace.
+	traced := p.TrustDecisions(pol, []TrustInput{{AAGUID: key}, {AAGUID: key}}, WithTrace())
+	if !reflect.DeepEqual(traced[0], traced[1]) {
+		t.Fatalf("repeated input decided differently: %+v and %+v", traced[0], traced[1])
+	}
+	traced[1].Trace[0].Check = "changed"
+	if traced[0].Trace[0].Check == "changed" {
+		t.Error("repeated inputs share a Trace")
+	}
+}
+
+/*
+BenchmarkTrustDecisions compares evaluating 100 credentials, a large user's registrations at
+step-up, with one TrustDecisions call and with a loop over TrustDecision: once with the credentials
+spread over a few authenticator models, as a user's usually are, and once with every credential of
+a different model, where the batch can share nothing but the setup.
+*/
+func BenchmarkTrustDecisions(b *testing.B) {
+	p, _ := syntheticProvider(b, 5000)
+	pol := StrictPolicy()
+	keys := p.AAGUIDs()
+	for _, tc := range []struct {
+		name   string
+		inputs []TrustInput
+	}{
+		{"models=8", trustInputs(keys[:8], 100)},
+		{"models=100", trustInputs(keys, 100)},
+	} {
+		b.Run(tc.name+"/batch", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p.TrustDecisions(pol, tc.inputs)
+			}
+		})
+		b.Run(tc.name+"/loop", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				out := make([]Decision, len(tc.inputs))
+				for j, in := range tc.inputs {
+					out[j] = p.TrustDecision(pol, in.AAGUID)
+				}
+			}
+		})
+	}
+}
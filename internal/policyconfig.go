@@ -0,0 +1,294 @@
+package aaguids
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PolicySchemaVersion is the version of the policy document written by Policy.MarshalJSON and the
+// highest one PolicyFromJSON reads.
+const PolicySchemaVersion = 1
+
+/*
+policyDocument is the JSON form of a Policy. Every field is written, defaults included, so a
+reviewed document states the whole policy; on reading, absent fields take the defaults of
+DefaultPolicy, so an empty document "{}" is the default policy.
+*/
+type policyDocument struct {
+	Version                      int                    `json:"version"`
+	Unknown                      string                 `json:"unknown"`
+	UnknownAllowlist             []AllowedUnknown       `json:"unknownAllowlist"`
+	RequireMetadataStatement     bool                   `json:"requireMetadataStatement"`
+	DenyStatuses                 *[]AuthenticatorStatus `json:"denyStatuses"`
+	MinCertificationLevel        AuthenticatorStatus    `json:"minCertificationLevel"`
+	MinBiometricLevel            BiometricCertLevel     `json:"minBiometricLevel"`
+	AllowEnterpriseAttestation   bool                   `json:"allowEnterpriseAttestation"`
+	EnterpriseAttestationAAGUIDs []string               `json:"enterpriseAttestationAAGUIDs"`
+	MaxMetadataStaleness         string                 `json:"maxMetadataStaleness"`
+	StaleMetadata                string                 `json:"staleMetadata"`
+	StaleMetadataGrace           string                 `json:"staleMetadataGrace"`
+}
+
+// DefaultPolicy returns the policy of the zero Policy, with its defaults spelled out: unknown
+// AAGUIDs are denied, DenyStatuses is a copy of DefaultDenyStatuses, and nothing else is required.
+func DefaultPolicy() Policy {
+	return Policy{Unknown: UnknownDeny, DenyStatuses: slices.Clone(DefaultDenyStatuses)}
+}
+
+// StrictPolicy returns a preset for high-assurance registrations: DefaultPolicy, also denying
+// UPDATE_AVAILABLE and NOT_FIDO_CERTIFIED entries, and requiring a metadata statement and
+// FIDO_CERTIFIED_L1.
+func StrictPolicy() Policy {
+	pol := DefaultPolicy()
+	pol.RequireMetadataStatement = true
+	pol.DenyStatuses = append(pol.DenyStatuses, UPDATE_AVAILABLE, NOT_FIDO_CERTIFIED)
+	pol.MinCertificationLevel = FIDO_CERTIFIED_L1
+	return pol
+}
+
+// PermissivePolicy returns a preset for consumer sites: DefaultPolicy, but accepting unknown
+// AAGUIDs with Decision.Warning set. Revoked and compromised models are still denied.
+func PermissivePolicy() Policy {
+	pol := DefaultPolicy()
+	pol.Unknown = UnknownAllowWithWarning
+	return pol
+}
+
+/*
+MarshalJSON encodes pol as a version PolicySchemaVersion policy document, e.g.
+
+	{
+	  "version": 1,
+	  "unknown": "deny",
+	  "unknownAllowlist": [],
+	  "requireMetadataStatement": false,
+	  "denyStatuses": ["REVOKED", ...],
+	  "minCertificationLevel": "",
+	  "minBiometricLevel": 0,
+	  "allowEnterpriseAttestation": false,
+	  "enterpriseAttestationAAGUIDs": [],
+	  "maxMetadataStaleness": "0s",
+	  "staleMetadata": "deny",
+	  "staleMetadataGrace": "0s"
+	}
+
+MaxMetadataStaleness and StaleMetadataGrace are written in time.Duration syntax, e.g. "168h".
+
+Defaults are written out: a nil DenyStatuses is written as DefaultDenyStatuses. AAGUIDs are written
+in canonical form (see Entry.Key). PolicyFromJSON
+reads the document back into an equivalent Policy, which encodes to the same document.
+*/
+func (pol Policy) MarshalJSON() ([]byte, error) {
+	deny := pol.DenyStatuses
+	if deny == nil {
+		deny = DefaultDenyStatuses
+	}
+	doc := policyDocument{
+		Version:                      PolicySchemaVersion,
+		Unknown:                      pol.Unknown.String(),
+		UnknownAllowlist:             pol.UnknownAllowlist,
+		RequireMetadataStatement:     pol.RequireMetadataStatement,
+		DenyStatuses:                 &deny,
+		MinCertificationLevel:        pol.MinCertificationLevel,
+		MinBiometricLevel:            pol.MinBiometricLevel,
+		AllowEnterpriseAttestation:   pol.AllowEnterpriseAttestation,
+		EnterpriseAttestationAAGUIDs: pol.EnterpriseAttestationAAGUIDs,
+		MaxMetadataStaleness:         pol.MaxMetadataStaleness.String(),
+		StaleMetadata:                pol.StaleMetadata.String(),
+		StaleMetadataGrace:           pol.StaleMetadataGrace.String(),
+	}
+	doc.UnknownAllowlist = make([]AllowedUnknown, len(pol.UnknownAllowlist))
+	for i, a := range pol.UnknownAllowlist {
+		a.AAGUID = canonicalKey(a.AAGUID)
+		doc.UnknownAllowlist[i] = a
+	}
+	doc.EnterpriseAttestationAAGUIDs = canonicalKeyList(pol.EnterpriseAttestationAAGUIDs)
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON decodes a policy document as PolicyFromJSON does.
+func (pol *Policy) UnmarshalJSON(b []byte) error {
+	p, err := PolicyFromJSON(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	*pol = p
+	return nil
+}
+
+/*
+PolicyFromJSON reads a policy document written by Policy.MarshalJSON, or by hand. Absent fields
+take the defaults of DefaultPolicy, so "{}" yields DefaultPolicy; an absent "version" is read as
+PolicySchemaVersion. An explicit empty "denyStatuses" denies no status.
+
+Validation is strict, and every problem found is reported, not just the first:
+
+  - unknown fields and versions newer than PolicySchemaVersion are rejected
+  - "unknown" must be "deny", "allow" or "allow_with_warning"
+  - statuses must be AuthenticatorStatus names, matched exactly; misspellings are rejected with the
+    closest name suggested, e.g. `denyStatuses[0]: unknown status "REVOKE" (did you mean REVOKED?)`
+  - "minCertificationLevel" must be a FIDO_CERTIFIED* status and "minBiometricLevel" 0, 1 or 2
+  - the AAGUIDs of "unknownAllowlist" and "enterpriseAttestationAAGUIDs" must be well-formed
+  - "maxMetadataStaleness" and "staleMetadataGrace" must be non-negative durations such as "72h",
+    and "staleMetadata" "deny" or "warn"
+*/
+func PolicyFromJSON(r io.Reader) (Policy, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var doc policyDocument
+	if err := dec.Decode(&doc); err != nil {
+		return Policy{}, fmt.Errorf("decoding policy: %w", err)
+	}
+	if dec.More() {
+		return Policy{}, errors.New("decoding policy: unexpected data after the policy document")
+	}
+	if doc.Version > PolicySchemaVersion || doc.Version < 0 {
+		return Policy{}, fmt.Errorf("policy: unsupported schema version %d (this package reads up to %d)", doc.Version, PolicySchemaVersion)
+	}
+
+	pol := DefaultPolicy()
+	var errs []error
+	if doc.Unknown != "" {
+		if err := pol.Unknown.UnmarshalText([]byte(doc.Unknown)); err != nil {
+			errs = append(errs, fmt.Errorf("unknown: %q is not one of deny, allow, allow_with_warning", doc.Unknown))
+		}
+	}
+	for i, a := range doc.UnknownAllowlist {
+		if _, err := ParseAAGUID(a.AAGUID); err != nil {
+			errs = append(errs, fmt.Errorf("unknownAllowlist[%d]: %w", i, err))
+		}
+	}
+	pol.UnknownAllowlist = doc.UnknownAllowlist
+	pol.RequireMetadataStatement = doc.RequireMetadataStatement
+	if doc.DenyStatuses != nil {
+		pol.DenyStatuses = *doc.DenyStatuses
+		if pol.DenyStatuses == nil {
+			pol.DenyStatuses = []AuthenticatorStatus{}
+		}
+		for i, s := range pol.DenyStatuses {
+			if _, ok := StatusInfo(s); !ok {
+				errs = append(errs, fmt.Errorf("denyStatuses[%d]: unknown status %q%s", i, s, suggestStatus(s, allStatusNames())))
+			}
+		}
+	}
+	if s := doc.MinCertificationLevel; s != "" && !isCertificationLevel(s) {
+		errs = append(errs, fmt.Errorf("minCertificationLevel: %q is not a certification level%s", s, suggestStatus(s, certificationLevelNames())))
+	}
+	pol.MinCertificationLevel = doc.MinCertificationLevel
+	if doc.MinBiometricLevel > BiometricCertLevel2 {
+		errs = append(errs, fmt.Errorf("minBiometricLevel: %d is not 0, 1 or 2", doc.MinBiometricLevel))
+	}
+	pol.MinBiometricLevel = doc.MinBiometricLevel
+	pol.AllowEnterpriseAttestation = doc.AllowEnterpriseAttestation
+	for i, id := range doc.EnterpriseAttestationAAGUIDs {
+		if _, err := ParseAAGUID(id); err != nil {
+			errs = append(errs, fmt.Errorf("enterpriseAttestationAAGUIDs[%d]: %w", i, err))
+		}
+	}
+	pol.EnterpriseAttestationAAGUIDs = doc.EnterpriseAttestationAAGUIDs
+	pol.MaxMetadataStaleness = parsePolicyDuration("maxMetadataStaleness", doc.MaxMetadataStaleness, &errs)
+	if doc.StaleMetadata != "" {
+		if err := pol.StaleMetadata.UnmarshalText([]byte(doc.StaleMetadata)); err != nil {
+			errs = append(errs, fmt.Errorf("staleMetadata: %q is not one of deny, warn", doc.StaleMetadata))
+		}
+	}
+	pol.StaleMetadataGrace = parsePolicyDuration("staleMetadataGrace", doc.StaleMetadataGrace, &errs)
+	if len(pol.UnknownAllowlist) == 0 {
+		pol.UnknownAllowlist = nil
+	}
+	if len(pol.EnterpriseAttestationAAGUIDs) == 0 {
+		pol.EnterpriseAttestationAAGUIDs = nil
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return Policy{}, fmt.Errorf("invalid policy:\n%w", err)
+	}
+	return pol, nil
+}
+
+// parsePolicyDuration parses the duration s of the field name, 0 if it is absent, and appends to
+// errs if it is malformed or negative.
+func parsePolicyDuration(name, s string, errs *[]error) time.Duration {
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	switch {
+	case err != nil:
+		*errs = append(*errs, fmt.Errorf("%s: %q is not a duration such as \"72h\"", name, s))
+	case d < 0:
+		*errs = append(*errs, fmt.Errorf("%s: %q is negative", name, s))
+	}
+	return d
+}
+
+// allStatusNames returns the names of every known status.
+func allStatusNames() []string {
+	names := make([]string, len(statusTable))
+	for i, m := range statusTable {
+		names[i] = string(m.Status)
+	}
+	return names
+}
+
+// certificationLevelNames returns the names of the FIDO_CERTIFIED* statuses.
+func certificationLevelNames() []string {
+	var names []string
+	for _, m := range statusTable {
+		if isCertificationLevel(m.Status) {
+			names = append(names, string(m.Status))
+		}
+	}
+	return names
+}
+
+/*
+suggestStatus returns " (did you mean X?)" for the candidate X closest to s, ignoring case and
+hyphens versus underscores, or "" if none is close: at most a third of the longer name may
+differ, so "REVOKE" suggests REVOKED while "BLOCKED" suggests nothing.
+*/
+func suggestStatus(s AuthenticatorStatus, candidates []string) string {
+	norm := func(v string) string { return strings.ReplaceAll(strings.ToUpper(v), "-", "_") }
+	in := norm(string(s))
+	best, bestDist := "", -1
+	for _, c := range candidates {
+		d := editDistance(in, norm(c))
+		if d*3 > max(len(in), len(c)) {
+			continue
+		}
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %s?)", best)
+}
+
+// editDistance returns the Levenshtein distance between a and b, counted in bytes.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
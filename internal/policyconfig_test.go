@@ -0,0 +1,253 @@
+package aaguids
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// policyDocumentJSON returns pol's policy document, indented as the golden files are.
+func policyDocumentJSON(t *testing.T, pol Policy) []byte {
+	t.Helper()
+	raw, err := json.Marshal(pol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// decodePolicy reads doc with PolicyFromJSON.
+func decodePolicy(t *testing.T, doc []byte) Policy {
+	t.Helper()
+	pol, err := PolicyFromJSON(bytes.NewReader(doc))
+	if err != nil {
+		t.Fatalf("PolicyFromJSON: %v", err)
+	}
+	return pol
+}
+
+// stalenessPolicy returns DefaultPolicy with a staleness limit and grace window.
+func stalenessPolicy() Policy {
+	pol := DefaultPolicy()
+	pol.MaxMetadataStaleness = 72 * time.Hour
+	pol.StaleMetadataGrace = 36*time.Hour + 30*time.Minute
+	return pol
+}
+
+// TestPolicyGoldens checks the documents of the presets and of a policy with the time-based keys
+// set against their golden files.
+func TestPolicyGoldens(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		pol  Policy
+	}{
+		{"default", DefaultPolicy()},
+		{"permissive", PermissivePolicy()},
+		{"strict", StrictPolicy()},
+		{"staleness", stalenessPolicy()},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join("testdata", "policy-"+tt.name+".json")
+			checkGolden(t, path, policyDocumentJSON(t, tt.pol))
+			if got := decodePolicy(t, readTestdata(t, "policy-"+tt.name+".json")); !reflect.DeepEqual(got, tt.pol) {
+				t.Errorf("%s decodes to %+v, want %+v", path, got, tt.pol)
+			}
+		})
+	}
+}
+
+func TestPolicyRoundTrip(t *testing.T) {
+	full := Policy{
+		Unknown: UnknownAllowWithWarning,
+		UnknownAllowlist: []AllowedUnknown{
+			{AAGUID: strings.ToUpper(testGPM), Expires: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), Note: "pilot"},
+		},
+		RequireMetadataStatement:     true,
+		DenyStatuses:                 []AuthenticatorStatus{REVOKED, USER_KEY_REMOTE_COMPROMISE},
+		MinCertificationLevel:        FIDO_CERTIFIED_L2,
+		MinBiometricLevel:            BiometricCertLevel1,
+		AllowEnterpriseAttestation:   true,
+		EnterpriseAttestationAAGUIDs: []string{strings.ToUpper(testYubiKey)},
+		MaxMetadataStaleness:         72 * time.Hour,
+		StaleMetadata:                StaleMetadataWarn,
+		StaleMetadataGrace:           90 * time.Minute,
+	}
+	for _, tt := range []struct {
+		name string
+		pol  Policy
+	}{
+		{"zero", Policy{}},
+		{"default", DefaultPolicy()},
+		{"permissive", PermissivePolicy()},
+		{"strict", StrictPolicy()},
+		{"full", full},
+		{"staleness", stalenessPolicy()},
+		{"grace without a staleness limit", Policy{StaleMetadataGrace: time.Hour}},
+		{"deny nothing", Policy{DenyStatuses: []AuthenticatorStatus{}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// The first encoding canonicalizes the AAGUIDs and spells out the defaults; from then
+			// on decoding and encoding again changes nothing.
+			first := policyDocumentJSON(t, tt.pol)
+			decoded := decodePolicy(t, first)
+			second := policyDocumentJSON(t, decoded)
+			if !bytes.Equal(first, second) {
+				t.Fatalf("document changed after a round trip:\n%s\nthen\n%s", first, second)
+			}
+			if again := decodePolicy(t, second); !reflect.DeepEqual(again, decoded) {
+				t.Errorf("policy changed after a round trip: %+v, then %+v", decoded, again)
+			}
+			if decoded.MaxMetadataStaleness != tt.pol.MaxMetadataStaleness || decoded.StaleMetadata != tt.pol.StaleMetadata ||
+				decoded.StaleMetadataGrace != tt.pol.StaleMetadataGrace {
+				t.Errorf("staleness %s, %s, grace %s after a round trip, want %s, %s, grace %s",
+					decoded.MaxMetadataStaleness, decoded.StaleMetadata, decoded.StaleMetadataGrace,
+					tt.pol.MaxMetadataStaleness, tt.pol.StaleMetadata, tt.pol.StaleMetadataGrace)
+			}
+			for _, id := range decoded.EnterpriseAttestationAAGUIDs {
+				if id != canonicalKey(id) {
+					t.Errorf("enterprise attestation AAGUID %q not written canonically", id)
+				}
+			}
+		})
+	}
+	if got := decodePolicy(t, []byte(`{"maxMetadataStaleness": "1.5h", "staleMetadataGrace": "90m"}`)); got.MaxMetadataStaleness != got.StaleMetadataGrace {
+		t.Errorf("hand-written durations decode to %s and %s, want 1h30m0s for both", got.MaxMetadataStaleness, got.StaleMetadataGrace)
+	}
+	if got := decodePolicy(t, []byte(`{"denyStatuses": []}`)); got.DenyStatuses == nil || len(got.DenyStatuses) != 0 {
+		t.Errorf("an empty denyStatuses decodes to %#v, want an empty list", got.DenyStatuses)
+	}
+}
+
+func TestPolicyFromJSONDefaults(t *testing.T) {
+	want := DefaultPolicy()
+	for _, doc := range []string{`{}`, `{"version": 1}`, ` { } `} {
+		if got := decodePolicy(t, []byte(doc)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s decodes to %+v, want DefaultPolicy %+v", doc, got, want)
+		}
+	}
+}
+
+func TestPolicyFromJSONErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		doc  string
+		want []string // substrings of the error; all must appear
+	}{
+		{"unknown field", `{"denyStatus": []}`, []string{`unknown field "denyStatus"`}},
+		{"newer version", `{"version": 2}`, []string{"unsupported schema version 2", "up to 1"}},
+		{"negative version", `{"version": -1}`, []string{"unsupported schema version -1"}},
+		{"trailing data", `{} {}`, []string{"unexpected data after the policy document"}},
+		{"unknown mode", `{"unknown": "permit"}`, []string{`unknown: "permit" is not one of deny, allow, allow_with_warning`}},
+		{"misspelled status", `{"denyStatuses": ["REVOKE", "user-key-remote-compromise"]}`, []string{
+			`denyStatuses[0]: unknown status "REVOKE" (did you mean REVOKED?)`,
+			`denyStatuses[1]: unknown status "user-key-remote-compromise" (did you mean USER_KEY_REMOTE_COMPROMISE?)`,
+		}},
+		{"unrelated status", `{"denyStatuses": ["BLOCKED"]}`, []string{`denyStatuses[0]: unknown status "BLOCKED"`}},
+		{"not a certification level", `{"minCertificationLevel": "FIDO_CERTIFIED_L9"}`, []string{
+			`minCertificationLevel: "FIDO_CERTIFIED_L9" is not a certification level (did you mean FIDO_CERTIFIED_L1?)`,
+		}},
+		{"biometric level", `{"minBiometricLevel": 3}`, []string{"minBiometricLevel: 3 is not 0, 1 or 2"}},
+		{"malformed AAGUIDs", `{"unknownAllowlist": [{"aaguid": "nope"}], "enterpriseAttestationAAGUIDs": ["1234"]}`, []string{
+			"unknownAllowlist[0]: ", "enterpriseAttestationAAGUIDs[0]: ",
+		}},
+		{"staleness", `{"maxMetadataStaleness": "3 days", "staleMetadata": "ignore"}`, []string{
+			`maxMetadataStaleness: "3 days" is not a duration`, `staleMetadata: "ignore" is not one of deny, warn`,
+		}},
+		{"negative staleness", `{"maxMetadataStaleness": "-1h"}`, []string{`maxMetadataStaleness: "-1h" is negative`}},
+		{"grace", `{"staleMetadataGrace": "a day"}`, []string{`staleMetadataGrace: "a day" is not a duration`}},
+		{"negative grace", `{"staleMetadataGrace": "-24h"}`, []string{`staleMetadataGrace: "-24h" is negative`}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PolicyFromJSON(strings.NewReader(tt.doc))
+			if err == nil {
+				t.Fatal("no error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+	_, err := PolicyFromJSON(strings.NewReader(`{"denyStatuses": ["BLOCKED"]}`))
+	if err != nil && strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("suggestion for an unrelated name: %v", err)
+	}
+}
+
+func TestPolicyDocumentKeys(t *testing.T) {
+	expires := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		key  string
+		set  func(*Policy)
+		want string // the key's value in the document
+	}{
+		{"version", func(*Policy) {}, "1"},
+		{"unknown", func(p *Policy) { p.Unknown = UnknownAllow }, `"allow"`},
+		{
+			"unknownAllowlist",
+			func(p *Policy) {
+				p.UnknownAllowlist = []AllowedUnknown{{AAGUID: testGPM, Expires: expires, Note: "pilot"}}
+			},
+			`[{"aaguid":"` + testGPM + `","expires":"2027-01-01T00:00:00Z","note":"pilot"}]`,
+		},
+		{"requireMetadataStatement", func(p *Policy) { p.RequireMetadataStatement = true }, "true"},
+		{"denyStatuses", func(p *Policy) { p.DenyStatuses = []AuthenticatorStatus{REVOKED} }, `["REVOKED"]`},
+		{"minCertificationLevel", func(p *Policy) { p.MinCertificationLevel = FIDO_CERTIFIED_L2 }, `"FIDO_CERTIFIED_L2"`},
+		{"minBiometricLevel", func(p *Policy) { p.MinBiometricLevel = BiometricCertLevel1 }, "1"},
+		{"allowEnterpriseAttestation", func(p *Policy) { p.AllowEnterpriseAttestation = true }, "true"},
+		{"enterpriseAttestationAAGUIDs", func(p *Policy) { p.EnterpriseAttestationAAGUIDs = []string{testYubiKey} }, `["` + testYubiKey + `"]`},
+		{"maxMetadataStaleness", func(p *Policy) { p.MaxMetadataStaleness = 72 * time.Hour }, `"72h0m0s"`},
+		{"staleMetadata", func(p *Policy) { p.StaleMetadata = StaleMetadataWarn }, `"warn"`},
+		{"staleMetadataGrace", func(p *Policy) { p.StaleMetadataGrace = 90 * time.Minute }, `"1h30m0s"`},
+	}
+
+	// Every key of policyDocument needs a row, so a new one cannot go untested.
+	rows := make(map[string]bool, len(tests))
+	for _, tt := range tests {
+		rows[tt.key] = true
+	}
+	docType := reflect.TypeFor[policyDocument]()
+	for i := range docType.NumField() {
+		key := jsonFieldName(docType.Field(i))
+		if !rows[key] {
+			t.Errorf("policyDocument key %q has no row", key)
+		}
+		delete(rows, key)
+	}
+	for key := range rows {
+		t.Errorf("row %q is not a policyDocument key", key)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			pol := DefaultPolicy()
+			tt.set(&pol)
+			raw, err := json.Marshal(pol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &doc); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(doc[tt.key]); got != tt.want {
+				t.Errorf("%q encodes as %s, want %s", tt.key, got, tt.want)
+			}
+			// The key alone decodes to the policy, the other keys taking their defaults.
+			alone := []byte(`{"` + tt.key + `": ` + tt.want + `}`)
+			if got := decodePolicy(t, alone); !reflect.DeepEqual(got, pol) {
+				t.Errorf("%s decodes to %+v, want %+v", alone, got, pol)
+			}
+		})
+	}
+}
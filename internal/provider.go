@@ -0,0 +1,412 @@
+package aaguids
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+Provider serves lookups over an immutable snapshot of metadata entries. Update swaps the whole
+snapshot atomically, so readers never observe a half-applied refresh: every lookup or iteration
+runs against the snapshot that was current when it started.
+
+The package-level functions (GetEntry, DatasetInfo, ...) are served by Default(), which wraps the
+embedded dataset.
+*/
+type Provider struct {
+	snap atomic.Pointer[snapshot]
+
+	updateMu           sync.Mutex    // serializes Update so tombstones carry over between snapshots
+	updated            chan struct{} // closed and replaced by every Update; guarded by updateMu
+	tombstoneRetention atomic.Int64  // see SetTombstoneRetention
+
+	watchMu  sync.Mutex
+	watchers map[*watcher]struct{}
+
+	isDefault bool         // set on the Provider returned by Default; see EnableConformanceDefault
+	logger    *slog.Logger // see WithLogger
+
+	legalMu         sync.Mutex
+	acceptedHeaders map[string]bool // LegalHeaderHash → accepted; see WithAcceptedLegalHeader
+
+	normMu      sync.Mutex                      // serializes AppendNormalizers
+	normalizers atomic.Pointer[NormalizerChain] // nil until hooks are appended; see normalizerChain
+
+	fetch            *FetchCoordinator // see WithFetchCoordinator
+	uniformLookup    atomic.Bool       // see WithUniformLookupTiming
+	maxStatusReports int               // see WithMergedStatusReportLimit and statusReportLimit
+	fallbackErr      error             // errors of the sources FirstAvailable skipped; see Health
+
+	refreshMu      sync.Mutex
+	lastRefresh    time.Time // see RecordRefresh
+	lastRefreshErr error
+}
+
+// snapshot is one immutable generation of a Provider's data.
+type snapshot struct {
+	entries        map[string]Entry
+	keys           []string            // sorted keys of entries
+	canonical      map[string]string   // lowercase AAGUID → key, for keys stored in another case
+	keyIDs         map[string]string   // lowercase attestation certificate key identifier → key
+	keyIDConflicts map[string][]string // identifiers left out of keyIDs → sorted keys listing them
+	aaids          map[string]string   // uppercase AAID → key
+	info           Dataset
+
+	rootFingerprints map[[32]byte][]string      // attestation root SHA-256 → sorted keys trusting it
+	removed          map[string]RemovedEntry    // canonical key → tombstone of an entry no longer in entries
+	trigrams         map[string]trigramSet      // key → trigrams of description and curated vendor
+	provenance       FieldProvenance            // see MergeEntries; nil unless built by UpdateMerged
+	legalHeaders     []string                   // sorted distinct legal headers of entries
+	raw              map[string]json.RawMessage // key → upstream JSON; nil unless retained, see RawEntryJSON
+	origin           DatasetOrigin              // how entries were loaded; see Health
+	loadedAt         time.Time                  // when the snapshot was installed
+	hasIcons         bool                       // some entry has an icon; see HealthStatus.IconsStripped
+
+	rootsOnce    sync.Once
+	rootSubjects map[string][]string // attestation root RawSubject → sorted keys trusting it
+
+	certVersionsOnce sync.Once
+	certVersionIdx   *certVersionIndex // see certVersions
+
+	timelines sync.Map // key → []StatusInterval, filled by Provider.StatusTimeline
+}
+
+// Filter reports whether an Entry belongs in a query result. A nil Filter matches all entries.
+type Filter func(Entry) bool
+
+// ProviderOption configures a Provider created by NewProvider.
+type ProviderOption func(*Provider)
+
+// WithLogger sets the logger the Provider reports warnings to (default slog.Default()).
+func WithLogger(l *slog.Logger) ProviderOption {
+	return func(p *Provider) { p.logger = l }
+}
+
+// NewProvider returns a Provider serving entries. The map must not be modified afterwards.
+func NewProvider(entries map[string]Entry, info Dataset, opts ...ProviderOption) *Provider {
+	p := &Provider{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.Update(entries, info)
+	return p
+}
+
+// log returns the Provider's logger.
+func (p *Provider) log() *slog.Logger {
+	if p.logger == nil {
+		return slog.Default()
+	}
+	return p.logger
+}
+
+/*
+Update atomically replaces the Provider's dataset and notifies Watch subscribers. Lookups and
+iterations that are already running keep using the previous snapshot. Entries of the previous
+dataset that are missing from entries are kept as tombstones (see RemovedEntries), and sources of
+info that contributed no entries keep the Version and UpdatedAt of the previous dataset (see
+DatasetSource). The map must not be modified afterwards.
+
+Update panics if p is the Default provider and info is conformance data, unless
+EnableConformanceDefault was called.
+*/
+func (p *Provider) Update(entries map[string]Entry, info Dataset) {
+	p.update(entries, info, nil, nil, OriginProvided)
+}
+
+// update implements Update, UpdateMerged and UpdateFromBLOB; origin says where entries came from
+// (see Health).
+func (p *Provider) update(entries map[string]Entry, info Dataset, prov FieldProvenance, raw map[string]json.RawMessage, origin DatasetOrigin) {
+	if p.isDefault && info.Conformance && !conformanceDefault.Load() {
+		panic("aaguids: conformance data loaded into the Default provider; call EnableConformanceDefault first")
+	}
+	entries = withCompatFields(entries)
+	keys := make([]string, 0, len(entries))
+	emitted := make(map[string]string, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+		emitted[k] = canonicalKey(k)
+	}
+	// Keys are kept as stored but ordered by their canonical form, the order callers see them in.
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Or(strings.Compare(emitted[a], emitted[b]), strings.Compare(a, b))
+	})
+	keyIDs, keyIDConflicts := keyIdentifierIndex(entries, keys)
+	headers := distinctLegalHeaders(entries)
+	info.LegalHeaderHashes = legalHeaderHashes(headers)
+	cur := &snapshot{
+		entries:          entries,
+		keys:             keys,
+		canonical:        canonicalKeys(keys),
+		keyIDs:           keyIDs,
+		keyIDConflicts:   keyIDConflicts,
+		aaids:            aaidIndex(entries, keys),
+		info:             info,
+		rootFingerprints: rootFingerprintIndex(entries, keys),
+		trigrams:         searchIndex(entries, keys),
+		provenance:       prov,
+		legalHeaders:     headers,
+		raw:              raw,
+		origin:           origin,
+		loadedAt:         time.Now(),
+		hasIcons:         anyIcons(entries),
+	}
+	p.updateMu.Lock()
+	defer p.updateMu.Unlock()
+	cur.removed = tombstones(p.snap.Load(), cur, int(p.tombstoneRetention.Load()))
+	cur.info.Sources = carrySourceFreshness(p.snap.Load(), cur.info.Sources)
+	old := p.snap.Swap(cur)
+	if p.updated != nil {
+		close(p.updated)
+	}
+	p.updated = make(chan struct{})
+	p.checkLegalHeaders(old, cur)
+	p.checkKeyIdentifiers(cur)
+	p.notifyWatchers(old, cur)
+}
+
+// changed returns a channel that is closed by the next Update.
+func (p *Provider) changed() <-chan struct{} {
+	p.updateMu.Lock()
+	defer p.updateMu.Unlock()
+	return p.updated
+}
+
+// defaultProvider wraps the embedded dataset; it is built on first use.
+var defaultProvider = sync.OnceValue(func() *Provider {
+	p := &Provider{isDefault: true}
+	p.update(metadata, datasetInfo, fieldProvenance, nil, OriginEmbedded)
+	return p
+})
+
+// Default returns the Provider backed by the dataset compiled into this package.
+func Default() *Provider {
+	return defaultProvider()
+}
+
+// current returns the snapshot in effect right now.
+func (p *Provider) current() *snapshot {
+	return p.snap.Load()
+}
+
+/*
+GetEntry retrieves the Entry identified by aaGuid from the current snapshot. aaGuid is a synthetic
+UAF or U2F key (see UAFKey and U2FKey) or an AAGUID in any spelling the Provider's NormalizerChain
+accepts: upper or lower case, with or without braces, a "urn:uuid:" prefix or dashes. Input that is
+not a valid AAGUID reports false, and so does the all-zero AAGUID (see IsZeroAAGUID), which
+identifies no model even if a dataset lists an entry under it.
+*/
+func (p *Provider) GetEntry(aaGuid string) (e Entry, exists bool) {
+	s := p.current()
+	k, ok := p.lookupKey(s, aaGuid)
+	if !ok {
+		return Entry{}, false
+	}
+	return s.entries[k], true
+}
+
+// GetEntries retrieves several entries from the embedded dataset at once. See Provider.GetEntries.
+func GetEntries(aaGuids []string) (found map[string]Entry, missing []string) {
+	return Default().GetEntries(aaGuids)
+}
+
+/*
+GetEntries looks up every ID of aaGuids as GetEntry does, all against the same snapshot. found maps
+each ID that exists, as supplied, to its Entry, so different spellings of one AAGUID each get a
+key; missing lists the IDs that do not exist once each, in the order first supplied. Duplicate IDs
+are looked up only once. found is never nil.
+*/
+func (p *Provider) GetEntries(aaGuids []string) (found map[string]Entry, missing []string) {
+	s := p.current()
+	found = make(map[string]Entry, len(aaGuids))
+	seen := make(map[string]bool, len(aaGuids))
+	for _, id := range aaGuids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if k, ok := p.lookupKey(s, id); ok {
+			found[id] = s.entries[k]
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return found, missing
+}
+
+var (
+	// ErrUnknownAAGUID reports a well-formed AAGUID or synthetic key that is not in the dataset.
+	ErrUnknownAAGUID = errors.New("aaguids: unknown AAGUID")
+	// ErrZeroAAGUID reports the all-zero AAGUID, which identifies no model (see IsZeroAAGUID). Errors
+	// wrapping it also wrap ErrUnknownAAGUID.
+	ErrZeroAAGUID = errors.New("aaguids: zero AAGUID identifies no model")
+	// ErrInvalidAAGUID reports input that is neither an AAGUID spelling the NormalizerChain accepts
+	// nor a UAF or U2F key; the wrapping error says what was wrong with it.
+	ErrInvalidAAGUID = errors.New("aaguids: invalid AAGUID")
+)
+
+// Lookup retrieves an Entry of the embedded dataset, or says why it cannot. See Provider.Lookup.
+func Lookup(aaGuid string) (Entry, error) {
+	return Default().Lookup(aaGuid)
+}
+
+/*
+Lookup retrieves the Entry identified by aaGuid like GetEntry, but returns an error instead of
+false: one wrapping ErrInvalidAAGUID, with the length or the non-hex character at fault, if aaGuid
+cannot name any entry, and one wrapping ErrUnknownAAGUID if it is well-formed but not in the
+dataset, naming it in canonical form. The all-zero AAGUID yields an error wrapping both
+ErrZeroAAGUID and ErrUnknownAAGUID. Test for them with errors.Is.
+*/
+func (p *Provider) Lookup(aaGuid string) (Entry, error) {
+	s := p.current()
+	if k, ok := p.lookupKey(s, aaGuid); ok {
+		return s.entries[k], nil
+	}
+	if err := p.checkKey(aaGuid); err != nil {
+		return Entry{}, err
+	}
+	id, err := p.ParseAAGUID(aaGuid)
+	switch {
+	case err != nil:
+		return Entry{}, boundedErrorf("%w %q", ErrUnknownAAGUID, normalizeKey(aaGuid))
+	case id.IsZero():
+		return Entry{}, fmt.Errorf("%w: %w %q", ErrZeroAAGUID, ErrUnknownAAGUID, id.String())
+	}
+	return Entry{}, fmt.Errorf("%w %q", ErrUnknownAAGUID, id.String())
+}
+
+// checkKey returns an error wrapping ErrInvalidAAGUID when aaGuid is neither a well-formed
+// synthetic UAF or U2F key nor an AAGUID p's NormalizerChain parses. It does not consult the
+// dataset.
+func (p *Provider) checkKey(aaGuid string) error {
+	var err error
+	if nk := normalizeKey(aaGuid); strings.HasPrefix(nk, u2fKeyPrefix) {
+		_, err = ParseKeyIdentifier(nk[len(u2fKeyPrefix):])
+	} else if strings.HasPrefix(nk, uafKeyPrefix) {
+		_, err = ParseAAID(nk[len(uafKeyPrefix):])
+	} else {
+		_, _, err = p.normalizerChain().Parse(aaGuid)
+	}
+	if err != nil {
+		return boundedErrorf("%w %q: %w", ErrInvalidAAGUID, aaGuid, err)
+	}
+	return nil
+}
+
+// resolveKey returns the dataset key that k names: the key itself, a synthetic U2F or UAF key in
+// any case, or an AAGUID spelling norm accepts.
+func (s *snapshot) resolveKey(norm NormalizerChain, k string) (string, bool) {
+	if _, ok := s.entries[k]; ok {
+		return k, true
+	}
+	if nk := normalizeKey(k); isSyntheticKey(nk) {
+		_, ok := s.entries[nk]
+		return nk, ok
+	}
+	id, _, err := norm.Parse(k)
+	if err != nil {
+		return "", false
+	}
+	c := id.String()
+	if _, ok := s.entries[c]; ok {
+		return c, true
+	}
+	c, ok := s.canonical[c]
+	return c, ok
+}
+
+// DatasetInfo returns the identity of the Provider's current dataset.
+func (p *Provider) DatasetInfo() Dataset {
+	info := p.current().info
+	info.Sources = append([]DatasetSource(nil), info.Sources...)
+	info.LegalHeaderHashes = append([]string(nil), info.LegalHeaderHashes...)
+	return info
+}
+
+/*
+All yields every entry keyed by its parsed AAGUID, in ascending AAGUID order. Entries whose key is
+not a well-formed AAGUID are skipped.
+
+Each range over the returned sequence pins the snapshot current when the range starts, so an Update
+landing mid-iteration is not observed. Breaking out of the loop stops the iteration immediately.
+*/
+func (p *Provider) All() iter.Seq2[AAGUID, Entry] {
+	return func(yield func(AAGUID, Entry) bool) {
+		s := p.current()
+		for _, k := range s.keys {
+			id, err := ParseAAGUID(k)
+			if err != nil {
+				continue
+			}
+			if !yield(id, s.entries[k]) {
+				return
+			}
+		}
+	}
+}
+
+// AAGUIDs returns the keys of the embedded dataset. See Provider.AAGUIDs.
+func AAGUIDs() []string {
+	return Default().AAGUIDs()
+}
+
+// AAGUIDs returns every dataset key of the current snapshot in sorted canonical form (see
+// Entry.Key): the AAGUIDs, plus the synthetic keys of UAF and U2F entries (see UAFKey and U2FKey),
+// so its length is the entry count.
+func (p *Provider) AAGUIDs() []string {
+	return canonicalKeyList(p.current().keys)
+}
+
+// Entries yields every entry of the embedded dataset. See Provider.Entries.
+func Entries() iter.Seq2[string, Entry] {
+	return Default().Entries()
+}
+
+/*
+Entries yields every entry keyed by its dataset key (see Entry.Key), UAF and U2F entries included, in
+ascending key order. Each Entry is a Clone, so callers can keep or modify it without affecting the
+Provider or other readers.
+
+As with All, each range pins one snapshot for its whole duration, and breaking out of the loop
+stops the iteration.
+*/
+func (p *Provider) Entries() iter.Seq2[string, Entry] {
+	return func(yield func(string, Entry) bool) {
+		s := p.current()
+		for _, k := range s.keys {
+			if !yield(canonicalKey(k), s.entries[k].Clone()) {
+				return
+			}
+		}
+	}
+}
+
+/*
+Where yields the entries matching f, in ascending AAGUID order. A nil f yields every entry.
+
+As with All, each range pins one snapshot for its whole duration, and breaking out of the loop
+stops the iteration without evaluating f on the remaining entries.
+*/
+func (p *Provider) Where(f Filter) iter.Seq[Entry] {
+	return func(yield func(Entry) bool) {
+		s := p.current()
+		for _, k := range s.keys {
+			e := s.entries[k]
+			if f != nil && !f(e) {
+				continue
+			}
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package aaguids
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAAGUIDsCoversEveryEntry(t *testing.T) {
+	blob := readTestBLOB(t, "blob-mixed.json")
+	entries := blob.EntriesByKey()
+	p := NewProvider(entries, Dataset{})
+
+	got := p.AAGUIDs()
+	if len(got) != len(entries) {
+		t.Fatalf("AAGUIDs() has %d keys, want one per entry (%d)", len(got), len(entries))
+	}
+	if !slices.IsSorted(got) {
+		t.Errorf("AAGUIDs() = %q, want sorted", got)
+	}
+	for _, k := range got {
+		if _, ok := p.GetEntry(k); !ok {
+			t.Errorf("GetEntry(%q) of a key from AAGUIDs() found nothing", k)
+		}
+	}
+
+	got[0] = "mutated"
+	if again := p.AAGUIDs(); again[0] == "mutated" {
+		t.Error("mutating the slice returned by AAGUIDs() changed a later result")
+	}
+}
+
+func TestGetEntries(t *testing.T) {
+	p := testProvider(t, Entry{AAGUID: testYubiKey}, Entry{AAGUID: testGPM},
+		Entry{AttestationCertificateKeyIdentifiers: []string{strings.TrimPrefix(testU2FKey, "u2f:")}})
+	const unknown, unknown2 = "00000000-0000-4000-8000-000000000001", "00000000-0000-4000-8000-000000000002"
+	upper := strings.ToUpper(testYubiKey)
+	tests := []struct {
+		name    string
+		ids     []string
+		found   map[string]string // ID as supplied → Key of its entry
+		missing []string
+	}{
+		{"empty", nil, map[string]string{}, nil},
+		{"duplicates", []string{testYubiKey, testGPM, testYubiKey}, map[string]string{testYubiKey: testYubiKey, testGPM: testGPM}, nil},
+		{
+			"mixed case",
+			[]string{upper, testYubiKey, strings.ToUpper(testU2FKey)},
+			map[string]string{upper: testYubiKey, testYubiKey: testYubiKey, strings.ToUpper(testU2FKey): testU2FKey},
+			nil,
+		},
+		{"other spellings", []string{"{" + testGPM + "}", "urn:uuid:" + testGPM}, map[string]string{"{" + testGPM + "}": testGPM, "urn:uuid:" + testGPM: testGPM}, nil},
+		{
+			"unknown mixed with known",
+			[]string{unknown2, testGPM, "not-an-aaguid", unknown, unknown2},
+			map[string]string{testGPM: testGPM},
+			[]string{unknown2, "not-an-aaguid", unknown},
+		},
+		{"all unknown", []string{unknown, unknown2, unknown}, map[string]string{}, []string{unknown, unknown2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, missing := p.GetEntries(tt.ids)
+			if found == nil {
+				t.Fatal("found is nil")
+			}
+			got := make(map[string]string, len(found))
+			for id, e := range found {
+				got[id] = e.Key()
+			}
+			if !maps.Equal(got, tt.found) {
+				t.Errorf("found %v, want %v", got, tt.found)
+			}
+			if !slices.Equal(missing, tt.missing) {
+				t.Errorf("missing %q, want %q", missing, tt.missing)
+			}
+		})
+	}
+}
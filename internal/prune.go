@@ -0,0 +1,171 @@
+package aaguids
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// prunedFormat identifies the container written by SavePruned.
+const prunedFormat = "aaguids-dataset/1"
+
+/*
+PruneReport describes the result of Prune:
+
+  - Requested: the number of keys passed to Prune
+  - Missing: the requested keys that matched no entry, as given
+  - EntriesBefore / EntriesAfter: entry counts of the original and the pruned Provider
+  - BytesBefore / BytesAfter: JSON-encoded size of the entries, a proxy for the embedded size
+*/
+type PruneReport struct {
+	Requested     int      `json:"requested"`
+	Missing       []string `json:"missing,omitempty"`
+	EntriesBefore int      `json:"entriesBefore"`
+	EntriesAfter  int      `json:"entriesAfter"`
+	BytesBefore   int      `json:"bytesBefore"`
+	BytesAfter    int      `json:"bytesAfter"`
+}
+
+// Prune prunes the embedded dataset. See Provider.Prune.
+func Prune(keep []string) (*Provider, PruneReport) {
+	return Default().Prune(keep)
+}
+
+/*
+Prune returns a new Provider holding only the entries of p named in keep, together with their field
+provenance and retained raw JSON, and a report of what was dropped. Keys are AAGUIDs in any spelling
+p's NormalizerChain accepts or synthetic UAF and U2F keys (see UAFKey and U2FKey). The pruned
+Provider has the same Dataset identity with EntryCount and Integrity recomputed, and p's logger,
+normalizers and FetchCoordinator; it starts with no watchers or accepted legal headers. p itself is
+not changed.
+*/
+func (p *Provider) Prune(keep []string) (*Provider, PruneReport) {
+	s, norm := p.current(), p.normalizerChain()
+	rep := PruneReport{Requested: len(keep), EntriesBefore: len(s.entries)}
+	entries := make(map[string]Entry)
+	for _, k := range keep {
+		key, ok := s.resolveKey(norm, k)
+		if !ok {
+			rep.Missing = append(rep.Missing, k)
+			continue
+		}
+		entries[key] = s.entries[key]
+	}
+	rep.EntriesAfter = len(entries)
+	rep.BytesBefore, rep.BytesAfter = encodedSize(s.entries), encodedSize(entries)
+
+	var prov FieldProvenance
+	var raw map[string]json.RawMessage
+	for k := range entries {
+		if fp, ok := s.provenance[k]; ok {
+			if prov == nil {
+				prov = make(FieldProvenance)
+			}
+			prov[k] = fp
+		}
+		if r, ok := s.raw[k]; ok {
+			if raw == nil {
+				raw = make(map[string]json.RawMessage)
+			}
+			raw[k] = r
+		}
+	}
+	info := s.info
+	info.EntryCount = len(entries)
+	info.Integrity, _ = ComputeIntegrity(entries)
+
+	pruned := &Provider{logger: p.logger, fetch: p.fetch}
+	if chain := p.normalizers.Load(); chain != nil {
+		pruned.normalizers.Store(chain)
+	}
+	pruned.update(entries, info, prov, raw, s.origin)
+	return pruned, rep
+}
+
+// encodedSize returns the length of the JSON encoding of entries, or 0 if it cannot be encoded.
+func encodedSize(entries map[string]Entry) int {
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		return 0
+	}
+	return len(raw)
+}
+
+// prunedDataset is the decoded content of a SavePruned container.
+type prunedDataset struct {
+	Format     string                     `json:"format"`
+	Dataset    Dataset                    `json:"dataset"`
+	Entries    map[string]Entry           `json:"entries"`
+	Provenance FieldProvenance            `json:"provenance,omitempty"`
+	Raw        map[string]json.RawMessage `json:"raw,omitempty"`
+}
+
+/*
+SavePruned writes the current snapshot of p, typically one returned by Prune, to w as a compact
+dataset that LoadPruned reads back: gzip-compressed JSON carrying the Dataset identity, the
+entries, their field provenance and any retained raw JSON. Unlike ExportJSON, nothing is indented,
+and loading it back yields the same entries and Integrity.
+*/
+func (p *Provider) SavePruned(w io.Writer) error {
+	s := p.current()
+	zw := gzip.NewWriter(w)
+	encErr := json.NewEncoder(zw).Encode(prunedDataset{
+		Format:     prunedFormat,
+		Dataset:    s.info,
+		Entries:    s.entries,
+		Provenance: s.provenance,
+		Raw:        s.raw,
+	})
+	if err := errors.Join(encErr, zw.Close()); err != nil {
+		return fmt.Errorf("writing pruned dataset: %w", err)
+	}
+	return nil
+}
+
+/*
+LoadPruned reads a dataset written by SavePruned into a new Provider configured with opts. The
+Dataset Integrity is checked against the entries, so a truncated or edited file is rejected rather
+than served.
+*/
+func LoadPruned(r io.Reader, opts ...ProviderOption) (*Provider, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing pruned dataset: %w", err)
+	}
+	var d prunedDataset
+	if err := json.NewDecoder(zr).Decode(&d); err != nil {
+		return nil, fmt.Errorf("decoding pruned dataset: %w", err)
+	}
+	if d.Format != prunedFormat {
+		return nil, fmt.Errorf("pruned dataset: unsupported format %q", d.Format)
+	}
+	if d.Entries == nil {
+		d.Entries = map[string]Entry{}
+	}
+	if err := checkIntegrity(d.Dataset, d.Entries); err != nil {
+		return nil, fmt.Errorf("pruned dataset: %w", err)
+	}
+	p := &Provider{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.update(d.Entries, d.Dataset, d.Provenance, d.Raw, OriginStore)
+	return p, nil
+}
+
+// checkIntegrity checks the entries read back from storage against info.Integrity, if it is set.
+func checkIntegrity(info Dataset, entries map[string]Entry) error {
+	if info.Integrity == "" {
+		return nil
+	}
+	got, err := ComputeIntegrity(entries)
+	if err != nil {
+		return err
+	}
+	if got != info.Integrity {
+		return fmt.Errorf("integrity %s does not match entries (%s)", info.Integrity, got)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package aaguids
+
+import "encoding/json"
+
+// ParseOption adjusts ParseMetadataBLOB.
+type ParseOption func(*parseConfig)
+
+// parseConfig is the configuration of one ParseMetadataBLOB call.
+type parseConfig struct {
+	rawEntries       bool
+	maxStatusReports int // see WithStatusReportLimit and statusReportLimit
+}
+
+/*
+WithRawEntries makes ParseMetadataBLOB keep the upstream JSON of every entry in
+BLOBPayload.RawEntries, so a Provider updated from the BLOB can serve it via RawEntryJSON. It
+roughly doubles the memory held per entry, which is why it is off by default.
+*/
+func WithRawEntries() ParseOption {
+	return func(c *parseConfig) { c.rawEntries = true }
+}
+
+/*
+RawEntryJSON returns the upstream JSON of the entry identified by aaGuid exactly as its source
+published it, for consumers that pass entries through rather than re-serializing Entry. ok is false
+when the Provider did not retain raw JSON for the entry:
+
+  - Providers updated by UpdateFromBLOB retain it when the BLOB was parsed with WithRawEntries
+  - Providers updated by UpdateMerged retain it for the sources that carry SourceEntries.Raw;
+    a merged entry returns the raw form of its highest-priority source, see RawEntryMerged
+  - Update and the embedded dataset, which is compiled from Go literals, never retain it
+*/
+func (p *Provider) RawEntryJSON(aaGuid string) (raw []byte, ok bool) {
+	s := p.current()
+	k, _ := p.lookupKey(s, aaGuid)
+	r, ok := s.raw[k]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), r...), true
+}
+
+// RawEntryMerged reports whether fields of the entry identified by aaGuid were filled in from a
+// lower-priority source by MergeEntries, in which case it differs from RawEntryJSON in the fields
+// listed by EntryFieldProvenance.
+func (p *Provider) RawEntryMerged(aaGuid string) bool {
+	s := p.current()
+	k, _ := p.lookupKey(s, aaGuid)
+	return len(s.provenance[k]) > 0
+}
+
+// mergedRaw returns, for every key of sources, the raw JSON of the highest-priority source that has
+// the key, or nil when no source carries raw JSON.
+func mergedRaw(sources []SourceEntries) map[string]json.RawMessage {
+	var raw map[string]json.RawMessage
+	seen := make(map[string]bool)
+	for _, src := range sources {
+		for k := range src.Entries {
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			if r, ok := src.Raw[k]; ok {
+				if raw == nil {
+					raw = make(map[string]json.RawMessage)
+				}
+				raw[k] = r
+			}
+		}
+	}
+	return raw
+}
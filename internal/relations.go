@@ -0,0 +1,188 @@
+package aaguids
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// RelationKind says how the AAGUIDs of an AAGUIDRelation are related.
+type RelationKind string
+
+const (
+	// RelationRebranded: one product sold under several names, each with its own AAGUID.
+	RelationRebranded RelationKind = "same-product-rebranded"
+	// RelationFirmwareSplit: one model line whose AAGUID changed between firmware versions.
+	RelationFirmwareSplit RelationKind = "firmware-line-split"
+	// RelationVendorError: AAGUIDs a vendor shipped on the wrong products, or reused across
+	// different products by mistake.
+	RelationVendorError RelationKind = "vendor-error"
+)
+
+// Valid reports whether k is one of the RelationKind constants.
+func (k RelationKind) Valid() bool {
+	switch k {
+	case RelationRebranded, RelationFirmwareSplit, RelationVendorError:
+		return true
+	}
+	return false
+}
+
+/*
+AAGUIDRelation is one group of the reviewed relations registry maintained in relations.json at the
+generator's repository root and stamped into this package by the generator:
+
+  - AAGUIDs: two or more lowercase AAGUIDs that belong together
+  - Kind: how they are related
+  - Note: a short reviewer note, e.g. the firmware versions or product names involved
+*/
+type AAGUIDRelation struct {
+	AAGUIDs []string     `json:"aaguids"`
+	Kind    RelationKind `json:"kind"`
+	Note    string       `json:"note,omitempty"`
+}
+
+// Relation is one AAGUID related to the one RelatedAAGUIDs was asked about.
+type Relation struct {
+	AAGUID string       `json:"aaguid"`
+	Kind   RelationKind `json:"kind"`
+	Note   string       `json:"note,omitempty"`
+}
+
+// Relations returns a copy of the curated relations registry.
+func Relations() []AAGUIDRelation {
+	out := make([]AAGUIDRelation, len(curatedRelations))
+	for i, r := range curatedRelations {
+		out[i] = r
+		out[i].AAGUIDs = slices.Clone(r.AAGUIDs)
+	}
+	return out
+}
+
+// RelatedAAGUIDs returns the AAGUIDs the relations registry relates to aaGuid. See
+// Provider.RelatedAAGUIDs.
+func RelatedAAGUIDs(aaGuid string) []Relation {
+	return Default().RelatedAAGUIDs(aaGuid)
+}
+
+/*
+RelatedAAGUIDs returns every AAGUID that shares a group of the curated relations registry with
+aaGuid, in registry order, each with the kind and note of its group. aaGuid may be any spelling the
+Provider's NormalizerChain accepts; the AAGUIDs returned are lowercase and need not be in p's
+dataset. It returns nil for AAGUIDs the registry does not mention.
+*/
+func (p *Provider) RelatedAAGUIDs(aaGuid string) []Relation {
+	id, _, err := p.normalizerChain().Parse(aaGuid)
+	if err != nil {
+		return nil
+	}
+	c := id.String()
+	var out []Relation
+	for _, r := range curatedRelations {
+		if !slices.Contains(r.AAGUIDs, c) {
+			continue
+		}
+		for _, other := range r.AAGUIDs {
+			if other != c {
+				out = append(out, Relation{AAGUID: other, Kind: r.Kind, Note: r.Note})
+			}
+		}
+	}
+	return out
+}
+
+/*
+relatedGroups maps every AAGUID of the relations registry to the sorted members of its merged
+group: groups sharing an AAGUID are merged transitively, so an AAGUID split across firmware lines
+and later rebranded ends up in one group with all of them.
+*/
+func relatedGroups(relations []AAGUIDRelation) map[string][]string {
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(x string) string {
+		if p, ok := parent[x]; ok && p != x {
+			root := find(p)
+			parent[x] = root
+			return root
+		}
+		parent[x] = x
+		return x
+	}
+	for _, r := range relations {
+		for _, id := range r.AAGUIDs[min(1, len(r.AAGUIDs)):] {
+			a, b := find(r.AAGUIDs[0]), find(id)
+			if a != b {
+				parent[max(a, b)] = min(a, b)
+			}
+		}
+	}
+	members := make(map[string][]string)
+	for id := range parent {
+		root := find(id)
+		members[root] = append(members[root], id)
+	}
+	groups := make(map[string][]string, len(parent))
+	for _, m := range members {
+		slices.Sort(m)
+		for _, id := range m {
+			groups[id] = m
+		}
+	}
+	return groups
+}
+
+// VendorQueryOption adjusts EntriesByVendor.
+type VendorQueryOption func(*vendorQuery)
+
+// vendorQuery is the configuration of one EntriesByVendor call.
+type vendorQuery struct {
+	mergeRelated bool
+}
+
+// MergeRelatedAAGUIDs makes EntriesByVendor also return the entries related to a matching entry
+// by the relations registry, merged transitively (see RelatedAAGUIDs).
+func MergeRelatedAAGUIDs() VendorQueryOption {
+	return func(q *vendorQuery) { q.mergeRelated = true }
+}
+
+// EntriesByVendor returns the embedded entries attributed to vendor. See Provider.EntriesByVendor.
+func EntriesByVendor(vendor string, opts ...VendorQueryOption) []Entry {
+	return Default().EntriesByVendor(vendor, opts...)
+}
+
+/*
+EntriesByVendor returns the entries whose Vendor name equals vendor, ignoring case, in ascending
+key order. With MergeRelatedAAGUIDs, every entry in the same relations group as a matching entry is
+included as well, whatever vendor it is attributed to, so a rebranded product or a firmware line
+with a new AAGUID counts as the same model family in analytics.
+*/
+func (p *Provider) EntriesByVendor(vendor string, opts ...VendorQueryOption) []Entry {
+	var q vendorQuery
+	for _, opt := range opts {
+		opt(&q)
+	}
+	s := p.current()
+	matched := make(map[string]bool)
+	for _, k := range s.keys {
+		if strings.EqualFold(p.Vendor(k).Name, vendor) {
+			matched[k] = true
+		}
+	}
+	if q.mergeRelated && len(curatedRelations) > 0 {
+		groups := relatedGroups(curatedRelations)
+		for _, k := range slices.Collect(maps.Keys(matched)) {
+			for _, id := range groups[strings.ToLower(k)] {
+				if key, ok := s.resolveKey(p.normalizerChain(), id); ok {
+					matched[key] = true
+				}
+			}
+		}
+	}
+	var out []Entry
+	for _, k := range s.keys {
+		if matched[k] {
+			out = append(out, s.entries[k])
+		}
+	}
+	return out
+}
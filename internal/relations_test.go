@@ -0,0 +1,113 @@
+package aaguids
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRelatedAAGUIDs(t *testing.T) {
+	const (
+		split, rebrand, unrelated = "00000000-0000-4000-8000-000000000001", "00000000-0000-4000-8000-000000000002",
+			"00000000-0000-4000-8000-000000000003"
+	)
+	old := curatedRelations
+	curatedRelations = []AAGUIDRelation{
+		{AAGUIDs: []string{testYubiKey, split}, Kind: RelationFirmwareSplit, Note: "firmware 5.1 and 5.2"},
+		{AAGUIDs: []string{split, rebrand}, Kind: RelationRebranded, Note: "sold as Acme Key"},
+	}
+	t.Cleanup(func() { curatedRelations = old })
+	p := testProvider(t)
+
+	for _, tt := range []struct {
+		aaguid string
+		want   []Relation
+	}{
+		{testYubiKey, []Relation{{split, RelationFirmwareSplit, "firmware 5.1 and 5.2"}}},
+		{"{" + strings.ToUpper(testYubiKey) + "}", []Relation{{split, RelationFirmwareSplit, "firmware 5.1 and 5.2"}}},
+		// In two groups: its relations in registry order, each with its own kind.
+		{split, []Relation{{testYubiKey, RelationFirmwareSplit, "firmware 5.1 and 5.2"}, {rebrand, RelationRebranded, "sold as Acme Key"}}},
+		{rebrand, []Relation{{split, RelationRebranded, "sold as Acme Key"}}},
+		{unrelated, nil},
+		{"not an aaguid", nil},
+	} {
+		if got := p.RelatedAAGUIDs(tt.aaguid); !slices.Equal(got, tt.want) {
+			t.Errorf("RelatedAAGUIDs(%q) = %v, want %v", tt.aaguid, got, tt.want)
+		}
+	}
+
+	// Relations is a copy.
+	r := Relations()
+	r[0].AAGUIDs[0] = unrelated
+	if curatedRelations[0].AAGUIDs[0] != testYubiKey {
+		t.Error("modifying the result of Relations changed the registry")
+	}
+}
+
+func TestRelatedGroupsMergeTransitively(t *testing.T) {
+	a, b, c, d, e := "00000000-0000-4000-8000-00000000000a", "00000000-0000-4000-8000-00000000000b",
+		"00000000-0000-4000-8000-00000000000c", "00000000-0000-4000-8000-00000000000d", "00000000-0000-4000-8000-00000000000e"
+	groups := relatedGroups([]AAGUIDRelation{
+		{AAGUIDs: []string{c, b}},
+		{AAGUIDs: []string{d, e}},
+		{AAGUIDs: []string{a, c}},
+	})
+	for id, want := range map[string][]string{a: {a, b, c}, b: {a, b, c}, c: {a, b, c}, d: {d, e}, e: {d, e}} {
+		if got := groups[id]; !slices.Equal(got, want) {
+			t.Errorf("group of %s = %v, want %v", id, got, want)
+		}
+	}
+	if len(groups) != 5 {
+		t.Errorf("%d AAGUIDs grouped, want 5", len(groups))
+	}
+}
+
+func TestEntriesByVendorMergeRelated(t *testing.T) {
+	const (
+		newFirmware = "00000000-0000-4000-8000-000000000001"
+		rebranded   = "00000000-0000-4000-8000-000000000002"
+		other       = "00000000-0000-4000-8000-000000000003"
+		unlisted    = "00000000-0000-4000-8000-000000000004"
+	)
+	old := curatedRelations
+	curatedRelations = []AAGUIDRelation{
+		{AAGUIDs: []string{testYubiKey, newFirmware}, Kind: RelationFirmwareSplit},
+		{AAGUIDs: []string{newFirmware, rebranded}, Kind: RelationRebranded},
+		// Related AAGUIDs need not be in the dataset.
+		{AAGUIDs: []string{other, unlisted}, Kind: RelationVendorError},
+	}
+	t.Cleanup(func() { curatedRelations = old })
+	entry := func(id, description string) Entry {
+		return Entry{AAGUID: id, MetadataStatement: MetadataStatement{Description: description}}
+	}
+	p := NewProvider(map[string]Entry{
+		strings.ToUpper(testYubiKey): entry(testYubiKey, "YubiKey 5 Series"),
+		newFirmware:                  entry(newFirmware, "Security Key by Yubico"),
+		rebranded:                    entry(rebranded, "FIDO2 Key by Acme Corp."),
+		other:                        entry(other, "Token2 PIN+"),
+	}, Dataset{})
+	keys := func(entries []Entry) []string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, strings.ToLower(e.AAGUID))
+		}
+		return out
+	}
+
+	for _, tt := range []struct {
+		vendor string
+		opts   []VendorQueryOption
+		want   []string
+	}{
+		{"yubico", nil, []string{newFirmware, testYubiKey}},
+		{"Yubico", []VendorQueryOption{MergeRelatedAAGUIDs()}, []string{newFirmware, rebranded, testYubiKey}},
+		{"Acme", nil, []string{rebranded}},
+		{"Acme", []VendorQueryOption{MergeRelatedAAGUIDs()}, []string{newFirmware, rebranded, testYubiKey}},
+		{"Token2", []VendorQueryOption{MergeRelatedAAGUIDs()}, []string{other}},
+		{"Nobody", []VendorQueryOption{MergeRelatedAAGUIDs()}, nil},
+	} {
+		if got := keys(p.EntriesByVendor(tt.vendor, tt.opts...)); !slices.Equal(got, tt.want) {
+			t.Errorf("EntriesByVendor(%q, merge %v) = %v, want %v", tt.vendor, len(tt.opts) > 0, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package aaguids
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"text/template"
+	"time"
+)
+
+// Count is one row of a Report tally.
+type Count struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+/*
+Report is a point-in-time summary of an authenticator ecosystem, built by BuildReport:
+
+  - StatusTotals: entries per latest status ("" counts entries without status reports)
+  - ProtocolTotals: entries per protocol family
+  - NewlyCertified / NewlyRevoked: entries that became certified / revoked since the previous dataset
+  - RemovedUpstream: entries of the previous dataset that are no longer published at all
+  - BiometricCertified: entries with at least one biometric certification of level 1 or above
+  - TopVendors: the vendors with the most entries, best first
+  - SilentSources: sources that contributed no entries this time but did to the previous dataset,
+    possibly an upstream outage, each with its previous entry count
+*/
+type Report struct {
+	GeneratedAt        time.Time      `json:"generatedAt"`
+	Dataset            Dataset        `json:"dataset"`
+	Previous           *Dataset       `json:"previous,omitempty"`
+	TotalEntries       int            `json:"totalEntries"`
+	StatusTotals       []Count        `json:"statusTotals"`
+	ProtocolTotals     []Count        `json:"protocolTotals"`
+	NewlyCertified     []EntrySummary `json:"newlyCertified"`
+	NewlyRevoked       []EntrySummary `json:"newlyRevoked"`
+	RemovedUpstream    []EntrySummary `json:"removedUpstream"`
+	BiometricCertified int            `json:"biometricCertified"`
+	TopVendors         []Count        `json:"topVendors"`
+	SilentSources      []Count        `json:"silentSources,omitempty"`
+}
+
+// reportTopVendors is the number of vendors listed in Report.TopVendors.
+const reportTopVendors = 10
+
+/*
+BuildReport collects a Report over the current snapshot of p. When previous is non-nil, entries are
+compared against it to find newly certified, newly revoked and removed models; otherwise those lists
+are empty.
+*/
+func BuildReport(p *Provider, previous *Provider, now time.Time) Report {
+	r := Report{GeneratedAt: now.UTC(), Dataset: p.DatasetInfo()}
+	var prior map[string]EntrySummary
+	var priorOrder []string
+	if previous != nil {
+		info := previous.DatasetInfo()
+		r.Previous = &info
+		prior = make(map[string]EntrySummary)
+		for e := range previous.Where(nil) {
+			s := e.Summary()
+			k := canonicalKey(s.AAGUID)
+			if _, dup := prior[k]; !dup {
+				priorOrder = append(priorOrder, k)
+			}
+			prior[k] = s
+		}
+	}
+	seen := make(map[string]bool)
+
+	statuses := make(map[string]int)
+	protocols := make(map[string]int)
+	vendors := make(map[string]int)
+	for e := range p.Where(nil) {
+		s := e.Summary()
+		r.TotalEntries++
+		statuses[string(s.Status)]++
+		protocols[s.ProtocolFamily]++
+		if e.HasBiometricCertification(BiometricCertLevel1) {
+			r.BiometricCertified++
+		}
+		if v := p.Vendor(e.Key()); v.Name != "" {
+			vendors[v.Name]++
+		}
+		if prior == nil {
+			continue
+		}
+		seen[canonicalKey(s.AAGUID)] = true
+		before, existed := prior[canonicalKey(s.AAGUID)]
+		if s.CertificationLevel != "" && s.Status != REVOKED && (!existed || before.CertificationLevel == "") {
+			r.NewlyCertified = append(r.NewlyCertified, s)
+		}
+		if s.Status == REVOKED && (!existed || before.Status != REVOKED) {
+			r.NewlyRevoked = append(r.NewlyRevoked, s)
+		}
+	}
+	for _, k := range priorOrder {
+		if !seen[k] {
+			r.RemovedUpstream = append(r.RemovedUpstream, prior[k])
+		}
+	}
+	r.StatusTotals = sortedCounts(statuses, 0)
+	r.ProtocolTotals = sortedCounts(protocols, 0)
+	r.TopVendors = sortedCounts(vendors, reportTopVendors)
+	if r.Previous != nil {
+		r.SilentSources = silentSources(r.Dataset.Sources, r.Previous.Sources)
+	}
+	return r
+}
+
+// silentSources lists the sources of cur with no entries whose namesake in prev had some, in the
+// order of cur.
+func silentSources(cur, prev []DatasetSource) []Count {
+	var out []Count
+	for _, src := range cur {
+		if src.EntryCount > 0 {
+			continue
+		}
+		for _, before := range prev {
+			if before.Name == src.Name && before.EntryCount > 0 {
+				out = append(out, Count{Key: src.Name, Count: before.EntryCount})
+				break
+			}
+		}
+	}
+	return out
+}
+
+// sortedCounts orders tallies by descending count, then key, keeping at most limit rows (0 for all).
+func sortedCounts(m map[string]int, limit int) []Count {
+	out := make([]Count, 0, len(m))
+	for k, n := range m {
+		out = append(out, Count{Key: k, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Key < out[j].Key
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// reportMarkdown renders a Report as Markdown.
+const reportMarkdown = `# Authenticator Metadata Report
+
+Generated {{.GeneratedAt.Format "2006-01-02"}} from MDS serial {{.Dataset.Serial}}{{with .Previous}} (compared with serial {{.Serial}}){{end}}.
+
+- Total entries: {{.TotalEntries}}
+- Biometric-certified entries: {{.BiometricCertified}}
+{{with .SilentSources}}
+## Sources that contributed nothing
+
+These sources contributed no entries this time; check for an upstream outage.
+
+| Source | Entries before |
+|---|---|
+{{range .}}| {{.Key}} | {{.Count}} |
+{{end}}{{end}}
+## Entries by status
+
+| Status | Entries |
+|---|---|
+{{range .StatusTotals}}| {{or .Key "(none)"}} | {{.Count}} |
+{{end}}
+## Entries by protocol
+
+| Protocol | Entries |
+|---|---|
+{{range .ProtocolTotals}}| {{or .Key "(unspecified)"}} | {{.Count}} |
+{{end}}
+## Top vendors
+
+| Vendor | Entries |
+|---|---|
+{{range .TopVendors}}| {{.Key}} | {{.Count}} |
+{{end}}
+## Newly certified
+{{range .NewlyCertified}}
+- {{.Description}} ({{.AAGUID}}) — {{.CertificationLevel}}{{else}}
+None.{{end}}
+
+## Newly revoked
+{{range .NewlyRevoked}}
+- {{.Description}} ({{.AAGUID}}){{with .StatusDate}} — {{.}}{{end}}{{else}}
+None.{{end}}
+
+## Removed upstream
+{{range .RemovedUpstream}}
+- {{.Description}} ({{.AAGUID}}){{with .Status}} — last status {{.}}{{end}}{{else}}
+None.{{end}}
+`
+
+// reportHTML renders a Report as a standalone HTML page.
+const reportHTML = `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Authenticator Metadata Report</title></head>
+<body>
+<h1>Authenticator Metadata Report</h1>
+<p>Generated {{.GeneratedAt.Format "2006-01-02"}} from MDS serial {{.Dataset.Serial}}{{with .Previous}} (compared with serial {{.Serial}}){{end}}.</p>
+<ul>
+<li>Total entries: {{.TotalEntries}}</li>
+<li>Biometric-certified entries: {{.BiometricCertified}}</li>
+</ul>
+{{with .SilentSources}}<h2>Sources that contributed nothing</h2>
+<p>These sources contributed no entries this time; check for an upstream outage.</p>
+<table><tr><th>Source</th><th>Entries before</th></tr>
+{{range .}}<tr><td>{{.Key}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}<h2>Entries by status</h2>
+<table><tr><th>Status</th><th>Entries</th></tr>
+{{range .StatusTotals}}<tr><td>{{or .Key "(none)"}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+<h2>Entries by protocol</h2>
+<table><tr><th>Protocol</th><th>Entries</th></tr>
+{{range .ProtocolTotals}}<tr><td>{{or .Key "(unspecified)"}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+<h2>Top vendors</h2>
+<table><tr><th>Vendor</th><th>Entries</th></tr>
+{{range .TopVendors}}<tr><td>{{.Key}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+<h2>Newly certified</h2>
+<ul>
+{{range .NewlyCertified}}<li>{{.Description}} ({{.AAGUID}}) — {{.CertificationLevel}}</li>
+{{else}}<li>None.</li>
+{{end}}</ul>
+<h2>Newly revoked</h2>
+<ul>
+{{range .NewlyRevoked}}<li>{{.Description}} ({{.AAGUID}}){{with .StatusDate}} — {{.}}{{end}}</li>
+{{else}}<li>None.</li>
+{{end}}</ul>
+<h2>Removed upstream</h2>
+<ul>
+{{range .RemovedUpstream}}<li>{{.Description}} ({{.AAGUID}}){{with .Status}} — last status {{.}}{{end}}</li>
+{{else}}<li>None.</li>
+{{end}}</ul>
+</body>
+</html>
+`
+
+var (
+	reportMarkdownTemplate = template.Must(template.New("report.md").Parse(reportMarkdown))
+	reportHTMLTemplate     = htmltemplate.Must(htmltemplate.New("report.html").Parse(reportHTML))
+)
+
+// Markdown writes the report as Markdown.
+func (r Report) Markdown(w io.Writer) error {
+	return reportMarkdownTemplate.Execute(w, r)
+}
+
+// HTML writes the report as a standalone HTML page. All dataset-derived text is escaped.
+func (r Report) HTML(w io.Writer) error {
+	return reportHTMLTemplate.Execute(w, r)
+}
@@ -0,0 +1,69 @@
+package aaguids
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// reportProviders returns the current and previous datasets of the report goldens: the mixed BLOB
+// fixture plus entries that were certified, revoked and removed between the two serials.
+func reportProviders(t *testing.T) (cur, prev *Provider) {
+	t.Helper()
+	entry := func(aaguid, description string, reports ...StatusReport) Entry {
+		return Entry{AAGUID: aaguid, StatusReports: reports, MetadataStatement: MetadataStatement{
+			AAGUID: aaguid, Description: description, ProtocolFamily: "fido2",
+		}}
+	}
+	const removed = "00000000-0000-4000-8000-000000000009"
+	certified := report(FIDO_CERTIFIED_L1, "2024-01-01")
+
+	current := readTestBLOB(t, "blob-mixed.json").EntriesByKey()
+	previous := readTestBLOB(t, "blob-mixed.json").EntriesByKey()
+	current[testGPM] = entry(testGPM, "Google Password Manager", certified, report(REVOKED, "2025-03-01"))
+	previous[testGPM] = entry(testGPM, "Google Password Manager", certified)
+	current[testWindowsHello] = entry(testWindowsHello, "Windows Hello <Hardware> Authenticator",
+		report(NOT_FIDO_CERTIFIED, "2023-01-01"), report(FIDO_CERTIFIED_L2, "2025-02-01"))
+	previous[testWindowsHello] = entry(testWindowsHello, "Windows Hello <Hardware> Authenticator", report(NOT_FIDO_CERTIFIED, "2023-01-01"))
+	bio := entry(testYubiKey57, "YubiKey Bio Series", certified)
+	bio.BiometricStatusReports = []BiometricStatusReport{bioReport(ModalityFingerprint, BiometricCertLevel1, "2024-06-01", "BIO-1")}
+	current[testYubiKey57] = bio
+	previous[removed] = entry(removed, "Discontinued Token", report(FIDO_CERTIFIED_L1, "2019-01-01"))
+
+	cur = NewProvider(current, Dataset{Serial: 42, NextUpdate: "2025-04-01", Sources: []DatasetSource{
+		{Name: "fido-mds3", EntryCount: len(current)},
+		{Name: "passkey-authenticator-aaguids", EntryCount: 0},
+	}})
+	prev = NewProvider(previous, Dataset{Serial: 41, NextUpdate: "2025-03-01", Sources: []DatasetSource{
+		{Name: "fido-mds3", EntryCount: len(previous)},
+		{Name: "passkey-authenticator-aaguids", EntryCount: 120},
+	}})
+	return cur, prev
+}
+
+func TestReportGoldens(t *testing.T) {
+	cur, prev := reportProviders(t)
+	now := time.Date(2025, 3, 15, 9, 30, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		name     string
+		previous *Provider
+	}{
+		{"report", prev},
+		{"report-no-previous", nil},
+	} {
+		r := BuildReport(cur, tt.previous, now)
+		for ext, render := range map[string]func(*bytes.Buffer) error{
+			"md":   func(b *bytes.Buffer) error { return r.Markdown(b) },
+			"html": func(b *bytes.Buffer) error { return r.HTML(b) },
+		} {
+			t.Run(tt.name+"."+ext, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := render(&buf); err != nil {
+					t.Fatal(err)
+				}
+				checkGolden(t, filepath.Join("testdata", tt.name+"."+ext), buf.Bytes())
+			})
+		}
+	}
+}
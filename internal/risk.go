@@ -0,0 +1,233 @@
+package aaguids
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+/*
+RiskWeights sets how much each component of RiskScore counts. Only the ratios matter; a zero
+weight leaves the component out of the score (it is still reported in the breakdown).
+*/
+type RiskWeights struct {
+	Status        float64 `json:"status"`
+	Certification float64 `json:"certification"`
+	Staleness     float64 `json:"staleness"`
+	Update        float64 `json:"update"`
+	Roots         float64 `json:"roots"`
+}
+
+// DefaultRiskWeights are the weights RiskScore uses unless WithRiskWeights is given.
+var DefaultRiskWeights = RiskWeights{Status: 40, Certification: 20, Staleness: 10, Update: 20, Roots: 10}
+
+// DefaultStalenessHorizon is the age of the last status change at which the staleness component
+// of RiskScore reaches its maximum, unless WithStalenessHorizon is given.
+const DefaultStalenessHorizon = 5 * 365 * 24 * time.Hour
+
+// RiskOption adjusts RiskScore.
+type RiskOption func(*riskConfig)
+
+// riskConfig is the configuration of one RiskScore call.
+type riskConfig struct {
+	weights RiskWeights
+	horizon time.Duration
+	now     time.Time
+	version *uint64
+}
+
+// WithRiskWeights replaces DefaultRiskWeights.
+func WithRiskWeights(w RiskWeights) RiskOption {
+	return func(c *riskConfig) { c.weights = w }
+}
+
+// WithStalenessHorizon replaces DefaultStalenessHorizon.
+func WithStalenessHorizon(d time.Duration) RiskOption {
+	return func(c *riskConfig) { c.horizon = d }
+}
+
+// RiskAt scores the entry as of now instead of time.Now, which makes scores reproducible.
+func RiskAt(now time.Time) RiskOption {
+	return func(c *riskConfig) { c.now = now }
+}
+
+// RiskForVersion scores the entry for an authenticator running firmware version v (its
+// authenticatorVersion), which decides whether an UPDATE_AVAILABLE report is still unresolved.
+func RiskForVersion(v uint64) RiskOption {
+	return func(c *riskConfig) { c.version = &v }
+}
+
+/*
+Score is the result of RiskScore:
+
+  - Value: the overall risk, from 0 (lowest) to 100 (highest)
+  - Components: the breakdown, one per RiskWeights field in declaration order, so a UI can show
+    what drove the score
+*/
+type Score struct {
+	Value      int              `json:"value"`
+	Components []ScoreComponent `json:"components"`
+}
+
+/*
+ScoreComponent is one signal of a Score:
+
+  - Name: "status", "certification", "staleness", "update" or "roots"
+  - Risk: the component's own risk, from 0 to 1
+  - Weight: its RiskWeights value
+  - Points: its share of Score.Value before rounding, 100 × Weight × Risk / (sum of weights)
+  - Detail: a short human-readable explanation of Risk
+*/
+type ScoreComponent struct {
+	Name   string  `json:"name"`
+	Risk   float64 `json:"risk"`
+	Weight float64 `json:"weight"`
+	Points float64 `json:"points"`
+	Detail string  `json:"detail"`
+}
+
+/*
+RiskScore condenses the entry's metadata into one 0–100 risk indicator for display, e.g. in an
+admin console. It is not a trust decision; use Policy for allow/deny.
+
+The score is the weighted mean of five component risks, each from 0 to 1, scaled to 100 and
+rounded to the nearest integer:
+
+  - status: the latest status in timeline order. REVOKED and the key compromise statuses score 1,
+    USER_VERIFICATION_BYPASS 0.9, NOT_FIDO_CERTIFIED and entries without reports 0.5,
+    UPDATE_AVAILABLE and SELF_ASSERTION_SUBMITTED 0.3, and FIDO_CERTIFIED* 0
+  - certification: 1 − rank/7 for the most recent FIDO_CERTIFIED* report, where FIDO_CERTIFIED
+    ranks 1 and FIDO_CERTIFIED_L3plus ranks 7; 1 without any certification
+  - staleness: the age of timeOfLastStatusChange (or of the latest report) relative to the
+    staleness horizon, capped at 1; 1 when no date is known
+  - update: 1 if an UPDATE_AVAILABLE report is unresolved, otherwise 0. With RiskForVersion, a
+    report is unresolved if it names a later authenticatorVersion than the firmware; without it,
+    if UPDATE_AVAILABLE is the latest status
+  - roots: the fraction of attestation root certificates that have expired; 1 if they do not
+    parse, 0 for entries without roots
+
+The same entry, options and time always give the same Score.
+*/
+func RiskScore(e Entry, opts ...RiskOption) Score {
+	cfg := riskConfig{weights: DefaultRiskWeights, horizon: DefaultStalenessHorizon}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.now.IsZero() {
+		cfg.now = time.Now()
+	}
+
+	w := cfg.weights
+	components := []ScoreComponent{
+		statusRisk(e, w.Status),
+		certificationRisk(e, w.Certification),
+		stalenessRisk(e, w.Staleness, cfg),
+		updateRisk(e, w.Update, cfg),
+		rootsRisk(e, w.Roots, cfg.now),
+	}
+	total := w.Status + w.Certification + w.Staleness + w.Update + w.Roots
+	var sum float64
+	for i := range components {
+		if total > 0 {
+			components[i].Points = 100 * components[i].Weight * components[i].Risk / total
+		}
+		sum += components[i].Points
+	}
+	return Score{Value: int(math.Round(sum)), Components: components}
+}
+
+func statusRisk(e Entry, weight float64) ScoreComponent {
+	c := ScoreComponent{Name: "status", Weight: weight}
+	r, ok := e.LatestStatus()
+	if !ok {
+		c.Risk, c.Detail = 0.5, "no status reports"
+		return c
+	}
+	switch r.Status {
+	case REVOKED, ATTESTATION_KEY_COMPROMISE, USER_KEY_REMOTE_COMPROMISE, USER_KEY_PHYSICAL_COMPROMISE:
+		c.Risk = 1
+	case USER_VERIFICATION_BYPASS:
+		c.Risk = 0.9
+	case UPDATE_AVAILABLE, SELF_ASSERTION_SUBMITTED:
+		c.Risk = 0.3
+	default:
+		if !isCertificationLevel(r.Status) {
+			c.Risk = 0.5 // NOT_FIDO_CERTIFIED and statuses this package does not know
+		}
+	}
+	c.Detail = "latest status " + string(r.Status)
+	return c
+}
+
+func certificationRisk(e Entry, weight float64) ScoreComponent {
+	c := ScoreComponent{Name: "certification", Weight: weight}
+	r, ok := e.latestCertification()
+	if !ok {
+		c.Risk, c.Detail = 1, "never certified"
+		return c
+	}
+	c.Risk = 1 - float64(certificationRank(r.Status))/7
+	c.Detail = "certified " + string(r.Status)
+	return c
+}
+
+func stalenessRisk(e Entry, weight float64, cfg riskConfig) ScoreComponent {
+	c := ScoreComponent{Name: "staleness", Weight: weight}
+	changed, ok := parseISODate(e.TimeOfLastStatusChange)
+	if !ok {
+		if r, latest := e.LatestStatus(); latest {
+			changed, ok = r.EffectiveTime()
+		}
+	}
+	if !ok {
+		c.Risk, c.Detail = 1, "date of last status change unknown"
+		return c
+	}
+	age := max(cfg.now.Sub(changed), 0)
+	c.Risk = 1
+	if cfg.horizon > 0 {
+		c.Risk = min(float64(age)/float64(cfg.horizon), 1)
+	}
+	c.Detail = fmt.Sprintf("last status change %s, %d days ago", changed.Format(time.DateOnly), int(age.Hours()/24))
+	return c
+}
+
+func updateRisk(e Entry, weight float64, cfg riskConfig) ScoreComponent {
+	c := ScoreComponent{Name: "update", Weight: weight, Detail: "no unresolved update"}
+	if cfg.version == nil {
+		if e.CurrentStatusIs(UPDATE_AVAILABLE) {
+			c.Risk, c.Detail = 1, "latest status is UPDATE_AVAILABLE"
+		}
+		return c
+	}
+	for _, r := range e.StatusReports {
+		if r.Status == UPDATE_AVAILABLE && r.AuthenticatorVersion != nil && *r.AuthenticatorVersion > *cfg.version {
+			c.Risk = 1
+			c.Detail = fmt.Sprintf("update to authenticatorVersion %d available for %d", *r.AuthenticatorVersion, *cfg.version)
+			break
+		}
+	}
+	return c
+}
+
+func rootsRisk(e Entry, weight float64, now time.Time) ScoreComponent {
+	c := ScoreComponent{Name: "roots", Weight: weight}
+	roots, err := e.MetadataStatement.parsedRoots()
+	if err != nil {
+		c.Risk, c.Detail = 1, "attestation roots do not parse"
+		return c
+	}
+	if len(roots) == 0 {
+		c.Detail = "no attestation roots"
+		return c
+	}
+	expired := 0
+	for _, r := range roots {
+		if now.After(r.NotAfter) {
+			expired++
+		}
+	}
+	c.Risk = float64(expired) / float64(len(roots))
+	c.Detail = fmt.Sprintf("%d of %d attestation roots expired", expired, len(roots))
+	return c
+}
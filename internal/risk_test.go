@@ -0,0 +1,92 @@
+package aaguids
+
+import (
+	"encoding/base64"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRiskScorePinned(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	root := base64.StdEncoding.EncodeToString(readTestCertificate(t, "u2f-root.pem").Raw) // valid until 2050
+	certifiedL2 := Entry{
+		TimeOfLastStatusChange: "2024-01-01",
+		StatusReports:          []StatusReport{report(FIDO_CERTIFIED_L2, "2024-01-01")},
+		MetadataStatement:      MetadataStatement{AttestationRootCertificates: []string{root}},
+	}
+	revoked := Entry{StatusReports: []StatusReport{report(FIDO_CERTIFIED_L1, "2020-01-01"), report(REVOKED, "2024-07-01")}}
+	update := Entry{
+		TimeOfLastStatusChange: "2022-01-01",
+		StatusReports: []StatusReport{
+			report(FIDO_CERTIFIED_L1, "2020-01-01"),
+			versioned(report(UPDATE_AVAILABLE, "2022-01-01"), 5),
+		},
+		MetadataStatement: MetadataStatement{AttestationRootCertificates: []string{"not a certificate"}},
+	}
+	tests := []struct {
+		name  string
+		entry Entry
+		opts  []RiskOption
+		risks [5]float64 // status, certification, staleness, update, roots
+		value int
+	}{
+		{
+			// 20 × (1 − 4/7) + 10 × 366/1825 = 8.57 + 2.01
+			name: "certified L2, changed a year ago", entry: certifiedL2,
+			risks: [5]float64{0, 3.0 / 7, 366.0 / 1825, 0, 0}, value: 11,
+		},
+		{
+			// 40 + 20 × (1 − 2/7) + 10 × 184/1825, dated by the latest report
+			name: "revoked", entry: revoked,
+			risks: [5]float64{1, 5.0 / 7, 184.0 / 1825, 0, 0}, value: 55,
+		},
+		{
+			// 40 × 0.5 + 20 + 10: nothing is known
+			name: "no metadata", entry: Entry{},
+			risks: [5]float64{0.5, 1, 1, 0, 0}, value: 50,
+		},
+		{
+			// 40 × 0.3 + 20 × 5/7 + 10 × 1096/1825 + 20 + 10
+			name: "update pending for the firmware", entry: update, opts: []RiskOption{RiskForVersion(3)},
+			risks: [5]float64{0.3, 5.0 / 7, 1096.0 / 1825, 1, 1}, value: 62,
+		},
+		{
+			name: "firmware already updated", entry: update, opts: []RiskOption{RiskForVersion(5)},
+			risks: [5]float64{0.3, 5.0 / 7, 1096.0 / 1825, 0, 1}, value: 42,
+		},
+		{
+			// Without a version, the latest status decides.
+			name: "update pending, version unknown", entry: update,
+			risks: [5]float64{0.3, 5.0 / 7, 1096.0 / 1825, 1, 1}, value: 62,
+		},
+		{
+			name: "status only", entry: revoked, opts: []RiskOption{WithRiskWeights(RiskWeights{Status: 1})},
+			risks: [5]float64{1, 5.0 / 7, 184.0 / 1825, 0, 0}, value: 100,
+		},
+		{
+			// 20 × 3/7 + 10 × 366/730
+			name: "shorter staleness horizon", entry: certifiedL2, opts: []RiskOption{WithStalenessHorizon(2 * 365 * 24 * time.Hour)},
+			risks: [5]float64{0, 3.0 / 7, 366.0 / 730, 0, 0}, value: 14,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := RiskScore(tt.entry, append([]RiskOption{RiskAt(now)}, tt.opts...)...)
+			if s.Value != tt.value {
+				t.Errorf("Value = %d, want %d; components %+v", s.Value, tt.value, s.Components)
+			}
+			if len(s.Components) != len(tt.risks) {
+				t.Fatalf("%d components, want %d", len(s.Components), len(tt.risks))
+			}
+			for i, c := range s.Components {
+				if math.Abs(c.Risk-tt.risks[i]) > 1e-9 {
+					t.Errorf("%s risk = %v, want %v (%s)", c.Name, c.Risk, tt.risks[i], c.Detail)
+				}
+			}
+			if again := RiskScore(tt.entry, append([]RiskOption{RiskAt(now)}, tt.opts...)...); again.Value != s.Value {
+				t.Errorf("second call scored %d, want the same %d", again.Value, s.Value)
+			}
+		})
+	}
+}
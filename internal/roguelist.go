@@ -0,0 +1,266 @@
+package aaguids
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRogueListUnavailable is returned by IsRogue when the entry's rogue list has not been
+	// fetched successfully yet; the wrapped error is the last fetch failure, if any.
+	ErrRogueListUnavailable = errors.New("aaguids: rogue list unavailable")
+	// ErrRogueListStale is returned by IsRogue when the cached rogue list is older than the
+	// staleness bound.
+	ErrRogueListStale = errors.New("aaguids: rogue list is stale")
+	// ErrRogueListHash is wrapped by fetch failures when a rogue list does not match rogueListHash.
+	ErrRogueListHash = errors.New("aaguids: rogue list does not match rogueListHash")
+)
+
+// RogueListEntry is one element of a rogue list, FIDO Metadata Service v3.0 § 3.1.8 “RogueListEntry
+// dictionary”: the revealed secret key of an individual rogue authenticator.
+type RogueListEntry struct {
+	SK   string `json:"sk"`
+	Date string `json:"date"`
+}
+
+// RogueListOption configures a RogueListManager.
+type RogueListOption func(*RogueListManager)
+
+// WithRogueListClient sets the HTTP client used to fetch rogue lists (default http.DefaultClient).
+// When the Provider has a FetchCoordinator, requests go through it with this client's settings.
+func WithRogueListClient(c *http.Client) RogueListOption {
+	return func(m *RogueListManager) { m.client = c }
+}
+
+// WithRogueListFilter limits prefetching to entries matching f (default: every entry with a
+// rogueListURL). IsRogue reports ErrRogueListUnavailable for other entries.
+func WithRogueListFilter(f Filter) RogueListOption {
+	return func(m *RogueListManager) { m.filter = f }
+}
+
+// WithRogueListInterval sets how often cached rogue lists are refreshed (default one hour).
+func WithRogueListInterval(d time.Duration) RogueListOption {
+	return func(m *RogueListManager) { m.interval = d }
+}
+
+// WithRogueListMaxStaleness sets how old a cached list may be before IsRogue stops answering from
+// it (default 24 hours).
+func WithRogueListMaxStaleness(d time.Duration) RogueListOption {
+	return func(m *RogueListManager) { m.maxStale = d }
+}
+
+// WithRogueListConcurrency bounds the number of rogue lists fetched at once (default 4).
+func WithRogueListConcurrency(n int) RogueListOption {
+	return func(m *RogueListManager) { m.concurrency = max(n, 1) }
+}
+
+/*
+RogueListManager prefetches and caches the rogue lists referenced by a Provider's entries, so that
+IsRogue can answer at decision time without a network round trip. It is optional: nothing fetches
+rogue lists unless a manager is created and Run or Refresh is called.
+
+Lists are fetched with bounded concurrency, verified against the entry's rogueListHash, and
+refreshed every interval as well as after every Provider.Update. A failed fetch keeps the
+previously cached list; IsRogue answers from it until it is older than the staleness bound.
+*/
+type RogueListManager struct {
+	p           *Provider
+	client      *http.Client
+	filter      Filter
+	interval    time.Duration
+	maxStale    time.Duration
+	concurrency int
+	now         func() time.Time
+
+	mu    sync.RWMutex
+	lists map[string]*rogueList // key → cached list
+}
+
+// rogueList is the cached state of one entry's rogue list.
+type rogueList struct {
+	url, hash string
+	sks       map[string]bool // normalized secret keys
+	fetched   time.Time       // zero until the first successful fetch
+	err       error           // last fetch failure, nil after a success
+}
+
+// NewRogueListManager returns a manager for the rogue lists of p's entries. Call Run (or Refresh)
+// to populate it.
+func NewRogueListManager(p *Provider, opts ...RogueListOption) *RogueListManager {
+	m := &RogueListManager{
+		p:           p,
+		client:      http.DefaultClient,
+		interval:    time.Hour,
+		maxStale:    24 * time.Hour,
+		concurrency: 4,
+		now:         time.Now,
+		lists:       make(map[string]*rogueList),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Run refreshes the cache immediately, then every interval and after every Update of the
+// Provider, until ctx is done.
+func (m *RogueListManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		changed := m.p.changed()
+		m.Refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-changed:
+		}
+	}
+}
+
+/*
+Refresh fetches the rogue lists of every selected entry in the current snapshot, at most
+concurrency at a time, and drops cached lists of entries that are no longer selected. It returns
+the fetch failures joined with errors.Join; they are also reported by IsRogue.
+*/
+func (m *RogueListManager) Refresh(ctx context.Context) error {
+	s := m.p.current()
+	want := make(map[string]Entry)
+	for _, k := range s.keys {
+		e := s.entries[k]
+		if e.RogueListURL != "" && (m.filter == nil || m.filter(e)) {
+			want[k] = e
+		}
+	}
+
+	m.mu.Lock()
+	for k, l := range m.lists {
+		if e, ok := want[k]; !ok || e.RogueListURL != l.url || e.RogueListHash != l.hash {
+			delete(m.lists, k)
+		}
+	}
+	m.mu.Unlock()
+
+	var (
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, m.concurrency)
+		errs = make([]error, 0)
+		emu  sync.Mutex
+	)
+	for k, e := range want {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			sks, err := m.fetch(ctx, e.RogueListURL, e.RogueListHash)
+			<-sem
+
+			m.mu.Lock()
+			l, ok := m.lists[k]
+			if !ok {
+				l = &rogueList{url: e.RogueListURL, hash: e.RogueListHash}
+				m.lists[k] = l
+			}
+			if err != nil {
+				l.err = boundedErrorf("%s: %w", canonicalKey(k), err)
+			} else {
+				l.sks, l.fetched, l.err = sks, m.now(), nil
+			}
+			m.mu.Unlock()
+
+			if err != nil {
+				emu.Lock()
+				errs = append(errs, boundedErrorf("%s: %w", canonicalKey(k), err))
+				emu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// fetch downloads and verifies one rogue list.
+func (m *RogueListManager) fetch(ctx context.Context, url, hash string) (map[string]bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.p.fetchClient(m.client).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if hash != "" {
+		want, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(hash, "="))
+		sum := sha256.Sum256(raw)
+		if err != nil || string(want) != string(sum[:]) {
+			return nil, ErrRogueListHash
+		}
+	}
+	var entries []RogueListEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("decoding rogue list: %w", err)
+	}
+	sks := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		sks[normalizeSK(e.SK)] = true
+	}
+	return sks, nil
+}
+
+/*
+IsRogue reports whether identifier, the base64url secret key of an individual authenticator, is on
+the cached rogue list of the entry identified by aaGuid. It never performs network requests.
+
+Entries without a rogueListURL are never rogue. Otherwise, when the answer cannot be known, IsRogue
+returns false together with ErrRogueListUnavailable (the list was never fetched, or the entry is
+not selected by the filter) or ErrRogueListStale (the last successful fetch is older than the
+staleness bound); the last fetch failure, if any, is wrapped as well.
+*/
+func (m *RogueListManager) IsRogue(aaGuid, identifier string) (bool, error) {
+	e, ok := m.p.GetEntry(aaGuid)
+	if !ok || e.RogueListURL == "" {
+		return false, nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	l, ok := m.lists[aaGuid]
+	switch {
+	case !ok:
+		return false, ErrRogueListUnavailable
+	case l.fetched.IsZero():
+		return false, boundedErrorf("%w: %w", ErrRogueListUnavailable, l.err)
+	case m.now().Sub(l.fetched) > m.maxStale:
+		if l.err != nil {
+			return false, boundedErrorf("%w: %w", ErrRogueListStale, l.err)
+		}
+		return false, ErrRogueListStale
+	}
+	return l.sks[normalizeSK(identifier)], nil
+}
+
+// normalizeSK strips base64 padding so padded and unpadded forms of a key compare equal.
+func normalizeSK(sk string) string {
+	return strings.TrimRight(sk, "=")
+}
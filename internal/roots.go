@@ -0,0 +1,143 @@
+package aaguids
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNoRootMatched is returned by VerifyAttestationChain when no attestation root of the entry
+	// issued the chain.
+	ErrNoRootMatched = errors.New("aaguids: attestation chain does not lead to any metadata root")
+	// ErrRootExpired is returned by VerifyAttestationChain when the chain leads to an attestation root
+	// of the entry that has expired.
+	ErrRootExpired = errors.New("aaguids: attestation root certificate has expired")
+)
+
+// parsedRoots decodes the statement's attestationRootCertificates (base64 DER).
+func (m MetadataStatement) parsedRoots() ([]*x509.Certificate, error) {
+	roots := make([]*x509.Certificate, 0, len(m.AttestationRootCertificates))
+	for i, c := range m.AttestationRootCertificates {
+		der, err := base64.StdEncoding.DecodeString(c)
+		if err != nil {
+			return nil, fmt.Errorf("decode attestationRootCertificates[%d]: %w", i, err)
+		}
+		root, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("parse attestationRootCertificates[%d]: %w", i, err)
+		}
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+/*
+VerifyAttestationChain verifies an attestation certificate chain (leaf first, then intermediates)
+against the attestation roots of e at time now. Failures caused by the trust anchor are reported
+distinctly: ErrRootExpired when the chain leads to one of the entry's roots that has expired, and
+ErrNoRootMatched when none of the roots issued the chain. Other failures (an expired leaf, a bad
+signature) are returned as reported by crypto/x509.
+*/
+func VerifyAttestationChain(e Entry, chain []*x509.Certificate, now time.Time) error {
+	if len(chain) == 0 {
+		return entryErrorf(e, "empty attestation chain")
+	}
+	roots, err := e.MetadataStatement.parsedRoots()
+	if err != nil {
+		return entryErrorf(e, "%w", err)
+	}
+	pool := x509.NewCertPool()
+	for _, r := range roots {
+		pool.AddCert(r)
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, verifyErr := chain[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if verifyErr == nil {
+		return nil
+	}
+
+	top := chain[len(chain)-1]
+	matched := false
+	for _, r := range roots {
+		if top.Equal(r) || top.CheckSignatureFrom(r) == nil {
+			matched = true
+			if now.After(r.NotAfter) {
+				return entryErrorf(e, "%w: %s expired %s", ErrRootExpired, r.Subject, r.NotAfter.Format(time.DateOnly))
+			}
+		}
+	}
+	if !matched {
+		return entryErrorf(e, "%w: chain issued by %s", ErrNoRootMatched, top.Issuer)
+	}
+	return entryErrorf(e, "verifying attestation chain: %w", verifyErr)
+}
+
+/*
+RootExpiry reports one attestation root that has expired or will expire soon.
+
+  - AAGUID: the entry's dataset key (see Entry.Key)
+  - Subject / NotAfter: the root certificate concerned
+  - Expired: the root is already past NotAfter
+  - AllExpired: every root of the entry is expired, so no attestation chain can verify (a total
+    outage for that model rather than a partial one)
+*/
+type RootExpiry struct {
+	Entry      Entry     `json:"-"`
+	AAGUID     string    `json:"aaguid"`
+	Subject    string    `json:"subject"`
+	NotAfter   time.Time `json:"notAfter"`
+	Expired    bool      `json:"expired"`
+	AllExpired bool      `json:"allExpired"`
+}
+
+// EntriesWithExpiringRoots lists embedded attestation roots expiring within the given window. See
+// Provider.EntriesWithExpiringRoots.
+func EntriesWithExpiringRoots(within time.Duration, now time.Time) []RootExpiry {
+	return Default().EntriesWithExpiringRoots(within, now)
+}
+
+/*
+EntriesWithExpiringRoots returns one RootExpiry for every attestation root that is expired at now
+or expires before now+within, in ascending AAGUID order. Roots that cannot be parsed are skipped.
+*/
+func (p *Provider) EntriesWithExpiringRoots(within time.Duration, now time.Time) []RootExpiry {
+	deadline := now.Add(within)
+	var out []RootExpiry
+	for e := range p.Where(nil) {
+		roots, err := e.MetadataStatement.parsedRoots()
+		if err != nil || len(roots) == 0 {
+			continue
+		}
+		allExpired := true
+		for _, r := range roots {
+			if !now.After(r.NotAfter) {
+				allExpired = false
+			}
+		}
+		for _, r := range roots {
+			if r.NotAfter.After(deadline) {
+				continue
+			}
+			out = append(out, RootExpiry{
+				Entry:      e,
+				AAGUID:     e.Key(),
+				Subject:    r.Subject.String(),
+				NotAfter:   r.NotAfter,
+				Expired:    now.After(r.NotAfter),
+				AllExpired: allExpired,
+			})
+		}
+	}
+	return out
+}
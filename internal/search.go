@@ -0,0 +1,173 @@
+package aaguids
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ScoredEntry is one FuzzySearchDescriptions result. Score is normalized to 0..1, 1 being a
+// match of every query trigram in a text of the same length.
+type ScoredEntry struct {
+	Key   string  `json:"key"`
+	Entry Entry   `json:"entry"`
+	Score float64 `json:"score"`
+}
+
+// fuzzyMinScore is the lowest score FuzzySearchDescriptions reports.
+const fuzzyMinScore = 0.3
+
+// trigramSet is the set of padded word trigrams of a text.
+type trigramSet map[string]struct{}
+
+/*
+trigrams splits s into lowercase words of letters and digits and returns the trigrams of each word
+padded with two leading blanks and one trailing blank, so "key" yields "  k", " ke", "key", "ey ".
+Padding weights word starts, which are rarely misspelled.
+*/
+func trigrams(s string) trigramSet {
+	set := make(trigramSet)
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		r := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			set[string(r[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// searchIndex precomputes the trigrams of every entry's description together with its curated
+// vendor name, if any.
+func searchIndex(entries map[string]Entry, keys []string) map[string]trigramSet {
+	idx := make(map[string]trigramSet, len(keys))
+	for _, k := range keys {
+		text := entries[k].DisplayName()
+		if v, ok := curatedVendors.lookup(strings.ToLower(k)); ok {
+			text = v + " " + text
+		}
+		idx[k] = trigrams(text)
+	}
+	return idx
+}
+
+// FuzzySearchDescriptions searches the embedded dataset; see Provider.FuzzySearchDescriptions.
+func FuzzySearchDescriptions(query string, maxResults int) []ScoredEntry {
+	return Default().FuzzySearchDescriptions(query, maxResults)
+}
+
+/*
+FuzzySearchDescriptions ranks entries by trigram similarity between query and their description
+and curated vendor name, tolerating typos such as "yubiky" or "fietian". Results are sorted
+best-first, ties in ascending key order, and limited to maxResults (0 for no limit); entries scoring
+below 0.3 are omitted.
+
+The score is 0.8 × the share of query trigrams found in the entry plus 0.2 × the Jaccard similarity
+of both sets, so entries containing the whole query rank first and shorter descriptions win ties.
+Entry trigrams are computed when the snapshot is loaded; a query only computes its own.
+*/
+func (p *Provider) FuzzySearchDescriptions(query string, maxResults int) []ScoredEntry {
+	q := trigrams(query)
+	if len(q) == 0 {
+		return nil
+	}
+	s := p.current()
+	var out []ScoredEntry
+	for _, k := range s.keys {
+		t := s.trigrams[k]
+		common := 0
+		for g := range q {
+			if _, ok := t[g]; ok {
+				common++
+			}
+		}
+		if common == 0 {
+			continue
+		}
+		jaccard := float64(common) / float64(len(q)+len(t)-common)
+		score := 0.8*float64(common)/float64(len(q)) + 0.2*jaccard
+		if score >= fuzzyMinScore {
+			out = append(out, ScoredEntry{Key: canonicalKey(k), Entry: s.entries[k], Score: score})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if maxResults > 0 && len(out) > maxResults {
+		out = out[:maxResults]
+	}
+	return out
+}
+
+// SearchByDescription searches the embedded dataset; see Provider.SearchByDescription.
+func SearchByDescription(query string) []Entry {
+	return Default().SearchByDescription(query)
+}
+
+/*
+SearchByDescription returns the entries whose description or any alternativeDescriptions value
+contains query, ignoring case with Unicode simple case folding, so "yubikey 5c nfc", "ЯНДЕКС" or
+CJK queries match as typed. The community display name is searched too, so entries known only from
+the community list can be found. Results are sorted by DisplayName (see ListEntriesSorted), then by
+key. An empty or blank query returns nil.
+*/
+func (p *Provider) SearchByDescription(query string) []Entry {
+	q := foldCase(strings.TrimSpace(query))
+	if q == "" {
+		return nil
+	}
+	type row struct {
+		e    Entry
+		key  string
+		name collationKey
+	}
+	s := p.current()
+	var rows []row
+	for _, k := range s.keys {
+		e := s.entries[k]
+		if !descriptionContains(e, q) {
+			continue
+		}
+		rows = append(rows, row{e: e, key: k, name: defaultCollator.key(e.DisplayName())})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if n := rows[i].name.compare(rows[j].name); n != 0 {
+			return n < 0
+		}
+		return rows[i].key < rows[j].key
+	})
+	out := make([]Entry, len(rows))
+	for i, r := range rows {
+		out[i] = r.e
+	}
+	return out
+}
+
+// descriptionContains reports whether the description, display name or an alternative description
+// of e contains folded, a foldCase result.
+func descriptionContains(e Entry, folded string) bool {
+	if strings.Contains(foldCase(e.MetadataStatement.Description), folded) ||
+		strings.Contains(foldCase(e.DisplayName()), folded) {
+		return true
+	}
+	for _, d := range e.MetadataStatement.AlternativeDescriptions {
+		if strings.Contains(foldCase(d), folded) {
+			return true
+		}
+	}
+	return false
+}
+
+// foldCase maps every rune of s to the smallest rune of its Unicode case folding orbit, so strings
+// differing only in case, including "K" (Kelvin sign) and "ſ", map to the same string.
+func foldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		return min
+	}, s)
+}
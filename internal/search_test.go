@@ -0,0 +1,106 @@
+package aaguids
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// searchCorpus returns a provider over descriptions of the largest vendors' models, keyed by
+// synthetic AAGUIDs in list order.
+func searchCorpus(t *testing.T) (*Provider, map[string]string) {
+	t.Helper()
+	descriptions := []string{
+		"YubiKey 5 Series",
+		"YubiKey 5 Series with NFC",
+		"YubiKey Bio Series - FIDO Edition",
+		"Security Key by Yubico",
+		"Feitian ePass FIDO2 Authenticator",
+		"Feitian BioPass FIDO2 Authenticator",
+		"Windows Hello Hardware Authenticator",
+		"Windows Hello Software Authenticator",
+		"Google Password Manager",
+		"Google Titan Security Key v2",
+		"iCloud Keychain",
+		"Thales IDPrime MD 3940 FIDO",
+		"HID Crescendo Key V3",
+		"Samsung Pass",
+		"1Password",
+		"Bitwarden",
+		"Solo 2 Security Key by SoloKeys",
+		"Token2 FIDO2 Security Key",
+	}
+	var entries []Entry
+	byDescription := make(map[string]string, len(descriptions))
+	for i, d := range descriptions {
+		id := fmt.Sprintf("00000000-0000-4000-8000-%012d", i+1)
+		entries = append(entries, Entry{AAGUID: id, MetadataStatement: MetadataStatement{Description: d}})
+		byDescription[d] = id
+	}
+	return testProvider(t, entries...), byDescription
+}
+
+func TestFuzzySearchMisspellings(t *testing.T) {
+	p, ids := searchCorpus(t)
+	for _, tt := range []struct {
+		query, want string
+	}{
+		{"yubiky", "YubiKey 5 Series"},
+		{"yubikey 5 nfc", "YubiKey 5 Series with NFC"},
+		{"yubikee bio", "YubiKey Bio Series - FIDO Edition"},
+		{"fietian epass", "Feitian ePass FIDO2 Authenticator"},
+		{"feitain biopass", "Feitian BioPass FIDO2 Authenticator"},
+		{"windos hello hardware", "Windows Hello Hardware Authenticator"},
+		{"gogle password manger", "Google Password Manager"},
+		{"googel titan", "Google Titan Security Key v2"},
+		{"icloud keychian", "iCloud Keychain"},
+		{"tales idprime", "Thales IDPrime MD 3940 FIDO"},
+		{"crescndo", "HID Crescendo Key V3"},
+		{"samsng pass", "Samsung Pass"},
+		{"1pasword", "1Password"},
+		{"bitwardn", "Bitwarden"},
+		{"solokeys", "Solo 2 Security Key by SoloKeys"},
+	} {
+		results := p.FuzzySearchDescriptions(tt.query, 3)
+		found := false
+		var got []string
+		for _, r := range results {
+			got = append(got, r.Entry.MetadataStatement.Description)
+			found = found || r.Key == ids[tt.want]
+		}
+		if !found {
+			t.Errorf("FuzzySearchDescriptions(%q, 3) = %q, want %q among them", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzySearchScores(t *testing.T) {
+	p, ids := searchCorpus(t)
+	score := func(query, description string) (float64, bool) {
+		for _, r := range p.FuzzySearchDescriptions(query, 0) {
+			if r.Key == ids[description] {
+				return r.Score, true
+			}
+		}
+		return 0, false
+	}
+	for _, tt := range []struct {
+		query, description string
+		want               float64
+	}{
+		// The query's trigrams all occur in a text with no others.
+		{"bitwarden", "Bitwarden", 1},
+		// 5 of the 7 trigrams of "yubiky" in the 17 of "yubikey", "5" and "series".
+		{"yubiky", "YubiKey 5 Series", 0.8*5/7 + 0.2*5/19},
+		// All 8 trigrams of the query, in a text of 8 + 5: only the Jaccard term is below 1.
+		{"samsung", "Samsung Pass", 0.8 + 0.2*8/13},
+	} {
+		got, ok := score(tt.query, tt.description)
+		if !ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("score of %q for %q = %v (found %v), want %v", tt.query, tt.description, got, ok, tt.want)
+		}
+	}
+	if got := p.FuzzySearchDescriptions("zzzz qqqq", 0); len(got) != 0 {
+		t.Errorf("unrelated query matched %d entries", len(got))
+	}
+}
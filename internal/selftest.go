@@ -0,0 +1,252 @@
+package aaguids
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrSelfTestFailed is returned by SelfTest when at least one check failed.
+var ErrSelfTestFailed = errors.New("aaguids: dataset self-test failed")
+
+// SelfTestCheck names one check of SelfTest.
+type SelfTestCheck string
+
+const (
+	SelfTestLoad            SelfTestCheck = "load"             // the dataset has entries, as many as it records
+	SelfTestIntegrity       SelfTestCheck = "integrity"        // the entries match Dataset.Integrity
+	SelfTestValidation      SelfTestCheck = "validation"       // every entry passes the generator's validations
+	SelfTestRequiredAAGUIDs SelfTestCheck = "required_aaguids" // every SelfTestConfig.RequiredAAGUIDs is present
+	SelfTestFreshness       SelfTestCheck = "freshness"        // the dataset is not past its nextUpdate
+)
+
+// selfTestChecks lists every check in the order SelfTest runs them.
+var selfTestChecks = []SelfTestCheck{
+	SelfTestLoad, SelfTestIntegrity, SelfTestValidation, SelfTestRequiredAAGUIDs, SelfTestFreshness,
+}
+
+/*
+SelfTestConfig configures SelfTest. The zero value runs every check with no required AAGUIDs:
+
+  - RequiredAAGUIDs: AAGUIDs that must be in the dataset, e.g. the models your users register; any
+    spelling GetEntry accepts
+  - Skip: checks not to run; they are reported as skipped
+  - Now: the time freshness is judged at; the zero value means time.Now
+*/
+type SelfTestConfig struct {
+	RequiredAAGUIDs []string
+	Skip            []SelfTestCheck
+	Now             time.Time
+}
+
+/*
+SelfTestResult is the outcome of one check in a SelfTestReport:
+
+  - Check: the check
+  - Passed / Skipped: its result; a skipped check counts as passed
+  - Detail: a one-line summary, e.g. the number of entries or how far nextUpdate has passed
+  - Problems: what failed, one item per entry or AAGUID; empty if the check passed
+*/
+type SelfTestResult struct {
+	Check    SelfTestCheck `json:"check"`
+	Passed   bool          `json:"passed"`
+	Skipped  bool          `json:"skipped,omitempty"`
+	Detail   string        `json:"detail,omitempty"`
+	Problems []string      `json:"problems,omitempty"`
+}
+
+/*
+SelfTestReport is the result of SelfTest:
+
+  - Passed: every check passed or was skipped
+  - Dataset: the identity of the dataset tested
+  - Version: the library release, see Version
+  - CheckedAt: the time freshness was judged at
+  - Checks: one result per check, in the order they ran
+
+It encodes as JSON for CI pipelines; WriteText prints it for people.
+*/
+type SelfTestReport struct {
+	Passed    bool             `json:"passed"`
+	Dataset   Dataset          `json:"dataset"`
+	Version   string           `json:"version"`
+	CheckedAt time.Time        `json:"checkedAt"`
+	Checks    []SelfTestResult `json:"checks"`
+}
+
+// SelfTest checks the embedded dataset. See Provider.SelfTest.
+func SelfTest(cfg SelfTestConfig) (SelfTestReport, error) {
+	return Default().SelfTest(cfg)
+}
+
+/*
+SelfTest checks the dataset p is serving, typically the embedded one at deploy time, so a binary
+built from a broken or outdated generation fails before it takes traffic. The checks, each of
+which can be skipped with SelfTestConfig.Skip, are:
+
+  - load: the dataset has entries, and as many as Dataset.EntryCount records if it is set
+  - integrity: the entries hash to Dataset.Integrity (see ComputeIntegrity), if it is set
+  - validation: every entry passes ValidateBiometricStatusReports, ValidateGetInfoAAGUID,
+    ValidateEntryAAIDs and ValidateUAFEntry, the validations the generator warns about
+  - required_aaguids: every SelfTestConfig.RequiredAAGUIDs is a valid AAGUID found by GetEntry
+  - freshness: Dataset.NextUpdate is set and has not passed (see Provider.Staleness)
+
+The report lists every check whatever the outcome. The error wraps ErrSelfTestFailed and names the
+failed checks if the report did not pass; it is nil otherwise.
+*/
+func (p *Provider) SelfTest(cfg SelfTestConfig) (SelfTestReport, error) {
+	s := p.current()
+	now := cfg.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	rep := SelfTestReport{Passed: true, Dataset: s.info, Version: Version(), CheckedAt: now}
+	var failed []string
+	for _, c := range selfTestChecks {
+		r := SelfTestResult{Check: c, Passed: true}
+		if slices.Contains(cfg.Skip, c) {
+			r.Skipped = true
+		} else {
+			r.Detail, r.Problems = p.selfTestCheck(s, c, cfg, now)
+			r.Passed = len(r.Problems) == 0
+		}
+		if !r.Passed {
+			rep.Passed = false
+			failed = append(failed, string(c))
+		}
+		rep.Checks = append(rep.Checks, r)
+	}
+	if !rep.Passed {
+		return rep, fmt.Errorf("%w: %s", ErrSelfTestFailed, strings.Join(failed, ", "))
+	}
+	return rep, nil
+}
+
+// selfTestCheck runs check c against s, returning its detail line and the problems found.
+func (p *Provider) selfTestCheck(s *snapshot, c SelfTestCheck, cfg SelfTestConfig, now time.Time) (string, []string) {
+	switch c {
+	case SelfTestLoad:
+		detail := fmt.Sprintf("%d entries", len(s.entries))
+		switch {
+		case len(s.entries) == 0:
+			return detail, []string{"the dataset has no entries"}
+		case s.info.EntryCount != 0 && s.info.EntryCount != len(s.entries):
+			return detail, []string{fmt.Sprintf("the dataset records %d entries", s.info.EntryCount)}
+		}
+		return detail, nil
+
+	case SelfTestIntegrity:
+		if s.info.Integrity == "" {
+			return "the dataset records no integrity digest", nil
+		}
+		if err := checkIntegrity(s.info, s.entries); err != nil {
+			return "", []string{err.Error()}
+		}
+		return s.info.Integrity, nil
+
+	case SelfTestValidation:
+		var problems []string
+		for _, k := range s.keys {
+			e := s.entries[k]
+			for _, validate := range []func(Entry) error{
+				ValidateBiometricStatusReports, ValidateGetInfoAAGUID, ValidateEntryAAIDs, ValidateUAFEntry,
+			} {
+				if err := validate(e); err != nil {
+					problems = append(problems, fmt.Sprintf("%s (%s): %v", k, e.DisplayName(), err))
+				}
+			}
+		}
+		return fmt.Sprintf("%d entries validated", len(s.keys)), problems
+
+	case SelfTestRequiredAAGUIDs:
+		var problems []string
+		for _, id := range cfg.RequiredAAGUIDs {
+			if _, err := ParseAAGUID(id); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", id, err))
+			} else if _, ok := p.GetEntry(id); !ok {
+				problems = append(problems, id+": not in the dataset")
+			}
+		}
+		return fmt.Sprintf("%d of %d present", len(cfg.RequiredAAGUIDs)-len(problems), len(cfg.RequiredAAGUIDs)), problems
+
+	case SelfTestFreshness:
+		if _, ok := parseISODate(s.info.NextUpdate); !ok {
+			return "", []string{fmt.Sprintf("the dataset has no valid nextUpdate (%q)", s.info.NextUpdate)}
+		}
+		if d, stale := s.staleness(now); stale {
+			return "", []string{fmt.Sprintf("nextUpdate %s passed %s ago", s.info.NextUpdate, d)}
+		}
+		return "nextUpdate " + s.info.NextUpdate, nil
+	}
+	return "", []string{fmt.Sprintf("unknown check %q", c)}
+}
+
+/*
+WriteText prints r for a terminal or a deploy log: a header identifying the dataset, one PASS,
+FAIL or SKIP line per check followed by its problems, and a final PASS or FAIL line.
+*/
+func (r SelfTestReport) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "aaguids self-test: dataset serial %d, nextUpdate %s, %d entries, library %s\n",
+		r.Dataset.Serial, r.Dataset.NextUpdate, r.Dataset.EntryCount, r.Version)
+	for _, c := range r.Checks {
+		verdict := "PASS"
+		switch {
+		case c.Skipped:
+			verdict = "SKIP"
+		case !c.Passed:
+			verdict = "FAIL"
+		}
+		fmt.Fprintf(&b, "  %s %s", verdict, c.Check)
+		if c.Detail != "" {
+			fmt.Fprintf(&b, ": %s", c.Detail)
+		}
+		b.WriteByte('\n')
+		for _, problem := range c.Problems {
+			fmt.Fprintf(&b, "       %s\n", problem)
+		}
+	}
+	if r.Passed {
+		b.WriteString("PASS\n")
+	} else {
+		b.WriteString("FAIL\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// RunSelfTest runs SelfTest against the embedded dataset and prints the report. See
+// Provider.RunSelfTest.
+func RunSelfTest(w io.Writer, asJSON bool, cfg SelfTestConfig) int {
+	return Default().RunSelfTest(w, asJSON, cfg)
+}
+
+/*
+RunSelfTest runs SelfTest, writes the report to w, as indented JSON if asJSON is set and as
+WriteText prints it otherwise, and returns the process exit code: 0 if the report passed, 1 if it
+did not or could not be written. It needs no flag package of its own, so a service can offer a
+verification flag with:
+
+	if *verifyMetadata {
+		os.Exit(aaguids.RunSelfTest(os.Stdout, false, aaguids.SelfTestConfig{RequiredAAGUIDs: mustHave}))
+	}
+*/
+func (p *Provider) RunSelfTest(w io.Writer, asJSON bool, cfg SelfTestConfig) int {
+	rep, testErr := p.SelfTest(cfg)
+	var err error
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(rep)
+	} else {
+		err = rep.WriteText(w)
+	}
+	if testErr != nil || err != nil {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package aaguids
+
+import "log/slog"
+
+/*
+ShadowPolicy runs a candidate Policy alongside the active one before it is enforced. Callers act on
+the active Decision only; whenever the candidate decides differently, OnDivergence receives a
+Divergence, from which a pre-rollout impact report can be built.
+
+  - Active: the policy in force
+  - Candidate: the policy being trialled
+  - OnDivergence: called synchronously for every divergence, e.g. to increment a metric; nil logs
+    each divergence at info level to the Provider's logger (see WithLogger)
+*/
+type ShadowPolicy struct {
+	Active       Policy
+	Candidate    Policy
+	OnDivergence func(Divergence)
+}
+
+/*
+Divergence describes one evaluation on which the active and candidate policies of a ShadowPolicy
+disagree, in either the outcome or the reason. It identifies the authenticator model only, never the
+user or the credential, so divergences can be logged and aggregated as they are.
+
+  - AAGUID: the canonical AAGUID evaluated (as given if it is malformed)
+  - Serial: the Dataset.Serial both decisions were made against
+  - ActiveAllowed / ActiveReason: the decision that was enforced
+  - CandidateAllowed / CandidateReason: what the candidate would have decided
+  - Status: the effective status the candidate saw ("" if none)
+*/
+type Divergence struct {
+	AAGUID           string              `json:"aaguid"`
+	Serial           int                 `json:"serial"`
+	ActiveAllowed    bool                `json:"activeAllowed"`
+	ActiveReason     ReasonCode          `json:"activeReason"`
+	CandidateAllowed bool                `json:"candidateAllowed"`
+	CandidateReason  ReasonCode          `json:"candidateReason"`
+	Status           AuthenticatorStatus `json:"status,omitempty"`
+}
+
+// Evaluate applies sp to aaGuid using the embedded dataset. See Provider.ShadowDecision.
+func (sp ShadowPolicy) Evaluate(aaGuid string, opts ...EvaluateOption) (active, candidate Decision) {
+	return Default().ShadowDecision(sp, aaGuid, opts...)
+}
+
+/*
+ShadowDecision evaluates both policies of sp for aaGuid with the same options and against the same
+snapshot, reports a Divergence when they disagree, and returns both decisions. Only active should
+be acted on.
+*/
+func (p *Provider) ShadowDecision(sp ShadowPolicy, aaGuid string, opts ...EvaluateOption) (active, candidate Decision) {
+	s, norm := p.current(), p.normalizerChain()
+	active = p.evaluation(opts).run(s, norm, sp.Active, aaGuid)
+	candidate = p.evaluation(opts).run(s, norm, sp.Candidate, aaGuid)
+	if active.Allowed == candidate.Allowed && active.Reason == candidate.Reason {
+		return active, candidate
+	}
+
+	div := Divergence{
+		AAGUID:           active.AAGUID,
+		Serial:           s.info.Serial,
+		ActiveAllowed:    active.Allowed,
+		ActiveReason:     active.Reason,
+		CandidateAllowed: candidate.Allowed,
+		CandidateReason:  candidate.Reason,
+		Status:           candidate.Status,
+	}
+	if sp.OnDivergence != nil {
+		sp.OnDivergence(div)
+	} else {
+		p.log().Info("candidate policy diverges from active policy",
+			slog.String("aaguid", div.AAGUID),
+			slog.Int("serial", div.Serial),
+			slog.Bool("activeAllowed", div.ActiveAllowed),
+			slog.String("activeReason", string(div.ActiveReason)),
+			slog.Bool("candidateAllowed", div.CandidateAllowed),
+			slog.String("candidateReason", string(div.CandidateReason)),
+			slog.String("status", string(div.Status)))
+	}
+	return active, candidate
+}
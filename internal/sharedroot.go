@@ -0,0 +1,122 @@
+package aaguids
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"slices"
+	"sort"
+	"text/template"
+)
+
+// SharedRootEntry is one entry listing a shared attestation root.
+type SharedRootEntry struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Vendor      string `json:"vendor"`
+}
+
+/*
+SharedRoot is an attestation root certificate listed by entries attributed to more than one vendor.
+
+  - Subject: the root's subject distinguished name ("" if the certificate does not parse)
+  - Fingerprint: lowercase hex SHA-256 of the root's DER encoding
+  - Vendors: the distinct vendor names of the sharing entries, sorted
+  - Entries: every entry listing the root, in ascending key order
+*/
+type SharedRoot struct {
+	Subject     string            `json:"subject"`
+	Fingerprint string            `json:"fingerprint"`
+	Vendors     []string          `json:"vendors"`
+	Entries     []SharedRootEntry `json:"entries"`
+}
+
+/*
+SharedRoots is the result of SharedRootReport: the attestation roots found in entries of more than
+one vendor. A shared root usually indicates white-labeled hardware or an upstream data error.
+Roots are ordered by descending vendor count, then fingerprint. The value marshals as JSON, and
+Markdown renders it for review.
+*/
+type SharedRoots struct {
+	Dataset Dataset      `json:"dataset"`
+	Roots   []SharedRoot `json:"roots"`
+}
+
+// SharedRootReport lists the attestation roots of the embedded dataset shared across vendors.
+func SharedRootReport() SharedRoots {
+	return Default().SharedRootReport()
+}
+
+/*
+SharedRootReport lists the attestation roots in the current snapshot that appear in entries
+attributed to more than one vendor, using the root fingerprint index and Vendor. Entries without a
+known vendor are listed with the root but do not count as a distinct vendor.
+*/
+func (p *Provider) SharedRootReport() SharedRoots {
+	s := p.current()
+	r := SharedRoots{Dataset: p.DatasetInfo()}
+	for fp, keys := range s.rootFingerprints {
+		if len(keys) < 2 {
+			continue
+		}
+		root := SharedRoot{Fingerprint: hex.EncodeToString(fp[:])}
+		for _, k := range keys {
+			e := s.entries[k]
+			v := p.Vendor(k).Name
+			root.Entries = append(root.Entries, SharedRootEntry{ID: k, Description: e.DisplayName(), Vendor: v})
+			if v != "" && !slices.Contains(root.Vendors, v) {
+				root.Vendors = append(root.Vendors, v)
+			}
+		}
+		if len(root.Vendors) < 2 {
+			continue
+		}
+		sort.Strings(root.Vendors)
+		root.Subject = rootSubject(s.entries[keys[0]].MetadataStatement, fp)
+		r.Roots = append(r.Roots, root)
+	}
+	sort.Slice(r.Roots, func(i, j int) bool {
+		if len(r.Roots[i].Vendors) != len(r.Roots[j].Vendors) {
+			return len(r.Roots[i].Vendors) > len(r.Roots[j].Vendors)
+		}
+		return r.Roots[i].Fingerprint < r.Roots[j].Fingerprint
+	})
+	return r
+}
+
+// rootSubject returns the subject of the attestation root of m with fingerprint fp, or "".
+func rootSubject(m MetadataStatement, fp [32]byte) string {
+	for i, f := range m.rootFingerprints() {
+		if f != fp {
+			continue
+		}
+		der, _ := base64.StdEncoding.DecodeString(m.AttestationRootCertificates[i])
+		if cert, err := x509.ParseCertificate(der); err == nil {
+			return cert.Subject.String()
+		}
+	}
+	return ""
+}
+
+// sharedRootsMarkdown renders SharedRoots as Markdown.
+const sharedRootsMarkdown = `# Attestation Roots Shared Across Vendors
+
+MDS serial {{.Dataset.Serial}}: {{len .Roots}} shared root(s).
+{{range .Roots}}
+## {{or .Subject "(unparseable certificate)"}}
+
+- Fingerprint (SHA-256): ` + "`{{.Fingerprint}}`" + `
+- Vendors: {{range $i, $v := .Vendors}}{{if $i}}, {{end}}{{$v}}{{end}}
+
+| Entry | Description | Vendor |
+|---|---|---|
+{{range .Entries}}| {{.ID}} | {{.Description}} | {{or .Vendor "(unknown)"}} |
+{{end}}{{end}}`
+
+var sharedRootsMarkdownTemplate = template.Must(template.New("sharedroots.md").Parse(sharedRootsMarkdown))
+
+// Markdown writes the shared-root report as Markdown.
+func (r SharedRoots) Markdown(w io.Writer) error {
+	return sharedRootsMarkdownTemplate.Execute(w, r)
+}
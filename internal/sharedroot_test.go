@@ -0,0 +1,58 @@
+package aaguids
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSharedRootReport(t *testing.T) {
+	der := func(name string) string { return base64.StdEncoding.EncodeToString(readTestCertificate(t, name).Raw) }
+	u2fRoot, unrelated, yubicoOnly := der("u2f-root.pem"), der("unrelated.pem"), der("u2f-attestation.pem")
+	entry := func(n, description string, roots ...string) Entry {
+		id := "00000000-0000-4000-8000-00000000000" + n
+		return Entry{AAGUID: id, MetadataStatement: MetadataStatement{
+			AAGUID: id, Description: description, AttestationRootCertificates: roots,
+		}}
+	}
+	p := testProvider(t,
+		entry("1", "Security Key by Yubico", u2fRoot, unrelated, yubicoOnly),
+		entry("2", "FIDO2 Key by Acme Corp.", u2fRoot),
+		entry("3", "Nitrokey, Inc.", u2fRoot),
+		// No vendor: listed with the root without counting as one.
+		entry("4", "", u2fRoot),
+		entry("5", "Token2 PIN+", unrelated),
+		// A second Yubico model: yubicoOnly is shared by one vendor and left out.
+		entry("6", "YubiKey 5 Series", yubicoOnly),
+		// A root listed by a single entry.
+		entry("7", "Authenticator by Example Widgets GmbH", der("attestation-subject-key-id.pem")),
+	)
+
+	r := p.SharedRootReport()
+	if len(r.Roots) != 2 {
+		t.Fatalf("%d shared roots, want 2: %+v", len(r.Roots), r.Roots)
+	}
+	// Three vendors before two.
+	if got := r.Roots[0]; got.Subject != "CN=Example U2F Root CA" || len(got.Entries) != 4 ||
+		!slices.Equal(got.Vendors, []string{"Acme", "Nitrokey", "Yubico"}) {
+		t.Errorf("first shared root = %+v, want the U2F root with three vendors and four entries", got)
+	}
+	if got := r.Roots[1]; got.Subject != "CN=Unrelated Self-Signed" || !slices.Equal(got.Vendors, []string{"Token2", "Yubico"}) {
+		t.Errorf("second shared root = %+v, want the unrelated root of Token2 and Yubico", got)
+	}
+
+	var md, js bytes.Buffer
+	if err := r.Markdown(&md); err != nil {
+		t.Fatal(err)
+	}
+	enc := json.NewEncoder(&js)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, filepath.Join("testdata", "shared-roots.md"), md.Bytes())
+	checkGolden(t, filepath.Join("testdata", "shared-roots.json"), js.Bytes())
+}
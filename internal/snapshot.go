@@ -0,0 +1,298 @@
+package aaguids
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+/*
+Snapshot container format versions read by LoadSnapshot and written by SaveSnapshot. A release
+reads its own version and the one before it, so files written by either side of a rolling upgrade
+load on both:
+
+  - 1: section table of 32-bit offsets and lengths
+  - 2: section table of 64-bit offsets and lengths with a CRC-32 (IEEE) of every stored section
+*/
+const (
+	SnapshotFormatVersion    = 2
+	MinSnapshotFormatVersion = SnapshotFormatVersion - 1
+)
+
+var (
+	// ErrInvalidSnapshot reports input that is not a readable snapshot container: bad magic bytes,
+	// a truncated or inconsistent section table, a checksum mismatch or undecodable sections.
+	ErrInvalidSnapshot = errors.New("aaguids: invalid snapshot")
+	// ErrUnsupportedSnapshot reports a well-formed snapshot this release cannot read: a format
+	// version outside MinSnapshotFormatVersion to SnapshotFormatVersion, or unknown feature flags.
+	ErrUnsupportedSnapshot = errors.New("aaguids: unsupported snapshot format")
+	// ErrLegacySnapshot reports a dataset written before the snapshot container, such as a
+	// SavePruned file or an ExportJSON document; the wrapping error says how to migrate it.
+	ErrLegacySnapshot = errors.New("aaguids: unversioned legacy dataset")
+)
+
+/*
+Layout of a snapshot container, all integers big-endian:
+
+	magic   [8]byte  snapshotMagic
+	version uint16   format version
+	count   uint16   number of sections
+	flags   uint32   feature flags
+	table   [count]  version 1: tag [4]byte, offset uint32, length uint32
+	                 version 2: tag [4]byte, offset uint64, length uint64, crc32 uint32
+	data             the sections, at the offsets given from the start of the container
+
+Readers skip sections with unknown tags, so sections can be added without a new version; a new
+version or feature flag is needed whenever existing sections change encoding.
+*/
+const (
+	snapshotMagic      = "AAGS\r\n\x1a\n" // the line endings catch text-mode transfers
+	snapshotHeaderSize = len(snapshotMagic) + 2 + 2 + 4
+
+	snapshotFlagGzip   uint32 = 1 << 0 // every section is gzip-compressed
+	snapshotFlagsKnown        = snapshotFlagGzip
+)
+
+// snapshotTableEntrySize is the size of one section table entry by format version.
+var snapshotTableEntrySize = map[uint16]int{1: 4 + 4 + 4, 2: 4 + 8 + 8 + 4}
+
+// Section tags; each section holds the JSON encoding of one part of the snapshot.
+const (
+	sectionDataset    = "DSET" // Dataset, required
+	sectionEntries    = "ENTS" // map[string]Entry, required
+	sectionProvenance = "PROV" // FieldProvenance
+	sectionRaw        = "RAWJ" // map[string]json.RawMessage
+)
+
+// SnapshotOption adjusts Provider.SaveSnapshot.
+type SnapshotOption func(*snapshotConfig)
+
+// snapshotConfig is the configuration of one SaveSnapshot call.
+type snapshotConfig struct {
+	version uint16
+}
+
+/*
+WithSnapshotFormatVersion writes format version v instead of SnapshotFormatVersion, e.g.
+MinSnapshotFormatVersion while instances of the previous release still read the file. SaveSnapshot
+fails for versions this release cannot write.
+*/
+func WithSnapshotFormatVersion(v int) SnapshotOption {
+	return func(c *snapshotConfig) { c.version = uint16(min(max(v, 0), 0xffff)) }
+}
+
+/*
+SaveSnapshot writes the current snapshot of p to w as a versioned snapshot container that
+LoadSnapshot reads back: the Dataset identity, the entries, their field provenance and any retained
+raw JSON, each in its own gzip-compressed section. Unlike SavePruned, the container records its
+format version, so a release that cannot read it fails with ErrUnsupportedSnapshot instead of
+misreading it.
+*/
+func (p *Provider) SaveSnapshot(w io.Writer, opts ...SnapshotOption) error {
+	cfg := snapshotConfig{version: SnapshotFormatVersion}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	entrySize, ok := snapshotTableEntrySize[cfg.version]
+	if !ok {
+		return fmt.Errorf("writing snapshot: %w: cannot write format version %d", ErrUnsupportedSnapshot, cfg.version)
+	}
+
+	s := p.current()
+	type section struct {
+		tag  string
+		data []byte
+	}
+	var sections []section
+	add := func(tag string, v any) error {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		encErr := json.NewEncoder(zw).Encode(v)
+		if err := errors.Join(encErr, zw.Close()); err != nil {
+			return fmt.Errorf("writing snapshot section %s: %w", tag, err)
+		}
+		sections = append(sections, section{tag, buf.Bytes()})
+		return nil
+	}
+	if err := add(sectionDataset, s.info); err != nil {
+		return err
+	}
+	if err := add(sectionEntries, s.entries); err != nil {
+		return err
+	}
+	if s.provenance != nil {
+		if err := add(sectionProvenance, s.provenance); err != nil {
+			return err
+		}
+	}
+	if s.raw != nil {
+		if err := add(sectionRaw, s.raw); err != nil {
+			return err
+		}
+	}
+
+	head := make([]byte, 0, snapshotHeaderSize+len(sections)*entrySize)
+	head = append(head, snapshotMagic...)
+	head = binary.BigEndian.AppendUint16(head, cfg.version)
+	head = binary.BigEndian.AppendUint16(head, uint16(len(sections)))
+	head = binary.BigEndian.AppendUint32(head, snapshotFlagGzip)
+	off := uint64(snapshotHeaderSize + len(sections)*entrySize)
+	for _, sec := range sections {
+		head = append(head, sec.tag...)
+		n := uint64(len(sec.data))
+		switch cfg.version {
+		case 1:
+			if off+n > 0xffffffff {
+				return fmt.Errorf("writing snapshot: %w: format version 1 is limited to 4 GiB", ErrUnsupportedSnapshot)
+			}
+			head = binary.BigEndian.AppendUint32(head, uint32(off))
+			head = binary.BigEndian.AppendUint32(head, uint32(n))
+		case 2:
+			head = binary.BigEndian.AppendUint64(head, off)
+			head = binary.BigEndian.AppendUint64(head, n)
+			head = binary.BigEndian.AppendUint32(head, crc32.ChecksumIEEE(sec.data))
+		}
+		off += n
+	}
+	if _, err := w.Write(head); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	for _, sec := range sections {
+		if _, err := w.Write(sec.data); err != nil {
+			return fmt.Errorf("writing snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+/*
+LoadSnapshot reads a snapshot container written by SaveSnapshot into a new Provider configured with
+opts. Errors wrap ErrUnsupportedSnapshot for format versions other than MinSnapshotFormatVersion to
+SnapshotFormatVersion or unknown feature flags, ErrLegacySnapshot for the unversioned SavePruned and
+ExportJSON formats, and ErrInvalidSnapshot otherwise. As with LoadPruned, the Dataset Integrity is
+checked against the entries.
+*/
+func LoadSnapshot(r io.Reader, opts ...ProviderOption) (*Provider, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	sections, err := snapshotSections(b)
+	if err != nil {
+		return nil, err
+	}
+	var d prunedDataset
+	for _, dec := range []struct {
+		tag      string
+		v        any
+		required bool
+	}{
+		{sectionDataset, &d.Dataset, true},
+		{sectionEntries, &d.Entries, true},
+		{sectionProvenance, &d.Provenance, false},
+		{sectionRaw, &d.Raw, false},
+	} {
+		data, ok := sections[dec.tag]
+		if !ok {
+			if dec.required {
+				return nil, fmt.Errorf("%w: no %s section", ErrInvalidSnapshot, dec.tag)
+			}
+			continue
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: section %s: %w", ErrInvalidSnapshot, dec.tag, err)
+		}
+		if err := json.NewDecoder(zr).Decode(dec.v); err != nil {
+			return nil, fmt.Errorf("%w: section %s: %w", ErrInvalidSnapshot, dec.tag, err)
+		}
+	}
+	if d.Entries == nil {
+		d.Entries = map[string]Entry{}
+	}
+	if err := checkIntegrity(d.Dataset, d.Entries); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	p := &Provider{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.update(d.Entries, d.Dataset, d.Provenance, d.Raw, OriginStore)
+	return p, nil
+}
+
+// snapshotSections validates the container header and section table of b and returns the stored
+// sections by tag.
+func snapshotSections(b []byte) (map[string][]byte, error) {
+	if !bytes.HasPrefix(b, []byte(snapshotMagic)) {
+		return nil, legacySnapshotError(b)
+	}
+	if len(b) < snapshotHeaderSize {
+		return nil, fmt.Errorf("%w: header is truncated", ErrInvalidSnapshot)
+	}
+	head := b[len(snapshotMagic):]
+	version, count, flags := binary.BigEndian.Uint16(head), int(binary.BigEndian.Uint16(head[2:])), binary.BigEndian.Uint32(head[4:])
+	entrySize, ok := snapshotTableEntrySize[version]
+	switch {
+	case version > SnapshotFormatVersion:
+		return nil, fmt.Errorf("%w: format version %d is newer than this release reads (%d to %d); upgrade the aaguids package",
+			ErrUnsupportedSnapshot, version, MinSnapshotFormatVersion, SnapshotFormatVersion)
+	case version < MinSnapshotFormatVersion || !ok:
+		return nil, fmt.Errorf("%w: format version %d is no longer read (%d to %d); re-save it with a release that reads both",
+			ErrUnsupportedSnapshot, version, MinSnapshotFormatVersion, SnapshotFormatVersion)
+	case flags&^snapshotFlagsKnown != 0:
+		return nil, fmt.Errorf("%w: unknown feature flags 0x%x; upgrade the aaguids package", ErrUnsupportedSnapshot, flags&^snapshotFlagsKnown)
+	case flags&snapshotFlagGzip == 0:
+		return nil, fmt.Errorf("%w: uncompressed sections are not supported", ErrUnsupportedSnapshot)
+	}
+
+	table := b[snapshotHeaderSize:]
+	if len(table) < count*entrySize {
+		return nil, fmt.Errorf("%w: section table is truncated", ErrInvalidSnapshot)
+	}
+	sections := make(map[string][]byte, count)
+	for i := range count {
+		e := table[i*entrySize : (i+1)*entrySize]
+		tag := string(e[:4])
+		var off, n uint64
+		var sum uint32
+		switch version {
+		case 1:
+			off, n = uint64(binary.BigEndian.Uint32(e[4:])), uint64(binary.BigEndian.Uint32(e[8:]))
+		case 2:
+			off, n, sum = binary.BigEndian.Uint64(e[4:]), binary.BigEndian.Uint64(e[12:]), binary.BigEndian.Uint32(e[20:])
+		}
+		if off > uint64(len(b)) || n > uint64(len(b))-off {
+			return nil, fmt.Errorf("%w: section %s lies outside the %d-byte input", ErrInvalidSnapshot, tag, len(b))
+		}
+		data := b[off : off+n]
+		if version >= 2 && crc32.ChecksumIEEE(data) != sum {
+			return nil, fmt.Errorf("%w: section %s fails its checksum", ErrInvalidSnapshot, tag)
+		}
+		if _, dup := sections[tag]; dup {
+			return nil, fmt.Errorf("%w: section %s appears twice", ErrInvalidSnapshot, tag)
+		}
+		sections[tag] = data
+	}
+	return sections, nil
+}
+
+// legacySnapshotError explains input without the snapshot magic: a SavePruned file or an
+// ExportJSON document wraps ErrLegacySnapshot with its migration, anything else ErrInvalidSnapshot.
+func legacySnapshotError(b []byte) error {
+	switch t := bytes.TrimLeft(b, " \t\r\n"); {
+	case bytes.HasPrefix(b, []byte{0x1f, 0x8b}):
+		return fmt.Errorf("%w: gzip data without a snapshot header, as written by SavePruned; read it with LoadPruned and write it again with SaveSnapshot", ErrLegacySnapshot)
+	case len(t) > 0 && t[0] == '{':
+		return fmt.Errorf("%w: JSON without a snapshot header, as written by ExportJSON; regenerate the snapshot with SaveSnapshot from the Provider it was exported from", ErrLegacySnapshot)
+	case bytes.HasPrefix([]byte(snapshotMagic), b):
+		return fmt.Errorf("%w: header is truncated", ErrInvalidSnapshot)
+	default:
+		return fmt.Errorf("%w: missing magic bytes", ErrInvalidSnapshot)
+	}
+}
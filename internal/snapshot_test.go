@@ -0,0 +1,166 @@
+package aaguids
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// snapshotFixtureProvider returns the Provider the snapshot fixtures were written from: the
+// entries of blob-mixed.json with their integrity digest.
+func snapshotFixtureProvider(t *testing.T) *Provider {
+	t.Helper()
+	blob := readTestBLOB(t, "blob-mixed.json")
+	entries := blob.EntriesByKey()
+	integrity, err := ComputeIntegrity(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewProvider(entries, Dataset{Serial: blob.No, NextUpdate: blob.NextUpdate, EntryCount: len(entries), Integrity: integrity})
+}
+
+// snapshotFixture returns the path of the fixture of format version v.
+func snapshotFixture(v int) string {
+	return filepath.Join("testdata", fmt.Sprintf("snapshot-v%d.snap", v))
+}
+
+/*
+TestSnapshotFixtures loads fixture files of the current and the previous format version, as
+written by a release of each, so a change to the reader that breaks either is caught. They are only
+rewritten with -update, which the writer of a format version must never need: a fixture that no
+longer loads means files in the field no longer load either.
+*/
+func TestSnapshotFixtures(t *testing.T) {
+	want := snapshotFixtureProvider(t)
+	for _, v := range []int{MinSnapshotFormatVersion, SnapshotFormatVersion} {
+		t.Run(fmt.Sprintf("version %d", v), func(t *testing.T) {
+			path := snapshotFixture(v)
+			if *updateGoldens {
+				var buf bytes.Buffer
+				if err := want.SaveSnapshot(&buf, WithSnapshotFormatVersion(v)); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if got := binary.BigEndian.Uint16(b[len(snapshotMagic):]); int(got) != v {
+				t.Fatalf("%s records format version %d, want %d", path, got, v)
+			}
+			p, err := LoadSnapshot(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("LoadSnapshot(%s): %v", path, err)
+			}
+			if got, want := p.DatasetInfo(), want.DatasetInfo(); got.Serial != want.Serial || got.Integrity != want.Integrity || got.NextUpdate != want.NextUpdate {
+				t.Errorf("dataset %+v, want %+v", got, want)
+			}
+			if got, want := p.AAGUIDs(), want.AAGUIDs(); !slices.Equal(got, want) {
+				t.Errorf("keys %q, want %q", got, want)
+			}
+			if h := p.Health(); h.Origin != OriginStore {
+				t.Errorf("origin %s, want %s", h.Origin, OriginStore)
+			}
+		})
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	src := snapshotFixtureProvider(t)
+	for _, v := range []int{MinSnapshotFormatVersion, SnapshotFormatVersion} {
+		var buf bytes.Buffer
+		if err := src.SaveSnapshot(&buf, WithSnapshotFormatVersion(v)); err != nil {
+			t.Fatalf("version %d: %v", v, err)
+		}
+		p, err := LoadSnapshot(&buf)
+		if err != nil {
+			t.Fatalf("version %d: %v", v, err)
+		}
+		for _, k := range src.AAGUIDs() {
+			want, _ := src.GetEntry(k)
+			got, ok := p.GetEntry(k)
+			a, errA := CanonicalJSON(got)
+			b, errB := CanonicalJSON(want)
+			if !ok || errA != nil || errB != nil || !bytes.Equal(a, b) {
+				t.Errorf("version %d: entry %s differs after a round trip", v, k)
+			}
+		}
+	}
+	for _, v := range []int{0, MinSnapshotFormatVersion - 1, SnapshotFormatVersion + 1} {
+		if err := src.SaveSnapshot(&bytes.Buffer{}, WithSnapshotFormatVersion(v)); !errors.Is(err, ErrUnsupportedSnapshot) {
+			t.Errorf("SaveSnapshot of version %d: %v, want ErrUnsupportedSnapshot", v, err)
+		}
+	}
+}
+
+// snapshotHeader returns a container header of version v with count sections and flags.
+func snapshotHeader(v uint16, count uint16, flags uint32) []byte {
+	head := append([]byte(nil), snapshotMagic...)
+	head = binary.BigEndian.AppendUint16(head, v)
+	head = binary.BigEndian.AppendUint16(head, count)
+	return binary.BigEndian.AppendUint32(head, flags)
+}
+
+func TestLoadSnapshotRejects(t *testing.T) {
+	src := snapshotFixtureProvider(t)
+	var current, pruned, exported bytes.Buffer
+	if err := src.SaveSnapshot(&current); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SavePruned(&pruned); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.ExportJSON(&exported); err != nil {
+		t.Fatal(err)
+	}
+	// corrupt returns the current snapshot with the byte at i changed.
+	corrupt := func(i int) []byte {
+		b := bytes.Clone(current.Bytes())
+		b[i] ^= 0xff
+		return b
+	}
+	lastSection := len(current.Bytes()) - 1
+	var plain bytes.Buffer
+	gzip.NewWriter(&plain).Close()
+
+	for _, tt := range []struct {
+		name string
+		in   []byte
+		want error
+		msg  string // a part of the message saying what to do
+	}{
+		{"newer version", snapshotHeader(SnapshotFormatVersion+1, 0, snapshotFlagGzip), ErrUnsupportedSnapshot, "upgrade the aaguids package"},
+		{"version before the previous one", snapshotHeader(MinSnapshotFormatVersion-1, 0, snapshotFlagGzip), ErrUnsupportedSnapshot, "re-save it"},
+		{"unknown feature flag", snapshotHeader(SnapshotFormatVersion, 0, snapshotFlagGzip|1<<7), ErrUnsupportedSnapshot, "unknown feature flags 0x80"},
+		{"uncompressed", snapshotHeader(SnapshotFormatVersion, 0, 0), ErrUnsupportedSnapshot, "uncompressed"},
+		{"SavePruned file", pruned.Bytes(), ErrLegacySnapshot, "read it with LoadPruned"},
+		{"ExportJSON document", exported.Bytes(), ErrLegacySnapshot, "regenerate the snapshot with SaveSnapshot"},
+		{"other gzip data", plain.Bytes(), ErrLegacySnapshot, "SavePruned"},
+		{"no magic", []byte("not a snapshot"), ErrInvalidSnapshot, "missing magic bytes"},
+		{"truncated magic", []byte(snapshotMagic[:4]), ErrInvalidSnapshot, "header is truncated"},
+		{"truncated header", []byte(snapshotMagic + "\x00"), ErrInvalidSnapshot, "header is truncated"},
+		{"truncated table", snapshotHeader(SnapshotFormatVersion, 3, snapshotFlagGzip), ErrInvalidSnapshot, "section table is truncated"},
+		{"checksum mismatch", corrupt(lastSection), ErrInvalidSnapshot, "fails its checksum"},
+		{"missing sections", snapshotHeader(SnapshotFormatVersion, 0, snapshotFlagGzip), ErrInvalidSnapshot, "no DSET section"},
+		{"section outside the input", current.Bytes()[:lastSection], ErrInvalidSnapshot, "lies outside"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadSnapshot(bytes.NewReader(tt.in))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("LoadSnapshot: %v, want %v", err, tt.want)
+			}
+			if !bytes.Contains([]byte(err.Error()), []byte(tt.msg)) {
+				t.Errorf("error %q does not mention %q", err, tt.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package aaguids
+
+import (
+	"fmt"
+	"time"
+)
+
+// staleness returns how far now is past the dataset's nextUpdate, and false if it is not past it
+// or the dataset has no parseable nextUpdate.
+func (s *snapshot) staleness(now time.Time) (time.Duration, bool) {
+	next, ok := parseISODate(s.info.NextUpdate)
+	if !ok || !now.After(next) {
+		return 0, false
+	}
+	return now.Sub(next), true
+}
+
+// Staleness reports how stale the embedded dataset is. See Provider.Staleness.
+func Staleness() time.Duration {
+	return Default().Staleness()
+}
+
+/*
+Staleness returns how far the current time is past the nextUpdate of p's dataset, or 0 if it has
+not passed or the dataset has none. A failed refresh leaves the last good snapshot in place, so
+lookups keep being served from it while Staleness grows: stale-while-revalidate. It is the measure
+behind HealthStatus.StaleBy, the WithStaleDegradedAfter and WithStaleNotReadyAfter thresholds of
+HealthHandler, and Policy.MaxMetadataStaleness.
+*/
+func (p *Provider) Staleness() time.Duration {
+	d, _ := p.current().staleness(time.Now())
+	return d
+}
+
+// StaleMetadataMode selects what a Policy does when the dataset is staler than
+// Policy.MaxMetadataStaleness.
+type StaleMetadataMode int
+
+const (
+	// StaleMetadataDeny rejects every AAGUID looked up in the stale dataset with
+	// ReasonMetadataTooStale. It is the zero value.
+	StaleMetadataDeny StaleMetadataMode = iota
+	// StaleMetadataWarn evaluates the policy as usual and sets Decision.Warning and
+	// Decision.MetadataStale.
+	StaleMetadataWarn
+)
+
+// String returns "deny" or "warn".
+func (m StaleMetadataMode) String() string {
+	switch m {
+	case StaleMetadataDeny:
+		return "deny"
+	case StaleMetadataWarn:
+		return "warn"
+	}
+	return fmt.Sprintf("StaleMetadataMode(%d)", int(m))
+}
+
+// MarshalText encodes the mode as its String form.
+func (m StaleMetadataMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText decodes the String form of a mode.
+func (m *StaleMetadataMode) UnmarshalText(b []byte) error {
+	for _, v := range []StaleMetadataMode{StaleMetadataDeny, StaleMetadataWarn} {
+		if v.String() == string(b) {
+			*m = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown stale metadata mode %q", b)
+}
@@ -0,0 +1,397 @@
+package aaguids
+
+import (
+	"iter"
+	"slices"
+	"time"
+)
+
+// parseISODate parses the effective dates used in MDS status reports with ParseISO8601Date.
+func parseISODate(s string) (time.Time, bool) {
+	d, err := ParseISO8601Date(s)
+	return d.Time, err == nil
+}
+
+/*
+EffectiveTime returns the parsed EffectiveDate of r, date-only values as midnight UTC, or false if
+it is unset or neither a date nor an RFC 3339 timestamp (see ISO8601Date).
+*/
+func (r StatusReport) EffectiveTime() (time.Time, bool) {
+	if r.EffectiveDate == nil {
+		return time.Time{}, false
+	}
+	return parseISODate(*r.EffectiveDate)
+}
+
+/*
+AppliesToVersion reports whether r applies to a device running authenticatorVersion v: a status is
+reported for its AuthenticatorVersion and later firmware (FIDO Metadata Service § 3.1.4.2), so r
+applies if v is at least r.AuthenticatorVersion. UPDATE_AVAILABLE is the exception: it announces
+the firmware of its AuthenticatorVersion, so it applies to the devices still running an earlier
+version. A report without AuthenticatorVersion applies to every version, which errs on the safe side
+for the security statuses MDS does not always scope.
+*/
+func (r StatusReport) AppliesToVersion(v uint64) bool {
+	switch {
+	case r.AuthenticatorVersion == nil:
+		return true
+	case r.Status == UPDATE_AVAILABLE:
+		return v < *r.AuthenticatorVersion
+	default:
+		return *r.AuthenticatorVersion <= v
+	}
+}
+
+// remediatesForVersion reports whether r is an UPDATE_AVAILABLE whose firmware a device running
+// authenticatorVersion v already has.
+func (r StatusReport) remediatesForVersion(v uint64) bool {
+	return r.Status == UPDATE_AVAILABLE && r.AuthenticatorVersion != nil && *r.AuthenticatorVersion <= v
+}
+
+/*
+scopeToVersion removes from reports, which must be in timeline order, those that do not apply to
+authenticatorVersion v and the security issues remediated by a later UPDATE_AVAILABLE whose firmware
+v already has. It reuses the storage of reports.
+*/
+func scopeToVersion(reports []StatusReport, v uint64) []StatusReport {
+	remediates := func(u StatusReport) bool { return u.remediatesForVersion(v) }
+	kept := reports[:0]
+	for i, r := range reports {
+		if !r.AppliesToVersion(v) {
+			continue
+		}
+		if slices.Contains(securityIssueStatuses, r.Status) && slices.ContainsFunc(reports[i+1:], remediates) {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+/*
+StatusHistory returns a copy of e.StatusReports in timeline order: ascending effective date, with
+undated or unparseable reports treated as effective from the beginning of time. The BLOB is meant
+to list reports earliest to latest but does not always, so callers should use this rather than
+StatusReports; reports with equal dates keep their position in the BLOB. Only the slice is copied,
+and e.StatusReports is never reordered; the values its pointer fields reference are shared, and
+read-only like the rest of the dataset (see Clone).
+*/
+func (e Entry) StatusHistory() []StatusReport {
+	// Dates are parsed once up front rather than in every comparison.
+	times := make([]time.Time, len(e.StatusReports))
+	for i, r := range e.StatusReports {
+		times[i], _ = r.EffectiveTime()
+	}
+	if slices.IsSortedFunc(times, time.Time.Compare) {
+		return slices.Clone(e.StatusReports)
+	}
+	idx := make([]int, len(times))
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortStableFunc(idx, func(a, b int) int { return times[a].Compare(times[b]) })
+	reports := make([]StatusReport, len(idx))
+	for i, j := range idx {
+		reports[i] = e.StatusReports[j]
+	}
+	return reports
+}
+
+/*
+StatusInterval is the period in which one status report stated an entry's status, as returned by
+StatusTimeline:
+
+  - Status / Report: the status and the report stating it
+  - From: the report's effective date, zero for an undated report (since the beginning of time)
+  - To: the effective date of the next report; zero for the current status, and when the next
+    report is undated too
+  - Current: whether this is the last interval, stating the current status
+*/
+type StatusInterval struct {
+	Status  AuthenticatorStatus `json:"status"`
+	From    time.Time           `json:"from,omitzero"`
+	To      time.Time           `json:"to,omitzero"`
+	Current bool                `json:"current"`
+	Report  StatusReport        `json:"report"`
+}
+
+/*
+StatusTimeline returns one interval per report of StatusHistory, so a UI can render when each
+status applied; the last interval is the current status (see LatestStatus). Reports sharing an
+effective date yield intervals with To equal to From, kept so no report goes missing. It returns nil
+if e has no status reports. Provider.StatusTimeline caches the result per snapshot.
+*/
+func (e Entry) StatusTimeline() []StatusInterval {
+	reports := e.StatusHistory()
+	if len(reports) == 0 {
+		return nil
+	}
+	out := make([]StatusInterval, len(reports))
+	for i, r := range reports {
+		out[i] = StatusInterval{Status: r.Status, Report: r}
+		out[i].From, _ = r.EffectiveTime()
+		if i > 0 {
+			out[i-1].To = out[i].From
+		}
+	}
+	out[len(out)-1].Current = true
+	return out
+}
+
+// StatusReportsSeq yields the entry's status reports in timeline order (see StatusHistory). Breaking
+// out of the loop stops the iteration; the entry's own slice is never reordered.
+func (e Entry) StatusReportsSeq() iter.Seq[StatusReport] {
+	return func(yield func(StatusReport) bool) {
+		for _, r := range e.StatusHistory() {
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+/*
+LatestStatus returns the report that states e's current status: the last one in timeline order,
+or false if e has no status reports. Reports are ordered by effectiveDate; undated or unparseable
+dates count as the beginning of time, so any dated report supersedes them; reports with equal or
+missing dates fall back to their position in StatusReports, which the specification orders
+earliest to latest, and the later one wins.
+*/
+func (e Entry) LatestStatus() (StatusReport, bool) {
+	reports := e.StatusHistory()
+	if len(reports) == 0 {
+		return StatusReport{}, false
+	}
+	return reports[len(reports)-1], true
+}
+
+/*
+StatusAt returns the report that stated e's status at t, e.g. when a credential was registered: the
+last one in timeline order effective on or before t, including reports effective exactly at t. An
+undated report counts as effective from the beginning of time, as in LatestStatus. It reports false
+if t precedes every report, or if e has none. AsOf applies the same rule to a policy evaluation.
+*/
+func (e Entry) StatusAt(t time.Time) (StatusReport, bool) {
+	reports := e.StatusHistory()
+	for i := len(reports) - 1; i >= 0; i-- {
+		if eff, ok := reports[i].EffectiveTime(); !ok || !eff.After(t) {
+			return reports[i], true
+		}
+	}
+	return StatusReport{}, false
+}
+
+// CurrentStatus returns the status of LatestStatus, or "" if e has no status reports.
+func (e Entry) CurrentStatus() AuthenticatorStatus {
+	r, _ := e.LatestStatus()
+	return r.Status
+}
+
+/*
+EffectiveStatusForVersion is CurrentStatus for a device running authenticatorVersion v: the status
+of the last report in timeline order that applies to v (see AppliesToVersion) and is not a security
+issue remediated by a later UPDATE_AVAILABLE v already has, or "" if there is none. A compromise
+reported for version 2 and an UPDATE_AVAILABLE for version 5 thus leave devices below version 5
+UPDATE_AVAILABLE (those from version 2 on also with a security issue, see
+HasSecurityIssueForVersion) and a version 5 one with the status it had before the compromise.
+Reports without AuthenticatorVersion apply to every device, so an unscoped compromise is never
+filtered away. This is the filtering WithAuthenticatorVersion applies to a policy evaluation.
+*/
+func (e Entry) EffectiveStatusForVersion(v uint64) AuthenticatorStatus {
+	reports := scopeToVersion(e.StatusHistory(), v)
+	if len(reports) == 0 {
+		return ""
+	}
+	return reports[len(reports)-1].Status
+}
+
+// isCertificationLevel reports whether s is FIDO_CERTIFIED or one of its leveled successors.
+func isCertificationLevel(s AuthenticatorStatus) bool {
+	switch s {
+	case FIDO_CERTIFIED, FIDO_CERTIFIED_L1, FIDO_CERTIFIED_L1plus, FIDO_CERTIFIED_L2,
+		FIDO_CERTIFIED_L2plus, FIDO_CERTIFIED_L3, FIDO_CERTIFIED_L3plus:
+		return true
+	}
+	return false
+}
+
+// latestCertification returns the most recent FIDO_CERTIFIED* report in timeline order.
+func (e Entry) latestCertification() (StatusReport, bool) {
+	reports := e.StatusHistory()
+	for i := len(reports) - 1; i >= 0; i-- {
+		if isCertificationLevel(reports[i].Status) {
+			return reports[i], true
+		}
+	}
+	return StatusReport{}, false
+}
+
+// CurrentStatusIs reports whether the latest status report of e, in timeline order, has any of the
+// given statuses. An entry without status reports matches nothing.
+func (e Entry) CurrentStatusIs(statuses ...AuthenticatorStatus) bool {
+	r, ok := e.LatestStatus()
+	if !ok {
+		return false
+	}
+	for _, s := range statuses {
+		if r.Status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// HasEverHadStatus reports whether any status report of e, current or superseded, has status.
+func (e Entry) HasEverHadStatus(status AuthenticatorStatus) bool {
+	for _, r := range e.StatusReports {
+		if r.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+StatusSince returns when the current run of status began: starting from the latest report in
+timeline order, it walks backwards over consecutive reports with that status and returns the
+effective date of the earliest one. It reports false if the latest report does not have status.
+
+If the earliest report of the run is undated, the run is treated as effective from the beginning of
+time and the zero time is returned together with true.
+*/
+func (e Entry) StatusSince(status AuthenticatorStatus) (time.Time, bool) {
+	reports := e.StatusHistory()
+	i := len(reports) - 1
+	if i < 0 || reports[i].Status != status {
+		return time.Time{}, false
+	}
+	for i > 0 && reports[i-1].Status == status {
+		i--
+	}
+	t, _ := reports[i].EffectiveTime()
+	return t, true
+}
+
+/*
+IsRevoked reports whether e's certification is revoked: a REVOKED report appears in its timeline
+and no FIDO_CERTIFIED* report follows it, so a model certified again after a revocation is not
+revoked. Later reports of other kinds, such as UPDATE_AVAILABLE, do not lift a revocation.
+*/
+func (e Entry) IsRevoked() bool {
+	revoked := false
+	for _, r := range e.StatusHistory() {
+		switch {
+		case r.Status == REVOKED:
+			revoked = true
+		case isCertificationLevel(r.Status):
+			revoked = false
+		}
+	}
+	return revoked
+}
+
+// securityIssueStatuses are the statuses reporting a security issue of the model, see
+// HasSecurityIssue.
+var securityIssueStatuses = []AuthenticatorStatus{
+	USER_VERIFICATION_BYPASS,
+	ATTESTATION_KEY_COMPROMISE,
+	USER_KEY_REMOTE_COMPROMISE,
+	USER_KEY_PHYSICAL_COMPROMISE,
+}
+
+/*
+HasSecurityIssue reports whether e's status history, current or superseded, contains any of
+USER_VERIFICATION_BYPASS, ATTESTATION_KEY_COMPROMISE, USER_KEY_REMOTE_COMPROMISE or
+USER_KEY_PHYSICAL_COMPROMISE. Without the firmware version of the user's device there is no telling
+whether a later UPDATE_AVAILABLE was applied, so every issue counts; use
+HasSecurityIssueForVersion when the version is known.
+*/
+func (e Entry) HasSecurityIssue() bool {
+	return slices.ContainsFunc(e.StatusReports, func(r StatusReport) bool {
+		return slices.Contains(securityIssueStatuses, r.Status)
+	})
+}
+
+/*
+HasSecurityIssueForVersion is HasSecurityIssue for a device running authenticatorVersion v (FIDO
+Metadata Service § 3.1.4.2), with the version scoping of WithAuthenticatorVersion:
+
+  - an issue reported for an authenticatorVersion above v is about later firmware and ignored
+  - an issue is remediated by an UPDATE_AVAILABLE report later in timeline order whose
+    authenticatorVersion is at most v, i.e. the device already runs the fixed firmware; an
+    UPDATE_AVAILABLE without authenticatorVersion remediates nothing
+
+It reports true if any issue is neither ignored nor remediated.
+*/
+func (e Entry) HasSecurityIssueForVersion(v uint64) bool {
+	return slices.ContainsFunc(scopeToVersion(e.StatusHistory(), v), func(r StatusReport) bool {
+		return slices.Contains(securityIssueStatuses, r.Status)
+	})
+}
+
+// EntriesWithStatus returns the embedded entries whose latest status is status. See
+// Provider.EntriesWithStatus.
+func EntriesWithStatus(status AuthenticatorStatus) []Entry {
+	return Default().EntriesWithStatus(status)
+}
+
+/*
+EntriesWithStatus returns the entries whose latest status report has status, in ascending key
+order, e.g. everything currently REVOKED. The latest report is the last one in timeline order, not
+the last element of StatusReports:
+
+  - reports are ordered by effectiveDate
+  - undated or unparseable dates count as the beginning of time, so any dated report supersedes them
+  - reports with the same date keep their BLOB order, and the later one wins
+*/
+func (p *Provider) EntriesWithStatus(status AuthenticatorStatus) []Entry {
+	return slices.Collect(p.Where(func(e Entry) bool { return e.CurrentStatusIs(status) }))
+}
+
+// EntriesEverHavingStatus returns the embedded entries that ever had status. See
+// Provider.EntriesEverHavingStatus.
+func EntriesEverHavingStatus(status AuthenticatorStatus) []Entry {
+	return Default().EntriesEverHavingStatus(status)
+}
+
+// EntriesEverHavingStatus returns the entries with any status report of status, current or
+// superseded, in ascending key order. Use it for compromises, which are often followed by
+// UPDATE_AVAILABLE or recertification reports that EntriesWithStatus would see instead.
+func (p *Provider) EntriesEverHavingStatus(status AuthenticatorStatus) []Entry {
+	return slices.Collect(p.Where(func(e Entry) bool { return e.HasEverHadStatus(status) }))
+}
+
+// EntriesUpdatedSince returns the embedded entries whose status changed after t. See
+// Provider.EntriesUpdatedSince.
+func EntriesUpdatedSince(t time.Time) []Entry {
+	return Default().EntriesUpdatedSince(t)
+}
+
+/*
+EntriesUpdatedSince returns the entries whose timeOfLastStatusChange is strictly after t, in
+ascending key order, for incremental mirroring. MDS publishes date-only values ("2024-05-01"),
+which carry no time of day, so such a value stands for the whole UTC day: it is after t unless t is
+at or past the following midnight. A sync at any time on 2024-05-01 therefore picks up that day's
+changes again on the next sync, rather than missing the ones made later that day. Full RFC 3339
+timestamps are compared exactly.
+
+Entries with an empty or unparseable timeOfLastStatusChange are always included, so that a
+malformed upstream value never hides a change.
+*/
+func (p *Provider) EntriesUpdatedSince(t time.Time) []Entry {
+	return slices.Collect(p.Where(func(e Entry) bool { return statusChangedAfter(e.TimeOfLastStatusChange, t) }))
+}
+
+// statusChangedAfter reports whether the timeOfLastStatusChange value s is after t; see
+// EntriesUpdatedSince.
+func statusChangedAfter(s string, t time.Time) bool {
+	d, err := ParseISO8601Date(s)
+	switch {
+	case err != nil:
+		return true
+	case d.DateOnly:
+		return t.Before(d.AddDate(0, 0, 1))
+	default:
+		return d.After(t)
+	}
+}
@@ -0,0 +1,369 @@
+package aaguids
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// report returns a status report effective on date, or undated when date is "".
+func report(status AuthenticatorStatus, date string) StatusReport {
+	r := StatusReport{Status: status}
+	if date != "" {
+		r.EffectiveDate = &date
+	}
+	return r
+}
+
+// day parses a YYYY-MM-DD date, or returns the zero time for "".
+func day(t *testing.T, s string) time.Time {
+	t.Helper()
+	if s == "" {
+		return time.Time{}
+	}
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestStatusPredicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports []StatusReport
+		current AuthenticatorStatus // "" when there is none
+		ever    []AuthenticatorStatus
+		since   string // start of the current run; "" for the beginning of time
+	}{
+		{name: "no reports"},
+		{
+			name:    "single report",
+			reports: []StatusReport{report(FIDO_CERTIFIED_L1, "2020-01-01")},
+			current: FIDO_CERTIFIED_L1, ever: []AuthenticatorStatus{FIDO_CERTIFIED_L1}, since: "2020-01-01",
+		},
+		{
+			name: "repeated status extends the run",
+			reports: []StatusReport{
+				report(FIDO_CERTIFIED_L1, "2020-01-01"), report(FIDO_CERTIFIED_L1, "2021-01-01"), report(FIDO_CERTIFIED_L1, "2022-01-01"),
+			},
+			current: FIDO_CERTIFIED_L1, ever: []AuthenticatorStatus{FIDO_CERTIFIED_L1}, since: "2020-01-01",
+		},
+		{
+			name: "interleaved notification breaks the run",
+			reports: []StatusReport{
+				report(FIDO_CERTIFIED_L1, "2020-01-01"), report(UPDATE_AVAILABLE, "2021-01-01"), report(FIDO_CERTIFIED_L1, "2022-01-01"),
+			},
+			current: FIDO_CERTIFIED_L1, ever: []AuthenticatorStatus{FIDO_CERTIFIED_L1, UPDATE_AVAILABLE}, since: "2022-01-01",
+		},
+		{
+			name: "notification run after certification",
+			reports: []StatusReport{
+				report(FIDO_CERTIFIED_L1, "2020-01-01"), report(UPDATE_AVAILABLE, "2021-01-01"), report(UPDATE_AVAILABLE, "2021-06-01"),
+			},
+			current: UPDATE_AVAILABLE, ever: []AuthenticatorStatus{FIDO_CERTIFIED_L1, UPDATE_AVAILABLE}, since: "2021-01-01",
+		},
+		{
+			name: "BLOB order differs from timeline order",
+			reports: []StatusReport{
+				report(FIDO_CERTIFIED_L1, "2022-01-01"), report(UPDATE_AVAILABLE, "2021-01-01"), report(FIDO_CERTIFIED_L1, "2020-01-01"),
+			},
+			current: FIDO_CERTIFIED_L1, ever: []AuthenticatorStatus{FIDO_CERTIFIED_L1, UPDATE_AVAILABLE}, since: "2022-01-01",
+		},
+		{
+			name: "repeated revocations around a notification",
+			reports: []StatusReport{
+				report(FIDO_CERTIFIED_L2, "2019-01-01"), report(REVOKED, "2020-01-01"),
+				report(UPDATE_AVAILABLE, "2021-01-01"), report(REVOKED, "2022-01-01"), report(REVOKED, "2022-02-01"),
+			},
+			current: REVOKED, ever: []AuthenticatorStatus{FIDO_CERTIFIED_L2, REVOKED, UPDATE_AVAILABLE}, since: "2022-01-01",
+		},
+		{
+			name:    "undated start of the run",
+			reports: []StatusReport{report(NOT_FIDO_CERTIFIED, ""), report(NOT_FIDO_CERTIFIED, "2021-01-01")},
+			current: NOT_FIDO_CERTIFIED, ever: []AuthenticatorStatus{NOT_FIDO_CERTIFIED}, since: "",
+		},
+		{
+			name:    "unparseable date counts as the beginning of time",
+			reports: []StatusReport{report(FIDO_CERTIFIED_L1, "2021-01-01"), report(REVOKED, "2021-13-45")},
+			current: FIDO_CERTIFIED_L1, ever: []AuthenticatorStatus{FIDO_CERTIFIED_L1, REVOKED}, since: "2021-01-01",
+		},
+		{
+			name: "same date keeps BLOB order",
+			reports: []StatusReport{
+				report(FIDO_CERTIFIED_L1, "2020-01-01"), report(FIDO_CERTIFIED_L1, "2021-01-01"), report(USER_KEY_PHYSICAL_COMPROMISE, "2021-01-01"),
+			},
+			current: USER_KEY_PHYSICAL_COMPROMISE, ever: []AuthenticatorStatus{FIDO_CERTIFIED_L1, USER_KEY_PHYSICAL_COMPROMISE}, since: "2021-01-01",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Entry{StatusReports: tt.reports}
+			for _, info := range AllStatusInfo() {
+				s := info.Status
+				if got, want := e.CurrentStatusIs(s), s == tt.current; got != want {
+					t.Errorf("CurrentStatusIs(%s) = %v, want %v", s, got, want)
+				}
+				if got, want := e.HasEverHadStatus(s), slices.Contains(tt.ever, s); got != want {
+					t.Errorf("HasEverHadStatus(%s) = %v, want %v", s, got, want)
+				}
+				since, ok := e.StatusSince(s)
+				if ok != (s == tt.current) {
+					t.Errorf("StatusSince(%s) reports %v", s, ok)
+				} else if ok && !since.Equal(day(t, tt.since)) {
+					t.Errorf("StatusSince(%s) = %s, want %s", s, since.Format(time.DateOnly), tt.since)
+				}
+			}
+			if e.CurrentStatusIs() {
+				t.Error("CurrentStatusIs() with no statuses matched")
+			}
+			if tt.current != "" && !e.CurrentStatusIs(SELF_ASSERTION_SUBMITTED, tt.current) {
+				t.Errorf("CurrentStatusIs(SELF_ASSERTION_SUBMITTED, %s) = false, want a match on any", tt.current)
+			}
+		})
+	}
+}
+
+// versioned returns r scoped to authenticatorVersion v.
+func versioned(r StatusReport, v uint64) StatusReport {
+	r.AuthenticatorVersion = &v
+	return r
+}
+
+func TestAppliesToVersion(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		report  StatusReport
+		applies []uint64
+		not     []uint64
+	}{
+		{"compromise from version 2", versioned(report(USER_VERIFICATION_BYPASS, ""), 2), []uint64{2, 3, 100}, []uint64{0, 1}},
+		{"certification from version 2", versioned(report(FIDO_CERTIFIED_L1, ""), 2), []uint64{2, 3}, []uint64{1}},
+		{"update to version 5", versioned(report(UPDATE_AVAILABLE, ""), 5), []uint64{0, 4}, []uint64{5, 6, 100}},
+		{"unscoped compromise", report(USER_VERIFICATION_BYPASS, ""), []uint64{0, 5, 100}, nil},
+		{"unscoped update", report(UPDATE_AVAILABLE, ""), []uint64{0, 5, 100}, nil},
+	} {
+		for _, v := range tt.applies {
+			if !tt.report.AppliesToVersion(v) {
+				t.Errorf("%s: does not apply to version %d", tt.name, v)
+			}
+		}
+		for _, v := range tt.not {
+			if tt.report.AppliesToVersion(v) {
+				t.Errorf("%s: applies to version %d", tt.name, v)
+			}
+		}
+	}
+}
+
+func TestStatusForVersion(t *testing.T) {
+	certified := report(FIDO_CERTIFIED_L1, "2020-01-01")
+	bypass := versioned(report(USER_VERIFICATION_BYPASS, "2021-01-01"), 2)
+	update := versioned(report(UPDATE_AVAILABLE, "2022-01-01"), 5)
+	for _, tt := range []struct {
+		name    string
+		reports []StatusReport
+		status  []AuthenticatorStatus // by authenticatorVersion, from 0
+		issue   []bool
+	}{
+		{
+			name:    "compromise fixed by an update",
+			reports: []StatusReport{certified, bypass, update},
+			// Below version 2 the device predates the bug but not the update, from version 5 on it
+			// runs the fix.
+			status: []AuthenticatorStatus{UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, FIDO_CERTIFIED_L1, FIDO_CERTIFIED_L1},
+			issue:  []bool{false, false, true, true, true, false, false},
+		},
+		{
+			name:    "BLOB order differs from timeline order",
+			reports: []StatusReport{update, bypass, certified},
+			status:  []AuthenticatorStatus{UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, FIDO_CERTIFIED_L1, FIDO_CERTIFIED_L1},
+			issue:   []bool{false, false, true, true, true, false, false},
+		},
+		{
+			name:    "update without a compromise",
+			reports: []StatusReport{certified, update},
+			status:  []AuthenticatorStatus{UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, FIDO_CERTIFIED_L1, FIDO_CERTIFIED_L1},
+			issue:   []bool{false, false, false, false, false, false, false},
+		},
+		{
+			name:    "unscoped update remediates nothing",
+			reports: []StatusReport{certified, bypass, report(UPDATE_AVAILABLE, "2022-01-01")},
+			status:  []AuthenticatorStatus{UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE},
+			issue:   []bool{false, false, true, true, true, true, true},
+		},
+		{
+			name:    "unscoped compromise after the update",
+			reports: []StatusReport{certified, update, report(USER_KEY_REMOTE_COMPROMISE, "2023-01-01")},
+			status:  []AuthenticatorStatus{USER_KEY_REMOTE_COMPROMISE, USER_KEY_REMOTE_COMPROMISE, USER_KEY_REMOTE_COMPROMISE, USER_KEY_REMOTE_COMPROMISE, USER_KEY_REMOTE_COMPROMISE, USER_KEY_REMOTE_COMPROMISE, USER_KEY_REMOTE_COMPROMISE},
+			issue:   []bool{true, true, true, true, true, true, true},
+		},
+		{
+			name:    "update only",
+			reports: []StatusReport{update},
+			status:  []AuthenticatorStatus{UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, "", ""},
+			issue:   []bool{false, false, false, false, false, false, false},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Entry{AAGUID: testYubiKey, StatusReports: tt.reports}
+			p := NewProvider(map[string]Entry{testYubiKey: e}, Dataset{})
+			for v, want := range tt.status {
+				if got := e.EffectiveStatusForVersion(uint64(v)); got != want {
+					t.Errorf("EffectiveStatusForVersion(%d) = %q, want %q", v, got, want)
+				}
+				if got := e.HasSecurityIssueForVersion(uint64(v)); got != tt.issue[v] {
+					t.Errorf("HasSecurityIssueForVersion(%d) = %v, want %v", v, got, tt.issue[v])
+				}
+				// A policy evaluation scoped to the version sees the same status, and the default
+				// deny statuses reject exactly the versions with a security issue.
+				version := WithAuthenticatorVersion(uint64(v))
+				if d := p.TrustDecision(Policy{DenyStatuses: []AuthenticatorStatus{}}, testYubiKey, version); d.Status != want {
+					t.Errorf("TrustDecision with version %d: status %q, want %q", v, d.Status, want)
+				}
+				if d := p.TrustDecision(Policy{}, testYubiKey, version); d.Allowed == tt.issue[v] {
+					t.Errorf("TrustDecision with version %d: allowed %v (%s), security issue %v", v, d.Allowed, d.Reason, tt.issue[v])
+				}
+			}
+		})
+	}
+}
+
+// numbered returns r with CertificateNumber set to n, to tell reports apart in results.
+func numbered(r StatusReport, n string) StatusReport {
+	r.CertificateNumber = &n
+	return r
+}
+
+func TestStatusAt(t *testing.T) {
+	dated := Entry{StatusReports: []StatusReport{
+		numbered(report(FIDO_CERTIFIED_L2, "2022-06-01"), "recertified"),
+		numbered(report(FIDO_CERTIFIED_L1, "2020-01-01"), "initial"),
+		numbered(report(REVOKED, "2024-03-15"), "revoked"),
+	}}
+	undated := Entry{StatusReports: []StatusReport{
+		numbered(report(NOT_FIDO_CERTIFIED, ""), "undated"),
+		numbered(report(FIDO_CERTIFIED_L1, "2020-01-01"), "initial"),
+	}}
+	tests := []struct {
+		name  string
+		entry Entry
+		at    string
+		want  string // "" when none is in effect
+	}{
+		{"before the first report", dated, "2019-12-31", ""},
+		{"on the first effective date", dated, "2020-01-01", "initial"},
+		{"between reports", dated, "2021-07-04", "initial"},
+		{"on a later effective date", dated, "2022-06-01", "recertified"},
+		{"the day before a report", dated, "2024-03-14", "recertified"},
+		{"after the last report", dated, "2030-01-01", "revoked"},
+		{"undated before every dated one", undated, "1999-01-01", "undated"},
+		{"dated supersedes undated", undated, "2020-01-01", "initial"},
+		{"no reports", Entry{}, "2030-01-01", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := day(t, tt.at)
+			r, ok := tt.entry.StatusAt(at)
+			got := ""
+			if ok {
+				got = *r.CertificateNumber
+			}
+			if got != tt.want {
+				t.Errorf("StatusAt(%s) = %q, want %q", tt.at, got, tt.want)
+			}
+			// A date-only report is in effect for its whole day, and not a nanosecond before it.
+			if r2, ok2 := tt.entry.StatusAt(at.Add(24*time.Hour - time.Nanosecond)); ok2 != ok || (ok && *r2.CertificateNumber != got) {
+				t.Errorf("StatusAt(%s 23:59:59.999999999) differs from midnight", tt.at)
+			}
+		})
+	}
+	if _, ok := dated.StatusAt(day(t, "2020-01-01").Add(-time.Nanosecond)); ok {
+		t.Error("StatusAt just before the first report found one")
+	}
+}
+
+func TestSameDayStatusBoundaries(t *testing.T) {
+	// Two reports share an effective date: the later one in the BLOB wins from that day on.
+	e := Entry{StatusReports: []StatusReport{
+		numbered(report(FIDO_CERTIFIED_L1, "2020-01-01"), "initial"),
+		numbered(versioned(report(USER_VERIFICATION_BYPASS, "2023-05-10"), 2), "bypass"),
+		numbered(versioned(report(UPDATE_AVAILABLE, "2023-05-10"), 5), "update"),
+	}}
+	for _, tt := range []struct {
+		at   string
+		want string
+	}{
+		{"2023-05-09", "initial"},
+		{"2023-05-10", "update"},
+		{"2023-05-11", "update"},
+	} {
+		if r, ok := e.StatusAt(day(t, tt.at)); !ok || *r.CertificateNumber != tt.want {
+			t.Errorf("StatusAt(%s) = %v, want %q", tt.at, r.CertificateNumber, tt.want)
+		}
+	}
+	// The same-day update still remediates the bypass, since it follows it in the BLOB.
+	for v, want := range []AuthenticatorStatus{UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, UPDATE_AVAILABLE, FIDO_CERTIFIED_L1} {
+		if got := e.EffectiveStatusForVersion(uint64(v)); got != want {
+			t.Errorf("EffectiveStatusForVersion(%d) = %q, want %q", v, got, want)
+		}
+	}
+	if !e.HasSecurityIssueForVersion(2) || e.HasSecurityIssueForVersion(5) {
+		t.Error("same-day update does not scope the bypass to versions 2 through 4")
+	}
+
+	// In the other BLOB order the bypass is the later report and nothing remediates it.
+	swapped := Entry{StatusReports: []StatusReport{e.StatusReports[0], e.StatusReports[2], e.StatusReports[1]}}
+	if r, _ := swapped.StatusAt(day(t, "2023-05-10")); *r.CertificateNumber != "bypass" {
+		t.Errorf("StatusAt(2023-05-10) = %q with the bypass last, want it", *r.CertificateNumber)
+	}
+	if got := swapped.EffectiveStatusForVersion(5); got != USER_VERIFICATION_BYPASS {
+		t.Errorf("EffectiveStatusForVersion(5) = %q with the bypass last, want USER_VERIFICATION_BYPASS", got)
+	}
+}
+
+func TestEntriesUpdatedSince(t *testing.T) {
+	entry := func(aaguid, changed string) Entry {
+		return Entry{AAGUID: aaguid, TimeOfLastStatusChange: changed}
+	}
+	p := testProvider(t,
+		entry(testYubiKey, "2024-05-01"),
+		entry(testGPM, "2024-05-01T12:00:00Z"),
+		entry(testYubiKey54, ""),
+		entry(testYubiKey57, "May 1st"),
+		entry(testWindowsHello, "2024-04-30"),
+	)
+	at := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	tests := []struct {
+		name  string
+		since time.Time
+		want  []string
+	}{
+		{"day before", at("2024-04-30T00:00:00Z"), []string{testYubiKey, testGPM, testYubiKey54, testYubiKey57, testWindowsHello}},
+		{"midnight of the change day", at("2024-05-01T00:00:00Z"), []string{testYubiKey, testGPM, testYubiKey54, testYubiKey57}},
+		{"exactly at the timestamp", at("2024-05-01T12:00:00Z"), []string{testYubiKey, testYubiKey54, testYubiKey57}},
+		{"just before the timestamp", at("2024-05-01T11:59:59.999Z"), []string{testYubiKey, testGPM, testYubiKey54, testYubiKey57}},
+		{"last instant of the change day", at("2024-05-01T23:59:59.999999999Z"), []string{testYubiKey, testYubiKey54, testYubiKey57}},
+		{"next midnight", at("2024-05-02T00:00:00Z"), []string{testYubiKey54, testYubiKey57}},
+		{"date-only in another zone", at("2024-05-01T20:00:00-05:00"), []string{testYubiKey54, testYubiKey57}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range p.EntriesUpdatedSince(tt.since) {
+				got = append(got, e.AAGUID)
+			}
+			slices.Sort(got)
+			want := slices.Sorted(slices.Values(tt.want))
+			if !slices.Equal(got, want) {
+				t.Errorf("EntriesUpdatedSince(%s) = %q, want %q", tt.since, got, want)
+			}
+		})
+	}
+}
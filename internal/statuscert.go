@@ -0,0 +1,74 @@
+package aaguids
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+/*
+ParsedCertificate decodes r.Certificate, the certificate of an ATTESTATION_KEY_COMPROMISE report
+identifying the compromised batch. MDS publishes it as standard base64 DER; URL-safe base64, with
+or without padding, and a PEM "CERTIFICATE" block are accepted too. It returns nil, nil if the
+report has no certificate. Nothing is cached: every call decodes again.
+*/
+func (r StatusReport) ParsedCertificate() (*x509.Certificate, error) {
+	if r.Certificate == nil || strings.TrimSpace(*r.Certificate) == "" {
+		return nil, nil
+	}
+	der, err := decodeCertificateDER(*r.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("decode status report certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse status report certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// decodeCertificateDER returns the DER bytes of s, a certificate in standard or URL-safe base64,
+// padded or not and possibly broken into lines, or in a PEM "CERTIFICATE" block.
+func decodeCertificateDER(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-----BEGIN") {
+		block, _ := pem.Decode([]byte(s))
+		switch {
+		case block == nil:
+			return nil, errors.New("malformed PEM block")
+		case block.Type != "CERTIFICATE":
+			return nil, fmt.Errorf("PEM block of type %q, not CERTIFICATE", block.Type)
+		}
+		return block.Bytes, nil
+	}
+	s = strings.Join(strings.Fields(s), "")
+	return base64RawURIDecode(strings.TrimRight(s, "="))
+}
+
+/*
+CompromisedAttestationCertificates returns the certificates of e's ATTESTATION_KEY_COMPROMISE
+reports, in timeline order and without duplicates, so a relying party can compare them against a
+presented attestation chain; WithAttestationCertificate does so during policy evaluation. Reports
+without a certificate, which mean every batch is affected, and certificates that do not parse are
+left out. It returns nil if there are none.
+*/
+func (e Entry) CompromisedAttestationCertificates() []*x509.Certificate {
+	var out []*x509.Certificate
+	for _, r := range e.StatusHistory() {
+		if r.Status != ATTESTATION_KEY_COMPROMISE {
+			continue
+		}
+		cert, err := r.ParsedCertificate()
+		if err != nil || cert == nil {
+			continue
+		}
+		if !slices.ContainsFunc(out, func(c *x509.Certificate) bool { return bytes.Equal(c.Raw, cert.Raw) }) {
+			out = append(out, cert)
+		}
+	}
+	return out
+}
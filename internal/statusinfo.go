@@ -0,0 +1,158 @@
+package aaguids
+
+import "fmt"
+
+// StatusCategory groups the AuthenticatorStatus values as the subsections of MDS § 3.1.4 do.
+type StatusCategory string
+
+const (
+	// CategoryCertification covers § 3.1.4.1 “Certification Related Statuses”.
+	CategoryCertification StatusCategory = "certification"
+	// CategorySecurityNotification covers § 3.1.4.2 “Security Notification Statuses”.
+	CategorySecurityNotification StatusCategory = "security_notification"
+	// CategoryInfo covers § 3.1.4.3 “Info Statuses”.
+	CategoryInfo StatusCategory = "info"
+)
+
+// StatusSeverity ranks how urgently a status calls for action by a relying party; higher is more
+// urgent.
+type StatusSeverity int
+
+const (
+	// SeverityNone: nothing to act on (the FIDO_CERTIFIED* levels).
+	SeverityNone StatusSeverity = iota
+	// SeverityInfo: worth knowing, no action needed (e.g. NOT_FIDO_CERTIFIED).
+	SeverityInfo
+	// SeverityWarning: action recommended (UPDATE_AVAILABLE, and statuses this package does not know).
+	SeverityWarning
+	// SeverityCritical: the authenticator should not be trusted (REVOKED and security notifications).
+	SeverityCritical
+)
+
+// String returns "none", "info", "warning" or "critical".
+func (s StatusSeverity) String() string {
+	switch s {
+	case SeverityNone:
+		return "none"
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	}
+	return fmt.Sprintf("StatusSeverity(%d)", int(s))
+}
+
+// MarshalText encodes the severity as its String form.
+func (s StatusSeverity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+/*
+StatusMetadata describes one AuthenticatorStatus for documentation and UI copy:
+
+  - Status: the status value
+  - Label: a short human-readable name, e.g. "FIDO Certified L1"
+  - Category / Severity: see StatusCategory and StatusSeverity
+  - SpecSection: the MDS section defining the status, e.g. "3.1.4.2"
+  - Fields: the JSON names of the optional StatusReport fields that are meaningful for it
+  - Description: one sentence on what the status means
+*/
+type StatusMetadata struct {
+	Status      AuthenticatorStatus `json:"status"`
+	Label       string              `json:"label"`
+	Category    StatusCategory      `json:"category"`
+	Severity    StatusSeverity      `json:"severity"`
+	SpecSection string              `json:"specSection"`
+	Fields      []string            `json:"fields"`
+	Description string              `json:"description"`
+}
+
+// Meaningful StatusReport fields shared by several statuses.
+var (
+	basicStatusFields         = []string{"effectiveDate", "authenticatorVersion", "url"}
+	certificationStatusFields = []string{"effectiveDate", "authenticatorVersion", "certificationDescriptor",
+		"certificateNumber", "certificationPolicyVersion", "certificationRequirementsVersion", "url"}
+)
+
+// statusTable is the single source of StatusInfo, AllStatusInfo, Label, Describe and Severity, in
+// the order the statuses are declared.
+var statusTable = []StatusMetadata{
+	{NOT_FIDO_CERTIFIED, "Not FIDO Certified", CategoryCertification, SeverityInfo, "3.1.4.1", basicStatusFields,
+		"The authenticator is not FIDO certified."},
+	{FIDO_CERTIFIED, "FIDO Certified", CategoryCertification, SeverityNone, "3.1.4.1", certificationStatusFields,
+		"The authenticator passed FIDO functional certification; superseded by FIDO_CERTIFIED_L1 in newer programs."},
+	{USER_VERIFICATION_BYPASS, "User Verification Bypass", CategorySecurityNotification, SeverityCritical, "3.1.4.2", basicStatusFields,
+		"Malware or an exploit can bypass user verification, allowing use without the user's knowledge or consent."},
+	{ATTESTATION_KEY_COMPROMISE, "Attestation Key Compromise", CategorySecurityNotification, SeverityCritical, "3.1.4.2",
+		[]string{"effectiveDate", "authenticatorVersion", "certificate", "url"},
+		"The attestation key is compromised; the certificate field, if set, identifies the affected batch."},
+	{USER_KEY_REMOTE_COMPROMISE, "User Key Remote Compromise", CategorySecurityNotification, SeverityCritical, "3.1.4.2", basicStatusFields,
+		"Known weaknesses allow user credential keys to be compromised remotely."},
+	{USER_KEY_PHYSICAL_COMPROMISE, "User Key Physical Compromise", CategorySecurityNotification, SeverityCritical, "3.1.4.2", basicStatusFields,
+		"An attacker in physical possession of the authenticator can extract user keys."},
+	{UPDATE_AVAILABLE, "Update Available", CategoryInfo, SeverityWarning, "3.1.4.3", basicStatusFields,
+		"A software or firmware update is available; authenticatorVersion is the updated version."},
+	{REVOKED, "Revoked", CategoryCertification, SeverityCritical, "3.1.4.1", basicStatusFields,
+		"The FIDO Alliance determined the authenticator is not trustworthy, e.g. fraudulent or backdoored."},
+	{SELF_ASSERTION_SUBMITTED, "Self-Assertion Submitted", CategoryCertification, SeverityInfo, "3.1.4.1", basicStatusFields,
+		"The vendor submitted the self-certification checklist to the FIDO Alliance."},
+	{FIDO_CERTIFIED_L1, "FIDO Certified L1", CategoryCertification, SeverityNone, "3.1.4.1", certificationStatusFields,
+		"The authenticator passed FIDO certification at level 1."},
+	{FIDO_CERTIFIED_L1plus, "FIDO Certified L1+", CategoryCertification, SeverityNone, "3.1.4.1", certificationStatusFields,
+		"The authenticator passed FIDO certification at level 1+, stricter than level 1."},
+	{FIDO_CERTIFIED_L2, "FIDO Certified L2", CategoryCertification, SeverityNone, "3.1.4.1", certificationStatusFields,
+		"The authenticator passed FIDO certification at level 2, stricter than level 1+."},
+	{FIDO_CERTIFIED_L2plus, "FIDO Certified L2+", CategoryCertification, SeverityNone, "3.1.4.1", certificationStatusFields,
+		"The authenticator passed FIDO certification at level 2+, stricter than level 2."},
+	{FIDO_CERTIFIED_L3, "FIDO Certified L3", CategoryCertification, SeverityNone, "3.1.4.1", certificationStatusFields,
+		"The authenticator passed FIDO certification at level 3, stricter than level 2+."},
+	{FIDO_CERTIFIED_L3plus, "FIDO Certified L3+", CategoryCertification, SeverityNone, "3.1.4.1", certificationStatusFields,
+		"The authenticator passed FIDO certification at level 3+, stricter than level 3."},
+}
+
+// StatusInfo returns the metadata of s, or false for a status this package does not know.
+func StatusInfo(s AuthenticatorStatus) (StatusMetadata, bool) {
+	for _, m := range statusTable {
+		if m.Status == s {
+			m.Fields = append([]string(nil), m.Fields...)
+			return m, true
+		}
+	}
+	return StatusMetadata{}, false
+}
+
+// AllStatusInfo returns the metadata of every known status, in declaration order. It encodes to
+// JSON as is.
+func AllStatusInfo() []StatusMetadata {
+	out := make([]StatusMetadata, len(statusTable))
+	for i, m := range statusTable {
+		m.Fields = append([]string(nil), m.Fields...)
+		out[i] = m
+	}
+	return out
+}
+
+// Label returns the short human-readable name of s, or s itself if it is not known.
+func (s AuthenticatorStatus) Label() string {
+	if m, ok := StatusInfo(s); ok {
+		return m.Label
+	}
+	return string(s)
+}
+
+// Describe returns the one-sentence description of s, or "" if it is not known.
+func (s AuthenticatorStatus) Describe() string {
+	m, _ := StatusInfo(s)
+	return m.Description
+}
+
+// Severity returns the severity of s; statuses this package does not know are SeverityWarning, so
+// new upstream values get looked at rather than ignored.
+func (s AuthenticatorStatus) Severity() StatusSeverity {
+	if m, ok := StatusInfo(s); ok {
+		return m.Severity
+	}
+	return SeverityWarning
+}
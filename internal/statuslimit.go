@@ -0,0 +1,156 @@
+package aaguids
+
+import (
+	"fmt"
+	"slices"
+)
+
+/*
+DefaultMaxStatusReports is the number of status reports an entry keeps by default when parsed by
+ParseMetadataBLOB or merged by UpdateMerged. Real entries carry a handful; the limit only bounds
+the memory a malformed or hostile BLOB can make every lookup and timeline walk pay for.
+*/
+const DefaultMaxStatusReports = 100
+
+/*
+StatusTruncation records an entry whose status reports were cut down to a limit, see
+WithStatusReportLimit:
+
+  - Key: the entry's dataset key, see Entry.Key
+  - Total: how many reports the source listed
+  - Kept: how many reports the entry retains
+*/
+type StatusTruncation struct {
+	Key   string `json:"key"`
+	Total int    `json:"total"`
+	Kept  int    `json:"kept"`
+}
+
+// String formats t for a warning, e.g. "<key>: 10000 status reports truncated to 100".
+func (t StatusTruncation) String() string {
+	return fmt.Sprintf("%s: %d status reports truncated to %d", t.Key, t.Total, t.Kept)
+}
+
+/*
+WithStatusReportLimit makes ParseMetadataBLOB keep at most n status reports per entry instead of
+DefaultMaxStatusReports; n <= 0 keeps all of them. The earliest and the most recent reports are
+kept, with the latest certification and security reports in between, so LatestStatus,
+CertificationLevel, IsRevoked and HasSecurityIssue answer as for the full history. Truncated
+entries are listed in BLOBPayload.StatusTruncations; BLOBPayload.RawEntries keeps the upstream JSON
+in full.
+*/
+func WithStatusReportLimit(n int) ParseOption {
+	return func(c *parseConfig) { c.maxStatusReports = statusReportLimit(n) }
+}
+
+/*
+WithMergedStatusReportLimit makes UpdateMerged keep at most n status reports per merged entry
+instead of DefaultMaxStatusReports; n <= 0 keeps all of them. Reports are kept as
+WithStatusReportLimit keeps them, and every truncated entry is logged as a warning. Update and
+UpdateFromBLOB apply no limit of their own: entries passed to Update are the caller's, and
+ParseMetadataBLOB already limited those of a BLOB.
+*/
+func WithMergedStatusReportLimit(n int) ProviderOption {
+	return func(p *Provider) { p.maxStatusReports = statusReportLimit(n) }
+}
+
+// statusReportLimit maps the n of the limit options to the stored form, where 0 means
+// DefaultMaxStatusReports and a negative value means unlimited.
+func statusReportLimit(n int) int {
+	if n <= 0 {
+		return -1
+	}
+	return n
+}
+
+// effectiveStatusReportLimit resolves a stored limit, returning 0 for unlimited.
+func effectiveStatusReportLimit(stored int) int {
+	switch {
+	case stored == 0:
+		return DefaultMaxStatusReports
+	case stored < 0:
+		return 0
+	}
+	return stored
+}
+
+/*
+limitStatusReports returns e with at most limit status reports, and whether any were dropped. A
+limit of 0 keeps all. The reports kept, in timeline order (see StatusHistory), are:
+
+  - the earliest quarter of limit, rounded down, so the entry's first certification stays visible
+  - the most recent ones, including the current status, for the remaining slots
+  - among the dropped middle, the latest certification report and the latest report of every
+    security status (REVOKED and those of HasSecurityIssue), taking the places of the oldest of the
+    most recent ones but never that of the current status
+
+so LatestStatus, CertificationLevel, IsRevoked and HasSecurityIssue answer as they did before;
+HasSecurityIssueForVersion may not, when a dropped report remediated an issue for some versions.
+e.StatusReports is not modified.
+*/
+func limitStatusReports(e Entry, limit int) (Entry, bool) {
+	if limit <= 0 || len(e.StatusReports) <= limit {
+		return e, false
+	}
+	sorted := e.StatusHistory()
+	head := limit / 4
+	tail := limit - head
+	middle := sorted[head : len(sorted)-tail]
+
+	// Pinned reports of the middle, latest first, as long as the current status keeps its slot.
+	var pinned []int
+	seen := make(map[AuthenticatorStatus]bool)
+	for i := len(middle) - 1; i >= 0 && len(pinned) < tail-1; i-- {
+		s := middle[i].Status
+		key := s
+		switch {
+		case isCertificationLevel(s):
+			key = FIDO_CERTIFIED // one certification report, whatever its level
+		case s != REVOKED && !slices.Contains(securityIssueStatuses, s):
+			continue
+		}
+		if !seen[key] {
+			seen[key] = true
+			pinned = append(pinned, i)
+		}
+	}
+	slices.Reverse(pinned)
+
+	kept := make([]StatusReport, 0, limit)
+	kept = append(kept, sorted[:head]...)
+	for _, i := range pinned {
+		kept = append(kept, middle[i])
+	}
+	kept = append(kept, sorted[len(sorted)-tail+len(pinned):]...)
+	e.StatusReports = kept
+	return e, true
+}
+
+// StatusTimeline returns the status timeline of the embedded entry identified by aaGuid. See
+// Provider.StatusTimeline.
+func StatusTimeline(aaGuid string) ([]StatusInterval, bool) {
+	return Default().StatusTimeline(aaGuid)
+}
+
+/*
+StatusTimeline returns the StatusTimeline of the entry identified by aaGuid, and false if there is
+no such entry. Unlike Entry.StatusTimeline, which sorts the reports on every call, the timeline is
+computed once per snapshot and shared by later calls until the next Update; each call returns a
+copy of it.
+*/
+func (p *Provider) StatusTimeline(aaGuid string) ([]StatusInterval, bool) {
+	s := p.current()
+	k, ok := p.lookupKey(s, aaGuid)
+	if !ok {
+		return nil, false
+	}
+	e, ok := s.entries[k]
+	if !ok {
+		return nil, false
+	}
+	v, ok := s.timelines.Load(k)
+	if !ok {
+		v, _ = s.timelines.LoadOrStore(k, e.StatusTimeline())
+	}
+	return slices.Clone(v.([]StatusInterval)), true
+}
@@ -0,0 +1,117 @@
+package aaguids
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// hugeHistoryEntry returns an entry with n status reports, reportsPerDay sharing each effective
+// date, listed with the days in reverse so StatusHistory has to sort them. Reports of one day keep
+// their ascending order, and CertificateNumber holds each report's index in that order. The last
+// report of the last day revokes the entry.
+func hugeHistoryEntry(n, reportsPerDay int) Entry {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	days := (n + reportsPerDay - 1) / reportsPerDay
+	reports := make([]StatusReport, 0, n)
+	for d := days - 1; d >= 0; d-- {
+		date := start.AddDate(0, 0, d).Format(time.DateOnly)
+		for i := d * reportsPerDay; i < min((d+1)*reportsPerDay, n); i++ {
+			status := FIDO_CERTIFIED_L1
+			if i%2 == 1 {
+				status = UPDATE_AVAILABLE
+			}
+			if i == n-1 {
+				status = REVOKED
+			}
+			r := report(status, date)
+			num := strconv.Itoa(i)
+			r.CertificateNumber = &num
+			reports = append(reports, r)
+		}
+	}
+	return Entry{AAGUID: testYubiKey, StatusReports: reports}
+}
+
+// reportIndex returns the index hugeHistoryEntry stored in r.
+func reportIndex(t *testing.T, r StatusReport) int {
+	t.Helper()
+	i, err := strconv.Atoi(*r.CertificateNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func TestHugeStatusHistory(t *testing.T) {
+	const n = 10_000
+	e := hugeHistoryEntry(n, 100)
+
+	// StatusHistory sorts by date and keeps the BLOB order of reports sharing one.
+	history := e.StatusHistory()
+	if len(history) != n {
+		t.Fatalf("StatusHistory has %d reports, want %d", len(history), n)
+	}
+	for i, r := range history {
+		if got := reportIndex(t, r); got != i {
+			t.Fatalf("StatusHistory()[%d] is report %d, want the sort to be stable", i, got)
+		}
+	}
+	timeline := e.StatusTimeline()
+	for i, iv := range timeline {
+		if got := reportIndex(t, iv.Report); got != i {
+			t.Fatalf("StatusTimeline()[%d] is report %d, want the sort to be stable", i, got)
+		}
+	}
+	if latest, _ := e.LatestStatus(); reportIndex(t, latest) != n-1 || latest.Status != REVOKED {
+		t.Errorf("LatestStatus() = report %d %s, want report %d REVOKED", reportIndex(t, latest), latest.Status, n-1)
+	}
+
+	// The provider computes the same timeline once per snapshot and hands out copies.
+	p := testProvider(t, e)
+	got, ok := p.StatusTimeline(testYubiKey)
+	if !ok || !slices.EqualFunc(got, timeline, func(a, b StatusInterval) bool {
+		return reportIndex(t, a.Report) == reportIndex(t, b.Report) && a.From.Equal(b.From) && a.To.Equal(b.To)
+	}) {
+		t.Fatal("Provider.StatusTimeline differs from Entry.StatusTimeline")
+	}
+	got[0].Status = ""
+	if again, _ := p.StatusTimeline(testYubiKey); again[0].Status != timeline[0].Status {
+		t.Error("modifying a returned timeline changed the cached one")
+	}
+
+	// Truncation bounds the reports kept and answers the status questions as the full history does.
+	limited, truncated := limitStatusReports(e, DefaultMaxStatusReports)
+	if !truncated || len(limited.StatusReports) != DefaultMaxStatusReports || cap(limited.StatusReports) != DefaultMaxStatusReports {
+		t.Fatalf("limitStatusReports kept %d reports (cap %d), truncated %v; want %d", len(limited.StatusReports), cap(limited.StatusReports), truncated, DefaultMaxStatusReports)
+	}
+	if a, _ := limited.LatestStatus(); reportIndex(t, a) != n-1 {
+		t.Errorf("LatestStatus() after truncation is report %d, want %d", reportIndex(t, a), n-1)
+	}
+	if limited.IsRevoked() != e.IsRevoked() || limited.CertificationLevel() != e.CertificationLevel() || limited.HasSecurityIssue() != e.HasSecurityIssue() {
+		t.Error("truncation changed IsRevoked, CertificationLevel or HasSecurityIssue")
+	}
+	kept := limited.StatusHistory()
+	if !slices.IsSortedFunc(kept, func(a, b StatusReport) int { return reportIndex(t, a) - reportIndex(t, b) }) {
+		t.Error("truncated reports are out of timeline order")
+	}
+	if reportIndex(t, kept[0]) != 0 {
+		t.Errorf("truncation dropped the earliest report, first kept is %d", reportIndex(t, kept[0]))
+	}
+}
+
+func BenchmarkStatusTimeline(b *testing.B) {
+	e := hugeHistoryEntry(10_000, 100)
+	b.Run("entry", func(b *testing.B) {
+		for b.Loop() {
+			e.StatusTimeline()
+		}
+	})
+	b.Run("provider", func(b *testing.B) {
+		p := testProvider(b, e)
+		for b.Loop() {
+			p.StatusTimeline(testYubiKey)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package aaguids
+
+import (
+	"strings"
+	"sync"
+)
+
+/*
+registeredTranslations holds the catalogs added with RegisterStatusTranslations, by lower-cased
+language tag. They take precedence over the embedded statusTranslations of the same tag.
+*/
+var registeredTranslations struct {
+	sync.RWMutex
+	byLang map[string]map[AuthenticatorStatus]string
+}
+
+/*
+RegisterStatusTranslations adds status descriptions for lang, an IETF language tag, to those
+StatusDescription serves, e.g. translations maintained outside this repository. They override the
+embedded descriptions of the same tag and those of earlier calls for the same statuses; statuses
+left out keep the description they had. The map is copied. It panics if lang is empty, and is meant
+to be called during initialization, like the catalog it extends.
+*/
+func RegisterStatusTranslations(lang string, descriptions map[AuthenticatorStatus]string) {
+	if lang == "" {
+		panic("aaguids: RegisterStatusTranslations: empty language tag")
+	}
+	key := strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+	registeredTranslations.Lock()
+	defer registeredTranslations.Unlock()
+	if registeredTranslations.byLang == nil {
+		registeredTranslations.byLang = make(map[string]map[AuthenticatorStatus]string)
+	}
+	m := registeredTranslations.byLang[key]
+	if m == nil {
+		m = make(map[AuthenticatorStatus]string, len(descriptions))
+		registeredTranslations.byLang[key] = m
+	}
+	for s, d := range descriptions {
+		m[s] = d
+	}
+}
+
+/*
+StatusDescription returns a sentence explaining s to end users in lang, an IETF language tag, e.g.
+"This authenticator has been revoked by the FIDO Alliance and should no longer be used." for
+REVOKED in "en". Descriptions come from the status-translations.json catalog embedded by the
+generator and from RegisterStatusTranslations, and the language is chosen among those describing s
+as DisplayNameFor chooses names: "pt-BR" finds "pt-BR", then "pt", then "pt-PT". Without a match,
+the English description is used, which the generator requires for every known status. For a status
+this package does not know and nobody registered, it returns Describe, i.e. "".
+
+The catalog format is one JSON object mapping language tags to status descriptions:
+
+	{"languages": {"en": {"REVOKED": "This authenticator has been revoked ..."}, "de": {...}}}
+*/
+func StatusDescription(s AuthenticatorStatus, lang string) string {
+	candidates := make(map[string]string)
+	for tag, m := range statusTranslations {
+		if d, ok := m[s]; ok {
+			candidates[strings.ToLower(tag)] = d
+		}
+	}
+	registeredTranslations.RLock()
+	for tag, m := range registeredTranslations.byLang {
+		if d, ok := m[s]; ok {
+			candidates[tag] = d
+		}
+	}
+	registeredTranslations.RUnlock()
+
+	if d, ok := matchLanguage(candidates, lang); ok {
+		return d
+	}
+	if d, ok := matchLanguage(candidates, "en"); ok {
+		return d
+	}
+	return s.Describe()
+}
@@ -0,0 +1,49 @@
+package aaguids
+
+import "slices"
+
+/*
+EntrySummary is a compact, ==-comparable digest of an Entry for use as a cache key or wire format.
+All fields are plain values, and Summary is a pure function of the Entry, so summarizing the same
+entry twice always yields identical values.
+
+  - AAGUID: the dataset key (see Entry.Key), so UAF and U2F entries carry their synthetic key
+  - Status / StatusDate: the latest status report in timeline order and its effective date
+  - CertificationLevel: the most recent FIDO_CERTIFIED* status, or "" if never certified
+*/
+type EntrySummary struct {
+	AAGUID             string              `json:"aaguid"`
+	Description        string              `json:"description"`
+	Status             AuthenticatorStatus `json:"status,omitempty"`
+	StatusDate         string              `json:"statusDate,omitempty"`
+	CertificationLevel AuthenticatorStatus `json:"certificationLevel,omitempty"`
+	ProtocolFamily     string              `json:"protocolFamily,omitempty"`
+}
+
+// Summary returns the EntrySummary of e.
+func (e Entry) Summary() EntrySummary {
+	s := EntrySummary{
+		AAGUID:         e.Key(),
+		Description:    e.DisplayName(),
+		ProtocolFamily: e.MetadataStatement.ProtocolFamily,
+	}
+	if r, ok := e.LatestStatus(); ok {
+		s.Status = r.Status
+		if r.EffectiveDate != nil {
+			s.StatusDate = *r.EffectiveDate
+		}
+	}
+	if r, ok := e.latestCertification(); ok {
+		s.CertificationLevel = r.Status
+	}
+	return s
+}
+
+// Summaries returns the summary of every entry in the current snapshot, in ascending AAGUID order.
+func (p *Provider) Summaries() []EntrySummary {
+	var out []EntrySummary
+	for e := range p.Where(nil) {
+		out = append(out, e.Summary())
+	}
+	return slices.Clip(out)
+}
@@ -0,0 +1,169 @@
+package aaguids
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// SyntheticOption adjusts SyntheticDataset.
+type SyntheticOption func(*synthetic)
+
+// synthetic is the configuration of one SyntheticDataset call.
+type synthetic struct {
+	biometricFraction float64
+	iconFraction      float64
+	maxIconBytes      int
+}
+
+// WithBiometricFraction sets the fraction of FIDO2 entries given biometric status reports
+// (default 0.1).
+func WithBiometricFraction(f float64) SyntheticOption {
+	return func(s *synthetic) { s.biometricFraction = f }
+}
+
+// WithIconFraction sets the fraction of entries given an icon (default 0.8) and the largest icon
+// size in bytes before base64 encoding (default 16 KiB; sizes are spread between 64 bytes and it).
+func WithIconFraction(f float64, maxBytes int) SyntheticOption {
+	return func(s *synthetic) { s.iconFraction, s.maxIconBytes = f, max(maxBytes, 64) }
+}
+
+// syntheticEpoch is the date of the first synthetic status report.
+var syntheticEpoch = time.Date(2015, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+/*
+SyntheticDataset returns n made-up but spec-valid entries for benchmarks and scalability tests,
+e.g. BLOBPayload{Entries: SyntheticDataset(10*len(real), 1)}.EntriesByKey() (which, as for MDS,
+keeps only the fido2 and u2f entries).
+
+The output depends only on n, seed and the options, so runs with the same seed compare like for
+like across commits. The distributions follow the production MDS:
+
+  - protocol families: about 70% fido2 (with AAGUID and authenticatorGetInfo), 20% u2f (keyed by
+    attestation certificate key identifier) and 10% uaf (with AAID)
+  - status histories of one to five reports in timeline order, most ending certified, a few with
+    UPDATE_AVAILABLE and about 2% revoked
+  - biometric status reports and icons on configurable fractions of the entries; icons are
+    random bytes behind a PNG signature, not real images
+*/
+func SyntheticDataset(n int, seed int64, opts ...SyntheticOption) []Entry {
+	cfg := synthetic{biometricFraction: 0.1, iconFraction: 0.8, maxIconBytes: 16 << 10}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	r := rand.New(rand.NewPCG(uint64(seed), 0x5eed))
+	out := make([]Entry, n)
+	for i := range out {
+		out[i] = syntheticEntry(r, i, cfg)
+	}
+	return out
+}
+
+func syntheticEntry(r *rand.Rand, i int, cfg synthetic) Entry {
+	e := Entry{
+		MetadataStatement: MetadataStatement{
+			LegalHeader:                 "Synthetic metadata for testing only.",
+			Description:                 fmt.Sprintf("Synthetic Authenticator %d", i),
+			AuthenticatorVersion:        uint64(r.IntN(10) + 1),
+			Schema:                      3,
+			AuthenticationAlgorithms:    []string{"secp256r1_ecdsa_sha256_raw"},
+			AttestationTypes:            []string{"basic_full"},
+			AttestationRootCertificates: []string{base64.StdEncoding.EncodeToString(randomBytes(r, 400+r.IntN(800)))},
+		},
+	}
+
+	switch f := r.Float64(); {
+	case f < 0.7:
+		var id AAGUID
+		copy(id[:], randomBytes(r, len(id)))
+		e.AAGUID = id.String()
+		e.MetadataStatement.AAGUID = e.AAGUID
+		e.MetadataStatement.ProtocolFamily = "fido2"
+		e.MetadataStatement.AuthenticatorGetInfo = syntheticGetInfo(r, id)
+	case f < 0.9:
+		ids := make([]string, 1+r.IntN(3))
+		for j := range ids {
+			ids[j] = hex.EncodeToString(randomBytes(r, 20))
+		}
+		e.AttestationCertificateKeyIdentifiers = ids
+		e.MetadataStatement.AttestationCertificateKeyIdentifiers = ids
+		e.MetadataStatement.ProtocolFamily = "u2f"
+	default:
+		e.AAID = fmt.Sprintf("%04X#%04X", r.IntN(1<<16), r.IntN(1<<16))
+		e.MetadataStatement.AAID = e.AAID
+		e.MetadataStatement.ProtocolFamily = "uaf"
+	}
+
+	e.StatusReports = syntheticStatusHistory(r)
+	e.TimeOfLastStatusChange = *e.StatusReports[len(e.StatusReports)-1].EffectiveDate
+
+	if e.MetadataStatement.ProtocolFamily == "fido2" && r.Float64() < cfg.biometricFraction {
+		date := *e.StatusReports[0].EffectiveDate
+		e.BiometricStatusReports = []BiometricStatusReport{{
+			CertLevel:     BiometricCertLevel(1 + r.IntN(2)),
+			Modality:      []BiometricModality{ModalityFingerprint, ModalityFaceprint}[r.IntN(2)],
+			EffectiveDate: &date,
+		}}
+	}
+	if r.Float64() < cfg.iconFraction {
+		size := 64 + r.IntN(cfg.maxIconBytes-63)
+		png := append([]byte("\x89PNG\r\n\x1a\n"), randomBytes(r, size)...)
+		e.MetadataStatement.Icon = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	}
+	return e
+}
+
+// syntheticGetInfo returns an authenticatorGetInfo for a FIDO2 entry, mixing CTAP 2.0 and 2.1
+// authenticators.
+func syntheticGetInfo(r *rand.Rand, id AAGUID) *AuthenticatorGetInfo {
+	g := &AuthenticatorGetInfo{
+		Versions:   []string{"U2F_V2", "FIDO_2_0"},
+		Extensions: []string{"hmac-secret"},
+		AAGUID:     hex.EncodeToString(id[:]),
+		Options:    map[string]bool{"rk": true, "up": true, "clientPin": r.IntN(2) == 0},
+		Transports: []string{"usb"},
+	}
+	if r.IntN(2) == 0 {
+		g.Versions = append(g.Versions, "FIDO_2_1")
+		g.Extensions = append(g.Extensions, "credProtect")
+		g.PinUvAuthProtocols = []uint{2, 1}
+		minPIN := uint64(4 + r.IntN(5))
+		g.MinPINLength = &minPIN
+	} else {
+		g.PinUvAuthProtocols = []uint{1}
+	}
+	return g
+}
+
+// syntheticStatusHistory returns one to five status reports in timeline order.
+func syntheticStatusHistory(r *rand.Rand) []StatusReport {
+	levels := []AuthenticatorStatus{FIDO_CERTIFIED_L1, FIDO_CERTIFIED_L1plus, FIDO_CERTIFIED_L2}
+	date := syntheticEpoch.AddDate(0, 0, r.IntN(3000))
+	report := func(s AuthenticatorStatus) StatusReport {
+		d := date.Format(time.DateOnly)
+		date = date.AddDate(0, 0, 30+r.IntN(400))
+		return StatusReport{Status: s, EffectiveDate: &d}
+	}
+
+	reports := []StatusReport{report(NOT_FIDO_CERTIFIED)}
+	for k := r.IntN(4); k > 0; k-- {
+		reports = append(reports, report(levels[r.IntN(len(levels))]))
+	}
+	switch f := r.Float64(); {
+	case f < 0.02:
+		reports = append(reports, report(REVOKED))
+	case f < 0.1:
+		reports = append(reports, report(UPDATE_AVAILABLE))
+	}
+	return reports
+}
+
+func randomBytes(r *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(r.UintN(256))
+	}
+	return b
+}
@@ -0,0 +1,116 @@
+package aaguids
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSyntheticDatasetDeterministic(t *testing.T) {
+	a, b := SyntheticDataset(300, 7), SyntheticDataset(300, 7)
+	if !reflect.DeepEqual(a, b) {
+		t.Fatal("two datasets of the same seed differ")
+	}
+	if reflect.DeepEqual(a, SyntheticDataset(300, 8)) {
+		t.Error("datasets of different seeds are equal")
+	}
+	if prefix := SyntheticDataset(100, 7); !reflect.DeepEqual(prefix, a[:100]) {
+		t.Error("a smaller dataset of the same seed is not a prefix of the larger one")
+	}
+}
+
+func TestSyntheticDatasetDistributions(t *testing.T) {
+	const n = 2000
+	families := map[string]int{}
+	var biometric, icons int
+	for _, e := range SyntheticDataset(n, 1) {
+		families[e.MetadataStatement.ProtocolFamily]++
+		if len(e.BiometricStatusReports) > 0 {
+			biometric++
+		}
+		if e.MetadataStatement.Icon != "" {
+			icons++
+		}
+		if e.Key() == "" {
+			t.Fatalf("entry %s has no dataset key", e.DisplayName())
+		}
+		for _, validate := range []func(Entry) error{ValidateGetInfoAAGUID, ValidateEntryAAIDs, ValidateBiometricStatusReports} {
+			if err := validate(e); err != nil {
+				t.Errorf("%s: %v", FormatEntryRef(e), err)
+			}
+		}
+		if last := e.StatusReports[len(e.StatusReports)-1]; e.TimeOfLastStatusChange != *last.EffectiveDate {
+			t.Errorf("%s: timeOfLastStatusChange %s is not the last report's date", e.Key(), e.TimeOfLastStatusChange)
+		}
+	}
+	// The shares are those documented on SyntheticDataset, with room for sampling noise.
+	for family, want := range map[string]float64{"fido2": 0.7, "u2f": 0.2, "uaf": 0.1} {
+		if got := float64(families[family]) / n; got < want-0.05 || got > want+0.05 {
+			t.Errorf("%s share %.2f, want about %.2f", family, got, want)
+		}
+	}
+	if got := float64(biometric) / float64(families["fido2"]); got < 0.05 || got > 0.15 {
+		t.Errorf("biometric share of fido2 entries %.2f, want about 0.1", got)
+	}
+	if got := float64(icons) / n; got < 0.75 || got > 0.85 {
+		t.Errorf("icon share %.2f, want about 0.8", got)
+	}
+
+	none := SyntheticDataset(200, 1, WithBiometricFraction(0), WithIconFraction(0, 0))
+	for _, e := range none {
+		if len(e.BiometricStatusReports) > 0 || e.MetadataStatement.Icon != "" {
+			t.Fatalf("%s has biometric reports or an icon with both fractions 0", e.Key())
+		}
+	}
+}
+
+// syntheticSizes are the dataset sizes benchmarks run at: about the production MDS, and ten
+// times it.
+var syntheticSizes = []int{500, 5000}
+
+// BenchmarkGetEntry looks up every key of a synthetic dataset in turn, in canonical form and in
+// the uppercase spelling that needs normalizing.
+func BenchmarkGetEntry(b *testing.B) {
+	for _, n := range syntheticSizes {
+		p, _ := syntheticProvider(b, n)
+		keys := p.AAGUIDs()
+		upper := make([]string, len(keys))
+		for i, k := range keys {
+			upper[i] = strings.ToUpper(k)
+		}
+		for _, in := range []struct {
+			name string
+			keys []string
+		}{{"canonical", keys}, {"uppercase", upper}} {
+			b.Run(fmt.Sprintf("n=%d/%s", n, in.name), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, ok := p.GetEntry(in.keys[i%len(in.keys)]); !ok {
+						b.Fatalf("GetEntry(%q) found nothing", in.keys[i%len(in.keys)])
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkTrustDecision evaluates the strict preset for every key of a synthetic dataset in turn,
+// without and with tracing.
+func BenchmarkTrustDecision(b *testing.B) {
+	pol := StrictPolicy()
+	for _, n := range syntheticSizes {
+		p, _ := syntheticProvider(b, n)
+		keys := p.AAGUIDs()
+		for _, tc := range []struct {
+			name string
+			opts []EvaluateOption
+		}{{"plain", nil}, {"traced", []EvaluateOption{WithTrace()}}} {
+			b.Run(fmt.Sprintf("n=%d/%s", n, tc.name), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					p.TrustDecision(pol, keys[i%len(keys)], tc.opts...)
+				}
+			})
+		}
+	}
+}
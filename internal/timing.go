@@ -0,0 +1,88 @@
+package aaguids
+
+/*
+WithUniformLookupTiming makes the Provider's lookups (GetEntry, ServeIcon, RawEntryJSON,
+TrustDecision, ...) do the same work whether or not the dataset holds the requested key, so a
+remote caller of a lookup-backed API cannot tell from response times which keys exist. See
+SetUniformLookupTiming.
+*/
+func WithUniformLookupTiming() ProviderOption {
+	return func(p *Provider) { p.uniformLookup.Store(true) }
+}
+
+/*
+SetUniformLookupTiming switches uniform lookup timing on or off, e.g. for the Default provider.
+By default a lookup returns as soon as it finds its key: an exact key is found with one map probe,
+while other spellings are normalized first and then probed again. With uniform timing every lookup
+normalizes its input and probes the exact, U2F, canonical and alias forms before choosing a result,
+so hits and misses of well-formed input cost the same. Input that fails to normalize still returns
+sooner, but that depends only on the input, not on the dataset.
+
+The cost is the normalization and the extra map probes on every hit: exact-key hits, otherwise a
+single map probe, become about as slow as misses (a few hundred nanoseconds), while misses and
+non-canonical spellings are barely affected. BenchmarkLookupTiming reports the median and 99th
+percentile of each case with and without uniform timing. To make only an HTTP handler uniform,
+pass WithUniformTiming to NewHTTPHandler instead.
+*/
+func (p *Provider) SetUniformLookupTiming(on bool) {
+	p.uniformLookup.Store(on)
+}
+
+// lookupKey resolves k like resolveKey, or like resolveKeyUniform under uniform lookup timing. The
+// zero AAGUID never resolves, even if the dataset holds an entry under it.
+func (p *Provider) lookupKey(s *snapshot, k string) (string, bool) {
+	return p.lookupKeyMode(s, k, p.uniformLookup.Load())
+}
+
+// lookupKeyMode is lookupKey with uniform timing chosen by the caller, for handlers that enable it
+// without switching the whole Provider (see WithUniformTiming).
+func (p *Provider) lookupKeyMode(s *snapshot, k string, uniform bool) (string, bool) {
+	var key string
+	var ok bool
+	if uniform {
+		key, ok = s.resolveKeyUniform(p.normalizerChain(), k)
+	} else {
+		key, ok = s.resolveKey(p.normalizerChain(), k)
+	}
+	return key, ok && key != zeroAAGUID
+}
+
+// resolveKeyUniform returns what resolveKey returns, after performing every probe whatever their
+// outcome.
+func (s *snapshot) resolveKeyUniform(norm NormalizerChain, k string) (string, bool) {
+	_, exact := s.entries[k]
+	nk := normalizeKey(k)
+	_, syntheticFound := s.entries[nk]
+	id, _, err := norm.Parse(k)
+	c := id.String()
+	_, direct := s.entries[c]
+	alias, aliased := s.canonical[c]
+	switch {
+	case exact:
+		return k, true
+	case isSyntheticKey(nk):
+		return nk, syntheticFound
+	case err != nil:
+		return "", false
+	case direct:
+		return c, true
+	}
+	return alias, aliased
+}
+
+// lookupCanonicalUniform returns what lookupCanonical returns, after probing both maps.
+func (s *snapshot) lookupCanonicalUniform(id string) (Entry, bool) {
+	if s == nil {
+		return Entry{}, false
+	}
+	e, direct := s.entries[id]
+	k, aliased := s.canonical[id]
+	aliasEntry := s.entries[k]
+	switch {
+	case direct:
+		return e, true
+	case aliased:
+		return aliasEntry, true
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,117 @@
+package aaguids
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// syntheticProvider returns a Provider over n synthetic entries (see SyntheticDataset) and one
+// fido2 key of it.
+func syntheticProvider(tb testing.TB, n int, opts ...ProviderOption) (*Provider, string) {
+	tb.Helper()
+	entries := BLOBPayload{Entries: SyntheticDataset(n, 1)}.EntriesByKey()
+	p := NewProvider(entries, Dataset{Serial: 1}, opts...)
+	for _, k := range p.current().keys {
+		if !isSyntheticKey(k) {
+			return p, k
+		}
+	}
+	tb.Fatal("synthetic dataset has no fido2 entry")
+	return nil, ""
+}
+
+func TestHTTPHandlerUniformTiming(t *testing.T) {
+	p, hit := syntheticProvider(t, 200)
+	paths := []string{
+		"/aaguids/" + hit,
+		"/aaguids/" + strings.ToUpper(hit),
+		"/aaguids/{" + hit + "}",
+		"/aaguids/00000000-0000-0000-0000-000000000001",
+		"/aaguids/00000000-0000-0000-0000-000000000000",
+		"/aaguids/not-an-aaguid",
+		"/aaguids/u2f:zz",
+		"/aaguids/" + hit + "/icon?fallback=avatar",
+		"/aaguids/00000000-0000-0000-0000-000000000001/icon",
+	}
+	plain, uniform := NewHTTPHandler(p), NewHTTPHandler(p, WithUniformTiming())
+	for _, path := range paths {
+		wantCode, wantBody := get(t, plain, path)
+		if code, body := get(t, uniform, path); code != wantCode || string(body) != string(wantBody) {
+			t.Errorf("GET %s: uniform timing served %d, without %d", path, code, wantCode)
+		}
+	}
+	if p.uniformLookup.Load() {
+		t.Error("WithUniformTiming switched the Provider to uniform timing")
+	}
+}
+
+// BenchmarkLookupTiming compares hits and misses without and with uniform timing. Besides the
+// mean, it reports the median and 99th percentile of individual lookups (which include the cost
+// of reading the clock), so the distributions of hits and misses can be compared:
+//
+//	go test -run '^$' -bench LookupTiming ./internal
+func BenchmarkLookupTiming(b *testing.B) {
+	p, hit := syntheticProvider(b, 2000)
+	inputs := []struct{ name, key string }{
+		{"hit-exact", hit},
+		{"hit-uppercase", strings.ToUpper(hit)},
+		{"miss", "00000000-0000-4000-8000-000000000001"},
+		{"miss-uppercase", "00000000-0000-4000-8000-00000000000A"},
+	}
+	s := p.current()
+	for _, mode := range []struct {
+		name    string
+		uniform bool
+	}{{"default", false}, {"uniform", true}} {
+		for _, in := range inputs {
+			b.Run(mode.name+"/"+in.name, func(b *testing.B) {
+				samples := make([]time.Duration, 0, min(b.N, 1<<20))
+				for i := 0; i < b.N; i++ {
+					start := time.Now()
+					p.lookupKeyMode(s, in.key, mode.uniform)
+					if d := time.Since(start); len(samples) < cap(samples) {
+						samples = append(samples, d)
+					}
+				}
+				slices.Sort(samples)
+				if n := len(samples); n > 0 {
+					b.ReportMetric(float64(samples[n/2]), "p50-ns")
+					b.ReportMetric(float64(samples[n*99/100]), "p99-ns")
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkHTTPHandlerEntry measures a whole entry request, whose encoding dominates the lookup.
+func BenchmarkHTTPHandlerEntry(b *testing.B) {
+	p, hit := syntheticProvider(b, 2000)
+	for _, opt := range []struct {
+		name string
+		opts []HandlerOption
+	}{{"default", nil}, {"uniform", []HandlerOption{WithUniformTiming()}}} {
+		h := NewHTTPHandler(p, opt.opts...)
+		for _, path := range []string{"/aaguids/" + hit, "/aaguids/00000000-0000-4000-8000-000000000001"} {
+			name := opt.name + "/hit"
+			if !strings.Contains(path, hit) {
+				name = opt.name + "/miss"
+			}
+			b.Run(name, func(b *testing.B) {
+				r, _ := http.NewRequest(http.MethodGet, path, nil)
+				for i := 0; i < b.N; i++ {
+					h.ServeHTTP(discardResponse{}, r)
+				}
+			})
+		}
+	}
+}
+
+// discardResponse is an http.ResponseWriter that drops everything.
+type discardResponse struct{}
+
+func (discardResponse) Header() http.Header         { return http.Header{} }
+func (discardResponse) Write(b []byte) (int, error) { return len(b), nil }
+func (discardResponse) WriteHeader(int)             {}
@@ -0,0 +1,146 @@
+package aaguids
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+/*
+RemovedEntry is the tombstone of an entry that disappeared from the dataset. An AAGUID vanishing
+from the MDS BLOB does not mean the authenticator became untrustworthy, so the last-known data is
+kept for callers that want to tell "removed upstream" apart from "never known".
+
+  - Key: the dataset key of the entry, in canonical form (see Entry.Key)
+  - Entry: the entry as last published
+  - RemovedSerial: the Dataset.Serial of the first snapshot without the entry
+*/
+type RemovedEntry struct {
+	Key           string `json:"key"`
+	Entry         Entry  `json:"entry"`
+	RemovedSerial int    `json:"removedSerial"`
+}
+
+/*
+SetTombstoneRetention sets how many dataset serials a tombstone is kept for: a tombstone is dropped
+once an update's Dataset.Serial exceeds its RemovedSerial by more than serials. The default, 0,
+keeps tombstones until the entry reappears. The setting applies from the next Update.
+*/
+func (p *Provider) SetTombstoneRetention(serials int) {
+	p.tombstoneRetention.Store(int64(serials))
+}
+
+// RemovedEntries returns the tombstones of the current snapshot, in ascending key order.
+func (p *Provider) RemovedEntries() []RemovedEntry {
+	s := p.current()
+	out := make([]RemovedEntry, 0, len(s.removed))
+	for _, r := range s.removed {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// GetRemovedEntry returns the tombstone of the entry aaGuid identifies, if it was removed from the
+// dataset and is still retained. aaGuid is resolved as Watch resolves its ids: in any spelling the
+// Provider's NormalizerChain parses, or as a synthetic UAF or U2F key.
+func (p *Provider) GetRemovedEntry(aaGuid string) (RemovedEntry, bool) {
+	s := p.current()
+	if r, ok := s.removed[canonicalKey(aaGuid)]; ok {
+		return r, true
+	}
+	k, err := p.normalizedKey(aaGuid)
+	if err != nil {
+		return RemovedEntry{}, false
+	}
+	r, ok := s.removed[k]
+	return r, ok
+}
+
+/*
+UpdateFromBLOB refreshes the MDS part of the Provider's dataset from a parsed MDS BLOB. The entries
+of blob, keyed as the generator keys them (see BLOBPayload.EntriesByKey), are merged with
+MergeEntries over the non-MDS data of the current dataset, the CommunityExtensions of its entries,
+so community entries and the community names and icons of MDS entries survive the refresh as they
+do a regeneration. MDS entries that were served before and are absent from blob become tombstones
+instead of being dropped; entries that also carry community data stay, with that data only.
+
+The Dataset.Sources are SourceFIDOMDS3 followed by the community sources of the current dataset.
+When blob was parsed with WithRawEntries, the upstream JSON of its entries is kept for RawEntryJSON.
+*/
+func (p *Provider) UpdateFromBLOB(blob BLOBPayload) {
+	mds := SourceEntries{Label: SourceFIDOMDS3, Entries: blob.EntriesByKey(), Raw: blob.RawEntriesByKey(), Source: DatasetSource{
+		Version:    strconv.Itoa(blob.No),
+		UpdatedAt:  time.Now().UTC().Format(time.RFC3339),
+		NextUpdate: blob.NextUpdate,
+	}}
+	sources := append([]SourceEntries{mds}, communitySources(p.current())...)
+	entries, prov := MergeEntries(sources...)
+	info := Dataset{Serial: blob.No, NextUpdate: blob.NextUpdate, EntryCount: len(entries), Sources: MergedSources(sources...)}
+	p.update(entries, info, prov, mergedRaw(sources), OriginFetched)
+}
+
+// communitySources splits the CommunityExtensions of the entries of s into one SourceEntries per
+// CommunityExtensions.Source, each entry reduced to its identifiers and extensions as the generator
+// builds community entries. Sources are ordered as s.info.Sources lists them, unlisted ones after
+// in label order, and carry the DatasetSource of the same name.
+func communitySources(s *snapshot) []SourceEntries {
+	byLabel := make(map[string]map[string]Entry)
+	for _, k := range s.keys {
+		e := s.entries[k]
+		if e.CommunityExtensions == nil {
+			continue
+		}
+		label := e.CommunityExtensions.Source
+		if byLabel[label] == nil {
+			byLabel[label] = make(map[string]Entry)
+		}
+		ext := *e.CommunityExtensions
+		byLabel[label][canonicalKey(k)] = Entry{
+			AAGUID:              e.AAGUID,
+			AAID:                e.AAID,
+			MetadataStatement:   MetadataStatement{AAGUID: e.MetadataStatement.AAGUID, AAID: e.MetadataStatement.AAID},
+			CommunityExtensions: &ext,
+		}
+	}
+	var out []SourceEntries
+	for _, src := range s.info.Sources {
+		if entries, ok := byLabel[src.Name]; ok && src.Name != SourceFIDOMDS3 {
+			out = append(out, SourceEntries{Label: src.Name, Entries: entries, Source: src})
+			delete(byLabel, src.Name)
+		}
+	}
+	for _, label := range slices.Sorted(maps.Keys(byLabel)) {
+		out = append(out, SourceEntries{Label: label, Entries: byLabel[label]})
+	}
+	return out
+}
+
+// tombstones carries the tombstones of old into cur, adds one for every entry of old missing from
+// cur, and drops tombstones of entries that reappeared or outlived the retention.
+func tombstones(old, cur *snapshot, retention int) map[string]RemovedEntry {
+	removed := make(map[string]RemovedEntry)
+	if old == nil {
+		return removed
+	}
+	for k, r := range old.removed {
+		removed[k] = r
+	}
+	present := make(map[string]bool, len(cur.keys))
+	for _, k := range cur.keys {
+		present[canonicalKey(k)] = true
+	}
+	for _, k := range old.keys {
+		if ck := canonicalKey(k); !present[ck] {
+			removed[ck] = RemovedEntry{Key: ck, Entry: old.entries[k], RemovedSerial: cur.info.Serial}
+		}
+	}
+	for k, r := range removed {
+		if present[k] || (retention > 0 && cur.info.Serial-r.RemovedSerial > retention) {
+			delete(removed, k)
+		}
+	}
+	return removed
+}
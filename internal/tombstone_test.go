@@ -0,0 +1,132 @@
+package aaguids
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	testYubiKey = "ee882879-721c-4913-9775-3dfcce97072a"
+	testGPM     = "ea9b8d66-4d01-1d21-3ce4-b6b48cb575d4" // community-only: Google Password Manager
+)
+
+// communityFeed returns a passkey-authenticator-aaguids style source naming the YubiKey of
+// blob-mixed.json and a synced authenticator the BLOB does not list.
+func communityFeed() SourceEntries {
+	entry := func(aaguid, name string) Entry {
+		return Entry{
+			AAGUID:              aaguid,
+			MetadataStatement:   MetadataStatement{AAGUID: aaguid},
+			CommunityExtensions: &CommunityExtensions{Source: "passkey-community", DisplayName: name},
+		}
+	}
+	return SourceEntries{
+		Label: "passkey-community",
+		Entries: map[string]Entry{
+			testYubiKey: entry(testYubiKey, "YubiKey 5"),
+			testGPM:     entry(testGPM, "Google Password Manager"),
+		},
+		Source: DatasetSource{Version: "sha256:00"},
+	}
+}
+
+func TestUpdateFromBLOBKeepsCommunityEntries(t *testing.T) {
+	blob := readTestBLOB(t, "blob-mixed.json")
+	p := NewProvider(nil, Dataset{})
+	p.UpdateMerged(Dataset{Serial: blob.No},
+		SourceEntries{Label: SourceFIDOMDS3, Entries: blob.EntriesByKey()}, communityFeed())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := p.Watch(ctx, []string{testGPM, testU2FKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The next BLOB drops the YubiKey and the U2F key; both were MDS entries.
+	next := blob
+	next.No++
+	next.Entries = slices.DeleteFunc(slices.Clone(blob.Entries), func(e Entry) bool {
+		return e.AAID == ""
+	})
+	p.UpdateFromBLOB(next)
+
+	if e, ok := p.GetEntry(testGPM); !ok || e.DisplayName() != "Google Password Manager" {
+		t.Errorf("community-only entry after refresh: %q, %v", e.DisplayName(), ok)
+	}
+	if e, ok := p.GetEntry(testYubiKey); !ok || e.DisplayName() != "YubiKey 5" || len(e.StatusReports) != 0 {
+		t.Errorf("YubiKey after refresh: %q, %v, %d status reports; want its community data only",
+			e.DisplayName(), ok, len(e.StatusReports))
+	}
+	if e, ok := p.GetEntry("uaf:4E4E#4005"); !ok || e.Summary().Status != "FIDO_CERTIFIED" {
+		t.Errorf("MDS entry still in the BLOB: %v", ok)
+	}
+
+	var removed []string
+	for _, r := range p.RemovedEntries() {
+		removed = append(removed, r.Key)
+	}
+	if !slices.Equal(removed, []string{testU2FKey}) {
+		t.Errorf("tombstones = %q, want only the MDS-only U2F entry", removed)
+	}
+
+	var names []string
+	for _, src := range p.DatasetInfo().Sources {
+		names = append(names, src.Name)
+	}
+	if !slices.Equal(names, []string{SourceFIDOMDS3, "passkey-community"}) {
+		t.Errorf("sources = %q", names)
+	}
+	if got := p.DatasetInfo().EntryCount; got != 3 {
+		t.Errorf("EntryCount = %d, want 3: the UAF entry and the two community entries", got)
+	}
+
+	// Events arrive in key order, so an event for the community entry would come first.
+	select {
+	case ev := <-ch:
+		if ev.AAGUID != testU2FKey || ev.Kind != ChangeRemoved {
+			t.Errorf("first event = %s %v, want the U2F entry's removal", ev.AAGUID, ev.Kind)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no ChangeRemoved event for the U2F entry")
+	}
+}
+
+func TestGetRemovedEntryNormalizesKeys(t *testing.T) {
+	blob := readTestBLOB(t, "blob-mixed.json")
+	entries := blob.EntriesByKey()
+	// A key stored in another case still gets a canonical tombstone.
+	entries["EE882879-721C-4913-9775-3DFCCE97072A"] = entries[testYubiKey]
+	delete(entries, testYubiKey)
+	p := NewProvider(entries, Dataset{Serial: 1})
+	p.Update(map[string]Entry{}, Dataset{Serial: 2})
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{testYubiKey, testYubiKey},
+		{"EE882879-721C-4913-9775-3DFCCE97072A", testYubiKey},
+		{"{EE882879-721C-4913-9775-3DFCCE97072A}", testYubiKey},
+		{"urn:uuid:ee882879-721c-4913-9775-3dfcce97072a", testYubiKey},
+		{"uaf:4e4e#4005", "uaf:4E4E#4005"},
+		{"U2F:BF7BCAA0D0C6187A8C6ABBDD16A15640E7C7BDE2", testU2FKey},
+	}
+	for _, tt := range tests {
+		r, ok := p.GetRemovedEntry(tt.in)
+		if !ok || r.Key != tt.want || r.RemovedSerial != 2 {
+			t.Errorf("GetRemovedEntry(%q) = %q serial %d, %v; want %q", tt.in, r.Key, r.RemovedSerial, ok, tt.want)
+		}
+	}
+	if _, ok := p.GetRemovedEntry("not-an-aaguid"); ok {
+		t.Error("GetRemovedEntry found a malformed id")
+	}
+
+	// An entry coming back under another spelling clears its tombstone.
+	p.Update(map[string]Entry{"EE882879-721C-4913-9775-3DFCCE97072A": {AAGUID: testYubiKey}}, Dataset{Serial: 3})
+	if _, ok := p.GetRemovedEntry(testYubiKey); ok {
+		t.Error("tombstone kept for an entry that reappeared")
+	}
+}
@@ -0,0 +1,72 @@
+package aaguids
+
+import (
+	"encoding/hex"
+)
+
+// TraceCheck names one check of a Policy evaluation.
+type TraceCheck string
+
+const (
+	CheckAAGUIDFormat          TraceCheck = "aaguid_format"
+	CheckZeroAAGUID            TraceCheck = "zero_aaguid"
+	CheckMetadataStaleness     TraceCheck = "metadata_staleness"
+	CheckDatasetLookup         TraceCheck = "dataset_lookup"
+	CheckMetadataStatement     TraceCheck = "metadata_statement"
+	CheckUnknownAllowlist      TraceCheck = "unknown_allowlist"
+	CheckUnknownMode           TraceCheck = "unknown_mode"
+	CheckAsOf                  TraceCheck = "as_of"
+	CheckVersionScoping        TraceCheck = "version_scoping"
+	CheckBatchCertificate      TraceCheck = "batch_certificate_match"
+	CheckStatus                TraceCheck = "status_evaluation"
+	CheckCertLevel             TraceCheck = "cert_level"
+	CheckBiometricLevel        TraceCheck = "biometric_level"
+	CheckEnterpriseAttestation TraceCheck = "enterprise_attestation"
+)
+
+// TraceOutcome is the result of one traced check.
+type TraceOutcome string
+
+const (
+	OutcomePass    TraceOutcome = "pass"
+	OutcomeFail    TraceOutcome = "fail"
+	OutcomeSkipped TraceOutcome = "skipped"
+)
+
+/*
+TraceStep records one check of a Policy evaluation: what it looked at and what it concluded.
+Inputs only ever hold short strings; certificates appear as "sha256:<hex>" fingerprints, never as
+certificate bytes, so traces can be logged and attached to support tickets as they are.
+*/
+type TraceStep struct {
+	Check   TraceCheck        `json:"check"`
+	Outcome TraceOutcome      `json:"outcome"`
+	Detail  string            `json:"detail,omitempty"`
+	Inputs  map[string]string `json:"inputs,omitempty"`
+}
+
+/*
+WithTrace makes TrustDecision fill Decision.Trace with every check that ran, in execution order.
+Without it no trace is built: every recording site is guarded, so untraced evaluations allocate
+nothing for tracing.
+*/
+func WithTrace() EvaluateOption {
+	return func(ev *evaluation) { ev.tracing = true }
+}
+
+// record appends a trace step; kv alternates input names and values. Callers check ev.tracing first.
+func (ev *evaluation) record(check TraceCheck, outcome TraceOutcome, detail string, kv ...string) {
+	step := TraceStep{Check: check, Outcome: outcome, Detail: detail}
+	if len(kv) > 0 {
+		step.Inputs = make(map[string]string, len(kv)/2)
+		for i := 0; i+1 < len(kv); i += 2 {
+			step.Inputs[kv[i]] = kv[i+1]
+		}
+	}
+	ev.trace = append(ev.trace, step)
+}
+
+// fingerprintString formats a SHA-256 certificate fingerprint for traces.
+func fingerprintString(fp [32]byte) string {
+	return "sha256:" + hex.EncodeToString(fp[:])
+}
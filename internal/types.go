@@ -267,7 +267,15 @@ attestation roots, and other properties. Key highlights include:
   - authenticatorVersion: earliest version that satisfies the statement’s security and functionality.
   - protocolFamily: "uaf", "u2f", or "fido2".
   - schema: metadata statement version (3 for v3.0).
+  - authenticationAlgorithms: signature algorithms supported, e.g. "secp256r1_ecdsa_sha256_raw".
+  - attestationTypes: attestation types supported, e.g. "basic_full" (see VerifyPackedAttestation).
+  - attestationRootCertificates: base64 DER trust anchors for the attestation certificate chain.
+  - userVerificationDetails: the accepted user verification combinations (see CapabilityMatrix).
+  - attachmentHint: how the authenticator attaches, e.g. "internal", "external", "nfc".
   - icon: data: URL (PNG) representing the authenticator visually.
+  - UAF: the members only UAF statements carry (see UAFDetails); nil unless protocolFamily is "uaf".
+
+Only spec-defined fields belong here; community additions live in Entry.CommunityExtensions.
 */
 type MetadataStatement struct {
 	LegalHeader                          string                 `json:"legalHeader"`
@@ -279,18 +287,95 @@ type MetadataStatement struct {
 	AuthenticatorVersion                 uint64                 `json:"authenticatorVersion"`
 	ProtocolFamily                       string                 `json:"protocolFamily"`
 	Schema                               uint16                 `json:"schema"`
+	AuthenticationAlgorithms             []string               `json:"authenticationAlgorithms"`
+	AttestationTypes                     []string               `json:"attestationTypes"`
+	AttestationRootCertificates          []string               `json:"attestationRootCertificates"`
+	UserVerificationDetails              [][]VerificationMethod `json:"userVerificationDetails"`
+	AttachmentHint                       []string               `json:"attachmentHint"`
 
 	// The fields below are selectively included from the “FIDO Metadata Statement” specification.
-	// They can be expanded further to include userVerificationDetails, etc. as needed.
-	KeyProtection bool `json:"-"` // Example placeholder; real spec field is an array of strings
-	// ... other fields ...
+	// UAF holds the members of UAF statements; MetadataStatement.UnmarshalJSON fills it.
+	UAF *UAFDetails `json:"-"`
 
 	// For demonstration here, we only show a subset. In a full implementation, all required
 	// metadata statement fields from §5 FIDO Metadata Statement would appear.
-	IsKeyRestricted                 bool   `json:"isKeyRestricted"`
-	IsFreshUserVerificationRequired bool   `json:"isFreshUserVerificationRequired"`
-	Icon                            string `json:"icon"`
-	IconDark                        string `json:"icon_dark"`
+	IsKeyRestricted                 bool                  `json:"isKeyRestricted"`
+	IsFreshUserVerificationRequired bool                  `json:"isFreshUserVerificationRequired"`
+	Icon                            string                `json:"icon"`
+	AuthenticatorGetInfo            *AuthenticatorGetInfo `json:"authenticatorGetInfo"`
+
+	// IconDark is the community list's dark-theme icon, copied from the entry's
+	// CommunityExtensions on the entries a Provider serves (see MIGRATION.md).
+	//
+	// Deprecated: community icons moved to Entry.CommunityExtensions; use its IconDark or
+	// Entry.IconFor(Dark).
+	IconDark string `json:"-" aaguids:"compat"`
+
+	// KeyProtection reports whether UAF.KeyProtection lists any protection, on the entries a
+	// Provider serves (see MIGRATION.md).
+	//
+	// Deprecated: the spec's keyProtection is a list of names; use UAF.KeyProtection.
+	KeyProtection bool `json:"-" aaguids:"compat"`
+}
+
+/*
+VerificationMethod is one VerificationMethodDescriptor of userVerificationDetails (“FIDO Metadata
+Statement” § 3.5), reduced to its method: a user verification method name from FIDO Registry
+§ 3.1, e.g. "fingerprint_internal" or "passcode_external". The accuracy descriptors (caDesc, baDesc,
+paDesc) are not kept.
+
+userVerificationDetails lists alternatives: the outer array is OR, and every method of one inner
+array must be performed together (AND).
+*/
+type VerificationMethod struct {
+	UserVerificationMethod string `json:"userVerificationMethod"`
+}
+
+/*
+AuthenticatorGetInfo
+§ 3.12 in “FIDO Metadata Statement” (field “authenticatorGetInfo”), mirroring the CTAP2
+authenticatorGetInfo response (CTAP 2.1 § 6.4) as reported by the vendor. Only FIDO2 authenticators
+carry it.
+
+  - versions: supported protocol versions, e.g. "U2F_V2", "FIDO_2_0", "FIDO_2_1"
+  - extensions: supported extension identifiers, e.g. "credProtect", "hmac-secret"
+  - aaguid: the AAGUID as 32 hex characters without dashes; Entry.AAGUID is authoritative when
+    they disagree (see Entry.GetInfoAAGUID)
+  - options: CTAP option IDs; an absent option and an option set to false mean different things
+  - pinUvAuthProtocols: supported PIN/UV auth protocol versions, in order of preference
+  - minPINLength, firmwareVersion, ...: optional CTAP 2.1 fields; nil when not reported
+*/
+type AuthenticatorGetInfo struct {
+	Versions                         []string                        `json:"versions"`
+	Extensions                       []string                        `json:"extensions"`
+	AAGUID                           string                          `json:"aaguid"`
+	Options                          map[string]bool                 `json:"options"`
+	MaxMsgSize                       *uint64                         `json:"maxMsgSize"`
+	PinUvAuthProtocols               []uint                          `json:"pinUvAuthProtocols"`
+	MaxCredentialCountInList         *uint64                         `json:"maxCredentialCountInList"`
+	MaxCredentialIdLength            *uint64                         `json:"maxCredentialIdLength"`
+	Transports                       []string                        `json:"transports"`
+	Algorithms                       []PublicKeyCredentialParameters `json:"algorithms"`
+	MaxSerializedLargeBlobArray      *uint64                         `json:"maxSerializedLargeBlobArray"`
+	ForcePINChange                   *bool                           `json:"forcePINChange"`
+	MinPINLength                     *uint64                         `json:"minPINLength"`
+	FirmwareVersion                  *uint64                         `json:"firmwareVersion"`
+	MaxCredBlobLength                *uint64                         `json:"maxCredBlobLength"`
+	MaxRPIDsForSetMinPINLength       *uint64                         `json:"maxRPIDsForSetMinPINLength"`
+	PreferredPlatformUvAttempts      *uint64                         `json:"preferredPlatformUvAttempts"`
+	UvModality                       *uint64                         `json:"uvModality"`
+	Certifications                   map[string]uint64               `json:"certifications"`
+	RemainingDiscoverableCredentials *uint64                         `json:"remainingDiscoverableCredentials"`
+}
+
+/*
+PublicKeyCredentialParameters
+WebAuthn § 5.3 “PublicKeyCredentialParameters”, as listed in authenticatorGetInfo "algorithms":
+a credential type (always "public-key") and a COSE algorithm identifier (e.g. -7 for ES256).
+*/
+type PublicKeyCredentialParameters struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
 }
 
 /*
@@ -300,19 +385,19 @@ BiometricStatusReport
 Contains details about biometric certification status for a specific modality (e.g. fingerprint).
 Relevant fields:
 
-  - certLevel: The biometric certification level
-  - modality: The user verification modality (e.g. "fingerprint_internal")
+  - certLevel: The biometric certification level (see BiometricCertLevel)
+  - modality: The user verification modality (e.g. "fingerprint_internal", see BiometricModality)
   - effectiveDate: When the certLevel took effect
   - certificationDescriptor, certificateNumber, policy version, requirements version, etc.
 */
 type BiometricStatusReport struct {
-	CertLevel                        uint8   `json:"certLevel"`
-	Modality                         string  `json:"modality"`
-	EffectiveDate                    *string `json:"effectiveDate"`
-	CertificationDescriptor          *string `json:"certificationDescriptor"`
-	CertificateNumber                *string `json:"certificateNumber"`
-	CertificationPolicyVersion       *string `json:"certificationPolicyVersion"`
-	CertificationRequirementsVersion *string `json:"certificationRequirementsVersion"`
+	CertLevel                        BiometricCertLevel `json:"certLevel"`
+	Modality                         BiometricModality  `json:"modality"`
+	EffectiveDate                    *string            `json:"effectiveDate"`
+	CertificationDescriptor          *string            `json:"certificationDescriptor"`
+	CertificateNumber                *string            `json:"certificateNumber"`
+	CertificationPolicyVersion       *string            `json:"certificationPolicyVersion"`
+	CertificationRequirementsVersion *string            `json:"certificationRequirementsVersion"`
 }
 
 /*
@@ -329,6 +414,8 @@ AAGUID (FIDO2), AAID (UAF), or attestationCertificateKeyIdentifiers (U2F).
   - statusReports: array describing status transitions, from earliest to latest
   - timeOfLastStatusChange: when this array last changed
   - rogueListURL, rogueListHash: optional for referencing a list of rogue individual authenticators
+  - communityExtensions: not part of MDS; what community lists add (see CommunityExtensions), nil
+    for entries they do not cover
 */
 type Entry struct {
 	AAGUID                               string                  `json:"aaguid"`
@@ -340,4 +427,5 @@ type Entry struct {
 	TimeOfLastStatusChange               string                  `json:"timeOfLastStatusChange"`
 	RogueListURL                         string                  `json:"rogueListURL"`
 	RogueListHash                        string                  `json:"rogueListHash"`
+	CommunityExtensions                  *CommunityExtensions    `json:"communityExtensions"`
 }
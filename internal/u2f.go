@@ -0,0 +1,370 @@
+package aaguids
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// u2fKeyPrefix and uafKeyPrefix mark the synthetic dataset keys of U2F and UAF entries, which have
+// no AAGUID.
+const (
+	u2fKeyPrefix = "u2f:"
+	uafKeyPrefix = "uaf:"
+)
+
+/*
+U2FKey returns the dataset key of a U2F entry: "u2f:" followed by its first attestation
+certificate key identifier in lowercase hex.
+
+U2F entries have neither an AAGUID nor an AAID, so this synthetic key stands in for the AAGUID
+wherever entries are keyed: the dataset map, GetEntry, Watch, reports, summaries and exports.
+Lookups accept the prefix and hex in any case.
+*/
+func U2FKey(keyIdentifier string) string {
+	return u2fKeyPrefix + strings.ToLower(keyIdentifier)
+}
+
+/*
+UAFKey returns the dataset key of a UAF entry: "uaf:" followed by its AAID in the canonical
+uppercase "VVVV#MMMM" form of AAID.String, e.g. "uaf:4E4E#4005". A malformed AAID is kept,
+uppercased. Like U2FKey, it stands in for the AAGUID wherever entries are keyed, and lookups accept
+the prefix and AAID in any case; GetEntryByAAID takes the bare AAID.
+*/
+func UAFKey(aaid string) string {
+	return uafKeyPrefix + aaidKey(aaid)
+}
+
+/*
+Key returns the dataset key of e: its AAGUID in the lowercase dashed form of AAGUID.String; for
+entries without one, the UAFKey of its AAID (of the entry, else of its statement), or else the
+U2FKey of its first attestation certificate key identifier. It returns "" for an entry with none
+of them. The key is canonical however the AAGUID is spelled in the entry, so it can be joined on by
+exact string comparison; a malformed AAGUID is returned as is.
+*/
+func (e Entry) Key() string {
+	switch {
+	case e.AAGUID != "":
+		return canonicalKey(e.AAGUID)
+	case e.AAID != "":
+		return UAFKey(e.AAID)
+	case e.MetadataStatement.AAID != "":
+		return UAFKey(e.MetadataStatement.AAID)
+	case len(e.AttestationCertificateKeyIdentifiers) > 0:
+		return U2FKey(e.AttestationCertificateKeyIdentifiers[0])
+	}
+	return ""
+}
+
+// normalizeKey returns synthetic keys in the form they are stored in: U2F keys in lowercase, UAF
+// keys as UAFKey spells them. Other keys are returned unchanged.
+func normalizeKey(k string) string {
+	switch {
+	case hasKeyPrefix(k, u2fKeyPrefix):
+		return strings.ToLower(k)
+	case hasKeyPrefix(k, uafKeyPrefix):
+		return UAFKey(k[len(uafKeyPrefix):])
+	}
+	return k
+}
+
+// hasKeyPrefix reports whether k is prefix, in any case, followed by something.
+func hasKeyPrefix(k, prefix string) bool {
+	return len(k) > len(prefix) && strings.EqualFold(k[:len(prefix)], prefix)
+}
+
+// isSyntheticKey reports whether k, as returned by normalizeKey, is a U2F or UAF key.
+func isSyntheticKey(k string) bool {
+	return strings.HasPrefix(k, u2fKeyPrefix) || strings.HasPrefix(k, uafKeyPrefix)
+}
+
+/*
+canonicalKey returns the form in which the package emits the dataset key k: AAGUIDs in the
+lowercase dashed form of AAGUID.String, synthetic U2F and UAF keys as normalizeKey spells them, and
+anything else as is. Keys are stored as the dataset spells them, so every export, event, log line
+and error message goes through this rather than using the stored key.
+*/
+func canonicalKey(k string) string {
+	if id, err := ParseAAGUID(k); err == nil {
+		return id.String()
+	}
+	return normalizeKey(k)
+}
+
+// canonicalKeyList returns a copy of keys with every key in canonicalKey form.
+func canonicalKeyList(keys []string) []string {
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = canonicalKey(k)
+	}
+	return out
+}
+
+/*
+ComputeCertificateKeyIdentifier returns the FIDO attestation certificate key identifier of cert:
+the lowercase hex SHA-1 of the subjectPublicKey BIT STRING of its SubjectPublicKeyInfo (RFC 5280
+§ 4.2.1.2 method 1), as listed in attestationCertificateKeyIdentifiers.
+*/
+func ComputeCertificateKeyIdentifier(cert *x509.Certificate) (string, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return "", fmt.Errorf("decoding SubjectPublicKeyInfo: %w", err)
+	}
+	sum := sha1.Sum(spki.PublicKey.Bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+/*
+GetEntryForAttestationCertificate finds the entry for a U2F attestation certificate in the
+embedded dataset. See Provider.GetEntryForAttestationCertificate.
+*/
+func GetEntryForAttestationCertificate(cert *x509.Certificate) (Entry, bool) {
+	return Default().GetEntryForAttestationCertificate(cert)
+}
+
+/*
+GetEntryForAttestationCertificate finds the entry for a U2F attestation certificate:
+
+ 1. its key identifier (ComputeCertificateKeyIdentifier) is looked up as by
+    GetEntryByKeyIdentifier
+ 2. failing that, its issuer is matched against the subjects of every entry's attestation roots,
+    preferring U2F entries; the match only counts if it is unambiguous, since large vendors share
+    one root across many models
+*/
+func (p *Provider) GetEntryForAttestationCertificate(cert *x509.Certificate) (Entry, bool) {
+	s := p.current()
+	if id, err := ComputeCertificateKeyIdentifier(cert); err == nil {
+		if k, ok := s.keyIDs[id]; ok {
+			return s.entries[k], true
+		}
+	}
+	candidates := s.rootSubjectIndex()[string(cert.RawIssuer)]
+	var u2f []string
+	for _, k := range candidates {
+		if s.entries[k].MetadataStatement.ProtocolFamily == "u2f" {
+			u2f = append(u2f, k)
+		}
+	}
+	if len(u2f) > 0 {
+		candidates = u2f
+	}
+	if len(candidates) != 1 {
+		return Entry{}, false
+	}
+	return s.entries[candidates[0]], true
+}
+
+// KeyIdentifierDerivation names how LookupByAttestationCertificate derived the key identifier that
+// matched; "" when nothing matched.
+type KeyIdentifierDerivation string
+
+const (
+	KeyIdentifierFromPublicKey    KeyIdentifierDerivation = "public-key-sha1" // see ComputeCertificateKeyIdentifier
+	KeyIdentifierFromSubjectKeyID KeyIdentifierDerivation = "subject-key-id"  // the certificate's SubjectKeyId extension
+)
+
+// LookupByAttestationCertificate finds the entry for an attestation leaf certificate in the embedded
+// dataset. See Provider.LookupByAttestationCertificate.
+func LookupByAttestationCertificate(cert *x509.Certificate) (Entry, KeyIdentifierDerivation, bool) {
+	return Default().LookupByAttestationCertificate(cert)
+}
+
+/*
+LookupByAttestationCertificate finds the entry listing the key identifier of an attestation leaf
+certificate, e.g. the x5c leaf of a WebAuthn registration, and reports which derivation matched:
+
+ 1. KeyIdentifierFromPublicKey: the SHA-1 of the subjectPublicKey, the U2F convention used by
+    attestationCertificateKeyIdentifiers (see ComputeCertificateKeyIdentifier)
+ 2. KeyIdentifierFromSubjectKeyID: the certificate's SubjectKeyId extension, for CAs that derive
+    it differently from method 1 of RFC 5280 § 4.2.1.2
+
+Both are looked up as by GetEntryByKeyIdentifier, so identifiers listed by several entries match
+nothing. Unlike GetEntryForAttestationCertificate, there is no fallback to the issuer.
+*/
+func (p *Provider) LookupByAttestationCertificate(cert *x509.Certificate) (Entry, KeyIdentifierDerivation, bool) {
+	if cert == nil {
+		return Entry{}, "", false
+	}
+	s := p.current()
+	if id, err := ComputeCertificateKeyIdentifier(cert); err == nil {
+		if k, ok := s.keyIDs[id]; ok {
+			return s.entries[k], KeyIdentifierFromPublicKey, true
+		}
+	}
+	if len(cert.SubjectKeyId) > 0 {
+		if k, ok := s.keyIDs[hex.EncodeToString(cert.SubjectKeyId)]; ok {
+			return s.entries[k], KeyIdentifierFromSubjectKeyID, true
+		}
+	}
+	return Entry{}, "", false
+}
+
+/*
+keyIdentifierIndex maps every attestation certificate key identifier, from both the entry and its
+statement, to the dataset key. An identifier listed by several entries is left out of the index
+and returned in conflicts with the sorted keys of all of them, so it never resolves to one of them
+silently.
+*/
+func keyIdentifierIndex(entries map[string]Entry, keys []string) (idx map[string]string, conflicts map[string][]string) {
+	idx = make(map[string]string)
+	for _, k := range keys {
+		e := entries[k]
+		ids := append(append([]string(nil), e.AttestationCertificateKeyIdentifiers...),
+			e.MetadataStatement.AttestationCertificateKeyIdentifiers...)
+		for _, id := range ids {
+			id = strings.ToLower(id)
+			if prev, ok := conflicts[id]; ok {
+				if prev[len(prev)-1] != k {
+					conflicts[id] = append(prev, k)
+				}
+				continue
+			}
+			if other, dup := idx[id]; dup && other != k {
+				if conflicts == nil {
+					conflicts = make(map[string][]string)
+				}
+				conflicts[id] = []string{other, k}
+				delete(idx, id)
+				continue
+			}
+			idx[id] = k
+		}
+	}
+	return idx, conflicts
+}
+
+// ParseKeyIdentifier validates an attestation certificate key identifier, an even-length hex
+// string in any case, and returns it in lowercase.
+func ParseKeyIdentifier(keyID string) (string, error) {
+	id := strings.TrimSpace(keyID)
+	if id == "" {
+		return "", errors.New("aaguids: empty attestation certificate key identifier")
+	}
+	if len(id)%2 != 0 {
+		return "", boundedErrorf("aaguids: attestation certificate key identifier %q has odd length %d", keyID, len(id))
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", boundedErrorf("aaguids: attestation certificate key identifier %q is not hex", keyID)
+	}
+	return strings.ToLower(id), nil
+}
+
+// GetEntryByKeyIdentifier finds the entry listing keyID in the embedded dataset. See
+// Provider.GetEntryByKeyIdentifier.
+func GetEntryByKeyIdentifier(keyID string) (Entry, bool) {
+	return Default().GetEntryByKeyIdentifier(keyID)
+}
+
+/*
+GetEntryByKeyIdentifier finds the entry whose attestationCertificateKeyIdentifiers, in the entry or
+its statement, include keyID in any hex case. It reports false for input ParseKeyIdentifier rejects
+and for identifiers listed by more than one entry (see KeyIdentifierConflicts).
+*/
+func (p *Provider) GetEntryByKeyIdentifier(keyID string) (Entry, bool) {
+	id, err := ParseKeyIdentifier(keyID)
+	if err != nil {
+		return Entry{}, false
+	}
+	s := p.current()
+	k, ok := s.keyIDs[id]
+	if !ok {
+		return Entry{}, false
+	}
+	return s.entries[k], true
+}
+
+// GetEntryWithKeyIdentifiers finds the entry of an authenticator in the embedded dataset by AAGUID or
+// key identifier. See Provider.GetEntryWithKeyIdentifiers.
+func GetEntryWithKeyIdentifiers(aaGuid string, keyIDs []string) (Entry, bool) {
+	return Default().GetEntryWithKeyIdentifiers(aaGuid, keyIDs)
+}
+
+/*
+GetEntryWithKeyIdentifiers finds the entry of an authenticator that may report the all-zero AAGUID,
+such as a U2F security key. A non-zero aaGuid is looked up as by GetEntry; the zero AAGUID, or an
+empty aaGuid, falls back to keyIDs, the attestation certificate key identifiers of the credential
+(see ComputeCertificateKeyIdentifier), tried in order as by GetEntryByKeyIdentifier.
+*/
+func (p *Provider) GetEntryWithKeyIdentifiers(aaGuid string, keyIDs []string) (Entry, bool) {
+	if aaGuid != "" {
+		id, err := p.ParseAAGUID(aaGuid)
+		if err != nil {
+			return p.GetEntry(aaGuid)
+		}
+		if !id.IsZero() {
+			return p.GetEntryID(id)
+		}
+	}
+	for _, k := range keyIDs {
+		if e, ok := p.GetEntryByKeyIdentifier(k); ok {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// KeyIdentifierConflicts reports the key identifiers of the embedded dataset listed by several
+// entries. See Provider.KeyIdentifierConflicts.
+func KeyIdentifierConflicts() map[string][]string {
+	return Default().KeyIdentifierConflicts()
+}
+
+// KeyIdentifierConflicts maps every key identifier of the current snapshot that is listed by more
+// than one entry to the sorted keys of those entries. Such identifiers resolve to no entry.
+func (p *Provider) KeyIdentifierConflicts() map[string][]string {
+	conflicts := make(map[string][]string, len(p.current().keyIDConflicts))
+	for id, keys := range p.current().keyIDConflicts {
+		conflicts[id] = canonicalKeyList(keys)
+	}
+	return conflicts
+}
+
+// checkKeyIdentifiers warns about the key identifier conflicts of cur.
+func (p *Provider) checkKeyIdentifiers(cur *snapshot) {
+	ids := make([]string, 0, len(cur.keyIDConflicts))
+	for id := range cur.keyIDConflicts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		p.log().Warn("aaguids: attestation certificate key identifier is listed by several entries",
+			slog.String("keyIdentifier", id), slog.Any("keys", canonicalKeyList(cur.keyIDConflicts[id])),
+			slog.Int("serial", cur.info.Serial))
+	}
+}
+
+// rootSubjectIndex maps attestation root subjects to the keys of entries trusting them. Parsing
+// every root is comparatively expensive, so the index is built on first use per snapshot.
+func (s *snapshot) rootSubjectIndex() map[string][]string {
+	s.rootsOnce.Do(func() {
+		s.rootSubjects = make(map[string][]string)
+		for _, k := range s.keys {
+			for _, c := range s.entries[k].MetadataStatement.AttestationRootCertificates {
+				der, err := base64.StdEncoding.DecodeString(c)
+				if err != nil {
+					continue
+				}
+				root, err := x509.ParseCertificate(der)
+				if err != nil {
+					continue
+				}
+				subj := string(root.RawSubject)
+				if ks := s.rootSubjects[subj]; len(ks) == 0 || ks[len(ks)-1] != k {
+					s.rootSubjects[subj] = append(ks, k)
+				}
+			}
+		}
+	})
+	return s.rootSubjects
+}
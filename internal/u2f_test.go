@@ -0,0 +1,371 @@
+package aaguids
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testU2FKey = "u2f:bf7bcaa0d0c6187a8c6abbdd16a15640e7c7bde2"
+
+func TestSyntheticKeysInExports(t *testing.T) {
+	blob := readTestBLOB(t, "blob-mixed.json")
+	p := NewProvider(blob.EntriesByKey(), Dataset{})
+	total := len(blob.EntriesByKey())
+
+	var jsonOut bytes.Buffer
+	if err := p.ExportJSON(&jsonOut); err != nil {
+		t.Fatal(err)
+	}
+	var exported struct {
+		Entries map[string]json.RawMessage `json:"entries"`
+	}
+	if err := json.Unmarshal(jsonOut.Bytes(), &exported); err != nil {
+		t.Fatal(err)
+	}
+
+	var csvOut bytes.Buffer
+	if err := p.ExportCapabilityMatrixCSV(&csvOut, nil); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&csvOut).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var matrixOut bytes.Buffer
+	if err := p.ExportCapabilityMatrixJSON(&matrixOut, nil); err != nil {
+		t.Fatal(err)
+	}
+	var matrix []json.RawMessage
+	if err := json.Unmarshal(matrixOut.Bytes(), &matrix); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := 0
+	for range p.Entries() {
+		entries++
+	}
+	counts := map[string]int{
+		"ExportJSON":                 len(exported.Entries),
+		"ExportCapabilityMatrixCSV":  len(rows) - 1,
+		"ExportCapabilityMatrixJSON": len(matrix),
+		"AAGUIDs":                    len(p.AAGUIDs()),
+		"Entries":                    entries,
+		"Summaries":                  len(p.Summaries()),
+	}
+	for export, n := range counts {
+		if n != total {
+			t.Errorf("%s has %d entries, want the dataset size %d", export, n, total)
+		}
+	}
+	if _, ok := exported.Entries[testU2FKey]; !ok {
+		t.Errorf("ExportJSON has no %s", testU2FKey)
+	}
+}
+
+func TestLookupBySyntheticKey(t *testing.T) {
+	p := NewProvider(readTestBLOB(t, "blob-mixed.json").EntriesByKey(), Dataset{})
+	for _, k := range []string{testU2FKey, strings.ToUpper(testU2FKey), "U2F:BF7BCAA0d0c6187a8c6abbdd16a15640e7c7bde2"} {
+		e, ok := p.GetEntry(k)
+		if !ok || e.MetadataStatement.ProtocolFamily != "u2f" {
+			t.Errorf("GetEntry(%q) = %v", k, ok)
+		}
+		if got := e.Key(); got != testU2FKey {
+			t.Errorf("Key() of GetEntry(%q) = %q, want %q", k, got, testU2FKey)
+		}
+	}
+}
+
+func TestMustGetEntryAcceptsEveryGetEntryKey(t *testing.T) {
+	blob := readTestBLOB(t, "blob-mixed.json")
+	old, oldInfo := Default().current().entries, Default().DatasetInfo()
+	Default().Update(blob.EntriesByKey(), Dataset{Serial: blob.No})
+	t.Cleanup(func() { Default().Update(old, oldInfo) })
+
+	for _, k := range []string{
+		testU2FKey,
+		"uaf:4e4e#4005",
+		"EE882879-721C-4913-9775-3DFCCE97072A",
+		"{ee882879-721c-4913-9775-3dfcce97072a}",
+		"urn:uuid:ee882879-721c-4913-9775-3dfcce97072a",
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("MustGetEntry(%q) panicked: %v", k, r)
+				}
+			}()
+			MustGetEntry(k)
+		}()
+	}
+	for k, want := range map[string]string{
+		"not-an-aaguid":                        "invalid AAGUID",
+		"00000000-0000-0000-0000-000000000042": "not present in dataset serial 42",
+		"u2f:00":                               "not present in dataset serial 42",
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(r.(string), want) {
+					t.Errorf("MustGetEntry(%q) panic = %v, want one containing %q", k, r, want)
+				}
+			}()
+			MustGetEntry(k)
+		}()
+	}
+}
+
+func TestWatchAcceptsNormalizedAndSyntheticKeys(t *testing.T) {
+	blob := readTestBLOB(t, "blob-mixed.json")
+	entries := blob.EntriesByKey()
+	p := NewProvider(entries, Dataset{Serial: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := p.Watch(ctx, []string{
+		"{EE882879-721C-4913-9775-3DFCCE97072A}",
+		"U2F:BF7BCAA0D0C6187A8C6ABBDD16A15640E7C7BDE2",
+		"uaf:4e4e#4005",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Watch(ctx, []string{"not-an-aaguid"}); err == nil {
+		t.Error("Watch accepted a malformed AAGUID")
+	}
+
+	p.Update(map[string]Entry{}, Dataset{Serial: 2})
+	got := map[string]ChangeKind{}
+	timeout := time.After(5 * time.Second)
+	for len(got) < 3 {
+		select {
+		case ev := <-ch:
+			got[ev.AAGUID] = ev.Kind
+		case <-timeout:
+			t.Fatalf("received %v, want removals of all three watched entries", got)
+		}
+	}
+	for _, k := range []string{"ee882879-721c-4913-9775-3dfcce97072a", testU2FKey, "uaf:4E4E#4005"} {
+		if got[k] != ChangeRemoved {
+			t.Errorf("event for %s = %v, want ChangeRemoved", k, got[k])
+		}
+	}
+}
+
+// readTestCertificate parses the PEM certificate testdata/name.
+func readTestCertificate(t *testing.T, name string) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode(readTestdata(t, name))
+	if block == nil {
+		t.Fatalf("%s holds no PEM block", name)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// testU2FAttestationKeyID is the key identifier of testdata/u2f-attestation.pem, the SHA-1 of its
+// subjectPublicKey as computed by
+//
+//	openssl x509 -pubkey -noout | openssl pkey -pubin -outform DER | tail -c 65 | sha1sum
+const testU2FAttestationKeyID = "1f2a38f5d8f1007a93f82c9d9f4cc5c170f73817"
+
+func TestGetEntryForAttestationCertificate(t *testing.T) {
+	root := readTestCertificate(t, "u2f-root.pem")
+	leaf := readTestCertificate(t, "u2f-attestation.pem")
+	// Another batch from the same root, whose key identifier the dataset does not list.
+	batch2 := readTestCertificate(t, "u2f-attestation-batch2.pem")
+	unrelated := readTestCertificate(t, "unrelated.pem")
+
+	id, err := ComputeCertificateKeyIdentifier(leaf)
+	if err != nil || id != testU2FAttestationKeyID {
+		t.Fatalf("ComputeCertificateKeyIdentifier = %q, %v; want %q", id, err, testU2FAttestationKeyID)
+	}
+	u2f := Entry{
+		AttestationCertificateKeyIdentifiers: []string{strings.ToUpper(id)},
+		MetadataStatement: MetadataStatement{
+			Description:                 "Example U2F key",
+			ProtocolFamily:              "u2f",
+			AttestationRootCertificates: []string{base64.StdEncoding.EncodeToString(root.Raw)},
+		},
+	}
+	if got, want := u2f.Key(), U2FKey(id); got != want || want != "u2f:"+testU2FAttestationKeyID {
+		t.Fatalf("Key() = %q, U2FKey = %q; want u2f:%s", got, want, testU2FAttestationKeyID)
+	}
+	p := testProvider(t, u2f, Entry{AAGUID: testYubiKey})
+
+	for _, tt := range []struct {
+		name string
+		cert *x509.Certificate
+		want bool
+	}{
+		{"listed key identifier", leaf, true},
+		{"issuer fallback", batch2, true},
+		{"unrelated certificate", unrelated, false},
+	} {
+		e, ok := p.GetEntryForAttestationCertificate(tt.cert)
+		if ok != tt.want || (ok && e.Key() != U2FKey(id)) {
+			t.Errorf("%s: GetEntryForAttestationCertificate = %q, %v; want %v", tt.name, e.Key(), ok, tt.want)
+		}
+	}
+	// The certificate resolves to the entry GetEntry finds by its U2F key.
+	byKey, ok := p.GetEntry(U2FKey(id))
+	if e, _ := p.GetEntryForAttestationCertificate(leaf); !ok || e.Key() != byKey.Key() {
+		t.Errorf("GetEntry(%q) = %q, %v; want the certificate's entry", U2FKey(id), byKey.Key(), ok)
+	}
+
+	// A second entry trusting the same root makes the issuer fallback ambiguous; the listed key
+	// identifier still resolves.
+	sibling := u2f
+	sibling.AttestationCertificateKeyIdentifiers = []string{"00112233445566778899aabbccddeeff00112233"}
+	p = testProvider(t, u2f, sibling)
+	if _, ok := p.GetEntryForAttestationCertificate(batch2); ok {
+		t.Error("ambiguous issuer matched an entry")
+	}
+	if _, ok := p.GetEntryForAttestationCertificate(leaf); !ok {
+		t.Error("listed key identifier stopped matching once the issuer became ambiguous")
+	}
+}
+
+func TestComputeCertificateKeyIdentifierMalformed(t *testing.T) {
+	leaf := readTestCertificate(t, "u2f-attestation.pem")
+	for name, spki := range map[string][]byte{
+		"empty":     nil,
+		"garbage":   []byte("not DER"),
+		"truncated": leaf.RawSubjectPublicKeyInfo[:len(leaf.RawSubjectPublicKeyInfo)/2],
+	} {
+		cert := &x509.Certificate{RawSubjectPublicKeyInfo: spki}
+		if id, err := ComputeCertificateKeyIdentifier(cert); err == nil {
+			t.Errorf("%s SubjectPublicKeyInfo: key identifier %q, want an error", name, id)
+		}
+		if _, ok := testProvider(t, Entry{AAGUID: testYubiKey}).GetEntryForAttestationCertificate(cert); ok {
+			t.Errorf("%s SubjectPublicKeyInfo matched an entry", name)
+		}
+	}
+}
+
+func TestLookupByAttestationCertificate(t *testing.T) {
+	leaf := readTestCertificate(t, "u2f-attestation.pem")
+	// Issued by the same root with a SubjectKeyId that is not the SHA-1 of its public key.
+	skiLeaf := readTestCertificate(t, "attestation-subject-key-id.pem")
+	publicKeyID, err := ComputeCertificateKeyIdentifier(skiLeaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subjectKeyID := hex.EncodeToString(skiLeaf.SubjectKeyId)
+	if publicKeyID == subjectKeyID {
+		t.Fatal("fixture's SubjectKeyId equals its public key identifier")
+	}
+	byPublicKey := Entry{AAGUID: testYubiKey, AttestationCertificateKeyIdentifiers: []string{testU2FAttestationKeyID}}
+	bySKI := Entry{AAGUID: testGPM, MetadataStatement: MetadataStatement{
+		AttestationCertificateKeyIdentifiers: []string{strings.ToUpper(subjectKeyID)},
+	}}
+	p := testProvider(t, byPublicKey, bySKI)
+
+	for _, tt := range []struct {
+		name   string
+		cert   *x509.Certificate
+		aaguid string
+		via    KeyIdentifierDerivation
+	}{
+		{"public key identifier", leaf, testYubiKey, KeyIdentifierFromPublicKey},
+		{"SubjectKeyId extension", skiLeaf, testGPM, KeyIdentifierFromSubjectKeyID},
+		// Same root as both, but there is no issuer fallback.
+		{"unlisted batch", readTestCertificate(t, "u2f-attestation-batch2.pem"), "", ""},
+		{"unrelated certificate", readTestCertificate(t, "unrelated.pem"), "", ""},
+		{"malformed certificate", &x509.Certificate{RawSubjectPublicKeyInfo: []byte("not DER")}, "", ""},
+		{"nil certificate", nil, "", ""},
+	} {
+		e, via, ok := p.LookupByAttestationCertificate(tt.cert)
+		if ok != (tt.aaguid != "") || e.AAGUID != tt.aaguid || via != tt.via {
+			t.Errorf("%s: LookupByAttestationCertificate = %q, %q, %v; want %q, %q", tt.name, e.AAGUID, via, ok, tt.aaguid, tt.via)
+		}
+	}
+
+	// The public-key derivation wins when both identifiers are listed.
+	both := bySKI
+	both.MetadataStatement.AttestationCertificateKeyIdentifiers = []string{subjectKeyID, publicKeyID}
+	if _, via, _ := testProvider(t, both).LookupByAttestationCertificate(skiLeaf); via != KeyIdentifierFromPublicKey {
+		t.Errorf("derivation with both identifiers listed = %q, want %q", via, KeyIdentifierFromPublicKey)
+	}
+}
+
+func TestGetEntryByKeyIdentifier(t *testing.T) {
+	const (
+		own, inStatement, shared = "aa01", "bb02", "cc03"
+		twice                    = "dd04"
+	)
+	u2f := func(n int, entryIDs, statementIDs []string) Entry {
+		return Entry{AttestationCertificateKeyIdentifiers: entryIDs, MetadataStatement: MetadataStatement{
+			Description:                          fmt.Sprintf("U2F key %d", n),
+			AttestationCertificateKeyIdentifiers: statementIDs,
+		}}
+	}
+	entries := map[string]Entry{
+		"u2f:01": u2f(1, []string{strings.ToUpper(own)}, nil),
+		"u2f:02": u2f(2, nil, []string{inStatement}),
+		"u2f:03": u2f(3, []string{shared}, nil),
+		"u2f:04": u2f(4, nil, []string{strings.ToUpper(shared)}),
+		"u2f:05": u2f(5, []string{shared}, nil),
+		// Listing an identifier in both places is not a conflict with itself.
+		"u2f:06": u2f(6, []string{twice}, []string{strings.ToUpper(twice)}),
+	}
+	var logs bytes.Buffer
+	p := NewProvider(entries, Dataset{Serial: 7}, WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	for _, tt := range []struct {
+		keyID, want string
+	}{
+		{own, "u2f:01"},
+		{strings.ToUpper(own), "u2f:01"},
+		{" " + own + "\n", "u2f:01"},
+		{inStatement, "u2f:02"},
+		{strings.ToUpper(inStatement), "u2f:02"},
+		{twice, "u2f:06"},
+		{shared, ""},
+		{"ee05", ""},
+	} {
+		e, ok := p.GetEntryByKeyIdentifier(tt.keyID)
+		want := entries[tt.want].MetadataStatement.Description
+		if ok != (tt.want != "") || e.MetadataStatement.Description != want {
+			t.Errorf("GetEntryByKeyIdentifier(%q) = %q, %v; want %q", tt.keyID, e.MetadataStatement.Description, ok, want)
+		}
+	}
+
+	want := map[string][]string{shared: {"u2f:03", "u2f:04", "u2f:05"}}
+	if got := p.KeyIdentifierConflicts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("KeyIdentifierConflicts() = %v, want %v", got, want)
+	}
+	if n := strings.Count(logs.String(), "listed by several entries"); n != 1 || !strings.Contains(logs.String(), "keyIdentifier="+shared) {
+		t.Errorf("want one warning naming %s, logged:\n%s", shared, logs.String())
+	}
+
+	for _, tt := range []struct {
+		keyID, err string
+	}{
+		{"", "empty"},
+		{"  ", "empty"},
+		{"abc", "odd length 3"},
+		{"zz01", "not hex"},
+	} {
+		if _, err := ParseKeyIdentifier(tt.keyID); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseKeyIdentifier(%q) = %v, want an error mentioning %q", tt.keyID, err, tt.err)
+		}
+		if _, ok := p.GetEntryByKeyIdentifier(tt.keyID); ok {
+			t.Errorf("GetEntryByKeyIdentifier(%q) found an entry", tt.keyID)
+		}
+	}
+}
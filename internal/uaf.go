@@ -0,0 +1,145 @@
+package aaguids
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AAID is a parsed UAF Authenticator Attestation ID: the vendor ID assigned by the FIDO Alliance
+// and the vendor's model ID.
+type AAID struct {
+	VendorID uint16
+	ModelID  uint16
+}
+
+// String returns the canonical uppercase "VVVV#MMMM" form of a, the one AAIDs returns.
+func (a AAID) String() string {
+	return fmt.Sprintf("%04X#%04X", a.VendorID, a.ModelID)
+}
+
+// ErrInvalidAAID reports an AAID that does not have the "hhhh#hhhh" format of the UAF protocol
+// specification; the wrapping error says what was wrong with it.
+var ErrInvalidAAID = errors.New("aaguids: invalid AAID")
+
+/*
+ParseAAID parses an AAID in the "hhhh#hhhh" format required by the UAF protocol specification:
+four hex digits of vendor ID, a "#", and four hex digits of model ID, in either case. Errors wrap
+ErrInvalidAAID.
+*/
+func ParseAAID(s string) (AAID, error) {
+	if len(s) != 9 {
+		return AAID{}, boundedErrorf("%w %q: must be 9 characters in the hhhh#hhhh layout, got %d", ErrInvalidAAID, s, len(s))
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case i == 4:
+			if c != '#' {
+				return AAID{}, boundedErrorf("%w %q: must have a # at position 5, got %q", ErrInvalidAAID, s, c)
+			}
+		case !isHexDigit(c):
+			return AAID{}, boundedErrorf("%w %q: non-hex character %q at position %d", ErrInvalidAAID, s, c, i+1)
+		}
+	}
+	vendor, _ := strconv.ParseUint(s[:4], 16, 16)
+	model, _ := strconv.ParseUint(s[5:], 16, 16)
+	return AAID{VendorID: uint16(vendor), ModelID: uint16(model)}, nil
+}
+
+// ValidateAAID reports whether s is a well-formed AAID, returning the error of ParseAAID if not.
+func ValidateAAID(s string) error {
+	_, err := ParseAAID(s)
+	return err
+}
+
+/*
+ValidateEntryAAIDs checks the AAIDs of e, of the entry and of its metadata statement, and returns
+an error wrapping ErrInvalidAAID for each malformed one, joined with errors.Join. ParseMetadataBLOB
+accepts malformed AAIDs, which GetEntryByAAID can then only match verbatim; the generator reports
+them as warnings.
+*/
+func ValidateEntryAAIDs(e Entry) error {
+	var errs []error
+	if e.AAID != "" {
+		if err := ValidateAAID(e.AAID); err != nil {
+			errs = append(errs, boundedErrorf("aaid: %w", err))
+		}
+	}
+	if e.MetadataStatement.AAID != "" {
+		if err := ValidateAAID(e.MetadataStatement.AAID); err != nil {
+			errs = append(errs, boundedErrorf("metadataStatement.aaid: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// aaidKey returns the index key of aaid: its canonical String form if it parses, or else the
+// trimmed, uppercased input, so malformed AAIDs of the dataset stay reachable.
+func aaidKey(aaid string) string {
+	aaid = strings.TrimSpace(aaid)
+	if a, err := ParseAAID(aaid); err == nil {
+		return a.String()
+	}
+	return strings.ToUpper(aaid)
+}
+
+// GetEntryByAAID finds the entry for a UAF authenticator in the embedded dataset. See
+// Provider.GetEntryByAAID.
+func GetEntryByAAID(aaid string) (Entry, bool) {
+	return Default().GetEntryByAAID(aaid)
+}
+
+/*
+GetEntryByAAID finds the entry whose AAID, in the canonical "XXXX#XXXX" form (vendor ID, then
+authenticator ID, in hex), matches aaid case-insensitively; both sides are normalized through
+ParseAAID, and malformed AAIDs are matched verbatim, ignoring case. UAF entries have no AAGUID;
+they are keyed by the UAFKey of their AAID, which GetEntry accepts too, but this is the way to reach
+them by the bare identifier a UAF client reports. The lookup uses an index built with each
+snapshot.
+*/
+func (p *Provider) GetEntryByAAID(aaid string) (Entry, bool) {
+	s := p.current()
+	k, ok := s.aaids[aaidKey(aaid)]
+	if !ok {
+		return Entry{}, false
+	}
+	return s.entries[k], true
+}
+
+// AAIDs returns the AAIDs of the embedded dataset. See Provider.AAIDs.
+func AAIDs() []string {
+	return Default().AAIDs()
+}
+
+// AAIDs returns a sorted copy of the distinct AAIDs, in uppercase, of the entries of the current
+// snapshot.
+func (p *Provider) AAIDs() []string {
+	idx := p.current().aaids
+	out := make([]string, 0, len(idx))
+	for aaid := range idx {
+		out = append(out, aaid)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// aaidIndex maps the AAID of every entry, from the entry or its statement, to the dataset key. The
+// first key in sorted order wins on duplicates.
+func aaidIndex(entries map[string]Entry, keys []string) map[string]string {
+	idx := make(map[string]string)
+	for _, k := range keys {
+		e := entries[k]
+		for _, aaid := range []string{e.AAID, e.MetadataStatement.AAID} {
+			if aaid == "" {
+				continue
+			}
+			aaid = aaidKey(aaid)
+			if _, dup := idx[aaid]; !dup {
+				idx[aaid] = k
+			}
+		}
+	}
+	return idx
+}
@@ -0,0 +1,147 @@
+package aaguids
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseAAID(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "4e4e#4005", want: "4E4E#4005"},
+		{in: "4E4E#4005", want: "4E4E#4005"},
+		{in: "0001#000a", want: "0001#000A"},
+		{in: "4e4e4005", wantErr: true},
+		{in: "4e4e-4005", wantErr: true},
+		{in: "4e4g#4005", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		a, err := ParseAAID(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAAID) {
+				t.Errorf("ParseAAID(%q) error = %v, want ErrInvalidAAID", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || a.String() != tt.want {
+			t.Errorf("ParseAAID(%q) = %v, %v, want %s", tt.in, a, err, tt.want)
+		}
+	}
+}
+
+func TestEntriesByKeyKeysUAFEntriesByAAID(t *testing.T) {
+	entries := readTestBLOB(t, "blob-mixed.json").EntriesByKey()
+	for _, k := range []string{
+		"ee882879-721c-4913-9775-3dfcce97072a",
+		"uaf:4E4E#4005",
+		"u2f:bf7bcaa0d0c6187a8c6abbdd16a15640e7c7bde2",
+	} {
+		if _, ok := entries[k]; !ok {
+			t.Errorf("EntriesByKey has no %q", k)
+		}
+	}
+	if len(entries) != 3 {
+		t.Errorf("EntriesByKey returned %d entries, want 3 (the entry without identifiers is skipped)", len(entries))
+	}
+}
+
+func TestKeyAndCanonicalKey(t *testing.T) {
+	tests := []struct {
+		name string
+		e    Entry
+		want string
+	}{
+		{"aaguid", Entry{AAGUID: "EE882879-721C-4913-9775-3DFCCE97072A"}, "ee882879-721c-4913-9775-3dfcce97072a"},
+		{"uaf", Entry{AAID: "4e4e#4005"}, "uaf:4E4E#4005"},
+		{"uaf statement only", Entry{MetadataStatement: MetadataStatement{AAID: "4e4e#4005"}}, "uaf:4E4E#4005"},
+		{"uaf before key identifiers", Entry{AAID: "4e4e#4005", AttestationCertificateKeyIdentifiers: []string{"AB"}}, "uaf:4E4E#4005"},
+		{"u2f", Entry{AttestationCertificateKeyIdentifiers: []string{"ABCD"}}, "u2f:abcd"},
+		{"none", Entry{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.e.Key(); got != tt.want {
+			t.Errorf("%s: Key() = %q, want %q", tt.name, got, tt.want)
+		}
+		if tt.want != "" {
+			if got := canonicalKey(tt.want); got != tt.want {
+				t.Errorf("%s: canonicalKey(%q) = %q, want it unchanged", tt.name, tt.want, got)
+			}
+		}
+	}
+	for in, want := range map[string]string{
+		"UAF:4e4e#4005": "uaf:4E4E#4005",
+		"Uaf:4E4E#4005": "uaf:4E4E#4005",
+		"U2F:ABCD":      "u2f:abcd",
+	} {
+		if got := canonicalKey(in); got != want {
+			t.Errorf("canonicalKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestUAFEntryWithoutAAGUID(t *testing.T) {
+	blob := readTestBLOB(t, "blob-mixed.json")
+	p := NewProvider(blob.EntriesByKey(), Dataset{Serial: blob.No, NextUpdate: blob.NextUpdate})
+
+	for _, aaid := range []string{"4e4e#4005", "4E4E#4005", " 4E4e#4005 "} {
+		e, ok := p.GetEntryByAAID(aaid)
+		if !ok || e.MetadataStatement.Description != "Touch ID UAF" {
+			t.Errorf("GetEntryByAAID(%q) = %q, %v", aaid, e.MetadataStatement.Description, ok)
+		}
+		if e.AAGUID != "" {
+			t.Errorf("GetEntryByAAID(%q) returned an entry with AAGUID %q", aaid, e.AAGUID)
+		}
+	}
+	if _, ok := p.GetEntryByAAID("4e4e#4006"); ok {
+		t.Error("GetEntryByAAID found an AAID that is not in the dataset")
+	}
+	for _, k := range []string{"uaf:4E4E#4005", "UAF:4e4e#4005"} {
+		if e, ok := p.GetEntry(k); !ok || e.AAID != "4e4e#4005" {
+			t.Errorf("GetEntry(%q) = %q, %v", k, e.AAID, ok)
+		}
+	}
+	if _, err := p.Lookup("uaf:4e4e"); !errors.Is(err, ErrInvalidAAGUID) {
+		t.Errorf("Lookup of a malformed UAF key: error = %v, want ErrInvalidAAGUID", err)
+	}
+	if _, err := p.Lookup("uaf:4e4e#4006"); !errors.Is(err, ErrUnknownAAGUID) {
+		t.Errorf("Lookup of an absent UAF key: error = %v, want ErrUnknownAAGUID", err)
+	}
+
+	var buf bytes.Buffer
+	if err := p.ExportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Entries map[string]json.RawMessage `json:"entries"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out.Entries["uaf:4E4E#4005"]; !ok || len(out.Entries) != 3 {
+		t.Errorf("ExportJSON entries = %d, want all 3 including uaf:4E4E#4005", len(out.Entries))
+	}
+}
+
+func TestAAIDs(t *testing.T) {
+	blob := readTestBLOB(t, "blob-mixed.json")
+	p := NewProvider(blob.EntriesByKey(), Dataset{})
+
+	got := p.AAIDs()
+	if !slices.Equal(got, []string{"4E4E#4005"}) {
+		t.Fatalf("AAIDs() = %q, want the one UAF entry's AAID", got)
+	}
+	if len(got) != len(p.EntriesUAF()) {
+		t.Errorf("AAIDs() has %d AAIDs for %d UAF entries", len(got), len(p.EntriesUAF()))
+	}
+	got[0] = "mutated"
+	if again := p.AAIDs(); again[0] != "4E4E#4005" {
+		t.Error("mutating the slice returned by AAIDs() changed a later result")
+	}
+}
@@ -0,0 +1,337 @@
+package aaguids
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+/*
+UAFDetails holds the members of a metadata statement that only UAF authenticators use (“FIDO
+Metadata Statement” § 4), as MetadataStatement.UAF:
+
+  - AssertionScheme: the assertion format, e.g. "UAFV1TLV"
+  - Upv: the UAF protocol versions supported
+  - PublicKeyAlgAndEncodings: the public key formats of registrations, e.g. "ecc_x962_raw"
+  - KeyProtection, MatcherProtection: how keys and the verification matcher are protected, e.g.
+    "hardware", "tee" (FIDO Registry § 3.2, § 3.3)
+  - CryptoStrength: the claimed strength in bits of the weakest algorithm; nil when not stated
+  - TcDisplay, TcDisplayContentType: the transaction confirmation display, if any, and the
+    content type it renders, e.g. "text/plain"
+  - LegacyUserVerification: userVerificationDetails as the numeric USER_VERIFY_* flags of schema-2
+    statements (FIDO Registry § 3.1), in the same shape; a method the registry does not define is 0
+
+Statements converted from schema 2 may carry numeric registry values where version 3 uses names:
+MetadataStatement.UnmarshalJSON translates them, including the singular authenticationAlgorithm and
+publicKeyAlgAndEncoding members, and keeps the numeric userVerification values in
+LegacyUserVerification. Numeric values the registry does not define are dropped from the named
+lists. LegacyUserVerification is not part of the JSON form; decoding JSON derives it from the method
+names again.
+*/
+type UAFDetails struct {
+	AssertionScheme          string       `json:"assertionScheme,omitempty"`
+	Upv                      []UAFVersion `json:"upv,omitempty"`
+	PublicKeyAlgAndEncodings []string     `json:"publicKeyAlgAndEncodings,omitempty"`
+	KeyProtection            []string     `json:"keyProtection,omitempty"`
+	MatcherProtection        []string     `json:"matcherProtection,omitempty"`
+	CryptoStrength           *uint16      `json:"cryptoStrength,omitempty"`
+	TcDisplay                []string     `json:"tcDisplay,omitempty"`
+	TcDisplayContentType     string       `json:"tcDisplayContentType,omitempty"`
+	LegacyUserVerification   [][]uint32   `json:"-"`
+}
+
+// UAFVersion is one UAF protocol version of UAFDetails.Upv, e.g. 1.1.
+type UAFVersion struct {
+	Major uint16 `json:"major"`
+	Minor uint16 `json:"minor"`
+}
+
+// String returns the "major.minor" form of v.
+func (v UAFVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// registryValue is a name of the FIDO Registry of Predefined Values and its numeric schema-2 form.
+type registryValue struct {
+	name  string
+	value uint32
+}
+
+// Numeric forms of the FIDO Registry of Predefined Values, by section.
+var (
+	userVerificationRegistry = []registryValue{ // § 3.1, flags
+		{"presence_internal", 0x1}, {"fingerprint_internal", 0x2}, {"passcode_internal", 0x4},
+		{"voiceprint_internal", 0x8}, {"faceprint_internal", 0x10}, {"location_internal", 0x20},
+		{"eyeprint_internal", 0x40}, {"pattern_internal", 0x80}, {"handprint_internal", 0x100},
+		{"none", 0x200}, {"all", 0x400}, {"passcode_external", 0x800}, {"pattern_external", 0x1000},
+	}
+	keyProtectionRegistry = []registryValue{ // § 3.2, flags
+		{"software", 0x1}, {"hardware", 0x2}, {"tee", 0x4}, {"secure_element", 0x8}, {"remote_handle", 0x10},
+	}
+	matcherProtectionRegistry = []registryValue{ // § 3.3, flags
+		{"software", 0x1}, {"tee", 0x2}, {"on_chip", 0x4},
+	}
+	attachmentHintRegistry = []registryValue{ // § 3.4, flags
+		{"internal", 0x1}, {"external", 0x2}, {"wired", 0x4}, {"wireless", 0x8}, {"nfc", 0x10},
+		{"bluetooth", 0x20}, {"network", 0x40}, {"ready", 0x80}, {"wifi_direct", 0x100},
+	}
+	tcDisplayRegistry = []registryValue{ // § 3.5, flags
+		{"any", 0x1}, {"privileged_software", 0x2}, {"tee", 0x4}, {"hardware", 0x8}, {"remote", 0x10},
+	}
+	authenticationAlgorithmRegistry = []registryValue{ // § 3.6.1
+		{"secp256r1_ecdsa_sha256_raw", 0x1}, {"secp256r1_ecdsa_sha256_der", 0x2},
+		{"rsassa_pss_sha256_raw", 0x3}, {"rsassa_pss_sha256_der", 0x4},
+		{"secp256k1_ecdsa_sha256_raw", 0x5}, {"secp256k1_ecdsa_sha256_der", 0x6},
+		{"sm2_sm3_raw", 0x7}, {"rsa_emsa_pkcs1_sha256_raw", 0x8}, {"rsa_emsa_pkcs1_sha256_der", 0x9},
+		{"rsassa_pss_sha384_raw", 0xA}, {"rsassa_pss_sha512_raw", 0xB},
+		{"rsassa_pkcsv15_sha256_raw", 0xC}, {"rsassa_pkcsv15_sha384_raw", 0xD},
+		{"rsassa_pkcsv15_sha512_raw", 0xE}, {"rsassa_pkcsv15_sha1_raw", 0xF},
+		{"secp384r1_ecdsa_sha384_raw", 0x10}, {"secp521r1_ecdsa_sha512_raw", 0x11},
+		{"ed25519_eddsa_sha512_raw", 0x12}, {"ed448_eddsa_sha512_raw", 0x13},
+	}
+	publicKeyRegistry = []registryValue{ // § 3.6.2
+		{"ecc_x962_raw", 0x100}, {"ecc_x962_der", 0x101}, {"rsa_2048_raw", 0x102},
+		{"rsa_2048_der", 0x103}, {"cose", 0x104},
+	}
+	attestationTypeRegistry = []registryValue{ // § 3.6.3
+		{AttestationBasicFull, 0x3E07}, {AttestationBasicSurrogate, 0x3E08}, {AttestationECDAA, 0x3E09},
+		{AttestationAttCA, 0x3E0A}, {AttestationNone, 0x3E0B}, {AttestationAnonCA, 0x3E0C},
+	}
+)
+
+// registryName returns the name of value in reg, or "" if reg does not define it.
+func registryName(reg []registryValue, value uint32) string {
+	if i := slices.IndexFunc(reg, func(r registryValue) bool { return r.value == value }); i >= 0 {
+		return reg[i].name
+	}
+	return ""
+}
+
+// registryNumber returns the numeric form of name in reg, or 0 if reg does not define it.
+func registryNumber(reg []registryValue, name string) uint32 {
+	if i := slices.IndexFunc(reg, func(r registryValue) bool { return r.name == name }); i >= 0 {
+		return reg[i].value
+	}
+	return 0
+}
+
+// registryFlagNames returns the names of the flags set in flags, in registry order.
+func registryFlagNames(reg []registryValue, flags uint32) []string {
+	names := []string{}
+	for _, r := range reg {
+		if flags&r.value != 0 {
+			names = append(names, r.name)
+		}
+	}
+	return names
+}
+
+/*
+UnmarshalJSON decodes a metadata statement. Members in the numeric encoding of schema-2 statements
+are translated to their version 3 names first (see UAFDetails), and for protocolFamily "uaf" the
+UAF members are decoded into m.UAF, which is nil for every other family.
+*/
+func (m *MetadataStatement) UnmarshalJSON(b []byte) error {
+	type plain MetadataStatement
+	*m = MetadataStatement{}
+	err := json.Unmarshal(b, (*plain)(m))
+	var typeErr *json.UnmarshalTypeError
+	if err != nil && !errors.As(err, &typeErr) {
+		return err
+	}
+	if err == nil && m.ProtocolFamily != "uaf" {
+		return nil
+	}
+	b, legacyUV, err := convertLegacyStatement(b)
+	if err != nil {
+		return fmt.Errorf("converting schema-2 members: %w", err)
+	}
+	*m = MetadataStatement{}
+	if err := json.Unmarshal(b, (*plain)(m)); err != nil {
+		return err
+	}
+	if m.ProtocolFamily != "uaf" {
+		return nil
+	}
+	m.UAF = &UAFDetails{}
+	if err := json.Unmarshal(b, m.UAF); err != nil {
+		return fmt.Errorf("decoding UAF members: %w", err)
+	}
+	m.UAF.LegacyUserVerification = legacyUV
+	return nil
+}
+
+// MarshalJSON encodes m with the members of m.UAF alongside the others, as statements carry them.
+func (m MetadataStatement) MarshalJSON() ([]byte, error) {
+	type plain MetadataStatement
+	return json.Marshal(struct {
+		plain
+		*UAFDetails
+	}{plain(m), m.UAF})
+}
+
+/*
+convertLegacyStatement rewrites the members of the statement b that use the numeric encoding of
+schema 2 to their version 3 names, and returns the numeric userVerification flag of every method of
+userVerificationDetails, taken from the statement or derived from the method name.
+*/
+func convertLegacyStatement(b []byte) ([]byte, [][]uint32, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, nil, err
+	}
+	for singular, plural := range map[string]string{
+		"authenticationAlgorithm": "authenticationAlgorithms",
+		"publicKeyAlgAndEncoding": "publicKeyAlgAndEncodings",
+	} {
+		if v, ok := obj[singular]; ok {
+			if _, ok := obj[plural]; !ok {
+				obj[plural] = json.RawMessage("[" + string(v) + "]")
+			}
+			delete(obj, singular)
+		}
+	}
+	for member, reg := range map[string][]registryValue{
+		"keyProtection": keyProtectionRegistry, "matcherProtection": matcherProtectionRegistry,
+		"attachmentHint": attachmentHintRegistry, "tcDisplay": tcDisplayRegistry,
+	} {
+		if v, ok := obj[member]; ok && isJSONNumber(v) {
+			var flags uint32
+			if err := json.Unmarshal(v, &flags); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", member, err)
+			}
+			obj[member], _ = json.Marshal(registryFlagNames(reg, flags))
+		}
+	}
+	for member, reg := range map[string][]registryValue{
+		"authenticationAlgorithms": authenticationAlgorithmRegistry,
+		"publicKeyAlgAndEncodings": publicKeyRegistry, "attestationTypes": attestationTypeRegistry,
+	} {
+		v, ok := obj[member]
+		if !ok || string(v) == "null" {
+			continue
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(v, &items); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", member, err)
+		}
+		names := []string{}
+		for _, item := range items {
+			var name string
+			if isJSONNumber(item) {
+				var n uint32
+				if err := json.Unmarshal(item, &n); err != nil {
+					return nil, nil, fmt.Errorf("%s: %w", member, err)
+				}
+				name = registryName(reg, n)
+			} else if err := json.Unmarshal(item, &name); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", member, err)
+			}
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		obj[member], _ = json.Marshal(names)
+	}
+
+	var legacy [][]uint32
+	if v, ok := obj["userVerificationDetails"]; ok && string(v) != "null" {
+		var details [][]map[string]json.RawMessage
+		if err := json.Unmarshal(v, &details); err != nil {
+			return nil, nil, fmt.Errorf("userVerificationDetails: %w", err)
+		}
+		legacy = make([][]uint32, len(details))
+		for i, methods := range details {
+			legacy[i] = make([]uint32, len(methods))
+			for j, method := range methods {
+				var name string
+				if raw, ok := method["userVerificationMethod"]; ok {
+					if err := json.Unmarshal(raw, &name); err != nil {
+						return nil, nil, fmt.Errorf("userVerificationDetails[%d][%d]: %w", i, j, err)
+					}
+				}
+				raw, ok := method["userVerification"]
+				if !ok {
+					legacy[i][j] = registryNumber(userVerificationRegistry, name)
+					continue
+				}
+				if err := json.Unmarshal(raw, &legacy[i][j]); err != nil {
+					return nil, nil, fmt.Errorf("userVerificationDetails[%d][%d]: %w", i, j, err)
+				}
+				if name == "" {
+					name = registryName(userVerificationRegistry, legacy[i][j])
+				}
+				delete(method, "userVerification")
+				method["userVerificationMethod"], _ = json.Marshal(name)
+			}
+		}
+		obj["userVerificationDetails"], _ = json.Marshal(details)
+	}
+	out, err := json.Marshal(obj)
+	return out, legacy, err
+}
+
+// isJSONNumber reports whether raw is a JSON number.
+func isJSONNumber(raw json.RawMessage) bool {
+	s := strings.TrimSpace(string(raw))
+	return s != "" && (s[0] == '-' || s[0] >= '0' && s[0] <= '9')
+}
+
+// UAFDetails returns the UAF members of e's metadata statement, or false unless its protocolFamily
+// is "uaf". The slices are shared with the dataset and must not be modified.
+func (e Entry) UAFDetails() (UAFDetails, bool) {
+	if u := e.MetadataStatement.UAF; u != nil {
+		return *u, true
+	}
+	return UAFDetails{}, false
+}
+
+// EntriesUAF returns the UAF entries of the embedded dataset. See Provider.EntriesUAF.
+func EntriesUAF() []Entry {
+	return Default().EntriesUAF()
+}
+
+// EntriesUAF returns the entries whose statement declares protocolFamily "uaf", in ascending key
+// order. Look them up by AAID with GetEntryByAAID.
+func (p *Provider) EntriesUAF() []Entry {
+	return slices.Collect(p.Where(ProtocolFamilyIs("uaf")))
+}
+
+// ErrIncompleteUAFEntry is returned by ValidateUAFEntry for a UAF entry lacking what UAF clients
+// need to use it.
+var ErrIncompleteUAFEntry = errors.New("aaguids: incomplete UAF entry")
+
+/*
+ValidateUAFEntry checks that a UAF entry is internally consistent, and returns an error wrapping
+ErrIncompleteUAFEntry for each problem, joined with errors.Join:
+
+  - neither the entry nor its statement has an AAID, or the two AAIDs differ
+  - the statement has no UAF details, or they lack assertionScheme or upv
+
+Entries of other protocol families pass. The generator reports failures as warnings.
+*/
+func ValidateUAFEntry(e Entry) error {
+	if e.MetadataStatement.ProtocolFamily != "uaf" {
+		return nil
+	}
+	var errs []error
+	switch a, m := e.AAID, e.MetadataStatement.AAID; {
+	case a == "" && m == "":
+		errs = append(errs, fmt.Errorf("%w: no aaid", ErrIncompleteUAFEntry))
+	case a != "" && m != "" && aaidKey(a) != aaidKey(m):
+		errs = append(errs, boundedErrorf("%w: aaid %q differs from metadataStatement.aaid %q", ErrIncompleteUAFEntry, a, m))
+	}
+	u, ok := e.UAFDetails()
+	switch {
+	case !ok:
+		errs = append(errs, fmt.Errorf("%w: no UAF details", ErrIncompleteUAFEntry))
+	case u.AssertionScheme == "":
+		errs = append(errs, fmt.Errorf("%w: no assertionScheme", ErrIncompleteUAFEntry))
+	}
+	if ok && len(u.Upv) == 0 {
+		errs = append(errs, fmt.Errorf("%w: no upv", ErrIncompleteUAFEntry))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,160 @@
+package aaguids
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// The fixtures are the sample UAF authenticator of the FIDO Metadata Statement specification, without
+// its attestation root certificate and icon: uaf-statement.json as a version 3 statement, and
+// uaf-statement-schema2.json with the numeric registry encoding of schema-2 statements.
+
+func decodeTestStatement(t *testing.T, name string) MetadataStatement {
+	t.Helper()
+	var m MetadataStatement
+	if err := json.Unmarshal(readTestdata(t, name), &m); err != nil {
+		t.Fatalf("decoding %s: %v", name, err)
+	}
+	return m
+}
+
+func TestUAFStatementDetails(t *testing.T) {
+	want := UAFDetails{
+		AssertionScheme:          "UAFV1TLV",
+		Upv:                      []UAFVersion{{1, 0}, {1, 1}},
+		PublicKeyAlgAndEncodings: []string{"ecc_x962_raw"},
+		KeyProtection:            []string{"hardware", "tee"},
+		MatcherProtection:        []string{"tee"},
+		CryptoStrength:           goPtr(uint16(128)),
+		TcDisplay:                []string{"any", "tee"},
+		TcDisplayContentType:     "image/png",
+		LegacyUserVerification:   [][]uint32{{0x2}},
+	}
+	for _, name := range []string{"uaf-statement.json", "uaf-statement-schema2.json"} {
+		t.Run(name, func(t *testing.T) {
+			m := decodeTestStatement(t, name)
+			if m.UAF == nil {
+				t.Fatal("UAF details not decoded")
+			}
+			if !reflect.DeepEqual(*m.UAF, want) {
+				t.Errorf("UAF = %+v, want %+v", *m.UAF, want)
+			}
+			for field, pair := range map[string][2]any{
+				"authenticationAlgorithms": {m.AuthenticationAlgorithms, []string{"secp256r1_ecdsa_sha256_raw"}},
+				"attestationTypes":         {m.AttestationTypes, []string{AttestationBasicFull}},
+				"attachmentHint":           {m.AttachmentHint, []string{"internal"}},
+				"userVerificationDetails": {m.UserVerificationDetails,
+					[][]VerificationMethod{{{UserVerificationMethod: "fingerprint_internal"}}}},
+			} {
+				if !reflect.DeepEqual(pair[0], pair[1]) {
+					t.Errorf("%s = %v, want %v", field, pair[0], pair[1])
+				}
+			}
+			if m.AAID != "1234#5678" || !m.IsKeyRestricted {
+				t.Errorf("aaid = %q, isKeyRestricted = %v", m.AAID, m.IsKeyRestricted)
+			}
+		})
+	}
+}
+
+func TestUAFStatementRoundTrip(t *testing.T) {
+	for _, name := range []string{"uaf-statement.json", "uaf-statement-schema2.json"} {
+		m := decodeTestStatement(t, name)
+		b, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again MetadataStatement
+		if err := json.Unmarshal(b, &again); err != nil {
+			t.Fatalf("%s: decoding the re-encoded statement: %v", name, err)
+		}
+		if !reflect.DeepEqual(again, m) {
+			t.Errorf("%s: round trip changed the statement:\n got %+v\nwant %+v", name, again, m)
+		}
+	}
+}
+
+func TestUAFStatementNullMembersRoundTrip(t *testing.T) {
+	// An Entry encodes absent lists as null; decoding that must not turn them into empty lists.
+	for _, in := range []string{
+		`{"protocolFamily":"uaf","assertionScheme":"UAFV1TLV"}`,
+		`{"protocolFamily":"uaf","assertionScheme":"UAFV1TLV","authenticationAlgorithms":null,"attestationTypes":null,"publicKeyAlgAndEncodings":null}`,
+	} {
+		var m MetadataStatement
+		if err := json.Unmarshal([]byte(in), &m); err != nil {
+			t.Fatal(err)
+		}
+		if m.AuthenticationAlgorithms != nil || m.AttestationTypes != nil || m.UAF.PublicKeyAlgAndEncodings != nil {
+			t.Errorf("%s: decoded absent lists as %#v, %#v, %#v, want nil", in,
+				m.AuthenticationAlgorithms, m.AttestationTypes, m.UAF.PublicKeyAlgAndEncodings)
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again MetadataStatement
+		if err := json.Unmarshal(b, &again); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(again, m) {
+			t.Errorf("%s: round trip changed the UAF details:\n got %#v\nwant %#v", in, *again.UAF, *m.UAF)
+		}
+	}
+}
+
+func TestUAFDetailsOnlyForUAF(t *testing.T) {
+	var m MetadataStatement
+	if err := json.Unmarshal([]byte(`{"protocolFamily":"fido2","keyProtection":["hardware"]}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.UAF != nil {
+		t.Errorf("UAF = %+v for a fido2 statement, want nil", m.UAF)
+	}
+	if _, ok := (Entry{MetadataStatement: m}).UAFDetails(); ok {
+		t.Error("UAFDetails reported details for a fido2 entry")
+	}
+}
+
+func TestEntriesUAFAndValidateUAFEntry(t *testing.T) {
+	uaf := Entry{AAID: "1234#5678", MetadataStatement: decodeTestStatement(t, "uaf-statement.json")}
+	fido2 := Entry{AAGUID: "ee882879-721c-4913-9775-3dfcce97072a",
+		MetadataStatement: MetadataStatement{ProtocolFamily: "fido2"}}
+	p := testProvider(t, uaf, fido2)
+
+	got := p.EntriesUAF()
+	if len(got) != 1 || got[0].AAID != "1234#5678" {
+		t.Fatalf("EntriesUAF() = %d entries, want the UAF one", len(got))
+	}
+	if d, ok := got[0].UAFDetails(); !ok || d.AssertionScheme != "UAFV1TLV" {
+		t.Errorf("UAFDetails() = %+v, %v", d, ok)
+	}
+	if e, ok := p.GetEntryByAAID("1234#5678"); !ok || e.MetadataStatement.UAF == nil {
+		t.Errorf("GetEntryByAAID did not find the UAF entry with its details")
+	}
+
+	if err := ValidateUAFEntry(uaf); err != nil {
+		t.Errorf("ValidateUAFEntry(complete entry) = %v", err)
+	}
+	if err := ValidateUAFEntry(fido2); err != nil {
+		t.Errorf("ValidateUAFEntry(fido2 entry) = %v", err)
+	}
+	tests := []struct {
+		name string
+		edit func(*Entry)
+	}{
+		{"no aaid", func(e *Entry) { e.AAID, e.MetadataStatement.AAID = "", "" }},
+		{"aaids differ", func(e *Entry) { e.AAID = "1234#5679" }},
+		{"no details", func(e *Entry) { e.MetadataStatement.UAF = nil }},
+		{"no assertion scheme", func(e *Entry) { e.MetadataStatement.UAF = &UAFDetails{Upv: []UAFVersion{{1, 1}}} }},
+		{"no upv", func(e *Entry) { e.MetadataStatement.UAF = &UAFDetails{AssertionScheme: "UAFV1TLV"} }},
+	}
+	for _, tt := range tests {
+		e := uaf
+		tt.edit(&e)
+		if err := ValidateUAFEntry(e); !errors.Is(err, ErrIncompleteUAFEntry) {
+			t.Errorf("%s: ValidateUAFEntry = %v, want ErrIncompleteUAFEntry", tt.name, err)
+		}
+	}
+}
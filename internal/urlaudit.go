@@ -0,0 +1,232 @@
+package aaguids
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+URLCheck is the result of checking one URL referenced by the dataset.
+
+  - Entries: keys of the entries referencing the URL, sorted
+  - Method: the request method that produced StatusCode (GET when HEAD is not supported)
+  - FinalURL / CrossHostRedirect: where redirects ended, and whether that is on another host
+  - TLSError: set when the request failed because of the TLS handshake or certificate
+  - Error: any other request failure, including a response body cut short
+*/
+type URLCheck struct {
+	URL               string   `json:"url"`
+	Entries           []string `json:"entries"`
+	Method            string   `json:"method,omitempty"`
+	StatusCode        int      `json:"statusCode,omitempty"`
+	FinalURL          string   `json:"finalURL,omitempty"`
+	CrossHostRedirect bool     `json:"crossHostRedirect,omitempty"`
+	TLSError          string   `json:"tlsError,omitempty"`
+	Error             string   `json:"error,omitempty"`
+}
+
+// OK reports whether the URL answered with a 2xx status.
+func (c URLCheck) OK() bool {
+	return c.StatusCode >= 200 && c.StatusCode < 300
+}
+
+// AuditOption adjusts AuditURLs.
+type AuditOption func(*audit)
+
+// audit is the configuration of one AuditURLs run.
+type audit struct {
+	hostDelay time.Duration
+	done      map[string]URLCheck
+}
+
+// defaultHostDelay is the minimum time between two requests to the same host.
+const defaultHostDelay = time.Second
+
+// WithHostDelay sets the minimum interval between two requests to the same host (default one
+// second). Requests to one host are never made concurrently.
+func WithHostDelay(d time.Duration) AuditOption {
+	return func(a *audit) { a.hostDelay = d }
+}
+
+// WithResume skips URLs already checked by an earlier, interrupted run and returns their previous
+// results instead. Checks that failed with an Error are retried.
+func WithResume(previous []URLCheck) AuditOption {
+	return func(a *audit) {
+		for _, c := range previous {
+			if c.Error == "" {
+				a.done[c.URL] = c
+			}
+		}
+	}
+}
+
+// AuditURLs checks the URLs of the embedded dataset. See Provider.AuditURLs.
+func AuditURLs(ctx context.Context, client *http.Client, concurrency int, opts ...AuditOption) []URLCheck {
+	return Default().AuditURLs(ctx, client, concurrency, opts...)
+}
+
+/*
+AuditURLs collects every unique URL of the status reports and rogue lists in the current snapshot
+and checks that it still resolves. It is opt-in tooling that performs network requests and is
+never run by the package itself.
+
+Each URL gets a HEAD request, retried as GET when the server rejects HEAD. At most concurrency
+requests run at once, and requests to the same host are serialized and spaced by the host delay. All
+requests go through client (http.DefaultClient if nil), so tests can fake the network with a custom
+Transport, and through the Provider's FetchCoordinator when one is set. Results are sorted by URL;
+when ctx is cancelled the remaining URLs are reported with ctx's error, and the results can be
+passed to WithResume to continue later.
+*/
+func (p *Provider) AuditURLs(ctx context.Context, client *http.Client, concurrency int, opts ...AuditOption) []URLCheck {
+	a := audit{hostDelay: defaultHostDelay, done: make(map[string]URLCheck)}
+	for _, opt := range opts {
+		opt(&a)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	client = p.fetchClient(client)
+
+	checks := collectURLs(p.current())
+	var (
+		wg    sync.WaitGroup
+		sem   = make(chan struct{}, concurrency)
+		hosts sync.Map // host → *hostGate
+	)
+	for i := range checks {
+		c := &checks[i]
+		if prev, ok := a.done[c.URL]; ok {
+			prev.Entries = c.Entries
+			*c = prev
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				c.Error = ctx.Err().Error()
+				return
+			}
+			defer func() { <-sem }()
+
+			host := c.URL
+			if u, err := url.Parse(c.URL); err == nil {
+				host = u.Host
+			}
+			g, _ := hosts.LoadOrStore(host, &hostGate{})
+			gate := g.(*hostGate)
+			gate.Lock()
+			defer gate.Unlock()
+			if wait := a.hostDelay - time.Since(gate.last); !gate.last.IsZero() && wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					c.Error = ctx.Err().Error()
+					return
+				}
+			}
+			checkURL(ctx, client, c)
+			gate.last = time.Now()
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+// hostGate serializes and spaces the requests to one host.
+type hostGate struct {
+	sync.Mutex
+	last time.Time
+}
+
+// collectURLs returns one URLCheck per unique status report or rogue list URL, sorted by URL.
+func collectURLs(s *snapshot) []URLCheck {
+	refs := make(map[string][]string)
+	add := func(u, key string) {
+		if u == "" {
+			return
+		}
+		if ks := refs[u]; len(ks) == 0 || ks[len(ks)-1] != key {
+			refs[u] = append(ks, key)
+		}
+	}
+	for _, k := range s.keys {
+		e := s.entries[k]
+		for _, r := range e.StatusReports {
+			if r.URL != nil {
+				add(*r.URL, k)
+			}
+		}
+		add(e.RogueListURL, k)
+	}
+	checks := make([]URLCheck, 0, len(refs))
+	for u, ks := range refs {
+		checks = append(checks, URLCheck{URL: u, Entries: ks})
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].URL < checks[j].URL })
+	return checks
+}
+
+// checkURL performs the HEAD (then GET) request for c and records the outcome in c.
+func checkURL(ctx context.Context, client *http.Client, c *URLCheck) {
+	resp, err := doRequest(ctx, client, http.MethodHead, c.URL)
+	c.Method = http.MethodHead
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = doRequest(ctx, client, http.MethodGet, c.URL)
+		c.Method = http.MethodGet
+	}
+	if err != nil {
+		if isTLSError(err) {
+			c.TLSError = err.Error()
+		} else {
+			c.Error = err.Error()
+		}
+		return
+	}
+	defer resp.Body.Close()
+	c.StatusCode = resp.StatusCode
+	if _, err := io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)); err != nil {
+		c.Error = "reading body: " + err.Error()
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		if final := resp.Request.URL.String(); final != c.URL {
+			c.FinalURL = final
+			if orig, err := url.Parse(c.URL); err == nil {
+				c.CrossHostRedirect = orig.Host != resp.Request.URL.Host
+			}
+		}
+	}
+}
+
+func doRequest(ctx context.Context, client *http.Client, method, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// isTLSError reports whether err stems from the TLS handshake or certificate verification.
+func isTLSError(err error) bool {
+	var (
+		verifyErr  *tls.CertificateVerificationError
+		recordErr  tls.RecordHeaderError
+		alertErr   tls.AlertError
+		unknownErr x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		invalidErr x509.CertificateInvalidError
+	)
+	return errors.As(err, &verifyErr) || errors.As(err, &recordErr) || errors.As(err, &alertErr) ||
+		errors.As(err, &unknownErr) || errors.As(err, &hostErr) || errors.As(err, &invalidErr)
+}
@@ -0,0 +1,123 @@
+package aaguids
+
+import (
+	"context"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAuditURLs(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		hits = make(map[string]int)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/ok":
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/truncated":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			// Promise more than is sent; the server closes the connection after the handler.
+			w.Header().Set("Content-Length", "100")
+			io.WriteString(w, "cut short")
+		case "/moved":
+			http.Redirect(w, r, "http://"+strings.Replace(r.Host, "127.0.0.1", "localhost", 1)+"/ok", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	tlsSrv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer tlsSrv.Close()
+
+	str := func(s string) *string { return &s }
+	withURL := func(u string) StatusReport {
+		r := report(FIDO_CERTIFIED_L1, "2024-01-01")
+		r.URL = str(u)
+		return r
+	}
+	p := testProvider(t,
+		Entry{AAGUID: testYubiKey, StatusReports: []StatusReport{withURL(srv.URL + "/ok"), withURL(srv.URL + "/down")}},
+		Entry{AAGUID: testGPM, StatusReports: []StatusReport{withURL(srv.URL + "/ok")}, RogueListURL: srv.URL + "/truncated"},
+		Entry{AAGUID: testWindowsHello, StatusReports: []StatusReport{withURL(srv.URL + "/moved"), withURL(srv.URL + "/gone")}},
+		Entry{AAGUID: testYubiKey54, StatusReports: []StatusReport{withURL(tlsSrv.URL + "/")}},
+	)
+
+	checks := p.AuditURLs(context.Background(), &http.Client{}, 3, WithHostDelay(0))
+	byURL := make(map[string]URLCheck, len(checks))
+	for i, c := range checks {
+		if i > 0 && checks[i-1].URL >= c.URL {
+			t.Errorf("results not sorted by URL: %q before %q", checks[i-1].URL, c.URL)
+		}
+		byURL[strings.TrimPrefix(strings.TrimPrefix(c.URL, srv.URL), tlsSrv.URL)] = c
+	}
+	if len(byURL) != 6 {
+		t.Fatalf("%d URLs checked, want 6: %+v", len(byURL), checks)
+	}
+
+	for _, tt := range []struct {
+		path   string
+		method string
+		status int
+		ok     bool
+		err    string
+	}{
+		{"/ok", http.MethodHead, http.StatusOK, true, ""},
+		{"/down", http.MethodHead, http.StatusServiceUnavailable, false, ""},
+		{"/gone", http.MethodHead, http.StatusNotFound, false, ""},
+		{"/truncated", http.MethodGet, http.StatusOK, true, "reading body: unexpected EOF"},
+		{"/moved", http.MethodHead, http.StatusOK, true, ""},
+	} {
+		c := byURL[tt.path]
+		if c.Method != tt.method || c.StatusCode != tt.status || c.OK() != tt.ok || c.Error != tt.err || c.TLSError != "" {
+			t.Errorf("%s: %+v; want %s %d, error %q", tt.path, c, tt.method, tt.status, tt.err)
+		}
+	}
+	if c := byURL["/ok"]; strings.Join(c.Entries, ",") != testGPM+","+testYubiKey {
+		t.Errorf("/ok entries = %q, want both referencing entries once", c.Entries)
+	}
+	if c := byURL["/moved"]; !c.CrossHostRedirect || !strings.Contains(c.FinalURL, "localhost") {
+		t.Errorf("/moved: FinalURL %q, CrossHostRedirect %v; want a cross-host redirect", c.FinalURL, c.CrossHostRedirect)
+	}
+	if c := byURL["/"]; c.TLSError == "" || c.Error != "" || c.StatusCode != 0 {
+		t.Errorf("untrusted TLS server: %+v, want a TLSError", c)
+	}
+
+	// Resuming requests only the URLs without a successful result.
+	mu.Lock()
+	clear(hits)
+	mu.Unlock()
+	again := p.AuditURLs(context.Background(), &http.Client{}, 3, WithHostDelay(0), WithResume(checks))
+	if len(again) != len(checks) {
+		t.Fatalf("resumed run returned %d results, want %d", len(again), len(checks))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := map[string]int{"HEAD /truncated": 1, "GET /truncated": 1}; !maps.Equal(hits, want) {
+		t.Errorf("resumed run requested %v, want only the truncated URL again (%v)", hits, want)
+	}
+}
+
+func TestAuditURLsCancelled(t *testing.T) {
+	str := func(s string) *string { return &s }
+	r := report(FIDO_CERTIFIED_L1, "2024-01-01")
+	r.URL = str("http://example.invalid/status")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	checks := testProvider(t, Entry{AAGUID: testYubiKey, StatusReports: []StatusReport{r}}).AuditURLs(ctx, &http.Client{}, 1)
+	if len(checks) != 1 || !strings.Contains(checks[0].Error, context.Canceled.Error()) {
+		t.Errorf("AuditURLs with a cancelled context = %+v, want the context error", checks)
+	}
+}
@@ -0,0 +1,168 @@
+package aaguids
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Confidence grades how a vendor name was determined.
+type Confidence int
+
+const (
+	// ConfidenceUnknown means no vendor could be determined.
+	ConfidenceUnknown Confidence = iota
+	// ConfidenceDerived means the vendor was guessed from the entry description.
+	ConfidenceDerived
+	// ConfidenceCurated means the vendor comes from the reviewed vendor table.
+	ConfidenceCurated
+)
+
+// String returns "unknown", "derived" or "curated".
+func (c Confidence) String() string {
+	switch c {
+	case ConfidenceDerived:
+		return "derived"
+	case ConfidenceCurated:
+		return "curated"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the confidence as its String form.
+func (c Confidence) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// VendorInfo is the vendor attributed to an AAGUID together with how it was determined.
+type VendorInfo struct {
+	Name       string     `json:"name"`
+	Confidence Confidence `json:"confidence"`
+}
+
+/*
+VendorTable is the reviewed AAGUID → vendor mapping maintained in vendors.json at the generator's
+repository root and stamped into this package by the generator. Exact matches take precedence over
+prefixes; among prefixes the longest match wins. Keys are lowercase.
+*/
+type VendorTable struct {
+	Exact    map[string]string `json:"exact"`
+	Prefixes []VendorPrefix    `json:"prefixes"`
+}
+
+// VendorPrefix attributes every AAGUID starting with Prefix to Vendor, for vendors that allocate
+// AAGUIDs from a fixed range.
+type VendorPrefix struct {
+	Prefix string `json:"prefix"`
+	Vendor string `json:"vendor"`
+}
+
+// lookup returns the curated vendor for the lowercase AAGUID id.
+func (t VendorTable) lookup(id string) (string, bool) {
+	if v, ok := t.Exact[id]; ok {
+		return v, true
+	}
+	best, bestLen := "", 0
+	for _, p := range t.Prefixes {
+		if len(p.Prefix) > bestLen && strings.HasPrefix(id, p.Prefix) {
+			best, bestLen = p.Vendor, len(p.Prefix)
+		}
+	}
+	return best, bestLen > 0
+}
+
+// Vendor returns the vendor of the authenticator identified by aaGuid in the embedded dataset.
+func Vendor(aaGuid string) VendorInfo {
+	return Default().Vendor(aaGuid)
+}
+
+/*
+Vendor returns the vendor of the authenticator identified by aaGuid: the curated table first, then
+a heuristic over the entry's description (see deriveVendor), then ConfidenceUnknown.
+*/
+func (p *Provider) Vendor(aaGuid string) VendorInfo {
+	if name, ok := curatedVendors.lookup(strings.ToLower(aaGuid)); ok {
+		return VendorInfo{Name: name, Confidence: ConfidenceCurated}
+	}
+	if e, ok := p.GetEntry(aaGuid); ok {
+		if name := deriveVendor(e.DisplayName()); name != "" {
+			return VendorInfo{Name: name, Confidence: ConfidenceDerived}
+		}
+	}
+	return VendorInfo{}
+}
+
+// productLines maps description prefixes naming a product line rather than a company to the
+// company behind it. Longer prefixes are listed before shorter ones sharing a first word.
+var productLines = []struct{ prefix, vendor string }{
+	{"windows hello", "Microsoft"},
+	{"microsoft", "Microsoft"},
+	{"icloud", "Apple"},
+	{"touch id", "Apple"},
+	{"face id", "Apple"},
+	{"google password manager", "Google"},
+	{"chrome", "Google"},
+	{"android", "Google"},
+	{"titan", "Google"},
+	{"yubikey", "Yubico"},
+	{"security key by yubico", "Yubico"},
+	{"epass", "Feitian"},
+	{"biopass", "Feitian"},
+	{"allinpass", "Feitian"},
+	{"iepass", "Feitian"},
+	{"safenet", "Thales"},
+	{"etoken", "Thales"},
+	{"idprime", "Thales"},
+	{"crescendo", "HID Global"},
+	{"solo", "SoloKeys"},
+	{"samsung pass", "Samsung"},
+	{"verimark", "Kensington"},
+	{"atkey", "AuthenTrend"},
+	{"hyperfido", "HyperSecu"},
+	{"hyper fido", "HyperSecu"},
+}
+
+// companySuffixes are trailing legal-form tokens stripped from derived vendor names.
+var companySuffixes = map[string]bool{
+	"inc": true, "inc.": true, "ltd": true, "ltd.": true, "llc": true, "gmbh": true, "ag": true,
+	"co": true, "co.": true, "corp": true, "corp.": true, "corporation": true, "limited": true,
+	"s.a.": true, "sa": true, "b.v.": true, "bv": true, "plc": true, "oy": true, "ab": true,
+}
+
+/*
+deriveVendor guesses a vendor from a metadata description:
+
+ 1. descriptions starting with a known product line ("Windows Hello ...") map to its company
+ 2. "<product> by <Company>" yields the text after the last " by "
+ 3. otherwise the first word is taken as the company name
+
+Legal-form suffixes ("Inc.", "Ltd", ...) and trailing punctuation are stripped from the result.
+*/
+func deriveVendor(description string) string {
+	d := strings.TrimSpace(description)
+	if d == "" {
+		return ""
+	}
+	lower := strings.ToLower(d)
+	for _, pl := range productLines {
+		if strings.HasPrefix(lower, pl.prefix) {
+			return pl.vendor
+		}
+	}
+	if i := strings.LastIndex(lower, " by "); i >= 0 {
+		return stripCompanySuffixes(d[i+len(" by "):])
+	}
+	first := strings.FieldsFunc(d, func(r rune) bool { return unicode.IsSpace(r) || r == ',' })
+	if len(first) == 0 {
+		return ""
+	}
+	return stripCompanySuffixes(first[0])
+}
+
+// stripCompanySuffixes removes trailing legal-form tokens and punctuation from a company name.
+func stripCompanySuffixes(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return unicode.IsSpace(r) || r == ',' })
+	for len(words) > 1 && companySuffixes[strings.ToLower(words[len(words)-1])] {
+		words = words[:len(words)-1]
+	}
+	return strings.TrimRight(strings.Join(words, " "), ".,;:")
+}
@@ -0,0 +1,52 @@
+package aaguids
+
+import "testing"
+
+func TestDeriveVendor(t *testing.T) {
+	for _, tt := range []struct {
+		description, want string
+	}{
+		{"Windows Hello Hardware Authenticator", "Microsoft"},
+		{"Windows Hello VBS Software Authenticator", "Microsoft"},
+		{"windows hello", "Microsoft"},
+		{"iCloud Keychain", "Apple"},
+		{"YubiKey 5 Series with NFC", "Yubico"},
+		{"Security Key by Yubico", "Yubico"},
+		{"Titan Security Key v2", "Google"},
+		{"FIDO2 Key by Acme Corp.", "Acme"},
+		{"Authenticator by Example Widgets GmbH", "Example Widgets"},
+		{"Nitrokey, Inc.", "Nitrokey"},
+		{"  Token2 PIN+  ", "Token2"},
+		{"", ""},
+	} {
+		if got := deriveVendor(tt.description); got != tt.want {
+			t.Errorf("deriveVendor(%q) = %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
+
+func TestVendorWindowsHello(t *testing.T) {
+	p := testProvider(t, Entry{
+		AAGUID:            testWindowsHello,
+		MetadataStatement: MetadataStatement{Description: "Windows Hello Hardware Authenticator"},
+	})
+
+	// Without a curated row the description's product line names Microsoft.
+	old := curatedVendors
+	curatedVendors = VendorTable{}
+	t.Cleanup(func() { curatedVendors = old })
+	if got, want := p.Vendor(testWindowsHello), (VendorInfo{"Microsoft", ConfidenceDerived}); got != want {
+		t.Errorf("Vendor(%s) without a curated row = %+v, want %+v", testWindowsHello, got, want)
+	}
+
+	// A curated row wins, whatever the AAGUID's case.
+	curatedVendors = VendorTable{Exact: map[string]string{testWindowsHello: "Microsoft"}}
+	for _, id := range []string{testWindowsHello, "08987058-CADC-4B81-B6E1-30DE50DCBE96"} {
+		if got, want := p.Vendor(id), (VendorInfo{"Microsoft", ConfidenceCurated}); got != want {
+			t.Errorf("Vendor(%s) = %+v, want %+v", id, got, want)
+		}
+	}
+	if got := p.Vendor("00000000-0000-4000-8000-000000000001"); got != (VendorInfo{}) {
+		t.Errorf("Vendor of an unknown AAGUID = %+v, want ConfidenceUnknown", got)
+	}
+}
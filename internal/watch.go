@@ -0,0 +1,274 @@
+package aaguids
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ChangeKind classifies a ChangeEvent.
+type ChangeKind int
+
+const (
+	// ChangeAdded means the AAGUID appeared in the dataset.
+	ChangeAdded ChangeKind = iota + 1
+	// ChangeRemoved means the AAGUID was removed upstream: it is no longer in the dataset, and its
+	// last-known entry is kept as a tombstone (see RemovedEntries).
+	ChangeRemoved
+	// ChangeStatusChanged means the entry's latest status report changed.
+	ChangeStatusChanged
+)
+
+// String returns "added", "removed" or "status_changed".
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeStatusChanged:
+		return "status_changed"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// MarshalText encodes the kind as its String form.
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+/*
+ChangeEvent reports that an update changed what the dataset says about one AAGUID.
+
+  - AAGUID: the watched AAGUID in canonical form, or the synthetic key of a UAF or U2F entry
+  - OldStatus / NewStatus: latest status before and after ("" when the entry was absent)
+  - Serial: the Dataset.Serial of the snapshot that produced the event
+  - Entry: the entry after the change, or the last-known entry for ChangeRemoved
+*/
+type ChangeEvent struct {
+	AAGUID    string              `json:"aaguid"`
+	Kind      ChangeKind          `json:"kind"`
+	OldStatus AuthenticatorStatus `json:"oldStatus,omitempty"`
+	NewStatus AuthenticatorStatus `json:"newStatus,omitempty"`
+	Serial    int                 `json:"serial"`
+	Entry     Entry               `json:"-"`
+}
+
+// watchBuffer is the capacity of channels returned by Watch.
+const watchBuffer = 16
+
+// watcher is one Watch subscription.
+type watcher struct {
+	ids  map[string]bool // canonical AAGUIDs being watched
+	wake chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]pendingChange
+	order   []string // pending AAGUIDs in arrival order
+}
+
+// pendingChange is an undelivered change, kept as before/after state so later changes coalesce.
+type pendingChange struct {
+	oldPresent, newPresent bool
+	oldStatus, newStatus   AuthenticatorStatus
+	serial                 int
+	entry                  Entry
+}
+
+/*
+Watch subscribes to changes affecting the given AAGUIDs. An event is delivered whenever Update adds
+one of them, removes it, or changes its latest status; AAGUIDs not currently in the dataset may be
+watched and produce ChangeAdded once they appear. AAGUIDs are accepted in any spelling GetEntry
+accepts, i.e. any the Provider's NormalizerChain parses. UAF and U2F entries are watched by their
+synthetic key (see UAFKey and U2FKey).
+
+The returned channel is buffered. When the consumer falls behind, undelivered changes for the same
+AAGUID are coalesced into one event spanning the oldest undelivered state to the newest, and
+changes that cancel out (e.g. added then removed) are dropped. Nothing else is discarded, so memory
+is bounded by the number of watched AAGUIDs. The channel is closed when ctx is done.
+*/
+func (p *Provider) Watch(ctx context.Context, aaguids []string) (<-chan ChangeEvent, error) {
+	ids := make(map[string]bool, len(aaguids))
+	for _, s := range aaguids {
+		k, err := p.watchKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("watch %q: %w", s, err)
+		}
+		ids[k] = true
+	}
+	w := &watcher{ids: ids, wake: make(chan struct{}, 1), pending: make(map[string]pendingChange)}
+	p.watchMu.Lock()
+	if p.watchers == nil {
+		p.watchers = make(map[*watcher]struct{})
+	}
+	p.watchers[w] = struct{}{}
+	p.watchMu.Unlock()
+
+	out := make(chan ChangeEvent, watchBuffer)
+	go func() {
+		defer close(out)
+		defer func() {
+			p.watchMu.Lock()
+			delete(p.watchers, w)
+			p.watchMu.Unlock()
+		}()
+		for {
+			ev, ok := w.next()
+			if !ok {
+				select {
+				case <-ctx.Done():
+					return
+				case <-w.wake:
+				}
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- ev:
+			}
+		}
+	}()
+	return out, nil
+}
+
+// next pops the oldest pending change as an event.
+func (w *watcher) next() (ChangeEvent, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.order) == 0 {
+		return ChangeEvent{}, false
+	}
+	id := w.order[0]
+	w.order = w.order[1:]
+	c := w.pending[id]
+	delete(w.pending, id)
+	return c.event(id), true
+}
+
+// event converts the coalesced state into a ChangeEvent.
+func (c pendingChange) event(id string) ChangeEvent {
+	ev := ChangeEvent{AAGUID: id, OldStatus: c.oldStatus, NewStatus: c.newStatus, Serial: c.serial, Entry: c.entry}
+	switch {
+	case !c.oldPresent:
+		ev.Kind = ChangeAdded
+	case !c.newPresent:
+		ev.Kind = ChangeRemoved
+	default:
+		ev.Kind = ChangeStatusChanged
+	}
+	return ev
+}
+
+// push merges a change into the pending set, dropping it when it cancels out an earlier one. order
+// holds each pending AAGUID exactly once, so it never outgrows the watched set.
+func (w *watcher) push(id string, c pendingChange) {
+	w.mu.Lock()
+	prev, queued := w.pending[id]
+	if queued {
+		c.oldPresent, c.oldStatus = prev.oldPresent, prev.oldStatus
+	}
+	switch {
+	case c.oldPresent == c.newPresent && (!c.newPresent || c.oldStatus == c.newStatus):
+		if queued {
+			delete(w.pending, id)
+			w.order = slices.DeleteFunc(w.order, func(o string) bool { return o == id })
+		}
+	default:
+		if !queued {
+			w.order = append(w.order, id)
+		}
+		w.pending[id] = c
+	}
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// watchKey returns the canonical key Watch tracks for s: the key GetEntry resolves s to, or, for
+// entries not in the current snapshot, its normalizedKey.
+func (p *Provider) watchKey(s string) (string, error) {
+	if k, ok := p.lookupKey(p.current(), s); ok {
+		return canonicalKey(k), nil
+	}
+	return p.normalizedKey(s)
+}
+
+// normalizedKey returns the canonical key s names without consulting the dataset: the synthetic
+// UAF or U2F key, or the AAGUID p's NormalizerChain parses s as.
+func (p *Provider) normalizedKey(s string) (string, error) {
+	if k := normalizeKey(s); isSyntheticKey(k) {
+		return k, nil
+	}
+	id, _, err := p.normalizerChain().Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// notifyWatchers diffs two snapshots for every watched AAGUID.
+func (p *Provider) notifyWatchers(old, cur *snapshot) {
+	p.watchMu.Lock()
+	watchers := make([]*watcher, 0, len(p.watchers))
+	for w := range p.watchers {
+		watchers = append(watchers, w)
+	}
+	p.watchMu.Unlock()
+
+	for _, w := range watchers {
+		ids := make([]string, 0, len(w.ids))
+		for id := range w.ids {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			oldE, oldOK := old.lookupCanonical(id)
+			newE, newOK := cur.lookupCanonical(id)
+			c := pendingChange{oldPresent: oldOK, newPresent: newOK, serial: cur.info.Serial, entry: newE}
+			if !newOK {
+				c.entry = oldE
+			}
+			if oldOK {
+				c.oldStatus = oldE.Summary().Status
+			}
+			if newOK {
+				c.newStatus = newE.Summary().Status
+			}
+			if oldOK == newOK && (!newOK || c.oldStatus == c.newStatus) {
+				continue
+			}
+			w.push(id, c)
+		}
+	}
+}
+
+// lookupCanonical finds the entry for a canonical lowercase AAGUID regardless of the key's case.
+func (s *snapshot) lookupCanonical(id string) (Entry, bool) {
+	if s == nil {
+		return Entry{}, false
+	}
+	if e, ok := s.entries[id]; ok {
+		return e, true
+	}
+	if k, ok := s.canonical[id]; ok {
+		return s.entries[k], true
+	}
+	return Entry{}, false
+}
+
+// canonicalKeys maps lowercase forms to the original keys for keys that are not already lowercase.
+func canonicalKeys(keys []string) map[string]string {
+	m := make(map[string]string)
+	for _, k := range keys {
+		if l := strings.ToLower(k); l != k {
+			m[l] = k
+		}
+	}
+	return m
+}
@@ -0,0 +1,71 @@
+package aaguids
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatchCancellingChangesStayBounded(t *testing.T) {
+	const cycles = 1000
+	yubiKey := Entry{AAGUID: testYubiKey, StatusReports: []StatusReport{report(FIDO_CERTIFIED_L1, "2024-01-01")}}
+	gpm := Entry{AAGUID: testGPM}
+	p := NewProvider(nil, Dataset{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := p.Watch(ctx, []string{testYubiKey, testGPM, "00000000-0000-4000-8000-000000000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w *watcher
+	p.watchMu.Lock()
+	for w = range p.watchers {
+	}
+	p.watchMu.Unlock()
+
+	// Nobody reads ch: once its buffer is full, every add/remove pair of the YubiKey cancels out.
+	for i := range cycles {
+		p.Update(map[string]Entry{testYubiKey: yubiKey, testGPM: gpm}, Dataset{Serial: 2*i + 1})
+		p.Update(map[string]Entry{testGPM: gpm}, Dataset{Serial: 2*i + 2})
+		w.mu.Lock()
+		order, pending := len(w.order), len(w.pending)
+		w.mu.Unlock()
+		if order > len(w.ids) || order != pending {
+			t.Fatalf("after %d cycles: %d AAGUIDs queued for %d pending changes and %d watched", i+1, order, pending, len(w.ids))
+		}
+	}
+
+	// What is delivered still tells a consistent story: the YubiKey alternates between added and
+	// removed, ending removed, and the GPM was added once.
+	var yubiKeyKinds []ChangeKind
+	gpmAdded := 0
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-ch:
+			switch ev.AAGUID {
+			case testYubiKey:
+				yubiKeyKinds = append(yubiKeyKinds, ev.Kind)
+			case testGPM:
+				gpmAdded++
+			}
+			continue
+		case <-time.After(50 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("events kept coming")
+		}
+		break
+	}
+	if gpmAdded != 1 {
+		t.Errorf("GPM delivered %d times, want once", gpmAdded)
+	}
+	for i, k := range yubiKeyKinds {
+		if want := []ChangeKind{ChangeAdded, ChangeRemoved}[i%2]; k != want {
+			t.Fatalf("YubiKey events %v: event %d is %v, want %v", yubiKeyKinds, i, k, want)
+		}
+	}
+	if len(yubiKeyKinds)%2 != 0 || len(yubiKeyKinds) > watchBuffer+2 {
+		t.Errorf("YubiKey delivered %d events, want pairs bounded by the channel buffer", len(yubiKeyKinds))
+	}
+}
@@ -2,9 +2,9 @@ package main
 
 import (
 	"context"
-	"crypto/x509"
-	_ "embed"
-	"encoding/base64"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -12,14 +12,19 @@ import (
 	"github.com/google/uuid"
 	"github.com/sky93/aaguid-information-generator/internal"
 	"go/format"
+	"go/token"
 	"io"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"runtime/debug"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // -----------------------------------------------------------------------------
@@ -29,72 +34,64 @@ import (
 // generatedByComment is the boilerplate comment marking auto-generated files.
 var generatedByComment = "// Code generated by aaguid-information-generator.; DO NOT EDIT."
 
-//go:embed internal/types.go
-var typesStr string
-
-// typesFileContent includes the above generatedByComment plus the embedded content of types.go.
-var typesFileContent = fmt.Sprintf("%s\n%s", generatedByComment, typesStr)
+// libraryFiles holds the sources of the aaguids package. Every file except metadata.go is copied
+// verbatim (plus generatedByComment) into the output package.
+//
+//go:embed internal/*.go
+var libraryFiles embed.FS
 
 //go:embed internal/metadata.go
 var metadataTemplate string
 
+// vendorsJSON is the reviewed AAGUID → vendor table stamped into the generated package.
+//
+//go:embed vendors.json
+var vendorsJSON []byte
+
+// relationsJSON is the reviewed registry of related AAGUIDs stamped into the generated package.
+//
+//go:embed relations.json
+var relationsJSON []byte
+
+// passkeyProvidersJSON is the reviewed allowlist of passkey providers that get exported AAGUID
+// constants in the generated package.
+//
+//go:embed passkey-providers.json
+var passkeyProvidersJSON []byte
+
+// statusTranslationsJSON is the reviewed catalog of end-user status descriptions stamped into the
+// generated package.
+//
+//go:embed status-translations.json
+var statusTranslationsJSON []byte
+
+// version is the generator release. Release tooling stamps it with
+// -ldflags "-X main.version=vX.Y.Z"; otherwise the module version from the build info is used.
+var version string
+
 // -----------------------------------------------------------------------------
 // Data Structures
 // -----------------------------------------------------------------------------
 
-/*
-BLOBPayload represents the top-level JSON payload from the MDS3 JWT. This payload matches the
-“Metadata BLOB Payload” described in the FIDO Metadata Service v3.0 specification (cf. §3.1.6
-“MetadataBLOBPayload” dictionary), although it is simplified to focus on the elements we need:
-
-  - LegalHeader: A statement referencing usage acceptance
-  - No: An incremental, monotonically increasing number identifying the MDS BLOB
-  - NextUpdate: A date by which a new BLOB update should be published
-  - Entries: A slice of Entry structures (from 'aaguids') describing various authenticator models
-*/
-type BLOBPayload struct {
-	LegalHeader string          `json:"legalHeader"`
-	No          int             `json:"no"`
-	NextUpdate  string          `json:"nextUpdate"`
-	Entries     []aaguids.Entry `json:"entries"`
-}
-
 type PassKeyJSONRecord struct {
 	Name      string  `json:"name"`
 	IconDark  *string `json:"icon_dark"`
 	IconLight *string `json:"icon_light"`
 }
 
-/*
-jwsHeader models the JWT header portion (for JWS) needed to parse the MDS3-signed JWT.
-
-Typical fields:
-  - Alg:  e.g. "RS256", "ES256", etc.
-  - Typ:  typically "JWT"
-  - X5c:  an array of base64-encoded certificates that chain back to a trusted root
-*/
-type jwsHeader struct {
-	Alg string   `json:"alg"`
-	Typ string   `json:"typ"`
-	X5c []string `json:"x5c"`
-}
-
 // -----------------------------------------------------------------------------
 // Constants
 // -----------------------------------------------------------------------------
 
-// mapMetadataSignatureType is used for bridging JOSE alg strings like "RS256" to x509.SignatureAlgorithm values.
-var mapMetadataSignatureType = map[string]x509.SignatureAlgorithm{
-	"RS256": x509.SHA256WithRSA,
-	"PS256": x509.SHA256WithRSAPSS,
-	"ES256": x509.ECDSAWithSHA256,
-	"RS384": x509.SHA384WithRSA,
-	"PS384": x509.SHA384WithRSAPSS,
-	"ES384": x509.ECDSAWithSHA384,
-	"RS512": x509.SHA512WithRSA,
-	"PS512": x509.SHA512WithRSAPSS,
-	"ES512": x509.ECDSAWithSHA512,
-}
+// mdsURL and passkeyAAGUIDsURL are the upstream feeds merged into the generated dataset.
+const (
+	mdsURL            = "https://mds3.fidoalliance.org/"
+	passkeyAAGUIDsURL = "https://raw.githubusercontent.com/passkeydeveloper/passkey-authenticator-aaguids/refs/heads/main/aaguid.json"
+
+	// passkeySource labels the passkey-authenticator-aaguids feed in CommunityExtensions and in
+	// field provenance.
+	passkeySource = "passkey-authenticator-aaguids"
+)
 
 // -----------------------------------------------------------------------------
 // Main Program
@@ -108,10 +105,34 @@ main is the entry point of this generator program. It:
  3. Unmarshals the top-level JSON payload into a BLOBPayload
  4. Builds a map of [AAGUID → Entry]
  5. Writes out two files under the chosen directory:
-    a. types.go (generated from embedded content)
-    b. metadata.go (containing a static `metadata` map literal of AAGUID → Entry)
+    a. types.go and the other library files (generated from embedded content)
+    b. metadata.go (containing a static `metadata` map literal of AAGUID → Entry and the dataset identity)
+
+Subcommands run tooling over the merged dataset instead of generating it:
+
+  - browse: the terminal browser (see browse.go)
+  - audit-urls: checks the URLs referenced by the dataset (see audit.go)
+  - audit: audits a stored credential inventory against the dataset (see audit.go)
+  - minimal: writes the minimal fallback dataset to a file (see minimal.go)
+  - snapshot: writes the full dataset to a versioned snapshot file (see snapshot.go)
 */
 func main() {
+	subcommands := map[string]func([]string) error{
+		"browse":     browseMain,
+		"audit-urls": auditURLsMain,
+		"audit":      auditInventoryMain,
+		"minimal":    minimalMain,
+		"snapshot":   snapshotMain,
+	}
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				panic(err)
+			}
+			return
+		}
+	}
+
 	outDir := flag.String("o", "internal/", "Output directory path (e.g. -o internal/)")
 	flag.Parse()
 
@@ -122,64 +143,14 @@ func main() {
 
 	ctx := context.Background()
 
-	// 1. Fetch the JWT from the MDS3 well-known URL.
-	jwtBytes, err := fetch(ctx, "https://mds3.fidoalliance.org/")
-	if err != nil {
-		panic(fmt.Errorf("fetching MDS3 JWT: %w", err))
-	}
-
-	passkeyAuthenticatorAaguidsBytes, err := fetch(ctx, "https://raw.githubusercontent.com/passkeydeveloper/passkey-authenticator-aaguids/refs/heads/main/aaguid.json")
-	if err != nil {
-		panic(fmt.Errorf("fetching MDS3 JWT: %w", err))
-	}
-
-	// 2. Parse and verify the JWT signature, returning the JSON payload portion.
-	_, payloadBytes, err := parseAndVerifyJWT(jwtBytes)
+	// 1-4. Fetch, verify and merge the upstream feeds.
+	blob, entriesMap, provenance, sources, err := loadDataset(ctx, "", "")
 	if err != nil {
-		panic(fmt.Errorf("JWT parsing & verification failed: %w", err))
-	}
-
-	// 3. Decode the JSON payload into our BLOBPayload structure.
-	var blob BLOBPayload
-	if err := json.Unmarshal(payloadBytes, &blob); err != nil {
-		panic(fmt.Errorf("cannot unmarshal MDS payload: %w", err))
+		panic(err)
 	}
 
-	var blobPassKey map[string]PassKeyJSONRecord
-	if err := json.Unmarshal(passkeyAuthenticatorAaguidsBytes, &blobPassKey); err != nil {
-		panic(fmt.Errorf("cannot unmarshal passkey-authenticator-aaguids JSON payload: %w", err))
-	}
-
-	// 4. Build a map of [AAGUID] → Entry. Skip entries without a valid AAGUID (e.g. for UAF).
-	entriesMap := make(map[string]aaguids.Entry)
-	for _, entry := range blob.Entries {
-		if entry.AAGUID == "" {
-			continue // skip if no AAGUID
-		}
-		if _, parseErr := uuid.Parse(entry.AAGUID); parseErr != nil {
-			continue // skip invalid UUID
-		}
-		entriesMap[entry.AAGUID] = entry
-	}
-
-	for aaguid, entry := range blobPassKey {
-		icon, iconDark := "", ""
-		if entry.IconDark != nil {
-			iconDark = *entry.IconDark
-		}
-		if entry.IconLight != nil {
-			icon = *entry.IconLight
-		}
-		entriesMap[aaguid] = aaguids.Entry{
-			AAGUID: aaguid,
-			MetadataStatement: aaguids.MetadataStatement{
-				AAGUID:      aaguid,
-				Description: entry.Name,
-				Icon:        icon,
-				IconDark:    iconDark,
-			},
-		}
-	}
+	// 4b. Report data-quality issues upstream should hear about; none of them stop generation.
+	validateDataset(entriesMap, blob.StatusTruncations)
 
 	// 5) Prepare the output folder for writing types.go and metadata.go
 	aaguidDir := path.Join(*outDir, "aaguids")
@@ -187,17 +158,26 @@ func main() {
 		panic(fmt.Errorf("failed to create aaguids output folder: %w", err))
 	}
 
-	// 5a. Format and write the embedded types.go content
-	typesFileContentFormatted, err := format.Source([]byte(typesFileContent))
+	// 5a. Format and write the embedded library files
+	if err := writeLibraryFiles(aaguidDir); err != nil {
+		panic(err)
+	}
+
+	// 5b) Create metadata.go with the static map literal for all AAGUIDs and the dataset identity
+	integrity, err := aaguids.ComputeIntegrity(entriesMap)
 	if err != nil {
-		panic(fmt.Errorf("formatting types.go content: %w", err))
+		panic(fmt.Errorf("computing dataset integrity: %w", err))
 	}
-	typesPath := filepath.Join(aaguidDir, "types.go")
-	if err := os.WriteFile(typesPath, typesFileContentFormatted, 0o644); err != nil {
-		panic(fmt.Errorf("writing types.go: %w", err))
+	info := aaguids.Dataset{
+		Serial:            blob.No,
+		NextUpdate:        blob.NextUpdate,
+		GeneratedAt:       time.Now().UTC().Format(time.RFC3339),
+		Sources:           sources,
+		EntryCount:        len(entriesMap),
+		Integrity:         integrity,
+		LegalHeaderHashes: aaguids.ComputeLegalHeaderHashes(entriesMap),
 	}
 
-	// 5b) Create metadata.go with the static map literal for all AAGUIDs
 	metadataLiteral := mapToGoLiteral(entriesMap)
 	metadataFile := strings.Replace(
 		metadataTemplate,
@@ -205,6 +185,85 @@ func main() {
 		fmt.Sprintf("= %s", metadataLiteral),
 		1,
 	)
+	metadataFile = strings.Replace(
+		metadataFile,
+		"var datasetInfo Dataset",
+		fmt.Sprintf("var datasetInfo = %s", structToLiteral("Dataset", info)),
+		1,
+	)
+	vendors, err := loadVendorTable(entriesMap)
+	if err != nil {
+		panic(err)
+	}
+	metadataFile = strings.Replace(
+		metadataFile,
+		"var curatedVendors VendorTable",
+		fmt.Sprintf("var curatedVendors = %s", structToLiteral("VendorTable", vendors)),
+		1,
+	)
+	minimal, minimalInfo, err := buildMinimalDataset(entriesMap, info)
+	if err != nil {
+		panic(err)
+	}
+	metadataFile = strings.Replace(
+		metadataFile,
+		"var minimalMetadata map[string]Entry",
+		fmt.Sprintf("var minimalMetadata = %s", mapToGoLiteral(minimal)),
+		1,
+	)
+	metadataFile = strings.Replace(
+		metadataFile,
+		"var minimalDatasetInfo Dataset",
+		fmt.Sprintf("var minimalDatasetInfo = %s", structToLiteral("Dataset", minimalInfo)),
+		1,
+	)
+	relations, err := loadRelations(entriesMap)
+	if err != nil {
+		panic(err)
+	}
+	metadataFile = strings.Replace(
+		metadataFile,
+		"var curatedRelations []AAGUIDRelation",
+		fmt.Sprintf("var curatedRelations = %s", valueToLiteral(relations)),
+		1,
+	)
+	providers, err := loadPasskeyProviders(entriesMap)
+	if err != nil {
+		panic(err)
+	}
+	metadataFile = stampPasskeyProviders(metadataFile, providers)
+	translations, err := loadStatusTranslations()
+	if err != nil {
+		panic(err)
+	}
+	metadataFile = strings.Replace(
+		metadataFile,
+		"var statusTranslations map[string]map[AuthenticatorStatus]string",
+		fmt.Sprintf("var statusTranslations = %s", valueToLiteral(translations)),
+		1,
+	)
+	metadataFile = strings.Replace(
+		metadataFile,
+		"var fieldProvenance FieldProvenance",
+		fmt.Sprintf("var fieldProvenance = %s", valueToLiteral(provenance)),
+		1,
+	)
+	cl, err := updateChangelog(filepath.Join(aaguidDir, changelogFile), entriesMap, blob.No, time.Now().UTC())
+	if err != nil {
+		panic(err)
+	}
+	metadataFile = strings.Replace(
+		metadataFile,
+		"var changelog Changelog",
+		fmt.Sprintf("var changelog = %s", structToLiteral("Changelog", cl)),
+		1,
+	)
+	metadataFile = strings.Replace(
+		metadataFile,
+		"var libraryVersion string",
+		fmt.Sprintf("var libraryVersion = %q", generatorVersion()),
+		1,
+	)
 
 	metadataFileFormatted, err := format.Source([]byte(metadataFile))
 	if err != nil {
@@ -214,136 +273,495 @@ func main() {
 	if err := os.WriteFile(metadataPath, metadataFileFormatted, 0o644); err != nil {
 		panic(fmt.Errorf("writing metadata.go: %w", err))
 	}
+	if err := writeChangelog(filepath.Join(aaguidDir, changelogFile), cl); err != nil {
+		panic(err)
+	}
 }
 
-// -----------------------------------------------------------------------------
-// Network & JWT Parsing
-// -----------------------------------------------------------------------------
-
 /*
-fetch downloads the raw JWT bytes from the specified url. It checks for 2xx responses
-and returns an error otherwise.
-
-This code expects the official MDS3 endpoint, typically "https://mds3.fidoalliance.org/".
+loadDataset fetches the MDS3 BLOB and the passkey-authenticator-aaguids list, verifies the BLOB and
+merges both into a map of [AAGUID → Entry], together with the provenance of merged fields and the
+Dataset.Sources of the merge: the version, fetch time and entry count of each feed (see
+aaguids.MergedSources). mdsFile and passkeyFile, when non-empty, are read from disk instead of
+being downloaded.
 */
-func fetch(ctx context.Context, url string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+func loadDataset(ctx context.Context, mdsFile, passkeyFile string) (blob aaguids.BLOBPayload, entriesMap map[string]aaguids.Entry, prov aaguids.FieldProvenance, sources []aaguids.DatasetSource, err error) {
+	// 1. Fetch the JWT from the MDS3 well-known URL.
+	jwtBytes, err := readSource(ctx, mdsFile, mdsURL)
 	if err != nil {
-		return nil, fmt.Errorf("creating HTTP request: %w", err)
+		return blob, nil, nil, nil, fmt.Errorf("fetching MDS3 JWT: %w", err)
 	}
-	resp, err := http.DefaultClient.Do(req)
+
+	passkeyAuthenticatorAaguidsBytes, err := readSource(ctx, passkeyFile, passkeyAAGUIDsURL)
 	if err != nil {
-		return nil, fmt.Errorf("fetching URL %q: %w", url, err)
+		return blob, nil, nil, nil, fmt.Errorf("fetching passkey-authenticator-aaguids JSON: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < http.StatusOK || resp.StatusCode > 299 {
-		return nil, fmt.Errorf("non-2xx response: %s", resp.Status)
+	// 2-3. Parse and verify the JWT signature, decoding the payload into a BLOBPayload.
+	blob, err = aaguids.ParseMetadataBLOB(jwtBytes, nil)
+	if err != nil {
+		return blob, nil, nil, nil, err
 	}
-	return io.ReadAll(resp.Body)
+
+	var blobPassKey map[string]PassKeyJSONRecord
+	if err := json.Unmarshal(passkeyAuthenticatorAaguidsBytes, &blobPassKey); err != nil {
+		return blob, nil, nil, nil, fmt.Errorf("cannot unmarshal passkey-authenticator-aaguids JSON payload: %w", err)
+	}
+
+	// 4. Build a map of [AAGUID] → Entry (see aaguids.BLOBPayload.EntriesByKey) and merge the
+	// community entries in: MDS stays authoritative for the metadata statement, and the community
+	// names and icons are attached as CommunityExtensions.
+	community := communityEntries(blobPassKey)
+
+	fetchedAt := time.Now().UTC().Format(time.RFC3339)
+	passkeyDigest := sha256.Sum256(passkeyAuthenticatorAaguidsBytes)
+	feeds := []aaguids.SourceEntries{
+		{Label: aaguids.SourceFIDOMDS3, Entries: blob.EntriesByKey(), Source: aaguids.DatasetSource{
+			URL: mdsURL, Version: strconv.Itoa(blob.No), UpdatedAt: fetchedAt, NextUpdate: blob.NextUpdate,
+		}},
+		{Label: passkeySource, Entries: community, Source: aaguids.DatasetSource{
+			URL: passkeyAAGUIDsURL, Version: "sha256:" + hex.EncodeToString(passkeyDigest[:]), UpdatedAt: fetchedAt,
+		}},
+	}
+	entriesMap, prov = aaguids.MergeEntries(feeds...)
+	return blob, entriesMap, prov, aaguids.MergedSources(feeds...), nil
 }
 
 /*
-parseAndVerifyJWT splits the given JWT into header, payload, and signature. It then:
+communityEntries converts the passkey-authenticator-aaguids records into community entries keyed by
+canonical AAGUID, the form blob.EntriesByKey keys MDS entries by, so an AAGUID the list spells in
+uppercase merges with its MDS entry instead of becoming a second entry. Records whose key is not a
+valid AAGUID are skipped with a warning, as are records spelling an AAGUID already seen in another
+case; of those, the first in key order wins.
+*/
+func communityEntries(records map[string]PassKeyJSONRecord) map[string]aaguids.Entry {
+	community := make(map[string]aaguids.Entry, len(records))
+	keys := make([]string, 0, len(records))
+	for k := range records {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, raw := range keys {
+		id, err := aaguids.ParseAAGUID(raw)
+		if err != nil {
+			warnf("passkey-authenticator-aaguids: skipping %q: %v", raw, err)
+			continue
+		}
+		aaguid := id.String()
+		if _, dup := community[aaguid]; dup {
+			warnf("passkey-authenticator-aaguids: skipping %q: %s is already listed in another case", raw, aaguid)
+			continue
+		}
+		entry := records[raw]
+		ext := &aaguids.CommunityExtensions{Source: passkeySource, DisplayName: entry.Name}
+		if entry.IconDark != nil {
+			ext.IconDark = *entry.IconDark
+		}
+		if entry.IconLight != nil {
+			ext.Icon = *entry.IconLight
+		}
+		community[aaguid] = aaguids.Entry{
+			AAGUID:              aaguid,
+			MetadataStatement:   aaguids.MetadataStatement{AAGUID: aaguid},
+			CommunityExtensions: ext,
+		}
+	}
+	return community
+}
 
-  - Decodes the header (which must have x5c certificates)
-  - Verifies the certificate chain (we rely on standard PKI validation)
-  - Uses the "alg" field to map to a x509.SignatureAlgorithm
-  - Verifies the signature across the "header.payload" with the leaf cert
+// readSource reads file if it is set and downloads url otherwise.
+func readSource(ctx context.Context, file, url string) ([]byte, error) {
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return fetch(ctx, url)
+}
 
-Returns:
-  - headerDecoded: The decoded header bytes
-  - payloadDecoded: The decoded payload bytes
-  - err: Any error from decoding or verification
+/*
+writeLibraryFiles copies every embedded library source except the metadata.go template into dir,
+prefixed with generatedByComment and gofmt-ed.
 */
-func parseAndVerifyJWT(jwtBytes []byte) (headerDecoded, payloadDecoded []byte, err error) {
-	parts := strings.Split(string(jwtBytes), ".")
-	if len(parts) != 3 {
-		return nil, nil, errors.New("invalid JWT: must have 3 dot-separated parts")
+func writeLibraryFiles(dir string) error {
+	files, err := libraryFiles.ReadDir("internal")
+	if err != nil {
+		return fmt.Errorf("listing embedded library files: %w", err)
+	}
+	for _, f := range files {
+		name := f.Name()
+		if name == "metadata.go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := libraryFiles.ReadFile(path.Join("internal", name))
+		if err != nil {
+			return fmt.Errorf("reading embedded %s: %w", name, err)
+		}
+		formatted, err := format.Source([]byte(fmt.Sprintf("%s\n%s", generatedByComment, src)))
+		if err != nil {
+			return fmt.Errorf("formatting %s content: %w", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), formatted, 0o644); err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
 	}
+	return nil
+}
 
-	headerPart, err := base64RawURIDecode(parts[0])
-	if err != nil {
-		return nil, nil, fmt.Errorf("decode header part: %w", err)
+// rootExpiryWarning is how far ahead the generator warns about expiring attestation roots.
+const rootExpiryWarning = 90 * 24 * time.Hour
+
+// warnf prints a generator warning to stderr.
+func warnf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "warning: "+format+"\n", args...)
+}
+
+/*
+validateDataset warns about entries that will cause trouble for relying parties:
+
+  - biometric status reports with a modality unknown to the FIDO Registry
+  - attestation roots that have expired or expire within rootExpiryWarning
+  - authenticatorGetInfo aaguids that disagree with the entry's AAGUID
+  - UAF entries without an AAID or UAF details (see aaguids.ValidateUAFEntry)
+  - entries whose status reports ParseMetadataBLOB truncated (see aaguids.WithStatusReportLimit)
+*/
+func validateDataset(entries map[string]aaguids.Entry, truncations []aaguids.StatusTruncation) {
+	for _, t := range truncations {
+		warnf("%s", t)
 	}
-	payloadPart, err := base64RawURIDecode(parts[1])
-	if err != nil {
-		return nil, nil, fmt.Errorf("decode payload part: %w", err)
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := aaguids.ValidateBiometricStatusReports(entries[k]); err != nil {
+			warnf("%s (%s): %v", k, entries[k].DisplayName(), err)
+		}
+		if err := aaguids.ValidateGetInfoAAGUID(entries[k]); err != nil {
+			warnf("%s (%s): %v", k, entries[k].DisplayName(), err)
+		}
+		if err := aaguids.ValidateEntryAAIDs(entries[k]); err != nil {
+			warnf("%s (%s): %v", k, entries[k].DisplayName(), err)
+		}
+		if err := aaguids.ValidateUAFEntry(entries[k]); err != nil {
+			warnf("%s (%s): %v", k, entries[k].DisplayName(), err)
+		}
+	}
+
+	p := aaguids.NewProvider(entries, aaguids.Dataset{})
+	for _, r := range p.EntriesWithExpiringRoots(rootExpiryWarning, time.Now()) {
+		state := "expires"
+		if r.Expired {
+			state = "expired"
+		}
+		if r.AllExpired {
+			state += " (all roots of this entry are expired)"
+		}
+		warnf("%s (%s): attestation root %q %s %s", r.AAGUID, r.Entry.DisplayName(),
+			r.Subject, state, r.NotAfter.Format(time.DateOnly))
 	}
-	signaturePart, err := base64RawURIDecode(parts[2])
+}
+
+// changelogFile is the cumulative changelog kept next to the generated package; commit it with the
+// package so the next run can extend it.
+const changelogFile = "changelog.json"
+
+/*
+updateChangelog reads the changelog at file (starting a new one if it does not exist) and records
+the current run, identified by its MDS serial and date:
+
+  - keys not recorded yet get a first-seen serial and date, except on the very first run, whose
+    entries predate the changelog and keep them unset
+  - recorded keys whose content digest differs get a new last-changed serial and date
+  - keys no longer in entries keep their record, so a reappearing entry is not reported as new
+*/
+func updateChangelog(file string, entries map[string]aaguids.Entry, serial int, now time.Time) (aaguids.Changelog, error) {
+	var cl aaguids.Changelog
+	raw, err := os.ReadFile(file)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(raw, &cl); err != nil {
+			return cl, fmt.Errorf("cannot unmarshal %s: %w", file, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		cl = aaguids.Changelog{BeganSerial: serial, Began: now.Format(time.DateOnly)}
+	default:
+		return cl, fmt.Errorf("reading %s: %w", file, err)
+	}
+	first := cl.Entries == nil
+	if first {
+		cl.Entries = make(map[string]aaguids.LifecycleRecord, len(entries))
+	}
+
+	date := now.Format(time.DateOnly)
+	for k, e := range entries {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return cl, fmt.Errorf("encoding entry %s for the changelog: %w", k, err)
+		}
+		sum := sha256.Sum256(b)
+		digest := "sha256:" + hex.EncodeToString(sum[:])
+
+		// Records are kept under the canonical key (see aaguids.Entry.Key), the form
+		// EntryLifecycle looks them up in; synthetic UAF keys keep their uppercase AAID.
+		if ek := e.Key(); ek != "" {
+			k = ek
+		} else {
+			k = strings.ToLower(k)
+		}
+		r, seen := cl.Entries[k]
+		switch {
+		case !seen && !first:
+			r.FirstSeenSerial, r.FirstSeen = serial, date
+		case seen && r.Digest != digest:
+			r.LastChangedSerial, r.LastChanged = serial, date
+		}
+		r.Digest = digest
+		cl.Entries[k] = r
+	}
+	return cl, nil
+}
+
+// writeChangelog stores cl as indented JSON at file.
+func writeChangelog(file string, cl aaguids.Changelog) error {
+	b, err := json.MarshalIndent(cl, "", "  ")
 	if err != nil {
-		return nil, nil, fmt.Errorf("decode signature part: %w", err)
+		return fmt.Errorf("encoding changelog: %w", err)
 	}
+	if err := os.WriteFile(file, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", file, err)
+	}
+	return nil
+}
 
-	// Unmarshal the header JSON, expecting a jwsHeader struct
-	var hdr jwsHeader
-	if unmarshalErr := json.Unmarshal(headerPart, &hdr); unmarshalErr != nil {
-		return nil, nil, fmt.Errorf("unmarshal JWT header: %w", unmarshalErr)
+/*
+loadVendorTable decodes the embedded vendors.json. AAGUIDs are lowercased; exact entries that are not
+present in the dataset are kept (the table may run ahead of MDS) but reported as warnings so stale
+rows get reviewed.
+*/
+func loadVendorTable(entries map[string]aaguids.Entry) (aaguids.VendorTable, error) {
+	var t aaguids.VendorTable
+	if err := json.Unmarshal(vendorsJSON, &t); err != nil {
+		return t, fmt.Errorf("cannot unmarshal vendors.json: %w", err)
+	}
+	present := make(map[string]bool, len(entries))
+	for k := range entries {
+		present[strings.ToLower(k)] = true
+	}
+	exact := make(map[string]string, len(t.Exact))
+	for id, vendor := range t.Exact {
+		if _, err := uuid.Parse(id); err != nil {
+			return t, fmt.Errorf("vendors.json: invalid AAGUID %q: %w", id, err)
+		}
+		id = strings.ToLower(id)
+		if !present[id] {
+			warnf("vendors.json: %s (%s) is not in the dataset", id, vendor)
+		}
+		exact[id] = vendor
 	}
-	if len(hdr.X5c) == 0 {
-		return nil, nil, errors.New("no x5c field present in header")
+	t.Exact = exact
+	for i := range t.Prefixes {
+		t.Prefixes[i].Prefix = strings.ToLower(t.Prefixes[i].Prefix)
 	}
+	return t, nil
+}
 
-	// Convert each base64 PEM entry in X5c to an x509.Certificate
-	certs := make([]*x509.Certificate, 0, len(hdr.X5c))
-	for i, c := range hdr.X5c {
-		der, decErr := base64.StdEncoding.DecodeString(c)
-		if decErr != nil {
-			return nil, nil, fmt.Errorf("decode x5c[%d]: %w", i, decErr)
+/*
+loadRelations decodes the embedded relations.json. Every group needs a known relation kind and at
+least two distinct, valid AAGUIDs, which are lowercased. As with vendors.json, AAGUIDs that are not
+in the dataset are kept but reported as warnings.
+*/
+func loadRelations(entries map[string]aaguids.Entry) ([]aaguids.AAGUIDRelation, error) {
+	var file struct {
+		Relations []aaguids.AAGUIDRelation `json:"relations"`
+	}
+	if err := json.Unmarshal(relationsJSON, &file); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal relations.json: %w", err)
+	}
+	present := make(map[string]bool, len(entries))
+	for k := range entries {
+		present[strings.ToLower(k)] = true
+	}
+	for i, r := range file.Relations {
+		if !r.Kind.Valid() {
+			return nil, fmt.Errorf("relations.json: group %d: unknown kind %q", i, r.Kind)
 		}
-		cert, parseErr := x509.ParseCertificate(der)
-		if parseErr != nil {
-			return nil, nil, fmt.Errorf("parse x5c[%d]: %w", i, parseErr)
+		seen := make(map[string]bool, len(r.AAGUIDs))
+		for j, id := range r.AAGUIDs {
+			if _, err := uuid.Parse(id); err != nil {
+				return nil, fmt.Errorf("relations.json: group %d: invalid AAGUID %q: %w", i, id, err)
+			}
+			id = strings.ToLower(id)
+			if seen[id] {
+				return nil, fmt.Errorf("relations.json: group %d: %s is listed twice", i, id)
+			}
+			seen[id] = true
+			if !present[id] {
+				warnf("relations.json: %s (%s) is not in the dataset", id, r.Note)
+			}
+			r.AAGUIDs[j] = id
+		}
+		if len(seen) < 2 {
+			return nil, fmt.Errorf("relations.json: group %d: needs at least two AAGUIDs", i)
 		}
-		certs = append(certs, cert)
 	}
+	return file.Relations, nil
+}
+
+// passkeyProvider is one row of passkey-providers.json: the name of the exported constant, its
+// AAGUID, and the description written as the constant's comment.
+type passkeyProvider struct {
+	Name        string `json:"name"`
+	AAGUID      string `json:"aaguid"`
+	Description string `json:"description"`
+}
 
-	leafCert := certs[0]
-	intermediates := x509.NewCertPool()
-	for _, ic := range certs[1:] {
-		intermediates.AddCert(ic)
+/*
+loadPasskeyProviders decodes the embedded passkey-providers.json. Names must be distinct exported
+Go identifiers and AAGUIDs valid and distinct; either mistake fails the run. Providers that do not
+resolve with GetEntry in the dataset are reported as warnings and get no constant, so every stamped
+constant names an embedded entry.
+*/
+func loadPasskeyProviders(entries map[string]aaguids.Entry) ([]passkeyProvider, error) {
+	var file struct {
+		Providers []passkeyProvider `json:"providers"`
+	}
+	if err := json.Unmarshal(passkeyProvidersJSON, &file); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal passkey-providers.json: %w", err)
+	}
+	p := aaguids.NewProvider(entries, aaguids.Dataset{})
+	names := make(map[string]bool, len(file.Providers))
+	ids := make(map[string]bool, len(file.Providers))
+	var out []passkeyProvider
+	for _, pp := range file.Providers {
+		if !token.IsIdentifier(pp.Name) || !token.IsExported(pp.Name) {
+			return nil, fmt.Errorf("passkey-providers.json: %q is not an exported Go identifier", pp.Name)
+		}
+		if _, err := uuid.Parse(pp.AAGUID); err != nil {
+			return nil, fmt.Errorf("passkey-providers.json: %s: invalid AAGUID %q: %w", pp.Name, pp.AAGUID, err)
+		}
+		pp.AAGUID = strings.ToLower(pp.AAGUID)
+		if names[pp.Name] || ids[pp.AAGUID] {
+			return nil, fmt.Errorf("passkey-providers.json: %s (%s) is listed twice", pp.Name, pp.AAGUID)
+		}
+		names[pp.Name], ids[pp.AAGUID] = true, true
+		if _, ok := p.GetEntry(pp.AAGUID); !ok {
+			warnf("passkey-providers.json: %s (%s) is not in the dataset; no constant generated", pp.AAGUID, pp.Description)
+			continue
+		}
+		out = append(out, pp)
 	}
+	return out, nil
+}
+
+// languageTagPattern matches the language tags accepted in status-translations.json: BCP 47
+// subtags of 1 to 8 letters or digits, starting with a 2- or 3-letter language.
+var languageTagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`)
 
-	// Perform minimal chain validation.
-	if _, verifyErr := leafCert.Verify(x509.VerifyOptions{
-		Intermediates: intermediates,
-	}); verifyErr != nil {
-		return nil, nil, fmt.Errorf("certificate chain verify fail: %w", verifyErr)
+/*
+loadStatusTranslations decodes the embedded status-translations.json. Every status this package
+knows (see aaguids.AllStatusInfo) must have an English description, so a new status cannot ship
+untranslated; statuses this package does not know, empty descriptions and malformed language tags
+fail the run as well. Other languages may leave statuses out, which then fall back to English.
+*/
+func loadStatusTranslations() (map[string]map[aaguids.AuthenticatorStatus]string, error) {
+	var file struct {
+		Languages map[string]map[aaguids.AuthenticatorStatus]string `json:"languages"`
+	}
+	if err := json.Unmarshal(statusTranslationsJSON, &file); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal status-translations.json: %w", err)
+	}
+	for _, m := range aaguids.AllStatusInfo() {
+		if _, ok := file.Languages["en"][m.Status]; !ok {
+			return nil, fmt.Errorf("status-translations.json: %s has no English description", m.Status)
+		}
+	}
+	for lang, descriptions := range file.Languages {
+		if !languageTagPattern.MatchString(lang) {
+			return nil, fmt.Errorf("status-translations.json: %q is not a BCP 47 language tag", lang)
+		}
+		for status, d := range descriptions {
+			if _, ok := aaguids.StatusInfo(status); !ok {
+				return nil, fmt.Errorf("status-translations.json: %s: unknown status %s", lang, status)
+			}
+			if strings.TrimSpace(d) == "" {
+				return nil, fmt.Errorf("status-translations.json: %s: %s has an empty description", lang, status)
+			}
+		}
 	}
+	return file.Languages, nil
+}
 
-	// Map the JOSE alg string to x509 signature algorithm
-	sigAlg, ok := mapMetadataSignatureType[hdr.Alg]
-	if !ok {
-		return nil, nil, fmt.Errorf("unrecognized signature alg: %s", hdr.Alg)
+// passkeyProviderAAGUIDs returns the AAGUIDs of providers, in order.
+func passkeyProviderAAGUIDs(providers []passkeyProvider) []string {
+	ids := make([]string, len(providers))
+	for i, pp := range providers {
+		ids[i] = pp.AAGUID
 	}
+	return ids
+}
 
-	// The actual signing input is "header.base64 + '.' + payload.base64"
-	signingInput := []byte(parts[0] + "." + parts[1])
-	if sigErr := leafCert.CheckSignature(sigAlg, signingInput, signaturePart); sigErr != nil {
-		return nil, nil, fmt.Errorf("JWT signature check failed: %w", sigErr)
+/*
+stampPasskeyProviders fills knownPasskeyProviders into metadataFile and declares the passkey
+provider constants after it, one commented constant per provider. Without providers no const block
+is written.
+*/
+func stampPasskeyProviders(metadataFile string, providers []passkeyProvider) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "var knownPasskeyProviders = %s", valueToLiteral(passkeyProviderAAGUIDs(providers)))
+	if len(providers) > 0 {
+		b.WriteString("\n\n// The well-known passkey providers of passkey-providers.json present in the dataset, e.g.\n")
+		b.WriteString("// GooglePasswordManager, as lowercase AAGUIDs.\nconst (\n")
+		for _, pp := range providers {
+			fmt.Fprintf(&b, "\t// %s is the AAGUID of %s.\n\t%s = %q\n", pp.Name, pp.Description, pp.Name, pp.AAGUID)
+		}
+		b.WriteString(")")
 	}
+	return strings.Replace(metadataFile, "var knownPasskeyProviders []string", b.String(), 1)
+}
 
-	return headerPart, payloadPart, nil
+/*
+generatorVersion reports the release stamped into the generated package: the -ldflags value of
+version if set, else the module version recorded by `go install ...@vX.Y.Z`, else "(devel)".
+*/
+func generatorVersion() string {
+	if version != "" {
+		return version
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" {
+		return bi.Main.Version
+	}
+	return "(devel)"
 }
 
 // -----------------------------------------------------------------------------
-// Encoding & Utility
+// Network & JWT Parsing
 // -----------------------------------------------------------------------------
 
+// fetchClient sends the downloads of fetch through a FetchCoordinator, so the sources of one run
+// are spaced per host like the library's own requests.
+var fetchClient = aaguids.NewFetchCoordinator().Client(nil)
+
 /*
-base64RawURIDecode performs a Base64-URL decode with or without padding. It transforms
-'-' → '+' and '_' → '/', then applies padding logic if needed, and finally decodes.
+fetch downloads the raw JWT bytes from the specified url. It checks for 2xx responses
+and returns an error otherwise.
+
+This code expects the official MDS3 endpoint, typically "https://mds3.fidoalliance.org/".
 */
-func base64RawURIDecode(s string) ([]byte, error) {
-	s = strings.ReplaceAll(s, "-", "+")
-	s = strings.ReplaceAll(s, "_", "/")
-	switch len(s) % 4 {
-	case 2:
-		s += "=="
-	case 3:
-		s += "="
-	}
-	return base64.StdEncoding.DecodeString(s)
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating HTTP request: %w", err)
+	}
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching URL %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("non-2xx response: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
 }
 
 // -----------------------------------------------------------------------------
@@ -426,6 +844,9 @@ func structToLiteral(typeName string, v interface{}) string {
 		if !field.IsExported() {
 			continue // skip unexported fields
 		}
+		if field.Tag.Get("aaguids") == "compat" {
+			continue // deprecated fields the package fills in when it loads entries
+		}
 		fieldValue := rv.Field(i).Interface()
 		goValueLit := valueToLiteral(fieldValue)
 		b.WriteString("  " + fieldName + ": " + goValueLit + ",\n")
@@ -459,7 +880,8 @@ func valueToLiteral(val interface{}) string {
 		underlying := valueToLiteral(rv.Elem().Interface())
 
 		// Then wrap it in goPtr(...)
-		return fmt.Sprintf("goPtr(%s(%s))", rv.Elem().Type().String(), underlying)
+		elemType := strings.ReplaceAll(rv.Elem().Type().String(), "aaguids.", "")
+		return fmt.Sprintf("goPtr(%s(%s))", elemType, underlying)
 	case reflect.String:
 		if reflect.TypeOf(val).String() == "aaguids.AuthenticatorStatus" {
 			s := rv.Convert(reflect.TypeFor[aaguids.AuthenticatorStatus]()).Interface().(aaguids.AuthenticatorStatus)
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sky93/aaguid-information-generator/internal"
+)
+
+func TestCommunityEntriesMergeWithMDSEntries(t *testing.T) {
+	const yubiKey = "ee882879-721c-4913-9775-3dfcce97072a"
+	name := func(s string) PassKeyJSONRecord { return PassKeyJSONRecord{Name: s} }
+	community := communityEntries(map[string]PassKeyJSONRecord{
+		"EE882879-721C-4913-9775-3DFCCE97072A": name("YubiKey 5"),
+		"ee882879-721c-4913-9775-3dfcce97072a": name("YubiKey 5 (lowercase duplicate)"),
+		"EA9B8D66-4D01-1D21-3CE4-B6B48CB575D4": name("Google Password Manager"),
+		"not-an-aaguid":                        name("Broken"),
+	})
+	if len(community) != 2 {
+		t.Fatalf("communityEntries returned %d entries, want 2 (malformed and duplicate keys skipped)", len(community))
+	}
+	if got := community[yubiKey].CommunityExtensions.DisplayName; got != "YubiKey 5" {
+		t.Errorf("duplicate spellings: kept %q, want the first in key order", got)
+	}
+
+	mds := map[string]aaguids.Entry{yubiKey: {
+		AAGUID:            yubiKey,
+		MetadataStatement: aaguids.MetadataStatement{AAGUID: yubiKey, Description: "YubiKey 5 Series"},
+	}}
+	entries, _ := aaguids.MergeEntries(
+		aaguids.SourceEntries{Label: aaguids.SourceFIDOMDS3, Entries: mds},
+		aaguids.SourceEntries{Label: passkeySource, Entries: community},
+	)
+	for k, e := range entries {
+		if e.Key() != k {
+			t.Errorf("dataset key %q is not the entry's canonical key %q", k, e.Key())
+		}
+	}
+	if e := entries[yubiKey]; len(entries) != 2 || e.MetadataStatement.Description == "" || e.CommunityExtensions == nil {
+		t.Errorf("merge: %d entries, YubiKey %+v; want the community name merged into the MDS entry", len(entries), e)
+	}
+
+	p := aaguids.NewProvider(entries, aaguids.Dataset{Serial: 1})
+	if err := p.ExportJSON(&bytes.Buffer{}); err != nil {
+		t.Errorf("ExportJSON: %v", err)
+	}
+	if n := len(p.AAGUIDs()); n != 2 {
+		t.Errorf("AAGUIDs() = %d keys, want 2", n)
+	}
+
+	// Regenerating from the same inputs records no change.
+	file := filepath.Join(t.TempDir(), changelogFile)
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	cl, err := updateChangelog(file, entries, 1, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeChangelog(file, cl); err != nil {
+		t.Fatal(err)
+	}
+	cl, err = updateChangelog(file, entries, 2, now.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, r := range cl.Entries {
+		if k != strings.ToLower(k) {
+			t.Errorf("changelog key %q is not canonical", k)
+		}
+		if r.LastChangedSerial != 0 {
+			t.Errorf("changelog marks unchanged entry %s changed in serial %d", k, r.LastChangedSerial)
+		}
+	}
+}
+
+// moduleLines returns the lines of the repository's go.mod or go.sum mentioning module.
+func moduleLines(t *testing.T, file, module string) string {
+	t.Helper()
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if f := strings.Fields(line); len(f) >= 2 && f[0] == module {
+			out = append(out, strings.Join(f, " "))
+		}
+	}
+	if len(out) == 0 {
+		t.Fatalf("%s does not mention %s", file, module)
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+func TestGeneratedPasskeyConstantsCompile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a generated package")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	const google, windowsHello = "ea9b8d66-4d01-1d21-3ce4-b6b48cb575d4", "08987058-cadc-4b81-b6e1-30de50dcbe96"
+	providers, err := loadPasskeyProviders(map[string]aaguids.Entry{
+		google:       {AAGUID: google},
+		windowsHello: {AAGUID: windowsHello},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 2 {
+		t.Fatalf("loadPasskeyProviders kept %d providers, want the 2 in the dataset", len(providers))
+	}
+
+	// A consumer module holding the generated package, with only the passkey providers stamped.
+	dir := t.TempDir()
+	pkg := filepath.Join(dir, "aaguids")
+	if err := os.Mkdir(pkg, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeLibraryFiles(pkg); err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := format.Source([]byte(stampPasskeyProviders(metadataTemplate, providers)))
+	if err != nil {
+		t.Fatalf("formatting metadata.go: %v", err)
+	}
+	const text = "golang.org/x/text"
+	files := map[string]string{
+		"aaguids/metadata.go": string(metadata),
+		"go.mod":              "module consumer\n\ngo 1.24\n\nrequire " + moduleLines(t, "go.mod", text),
+		"go.sum":              moduleLines(t, "go.sum", text),
+		"main.go": `package main
+
+import (
+	"fmt"
+	"slices"
+
+	"consumer/aaguids"
+)
+
+func main() {
+	want := []string{aaguids.GooglePasswordManager, aaguids.WindowsHelloHardware}
+	if got := aaguids.KnownPasskeyProviders(); !slices.Equal(got, want) {
+		panic(fmt.Sprintf("KnownPasskeyProviders() = %q, want %q", got, want))
+	}
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(goTool, "run", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go run of the generated package: %v\n%s", err, out)
+	}
+
+	// Without providers no const block is stamped, and the file still formats.
+	if got := stampPasskeyProviders(metadataTemplate, nil); strings.Contains(got, "const (") {
+		t.Error("metadata.go without providers has a const block")
+	} else if _, err := format.Source([]byte(got)); err != nil {
+		t.Errorf("metadata.go without providers: %v", err)
+	}
+}
+
+func TestVendorTableCuratesWindowsHello(t *testing.T) {
+	const hardware, vbs, software = "08987058-cadc-4b81-b6e1-30de50dcbe96", "9ddd1817-af5a-4672-a2b9-3e3dd95000a9", "6028b017-b1d4-4c02-b4b3-afcdafc96bb2"
+	entries := map[string]aaguids.Entry{hardware: {AAGUID: hardware}, vbs: {AAGUID: vbs}, software: {AAGUID: software}}
+	table, err := loadVendorTable(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{hardware, vbs, software} {
+		if got := table.Exact[id]; got != "Microsoft" {
+			t.Errorf("vendors.json attributes Windows Hello %s to %q, want Microsoft", id, got)
+		}
+	}
+}
+
+func TestLoadRelations(t *testing.T) {
+	const a, b = "ee882879-721c-4913-9775-3dfcce97072a", "cb69481e-8ff7-4039-93ec-0a2729a154a8"
+	entries := map[string]aaguids.Entry{a: {AAGUID: a}}
+
+	// The registry in the repository is valid.
+	if _, err := loadRelations(entries); err != nil {
+		t.Fatalf("relations.json: %v", err)
+	}
+
+	old := relationsJSON
+	t.Cleanup(func() { relationsJSON = old })
+	group := func(kind string, ids ...string) []byte {
+		quoted, _ := json.Marshal(ids)
+		return []byte(fmt.Sprintf(`{"relations": [{"aaguids": %s, "kind": %q}]}`, quoted, kind))
+	}
+	for _, tt := range []struct {
+		name string
+		json []byte
+		err  string
+	}{
+		{"unknown kind", group("same-vendor", a, b), `unknown kind "same-vendor"`},
+		{"invalid AAGUID", group("vendor-error", a, "not-an-aaguid"), `invalid AAGUID "not-an-aaguid"`},
+		{"duplicate in another case", group("vendor-error", a, strings.ToUpper(a)), a + " is listed twice"},
+		{"single member", group("vendor-error", a), "needs at least two AAGUIDs"},
+		{"malformed", []byte(`{"relations": {}}`), "cannot unmarshal relations.json"},
+	} {
+		relationsJSON = tt.json
+		if _, err := loadRelations(entries); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: loadRelations = %v, want an error mentioning %q", tt.name, err, tt.err)
+		}
+	}
+
+	// Members are lowercased; one missing from the dataset is kept, with a warning.
+	relationsJSON = group("firmware-line-split", strings.ToUpper(a), b)
+	relations, err := loadRelations(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(relations) != 1 || strings.Join(relations[0].AAGUIDs, " ") != a+" "+b || relations[0].Kind != aaguids.RelationFirmwareSplit {
+		t.Errorf("loadRelations = %+v, want the lowercase group", relations)
+	}
+}
+
+// updateGoldens rewrites the golden files of this package's tests instead of comparing with them.
+var updateGoldens = flag.Bool("update", false, "rewrite testdata golden files")
+
+// browseProvider returns the fixed dataset of the browser goldens: a certified security key with a
+// biometric certification and a trust anchor, a model still revoked after a later update notice, one
+// certified again after its revocation, and a U2F key.
+func browseProvider(t *testing.T) *aaguids.Provider {
+	t.Helper()
+	pemBytes, err := os.ReadFile(filepath.Join("internal", "testdata", "u2f-root.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(pemBytes)
+	str := func(s string) *string { return &s }
+	status := func(s aaguids.AuthenticatorStatus, date string) aaguids.StatusReport {
+		return aaguids.StatusReport{Status: s, EffectiveDate: str(date)}
+	}
+	entry := func(id, description, family string, reports ...aaguids.StatusReport) aaguids.Entry {
+		return aaguids.Entry{AAGUID: id, StatusReports: reports, MetadataStatement: aaguids.MetadataStatement{
+			AAGUID: id, Description: description, ProtocolFamily: family,
+		}}
+	}
+	const bio, revoked, recertified = "00000000-0000-4000-8000-000000000001", "00000000-0000-4000-8000-000000000002",
+		"00000000-0000-4000-8000-000000000003"
+	const u2fKeyID = "bf7bcaa0d0c6187a8c6abbdd16a15640e7c7bde2"
+
+	b := entry(bio, "Bio Key by Yubico", "fido2", status(aaguids.FIDO_CERTIFIED_L2, "2023-05-01"))
+	b.BiometricStatusReports = []aaguids.BiometricStatusReport{{
+		CertLevel: aaguids.BiometricCertLevel1, Modality: aaguids.ModalityFingerprint, EffectiveDate: str("2023-06-01"),
+	}}
+	b.MetadataStatement.AttestationRootCertificates = []string{base64.StdEncoding.EncodeToString(block.Bytes), "not base64!"}
+	b.MetadataStatement.AuthenticatorGetInfo = &aaguids.AuthenticatorGetInfo{Transports: []string{"usb", "nfc"}}
+	u2f := aaguids.Entry{
+		AttestationCertificateKeyIdentifiers: []string{u2fKeyID},
+		StatusReports:                        []aaguids.StatusReport{status(aaguids.FIDO_CERTIFIED, "2019-02-01")},
+		MetadataStatement:                    aaguids.MetadataStatement{Description: "Legacy U2F Token", ProtocolFamily: "u2f"},
+	}
+	return aaguids.NewProvider(map[string]aaguids.Entry{
+		bio: b,
+		revoked: entry(revoked, "FIDO2 Key by Acme Corp.", "fido2", status(aaguids.FIDO_CERTIFIED_L1, "2021-01-01"),
+			status(aaguids.REVOKED, "2022-03-01"), status(aaguids.UPDATE_AVAILABLE, "2022-09-01")),
+		recertified: entry(recertified, "Token2 PIN+", "fido2", status(aaguids.FIDO_CERTIFIED_L1, "2021-01-01"),
+			status(aaguids.REVOKED, "2022-03-01"), status(aaguids.FIDO_CERTIFIED_L1, "2023-01-01")),
+		u2f.Key(): u2f,
+	}, aaguids.Dataset{Serial: 1})
+}
+
+func TestBrowserViewGoldens(t *testing.T) {
+	// Each step is typed into a fresh browser; the golden is the screen that follows.
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+	for _, tt := range []struct {
+		name string
+		keys []tea.KeyMsg
+	}{
+		{"browse-all", nil},
+		{"browse-search-vendor", []tea.KeyMsg{runes("yubico")}},
+		{"browse-revoked", []tea.KeyMsg{{Type: tea.KeyCtrlR}}},
+		{"browse-fido2-second", []tea.KeyMsg{{Type: tea.KeyCtrlF}, {Type: tea.KeyDown}}},
+		{"browse-biometric", []tea.KeyMsg{{Type: tea.KeyCtrlB}}},
+		{"browse-no-match", []tea.KeyMsg{runes("zzz")}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBrowser(browseProvider(t))
+			b.Update(tea.WindowSizeMsg{Width: 100, Height: 24})
+			for _, k := range tt.keys {
+				b.Update(k)
+			}
+			got := []byte(b.View())
+			path := filepath.Join("testdata", tt.name+".golden")
+			if *updateGoldens {
+				if err := os.MkdirAll("testdata", 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s is out of date (run go test -update):\n got %s\nwant %s", path, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sky93/aaguid-information-generator/internal"
+)
+
+// minimalAAGUIDsJSON is the reviewed list of AAGUIDs kept in the minimal fallback dataset.
+//
+//go:embed minimal-aaguids.json
+var minimalAAGUIDsJSON []byte
+
+// minimalAAGUIDs is the decoded form of minimal-aaguids.json: AAGUID → model name, the name only
+// serving reviewers.
+type minimalAAGUIDs struct {
+	AAGUIDs map[string]string `json:"aaguids"`
+}
+
+/*
+buildMinimalDataset selects the AAGUIDs of minimal-aaguids.json from entries and reduces them to
+status-only entries (see minimalEntry). AAGUIDs missing from the dataset are reported as warnings,
+like stale vendors.json rows, and left out.
+*/
+func buildMinimalDataset(entries map[string]aaguids.Entry, info aaguids.Dataset) (map[string]aaguids.Entry, aaguids.Dataset, error) {
+	var list minimalAAGUIDs
+	if err := json.Unmarshal(minimalAAGUIDsJSON, &list); err != nil {
+		return nil, info, fmt.Errorf("cannot unmarshal minimal-aaguids.json: %w", err)
+	}
+	keep := make([]string, 0, len(list.AAGUIDs))
+	for id, name := range list.AAGUIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, info, fmt.Errorf("minimal-aaguids.json: invalid AAGUID %q (%s): %w", id, name, err)
+		}
+		keep = append(keep, id)
+	}
+	sort.Strings(keep)
+	pruned, rep := aaguids.NewProvider(entries, info).Prune(keep)
+	for _, id := range rep.Missing {
+		warnf("minimal-aaguids.json: %s (%s) is not in the dataset", strings.ToLower(id), list.AAGUIDs[id])
+	}
+
+	minimal := make(map[string]aaguids.Entry, rep.EntriesAfter)
+	for k, e := range pruned.Entries() {
+		minimal[k] = minimalEntry(e)
+	}
+	info.Sources = []aaguids.DatasetSource{{Name: aaguids.SourceEmbeddedMinimal}}
+	info.EntryCount = len(minimal)
+	info.LegalHeaderHashes = nil
+	integrity, err := aaguids.ComputeIntegrity(minimal)
+	if err != nil {
+		return nil, info, fmt.Errorf("computing minimal dataset integrity: %w", err)
+	}
+	info.Integrity = integrity
+	return minimal, info, nil
+}
+
+// minimalEntry keeps the fields of e that status checks and display names need: the AAGUID, the
+// description, the community display name (without icons), the status reports and
+// timeOfLastStatusChange.
+func minimalEntry(e aaguids.Entry) aaguids.Entry {
+	m := aaguids.Entry{
+		AAGUID: e.AAGUID,
+		MetadataStatement: aaguids.MetadataStatement{
+			AAGUID:         e.MetadataStatement.AAGUID,
+			Description:    e.MetadataStatement.Description,
+			ProtocolFamily: e.MetadataStatement.ProtocolFamily,
+		},
+		StatusReports:          e.StatusReports,
+		TimeOfLastStatusChange: e.TimeOfLastStatusChange,
+	}
+	if c := e.CommunityExtensions; c != nil {
+		m.CommunityExtensions = &aaguids.CommunityExtensions{Source: c.Source, DisplayName: c.DisplayName}
+	}
+	return m
+}
+
+/*
+minimalMain implements the minimal subcommand: it builds the minimal fallback dataset, the one
+stamped into every generated package as FromEmbeddedMinimal, and writes it to a SavePruned file
+that LoadPruned and PrunedFileSource read. It is meant for reviewing minimal-aaguids.json changes
+and for shipping the fallback separately from the full package.
+*/
+func minimalMain(args []string) error {
+	fset := flag.NewFlagSet("minimal", flag.ExitOnError)
+	mdsFile := fset.String("mds-file", "", "Read the MDS3 BLOB (JWT) from this file instead of downloading it")
+	passkeyFile := fset.String("passkey-file", "", "Read the passkey-authenticator-aaguids JSON from this file instead of downloading it")
+	out := fset.String("out", "minimal-dataset.gz", "Write the minimal dataset to this file")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	blob, entries, _, _, err := loadDataset(context.Background(), *mdsFile, *passkeyFile)
+	if err != nil {
+		return err
+	}
+	info := aaguids.Dataset{Serial: blob.No, NextUpdate: blob.NextUpdate, GeneratedAt: time.Now().UTC().Format(time.RFC3339)}
+	minimal, info, err := buildMinimalDataset(entries, info)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := aaguids.NewProvider(minimal, info).SavePruned(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d of %d entries to %s\n", len(minimal), len(entries), *out)
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sky93/aaguid-information-generator/internal"
+)
+
+/*
+snapshotMain implements the snapshot subcommand: it writes the full merged dataset, with the same
+Dataset identity the generated package embeds, to a SaveSnapshot container that LoadSnapshot and
+SnapshotFileSource read. The format version is stamped explicitly with -format-version, so during a
+rolling upgrade the file can be written in the previous version until every reader is updated.
+*/
+func snapshotMain(args []string) error {
+	fset := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	mdsFile := fset.String("mds-file", "", "Read the MDS3 BLOB (JWT) from this file instead of downloading it")
+	passkeyFile := fset.String("passkey-file", "", "Read the passkey-authenticator-aaguids JSON from this file instead of downloading it")
+	out := fset.String("out", "dataset.snap", "Write the snapshot to this file")
+	version := fset.Int("format-version", aaguids.SnapshotFormatVersion,
+		fmt.Sprintf("Snapshot format version to write (%d to %d)", aaguids.MinSnapshotFormatVersion, aaguids.SnapshotFormatVersion))
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	blob, entries, _, sources, err := loadDataset(context.Background(), *mdsFile, *passkeyFile)
+	if err != nil {
+		return err
+	}
+	integrity, err := aaguids.ComputeIntegrity(entries)
+	if err != nil {
+		return fmt.Errorf("computing dataset integrity: %w", err)
+	}
+	info := aaguids.Dataset{
+		Serial:            blob.No,
+		NextUpdate:        blob.NextUpdate,
+		GeneratedAt:       time.Now().UTC().Format(time.RFC3339),
+		Sources:           sources,
+		EntryCount:        len(entries),
+		Integrity:         integrity,
+		LegalHeaderHashes: aaguids.ComputeLegalHeaderHashes(entries),
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := aaguids.NewProvider(entries, info).SaveSnapshot(f, aaguids.WithSnapshotFormatVersion(*version)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d entries to %s (snapshot format version %d)\n", len(entries), *out, *version)
+	return nil
+}